@@ -0,0 +1,382 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// ACCESS CONTROL
+// =============================================================================
+
+// ACLExtension is the extension key holding the ACL of a scene, node or
+// edge
+const ACLExtension = "acl"
+
+// ErrAccessDenied is returned when a principal changes elements it may not
+// write
+var ErrAccessDenied = errors.New("access denied")
+
+// ErrUnauthenticated is returned when a request's credentials do not
+// identify a principal
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ACL labels a scene, node or edge with the principals allowed to see and
+// change it. Entries are principal IDs or group names. An empty Read list
+// lets everyone read; an empty Write list lets every reader write. Writers
+// may always read.
+//
+// Nodes and edges without an ACL inherit the scene's, and a scene without
+// one is open to all.
+type ACL struct {
+	Read  []string `json:"read,omitempty"`
+	Write []string `json:"write,omitempty"`
+}
+
+// Principal is a user or service acting on scenes, with the groups it
+// belongs to
+type Principal struct {
+	ID     string   `json:"id"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// principalKey is the context key of the acting principal
+type principalKey struct{}
+
+// WithPrincipal returns a context that records who is acting, for ACLGuard.
+// The principal must have been authenticated; the actor recorded by
+// WithActor is never used for access decisions.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal recorded by WithPrincipal, or
+// the anonymous zero Principal, which only matches open ACLs
+func PrincipalFromContext(ctx context.Context) Principal {
+	p, _ := ctx.Value(principalKey{}).(Principal)
+	return p
+}
+
+// matches reports whether the principal is named in the entries
+func (p Principal) matches(entries []string) bool {
+	if p.ID != "" && slices.Contains(entries, p.ID) {
+		return true
+	}
+	return slices.ContainsFunc(p.Groups, func(g string) bool { return g != "" && slices.Contains(entries, g) })
+}
+
+// CanRead reports whether the ACL lets the principal see what it labels. A
+// nil ACL allows everyone.
+func (a *ACL) CanRead(p Principal) bool {
+	return a == nil || len(a.Read) == 0 || p.matches(a.Read) || (len(a.Write) > 0 && p.matches(a.Write))
+}
+
+// CanWrite reports whether the ACL lets the principal change what it
+// labels. A nil ACL allows everyone.
+func (a *ACL) CanWrite(p Principal) bool {
+	if a == nil {
+		return true
+	}
+	if len(a.Write) == 0 {
+		return a.CanRead(p)
+	}
+	return p.matches(a.Write)
+}
+
+// lockedACL is the ACL no principal matches, as entries are never empty
+var lockedACL = ACL{Read: []string{""}, Write: []string{""}}
+
+// decodeACL returns the ACL in an extension map, or fallback when there is
+// none
+func decodeACL(ext map[string]interface{}, fallback *ACL) *ACL {
+	v, ok := ext[ACLExtension]
+	if !ok {
+		return fallback
+	}
+	var acl ACL
+	if err := roundTripJSON(v, &acl); err != nil {
+		// A malformed ACL must not open the element up
+		return &lockedACL
+	}
+	return &acl
+}
+
+// SceneACL returns the scene's ACL, or nil when it has none
+func (sf *SceneFile) SceneACL() *ACL {
+	return decodeACL(sf.Extensions, nil)
+}
+
+// NodeACL returns the effective ACL of a node: its own or the scene's
+func (sf *SceneFile) NodeACL(node *SceneNode) *ACL {
+	return decodeACL(node.Extensions, sf.SceneACL())
+}
+
+// EdgeACL returns the effective ACL of an edge: its own or the scene's
+func (sf *SceneFile) EdgeACL(edge *SceneEdge) *ACL {
+	return decodeACL(edge.Extensions, sf.SceneACL())
+}
+
+// AccessDeniedError lists the changes a principal was not allowed to make
+type AccessDeniedError struct {
+	Principal string    `json:"principal"`
+	Denied    SceneDiff `json:"denied"`
+}
+
+func (e *AccessDeniedError) Error() string {
+	var parts []string
+	if len(e.Denied.Fields) > 0 {
+		parts = append(parts, "scene fields "+strings.Join(e.Denied.Fields, ", "))
+	}
+	for _, c := range e.Denied.Nodes {
+		parts = append(parts, fmt.Sprintf("node %s (%s)", c.ID, c.Kind))
+	}
+	for _, c := range e.Denied.Edges {
+		parts = append(parts, fmt.Sprintf("edge %s (%s)", c.ID, c.Kind))
+	}
+	return fmt.Sprintf("%s: %q may not change %s", ErrAccessDenied, e.Principal, strings.Join(parts, "; "))
+}
+
+// Unwrap returns ErrAccessDenied so callers can use errors.Is
+func (e *AccessDeniedError) Unwrap() error {
+	return ErrAccessDenied
+}
+
+// DeniedChanges returns the part of the diff from base to changed that the
+// principal may not make. Modifying or removing an element needs write
+// access under its ACL in base, so an ACL cannot be loosened by someone it
+// excludes. Adding elements and changing scene-level fields needs write
+// access to the scene.
+func DeniedChanges(base, changed *SceneFile, p Principal) SceneDiff {
+	d := Diff(base, changed)
+	var denied SceneDiff
+	sceneWritable := base.SceneACL().CanWrite(p)
+	if !sceneWritable {
+		denied.Fields = d.Fields
+	}
+	for _, c := range d.Nodes {
+		allowed := sceneWritable
+		if c.Kind != ChangeAdded {
+			allowed = base.NodeACL(base.FindNode(c.ID)).CanWrite(p)
+		}
+		if !allowed {
+			denied.Nodes = append(denied.Nodes, c)
+		}
+	}
+	for _, c := range d.Edges {
+		allowed := sceneWritable
+		if c.Kind != ChangeAdded {
+			allowed = base.EdgeACL(base.FindEdge(c.ID)).CanWrite(p)
+		}
+		if !allowed {
+			denied.Edges = append(denied.Edges, c)
+		}
+	}
+	return denied
+}
+
+// CheckWriteAccess returns an *AccessDeniedError when the change from base
+// to changed includes anything DeniedChanges rejects
+func CheckWriteAccess(base, changed *SceneFile, p Principal) error {
+	if denied := DeniedChanges(base, changed, p); !denied.Empty() {
+		return &AccessDeniedError{Principal: p.ID, Denied: denied}
+	}
+	return nil
+}
+
+// MergeWithAccess applies the changes from base to changed that the
+// principal may make and returns the result with the changes it left out.
+// Denied modifications and removals keep the base element, denied
+// additions are dropped, and denied scene-level changes keep the base
+// fields. Neither input is modified.
+func MergeWithAccess(base, changed *SceneFile, p Principal) (SceneFile, SceneDiff) {
+	denied := DeniedChanges(base, changed, p)
+	if denied.Empty() {
+		return *changed, denied
+	}
+	out := *changed
+	if len(denied.Fields) > 0 {
+		out = *base
+	}
+
+	deniedNodes := make(map[string]ChangeKind, len(denied.Nodes))
+	for _, c := range denied.Nodes {
+		deniedNodes[c.ID] = c.Kind
+	}
+	out.Scene.Nodes = make([]SceneNode, 0, len(changed.Scene.Nodes))
+	for _, n := range changed.Scene.Nodes {
+		switch deniedNodes[n.ID] {
+		case ChangeAdded:
+		case ChangeModified:
+			out.Scene.Nodes = append(out.Scene.Nodes, *base.FindNode(n.ID))
+		default:
+			out.Scene.Nodes = append(out.Scene.Nodes, n)
+		}
+	}
+	for _, n := range base.Scene.Nodes {
+		if deniedNodes[n.ID] == ChangeRemoved {
+			out.Scene.Nodes = append(out.Scene.Nodes, n)
+		}
+	}
+
+	deniedEdges := make(map[string]ChangeKind, len(denied.Edges))
+	for _, c := range denied.Edges {
+		deniedEdges[c.ID] = c.Kind
+	}
+	out.Scene.Edges = make([]SceneEdge, 0, len(changed.Scene.Edges))
+	for _, e := range changed.Scene.Edges {
+		switch deniedEdges[e.ID] {
+		case ChangeAdded:
+		case ChangeModified:
+			out.Scene.Edges = append(out.Scene.Edges, *base.FindEdge(e.ID))
+		default:
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	for _, e := range base.Scene.Edges {
+		if deniedEdges[e.ID] == ChangeRemoved {
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	return out, denied
+}
+
+// VisibleTo returns the part of the diff the principal may see. Elements
+// are looked up in each of the scenes, typically the two the diff was made
+// from, and must be readable in every scene they appear in; scene-level
+// fields need read access to every scene.
+func (d SceneDiff) VisibleTo(p Principal, scenes ...*SceneFile) SceneDiff {
+	var visible SceneDiff
+	if !slices.ContainsFunc(scenes, func(sf *SceneFile) bool { return !sf.SceneACL().CanRead(p) }) {
+		visible.Fields = d.Fields
+	}
+	for _, c := range d.Nodes {
+		readable := !slices.ContainsFunc(scenes, func(sf *SceneFile) bool {
+			n := sf.FindNode(c.ID)
+			return n != nil && !sf.NodeACL(n).CanRead(p)
+		})
+		if readable {
+			visible.Nodes = append(visible.Nodes, c)
+		}
+	}
+	for _, c := range d.Edges {
+		readable := !slices.ContainsFunc(scenes, func(sf *SceneFile) bool {
+			e := sf.FindEdge(c.ID)
+			return e != nil && !sf.EdgeACL(e).CanRead(p)
+		})
+		if readable {
+			visible.Edges = append(visible.Edges, c)
+		}
+	}
+	return visible
+}
+
+// hiddenFrom returns the IDs of the nodes and edges the principal may not
+// read. Edges from or to hidden nodes are hidden with them.
+func (sf *SceneFile) hiddenFrom(p Principal) (map[string]bool, map[string]bool) {
+	sceneACL := sf.SceneACL()
+	nodes := make(map[string]bool)
+	for i := range sf.Scene.Nodes {
+		if !decodeACL(sf.Scene.Nodes[i].Extensions, sceneACL).CanRead(p) {
+			nodes[sf.Scene.Nodes[i].ID] = true
+		}
+	}
+	edges := make(map[string]bool)
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		if nodes[e.Source] || (!e.IsExternal() && nodes[e.Target]) || !decodeACL(e.Extensions, sceneACL).CanRead(p) {
+			edges[e.ID] = true
+		}
+	}
+	return nodes, edges
+}
+
+// ReadableBy returns the part of the scene the principal may read, leaving
+// out the nodes and edges it may not read and the edges from or to hidden
+// nodes. It reports false, with an empty scene, when the principal may not
+// read the scene itself. The scene is not modified.
+func (sf *SceneFile) ReadableBy(p Principal) (SceneFile, bool) {
+	if !sf.SceneACL().CanRead(p) {
+		return SceneFile{}, false
+	}
+	hiddenNodes, hiddenEdges := sf.hiddenFrom(p)
+	if len(hiddenNodes) == 0 && len(hiddenEdges) == 0 {
+		return *sf, true
+	}
+	out := *sf
+	out.Scene.Nodes = make([]SceneNode, 0, len(sf.Scene.Nodes)-len(hiddenNodes))
+	for _, n := range sf.Scene.Nodes {
+		if !hiddenNodes[n.ID] {
+			out.Scene.Nodes = append(out.Scene.Nodes, n)
+		}
+	}
+	out.Scene.Edges = make([]SceneEdge, 0, len(sf.Scene.Edges)-len(hiddenEdges))
+	for _, e := range sf.Scene.Edges {
+		if !hiddenEdges[e.ID] {
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	return out, true
+}
+
+// RestoreHidden returns changed with the nodes and edges of base that
+// ReadableBy hides from the principal added back, so a write from a client
+// that only saw part of a scene does not remove the rest. Hidden elements
+// the write includes are kept as written for the guards to judge. Neither
+// input is modified.
+func RestoreHidden(base, changed *SceneFile, p Principal) SceneFile {
+	out := *changed
+	hiddenNodes, hiddenEdges := base.hiddenFrom(p)
+	if len(hiddenNodes) == 0 && len(hiddenEdges) == 0 {
+		return out
+	}
+	written := make(map[string]bool, len(changed.Scene.Nodes))
+	for _, n := range changed.Scene.Nodes {
+		written[n.ID] = true
+	}
+	out.Scene.Nodes = slices.Clone(changed.Scene.Nodes)
+	for _, n := range base.Scene.Nodes {
+		if hiddenNodes[n.ID] && !written[n.ID] {
+			out.Scene.Nodes = append(out.Scene.Nodes, n)
+		}
+	}
+	written = make(map[string]bool, len(changed.Scene.Edges))
+	for _, e := range changed.Scene.Edges {
+		written[e.ID] = true
+	}
+	out.Scene.Edges = slices.Clone(changed.Scene.Edges)
+	for _, e := range base.Scene.Edges {
+		if hiddenEdges[e.ID] && !written[e.ID] {
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	return out
+}
+
+// ACLGuard is a WriteGuard enforcing ACLs for the principal in the context.
+// Every write needs an authenticated principal and fails with
+// ErrUnauthenticated without one, so a store guarded by ACLGuard refuses
+// writes from servers that do not authenticate requests. Writes are checked
+// with CheckWriteAccess; deleting a scene needs write access to it. Any
+// principal may create a scene.
+func ACLGuard(ctx context.Context, id string, prev, next *SceneFile) error {
+	p := PrincipalFromContext(ctx)
+	if p.ID == "" {
+		return fmt.Errorf("%w: scene %s: writes need an authenticated principal", ErrUnauthenticated, id)
+	}
+	if prev == nil {
+		return nil
+	}
+	if next == nil {
+		if !prev.SceneACL().CanWrite(p) {
+			return fmt.Errorf("%w: %q may not delete scene %s", ErrAccessDenied, p.ID, id)
+		}
+		return nil
+	}
+	if err := CheckWriteAccess(prev, next, p); err != nil {
+		return fmt.Errorf("scene %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// newACLScene returns a scene whose node b only ops may write and whose
+// node c only admins may see
+func newACLScene() SceneFile {
+	sf := newDiffScene()
+	sf.Scene.Nodes[1].Extensions = map[string]interface{}{ACLExtension: ACL{Write: []string{"ops"}}}
+	// ACLs decoded from JSON are generic maps
+	sf.Scene.Nodes[2].Extensions = map[string]interface{}{ACLExtension: map[string]interface{}{"read": []interface{}{"admins"}}}
+	return sf
+}
+
+// TestACL tests read and write checks with groups and inheritance
+func TestACL(t *testing.T) {
+	sf := newACLScene()
+	ada := Principal{ID: "ada", Groups: []string{"ops"}}
+	bob := Principal{ID: "bob"}
+	if acl := sf.NodeACL(&sf.Scene.Nodes[0]); acl != nil || !acl.CanWrite(bob) {
+		t.Errorf("open node mismatch: got %+v", acl)
+	}
+	b := sf.NodeACL(&sf.Scene.Nodes[1])
+	if !b.CanRead(bob) || b.CanWrite(bob) || !b.CanWrite(ada) {
+		t.Errorf("write-restricted node mismatch: got %+v", b)
+	}
+	c := sf.NodeACL(&sf.Scene.Nodes[2])
+	if c.CanRead(bob) || c.CanWrite(bob) || !c.CanWrite(Principal{ID: "admins"}) {
+		t.Errorf("read-restricted node mismatch: got %+v", c)
+	}
+	sf.Extensions = map[string]interface{}{ACLExtension: ACL{Write: []string{"ada"}}}
+	if sf.NodeACL(&sf.Scene.Nodes[0]).CanWrite(bob) {
+		t.Error("expected node to inherit the scene ACL")
+	}
+	sf.Scene.Nodes[0].Extensions = map[string]interface{}{ACLExtension: "bogus"}
+	if sf.NodeACL(&sf.Scene.Nodes[0]).CanRead(ada) {
+		t.Error("expected malformed ACL to deny access")
+	}
+}
+
+// TestCheckWriteAccess tests rejecting changes to protected elements
+func TestCheckWriteAccess(t *testing.T) {
+	base := newACLScene()
+	changed := newACLScene()
+	changed.Scene.Nodes[0].Name = "A2"
+	changed.Scene.Nodes[1].Name = "B2"
+	// Loosening an ACL needs write access under the old one
+	changed.Scene.Nodes[2].Extensions = nil
+
+	bob := Principal{ID: "bob"}
+	err := CheckWriteAccess(&base, &changed, bob)
+	var denied *AccessDeniedError
+	if !errors.As(err, &denied) || !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected *AccessDeniedError, got %v", err)
+	}
+	if len(denied.Denied.Nodes) != 2 || denied.Denied.Nodes[0].ID != "b" || denied.Denied.Nodes[1].ID != "c" {
+		t.Errorf("denied mismatch: got %+v", denied.Denied.Nodes)
+	}
+	if err := CheckWriteAccess(&base, &changed, Principal{ID: "root", Groups: []string{"ops", "admins"}}); err != nil {
+		t.Errorf("CheckWriteAccess failed: %v", err)
+	}
+}
+
+// TestMergeWithAccess tests applying only the permitted changes
+func TestMergeWithAccess(t *testing.T) {
+	base := newACLScene()
+	base.Extensions = map[string]interface{}{ACLExtension: ACL{Write: []string{"ops"}}}
+	changed := base
+	changed.Metadata.Description = "edited"
+	changed.Scene.Nodes = append([]SceneNode(nil), base.Scene.Nodes[1:]...)
+	changed.Scene.Nodes[0].Name = "B2"
+	changed.AddNode(SceneNode{ID: "d", Name: "D", Transform: NewTransform()})
+
+	merged, denied := MergeWithAccess(&base, &changed, Principal{ID: "bob"})
+	if merged.Metadata.Description != "" || len(denied.Fields) != 1 {
+		t.Errorf("scene fields mismatch: got %q, denied %v", merged.Metadata.Description, denied.Fields)
+	}
+	if n := merged.FindNode("b"); n == nil || n.Name != "B" {
+		t.Errorf("expected denied modification kept from base, got %+v", n)
+	}
+	if merged.FindNode("a") == nil || merged.FindNode("d") != nil {
+		t.Error("expected denied removal restored and denied addition dropped")
+	}
+	if len(denied.Nodes) != 3 {
+		t.Errorf("denied nodes mismatch: got %+v", denied.Nodes)
+	}
+
+	merged, denied = MergeWithAccess(&base, &changed, Principal{ID: "ada", Groups: []string{"ops"}})
+	if !denied.Empty() || merged.FindNode("b").Name != "B2" || merged.FindNode("d") == nil {
+		t.Errorf("expected all changes applied, denied %+v", denied)
+	}
+}
+
+// TestSceneDiff_VisibleTo tests hiding changes to unreadable elements
+func TestSceneDiff_VisibleTo(t *testing.T) {
+	base := newACLScene()
+	changed := newACLScene()
+	changed.Scene.Nodes[1].Name = "B2"
+	changed.Scene.Nodes[2].Name = "C2"
+	d := Diff(&base, &changed)
+	if visible := d.VisibleTo(Principal{ID: "bob"}, &base, &changed); len(visible.Nodes) != 1 || visible.Nodes[0].ID != "b" {
+		t.Errorf("visible mismatch: got %+v", visible.Nodes)
+	}
+	if visible := d.VisibleTo(Principal{ID: "x", Groups: []string{"admins"}}, &base, &changed); len(visible.Nodes) != 2 {
+		t.Errorf("visible mismatch: got %+v", visible.Nodes)
+	}
+}
+
+// TestReadableBy tests hiding unreadable elements from a scene and keeping
+// them through writes of what remains
+func TestReadableBy(t *testing.T) {
+	sf := newACLScene()
+	sf.AddEdge(SceneEdge{ID: "b-c", Source: "b", Target: "c"})
+	bob := Principal{ID: "bob"}
+	view, ok := sf.ReadableBy(bob)
+	if !ok || len(view.Scene.Nodes) != 2 || view.FindNode("c") != nil || len(view.Scene.Edges) != 1 || view.FindEdge("b-c") != nil {
+		t.Errorf("view mismatch for bob: got %v %+v", ok, view.Scene)
+	}
+	if len(sf.Scene.Nodes) != 3 || len(sf.Scene.Edges) != 2 {
+		t.Error("expected ReadableBy to leave the scene unmodified")
+	}
+	if view, _ := sf.ReadableBy(Principal{ID: "admins"}); len(view.Scene.Nodes) != 3 || len(view.Scene.Edges) != 2 {
+		t.Errorf("view mismatch for admins: got %+v", view.Scene)
+	}
+
+	view.Scene.Nodes = view.Scene.Nodes[1:]
+	view.Scene.Edges = nil
+	written := RestoreHidden(&sf, &view, bob)
+	if len(written.Scene.Nodes) != 2 || written.FindNode("a") != nil || written.FindNode("c") == nil ||
+		len(written.Scene.Edges) != 1 || written.FindEdge("b-c") == nil {
+		t.Errorf("restored scene mismatch: got %+v", written.Scene)
+	}
+
+	sf.Extensions = map[string]interface{}{ACLExtension: ACL{Read: []string{"admins"}}}
+	if _, ok := sf.ReadableBy(bob); ok {
+		t.Error("expected a read-restricted scene to be unreadable")
+	}
+}
+
+// TestACLGuard tests enforcing ACLs for the principal in the context
+func TestACLGuard(t *testing.T) {
+	ctx := context.Background()
+	store := NewGuardedStore(NewMemorySceneStore(), ACLGuard)
+	sf := newACLScene()
+	sf.Extensions = map[string]interface{}{ACLExtension: ACL{Write: []string{"ops"}}}
+	ops := WithPrincipal(ctx, Principal{ID: "ada", Groups: []string{"ops"}})
+	bob := WithPrincipal(ctx, Principal{ID: "bob"})
+	if _, err := store.Put(WithActor(ctx, "ada"), "prod", sf, 0); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected ErrUnauthenticated for an actor without a principal, got %v", err)
+	}
+	if _, err := store.Put(bob, "prod", sf, 0); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	sf.Scene.Nodes[1].Name = "B2"
+	if _, err := store.Put(bob, "prod", sf, AnyRevision); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied, got %v", err)
+	}
+	if _, err := store.Put(ops, "prod", sf, AnyRevision); err != nil {
+		t.Errorf("Put failed: %v", err)
+	}
+	if err := store.Delete(bob, "prod", AnyRevision); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied, got %v", err)
+	}
+	if err := store.Delete(ops, "prod", AnyRevision); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+}
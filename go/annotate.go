@@ -0,0 +1,118 @@
+package starfleet
+
+// =============================================================================
+// DIFF ANNOTATION
+// =============================================================================
+
+// DiffExtension is the node and edge extension key holding the ChangeKind of
+// an element in an annotated diff scene
+const DiffExtension = "diff"
+
+// DiffFieldsExtension is the node and edge extension key listing the changed
+// properties of a modified element
+const DiffFieldsExtension = "diffFields"
+
+// Colors used to annotate a diff scene
+var (
+	DiffAddedColor    = NewColor(0.2, 0.8, 0.3)
+	DiffRemovedColor  = NewColor(0.9, 0.2, 0.2)
+	DiffModifiedColor = NewColor(1.0, 0.75, 0.0)
+)
+
+// diffGhostOpacity is the opacity of removed elements
+const diffGhostOpacity = 0.3
+
+// AnnotateDiff returns a copy of changed for visual review of the changes
+// since base. Added nodes and edges are colored green and modified ones
+// amber; removed ones are restored from base as translucent red ghosts, with
+// removed edges dashed. Every changed element is tagged with DiffExtension,
+// and modified ones list their changed properties in DiffFieldsExtension.
+// Neither input is modified.
+func AnnotateDiff(base, changed *SceneFile) SceneFile {
+	d := Diff(base, changed)
+	out := *changed
+	out.Scene.Nodes = make([]SceneNode, len(changed.Scene.Nodes), len(changed.Scene.Nodes)+len(d.Nodes))
+	copy(out.Scene.Nodes, changed.Scene.Nodes)
+	out.Scene.Edges = make([]SceneEdge, len(changed.Scene.Edges), len(changed.Scene.Edges)+len(d.Edges))
+	copy(out.Scene.Edges, changed.Scene.Edges)
+
+	nodeIndex := make(map[string]int, len(out.Scene.Nodes))
+	for i := range out.Scene.Nodes {
+		nodeIndex[out.Scene.Nodes[i].ID] = i
+	}
+	for _, c := range d.Nodes {
+		// Removed nodes are restored from base, so their library references
+		// resolve there
+		src, node := changed, SceneNode{}
+		if c.Kind == ChangeRemoved {
+			src, node = base, *base.FindNode(c.ID)
+		} else {
+			node = out.Scene.Nodes[nodeIndex[c.ID]]
+		}
+		material := Material{}
+		if m := src.ResolveMaterial(&node); m != nil {
+			material = *m
+		}
+		material.TextureRegion, material.Texture = nil, ""
+		material.Color = diffColor(c.Kind)
+		if c.Kind == ChangeRemoved {
+			material.Opacity, material.Transparent = diffGhostOpacity, true
+		}
+		if node.Geometry == nil {
+			node.Geometry = src.ResolveGeometry(&node)
+		}
+		node.Material, node.MaterialRef, node.GeometryRef = &material, "", ""
+		node.Extensions = diffExtensions(node.Extensions, c)
+
+		if c.Kind == ChangeRemoved {
+			out.Scene.Nodes = append(out.Scene.Nodes, node)
+		} else {
+			out.Scene.Nodes[nodeIndex[c.ID]] = node
+		}
+	}
+
+	edgeIndex := make(map[string]int, len(out.Scene.Edges))
+	for i := range out.Scene.Edges {
+		edgeIndex[out.Scene.Edges[i].ID] = i
+	}
+	for _, c := range d.Edges {
+		var edge SceneEdge
+		if c.Kind == ChangeRemoved {
+			edge = *base.FindEdge(c.ID)
+		} else {
+			edge = out.Scene.Edges[edgeIndex[c.ID]]
+		}
+		edge.Color = diffColor(c.Kind)
+		edge.Extensions = diffExtensions(edge.Extensions, c)
+		if c.Kind == ChangeRemoved {
+			edge.Opacity, edge.Style = diffGhostOpacity, EdgeStyleDashed
+			out.Scene.Edges = append(out.Scene.Edges, edge)
+		} else {
+			out.Scene.Edges[edgeIndex[c.ID]] = edge
+		}
+	}
+	return out
+}
+
+// diffColor returns the annotation color for a change
+func diffColor(kind ChangeKind) *Color {
+	var c Color
+	switch kind {
+	case ChangeAdded:
+		c = DiffAddedColor
+	case ChangeRemoved:
+		c = DiffRemovedColor
+	default:
+		c = DiffModifiedColor
+	}
+	return &c
+}
+
+// diffExtensions returns a copy of ext tagged with a change
+func diffExtensions(ext map[string]interface{}, c ElementChange) map[string]interface{} {
+	out := withExtension(ext, DiffExtension, string(c.Kind))
+	if len(c.Fields) > 0 {
+		out[DiffFieldsExtension] = c.Fields
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package starfleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAnnotateDiff tests coloring and tagging of added, removed and modified
+// elements
+func TestAnnotateDiff(t *testing.T) {
+	base := newDiffScene()
+	base.Materials = map[string]Material{"steel": {Metalness: 0.8}}
+	base.Scene.Nodes[2].MaterialRef = "steel"
+
+	changed := newDiffScene()
+	changed.Materials = nil
+	changed.Scene.Nodes = changed.Scene.Nodes[:2]
+	changed.Scene.Nodes[1].Name = "B2"
+	changed.AddNode(SceneNode{ID: "d", Type: "server", Name: "D", Transform: NewTransform()})
+	changed.Scene.Edges = nil
+	changed.AddEdge(SceneEdge{ID: "a-d", Source: "a", Target: "d"})
+
+	out := AnnotateDiff(&base, &changed)
+	if len(out.Scene.Nodes) != 4 || len(out.Scene.Edges) != 2 {
+		t.Fatalf("element count mismatch: got %d nodes, %d edges", len(out.Scene.Nodes), len(out.Scene.Edges))
+	}
+
+	if a := out.FindNode("a"); a.Material != nil || a.Extensions != nil {
+		t.Errorf("unchanged node should not be annotated: got %+v", a)
+	}
+	b := out.FindNode("b")
+	if *b.Material.Color != DiffModifiedColor || b.Extensions[DiffExtension] != "modified" ||
+		!reflect.DeepEqual(b.Extensions[DiffFieldsExtension], []string{"name"}) {
+		t.Errorf("modified node mismatch: got %+v", b)
+	}
+	if d := out.FindNode("d"); *d.Material.Color != DiffAddedColor || d.Extensions[DiffExtension] != "added" {
+		t.Errorf("added node mismatch: got %+v", d)
+	}
+	c := out.FindNode("c")
+	if c == nil || *c.Material.Color != DiffRemovedColor || c.Material.Opacity != diffGhostOpacity ||
+		c.Material.Metalness != 0.8 || c.MaterialRef != "" {
+		t.Errorf("removed node mismatch: got %+v", c)
+	}
+
+	ab, ad := out.FindEdge("a-b"), out.FindEdge("a-d")
+	if ab == nil || *ab.Color != DiffRemovedColor || ab.Style != EdgeStyleDashed {
+		t.Errorf("removed edge mismatch: got %+v", ab)
+	}
+	if *ad.Color != DiffAddedColor {
+		t.Errorf("added edge mismatch: got %+v", ad)
+	}
+	if result := ValidateScene(&out); !result.Valid {
+		t.Errorf("annotated scene is invalid: %v", result.Errors)
+	}
+
+	// Inputs are left untouched
+	if changed.FindNode("b").Material != nil || len(changed.Scene.Nodes) != 3 {
+		t.Error("AnnotateDiff modified its input")
+	}
+}
@@ -0,0 +1,106 @@
+package starfleet
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// ANOMALY DETECTION
+// =============================================================================
+
+// AnomalyExtension is the node and edge extension key listing the metrics
+// currently flagged as anomalous
+const AnomalyExtension = "anomaly"
+
+// Anomaly is a data point a detector flagged as unusual. Anomalies of edge
+// metrics set EdgeID instead of NodeID.
+type Anomaly struct {
+	NodeID string    `json:"nodeId,omitempty"`
+	EdgeID string    `json:"edgeId,omitempty"`
+	Metric string    `json:"metric" validate:"required"`
+	Time   time.Time `json:"time"`
+	Value  float64   `json:"value"`
+	Score  float64   `json:"score"`
+}
+
+// AnomalyDetector scores the values of metric series as they arrive. Series
+// are identified by an opaque key and values arrive in time order.
+// Implementations keep per-series state and must be safe for concurrent use.
+type AnomalyDetector interface {
+	Observe(series string, t time.Time, value float64) (score float64, anomalous bool)
+}
+
+// AnomalyDetectorFunc adapts a function to the AnomalyDetector interface
+type AnomalyDetectorFunc func(series string, t time.Time, value float64) (float64, bool)
+
+// Observe calls f
+func (f AnomalyDetectorFunc) Observe(series string, t time.Time, value float64) (float64, bool) {
+	return f(series, t, value)
+}
+
+// EWMADetector flags values whose z-score against an exponentially weighted
+// moving mean and variance exceeds Threshold. Alpha is the weight of each new
+// value, and no value is flagged until a series has MinSamples values.
+// Anomalous values are still folded into the baseline, so a lasting level
+// shift stops being flagged once the baseline catches up.
+type EWMADetector struct {
+	Alpha      float64
+	Threshold  float64
+	MinSamples int
+
+	mu     sync.Mutex
+	series map[string]*ewmaState
+}
+
+type ewmaState struct {
+	mean     float64
+	variance float64
+	n        int
+}
+
+// NewEWMADetector creates a detector with smoothing alpha and a z-score
+// threshold, warming up over 10 samples
+func NewEWMADetector(alpha, threshold float64) *EWMADetector {
+	return &EWMADetector{Alpha: alpha, Threshold: threshold, MinSamples: 10, series: make(map[string]*ewmaState)}
+}
+
+// Observe scores a value against the series baseline and then updates it
+func (d *EWMADetector) Observe(series string, _ time.Time, value float64) (float64, bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.series == nil {
+		d.series = make(map[string]*ewmaState)
+	}
+	s, ok := d.series[series]
+	if !ok {
+		d.series[series] = &ewmaState{mean: value, n: 1}
+		return 0, false
+	}
+
+	diff := value - s.mean
+	var score float64
+	if sd := math.Sqrt(s.variance); sd > 0 {
+		score = math.Abs(diff) / sd
+	} else if diff != 0 {
+		score = math.Inf(1)
+	}
+	anomalous := s.n >= d.MinSamples && score > d.Threshold
+
+	incr := d.Alpha * diff
+	s.mean += incr
+	s.variance = (1 - d.Alpha) * (s.variance + diff*incr)
+	s.n++
+	return score, anomalous
+}
+
+// Reset forgets the baseline of a series
+func (d *EWMADetector) Reset(series string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.series, series)
+}
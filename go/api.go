@@ -0,0 +1,258 @@
+package starfleet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// SCENE SERVICE API
+// =============================================================================
+
+// Error codes reported by the scene service
+const (
+	APIErrorNotFound             = "not_found"
+	APIErrorBadRequest           = "bad_request"
+	APIErrorInvalidScene         = "invalid_scene"
+	APIErrorRevisionConflict     = "revision_conflict"
+	APIErrorPreconditionRequired = "precondition_required"
+	APIErrorUnsupportedMedia     = "unsupported_media_type"
+	APIErrorNotImplemented       = "not_implemented"
+	APIErrorInvalidTransition    = "invalid_transition"
+	APIErrorApprovalRequired     = "approval_required"
+	APIErrorChangeControlled     = "change_controlled"
+	APIErrorUnsupportedVersion   = "unsupported_version"
+	APIErrorIncompatibleVersion  = "incompatible_version"
+	APIErrorUnsupportedViewer    = "unsupported_viewer"
+	APIErrorInvalidSignature     = "invalid_signature"
+	APIErrorUnavailable          = "unavailable"
+	APIErrorPatchTestFailed      = "patch_test_failed"
+	APIErrorStaleElement         = "stale_element"
+	APIErrorAccessDenied         = "access_denied"
+	APIErrorUnauthenticated      = "unauthenticated"
+	APIErrorInternal             = "internal"
+)
+
+// ActorHeader names the user on whose behalf a request is made
+const ActorHeader = "X-Starfleet-Actor"
+
+// BaseRevisionHeader carries the entity tag of the revision an
+// unconditional write was edited from, so the nodes and edges it removes
+// can be checked against that revision
+const BaseRevisionHeader = "X-Starfleet-Base-Revision"
+
+// AcceptVersionHeader carries the range of scene format versions a reader
+// understands, such as "^0.1.0"
+const AcceptVersionHeader = "X-Starfleet-Accept-Version"
+
+// DowngradeLostHeader lists, comma-separated, the capabilities whose data
+// was dropped when a scene was downgraded for the reader
+const DowngradeLostHeader = "X-Starfleet-Downgrade-Lost"
+
+// ViewerVersionHeader carries the version of the viewer asking for a scene,
+// checked against the scene's requirements
+const ViewerVersionHeader = "X-Starfleet-Viewer-Version"
+
+// ViewerFeaturesHeader lists, comma-separated, the rendering features the
+// viewer supports
+const ViewerFeaturesHeader = "X-Starfleet-Viewer-Features"
+
+// ViewerDisabledHeader lists, comma-separated, the optional features
+// stripped from a scene because the viewer lacks them
+const ViewerDisabledHeader = "X-Starfleet-Viewer-Disabled"
+
+// APIError is the JSON body of every error response from the scene service.
+// Conflict is set for revision conflicts, Stale for stale element writes,
+// Validation for rejected scenes, Compatibility for version mismatches and
+// Viewer for viewers that cannot render a scene.
+type APIError struct {
+	// Status is the HTTP status code; it is not part of the body
+	Status        int                    `json:"-"`
+	Code          string                 `json:"code" validate:"required"`
+	Message       string                 `json:"message"`
+	Conflict      *RevisionConflictError `json:"conflict,omitempty"`
+	Stale         *StaleElementError     `json:"stale,omitempty"`
+	Validation    *ValidationResult      `json:"validation,omitempty"`
+	Compatibility *CompatibilityResult   `json:"compatibility,omitempty"`
+	Viewer        *ViewerNegotiation     `json:"viewer,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("scene service: %d %s: %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("scene service: %s: %s", e.Code, e.Message)
+}
+
+// Unwrap maps well-known codes onto the SDK's sentinel errors
+func (e *APIError) Unwrap() error {
+	switch e.Code {
+	case APIErrorRevisionConflict:
+		if e.Conflict != nil {
+			return e.Conflict
+		}
+		return ErrRevisionConflict
+	case APIErrorNotFound:
+		return ErrSceneNotFound
+	case APIErrorInvalidTransition:
+		return ErrInvalidTransition
+	case APIErrorApprovalRequired:
+		return ErrApprovalRequired
+	case APIErrorChangeControlled:
+		return ErrChangeControlled
+	case APIErrorInvalidSignature:
+		return ErrInvalidURLSignature
+	case APIErrorPatchTestFailed:
+		return ErrPatchTestFailed
+	case APIErrorAccessDenied:
+		return ErrAccessDenied
+	case APIErrorUnauthenticated:
+		return ErrUnauthenticated
+	case APIErrorUnsupportedViewer:
+		return ErrUnsupportedViewer
+	case APIErrorStaleElement:
+		if e.Stale != nil {
+			return e.Stale
+		}
+		return ErrStaleElement
+	}
+	return nil
+}
+
+// ScenePage is one page of the scene catalog. NextCursor is empty on the
+// last page.
+type ScenePage struct {
+	Scenes     []SceneSummary `json:"scenes"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// CompatibilityInfo describes the scene formats a service reads and writes
+type CompatibilityInfo struct {
+	SchemaVersion string          `json:"schemaVersion"`
+	Accepts       string          `json:"accepts"`
+	Capabilities  []Capability    `json:"capabilities"`
+	Releases      []SchemaRelease `json:"releases"`
+}
+
+// LocalCompatibility describes the formats supported by this SDK
+func LocalCompatibility() CompatibilityInfo {
+	caps, _ := CapabilitiesFor(SchemaVersion)
+	return CompatibilityInfo{
+		SchemaVersion: SchemaVersion,
+		Accepts:       "<=" + SchemaVersion,
+		Capabilities:  caps,
+		Releases:      SchemaReleases(),
+	}
+}
+
+// TransitionRequest asks the service to move a scene to another lifecycle
+// state
+type TransitionRequest struct {
+	State   LifecycleState `json:"state" validate:"required"`
+	Comment string         `json:"comment,omitempty"`
+}
+
+// ApprovalRequest records the acting user's approval of a scene in review
+type ApprovalRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// ApprovalPolicyRequest replaces the approval policy of a draft scene
+type ApprovalPolicyRequest struct {
+	RequiredApprovers []string `json:"requiredApprovers,omitempty"`
+	MinApprovals      int      `json:"minApprovals,omitempty" validate:"omitempty,min=0"`
+	AllowSelfApproval bool     `json:"allowSelfApproval,omitempty"`
+}
+
+// SignedURLRequest asks the service for a download URL that needs no other
+// credentials. Exactly one of Scene and Asset is set; Asset is a blob
+// digest. TTL is in seconds; zero uses the service default.
+type SignedURLRequest struct {
+	Scene    string  `json:"scene,omitempty" validate:"required_without=Asset"`
+	Revision int64   `json:"revision,omitempty" validate:"omitempty,min=1"`
+	Asset    string  `json:"asset,omitempty" validate:"required_without=Scene"`
+	TTL      float64 `json:"ttl,omitempty" validate:"omitempty,gt=0"`
+}
+
+// ImportRequest asks the service to run a registered importer in the
+// background
+type ImportRequest struct {
+	Importer string         `json:"importer" validate:"required"`
+	Config   ImporterConfig `json:"config,omitempty"`
+}
+
+// SearchResponse lists the nodes of a scene matching a search, at the
+// revision that was searched
+type SearchResponse struct {
+	Revision int64          `json:"revision"`
+	Results  []SearchResult `json:"results"`
+}
+
+// SceneStatsResponse is the body of GET /scenes/{id}/stats: the statistics
+// of a scene at the revision they were computed for
+type SceneStatsResponse struct {
+	Revision int64              `json:"revision"`
+	Stats    DetailedSceneStats `json:"stats"`
+}
+
+// SceneEventType identifies a change to a stored scene
+type SceneEventType string
+
+const (
+	// SceneEventSnapshot carries the current state when a stream opens
+	SceneEventSnapshot SceneEventType = "snapshot"
+	SceneEventCreated  SceneEventType = "created"
+	SceneEventUpdated  SceneEventType = "updated"
+	SceneEventDeleted  SceneEventType = "deleted"
+	// SceneEventImported is sent when a scheduled import changes a scene;
+	// the payload explains each change with its provenance
+	SceneEventImported SceneEventType = "imported"
+	// SceneEventPresence reports who is viewing the scene and where; it
+	// carries no revision or scene
+	SceneEventPresence SceneEventType = "presence"
+	// SceneEventMembership reports how the smart groups of a scene changed
+	// with a revision; it carries no scene
+	SceneEventMembership SceneEventType = "membership"
+	// SceneEventExpired is sent when an ExpirySweeper removes expired nodes
+	// and edges; Expired lists them
+	SceneEventExpired SceneEventType = "expired"
+)
+
+// SceneEvent describes a change to a stored scene. Scene holds the new
+// content and is nil for deletions; Presence is set for presence events,
+// Membership for membership events and Expired for expiry events.
+type SceneEvent struct {
+	Type       SceneEventType     `json:"type" validate:"required"`
+	ID         string             `json:"id" validate:"required"`
+	Revision   int64              `json:"revision,omitempty"`
+	Time       time.Time          `json:"time"`
+	Scene      *SceneFile         `json:"scene,omitempty"`
+	Presence   *Presence          `json:"presence,omitempty"`
+	Membership []MembershipChange `json:"membership,omitempty"`
+	Expired    *ExpiredElements   `json:"expired,omitempty"`
+}
+
+// RevisionTag formats a revision as a strong HTTP entity tag
+func RevisionTag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// ParseRevisionTag parses an entity tag produced by RevisionTag. The
+// wildcard "*" parses as AnyRevision.
+func ParseRevisionTag(tag string) (int64, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return AnyRevision, nil
+	}
+	unquoted := strings.TrimPrefix(tag, "W/")
+	if len(unquoted) < 2 || unquoted[0] != '"' || unquoted[len(unquoted)-1] != '"' {
+		return 0, fmt.Errorf("parse revision tag %q: not a quoted entity tag", tag)
+	}
+	revision, err := strconv.ParseInt(unquoted[1:len(unquoted)-1], 10, 64)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("parse revision tag %q: invalid revision", tag)
+	}
+	return revision, nil
+}
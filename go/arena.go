@@ -0,0 +1,111 @@
+package starfleet
+
+import "sync"
+
+// =============================================================================
+// SCENE ARENAS
+// =============================================================================
+
+// DefaultArenaChunk is the number of nodes or edges in an arena slab when
+// NewSceneArena is not given a size
+const DefaultArenaChunk = 4096
+
+// SceneArena hands out node and edge slices carved from large reusable
+// slabs, for code that builds and discards many short-lived scenes, such
+// as a streaming reconciler. Everything taken from an arena is released
+// together by Reset, which recycles the slabs instead of leaving the
+// scenes to the garbage collector.
+//
+// Scenes using arena slices must not be used after Reset. Appending past a
+// slice's capacity moves it to the heap as usual, so growing a scene is
+// safe, if no longer cheap. The methods of a nil arena allocate normally.
+// A SceneArena is safe for concurrent use.
+type SceneArena struct {
+	mu    sync.Mutex
+	nodes slab[SceneNode]
+	edges slab[SceneEdge]
+}
+
+// NewSceneArena creates an arena with slabs of chunk elements, or of
+// DefaultArenaChunk when chunk is not positive. Requests larger than a
+// slab get a slab of their own, which is recycled like the others.
+func NewSceneArena(chunk int) *SceneArena {
+	if chunk <= 0 {
+		chunk = DefaultArenaChunk
+	}
+	return &SceneArena{nodes: slab[SceneNode]{size: chunk}, edges: slab[SceneEdge]{size: chunk}}
+}
+
+// Nodes returns an empty node slice with room for n nodes
+func (a *SceneArena) Nodes(n int) []SceneNode {
+	if a == nil {
+		return make([]SceneNode, 0, n)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nodes.take(n)
+}
+
+// Edges returns an empty edge slice with room for n edges
+func (a *SceneArena) Edges(n int) []SceneEdge {
+	if a == nil {
+		return make([]SceneEdge, 0, n)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.edges.take(n)
+}
+
+// Clone returns a copy of a scene whose nodes and edges live in the arena.
+// Like Reconcile, it copies elements shallowly: their maps and slices are
+// shared with sf.
+func (a *SceneArena) Clone(sf *SceneFile) SceneFile {
+	out := *sf
+	out.Scene.Nodes = append(a.Nodes(len(sf.Scene.Nodes)), sf.Scene.Nodes...)
+	out.Scene.Edges = append(a.Edges(len(sf.Scene.Edges)), sf.Scene.Edges...)
+	return out
+}
+
+// Reset releases everything taken from the arena for reuse. Slabs are
+// cleared so they do not keep the maps and strings of released elements
+// alive.
+func (a *SceneArena) Reset() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nodes.reset()
+	a.edges.reset()
+}
+
+// slab is a list of chunks handed out front to back
+type slab[T any] struct {
+	size   int
+	chunks [][]T
+	// cur and off locate the free space in the current chunk
+	cur, off int
+}
+
+// take returns an empty slice with capacity n from the first chunk with
+// room, skipping chunks too small and adding one when none is left
+func (s *slab[T]) take(n int) []T {
+	for ; s.cur < len(s.chunks); s.cur, s.off = s.cur+1, 0 {
+		if chunk := s.chunks[s.cur]; len(chunk)-s.off >= n {
+			out := chunk[s.off : s.off : s.off+n]
+			s.off += n
+			return out
+		}
+	}
+	s.chunks = append(s.chunks, make([]T, max(s.size, n)))
+	s.off = n
+	return s.chunks[s.cur][0:0:n]
+}
+
+// reset clears the chunks in use and starts over from the first
+func (s *slab[T]) reset() {
+	for i := 0; i < len(s.chunks) && i <= s.cur; i++ {
+		clear(s.chunks[i])
+	}
+	s.cur, s.off = 0, 0
+}
@@ -0,0 +1,86 @@
+package starfleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestSceneArena tests carving slices from slabs and recycling them
+func TestSceneArena(t *testing.T) {
+	arena := NewSceneArena(8)
+	a, b := arena.Nodes(3), arena.Nodes(5)
+	if len(a) != 0 || cap(a) != 3 || cap(b) != 5 {
+		t.Fatalf("slice mismatch: got len %d cap %d and cap %d", len(a), cap(a), cap(b))
+	}
+	first := &a[:1][0]
+	b = append(b, SceneNode{ID: "b"})
+	// Growing a slice past its room moves it rather than spilling into
+	// its neighbor
+	a = append(a, SceneNode{ID: "a1"}, SceneNode{ID: "a2"}, SceneNode{ID: "a3"}, SceneNode{ID: "a4"})
+	if b[0].ID != "b" || &a[0] == first {
+		t.Errorf("neighbor mismatch: got %q, want %q", b[0].ID, "b")
+	}
+
+	big := arena.Edges(20)
+	if cap(big) != 20 {
+		t.Errorf("oversize capacity mismatch: got %d, want 20", cap(big))
+	}
+	bigFirst := &big[:1][0]
+
+	arena.Reset()
+	again := arena.Nodes(3)
+	if &again[:1][0] != first {
+		t.Error("slab was not reused after Reset")
+	}
+	if again[:cap(again)][2].ID != "" || b[:1][0].ID != "" {
+		t.Error("released nodes were not cleared")
+	}
+	if got := arena.Edges(20); &got[:1][0] != bigFirst {
+		t.Error("oversize slab was not reused after Reset")
+	}
+
+	var none *SceneArena
+	if nodes := none.Nodes(4); nodes == nil || cap(nodes) != 4 {
+		t.Errorf("nil arena mismatch: got %v with cap %d", nodes, cap(nodes))
+	}
+	none.Reset()
+}
+
+// TestReconcile_Arena tests that reconciling into an arena gives the same
+// scene as reconciling onto the heap
+func TestReconcile_Arena(t *testing.T) {
+	current := newDiffScene()
+	imported := NewSceneFile("Import")
+	imported.AddNode(SceneNode{ID: "a", Type: "vm", Name: "A2", Transform: NewTransform()})
+	imported.AddNode(SceneNode{ID: "d", Type: "db", Name: "D", Transform: NewTransform()})
+	imported.AddEdge(SceneEdge{ID: "a-d", Source: "a", Target: "d"})
+
+	arena := NewSceneArena(16)
+	want, wantDiff := Reconcile(&current, &imported, ReconcileOptions{Source: "aws"})
+	got, gotDiff := Reconcile(&current, &imported, ReconcileOptions{Source: "aws", Arena: arena})
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(gotDiff, wantDiff) {
+		t.Errorf("scene mismatch: got %+v, want %+v", got.Scene, want.Scene)
+	}
+
+	clone := arena.Clone(&current)
+	if !reflect.DeepEqual(clone, current) {
+		t.Errorf("clone mismatch: got %+v, want %+v", clone.Scene, current.Scene)
+	}
+}
+
+// BenchmarkReconcile_Arena compares reconciling with and without an arena
+func BenchmarkReconcile_Arena(b *testing.B) {
+	current, imported := newLargeScene(5000), newLargeScene(5000)
+	for _, tt := range []struct {
+		name  string
+		arena *SceneArena
+	}{{"heap", nil}, {"arena", NewSceneArena(0)}} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Reconcile(&current, &imported, ReconcileOptions{Source: "bench", Arena: tt.arena})
+				tt.arena.Reset()
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for DecodeConfig
+	_ "image/jpeg" // register JPEG for DecodeConfig
+	_ "image/png"  // register PNG for DecodeConfig
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// ASSET STORAGE
+// =============================================================================
+
+// ErrAssetNotFound is returned when an asset cannot be read from storage
+var ErrAssetNotFound = errors.New("asset not found")
+
+// AssetStorage reads and writes the binary content behind scene asset URIs
+type AssetStorage interface {
+	// ReadAsset returns the content stored at uri
+	ReadAsset(ctx context.Context, uri string) ([]byte, error)
+	// WriteAsset stores content under name and returns its uri
+	WriteAsset(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// MemoryAssetStorage is an in-memory AssetStorage keyed by uri
+type MemoryAssetStorage struct {
+	mu     sync.RWMutex
+	assets map[string][]byte
+}
+
+// NewMemoryAssetStorage creates an empty in-memory asset storage
+func NewMemoryAssetStorage() *MemoryAssetStorage {
+	return &MemoryAssetStorage{assets: make(map[string][]byte)}
+}
+
+// ReadAsset implements AssetStorage
+func (s *MemoryAssetStorage) ReadAsset(ctx context.Context, uri string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.assets[uri]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, uri)
+	}
+	return data, nil
+}
+
+// WriteAsset implements AssetStorage; the name is used as the uri
+func (s *MemoryAssetStorage) WriteAsset(ctx context.Context, name string, data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets[name] = append([]byte(nil), data...)
+	return name, nil
+}
+
+// DirAssetStorage stores assets as files below a root directory. URIs are
+// slash-separated paths relative to the root.
+type DirAssetStorage struct {
+	Root string
+}
+
+// path resolves a uri to a file path, rejecting escapes from the root
+func (s DirAssetStorage) path(uri string) (string, error) {
+	clean := path.Clean("/" + uri)
+	if clean == "/" {
+		return "", fmt.Errorf("%w: %s", ErrAssetNotFound, uri)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean[1:])), nil
+}
+
+// ReadAsset implements AssetStorage
+func (s DirAssetStorage) ReadAsset(ctx context.Context, uri string) ([]byte, error) {
+	p, err := s.path(uri)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, uri)
+	}
+	return data, err
+}
+
+// WriteAsset implements AssetStorage
+func (s DirAssetStorage) WriteAsset(ctx context.Context, name string, data []byte) (string, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return "", err
+	}
+	return path.Clean("/" + name)[1:], nil
+}
+
+// =============================================================================
+// TEXTURE FORMATS
+// =============================================================================
+
+// TextureFormat represents the container format of a texture
+type TextureFormat string
+
+const (
+	TextureUnknown TextureFormat = ""
+	TexturePNG     TextureFormat = "png"
+	TextureJPEG    TextureFormat = "jpeg"
+	TextureGIF     TextureFormat = "gif"
+	TextureWebP    TextureFormat = "webp"
+	TextureKTX2    TextureFormat = "ktx2"
+	TextureBasis   TextureFormat = "basis"
+)
+
+// ktx2Magic is the identifier at the start of every KTX2 file
+var ktx2Magic = []byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// DetectTextureFormat identifies a texture container from its magic bytes
+func DetectTextureFormat(data []byte) TextureFormat {
+	switch {
+	case bytes.HasPrefix(data, ktx2Magic):
+		return TextureKTX2
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return TexturePNG
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return TextureJPEG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return TextureGIF
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return TextureWebP
+	case bytes.HasPrefix(data, []byte("sB")):
+		return TextureBasis
+	default:
+		return TextureUnknown
+	}
+}
+
+// TextureInfo represents the properties of a texture read from its header
+type TextureInfo struct {
+	Format TextureFormat `json:"format"`
+	Width  int           `json:"width"`
+	Height int           `json:"height"`
+	// Levels is the number of mip levels stored; zero for KTX2 files that
+	// request runtime generation
+	Levels int `json:"levels"`
+}
+
+// InspectTexture reads format and dimensions from texture data. Dimensions
+// are reported for PNG, JPEG, GIF and KTX2.
+func InspectTexture(data []byte) (TextureInfo, error) {
+	info := TextureInfo{Format: DetectTextureFormat(data), Levels: 1}
+	switch info.Format {
+	case TextureUnknown:
+		return info, errors.New("unrecognized texture format")
+	case TextureKTX2:
+		// Header: identifier, vkFormat, typeSize, pixelWidth, pixelHeight,
+		// pixelDepth, layerCount, faceCount, levelCount
+		if len(data) < len(ktx2Magic)+32 {
+			return info, errors.New("truncated ktx2 header")
+		}
+		h := data[len(ktx2Magic):]
+		info.Width = int(binary.LittleEndian.Uint32(h[8:]))
+		info.Height = int(binary.LittleEndian.Uint32(h[12:]))
+		info.Levels = int(binary.LittleEndian.Uint32(h[28:]))
+	case TexturePNG, TextureJPEG, TextureGIF:
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return info, err
+		}
+		info.Width, info.Height = cfg.Width, cfg.Height
+	}
+	return info, nil
+}
+
+// isPowerOfTwo reports whether n is a positive power of two
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// =============================================================================
+// TEXTURE TRANSCODING
+// =============================================================================
+
+// TextureTranscoder converts source textures into mipmapped KTX2 files with
+// Basis Universal supercompression
+type TextureTranscoder interface {
+	TranscodeTexture(ctx context.Context, data []byte, format TextureFormat) ([]byte, error)
+}
+
+// TextureTranscoderFunc adapts a function to the TextureTranscoder interface
+type TextureTranscoderFunc func(ctx context.Context, data []byte, format TextureFormat) ([]byte, error)
+
+// TranscodeTexture implements TextureTranscoder
+func (f TextureTranscoderFunc) TranscodeTexture(ctx context.Context, data []byte, format TextureFormat) ([]byte, error) {
+	return f(ctx, data, format)
+}
+
+// BasisuTranscoder transcodes textures by running the basisu command line
+// encoder from the Basis Universal project, which must be installed
+// separately
+type BasisuTranscoder struct {
+	// Path is the basisu executable; "basisu" on PATH when empty
+	Path string
+	// UASTC selects the higher quality UASTC mode instead of ETC1S
+	UASTC bool
+}
+
+// TranscodeTexture implements TextureTranscoder
+func (b BasisuTranscoder) TranscodeTexture(ctx context.Context, data []byte, format TextureFormat) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "starfleet-basisu")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "texture."+string(format))
+	out := filepath.Join(dir, "texture.ktx2")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+	bin := b.Path
+	if bin == "" {
+		bin = "basisu"
+	}
+	args := []string{"-ktx2", "-mipmap", "-file", in, "-output_file", out}
+	if b.UASTC {
+		args = append(args, "-uastc")
+	}
+	cmd := exec.CommandContext(ctx, bin, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("basisu: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(out)
+}
+
+// =============================================================================
+// ASSET MANAGER
+// =============================================================================
+
+// AssetManager processes the binary assets referenced by scenes
+type AssetManager struct {
+	Storage AssetStorage
+	// Transcoder produces KTX2 textures; textures are only validated when nil
+	Transcoder TextureTranscoder
+}
+
+// NewAssetManager creates an asset manager over storage
+func NewAssetManager(storage AssetStorage, transcoder TextureTranscoder) *AssetManager {
+	return &AssetManager{Storage: storage, Transcoder: transcoder}
+}
+
+// TextureReport represents the outcome of texture processing
+type TextureReport struct {
+	Textures   int      `json:"textures"`
+	Transcoded int      `json:"transcoded"`
+	Rewritten  int      `json:"rewritten"`
+	Warnings   []string `json:"warnings,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// textureRef is a material texture reference and where it was found
+type textureRef struct {
+	texture string
+	update  func(func(*Material))
+	owner   string
+}
+
+// materialTextures collects the texture references of every material in
+// the scene, including the material library
+func materialTextures(sf *SceneFile) []textureRef {
+	var refs []textureRef
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		if node.Material != nil && node.Material.Texture != "" {
+			material := node.Material
+			refs = append(refs, textureRef{
+				texture: material.Texture,
+				update:  func(f func(*Material)) { f(material) },
+				owner:   "node " + node.ID,
+			})
+		}
+	}
+	keys := make([]string, 0, len(sf.Materials))
+	for key, m := range sf.Materials {
+		if m.Texture != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		refs = append(refs, textureRef{
+			texture: sf.Materials[key].Texture,
+			update: func(f func(*Material)) {
+				m := sf.Materials[key]
+				f(&m)
+				sf.Materials[key] = m
+			},
+			owner: "material " + key,
+		})
+	}
+	return refs
+}
+
+// ProcessTextures validates every material texture and, when a transcoder
+// is configured, generates a mipmapped KTX2 version and rewrites the
+// material to reference it. Texture values that name an entry of
+// SceneFile.Assets are resolved through it and the KTX2 file is registered
+// as a sibling asset with a "-ktx2" suffix; other values are treated as
+// storage uris and replaced directly. Identical textures are processed once.
+func (am *AssetManager) ProcessTextures(ctx context.Context, sf *SceneFile) (TextureReport, error) {
+	report := TextureReport{}
+	done := make(map[string]string)
+	for _, ref := range materialTextures(sf) {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Textures++
+		texture := ref.texture
+		if replacement, ok := done[texture]; ok {
+			if replacement != texture {
+				ref.update(func(m *Material) { m.Texture = replacement })
+				report.Rewritten++
+			}
+			continue
+		}
+		done[texture] = texture
+
+		uri, isAsset := sf.Assets[texture]
+		if !isAsset {
+			uri = texture
+		}
+		data, err := am.Storage.ReadAsset(ctx, uri)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: texture %q: %v", ref.owner, texture, err))
+			continue
+		}
+		info, err := InspectTexture(data)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: texture %q: %v", ref.owner, texture, err))
+			continue
+		}
+		if info.Width > 0 && (!isPowerOfTwo(info.Width) || !isPowerOfTwo(info.Height)) {
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"%s: texture %q is %dx%d, not a power of two", ref.owner, texture, info.Width, info.Height))
+		}
+		if info.Format == TextureKTX2 || am.Transcoder == nil {
+			continue
+		}
+
+		out, err := am.Transcoder.TranscodeTexture(ctx, data, info.Format)
+		if err != nil {
+			return report, fmt.Errorf("transcode %s: %w", texture, err)
+		}
+		if DetectTextureFormat(out) != TextureKTX2 {
+			return report, fmt.Errorf("transcode %s: transcoder did not produce ktx2", texture)
+		}
+		newURI, err := am.Storage.WriteAsset(ctx, strings.TrimSuffix(uri, path.Ext(uri))+".ktx2", out)
+		if err != nil {
+			return report, err
+		}
+		replacement := newURI
+		if isAsset {
+			replacement = texture + "-ktx2"
+			sf.Assets[replacement] = newURI
+		}
+		done[texture] = replacement
+		ref.update(func(m *Material) { m.Texture = replacement })
+		report.Transcoded++
+		report.Rewritten++
+	}
+	return report, nil
+}
@@ -0,0 +1,134 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodeTestPNG returns a blank PNG of the given size
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// encodeTestKTX2 returns a KTX2 header with the given size and levels
+func encodeTestKTX2(w, h, levels int) []byte {
+	header := make([]byte, 32)
+	binary.LittleEndian.PutUint32(header[8:], uint32(w))
+	binary.LittleEndian.PutUint32(header[12:], uint32(h))
+	binary.LittleEndian.PutUint32(header[28:], uint32(levels))
+	return append(append([]byte(nil), ktx2Magic...), header...)
+}
+
+// TestInspectTexture tests format detection and header parsing
+func TestInspectTexture(t *testing.T) {
+	info, err := InspectTexture(encodeTestPNG(t, 64, 32))
+	if err != nil {
+		t.Fatalf("InspectTexture failed: %v", err)
+	}
+	if info.Format != TexturePNG || info.Width != 64 || info.Height != 32 {
+		t.Errorf("png info mismatch: got %+v", info)
+	}
+
+	info, err = InspectTexture(encodeTestKTX2(256, 256, 9))
+	if err != nil {
+		t.Fatalf("InspectTexture failed: %v", err)
+	}
+	if info.Format != TextureKTX2 || info.Width != 256 || info.Levels != 9 {
+		t.Errorf("ktx2 info mismatch: got %+v", info)
+	}
+
+	if _, err := InspectTexture([]byte("not an image")); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+// TestProcessTextures tests transcoding and reference rewriting
+func TestProcessTextures(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryAssetStorage()
+	storage.WriteAsset(ctx, "textures/rack.png", encodeTestPNG(t, 128, 128))
+	storage.WriteAsset(ctx, "textures/logo.png", encodeTestPNG(t, 100, 60))
+	storage.WriteAsset(ctx, "textures/ready.ktx2", encodeTestKTX2(64, 64, 7))
+
+	sf := NewSceneFile("Textures")
+	sf.Assets["rack"] = "textures/rack.png"
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "a", Material: &Material{Texture: "rack"}},
+		{ID: "b", Material: &Material{Texture: "rack"}},
+		{ID: "c", Material: &Material{Texture: "textures/ready.ktx2"}},
+		{ID: "d", Material: &Material{Texture: "textures/missing.png"}},
+	}
+	sf.Materials = map[string]Material{"logo": {Texture: "textures/logo.png"}}
+
+	calls := 0
+	transcoder := TextureTranscoderFunc(func(ctx context.Context, data []byte, format TextureFormat) ([]byte, error) {
+		calls++
+		info, err := InspectTexture(data)
+		if err != nil {
+			return nil, err
+		}
+		return encodeTestKTX2(info.Width, info.Height, 8), nil
+	})
+
+	report, err := NewAssetManager(storage, transcoder).ProcessTextures(ctx, &sf)
+	if err != nil {
+		t.Fatalf("ProcessTextures failed: %v", err)
+	}
+	if calls != 2 || report.Transcoded != 2 {
+		t.Errorf("transcode count mismatch: got %d calls, %d transcoded, want 2", calls, report.Transcoded)
+	}
+	if report.Rewritten != 3 {
+		t.Errorf("rewritten count mismatch: got %d, want 3", report.Rewritten)
+	}
+	if len(report.Errors) != 1 || len(report.Warnings) != 1 {
+		t.Errorf("expected one error and one warning, got %v and %v", report.Errors, report.Warnings)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if got := sf.FindNode(id).Material.Texture; got != "rack-ktx2" {
+			t.Errorf("node %s texture mismatch: got %q, want %q", id, got, "rack-ktx2")
+		}
+	}
+	if got := sf.Assets["rack-ktx2"]; got != "textures/rack.ktx2" {
+		t.Errorf("asset uri mismatch: got %q, want %q", got, "textures/rack.ktx2")
+	}
+	if got := sf.Materials["logo"].Texture; got != "textures/logo.ktx2" {
+		t.Errorf("library texture mismatch: got %q, want %q", got, "textures/logo.ktx2")
+	}
+	if got := sf.FindNode("c").Material.Texture; got != "textures/ready.ktx2" {
+		t.Errorf("ktx2 texture should be unchanged, got %q", got)
+	}
+}
+
+// TestDirAssetStorage tests that uris cannot escape the root directory
+func TestDirAssetStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	storage := DirAssetStorage{Root: filepath.Join(root, "assets")}
+
+	uri, err := storage.WriteAsset(ctx, "../../escape.bin", []byte{1, 2, 3})
+	if err != nil {
+		t.Fatalf("WriteAsset failed: %v", err)
+	}
+	if uri != "escape.bin" {
+		t.Errorf("uri mismatch: got %q, want %q", uri, "escape.bin")
+	}
+	if _, err := os.Stat(filepath.Join(root, "assets", "escape.bin")); err != nil {
+		t.Errorf("expected file inside root: %v", err)
+	}
+	if _, err := storage.ReadAsset(ctx, "missing.bin"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrAssetNotFound)
+	}
+}
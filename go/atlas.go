@@ -0,0 +1,251 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	"image/png"
+	"sort"
+)
+
+// =============================================================================
+// TEXTURE ATLASES
+// =============================================================================
+
+// TextureRegion represents the part of a texture a material samples, in
+// normalized image coordinates with the origin at the top-left corner
+type TextureRegion struct {
+	U      float64 `json:"u" validate:"min=0,max=1"`
+	V      float64 `json:"v" validate:"min=0,max=1"`
+	Width  float64 `json:"width" validate:"gt=0,max=1"`
+	Height float64 `json:"height" validate:"gt=0,max=1"`
+}
+
+// AtlasOptions represents the configuration of BuildAtlas
+type AtlasOptions struct {
+	// Name is the asset key and storage name of the atlas; "atlas" when empty
+	Name string
+	// MaxSize caps the atlas width and height in pixels; 4096 when zero
+	MaxSize int
+	// MaxTextureSize excludes textures wider or taller than this, which are
+	// better served on their own; 256 when zero
+	MaxTextureSize int
+	// Padding is the gutter around each texture, filled by extending its
+	// edge pixels to avoid bleeding under filtering; 2 when zero
+	Padding int
+}
+
+// normalized returns the options with defaults applied
+func (o AtlasOptions) normalized() AtlasOptions {
+	if o.Name == "" {
+		o.Name = "atlas"
+	}
+	if o.MaxSize <= 0 {
+		o.MaxSize = 4096
+	}
+	if o.MaxTextureSize <= 0 {
+		o.MaxTextureSize = 256
+	}
+	if o.Padding <= 0 {
+		o.Padding = 2
+	}
+	return o
+}
+
+// AtlasReport represents the outcome of BuildAtlas
+type AtlasReport struct {
+	Width     int      `json:"width"`
+	Height    int      `json:"height"`
+	Packed    int      `json:"packed"`
+	Rewritten int      `json:"rewritten"`
+	Skipped   []string `json:"skipped,omitempty"`
+}
+
+// atlasEntry is a texture waiting to be packed
+type atlasEntry struct {
+	texture string
+	img     image.Image
+	x, y    int
+}
+
+// BuildAtlas packs small material textures into a single PNG atlas asset
+// and rewrites each material to sample its region of the atlas. Textures
+// that cannot be decoded, are too large, or do not fit are left untouched
+// and listed in the report. Materials that already sample a region are not
+// packed. Textures are packed on shelves ordered by height, which is close
+// to optimal for the uniformly sized icons typical of node textures.
+func (am *AssetManager) BuildAtlas(ctx context.Context, sf *SceneFile, opts AtlasOptions) (AtlasReport, error) {
+	opts = opts.normalized()
+	report := AtlasReport{}
+
+	refs := materialTextures(sf)
+	entries := make(map[string]*atlasEntry)
+	var order []*atlasEntry
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if _, seen := entries[ref.texture]; seen || ref.texture == opts.Name {
+			continue
+		}
+		inRegion := false
+		ref.update(func(m *Material) { inRegion = m.TextureRegion != nil })
+		if inRegion {
+			continue
+		}
+		uri, ok := sf.Assets[ref.texture]
+		if !ok {
+			uri = ref.texture
+		}
+		entries[ref.texture] = nil
+		data, err := am.Storage.ReadAsset(ctx, uri)
+		if err != nil {
+			report.Skipped = append(report.Skipped, ref.texture)
+			continue
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			report.Skipped = append(report.Skipped, ref.texture)
+			continue
+		}
+		size := img.Bounds().Size()
+		if size.X > opts.MaxTextureSize || size.Y > opts.MaxTextureSize {
+			report.Skipped = append(report.Skipped, ref.texture)
+			continue
+		}
+		entry := &atlasEntry{texture: ref.texture, img: img}
+		entries[ref.texture] = entry
+		order = append(order, entry)
+	}
+	if len(order) < 2 {
+		// A single texture gains nothing from an atlas
+		for _, e := range order {
+			report.Skipped = append(report.Skipped, e.texture)
+		}
+		return report, nil
+	}
+
+	placed := packShelves(order, opts)
+	for _, e := range order {
+		if e.x < 0 {
+			report.Skipped = append(report.Skipped, e.texture)
+			entries[e.texture] = nil
+		}
+	}
+	width, height := 0, 0
+	for _, e := range placed {
+		size := e.img.Bounds().Size()
+		width = max(width, e.x+size.X+opts.Padding)
+		height = max(height, e.y+size.Y+opts.Padding)
+	}
+	width, height = nextPowerOfTwo(width), nextPowerOfTwo(height)
+
+	atlas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for _, e := range placed {
+		drawPadded(atlas, e, opts.Padding)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, atlas); err != nil {
+		return report, err
+	}
+	uri, err := am.Storage.WriteAsset(ctx, opts.Name+".png", buf.Bytes())
+	if err != nil {
+		return report, err
+	}
+	if sf.Assets == nil {
+		sf.Assets = make(map[string]string)
+	}
+	sf.Assets[opts.Name] = uri
+
+	for _, ref := range refs {
+		e := entries[ref.texture]
+		if e == nil {
+			continue
+		}
+		size := e.img.Bounds().Size()
+		region := TextureRegion{
+			U:      float64(e.x) / float64(width),
+			V:      float64(e.y) / float64(height),
+			Width:  float64(size.X) / float64(width),
+			Height: float64(size.Y) / float64(height),
+		}
+		ref.update(func(m *Material) {
+			if m.TextureRegion == nil {
+				m.Texture = opts.Name
+				m.TextureRegion = &region
+			}
+		})
+		report.Rewritten++
+	}
+	report.Width, report.Height, report.Packed = width, height, len(placed)
+	return report, nil
+}
+
+// packShelves assigns atlas positions to entries, tallest first, and marks
+// entries that do not fit with x = -1. The shelf width is the smallest power
+// of two that could hold the total area, so atlases stay roughly square.
+func packShelves(entries []*atlasEntry, opts AtlasOptions) []*atlasEntry {
+	sorted := append([]*atlasEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].img.Bounds().Dy() > sorted[j].img.Bounds().Dy()
+	})
+
+	area, widest := 0, 0
+	for _, e := range sorted {
+		size := e.img.Bounds().Size()
+		w, h := size.X+2*opts.Padding, size.Y+2*opts.Padding
+		area += w * h
+		widest = max(widest, w)
+	}
+	shelfWidth := nextPowerOfTwo(widest)
+	for shelfWidth*shelfWidth < area && shelfWidth < opts.MaxSize {
+		shelfWidth *= 2
+	}
+	shelfWidth = min(shelfWidth, opts.MaxSize)
+
+	var placed []*atlasEntry
+	x, y, shelfHeight := 0, 0, 0
+	for _, e := range sorted {
+		size := e.img.Bounds().Size()
+		w, h := size.X+2*opts.Padding, size.Y+2*opts.Padding
+		if x+w > shelfWidth {
+			x, y, shelfHeight = 0, y+shelfHeight, 0
+		}
+		if w > shelfWidth || y+h > opts.MaxSize {
+			e.x = -1
+			continue
+		}
+		e.x, e.y = x+opts.Padding, y+opts.Padding
+		x += w
+		shelfHeight = max(shelfHeight, h)
+		placed = append(placed, e)
+	}
+	return placed
+}
+
+// drawPadded copies an entry into the atlas and extends its edge pixels
+// into the surrounding gutter
+func drawPadded(atlas *image.NRGBA, e *atlasEntry, padding int) {
+	b := e.img.Bounds()
+	draw.Draw(atlas, image.Rect(e.x, e.y, e.x+b.Dx(), e.y+b.Dy()), e.img, b.Min, draw.Src)
+	for y := -padding; y < b.Dy()+padding; y++ {
+		for x := -padding; x < b.Dx()+padding; x++ {
+			if x >= 0 && x < b.Dx() && y >= 0 && y < b.Dy() {
+				continue
+			}
+			sx := min(max(x, 0), b.Dx()-1)
+			sy := min(max(y, 0), b.Dy()-1)
+			atlas.Set(e.x+x, e.y+y, atlas.At(e.x+sx, e.y+sy))
+		}
+	}
+}
+
+// nextPowerOfTwo returns the smallest power of two not below n
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
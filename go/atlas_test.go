@@ -0,0 +1,97 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// encodeSolidPNG returns a PNG of the given size filled with one color
+func encodeSolidPNG(t *testing.T, w, h int, c color.NRGBA) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestBuildAtlas tests packing and region rewriting
+func TestBuildAtlas(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryAssetStorage()
+	colors := map[string]color.NRGBA{
+		"icons/server.png":   {255, 0, 0, 255},
+		"icons/database.png": {0, 255, 0, 255},
+		"icons/queue.png":    {0, 0, 255, 255},
+	}
+	for uri, c := range colors {
+		storage.WriteAsset(ctx, uri, encodeSolidPNG(t, 32, 32, c))
+	}
+	storage.WriteAsset(ctx, "big.png", encodeSolidPNG(t, 512, 512, color.NRGBA{A: 255}))
+
+	sf := NewSceneFile("Icons")
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "web", Material: &Material{Texture: "icons/server.png"}},
+		{ID: "api", Material: &Material{Texture: "icons/server.png"}},
+		{ID: "db", Material: &Material{Texture: "icons/database.png"}},
+		{ID: "floor", Material: &Material{Texture: "big.png"}},
+	}
+	sf.Materials = map[string]Material{"queue": {Texture: "icons/queue.png"}}
+
+	report, err := NewAssetManager(storage, nil).BuildAtlas(ctx, &sf, AtlasOptions{})
+	if err != nil {
+		t.Fatalf("BuildAtlas failed: %v", err)
+	}
+	if report.Packed != 3 || report.Rewritten != 4 {
+		t.Errorf("report mismatch: got %+v", report)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != "big.png" {
+		t.Errorf("skipped mismatch: got %v, want [big.png]", report.Skipped)
+	}
+	if got := sf.FindNode("floor").Material.Texture; got != "big.png" {
+		t.Errorf("large texture should be untouched, got %q", got)
+	}
+
+	data, err := storage.ReadAsset(ctx, sf.Assets["atlas"])
+	if err != nil {
+		t.Fatalf("atlas asset missing: %v", err)
+	}
+	atlas, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode failed: %v", err)
+	}
+	if atlas.Bounds().Dx() != report.Width || atlas.Bounds().Dy() != report.Height {
+		t.Errorf("atlas size mismatch: got %v, want %dx%d", atlas.Bounds(), report.Width, report.Height)
+	}
+
+	check := func(owner string, m Material, want color.NRGBA) {
+		if m.Texture != "atlas" || m.TextureRegion == nil {
+			t.Errorf("%s not rewritten: %+v", owner, m)
+			return
+		}
+		r := m.TextureRegion
+		// Sample the region center and a gutter pixel outside it
+		cx := int((r.U + r.Width/2) * float64(report.Width))
+		cy := int((r.V + r.Height/2) * float64(report.Height))
+		gx := int(r.U*float64(report.Width)) - 1
+		for _, p := range [][2]int{{cx, cy}, {gx, cy}} {
+			got := color.NRGBAModel.Convert(atlas.At(p[0], p[1])).(color.NRGBA)
+			if got != want {
+				t.Errorf("%s pixel %v mismatch: got %v, want %v", owner, p, got, want)
+			}
+		}
+	}
+	check("web", *sf.FindNode("web").Material, colors["icons/server.png"])
+	check("db", *sf.FindNode("db").Material, colors["icons/database.png"])
+	check("queue", sf.Materials["queue"], colors["icons/queue.png"])
+}
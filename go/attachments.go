@@ -0,0 +1,125 @@
+package starfleet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// =============================================================================
+// MEDIA ATTACHMENTS
+// =============================================================================
+
+// AttachmentKind represents the type of media attached to a node
+type AttachmentKind string
+
+const (
+	AttachmentAudio    AttachmentKind = "audio"
+	AttachmentImage    AttachmentKind = "image"
+	AttachmentDocument AttachmentKind = "document"
+	AttachmentLink     AttachmentKind = "link"
+)
+
+// Attachment represents supporting media bundled with a node. Content is
+// referenced either through Asset, a key into SceneFile.Assets, or through a
+// direct URL. Audio attachments with OnStatus set act as cues played when the
+// node transitions into one of the listed statuses.
+type Attachment struct {
+	ID          string         `json:"id" validate:"required"`
+	Kind        AttachmentKind `json:"kind" validate:"required"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Asset       string         `json:"asset,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	MimeType    string         `json:"mimeType,omitempty"`
+	OnStatus    []NodeStatus   `json:"onStatus,omitempty"`
+}
+
+// AttachMedia adds an attachment to a node. When uri is non-empty it is
+// registered in the scene assets under the attachment's Asset key (or the
+// attachment ID when no key is set) so the content travels with the scene.
+func (sf *SceneFile) AttachMedia(nodeID string, attachment Attachment, uri string) error {
+	node := sf.FindNode(nodeID)
+	if node == nil {
+		return fmt.Errorf("attach %s: %w: %s", attachment.ID, ErrNodeNotFound, nodeID)
+	}
+	if uri != "" {
+		if attachment.Asset == "" {
+			attachment.Asset = attachment.ID
+		}
+		if sf.Assets == nil {
+			sf.Assets = make(map[string]string)
+		}
+		sf.Assets[attachment.Asset] = uri
+	}
+	node.Attachments = append(node.Attachments, attachment)
+	return nil
+}
+
+// AttachmentURL resolves the location of an attachment's content, preferring
+// the scene asset registry over a direct URL
+func (sf *SceneFile) AttachmentURL(attachment Attachment) (string, bool) {
+	if attachment.Asset != "" {
+		uri, ok := sf.Assets[attachment.Asset]
+		return uri, ok
+	}
+	return attachment.URL, attachment.URL != ""
+}
+
+// StatusCues returns the audio attachments of a node that should play when it
+// enters the given status
+func (n *SceneNode) StatusCues(status NodeStatus) []Attachment {
+	var cues []Attachment
+	for _, a := range n.Attachments {
+		if a.Kind != AttachmentAudio {
+			continue
+		}
+		for _, s := range a.OnStatus {
+			if s == status {
+				cues = append(cues, a)
+				break
+			}
+		}
+	}
+	return cues
+}
+
+// ValidateAttachments checks that every attachment has a kind, resolvable
+// content and, for audio cues, only known statuses. It returns one message
+// per problem found.
+func ValidateAttachments(sf *SceneFile) []string {
+	var errs []string
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		seen := make(map[string]bool, len(node.Attachments))
+		for _, a := range node.Attachments {
+			if seen[a.ID] {
+				errs = append(errs, fmt.Sprintf("Node %s has duplicate attachment id: %s", node.ID, a.ID))
+			}
+			seen[a.ID] = true
+
+			switch a.Kind {
+			case AttachmentAudio, AttachmentImage, AttachmentDocument, AttachmentLink:
+			default:
+				errs = append(errs, fmt.Sprintf("Attachment %s on node %s has unknown kind: %s", a.ID, node.ID, a.Kind))
+			}
+			if a.Asset != "" {
+				if _, ok := sf.Assets[a.Asset]; !ok {
+					errs = append(errs, fmt.Sprintf("Attachment %s on node %s references non-existent asset: %s", a.ID, node.ID, a.Asset))
+				}
+			} else if strings.TrimSpace(a.URL) == "" {
+				errs = append(errs, fmt.Sprintf("Attachment %s on node %s has no asset or url", a.ID, node.ID))
+			}
+			if len(a.OnStatus) > 0 && a.Kind != AttachmentAudio {
+				errs = append(errs, fmt.Sprintf("Attachment %s on node %s uses onStatus but is not audio", a.ID, node.ID))
+			}
+			for _, s := range a.OnStatus {
+				switch s {
+				case NodeStatusHealthy, NodeStatusWarning, NodeStatusCritical, NodeStatusUnknown:
+				default:
+					errs = append(errs, fmt.Sprintf("Attachment %s on node %s has unknown onStatus: %s", a.ID, node.ID, s))
+				}
+			}
+		}
+	}
+	return errs
+}
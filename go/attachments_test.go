@@ -0,0 +1,65 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestAttachMedia tests attaching media and registering assets
+func TestAttachMedia(t *testing.T) {
+	sf := NewSceneFile("Incident")
+	sf.AddNode(SceneNode{ID: "db", Type: "database", Name: "DB", Transform: NewTransform()})
+
+	err := sf.AttachMedia("db", Attachment{ID: "alarm", Kind: AttachmentAudio, OnStatus: []NodeStatus{NodeStatusCritical}}, "assets/alarm.ogg")
+	if err != nil {
+		t.Fatalf("AttachMedia failed: %v", err)
+	}
+	err = sf.AttachMedia("db", Attachment{ID: "runbook", Kind: AttachmentLink, URL: "https://wiki.example.com/db"}, "")
+	if err != nil {
+		t.Fatalf("AttachMedia failed: %v", err)
+	}
+
+	node := sf.FindNode("db")
+	if len(node.Attachments) != 2 {
+		t.Fatalf("Attachment count mismatch: got %d, want 2", len(node.Attachments))
+	}
+	if uri, ok := sf.AttachmentURL(node.Attachments[0]); !ok || uri != "assets/alarm.ogg" {
+		t.Errorf("Asset attachment URL mismatch: got %s", uri)
+	}
+	if uri, ok := sf.AttachmentURL(node.Attachments[1]); !ok || uri != "https://wiki.example.com/db" {
+		t.Errorf("Link attachment URL mismatch: got %s", uri)
+	}
+	if cues := node.StatusCues(NodeStatusCritical); len(cues) != 1 || cues[0].ID != "alarm" {
+		t.Errorf("StatusCues mismatch: got %+v", cues)
+	}
+	if cues := node.StatusCues(NodeStatusHealthy); len(cues) != 0 {
+		t.Errorf("StatusCues should be empty for healthy: got %+v", cues)
+	}
+
+	err = sf.AttachMedia("missing", Attachment{ID: "x", Kind: AttachmentImage}, "")
+	if !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("AttachMedia error mismatch: got %v, want ErrNodeNotFound", err)
+	}
+}
+
+// TestValidateAttachments tests attachment validation
+func TestValidateAttachments(t *testing.T) {
+	sf := NewSceneFile("Incident")
+	sf.AddNode(SceneNode{
+		ID:        "db",
+		Type:      "database",
+		Name:      "DB",
+		Transform: NewTransform(),
+		Attachments: []Attachment{
+			{ID: "graph", Kind: AttachmentImage, Asset: "missing"},
+			{ID: "graph", Kind: "video", URL: "https://example.com"},
+			{ID: "doc", Kind: AttachmentDocument, OnStatus: []NodeStatus{NodeStatusWarning}},
+			{ID: "siren", Kind: AttachmentAudio, URL: "https://example.com/siren.ogg", OnStatus: []NodeStatus{NodeStatusCritical, "down"}},
+		},
+	})
+
+	errs := ValidateAttachments(&sf)
+	if len(errs) != 6 {
+		t.Errorf("ValidateAttachments error count mismatch: got %d, want 6: %v", len(errs), errs)
+	}
+}
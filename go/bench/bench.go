@@ -0,0 +1,355 @@
+// Package bench measures how long common scene operations take on scenes of
+// realistic size, so performance can be compared across releases on the
+// same workloads.
+//
+// Run generates service graphs at the configured scales with Generate, and
+// loads any scene files given, then times each operation on each scene:
+//
+//	parse      decoding the scene with starfleet.OpenReader
+//	validate   starfleet.ValidateSceneContext
+//	layout     the configured layout, by default a globe geo layout
+//	diff       starfleet.Diff against a copy with about 1% of nodes changed
+//	serve      GET /scenes/{id} from a server.Server over a memory store,
+//	           in process and without the network
+//
+// Each operation runs a warm-up iteration and then the configured number of
+// timed ones, on a fresh copy of the scene where it modifies it. The Report
+// holds the spread of the timings with the memory each iteration allocated,
+// and encodes as JSON so reports from different releases can be compared.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+	"github.com/hyperdrive-technology/starfleet-sdk-go/server"
+)
+
+// Operations
+const (
+	OpParse    = "parse"
+	OpValidate = "validate"
+	OpLayout   = "layout"
+	OpDiff     = "diff"
+	OpServe    = "serve"
+)
+
+// Operations lists every operation in the order Run measures them
+var Operations = []string{OpParse, OpValidate, OpLayout, OpDiff, OpServe}
+
+// DefaultScales are the generated scene sizes, in nodes, when none are set
+var DefaultScales = []int{100, 1000, 10000}
+
+// DefaultIterations is the number of timed iterations when none is set
+const DefaultIterations = 5
+
+// Options configures a benchmark run
+type Options struct {
+	// Scales are the node counts of generated scenes; nil uses
+	// DefaultScales and an empty non-nil slice generates none
+	Scales []int `json:"scales,omitempty"`
+	// Files are scene files measured alongside the generated scenes
+	Files []string `json:"files,omitempty"`
+	// Operations restricts the run to some operations; empty runs all
+	Operations []string `json:"operations,omitempty"`
+	// Iterations is the number of timed runs of each operation
+	Iterations int `json:"iterations,omitempty"`
+	// Seed makes generated scenes reproducible
+	Seed int64 `json:"seed,omitempty"`
+	// Layout is the layout timed by the layout operation; nil uses a
+	// starfleet.GeoLayout on a globe
+	Layout starfleet.Layout `json:"-"`
+}
+
+// Report is the outcome of a benchmark run
+type Report struct {
+	Started    time.Time `json:"started"`
+	GoVersion  string    `json:"goVersion"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	CPUs       int       `json:"cpus"`
+	Iterations int       `json:"iterations"`
+	Seed       int64     `json:"seed"`
+	Results    []Result  `json:"results"`
+}
+
+// Result is the measurement of one operation on one scene. Durations
+// encode as nanoseconds.
+type Result struct {
+	Scene     string `json:"scene"`
+	Operation string `json:"operation"`
+	Nodes     int    `json:"nodes"`
+	Edges     int    `json:"edges"`
+	// Bytes is the size of the scene as JSON, or of the file it was read from
+	Bytes int `json:"bytes"`
+
+	Min    time.Duration `json:"min"`
+	Median time.Duration `json:"median"`
+	P95    time.Duration `json:"p95"`
+	Max    time.Duration `json:"max"`
+	Mean   time.Duration `json:"mean"`
+	// AllocsPerOp and BytesPerOp are the heap allocations of an iteration
+	AllocsPerOp uint64 `json:"allocsPerOp"`
+	BytesPerOp  uint64 `json:"bytesPerOp"`
+
+	// Error is set, and the timings left zero, when the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// Run measures the configured operations on every scene. A failing
+// operation is recorded in its Result and the run goes on; Run itself only
+// fails on bad options, unreadable files or cancellation.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.Iterations <= 0 {
+		opts.Iterations = DefaultIterations
+	}
+	if opts.Scales == nil {
+		opts.Scales = DefaultScales
+	}
+	ops := opts.Operations
+	if len(ops) == 0 {
+		ops = Operations
+	}
+	for _, op := range ops {
+		if !slices.Contains(Operations, op) {
+			return Report{}, fmt.Errorf("bench: unknown operation %q", op)
+		}
+	}
+	if opts.Layout == nil {
+		opts.Layout = starfleet.NewGeoLayout(starfleet.GeoSphere)
+	}
+
+	report := Report{
+		Started:    time.Now().UTC(),
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		Iterations: opts.Iterations,
+		Seed:       opts.Seed,
+	}
+	var workloads []*workload
+	for _, n := range opts.Scales {
+		if n <= 0 {
+			return Report{}, fmt.Errorf("bench: scale %d is not positive", n)
+		}
+		w, err := generatedWorkload(n, opts.Seed)
+		if err != nil {
+			return Report{}, err
+		}
+		workloads = append(workloads, w)
+	}
+	for _, path := range opts.Files {
+		w, err := fileWorkload(path)
+		if err != nil {
+			return Report{}, err
+		}
+		workloads = append(workloads, w)
+	}
+
+	for _, w := range workloads {
+		for _, op := range Operations {
+			if !slices.Contains(ops, op) {
+				continue
+			}
+			result, err := w.measure(ctx, op, opts)
+			if err != nil {
+				return Report{}, err
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report, nil
+}
+
+// workload is a scene with what its operations need prepared
+type workload struct {
+	name string
+	// data is the encoded scene and file the name parse reads it under
+	data []byte
+	file string
+	sf   starfleet.SceneFile
+	// changed is the scene diff compares against
+	changed starfleet.SceneFile
+	seed    int64
+}
+
+// generatedWorkload generates a scene of n nodes
+func generatedWorkload(n int, seed int64) (*workload, error) {
+	sf := Generate(n, seed)
+	data, err := json.Marshal(sf)
+	if err != nil {
+		return nil, fmt.Errorf("bench: %w", err)
+	}
+	return &workload{name: fmt.Sprintf("generated-%d", n), data: data, file: "scene.json", sf: sf, seed: seed}, nil
+}
+
+// fileWorkload reads a scene file in any format starfleet.Open reads
+func fileWorkload(path string) (*workload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("bench: %w", err)
+	}
+	sf, err := starfleet.OpenReader(bytes.NewReader(data), path, starfleet.DefaultOpenOptions())
+	if err != nil {
+		return nil, fmt.Errorf("bench: %s: %w", path, err)
+	}
+	return &workload{name: path, data: data, file: filepath.Base(path), sf: sf}, nil
+}
+
+// measure times one operation on the workload
+func (w *workload) measure(ctx context.Context, op string, opts Options) (Result, error) {
+	result := Result{
+		Scene:     w.name,
+		Operation: op,
+		Nodes:     len(w.sf.Scene.Nodes),
+		Edges:     len(w.sf.Scene.Edges),
+		Bytes:     len(w.data),
+	}
+	prepare, err := w.operation(ctx, op, opts)
+	if err == nil {
+		var times []time.Duration
+		times, result.AllocsPerOp, result.BytesPerOp, err = timeIterations(ctx, opts.Iterations, prepare)
+		result.summarize(times)
+	}
+	if ctx.Err() != nil {
+		return Result{}, ctx.Err()
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// operation returns a function preparing one iteration of an operation.
+// Preparation is not timed; the function it returns is.
+func (w *workload) operation(ctx context.Context, op string, opts Options) (func() (func() error, error), error) {
+	ready := func(timed func() error) func() (func() error, error) {
+		return func() (func() error, error) { return timed, nil }
+	}
+	switch op {
+	case OpParse:
+		return ready(func() error {
+			_, err := starfleet.OpenReader(bytes.NewReader(w.data), w.file, starfleet.DefaultOpenOptions())
+			return err
+		}), nil
+
+	case OpValidate:
+		return ready(func() error {
+			_, err := starfleet.ValidateSceneContext(ctx, &w.sf)
+			return err
+		}), nil
+
+	case OpLayout:
+		// Layouts move nodes, so each iteration gets its own copy
+		return func() (func() error, error) {
+			var sf starfleet.SceneFile
+			if err := clone(&w.sf, &sf); err != nil {
+				return nil, err
+			}
+			return func() error {
+				_, err := starfleet.ApplyLayout(ctx, opts.Layout, &sf)
+				return err
+			}, nil
+		}, nil
+
+	case OpDiff:
+		if len(w.changed.Scene.Nodes) == 0 {
+			changed, err := perturb(&w.sf, w.seed)
+			if err != nil {
+				return nil, err
+			}
+			w.changed = changed
+		}
+		return ready(func() error {
+			starfleet.Diff(&w.sf, &w.changed)
+			return nil
+		}), nil
+
+	case OpServe:
+		store := starfleet.NewMemorySceneStore()
+		if _, err := store.Put(ctx, "bench", w.sf, 0); err != nil {
+			return nil, err
+		}
+		srv := server.New(store)
+		return ready(func() error {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/scenes/bench", nil).WithContext(ctx))
+			if rec.Code != http.StatusOK {
+				return fmt.Errorf("GET /scenes/bench: %d %s", rec.Code, bytes.TrimSpace(rec.Body.Bytes()))
+			}
+			return nil
+		}), nil
+	}
+	return nil, errors.New("unreachable")
+}
+
+// timeIterations runs a warm-up iteration and then n timed ones, returning
+// their durations and the mean heap allocations of the timed ones
+func timeIterations(ctx context.Context, n int, prepare func() (func() error, error)) ([]time.Duration, uint64, uint64, error) {
+	times := make([]time.Duration, 0, n)
+	var allocs, bytes uint64
+	var before, after runtime.MemStats
+	for i := 0; i <= n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, 0, err
+		}
+		timed, err := prepare()
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		err = timed()
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if i == 0 {
+			continue
+		}
+		times = append(times, elapsed)
+		allocs += after.Mallocs - before.Mallocs
+		bytes += after.TotalAlloc - before.TotalAlloc
+	}
+	return times, allocs / uint64(n), bytes / uint64(n), nil
+}
+
+// summarize fills in the spread of the timings
+func (r *Result) summarize(times []time.Duration) {
+	if len(times) == 0 {
+		return
+	}
+	sorted := slices.Clone(times)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	var total time.Duration
+	for _, t := range sorted {
+		total += t
+	}
+	r.Min, r.Max = sorted[0], sorted[len(sorted)-1]
+	r.Median = sorted[len(sorted)/2]
+	r.P95 = sorted[min(len(sorted)-1, len(sorted)*95/100)]
+	r.Mean = total / time.Duration(len(sorted))
+}
+
+// clone deep-copies a scene through JSON
+func clone(sf *starfleet.SceneFile, out *starfleet.SceneFile) error {
+	data, err := json.Marshal(sf)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,102 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// TestGenerate tests that generated scenes are valid and reproducible
+func TestGenerate(t *testing.T) {
+	sf := Generate(600, 7)
+	if r := starfleet.ValidateScene(&sf); !r.Valid {
+		t.Fatalf("generated scene is invalid: %v", r.Errors)
+	}
+	// Two regions of group nodes hold the 600 services
+	if got := len(sf.Scene.Nodes); got != 602 {
+		t.Errorf("node count mismatch: got %d, want 602", got)
+	}
+	if got := len(sf.Scene.Edges); got < 599 || got > 1200 {
+		t.Errorf("edge count mismatch: got %d", got)
+	}
+	if again := Generate(600, 7); !reflect.DeepEqual(again.Scene, sf.Scene) {
+		t.Error("same seed should generate the same scene")
+	}
+	if other := Generate(600, 8); reflect.DeepEqual(other.Scene.Edges, sf.Scene.Edges) {
+		t.Error("different seeds should generate different scenes")
+	}
+	if sf := Generate(1, 0); len(sf.Scene.Nodes) != 2 || len(sf.Scene.Edges) != 0 {
+		t.Errorf("single node scene mismatch: got %+v", sf.Scene)
+	}
+}
+
+// TestRun tests measuring every operation on generated and loaded scenes
+func TestRun(t *testing.T) {
+	sf := Generate(50, 1)
+	data, _ := json.Marshal(sf)
+	path := filepath.Join(t.TempDir(), "scene.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), Options{Scales: []int{20}, Files: []string{path}, Iterations: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Iterations != 2 || report.CPUs == 0 || report.GoVersion == "" {
+		t.Errorf("report mismatch: got %+v", report)
+	}
+	if len(report.Results) != 2*len(Operations) {
+		t.Fatalf("result count mismatch: got %d, want %d", len(report.Results), 2*len(Operations))
+	}
+	for i, r := range report.Results {
+		wantScene := "generated-20"
+		if i >= len(Operations) {
+			wantScene = path
+		}
+		if r.Scene != wantScene || r.Operation != Operations[i%len(Operations)] {
+			t.Errorf("result %d mismatch: got %s %s", i, r.Scene, r.Operation)
+		}
+		if r.Error != "" || r.Min <= 0 || r.Min > r.Median || r.Median > r.Max || r.Bytes == 0 {
+			t.Errorf("%s %s: measurement mismatch: got %+v", r.Scene, r.Operation, r)
+		}
+	}
+	if r := report.Results[len(Operations)]; r.Nodes != len(sf.Scene.Nodes) || r.Bytes != len(data) {
+		t.Errorf("file result mismatch: got %+v", r)
+	}
+
+	// Failures are recorded per operation
+	failing := starfleet.LayoutFunc(func(*starfleet.SceneFile) (starfleet.LayoutResult, error) {
+		return starfleet.LayoutResult{}, errors.New("no room")
+	})
+	report, err = Run(context.Background(), Options{Scales: []int{5}, Operations: []string{OpLayout}, Iterations: 1, Layout: failing})
+	if err != nil || len(report.Results) != 1 || report.Results[0].Error != "no room" || report.Results[0].Median != 0 {
+		t.Errorf("failure mismatch: got %+v, %v", report.Results, err)
+	}
+}
+
+// TestRun_Errors tests that bad options and cancellation fail the run
+func TestRun_Errors(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range []Options{
+		{Operations: []string{"render"}},
+		{Scales: []int{0}},
+		{Scales: []int{}, Files: []string{filepath.Join(t.TempDir(), "missing.json")}},
+	} {
+		if _, err := Run(ctx, opts); err == nil || !strings.HasPrefix(err.Error(), "bench: ") {
+			t.Errorf("expected error for %+v, got %v", opts, err)
+		}
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Run(cancelled, Options{Scales: []int{5}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
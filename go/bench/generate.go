@@ -0,0 +1,129 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// serviceTypes are the node types generated scenes draw from, weighted
+// towards services the way real service graphs are
+var serviceTypes = []string{"service", "service", "service", "service", "database", "cache", "queue"}
+
+// nodesPerRegion is roughly how many services a generated region holds
+const nodesPerRegion = 250
+
+// Generate builds a service graph of n nodes spread over cloud regions,
+// with metadata, tags, metrics and statuses like those importers produce.
+// Each region is a group node holding its services, and every service calls
+// one or two others. The same n and seed always give the same nodes and
+// edges.
+func Generate(n int, seed int64) starfleet.SceneFile {
+	rng := rand.New(rand.NewSource(seed))
+	sf := starfleet.NewSceneFile(fmt.Sprintf("Generated %d", n))
+
+	regions := make([]string, 0, len(starfleet.DefaultRegionLocations))
+	for name := range starfleet.DefaultRegionLocations {
+		regions = append(regions, name)
+	}
+	sort.Strings(regions)
+	regions = regions[:min(max(1, n/nodesPerRegion), len(regions))]
+	for _, region := range regions {
+		sf.AddNode(starfleet.SceneNode{
+			ID:        region,
+			Type:      "region",
+			Name:      region,
+			Transform: starfleet.NewTransform(),
+			Metadata:  map[string]interface{}{"region": region},
+		})
+	}
+
+	members := make([][]string, len(regions))
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("svc-%d", i)
+		r := rng.Intn(len(regions))
+		typ := serviceTypes[rng.Intn(len(serviceTypes))]
+		status := starfleet.NodeStatusHealthy
+		switch p := rng.Float64(); {
+		case p < 0.02:
+			status = starfleet.NodeStatusCritical
+		case p < 0.08:
+			status = starfleet.NodeStatusWarning
+		}
+		env := "prod"
+		if rng.Intn(5) == 0 {
+			env = "staging"
+		}
+		sf.AddNode(starfleet.SceneNode{
+			ID:        id,
+			Type:      typ,
+			Name:      fmt.Sprintf("%s %d", typ, i),
+			Parent:    regions[r],
+			Transform: starfleet.NewTransformWithPosition(rng.Float64()*100, 0, rng.Float64()*100),
+			Tags:      []string{env, "team-" + string(rune('a'+rng.Intn(8)))},
+			Status:    status,
+			Metadata: map[string]interface{}{
+				"region":  regions[r],
+				"owner":   fmt.Sprintf("team-%d@example.com", rng.Intn(20)),
+				"version": fmt.Sprintf("1.%d.%d", rng.Intn(30), rng.Intn(10)),
+			},
+			Metrics: map[string]interface{}{
+				"cpu":       rng.Float64(),
+				"rps":       float64(rng.Intn(5000)),
+				"latencyMs": 1 + rng.ExpFloat64()*20,
+			},
+		})
+		members[r] = append(members[r], id)
+	}
+	for i, region := range regions {
+		sf.FindNode(region).Children = members[i]
+	}
+
+	// Every service after the first calls an earlier one, which keeps the
+	// graph connected, and half call a second
+	seen := make(map[[2]int]bool)
+	call := func(from, to int) {
+		if from == to || seen[[2]int{from, to}] {
+			return
+		}
+		seen[[2]int{from, to}] = true
+		sf.AddEdge(starfleet.SceneEdge{
+			ID:        fmt.Sprintf("call-%d", len(sf.Scene.Edges)),
+			Source:    fmt.Sprintf("svc-%d", from),
+			Target:    fmt.Sprintf("svc-%d", to),
+			Type:      "calls",
+			Direction: starfleet.EdgeDirected,
+			Metrics: map[string]interface{}{
+				"rps":       float64(rng.Intn(1000)),
+				"errorRate": rng.Float64() * 0.05,
+			},
+		})
+	}
+	for i := 1; i < n; i++ {
+		call(i, rng.Intn(i))
+		if rng.Intn(2) == 0 {
+			call(i, rng.Intn(n))
+		}
+	}
+	return sf
+}
+
+// perturb returns a copy of the scene with about one node in a hundred
+// moved and restatused and one node added, as a typical write would
+// change it
+func perturb(sf *starfleet.SceneFile, seed int64) (starfleet.SceneFile, error) {
+	var out starfleet.SceneFile
+	if err := clone(sf, &out); err != nil {
+		return out, err
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for i := 0; i < max(1, len(out.Scene.Nodes)/100) && len(out.Scene.Nodes) > 0; i++ {
+		node := &out.Scene.Nodes[rng.Intn(len(out.Scene.Nodes))]
+		node.Transform.Position.X += 1
+		node.Status = starfleet.NodeStatusWarning
+	}
+	out.AddNode(starfleet.SceneNode{ID: "bench-added", Type: "service", Name: "Added", Transform: starfleet.NewTransform()})
+	return out, nil
+}
@@ -0,0 +1,466 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// CONTENT-ADDRESSED BLOBS
+// =============================================================================
+
+// Sentinel errors returned by blob stores
+var (
+	ErrInvalidDigest  = errors.New("invalid digest")
+	ErrDigestMismatch = errors.New("digest mismatch")
+)
+
+// Digest identifies content by its SHA-256 hash, written "sha256:<hex>".
+// Scenes reference blobs by using the digest as the asset URI.
+type Digest string
+
+// DigestOf returns the digest of data
+func DigestOf(data []byte) Digest {
+	sum := sha256.Sum256(data)
+	return Digest("sha256:" + hex.EncodeToString(sum[:]))
+}
+
+// ParseDigest checks that s is a well-formed SHA-256 digest
+func ParseDigest(s string) (Digest, error) {
+	hexPart, ok := strings.CutPrefix(s, "sha256:")
+	if !ok || len(hexPart) != sha256.Size*2 || strings.ToLower(hexPart) != hexPart {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDigest, s)
+	}
+	if _, err := hex.DecodeString(hexPart); err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDigest, s)
+	}
+	return Digest(s), nil
+}
+
+// Hex returns the hash part of the digest
+func (d Digest) Hex() string {
+	return strings.TrimPrefix(string(d), "sha256:")
+}
+
+// Verify checks that data hashes to the digest
+func (d Digest) Verify(data []byte) error {
+	if got := DigestOf(data); got != d {
+		return fmt.Errorf("%w: want %s, got %s", ErrDigestMismatch, d, got)
+	}
+	return nil
+}
+
+// BlobStore stores immutable content under its digest. Storing the same
+// content twice stores it once. Get verifies content against its digest, so
+// corrupted blobs fail with ErrDigestMismatch instead of being returned.
+type BlobStore interface {
+	// Put stores data and returns its digest
+	Put(ctx context.Context, data []byte) (Digest, error)
+	// Get returns the content of a digest, or ErrAssetNotFound
+	Get(ctx context.Context, d Digest) ([]byte, error)
+	// Has reports whether a digest is stored
+	Has(ctx context.Context, d Digest) (bool, error)
+}
+
+// MemoryBlobStore is an in-memory BlobStore
+type MemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[Digest][]byte
+}
+
+// NewMemoryBlobStore creates an empty in-memory blob store
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: make(map[Digest][]byte)}
+}
+
+// Put implements BlobStore
+func (s *MemoryBlobStore) Put(ctx context.Context, data []byte) (Digest, error) {
+	d := DigestOf(data)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blobs[d]; !ok {
+		s.blobs[d] = append([]byte(nil), data...)
+	}
+	return d, nil
+}
+
+// Get implements BlobStore
+func (s *MemoryBlobStore) Get(ctx context.Context, d Digest) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.blobs[d]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, d)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Has implements BlobStore
+func (s *MemoryBlobStore) Has(ctx context.Context, d Digest) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.blobs[d]
+	return ok, nil
+}
+
+// Len returns the number of distinct blobs stored
+func (s *MemoryBlobStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.blobs)
+}
+
+// DirBlobStore stores blobs as files below Root, fanned out by the first
+// two hex digits as in sha256/ab/abcdef...
+type DirBlobStore struct {
+	Root string
+}
+
+// path returns the file path of a digest
+func (s DirBlobStore) path(d Digest) (string, error) {
+	if _, err := ParseDigest(string(d)); err != nil {
+		return "", err
+	}
+	h := d.Hex()
+	return filepath.Join(s.Root, "sha256", h[:2], h), nil
+}
+
+// Put implements BlobStore. Files are written to a temporary name and
+// renamed so readers never see partial content.
+func (s DirBlobStore) Put(ctx context.Context, data []byte) (Digest, error) {
+	d := DigestOf(data)
+	p, _ := s.path(d)
+	if _, err := os.Stat(p); err == nil {
+		return d, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".blob-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return d, nil
+}
+
+// Get implements BlobStore
+func (s DirBlobStore) Get(ctx context.Context, d Digest) ([]byte, error) {
+	p, err := s.path(d)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, d)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Verify(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Has implements BlobStore
+func (s DirBlobStore) Has(ctx context.Context, d Digest) (bool, error) {
+	p, err := s.path(d)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// S3BlobStore stores blobs in an S3-compatible bucket under
+// Prefix + "sha256/<hex>", addressing the bucket path-style on Endpoint,
+// such as "https://s3.us-east-1.amazonaws.com" or a MinIO server. Requests
+// are signed with AWS Signature Version 4.
+type S3BlobStore struct {
+	Endpoint        string
+	Bucket          string
+	Prefix          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Client          *http.Client
+
+	now func() time.Time
+}
+
+// emptySHA256 is the hex SHA-256 of an empty payload
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// NewS3BlobStore creates an S3 blob store using http.DefaultClient
+func NewS3BlobStore(endpoint, bucket, region, accessKeyID, secretAccessKey string) *S3BlobStore {
+	return &S3BlobStore{
+		Endpoint:        strings.TrimSuffix(endpoint, "/"),
+		Bucket:          bucket,
+		Region:          region,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Client:          http.DefaultClient,
+		now:             time.Now,
+	}
+}
+
+// Put implements BlobStore. Content already in the bucket is not uploaded
+// again; uploads carry their hash so S3 rejects corrupted transfers.
+func (s *S3BlobStore) Put(ctx context.Context, data []byte) (Digest, error) {
+	d := DigestOf(data)
+	if ok, err := s.Has(ctx, d); err != nil || ok {
+		return d, err
+	}
+	resp, err := s.do(ctx, http.MethodPut, d, data)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", s.statusError("put", d, resp)
+	}
+	return d, nil
+}
+
+// Get implements BlobStore
+func (s *S3BlobStore) Get(ctx context.Context, d Digest) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, d)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, s.statusError("get", d, resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 get %s: %w", d, err)
+	}
+	if err := d.Verify(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Has implements BlobStore
+func (s *S3BlobStore) Has(ctx context.Context, d Digest) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, d, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode/100 == 2:
+		return true, nil
+	}
+	return false, s.statusError("head", d, resp)
+}
+
+// do sends a signed request for a blob's object
+func (s *S3BlobStore) do(ctx context.Context, method string, d Digest, body []byte) (*http.Response, error) {
+	if _, err := ParseDigest(string(d)); err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/%s/%ssha256/%s", s.Endpoint, s.Bucket, s.Prefix, d.Hex())
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payload := emptySHA256
+	if body != nil {
+		payload = d.Hex()
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	s.sign(req, payload)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s: %w", strings.ToLower(method), d, err)
+	}
+	return resp, nil
+}
+
+// statusError describes an unexpected S3 response
+func (s *S3BlobStore) statusError(op string, d Digest, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s %s: status %d: %s", op, d, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
+
+// sign adds AWS Signature Version 4 headers covering the host and every
+// header already set on the request
+func (s *S3BlobStore) sign(req *http.Request, payloadHash string) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	amzDate, day := t.Format("20060102T150405Z"), t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, k := range names {
+		canonical.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20")
+	request := strings.Join([]string{req.Method, path, query, canonical.String(), signed, payloadHash}, "\n")
+	requestHash := sha256.Sum256([]byte(request))
+
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range []string{day, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signed, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// BlobAssetStorage adapts a BlobStore to AssetStorage so tools such as
+// AssetManager write content-addressed assets. URIs are digests and names
+// are ignored, so identical content written under different names is
+// stored once.
+type BlobAssetStorage struct {
+	Blobs BlobStore
+}
+
+// ReadAsset implements AssetStorage
+func (s BlobAssetStorage) ReadAsset(ctx context.Context, uri string) ([]byte, error) {
+	d, err := ParseDigest(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, uri)
+	}
+	return s.Blobs.Get(ctx, d)
+}
+
+// WriteAsset implements AssetStorage
+func (s BlobAssetStorage) WriteAsset(ctx context.Context, name string, data []byte) (string, error) {
+	d, err := s.Blobs.Put(ctx, data)
+	return string(d), err
+}
+
+// ImportAssets copies every asset of a scene from source into blobs and
+// rewrites SceneFile.Assets to reference digests. Assets that already
+// reference a digest are checked to exist. It returns the number of assets
+// imported.
+func ImportAssets(ctx context.Context, sf *SceneFile, source AssetStorage, blobs BlobStore) (int, error) {
+	names := make([]string, 0, len(sf.Assets))
+	for name := range sf.Assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	imported := 0
+	for _, name := range names {
+		uri := sf.Assets[name]
+		if d, err := ParseDigest(uri); err == nil {
+			ok, err := blobs.Has(ctx, d)
+			if err != nil {
+				return imported, fmt.Errorf("import asset %s: %w", name, err)
+			}
+			if !ok {
+				return imported, fmt.Errorf("import asset %s: %w: %s", name, ErrAssetNotFound, d)
+			}
+			continue
+		}
+		data, err := source.ReadAsset(ctx, uri)
+		if err != nil {
+			return imported, fmt.Errorf("import asset %s: %w", name, err)
+		}
+		d, err := blobs.Put(ctx, data)
+		if err != nil {
+			return imported, fmt.Errorf("import asset %s: %w", name, err)
+		}
+		sf.Assets[name] = string(d)
+		imported++
+	}
+	return imported, nil
+}
+
+// VerifyAssets loads every digest-referenced asset of a scene and returns a
+// message for each one that is missing or corrupt. A non-nil error is only
+// returned when the context is cancelled.
+func VerifyAssets(ctx context.Context, sf *SceneFile, blobs BlobStore) ([]string, error) {
+	names := make([]string, 0, len(sf.Assets))
+	for name := range sf.Assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []string
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return errs, err
+		}
+		d, err := ParseDigest(sf.Assets[name])
+		if err != nil {
+			continue
+		}
+		if _, err := blobs.Get(ctx, d); err != nil {
+			errs = append(errs, fmt.Sprintf("Asset %s failed verification: %v", name, err))
+		}
+	}
+	return errs, nil
+}
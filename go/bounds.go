@@ -0,0 +1,266 @@
+package starfleet
+
+import (
+	"context"
+	"math"
+)
+
+// =============================================================================
+// BOUNDING VOLUMES
+// =============================================================================
+
+// BoundingSphere represents a sphere enclosing a node or set of nodes
+type BoundingSphere struct {
+	Center Vector3 `json:"center"`
+	Radius float64 `json:"radius" validate:"min=0"`
+}
+
+// OBB represents an oriented bounding box. Axes are orthonormal and
+// HalfExtents holds the half size along each of them.
+type OBB struct {
+	Center      Vector3    `json:"center"`
+	HalfExtents Vector3    `json:"halfExtents"`
+	Axes        [3]Vector3 `json:"axes"`
+}
+
+// localBounds returns the center and half size of a geometry in local
+// space. Custom meshes use their actual vertex bounds, which need not be
+// centered on the origin.
+func localBounds(g *Geometry) (Vector3, Vector3) {
+	if g != nil && g.Mesh != nil && g.Mesh.VertexCount() > 0 &&
+		g.Type != GeometryBox && g.Type != GeometrySphere && g.Type != GeometryCylinder && g.Type != GeometryPlane {
+		lo, hi := g.Mesh.bounds()
+		return lo.Lerp(hi, 0.5), hi.Sub(lo).Scale(0.5)
+	}
+	return Vector3{}, geometryHalfExtents(g)
+}
+
+// NodeOBB returns the oriented bounding box of a node's geometry after its
+// transform
+func (sf *SceneFile) NodeOBB(node *SceneNode) OBB {
+	center, half := localBounds(sf.ResolveGeometry(node))
+	s := node.Transform.Scale
+	m := rotationMatrix(node.Transform.Rotation)
+	column := func(c int) Vector3 { return Vector3{X: m[0][c], Y: m[1][c], Z: m[2][c]} }
+	return OBB{
+		Center:      transformPoint(node.Transform, center),
+		HalfExtents: Vector3{X: half.X * math.Abs(s.X), Y: half.Y * math.Abs(s.Y), Z: half.Z * math.Abs(s.Z)},
+		Axes:        [3]Vector3{column(0), column(1), column(2)},
+	}
+}
+
+// NodeBoundingSphere returns a sphere enclosing a node's geometry after its
+// transform. Spheres with uniform scale are exact; other shapes use the
+// sphere around their oriented box.
+func (sf *SceneFile) NodeBoundingSphere(node *SceneNode) BoundingSphere {
+	g := sf.ResolveGeometry(node)
+	s := node.Transform.Scale
+	if g != nil && g.Type == GeometrySphere && s.X == s.Y && s.Y == s.Z {
+		return BoundingSphere{Center: node.Transform.Position, Radius: g.parameter("radius", 0.5) * math.Abs(s.X)}
+	}
+	return sf.NodeOBB(node).BoundingSphere()
+}
+
+// SceneBoundingSphere returns a sphere enclosing every node, or a zero
+// sphere for empty scenes
+func (sf *SceneFile) SceneBoundingSphere() BoundingSphere {
+	spheres := make([]BoundingSphere, len(sf.Scene.Nodes))
+	_ = parallelFor(context.Background(), len(spheres), parallelBlock, func(i int) {
+		spheres[i] = sf.NodeBoundingSphere(&sf.Scene.Nodes[i])
+	})
+	return UnionSpheres(spheres...)
+}
+
+// Contains reports whether a point lies inside the sphere
+func (s BoundingSphere) Contains(p Vector3) bool {
+	return p.Sub(s.Center).Length() <= s.Radius+1e-9
+}
+
+// Intersects reports whether two spheres overlap
+func (s BoundingSphere) Intersects(o BoundingSphere) bool {
+	return s.Center.Sub(o.Center).Length() <= s.Radius+o.Radius
+}
+
+// Distance returns the distance from a point to the sphere surface, zero
+// inside it, as used for LOD selection
+func (s BoundingSphere) Distance(p Vector3) float64 {
+	return math.Max(0, p.Sub(s.Center).Length()-s.Radius)
+}
+
+// Union returns the smallest sphere enclosing both spheres
+func (s BoundingSphere) Union(o BoundingSphere) BoundingSphere {
+	d := o.Center.Sub(s.Center)
+	dist := d.Length()
+	switch {
+	case dist+o.Radius <= s.Radius:
+		return s
+	case dist+s.Radius <= o.Radius:
+		return o
+	}
+	radius := (dist + s.Radius + o.Radius) / 2
+	return BoundingSphere{Center: s.Center.Add(d.Scale((radius - s.Radius) / dist)), Radius: radius}
+}
+
+// UnionSpheres returns a sphere enclosing all spheres. It merges them
+// pairwise in order, which is not minimal but stays within a small factor.
+// Spheres with a non-finite center or radius are skipped, so one node with
+// a broken position does not leave the whole scene unframeable.
+func UnionSpheres(spheres ...BoundingSphere) BoundingSphere {
+	var out BoundingSphere
+	first := true
+	for _, s := range spheres {
+		if !s.Center.IsFinite() || !isFinite(s.Radius) {
+			continue
+		}
+		if first {
+			out, first = s, false
+			continue
+		}
+		out = out.Union(s)
+	}
+	return out
+}
+
+// IntersectRay returns the distance along a unit direction to the first
+// intersection with the sphere, or false when the ray misses
+func (s BoundingSphere) IntersectRay(origin, dir Vector3) (float64, bool) {
+	oc := origin.Sub(s.Center)
+	b := oc.Dot(dir)
+	c := oc.Dot(oc) - s.Radius*s.Radius
+	disc := b*b - c
+	if disc < 0 {
+		return 0, false
+	}
+	t := -b - math.Sqrt(disc)
+	if t < 0 {
+		t = -b + math.Sqrt(disc)
+	}
+	return t, t >= 0
+}
+
+// Corners returns the eight corners of the box
+func (b OBB) Corners() [8]Vector3 {
+	var corners [8]Vector3
+	for i := range corners {
+		p := b.Center
+		for axis, h := range [3]float64{b.HalfExtents.X, b.HalfExtents.Y, b.HalfExtents.Z} {
+			if i&(1<<axis) != 0 {
+				p = p.Add(b.Axes[axis].Scale(h))
+			} else {
+				p = p.Sub(b.Axes[axis].Scale(h))
+			}
+		}
+		corners[i] = p
+	}
+	return corners
+}
+
+// BoundingSphere returns the sphere through the corners of the box
+func (b OBB) BoundingSphere() BoundingSphere {
+	return BoundingSphere{Center: b.Center, Radius: b.HalfExtents.Length()}
+}
+
+// AABB returns the axis-aligned bounds enclosing the box
+func (b OBB) AABB() Bounds {
+	h := Vector3{}
+	for axis, e := range [3]float64{b.HalfExtents.X, b.HalfExtents.Y, b.HalfExtents.Z} {
+		a := b.Axes[axis]
+		h = h.Add(Vector3{X: math.Abs(a.X) * e, Y: math.Abs(a.Y) * e, Z: math.Abs(a.Z) * e})
+	}
+	return Bounds{Min: b.Center.Sub(h), Max: b.Center.Add(h)}
+}
+
+// local returns the coordinates of a point in the box frame
+func (b OBB) local(p Vector3) Vector3 {
+	d := p.Sub(b.Center)
+	return Vector3{X: d.Dot(b.Axes[0]), Y: d.Dot(b.Axes[1]), Z: d.Dot(b.Axes[2])}
+}
+
+// Contains reports whether a point lies inside the box
+func (b OBB) Contains(p Vector3) bool {
+	l := b.local(p)
+	const eps = 1e-9
+	return math.Abs(l.X) <= b.HalfExtents.X+eps && math.Abs(l.Y) <= b.HalfExtents.Y+eps &&
+		math.Abs(l.Z) <= b.HalfExtents.Z+eps
+}
+
+// Union returns a box enclosing both boxes, oriented like the receiver.
+// Orientations are not blended, so the union of two differently rotated
+// boxes is looser than the optimal box.
+func (b OBB) Union(o OBB) OBB {
+	inf := math.Inf(1)
+	lo, hi := Vector3{X: inf, Y: inf, Z: inf}, Vector3{X: -inf, Y: -inf, Z: -inf}
+	for _, box := range []OBB{b, o} {
+		for _, c := range box.Corners() {
+			l := b.local(c)
+			lo = Vector3{X: math.Min(lo.X, l.X), Y: math.Min(lo.Y, l.Y), Z: math.Min(lo.Z, l.Z)}
+			hi = Vector3{X: math.Max(hi.X, l.X), Y: math.Max(hi.Y, l.Y), Z: math.Max(hi.Z, l.Z)}
+		}
+	}
+	mid := lo.Lerp(hi, 0.5)
+	return OBB{
+		Center:      b.Center.Add(b.Axes[0].Scale(mid.X)).Add(b.Axes[1].Scale(mid.Y)).Add(b.Axes[2].Scale(mid.Z)),
+		HalfExtents: hi.Sub(lo).Scale(0.5),
+		Axes:        b.Axes,
+	}
+}
+
+// IntersectRay returns the distance along a unit direction to the first
+// intersection with the box, or false when the ray misses. Rays starting
+// inside the box report zero.
+func (b OBB) IntersectRay(origin, dir Vector3) (float64, bool) {
+	o, d := b.local(origin), Vector3{X: dir.Dot(b.Axes[0]), Y: dir.Dot(b.Axes[1]), Z: dir.Dot(b.Axes[2])}
+	tMin, tMax := 0.0, math.Inf(1)
+	slab := func(o, d, h float64) bool {
+		if math.Abs(d) < 1e-12 {
+			return math.Abs(o) <= h
+		}
+		t1, t2 := (-h-o)/d, (h-o)/d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tMin, tMax = math.Max(tMin, t1), math.Min(tMax, t2)
+		return tMin <= tMax
+	}
+	if !slab(o.X, d.X, b.HalfExtents.X) || !slab(o.Y, d.Y, b.HalfExtents.Y) || !slab(o.Z, d.Z, b.HalfExtents.Z) {
+		return 0, false
+	}
+	return tMin, true
+}
+
+// Union returns the axis-aligned bounds enclosing both bounds
+func (b Bounds) Union(o Bounds) Bounds {
+	return Bounds{
+		Min: Vector3{X: math.Min(b.Min.X, o.Min.X), Y: math.Min(b.Min.Y, o.Min.Y), Z: math.Min(b.Min.Z, o.Min.Z)},
+		Max: Vector3{X: math.Max(b.Max.X, o.Max.X), Y: math.Max(b.Max.Y, o.Max.Y), Z: math.Max(b.Max.Z, o.Max.Z)},
+	}
+}
+
+// Center returns the midpoint of the bounds
+func (b Bounds) Center() Vector3 {
+	return b.Min.Lerp(b.Max, 0.5)
+}
+
+// Contains reports whether a point lies inside the bounds
+func (b Bounds) Contains(p Vector3) bool {
+	return p.X >= b.Min.X && p.X <= b.Max.X && p.Y >= b.Min.Y && p.Y <= b.Max.Y && p.Z >= b.Min.Z && p.Z <= b.Max.Z
+}
+
+// PickNode returns the node whose oriented bounds a ray hits first, or nil.
+// The direction need not be normalized.
+func (sf *SceneFile) PickNode(origin, dir Vector3) *SceneNode {
+	dir = dir.Normalize()
+	var best *SceneNode
+	bestT := math.Inf(1)
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		// The sphere test rejects most misses before the box test
+		if _, hit := sf.NodeBoundingSphere(node).IntersectRay(origin, dir); !hit {
+			continue
+		}
+		if t, hit := sf.NodeOBB(node).IntersectRay(origin, dir); hit && t < bestT {
+			best, bestT = node, t
+		}
+	}
+	return best
+}
@@ -0,0 +1,95 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// TestNodeOBB tests oriented bounds of a rotated, scaled box
+func TestNodeOBB(t *testing.T) {
+	sf := NewSceneFile("Bounds")
+	sf.Scene.Nodes = []SceneNode{{
+		ID:       "beam",
+		Geometry: &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{"width": 4.0, "height": 1.0, "depth": 1.0}},
+		Transform: Transform{
+			Position: Vector3{X: 1, Y: 2, Z: 3},
+			Rotation: Euler3{Y: math.Pi / 4},
+			Scale:    Scale3{X: 2, Y: 1, Z: 1},
+		},
+	}}
+	obb := sf.NodeOBB(&sf.Scene.Nodes[0])
+	if obb.HalfExtents != (Vector3{X: 4, Y: 0.5, Z: 0.5}) {
+		t.Errorf("half extents mismatch: got %v", obb.HalfExtents)
+	}
+	tip := Vector3{X: 1, Y: 2, Z: 3}.Add(Vector3{X: math.Sqrt2 / 2, Z: -math.Sqrt2 / 2}.Scale(3.9))
+	if !obb.Contains(tip) {
+		t.Errorf("expected OBB to contain %v", tip)
+	}
+	if obb.Contains(Vector3{X: 1 + 3.9, Y: 2, Z: 3}) {
+		t.Error("OBB should not contain a point along the unrotated axis")
+	}
+
+	aabb := obb.AABB()
+	for _, c := range obb.Corners() {
+		if !aabb.Contains(c) {
+			t.Errorf("AABB should contain corner %v", c)
+		}
+	}
+	if r := sf.NodeBoundingSphere(&sf.Scene.Nodes[0]).Radius; math.Abs(r-obb.HalfExtents.Length()) > 1e-9 {
+		t.Errorf("sphere radius mismatch: got %v, want %v", r, obb.HalfExtents.Length())
+	}
+}
+
+// TestBoundingSphere_Union tests sphere merging and containment
+func TestBoundingSphere_Union(t *testing.T) {
+	a := BoundingSphere{Center: Vector3{X: -2}, Radius: 1}
+	b := BoundingSphere{Center: Vector3{X: 3}, Radius: 2}
+	u := a.Union(b)
+	if math.Abs(u.Radius-4) > 1e-9 || math.Abs(u.Center.X-1) > 1e-9 {
+		t.Errorf("union mismatch: got %+v, want center x=1 radius 4", u)
+	}
+	inner := BoundingSphere{Center: Vector3{X: 3.5}, Radius: 0.5}
+	if got := b.Union(inner); got != b {
+		t.Errorf("enclosed union mismatch: got %+v, want %+v", got, b)
+	}
+	if d := u.Distance(Vector3{X: 10}); math.Abs(d-5) > 1e-9 {
+		t.Errorf("distance mismatch: got %v, want 5", d)
+	}
+}
+
+// TestOBB_Union tests that the union encloses both boxes
+func TestOBB_Union(t *testing.T) {
+	axes := [3]Vector3{{X: 1}, {Y: 1}, {Z: 1}}
+	a := OBB{Center: Vector3{}, HalfExtents: Vector3{X: 1, Y: 1, Z: 1}, Axes: axes}
+	b := OBB{Center: Vector3{X: 5}, HalfExtents: Vector3{X: 1, Y: 2, Z: 1}, Axes: axes}
+	u := a.Union(b)
+	for _, box := range []OBB{a, b} {
+		for _, c := range box.Corners() {
+			if !u.Contains(c) {
+				t.Errorf("union should contain corner %v", c)
+			}
+		}
+	}
+	if u.HalfExtents != (Vector3{X: 3.5, Y: 2, Z: 1}) {
+		t.Errorf("union extents mismatch: got %v", u.HalfExtents)
+	}
+}
+
+// TestPickNode tests ray picking against oriented bounds
+func TestPickNode(t *testing.T) {
+	sf := NewSceneFile("Pick")
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "near", Transform: NewTransformWithPosition(0, 0, 2)},
+		{ID: "far", Transform: NewTransformWithPosition(0, 0, -2)},
+		{ID: "aside", Transform: NewTransformWithPosition(5, 0, 0)},
+	}
+	if n := sf.PickNode(Vector3{Z: 10}, Vector3{Z: -1}); n == nil || n.ID != "near" {
+		t.Errorf("pick mismatch: got %v, want near", n)
+	}
+	if n := sf.PickNode(Vector3{X: 5, Z: 10}, Vector3{Z: -3}); n == nil || n.ID != "aside" {
+		t.Errorf("pick mismatch: got %v, want aside", n)
+	}
+	if n := sf.PickNode(Vector3{X: 20, Z: 10}, Vector3{Z: -1}); n != nil {
+		t.Errorf("expected miss, got %s", n.ID)
+	}
+}
@@ -0,0 +1,146 @@
+package starfleet
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// =============================================================================
+// BULK EDITING
+// =============================================================================
+
+// MutationOp is the kind of change a Mutation makes
+type MutationOp string
+
+const (
+	// MutationAddTag adds the tag Key to nodes that lack it
+	MutationAddTag MutationOp = "addTag"
+	// MutationRemoveTag removes the tag Key from nodes
+	MutationRemoveTag MutationOp = "removeTag"
+	// MutationSetMetadata sets the metadata key Key to Value
+	MutationSetMetadata MutationOp = "setMetadata"
+	// MutationDeleteMetadata removes the metadata key Key
+	MutationDeleteMetadata MutationOp = "deleteMetadata"
+	// MutationSetMaterial sets the node material property at path Key, such
+	// as "opacity" or "color.r", to Value
+	MutationSetMaterial MutationOp = "setMaterial"
+)
+
+// Mutation is one typed change UpdateWhere applies to every matching
+// element. Tags and materials only exist on nodes, so those mutations skip
+// edges.
+type Mutation struct {
+	Op    MutationOp  `json:"op" validate:"required,oneof=addTag removeTag setMetadata deleteMetadata setMaterial"`
+	Key   string      `json:"key" validate:"required"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// UpdateReport describes what UpdateWhere did. Matched counts the nodes and
+// edges the selector matched, whether or not the mutations changed them.
+type UpdateReport struct {
+	Matched int       `json:"matched"`
+	Changes SceneDiff `json:"changes"`
+}
+
+// UpdateWhere applies mutations, in order, to every node and edge matching
+// the selector and reports the changes. Materials shared through the
+// library are copied inline before they are changed, so other nodes keep
+// theirs. The update is atomic: when a mutation is malformed or a material
+// value does not fit its property, an error is returned and sf is left
+// unchanged.
+func UpdateWhere(sf *SceneFile, sel Selector, mutations ...Mutation) (UpdateReport, error) {
+	for i, m := range mutations {
+		switch m.Op {
+		case MutationAddTag, MutationRemoveTag, MutationSetMetadata, MutationDeleteMetadata, MutationSetMaterial:
+		default:
+			return UpdateReport{}, fmt.Errorf("update: mutation %d has unknown op %q", i, m.Op)
+		}
+		if m.Key == "" {
+			return UpdateReport{}, fmt.Errorf("update: mutation %d (%s) has no key", i, m.Op)
+		}
+	}
+
+	var report UpdateReport
+	nodes := slices.Clone(sf.Scene.Nodes)
+	for i := range nodes {
+		n := &nodes[i]
+		if !sel.MatchNode(n) {
+			continue
+		}
+		report.Matched++
+		n.Tags = slices.Clone(n.Tags)
+		n.Metadata = maps.Clone(n.Metadata)
+		for _, m := range mutations {
+			switch m.Op {
+			case MutationAddTag:
+				if !slices.Contains(n.Tags, m.Key) {
+					n.Tags = append(n.Tags, m.Key)
+				}
+			case MutationRemoveTag:
+				n.Tags = slices.DeleteFunc(n.Tags, func(t string) bool { return t == m.Key })
+			case MutationSetMaterial:
+				if err := setMaterialProperty(sf, n, m.Key, m.Value); err != nil {
+					return UpdateReport{}, fmt.Errorf("update node %s: %w", n.ID, err)
+				}
+			default:
+				n.Metadata = mutateMetadata(n.Metadata, m)
+			}
+		}
+	}
+	edges := slices.Clone(sf.Scene.Edges)
+	for i := range edges {
+		e := &edges[i]
+		if !sel.MatchEdge(e) {
+			continue
+		}
+		report.Matched++
+		e.Metadata = maps.Clone(e.Metadata)
+		for _, m := range mutations {
+			if m.Op == MutationSetMetadata || m.Op == MutationDeleteMetadata {
+				e.Metadata = mutateMetadata(e.Metadata, m)
+			}
+		}
+	}
+
+	before := *sf
+	sf.Scene.Nodes, sf.Scene.Edges = nodes, edges
+	report.Changes = Diff(&before, sf)
+	return report, nil
+}
+
+// mutateMetadata applies a metadata mutation to a map the caller owns
+func mutateMetadata(metadata map[string]interface{}, m Mutation) map[string]interface{} {
+	if m.Op == MutationDeleteMetadata {
+		delete(metadata, m.Key)
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata[m.Key] = m.Value
+	return metadata
+}
+
+// setMaterialProperty sets a property of a node's material, inlining a
+// library material first. Nodes already matching the value are left as
+// they are.
+func setMaterialProperty(sf *SceneFile, node *SceneNode, path string, value interface{}) error {
+	var material Material
+	resolved := sf.ResolveMaterial(node)
+	if resolved != nil {
+		// Round trip so nested colors are not shared with the original
+		if err := roundTripJSON(resolved, &material); err != nil {
+			return err
+		}
+	}
+	if err := SetProperty(&material, path, value); err != nil {
+		return err
+	}
+	if resolved != nil && reflect.DeepEqual(&material, resolved) {
+		return nil
+	}
+	node.Material, node.MaterialRef = &material, ""
+	return nil
+}
@@ -0,0 +1,75 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestUpdateWhere tests applying mutations to matching elements
+func TestUpdateWhere(t *testing.T) {
+	sf := newDiffScene()
+	sf.Materials = map[string]Material{"steel": {Color: &Color{R: 0.5, G: 0.5, B: 0.5}}}
+	for i := range sf.Scene.Nodes {
+		sf.Scene.Nodes[i].MaterialRef = "steel"
+	}
+	sf.Scene.Nodes[2].Type = "db"
+	sf.Scene.Nodes[0].Tags = []string{"legacy"}
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{"owner": "ops"}
+	original := sf.Scene.Nodes
+
+	sel, _ := ParseSelector("type=server")
+	report, err := UpdateWhere(&sf, sel,
+		Mutation{Op: MutationAddTag, Key: "prod"},
+		Mutation{Op: MutationRemoveTag, Key: "legacy"},
+		Mutation{Op: MutationSetMetadata, Key: "env", Value: "production"},
+		Mutation{Op: MutationDeleteMetadata, Key: "owner"},
+		Mutation{Op: MutationSetMaterial, Key: "opacity", Value: 0.5},
+	)
+	if err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	// The edge matches the type-only selector too but has no type
+	if report.Matched != 2 || len(report.Changes.Nodes) != 2 || len(report.Changes.Edges) != 0 {
+		t.Errorf("report mismatch: got %+v", report)
+	}
+	a := sf.FindNode("a")
+	if len(a.Tags) != 1 || a.Tags[0] != "prod" || a.Metadata["env"] != "production" || a.Metadata["owner"] != nil {
+		t.Errorf("node mismatch: got tags %v, metadata %v", a.Tags, a.Metadata)
+	}
+	if a.MaterialRef != "" || a.Material.Opacity != 0.5 || a.Material.Color.R != 0.5 {
+		t.Errorf("material mismatch: got %q %+v", a.MaterialRef, a.Material)
+	}
+	if c := sf.FindNode("c"); c.MaterialRef != "steel" || c.Tags != nil {
+		t.Errorf("unmatched node changed: got %+v", c)
+	}
+	if original[0].Tags[0] != "legacy" || original[0].Metadata["owner"] != "ops" {
+		t.Error("previous node slice modified")
+	}
+
+	sel, _ = ParseSelector("kind=edge")
+	if report, err := UpdateWhere(&sf, sel, Mutation{Op: MutationSetMetadata, Key: "protocol", Value: "grpc"}, Mutation{Op: MutationAddTag, Key: "x"}); err != nil || report.Matched != 1 || sf.Scene.Edges[0].Metadata["protocol"] != "grpc" {
+		t.Errorf("edge update mismatch: got %+v, %v", report, err)
+	}
+}
+
+// TestUpdateWhere_Atomic tests that failed updates leave the scene
+// unchanged
+func TestUpdateWhere_Atomic(t *testing.T) {
+	sf := newDiffScene()
+	_, err := UpdateWhere(&sf, Selector{},
+		Mutation{Op: MutationAddTag, Key: "prod"},
+		Mutation{Op: MutationSetMaterial, Key: "opacity", Value: "opaque"},
+	)
+	if !errors.Is(err, ErrPropertyType) {
+		t.Errorf("expected ErrPropertyType, got %v", err)
+	}
+	if sf.Scene.Nodes[0].Tags != nil || sf.Scene.Nodes[0].Material != nil {
+		t.Errorf("scene modified: got %+v", sf.Scene.Nodes[0])
+	}
+	if _, err := UpdateWhere(&sf, Selector{}, Mutation{Op: "rename", Key: "x"}); err == nil {
+		t.Error("expected error for unknown op")
+	}
+	if _, err := UpdateWhere(&sf, Selector{}, Mutation{Op: MutationSetMetadata}); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
@@ -0,0 +1,217 @@
+package starfleet
+
+import "fmt"
+
+// =============================================================================
+// EDGE AGGREGATION AND BUNDLING
+// =============================================================================
+
+// AggregatedEdgesExtension is the edge extension key listing the IDs of edges
+// merged into an aggregate edge
+const AggregatedEdgesExtension = "aggregatedEdges"
+
+// EffectiveWeight returns the edge weight, treating an unset weight as 1
+func (e *SceneEdge) EffectiveWeight() float64 {
+	if e.Weight <= 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// AggregateParallelEdges merges edges that share endpoints, type and
+// direction into a single weighted edge, regardless of their keys and ports.
+// The first edge of each group is kept; its weight becomes the sum of the
+// group weights, numeric metrics are summed and the merged edge IDs are
+// recorded under AggregatedEdgesExtension. It returns the number of edges
+// removed.
+func AggregateParallelEdges(sf *SceneFile) int {
+	groups := make(map[string]int, len(sf.Scene.Edges))
+	kept := make([]SceneEdge, 0, len(sf.Scene.Edges))
+
+	for _, edge := range sf.Scene.Edges {
+		source, target := edge.Source, edge.Target
+		direction := edge.Direction
+		if edge.IsDirected() {
+			direction = EdgeDirected
+		} else if source > target {
+			source, target = target, source
+		}
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%s", source, target, edge.Type, direction)
+
+		i, ok := groups[key]
+		if !ok {
+			groups[key] = len(kept)
+			kept = append(kept, edge)
+			continue
+		}
+
+		agg := &kept[i]
+		if agg.Extensions == nil || agg.Extensions[AggregatedEdgesExtension] == nil {
+			agg.Extensions = copyMap(agg.Extensions)
+			agg.Extensions[AggregatedEdgesExtension] = []string{agg.ID}
+			agg.Metrics = copyMap(agg.Metrics)
+			agg.Key, agg.SourcePort, agg.TargetPort = "", "", ""
+		}
+		agg.Weight = agg.EffectiveWeight() + edge.EffectiveWeight()
+		ids := toStringSlice(agg.Extensions[AggregatedEdgesExtension])
+		agg.Extensions[AggregatedEdgesExtension] = append(ids, edge.ID)
+		for name, v := range edge.Metrics {
+			n, ok := toFloat64(v)
+			if !ok {
+				continue
+			}
+			if existing, ok := toFloat64(agg.Metrics[name]); ok {
+				agg.Metrics[name] = existing + n
+			} else if _, present := agg.Metrics[name]; !present {
+				agg.Metrics[name] = n
+			}
+		}
+	}
+
+	removed := len(sf.Scene.Edges) - len(kept)
+	sf.Scene.Edges = kept
+	return removed
+}
+
+// copyMap returns a shallow copy of a map, allocating one when nil
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// BundleOptions represents options for hierarchical edge bundling
+type BundleOptions struct {
+	// Beta controls bundling strength from 0 (straight) to 1 (fully bundled)
+	Beta float64
+	// Samples is the number of waypoints generated per spline segment
+	Samples int
+	// EdgeTypes restricts bundling to the listed edge types when non-empty
+	EdgeTypes []string
+}
+
+// BundleEdges applies hierarchical edge bundling: each edge is routed along
+// the Parent hierarchy between its endpoints, using the positions of the
+// ancestors on that path as B-spline control points, straightened by Beta.
+// The sampled curve replaces the edge waypoints. Edges whose endpoints share
+// a parent or have no hierarchy keep their waypoints. It returns the number
+// of edges bundled.
+func BundleEdges(sf *SceneFile, opts BundleOptions) int {
+	beta := opts.Beta
+	if beta <= 0 || beta > 1 {
+		beta = 0.85
+	}
+	samples := opts.Samples
+	if samples <= 0 {
+		samples = 4
+	}
+	types := make(map[string]bool, len(opts.EdgeTypes))
+	for _, t := range opts.EdgeTypes {
+		types[t] = true
+	}
+
+	nodes := make(map[string]*SceneNode, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodes[sf.Scene.Nodes[i].ID] = &sf.Scene.Nodes[i]
+	}
+
+	bundled := 0
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if len(types) > 0 && !types[edge.Type] {
+			continue
+		}
+		source, target := nodes[edge.Source], nodes[edge.Target]
+		if source == nil || target == nil {
+			continue
+		}
+		path := hierarchyPath(nodes, source, target)
+		if len(path) < 3 {
+			continue
+		}
+
+		control := make([]Vector3, len(path))
+		first, last := path[0].Transform.Position, path[len(path)-1].Transform.Position
+		for j, n := range path {
+			straight := first.Lerp(last, float64(j)/float64(len(path)-1))
+			control[j] = n.Transform.Position.Scale(beta).Add(straight.Scale(1 - beta))
+		}
+		edge.Waypoints = sampleBSpline(control, samples)
+		bundled++
+	}
+	return bundled
+}
+
+// hierarchyPath returns the nodes from source up to the lowest common
+// ancestor and down to target. The common ancestor itself is omitted, as in
+// Holten's formulation, unless it is one of the endpoints.
+func hierarchyPath(nodes map[string]*SceneNode, source, target *SceneNode) []*SceneNode {
+	ancestors := func(n *SceneNode) []*SceneNode {
+		chain := []*SceneNode{n}
+		seen := map[string]bool{n.ID: true}
+		for n.Parent != "" {
+			p := nodes[n.Parent]
+			if p == nil || seen[p.ID] {
+				break
+			}
+			seen[p.ID] = true
+			chain = append(chain, p)
+			n = p
+		}
+		return chain
+	}
+
+	up, down := ancestors(source), ancestors(target)
+	index := make(map[string]int, len(down))
+	for i, n := range down {
+		index[n.ID] = i
+	}
+	for i, n := range up {
+		j, ok := index[n.ID]
+		if !ok {
+			continue
+		}
+		path := append([]*SceneNode{}, up[:i]...)
+		if i == 0 || j == 0 {
+			path = append(path, n)
+		}
+		for k := j - 1; k >= 0; k-- {
+			path = append(path, down[k])
+		}
+		return path
+	}
+	return nil
+}
+
+// sampleBSpline samples a clamped uniform cubic B-spline through control
+// points, returning the interior points (endpoints excluded)
+func sampleBSpline(control []Vector3, samples int) []Vector3 {
+	pts := make([]Vector3, 0, len(control)+4)
+	pts = append(pts, control[0], control[0])
+	pts = append(pts, control...)
+	pts = append(pts, control[len(control)-1], control[len(control)-1])
+
+	out := make([]Vector3, 0, (len(pts)-3)*samples)
+	for i := 0; i+3 < len(pts); i++ {
+		for s := 0; s < samples; s++ {
+			if i == 0 && s == 0 {
+				continue
+			}
+			t := float64(s) / float64(samples)
+			out = append(out, bsplinePoint(pts[i], pts[i+1], pts[i+2], pts[i+3], t))
+		}
+	}
+	return out
+}
+
+// bsplinePoint evaluates a uniform cubic B-spline segment
+func bsplinePoint(p0, p1, p2, p3 Vector3, t float64) Vector3 {
+	t2, t3 := t*t, t*t*t
+	b0 := (1 - 3*t + 3*t2 - t3) / 6
+	b1 := (4 - 6*t2 + 3*t3) / 6
+	b2 := (1 + 3*t + 3*t2 - 3*t3) / 6
+	b3 := t3 / 6
+	return p0.Scale(b0).Add(p1.Scale(b1)).Add(p2.Scale(b2)).Add(p3.Scale(b3))
+}
@@ -0,0 +1,74 @@
+package starfleet
+
+import "testing"
+
+// TestAggregateParallelEdges tests merging parallel edges into weighted edges
+func TestAggregateParallelEdges(t *testing.T) {
+	sf := NewSceneFile("Parallel")
+	sf.AddEdge(SceneEdge{ID: "e1", Source: "a", Target: "b", Key: "1", Metrics: map[string]interface{}{"rps": 10}})
+	sf.AddEdge(SceneEdge{ID: "e2", Source: "a", Target: "b", Key: "2", Weight: 2, Metrics: map[string]interface{}{"rps": 5.5, "proto": "tcp"}})
+	sf.AddEdge(SceneEdge{ID: "e3", Source: "b", Target: "a"})
+	sf.AddEdge(SceneEdge{ID: "u1", Source: "c", Target: "d", Direction: EdgeUndirected})
+	sf.AddEdge(SceneEdge{ID: "u2", Source: "d", Target: "c", Direction: EdgeUndirected})
+	sf.AddEdge(SceneEdge{ID: "b1", Source: "d", Target: "c", Direction: EdgeBidirectional})
+
+	removed := AggregateParallelEdges(&sf)
+	if removed != 2 || len(sf.Scene.Edges) != 4 {
+		t.Fatalf("AggregateParallelEdges mismatch: removed %d, remaining %d", removed, len(sf.Scene.Edges))
+	}
+
+	agg := sf.FindEdge("e1")
+	if agg.Weight != 3 {
+		t.Errorf("Aggregate weight mismatch: got %f, want 3", agg.Weight)
+	}
+	if agg.Metrics["rps"] != 15.5 {
+		t.Errorf("Aggregate metric mismatch: got %v, want 15.5", agg.Metrics["rps"])
+	}
+	ids := toStringSlice(agg.Extensions[AggregatedEdgesExtension])
+	if len(ids) != 2 || ids[0] != "e1" || ids[1] != "e2" {
+		t.Errorf("Aggregated ids mismatch: got %v", ids)
+	}
+	if agg.Key != "" {
+		t.Errorf("Aggregate edge should drop its key: got %s", agg.Key)
+	}
+	if sf.FindEdge("e3") == nil || sf.FindEdge("u1").Weight != 2 {
+		t.Errorf("Reverse directed edge must stay separate and undirected edges must merge")
+	}
+	if sf.FindEdge("b1") == nil || sf.FindEdge("b1").Weight != 0 {
+		t.Errorf("Bidirectional edge must stay separate from undirected edges")
+	}
+}
+
+// TestBundleEdges tests hierarchical edge bundling waypoints
+func TestBundleEdges(t *testing.T) {
+	sf := NewSceneFile("Bundles")
+	sf.AddNode(SceneNode{ID: "root", Transform: NewTransformWithPosition(0, 10, 0)})
+	sf.AddNode(SceneNode{ID: "g1", Parent: "root", Transform: NewTransformWithPosition(-5, 5, 0)})
+	sf.AddNode(SceneNode{ID: "g2", Parent: "root", Transform: NewTransformWithPosition(5, 5, 0)})
+	sf.AddNode(SceneNode{ID: "a", Parent: "g1", Transform: NewTransformWithPosition(-6, 0, 0)})
+	sf.AddNode(SceneNode{ID: "b", Parent: "g1", Transform: NewTransformWithPosition(-4, 0, 0)})
+	sf.AddNode(SceneNode{ID: "c", Parent: "g2", Transform: NewTransformWithPosition(5, 0, 0)})
+	sf.AddEdge(SceneEdge{ID: "cross", Source: "a", Target: "c"})
+	sf.AddEdge(SceneEdge{ID: "sibling", Source: "a", Target: "b", Waypoints: []Vector3{{X: -5, Y: -1}}})
+
+	if n := BundleEdges(&sf, BundleOptions{Samples: 3}); n != 1 {
+		t.Fatalf("BundleEdges count mismatch: got %d, want 1", n)
+	}
+
+	cross := sf.FindEdge("cross")
+	if len(cross.Waypoints) == 0 {
+		t.Fatalf("Cross-group edge has no waypoints")
+	}
+	peak := 0.0
+	for _, w := range cross.Waypoints {
+		if w.Y > peak {
+			peak = w.Y
+		}
+	}
+	if peak <= 1 {
+		t.Errorf("Bundled edge should bend toward group nodes: peak %f", peak)
+	}
+	if w := sf.FindEdge("sibling").Waypoints; len(w) != 1 || w[0] != (Vector3{X: -5, Y: -1}) {
+		t.Errorf("Sibling edge should keep its waypoints: got %v", w)
+	}
+}
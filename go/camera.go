@@ -0,0 +1,77 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// CAMERA FRAMING
+// =============================================================================
+
+// defaultViewDirection is the direction from target to camera used when a
+// camera has none: above, to the right and in front of the scene
+var defaultViewDirection = Vector3{X: 1, Y: 1, Z: 1.5}.Normalize()
+
+// FrameNodes returns a copy of the camera moved so the selected nodes fill
+// the view. The viewing direction is kept, the target moves to the center of
+// the selection, and the distance is chosen so the selection's bounding
+// sphere fits the field of view with padding as a fraction of its radius.
+// The vertical field of view is used, which fits landscape viewports.
+// Near and far planes, when set, are adjusted to bracket the selection.
+func FrameNodes(camera Camera, sf *SceneFile, nodeIDs []string, padding float64) (Camera, error) {
+	spheres := make([]BoundingSphere, 0, len(nodeIDs))
+	for _, id := range nodeIDs {
+		node := sf.FindNode(id)
+		if node == nil {
+			return camera, fmt.Errorf("frame nodes: %w: %s", ErrNodeNotFound, id)
+		}
+		spheres = append(spheres, sf.NodeBoundingSphere(node))
+	}
+	if len(spheres) == 0 {
+		return camera, nil
+	}
+	return frameSphere(camera, UnionSpheres(spheres...), padding, 1), nil
+}
+
+// FitSceneCamera frames every node with the scene camera, creating one with
+// the default field of view when the scene has none
+func FitSceneCamera(sf *SceneFile) {
+	camera := Camera{FOV: DefaultCameraFOV}
+	if sf.Scene.Camera != nil {
+		camera = *sf.Scene.Camera
+	}
+	if len(sf.Scene.Nodes) > 0 {
+		camera = frameSphere(camera, sf.SceneBoundingSphere(), 0.1, 1)
+	} else if camera.Position == camera.Target {
+		camera.Position = camera.Target.Add(defaultViewDirection.Scale(10))
+	}
+	sf.Scene.Camera = &camera
+}
+
+// frameSphere positions a camera to fit a sphere. Aspect ratios below one
+// narrow the effective field of view to the horizontal one.
+func frameSphere(camera Camera, s BoundingSphere, padding, aspect float64) Camera {
+	direction := camera.Position.Sub(camera.Target).Normalize()
+	if direction.Length() == 0 {
+		direction = defaultViewDirection
+	}
+	fov := camera.FOV
+	if fov <= 0 {
+		fov = DefaultCameraFOV
+	}
+	half := fov * math.Pi / 360
+	if aspect > 0 && aspect < 1 {
+		half = math.Atan(math.Tan(half) * aspect)
+	}
+
+	radius := math.Max(s.Radius, 1e-3) * (1 + math.Max(padding, 0))
+	distance := radius / math.Sin(half)
+	camera.Target = s.Center
+	camera.Position = s.Center.Add(direction.Scale(distance))
+	if camera.Near > 0 || camera.Far > 0 {
+		camera.Near = math.Max(distance-radius, distance*1e-3)
+		camera.Far = distance + radius
+	}
+	return camera
+}
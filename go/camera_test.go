@@ -0,0 +1,70 @@
+package starfleet
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestFrameNodes tests that the selection fits the field of view
+func TestFrameNodes(t *testing.T) {
+	sf := NewSceneFile("Frame")
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "a", Transform: NewTransformWithPosition(-4, 0, 0)},
+		{ID: "b", Transform: NewTransformWithPosition(4, 0, 0)},
+		{ID: "c", Transform: NewTransformWithPosition(100, 0, 0)},
+	}
+	camera := Camera{Position: Vector3{Z: 5}, Target: Vector3{}, FOV: 60, Near: 0.1, Far: 1000}
+
+	framed, err := FrameNodes(camera, &sf, []string{"a", "b"}, 0)
+	if err != nil {
+		t.Fatalf("FrameNodes failed: %v", err)
+	}
+	if framed.Target.Length() > 1e-9 {
+		t.Errorf("target mismatch: got %v, want origin", framed.Target)
+	}
+	if d := framed.Position.Sub(framed.Target).Normalize(); d.Sub(Vector3{Z: 1}).Length() > 1e-9 {
+		t.Errorf("view direction should be kept, got %v", d)
+	}
+	// Every selected node's sphere must lie within the half angle
+	for _, id := range []string{"a", "b"} {
+		s := sf.NodeBoundingSphere(sf.FindNode(id))
+		toNode := s.Center.Sub(framed.Position)
+		angle := math.Acos(toNode.Normalize().Dot(framed.Target.Sub(framed.Position).Normalize()))
+		if angle+math.Asin(s.Radius/toNode.Length()) > 30*math.Pi/180+1e-9 {
+			t.Errorf("node %s outside the view", id)
+		}
+	}
+	if framed.Near <= 0 || framed.Far <= framed.Near {
+		t.Errorf("clip planes mismatch: near %v, far %v", framed.Near, framed.Far)
+	}
+
+	padded, _ := FrameNodes(camera, &sf, []string{"a", "b"}, 0.5)
+	if padded.Position.Z <= framed.Position.Z {
+		t.Error("padding should move the camera back")
+	}
+
+	if _, err := FrameNodes(camera, &sf, []string{"missing"}, 0); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrNodeNotFound)
+	}
+}
+
+// TestFitSceneCamera tests whole-scene framing without an existing camera
+func TestFitSceneCamera(t *testing.T) {
+	sf := NewSceneFile("Fit")
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "a", Transform: NewTransformWithPosition(10, 0, 10)},
+		{ID: "b", Transform: NewTransformWithPosition(20, 0, 10)},
+	}
+	FitSceneCamera(&sf)
+	camera := sf.Scene.Camera
+	if camera == nil {
+		t.Fatal("expected a camera")
+	}
+	if camera.Target.Sub(Vector3{X: 15, Z: 10}).Length() > 1e-9 {
+		t.Errorf("target mismatch: got %v, want (15, 0, 10)", camera.Target)
+	}
+	if camera.FOV != DefaultCameraFOV || camera.Position.Y <= 0 {
+		t.Errorf("camera mismatch: got %+v", camera)
+	}
+}
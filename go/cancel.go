@@ -0,0 +1,28 @@
+package starfleet
+
+import "context"
+
+// =============================================================================
+// CANCELLATION
+// =============================================================================
+
+// cancelCheckInterval is the number of loop iterations between context
+// checks in hot loops, keeping the checks cheap on huge scenes
+const cancelCheckInterval = 256
+
+// canceler checks a context every cancelCheckInterval calls to check, so
+// heavy operations stop soon after their caller gives up
+type canceler struct {
+	ctx context.Context
+	n   int
+}
+
+// check returns the context's error on every cancelCheckInterval-th call
+// once it is done, and nil otherwise
+func (c *canceler) check() error {
+	c.n++
+	if c.n%cancelCheckInterval != 0 {
+		return nil
+	}
+	return c.ctx.Err()
+}
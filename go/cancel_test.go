@@ -0,0 +1,107 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// countdownContext reports cancellation after its first n checks, so tests
+// can cancel an operation once it is inside its loops
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func newLargeScene(n int) SceneFile {
+	sf := NewSceneFile("Large")
+	for i := 0; i < n; i++ {
+		sf.AddNode(SceneNode{ID: fmt.Sprintf("n%d", i), Type: "server", Name: "N", Transform: NewTransform()})
+		if i > 0 {
+			sf.AddEdge(SceneEdge{ID: fmt.Sprintf("e%d", i), Source: fmt.Sprintf("n%d", i-1), Target: fmt.Sprintf("n%d", i)})
+		}
+	}
+	return sf
+}
+
+// TestContextCancellation tests that heavy operations stop inside their
+// loops once the context is canceled
+func TestContextCancellation(t *testing.T) {
+	sf := newLargeScene(4 * cancelCheckInterval)
+	changed := newLargeScene(4 * cancelCheckInterval)
+	layout := &ConstrainedLayout{Constraints: LayoutConstraints{MinSpacing: 1}}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"diff", func(ctx context.Context) error {
+			_, err := DiffContext(ctx, &sf, &changed)
+			return err
+		}},
+		{"validate", func(ctx context.Context) error {
+			_, err := ValidateSceneContext(ctx, &sf)
+			return err
+		}},
+		{"check", func(ctx context.Context) error {
+			_, err := CheckSceneContext(ctx, &sf)
+			return err
+		}},
+		{"layout", func(ctx context.Context) error {
+			clone := newLargeScene(4 * cancelCheckInterval)
+			_, err := ApplyLayout(ctx, layout, &clone)
+			return err
+		}},
+		{"render", func(ctx context.Context) error {
+			_, err := RenderImageContext(ctx, &sf, 8, 8)
+			return err
+		}},
+		{"stats", func(ctx context.Context) error {
+			_, err := CalculateSceneStatsContext(ctx, &sf)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// One check passes at the start, the next one cancels
+			ctx := &countdownContext{Context: context.Background(), n: 1}
+			if err := tt.run(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("error mismatch: got %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+// TestApplyLayout_PlainLayout tests that layouts without context support
+// still run, and are skipped once the context is done
+func TestApplyLayout_PlainLayout(t *testing.T) {
+	runs := 0
+	layout := LayoutFunc(func(sf *SceneFile) (LayoutResult, error) {
+		runs++
+		return LayoutResult{}, nil
+	})
+	sf := newDiffScene()
+	if _, err := ApplyLayout(context.Background(), layout, &sf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ApplyLayout(ctx, layout, &sf); !errors.Is(err, context.Canceled) {
+		t.Errorf("error mismatch: got %v, want %v", err, context.Canceled)
+	}
+	if runs != 1 {
+		t.Errorf("runs mismatch: got %d, want 1", runs)
+	}
+}
@@ -0,0 +1,700 @@
+// Package client is a Go client for the scene service in package server.
+//
+// Writes take the revision they are based on. A stale revision returns an
+// error for which errors.Is(err, starfleet.ErrRevisionConflict) holds; use
+// errors.As with *starfleet.RevisionConflictError to inspect the diff of
+// changes made in the meantime.
+//
+// Requests are retried on transport errors and on 429, 502, 503 and 504
+// responses according to the client's RetryPolicy. Writes are conditional on
+// a revision or replace the whole scene, so repeating one is harmless: a
+// duplicate conditional write fails with a conflict. Unconditional patches,
+// lifecycle changes and import submissions are the exception and are sent
+// only once.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// Authenticator adds credentials to outgoing requests
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate calls f
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// BearerToken authenticates with a static bearer token
+type BearerToken string
+
+// Authenticate sets the Authorization header
+func (t BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// RetryPolicy controls retries of failed requests. Delays grow
+// exponentially from MinBackoff up to MaxBackoff with jitter; a Retry-After
+// header from the server takes precedence but is also capped at MaxBackoff.
+// When the delay would outlast the context deadline, the failed response is
+// returned instead of waiting.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; values below 2 disable retries
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is used by clients created with New
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 4, MinBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// backoff returns the delay before the given retry, counting from one
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Jitter over the upper half keeps clients from retrying in lockstep
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Client calls a scene service
+type Client struct {
+	// BaseURL is the service root, such as https://scenes.example.com
+	BaseURL string
+	// HTTPClient sends requests; nil uses http.DefaultClient
+	HTTPClient *http.Client
+	// Auth adds credentials to every request; nil sends none
+	Auth Authenticator
+	// Actor is sent with every request to attribute changes
+	Actor string
+	// AcceptVersion is the range of scene format versions the caller
+	// understands; scenes outside it fail with a compatibility error
+	AcceptVersion string
+	// Viewer describes the caller when it renders scenes, so the service
+	// can strip features it lacks; scenes it cannot render at all fail with
+	// starfleet.ErrUnsupportedViewer
+	Viewer *starfleet.ViewerInfo
+	Retry  RetryPolicy
+}
+
+// New creates a client for the service at baseURL with the default retry
+// policy
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Retry: DefaultRetryPolicy}
+}
+
+// ListScenes returns summaries of all stored scenes, following pagination
+func (c *Client) ListScenes(ctx context.Context) ([]starfleet.SceneSummary, error) {
+	var summaries []starfleet.SceneSummary
+	it := c.Scenes(ctx, 0)
+	for it.Next() {
+		summaries = append(summaries, it.Scene())
+	}
+	return summaries, it.Err()
+}
+
+// ListScenesPage returns one page of the catalog. A zero limit uses the
+// server's default page size and an empty cursor starts at the beginning.
+func (c *Client) ListScenesPage(ctx context.Context, limit int, cursor string) (starfleet.ScenePage, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	path := "/scenes"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var page starfleet.ScenePage
+	err := c.do(ctx, request{method: http.MethodGet, path: path, out: &page})
+	return page, err
+}
+
+// SceneIterator walks the scene catalog page by page
+type SceneIterator struct {
+	ctx      context.Context
+	client   *Client
+	limit    int
+	page     []starfleet.SceneSummary
+	index    int
+	cursor   string
+	current  starfleet.SceneSummary
+	err      error
+	finished bool
+}
+
+// Scenes returns an iterator over the catalog fetching pageSize summaries
+// per request; zero uses the server default
+func (c *Client) Scenes(ctx context.Context, pageSize int) *SceneIterator {
+	return &SceneIterator{ctx: ctx, client: c, limit: pageSize}
+}
+
+// Next advances to the next summary and reports whether there is one
+func (it *SceneIterator) Next() bool {
+	for it.err == nil {
+		if it.index < len(it.page) {
+			it.current = it.page[it.index]
+			it.index++
+			return true
+		}
+		if it.finished {
+			return false
+		}
+		page, err := it.client.ListScenesPage(it.ctx, it.limit, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.index, it.cursor = page.Scenes, 0, page.NextCursor
+		it.finished = page.NextCursor == ""
+	}
+	return false
+}
+
+// Scene returns the current summary
+func (it *SceneIterator) Scene() starfleet.SceneSummary {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *SceneIterator) Err() error {
+	return it.err
+}
+
+// GetScene returns the latest revision of a scene
+func (c *Client) GetScene(ctx context.Context, id string) (starfleet.SceneRevision, error) {
+	return c.GetSceneRevision(ctx, id, 0)
+}
+
+// GetSceneRevision returns a specific revision of a scene; zero selects the
+// latest
+func (c *Client) GetSceneRevision(ctx context.Context, id string, revision int64) (starfleet.SceneRevision, error) {
+	path := scenePath(id)
+	if revision > 0 {
+		path += "?revision=" + strconv.FormatInt(revision, 10)
+	}
+	var rev starfleet.SceneRevision
+	err := c.do(ctx, request{method: http.MethodGet, path: path, out: &rev})
+	return rev, err
+}
+
+// PutScene replaces a scene. revision is the revision the new content is
+// based on, zero to create the scene, or starfleet.AnyRevision to overwrite
+// unconditionally. Unconditional writes that leave out nodes or edges fail
+// with a *starfleet.StaleElementError, since the server cannot tell whether
+// they were added after sf was read.
+func (c *Client) PutScene(ctx context.Context, id string, sf *starfleet.SceneFile, revision int64) (starfleet.SceneRevision, error) {
+	body, err := json.Marshal(sf)
+	if err != nil {
+		return starfleet.SceneRevision{}, fmt.Errorf("put scene %s: %w", id, err)
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if revision == 0 {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", revisionTag(revision))
+	}
+	var rev starfleet.SceneRevision
+	err = c.do(ctx, request{method: http.MethodPut, path: scenePath(id), header: header, body: body, out: &rev})
+	return rev, err
+}
+
+// PatchScene applies an RFC 7386 merge patch to the given revision of a
+// scene, or to whatever is current with starfleet.AnyRevision. Patches
+// against AnyRevision are not retried.
+func (c *Client) PatchScene(ctx context.Context, id string, patch []byte, revision int64) (starfleet.SceneRevision, error) {
+	header := http.Header{
+		"Content-Type": {starfleet.MergePatchContentType},
+		"If-Match":     {revisionTag(revision)},
+	}
+	var rev starfleet.SceneRevision
+	err := c.do(ctx, request{
+		method:  http.MethodPatch,
+		path:    scenePath(id),
+		header:  header,
+		body:    patch,
+		out:     &rev,
+		noRetry: revision == starfleet.AnyRevision,
+	})
+	return rev, err
+}
+
+// PatchSceneOps applies an RFC 6902 JSON patch to the given revision of a
+// scene, or to whatever is current with starfleet.AnyRevision. Paths may
+// address nodes and edges by ID; see starfleet.PatchOp. Patches against
+// AnyRevision are not retried.
+func (c *Client) PatchSceneOps(ctx context.Context, id string, ops []starfleet.PatchOp, revision int64) (starfleet.SceneRevision, error) {
+	body, err := json.Marshal(ops)
+	if err != nil {
+		return starfleet.SceneRevision{}, err
+	}
+	header := http.Header{
+		"Content-Type": {starfleet.JSONPatchContentType},
+		"If-Match":     {revisionTag(revision)},
+	}
+	var rev starfleet.SceneRevision
+	err = c.do(ctx, request{
+		method:  http.MethodPatch,
+		path:    scenePath(id),
+		header:  header,
+		body:    body,
+		out:     &rev,
+		noRetry: revision == starfleet.AnyRevision,
+	})
+	return rev, err
+}
+
+// DeleteScene removes a scene at the given revision
+func (c *Client) DeleteScene(ctx context.Context, id string, revision int64) error {
+	header := http.Header{"If-Match": {revisionTag(revision)}}
+	return c.do(ctx, request{method: http.MethodDelete, path: scenePath(id), header: header})
+}
+
+// TransitionScene moves a scene to another lifecycle state. revision guards
+// the transition like a write; starfleet.AnyRevision applies it to the
+// current revision.
+func (c *Client) TransitionScene(ctx context.Context, id string, state starfleet.LifecycleState, comment string, revision int64) (starfleet.SceneRevision, error) {
+	return c.postLifecycle(ctx, scenePath(id)+"/lifecycle", starfleet.TransitionRequest{State: state, Comment: comment}, revision)
+}
+
+// ApproveScene records the principal the server authenticates the client as
+// approving a scene in review; the client's Actor is not used
+func (c *Client) ApproveScene(ctx context.Context, id, comment string, revision int64) (starfleet.SceneRevision, error) {
+	return c.postLifecycle(ctx, scenePath(id)+"/approvals", starfleet.ApprovalRequest{Comment: comment}, revision)
+}
+
+// SetApprovalPolicy replaces the approval policy of a draft scene; the
+// server only lets its policy admins do so
+func (c *Client) SetApprovalPolicy(ctx context.Context, id string, policy starfleet.ApprovalPolicyRequest, revision int64) (starfleet.SceneRevision, error) {
+	return c.postLifecycle(ctx, scenePath(id)+"/approval-policy", policy, revision)
+}
+
+func (c *Client) postLifecycle(ctx context.Context, path string, body interface{}, revision int64) (starfleet.SceneRevision, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return starfleet.SceneRevision{}, fmt.Errorf("POST %s: %w", path, err)
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if revision != starfleet.AnyRevision {
+		header.Set("If-Match", revisionTag(revision))
+	}
+	var rev starfleet.SceneRevision
+	err = c.do(ctx, request{
+		method:  http.MethodPost,
+		path:    path,
+		header:  header,
+		body:    data,
+		out:     &rev,
+		noRetry: revision == starfleet.AnyRevision,
+	})
+	return rev, err
+}
+
+// Compatibility returns the scene format versions the service supports
+func (c *Client) Compatibility(ctx context.Context) (starfleet.CompatibilityInfo, error) {
+	var info starfleet.CompatibilityInfo
+	err := c.do(ctx, request{method: http.MethodGet, path: "/compatibility", out: &info})
+	return info, err
+}
+
+// QueryMetrics runs a metrics query on the server. The signature matches
+// starfleet.MetricsSource, so a client can stand in for a local provider.
+func (c *Client) QueryMetrics(ctx context.Context, query starfleet.MetricsQuery) ([]starfleet.MetricsResult, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("query metrics: %w", err)
+	}
+	var results []starfleet.MetricsResult
+	err = c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/metrics/query",
+		header: http.Header{"Content-Type": {"application/json"}},
+		body:   body,
+		out:    &results,
+	})
+	return results, err
+}
+
+// SignURL asks the server for a download URL for a scene or an asset that
+// needs no credentials
+func (c *Client) SignURL(ctx context.Context, req starfleet.SignedURLRequest) (starfleet.SignedURL, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return starfleet.SignedURL{}, fmt.Errorf("sign url: %w", err)
+	}
+	var signed starfleet.SignedURL
+	err = c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/signed-urls",
+		header: http.Header{"Content-Type": {"application/json"}},
+		body:   body,
+		out:    &signed,
+	})
+	return signed, err
+}
+
+// ValidateScene checks a scene on the server the way a write would, without
+// storing it
+func (c *Client) ValidateScene(ctx context.Context, sf *starfleet.SceneFile) (starfleet.ValidationResult, error) {
+	body, err := json.Marshal(sf)
+	if err != nil {
+		return starfleet.ValidationResult{}, fmt.Errorf("validate scene: %w", err)
+	}
+	var result starfleet.ValidationResult
+	err = c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/validate",
+		header: http.Header{"Content-Type": {"application/json"}},
+		body:   body,
+		out:    &result,
+	})
+	return result, err
+}
+
+// SubmitImport starts an import on the server and returns the queued job
+func (c *Client) SubmitImport(ctx context.Context, req starfleet.ImportRequest) (starfleet.ImportJob, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return starfleet.ImportJob{}, fmt.Errorf("submit import: %w", err)
+	}
+	// Each submission starts a new job, so it is never repeated
+	var job starfleet.ImportJob
+	err = c.do(ctx, request{
+		method:  http.MethodPost,
+		path:    "/imports",
+		header:  http.Header{"Content-Type": {"application/json"}},
+		body:    body,
+		out:     &job,
+		noRetry: true,
+	})
+	return job, err
+}
+
+// GetImport returns the current state of an import job
+func (c *Client) GetImport(ctx context.Context, id string) (starfleet.ImportJob, error) {
+	var job starfleet.ImportJob
+	err := c.do(ctx, request{method: http.MethodGet, path: "/imports/" + url.PathEscape(id), out: &job})
+	return job, err
+}
+
+// CancelImport cancels an import job
+func (c *Client) CancelImport(ctx context.Context, id string) (starfleet.ImportJob, error) {
+	var job starfleet.ImportJob
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/imports/" + url.PathEscape(id), out: &job})
+	return job, err
+}
+
+// WaitImport polls an import job every interval until it finishes
+func (c *Client) WaitImport(ctx context.Context, id string, interval time.Duration) (starfleet.ImportJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.GetImport(ctx, id)
+		if err != nil || job.State.Done() {
+			return job, err
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// UpdatePresence announces the client's viewer on a scene, with the
+// camera, selection and privacy settings to share. Send it whenever they
+// change and at least every 30 seconds, the default timeout, to stay present;
+// the service throttles broadcasts, so callers need not. The service names
+// the viewer after the client's user, ignoring p.User.
+func (c *Client) UpdatePresence(ctx context.Context, sceneID string, p *starfleet.Presence) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("update presence %s: %w", p.Session, err)
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	return c.do(ctx, request{method: http.MethodPost, path: scenePath(sceneID) + "/presence", header: header, body: body})
+}
+
+// ListPresence returns the viewers present on a scene, as the others see
+// them
+func (c *Client) ListPresence(ctx context.Context, sceneID string) ([]starfleet.Presence, error) {
+	var present []starfleet.Presence
+	err := c.do(ctx, request{method: http.MethodGet, path: scenePath(sceneID) + "/presence", out: &present})
+	return present, err
+}
+
+// LeavePresence removes a viewer of the client's user from a scene
+func (c *Client) LeavePresence(ctx context.Context, sceneID, session string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: scenePath(sceneID) + "/presence/" + url.PathEscape(session)})
+}
+
+// Search searches the nodes of a scene, as starfleet.SearchIndex does,
+// returning at most limit results; zero uses the server default
+func (c *Client) Search(ctx context.Context, sceneID, query string, limit int) (starfleet.SearchResponse, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	var resp starfleet.SearchResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: scenePath(sceneID) + "/search?" + params.Encode(), out: &resp})
+	return resp, err
+}
+
+// Stats returns the statistics of the latest revision of a scene
+func (c *Client) Stats(ctx context.Context, sceneID string) (starfleet.SceneStatsResponse, error) {
+	var resp starfleet.SceneStatsResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: scenePath(sceneID) + "/stats", out: &resp})
+	return resp, err
+}
+
+// ListGroups evaluates the saved queries of a scene as smart groups
+func (c *Client) ListGroups(ctx context.Context, sceneID string) ([]starfleet.SmartGroup, error) {
+	var groups []starfleet.SmartGroup
+	err := c.do(ctx, request{method: http.MethodGet, path: scenePath(sceneID) + "/groups", out: &groups})
+	return groups, err
+}
+
+// GetGroup evaluates one saved query of a scene
+func (c *Client) GetGroup(ctx context.Context, sceneID, name string) (starfleet.SmartGroup, error) {
+	var group starfleet.SmartGroup
+	err := c.do(ctx, request{method: http.MethodGet, path: scenePath(sceneID) + "/groups/" + url.PathEscape(name), out: &group})
+	return group, err
+}
+
+// ListSessions returns the viewer sessions of a scene
+func (c *Client) ListSessions(ctx context.Context, sceneID string) ([]starfleet.ViewerSession, error) {
+	var sessions []starfleet.ViewerSession
+	err := c.do(ctx, request{method: http.MethodGet, path: scenePath(sceneID) + "/sessions", out: &sessions})
+	return sessions, err
+}
+
+// GetSession returns a viewer session
+func (c *Client) GetSession(ctx context.Context, sceneID, id string) (starfleet.ViewerSession, error) {
+	var session starfleet.ViewerSession
+	err := c.do(ctx, request{method: http.MethodGet, path: sessionPath(sceneID, id), out: &session})
+	return session, err
+}
+
+// PutSession writes a viewer session based on the given session revision:
+// zero creates it and starfleet.AnyRevision overwrites whatever is stored.
+// The server records the client's user as the session's, and only lets
+// that user write or delete it.
+func (c *Client) PutSession(ctx context.Context, session *starfleet.ViewerSession, revision int64) (starfleet.ViewerSession, error) {
+	body, err := json.Marshal(session)
+	if err != nil {
+		return starfleet.ViewerSession{}, fmt.Errorf("put session %s: %w", session.ID, err)
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	switch revision {
+	case starfleet.AnyRevision:
+	case 0:
+		header.Set("If-None-Match", "*")
+	default:
+		header.Set("If-Match", revisionTag(revision))
+	}
+	var stored starfleet.ViewerSession
+	err = c.do(ctx, request{method: http.MethodPut, path: sessionPath(session.SceneID, session.ID), header: header, body: body, out: &stored})
+	return stored, err
+}
+
+// DeleteSession ends a viewer session
+func (c *Client) DeleteSession(ctx context.Context, sceneID, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: sessionPath(sceneID, id)})
+}
+
+// MetricsSource returns the client's metrics endpoint as a
+// starfleet.MetricsSource
+func (c *Client) MetricsSource() starfleet.MetricsSource {
+	return starfleet.MetricsSourceFunc(c.QueryMetrics)
+}
+
+func scenePath(id string) string {
+	return "/scenes/" + url.PathEscape(id)
+}
+
+// sessionPath returns the URL path of a viewer session
+func sessionPath(sceneID, id string) string {
+	return scenePath(sceneID) + "/sessions/" + url.PathEscape(id)
+}
+
+func revisionTag(revision int64) string {
+	if revision == starfleet.AnyRevision {
+		return "*"
+	}
+	return starfleet.RevisionTag(revision)
+}
+
+// request describes one API call
+type request struct {
+	method  string
+	path    string
+	header  http.Header
+	body    []byte
+	out     interface{}
+	noRetry bool
+}
+
+// do sends a request with retries and decodes a JSON response into out.
+// Error responses are returned as *starfleet.APIError.
+func (c *Client) do(ctx context.Context, req request) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", req.method, req.path, err)
+	}
+	if resp.StatusCode >= 300 {
+		return decodeAPIError(resp.StatusCode, data)
+	}
+	if req.out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, req.out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// send performs the HTTP exchange, retrying transient failures. The caller
+// closes the response body.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 || req.noRetry {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if req.body != nil {
+			body = bytes.NewReader(req.body)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, c.BaseURL+req.path, body)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range req.header {
+			httpReq.Header[k] = v
+		}
+		if httpReq.Header.Get("Accept") == "" {
+			httpReq.Header.Set("Accept", "application/json")
+		}
+		if c.Actor != "" {
+			httpReq.Header.Set(starfleet.ActorHeader, c.Actor)
+		}
+		if c.AcceptVersion != "" {
+			httpReq.Header.Set(starfleet.AcceptVersionHeader, c.AcceptVersion)
+		}
+		if c.Viewer != nil {
+			features := make([]string, len(c.Viewer.Features))
+			for i, f := range c.Viewer.Features {
+				features[i] = string(f)
+			}
+			httpReq.Header.Set(starfleet.ViewerVersionHeader, c.Viewer.Version)
+			httpReq.Header.Set(starfleet.ViewerFeaturesHeader, strings.Join(features, ","))
+		}
+		if c.Auth != nil {
+			if err := c.Auth.Authenticate(httpReq); err != nil {
+				return nil, fmt.Errorf("%s %s: authenticate: %w", req.method, req.path, err)
+			}
+		}
+
+		resp, err := httpClient.Do(httpReq)
+		if attempt >= attempts || !retryable(resp, err) {
+			return resp, err
+		}
+		delay := c.Retry.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = after
+				if c.Retry.MaxBackoff > 0 && delay > c.Retry.MaxBackoff {
+					delay = c.Retry.MaxBackoff
+				}
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return resp, nil
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a failed attempt is worth repeating
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// decodeAPIError turns an error response into a *starfleet.APIError, also
+// for servers or proxies that do not answer with the service's error body
+func decodeAPIError(status int, data []byte) error {
+	apiErr := &starfleet.APIError{}
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Code == "" {
+		apiErr.Code = http.StatusText(status)
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	apiErr.Status = status
+	return apiErr
+}
@@ -0,0 +1,616 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+	"github.com/hyperdrive-technology/starfleet-sdk-go/server"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	ts := httptest.NewServer(server.New(starfleet.NewMemorySceneStore()))
+	t.Cleanup(ts.Close)
+	return New(ts.URL)
+}
+
+func newTestScene() *starfleet.SceneFile {
+	sf := starfleet.NewSceneFile("Test")
+	sf.AddNode(starfleet.SceneNode{ID: "api", Type: "server", Name: "API", Transform: starfleet.NewTransform()})
+	return &sf
+}
+
+// TestClient_ConcurrentEdits tests that the second of two writers based on
+// the same revision gets a conflict describing the first writer's change
+func TestClient_ConcurrentEdits(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	created, err := c.PutScene(ctx, "prod", newTestScene(), 0)
+	if err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), 0); !errors.Is(err, starfleet.ErrRevisionConflict) {
+		t.Errorf("expected conflict when creating twice, got %v", err)
+	}
+
+	if _, err := c.PatchScene(ctx, "prod", []byte(`{"metadata": {"author": "alice"}}`), created.Revision); err != nil {
+		t.Fatalf("PatchScene failed: %v", err)
+	}
+	_, err = c.PatchScene(ctx, "prod", []byte(`{"metadata": {"author": "bob"}}`), created.Revision)
+	var conflict *starfleet.RevisionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected RevisionConflictError, got %v", err)
+	}
+	if conflict.Diff == nil || len(conflict.Diff.Fields) != 1 || conflict.Diff.Fields[0] != "metadata.author" {
+		t.Errorf("diff mismatch: got %+v", conflict.Diff)
+	}
+
+	latest, err := c.GetScene(ctx, "prod")
+	if err != nil {
+		t.Fatalf("GetScene failed: %v", err)
+	}
+	if latest.Revision != 2 || latest.Scene.Metadata.Author != "alice" {
+		t.Errorf("latest mismatch: got %d %q", latest.Revision, latest.Scene.Metadata.Author)
+	}
+	first, err := c.GetSceneRevision(ctx, "prod", 1)
+	if err != nil || first.Scene.Metadata.Author != "" {
+		t.Errorf("revision 1 mismatch: got %+v, %v", first.Scene.Metadata, err)
+	}
+
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), starfleet.AnyRevision); err != nil {
+		t.Errorf("unconditional PutScene failed: %v", err)
+	}
+	summaries, err := c.ListScenes(ctx)
+	if err != nil || len(summaries) != 1 || summaries[0].Revision != 3 {
+		t.Errorf("ListScenes mismatch: got %+v, %v", summaries, err)
+	}
+	if err := c.DeleteScene(ctx, "prod", 3); err != nil {
+		t.Errorf("DeleteScene failed: %v", err)
+	}
+	if _, err := c.GetScene(ctx, "prod"); !errors.Is(err, starfleet.ErrSceneNotFound) {
+		t.Errorf("expected ErrSceneNotFound, got %v", err)
+	}
+}
+
+// TestClient_Pagination tests that the iterator follows cursors across pages
+func TestClient_Pagination(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	for i := 0; i < 5; i++ {
+		if _, err := c.PutScene(ctx, fmt.Sprintf("scene-%d", i), newTestScene(), 0); err != nil {
+			t.Fatalf("PutScene failed: %v", err)
+		}
+	}
+
+	page, err := c.ListScenesPage(ctx, 2, "")
+	if err != nil {
+		t.Fatalf("ListScenesPage failed: %v", err)
+	}
+	if len(page.Scenes) != 2 || page.NextCursor == "" {
+		t.Errorf("first page mismatch: got %+v", page)
+	}
+
+	var ids []string
+	it := c.Scenes(ctx, 2)
+	for it.Next() {
+		ids = append(ids, it.Scene().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	want := []string{"scene-0", "scene-1", "scene-2", "scene-3", "scene-4"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids mismatch: got %v, want %v", ids, want)
+	}
+}
+
+// TestClient_RetryAndAuth tests retries of transient failures and that
+// credentials are sent on every attempt
+func TestClient_RetryAndAuth(t *testing.T) {
+	var attempts atomic.Int32
+	srv := server.New(starfleet.NewMemorySceneStore())
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if attempts.Add(1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	c := New(ts.URL)
+	c.Auth = BearerToken("secret")
+	c.Retry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	if _, err := c.ListScenes(context.Background()); err != nil {
+		t.Fatalf("ListScenes failed after retries: %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("attempt count mismatch: got %d, want 3", got)
+	}
+
+	c.Auth = nil
+	_, err := c.ListScenes(context.Background())
+	var apiErr *starfleet.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Errorf("expected 401 APIError, got %v", err)
+	}
+}
+
+// TestClient_RetryAfterCapped tests that Retry-After delays are capped at
+// MaxBackoff and never outlast the context deadline
+func TestClient_RetryAfterCapped(t *testing.T) {
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(ts.Close)
+
+	c := New(ts.URL)
+	c.Retry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	start := time.Now()
+	_, err := c.ListScenes(context.Background())
+	var apiErr *starfleet.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 APIError, got %v", err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("attempt count mismatch: got %d, want 3", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected capped delays, took %v", elapsed)
+	}
+
+	// A delay past the deadline returns the failure at once
+	attempts.Store(0)
+	c.Retry.MaxBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = c.ListScenes(ctx)
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 APIError before the deadline, got %v", err)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempt count mismatch: got %d, want 1", got)
+	}
+}
+
+// TestClient_QueryMetrics tests the metrics endpoint round trip
+func TestClient_QueryMetrics(t *testing.T) {
+	srv := server.New(starfleet.NewMemorySceneStore())
+	srv.Metrics = starfleet.MetricsSourceFunc(func(ctx context.Context, q starfleet.MetricsQuery) ([]starfleet.MetricsResult, error) {
+		var results []starfleet.MetricsResult
+		for _, id := range q.NodeIDs {
+			results = append(results, starfleet.MetricsResult{
+				NodeID:     id,
+				MetricName: "cpu",
+				DataPoints: []starfleet.MetricsDataPoint{{Timestamp: time.Unix(0, 0).UTC(), Value: 0.5}},
+			})
+		}
+		return results, nil
+	})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	results, err := New(ts.URL).MetricsSource().Query(context.Background(), starfleet.MetricsQuery{NodeIDs: []string{"api", "db"}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 || results[1].NodeID != "db" || results[0].DataPoints[0].Value != 0.5 {
+		t.Errorf("results mismatch: got %+v", results)
+	}
+}
+
+// TestClient_StreamScene tests that a stream delivers a snapshot, updates
+// and the final deletion
+func TestClient_StreamScene(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := newTestClient(t)
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+
+	stream, err := c.StreamScene(ctx, "prod")
+	if err != nil {
+		t.Fatalf("StreamScene failed: %v", err)
+	}
+	defer stream.Close()
+	if !stream.Next() || stream.Event().Type != starfleet.SceneEventSnapshot || stream.Event().Revision != 1 {
+		t.Fatalf("snapshot mismatch: got %+v, err %v", stream.Event(), stream.Err())
+	}
+
+	if _, err := c.PatchScene(ctx, "prod", []byte(`{"metadata": {"author": "alice"}}`), 1); err != nil {
+		t.Fatalf("PatchScene failed: %v", err)
+	}
+	if !stream.Next() {
+		t.Fatalf("expected update event, err %v", stream.Err())
+	}
+	if e := stream.Event(); e.Type != starfleet.SceneEventUpdated || e.Revision != 2 || e.Scene.Metadata.Author != "alice" {
+		t.Errorf("update mismatch: got %+v", e)
+	}
+
+	if err := c.DeleteScene(ctx, "prod", 2); err != nil {
+		t.Fatalf("DeleteScene failed: %v", err)
+	}
+	if !stream.Next() || stream.Event().Type != starfleet.SceneEventDeleted {
+		t.Fatalf("expected delete event, got %+v, err %v", stream.Event(), stream.Err())
+	}
+	if stream.Next() {
+		t.Errorf("expected stream to end after deletion, got %+v", stream.Event())
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("unexpected stream error: %v", err)
+	}
+}
+
+// TestClient_Lifecycle tests review and publishing through the API against
+// a change-controlled store
+func TestClient_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := starfleet.NewGuardedStore(starfleet.NewMemorySceneStore(), starfleet.LifecycleGuard)
+	srv := server.New(store)
+	srv.PolicyAdmins = []string{"bob"}
+	srv.Authenticate = func(r *http.Request) (starfleet.Principal, error) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer alice-token":
+			return starfleet.Principal{ID: "alice"}, nil
+		case "Bearer bob-token":
+			return starfleet.Principal{ID: "bob"}, nil
+		}
+		return starfleet.Principal{}, starfleet.ErrUnauthenticated
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	author := New(ts.URL)
+	author.Auth = BearerToken("alice-token")
+	reviewer := New(ts.URL)
+	reviewer.Auth = BearerToken("bob-token")
+	impostor := New(ts.URL)
+	impostor.Auth = BearerToken("alice-token")
+	impostor.Actor = "bob"
+
+	sf := newTestScene()
+	sf.Metadata.Lifecycle = &starfleet.Lifecycle{State: starfleet.LifecycleDraft, RequiredApprovers: []string{"bob"}}
+	if _, err := author.PutScene(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	if _, err := author.PatchScene(ctx, "prod", []byte(`{"metadata": {"lifecycle": {"requiredApprovers": null}}}`), 1); !errors.Is(err, starfleet.ErrChangeControlled) {
+		t.Errorf("expected clearing the approval policy by a write to fail, got %v", err)
+	}
+	policy := starfleet.ApprovalPolicyRequest{RequiredApprovers: []string{"bob"}, MinApprovals: 1}
+	if _, err := author.SetApprovalPolicy(ctx, "prod", starfleet.ApprovalPolicyRequest{}, 1); !errors.Is(err, starfleet.ErrAccessDenied) {
+		t.Errorf("expected a policy change by a non-admin to fail, got %v", err)
+	}
+	if _, err := reviewer.SetApprovalPolicy(ctx, "prod", policy, 1); err != nil {
+		t.Fatalf("SetApprovalPolicy failed: %v", err)
+	}
+	if _, err := New(ts.URL).TransitionScene(ctx, "prod", starfleet.LifecycleInReview, "ready", 2); !errors.Is(err, starfleet.ErrUnauthenticated) {
+		t.Errorf("expected anonymous transition to fail, got %v", err)
+	}
+	if _, err := author.TransitionScene(ctx, "prod", starfleet.LifecycleInReview, "ready", 2); err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if _, err := author.TransitionScene(ctx, "prod", starfleet.LifecyclePublished, "", starfleet.AnyRevision); !errors.Is(err, starfleet.ErrApprovalRequired) {
+		t.Errorf("expected publish without approval to fail, got %v", err)
+	}
+	if _, err := author.PatchScene(ctx, "prod", []byte(`{"metadata": {"description": "sneaky"}}`), 3); !errors.Is(err, starfleet.ErrChangeControlled) {
+		t.Errorf("expected edit during review to fail, got %v", err)
+	}
+	if _, err := reviewer.SetApprovalPolicy(ctx, "prod", starfleet.ApprovalPolicyRequest{}, 3); !errors.Is(err, starfleet.ErrChangeControlled) {
+		t.Errorf("expected a policy change during review to fail, got %v", err)
+	}
+	if _, err := impostor.ApproveScene(ctx, "prod", "lgtm", 3); !errors.Is(err, starfleet.ErrApprovalRequired) {
+		t.Errorf("expected approval under a claimed actor to fail, got %v", err)
+	}
+	if _, err := New(ts.URL).ApproveScene(ctx, "prod", "lgtm", 3); !errors.Is(err, starfleet.ErrUnauthenticated) {
+		t.Errorf("expected anonymous approval to fail, got %v", err)
+	}
+	if _, err := reviewer.ApproveScene(ctx, "prod", "lgtm", 3); err != nil {
+		t.Fatalf("ApproveScene failed: %v", err)
+	}
+	rev, err := author.TransitionScene(ctx, "prod", starfleet.LifecyclePublished, "", 4)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	lc := rev.Scene.Metadata.Lifecycle
+	if lc.State != starfleet.LifecyclePublished || len(lc.Approvals) != 1 || lc.History[1].Actor != "alice" {
+		t.Errorf("lifecycle mismatch: got %+v", lc)
+	}
+}
+
+// TestClient_Compatibility tests version negotiation with the server
+func TestClient_Compatibility(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	info, err := c.Compatibility(ctx)
+	if err != nil {
+		t.Fatalf("Compatibility failed: %v", err)
+	}
+	if info.SchemaVersion != starfleet.SchemaVersion || len(info.Releases) == 0 {
+		t.Errorf("compatibility mismatch: got %+v", info)
+	}
+
+	sf := newTestScene()
+	sf.Scene.Nodes[0].Label = &starfleet.Label{Text: "API"}
+	if _, err := c.PutScene(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	c.AcceptVersion = "^5.0.0"
+	_, err = c.GetScene(ctx, "prod")
+	var apiErr *starfleet.APIError
+	if !errors.As(err, &apiErr) || apiErr.Compatibility == nil || apiErr.Compatibility.Action != starfleet.CompatibilityReject {
+		t.Errorf("expected an incompatible version error, got %v", err)
+	}
+
+	c.AcceptVersion = ""
+	sf.Require(starfleet.FeatureRequirement{Feature: starfleet.ViewerFeatureInstancing, Required: true})
+	sf.Requirements.Fallback = "Open in the desktop viewer"
+	if _, err := c.PutScene(ctx, "prod", sf, 1); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	c.Viewer = &starfleet.ViewerInfo{Version: "1.0.0"}
+	_, err = c.GetScene(ctx, "prod")
+	if !errors.Is(err, starfleet.ErrUnsupportedViewer) || !errors.As(err, &apiErr) || apiErr.Viewer.Fallback != "Open in the desktop viewer" {
+		t.Errorf("expected an unsupported viewer error, got %v", err)
+	}
+	c.Viewer.Features = []starfleet.ViewerFeature{starfleet.ViewerFeatureInstancing}
+	if _, err := c.GetScene(ctx, "prod"); err != nil {
+		t.Errorf("GetScene failed: %v", err)
+	}
+}
+
+// TestClient_SignURL tests minting a signed scene URL and fetching it
+// without credentials
+func TestClient_SignURL(t *testing.T) {
+	ctx := context.Background()
+	srv := server.New(starfleet.NewMemorySceneStore())
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	srv.URLSigner = starfleet.NewURLSigner(ts.URL, "secret")
+	c := New(ts.URL)
+
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	signed, err := c.SignURL(ctx, starfleet.SignedURLRequest{Scene: "prod", TTL: 60})
+	if err != nil {
+		t.Fatalf("SignURL failed: %v", err)
+	}
+	resp, err := http.Get(signed.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status mismatch: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if _, err := c.SignURL(ctx, starfleet.SignedURLRequest{Scene: "missing"}); !errors.Is(err, starfleet.ErrSceneNotFound) {
+		t.Errorf("expected ErrSceneNotFound, got %v", err)
+	}
+}
+
+// TestClient_SubmitImportNoRetry tests that a failed import submission is not
+// repeated, since each submission starts a new job
+func TestClient_SubmitImportNoRetry(t *testing.T) {
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(ts.Close)
+
+	c := New(ts.URL)
+	c.Retry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	if _, err := c.SubmitImport(context.Background(), starfleet.ImportRequest{Importer: "static"}); err == nil {
+		t.Error("expected error from failed submission")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempt count mismatch: got %d, want 1", got)
+	}
+}
+
+// TestClient_Imports tests submitting and waiting for an import job
+func TestClient_Imports(t *testing.T) {
+	ctx := context.Background()
+	srv := server.New(starfleet.NewMemorySceneStore())
+	srv.Imports = starfleet.NewImportQueue(1)
+	srv.Importers = map[string]starfleet.Importer{
+		"static": starfleet.ImporterFunc(func(_ context.Context, _ starfleet.ImporterConfig, progress starfleet.ProgressFunc) (starfleet.ImportResult, error) {
+			progress(starfleet.ImportProgress{Discovered: 1, Built: 1})
+			return starfleet.ImportResult{Scene: *newTestScene()}, nil
+		}),
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	c := New(ts.URL)
+
+	job, err := c.SubmitImport(ctx, starfleet.ImportRequest{Importer: "static"})
+	if err != nil {
+		t.Fatalf("SubmitImport failed: %v", err)
+	}
+	done, err := c.WaitImport(ctx, job.ID, time.Millisecond)
+	if err != nil || done.State != starfleet.ImportJobSucceeded || done.Result == nil || done.Progress.Built != 1 {
+		t.Errorf("WaitImport mismatch: got %+v, %v", done, err)
+	}
+	if _, err := c.SubmitImport(ctx, starfleet.ImportRequest{Importer: "nope"}); err == nil {
+		t.Error("expected error for unknown importer")
+	}
+}
+
+// TestClient_ValidateScene tests dry-run validation
+func TestClient_ValidateScene(t *testing.T) {
+	c := newTestClient(t)
+	sf := newTestScene()
+	if result, err := c.ValidateScene(context.Background(), sf); err != nil || !result.Valid {
+		t.Errorf("ValidateScene mismatch: got %+v, %v", result, err)
+	}
+	sf.Version = "9.0.0"
+	if result, err := c.ValidateScene(context.Background(), sf); err != nil || result.Valid || !strings.HasPrefix(result.Errors[0], "Unsupported scene version") {
+		t.Errorf("ValidateScene mismatch: got %+v, %v", result, err)
+	}
+}
+
+// TestClient_PatchSceneOps tests sending JSON patches
+func TestClient_PatchSceneOps(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	created, err := c.PutScene(ctx, "prod", newTestScene(), 0)
+	if err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	ops := []starfleet.PatchOp{
+		{Op: "test", Path: "/nodes/api/name", Value: "API"},
+		{Op: "add", Path: "/nodes/api/tags", Value: []string{"edge"}},
+	}
+	rev, err := c.PatchSceneOps(ctx, "prod", ops, created.Revision)
+	if err != nil || rev.Scene.FindNode("api").Tags[0] != "edge" {
+		t.Fatalf("PatchSceneOps mismatch: got %+v, %v", rev.Scene.FindNode("api"), err)
+	}
+	if _, err := c.PatchSceneOps(ctx, "prod", ops[:1], starfleet.AnyRevision); err != nil {
+		t.Errorf("PatchSceneOps failed: %v", err)
+	}
+	ops[0].Value = "Gateway"
+	if _, err := c.PatchSceneOps(ctx, "prod", ops, starfleet.AnyRevision); !errors.Is(err, starfleet.ErrPatchTestFailed) {
+		t.Errorf("expected ErrPatchTestFailed, got %v", err)
+	}
+}
+
+// TestClient_Sessions tests the viewer session round trip
+func TestClient_Sessions(t *testing.T) {
+	ctx := context.Background()
+	srv := server.New(starfleet.NewMemorySceneStore())
+	srv.Sessions = starfleet.NewSessionStore()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	c := New(ts.URL)
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+
+	session := &starfleet.ViewerSession{ID: "s1", SceneID: "prod", Filters: map[string]string{"status": "critical"}}
+	created, err := c.PutSession(ctx, session, 0)
+	if err != nil || created.Revision != 1 {
+		t.Fatalf("PutSession mismatch: got %+v, %v", created, err)
+	}
+	if _, err := c.PutSession(ctx, session, 0); !errors.Is(err, starfleet.ErrRevisionConflict) {
+		t.Errorf("expected ErrRevisionConflict, got %v", err)
+	}
+	session.Selection = []string{"api"}
+	if updated, err := c.PutSession(ctx, session, created.Revision); err != nil || updated.Revision != 2 {
+		t.Errorf("PutSession mismatch: got %+v, %v", updated, err)
+	}
+	got, err := c.GetSession(ctx, "prod", "s1")
+	if err != nil || got.Selection[0] != "api" || got.Filters["status"] != "critical" {
+		t.Errorf("GetSession mismatch: got %+v, %v", got, err)
+	}
+	if sessions, err := c.ListSessions(ctx, "prod"); err != nil || len(sessions) != 1 {
+		t.Errorf("ListSessions mismatch: got %+v, %v", sessions, err)
+	}
+	if err := c.DeleteSession(ctx, "prod", "s1"); err != nil {
+		t.Errorf("DeleteSession failed: %v", err)
+	}
+}
+
+// TestClient_Presence tests announcing, listing and leaving presence
+func TestClient_Presence(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	p := &starfleet.Presence{Session: "s1", User: "ada", Privacy: starfleet.PresencePrivacy{Anonymous: true}}
+	if err := c.UpdatePresence(ctx, "prod", p); err != nil {
+		t.Fatalf("UpdatePresence failed: %v", err)
+	}
+	present, err := c.ListPresence(ctx, "prod")
+	if err != nil || len(present) != 1 || present[0].Session != "s1" || present[0].User != "" {
+		t.Errorf("ListPresence mismatch: got %+v, %v", present, err)
+	}
+	if err := c.LeavePresence(ctx, "prod", "s1"); err != nil {
+		t.Errorf("LeavePresence failed: %v", err)
+	}
+	if present, err := c.ListPresence(ctx, "prod"); err != nil || len(present) != 0 {
+		t.Errorf("ListPresence mismatch after leave: got %+v, %v", present, err)
+	}
+}
+
+// TestClient_Search tests searching a scene and reading its statistics
+func TestClient_Search(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+	if _, err := c.PutScene(ctx, "prod", newTestScene(), 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	resp, err := c.Search(ctx, "prod", "name:api", 5)
+	if err != nil || resp.Revision != 1 || len(resp.Results) != 1 || resp.Results[0].NodeID != "api" {
+		t.Errorf("Search mismatch: got %+v, %v", resp, err)
+	}
+	stats, err := c.Stats(ctx, "prod")
+	if err != nil || stats.Revision != 1 || stats.Stats.NodeCount != 1 || stats.Stats.IsolatedNodes != 1 {
+		t.Errorf("Stats mismatch: got %+v, %v", stats, err)
+	}
+}
+
+// TestClient_Groups tests evaluating smart groups and following their
+// membership
+func TestClient_Groups(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := newTestClient(t)
+	sf := newTestScene()
+	sf.SaveQuery(starfleet.SavedQuery{Name: "servers", Query: "type=server"})
+	if _, err := c.PutScene(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	groups, err := c.ListGroups(ctx, "prod")
+	if err != nil || len(groups) != 1 || groups[0].Name != "servers" {
+		t.Errorf("ListGroups mismatch: got %+v, %v", groups, err)
+	}
+	var apiErr *starfleet.APIError
+	if _, err := c.GetGroup(ctx, "prod", "missing"); !errors.As(err, &apiErr) || apiErr.Code != starfleet.APIErrorNotFound {
+		t.Errorf("expected not_found, got %v", err)
+	}
+
+	stream, err := c.StreamMembership(ctx, "prod", "servers")
+	if err != nil {
+		t.Fatalf("StreamMembership failed: %v", err)
+	}
+	defer stream.Close()
+	if !stream.Next() || stream.Event().Type != starfleet.SceneEventMembership || len(stream.Event().Membership) != 1 {
+		t.Fatalf("initial membership mismatch: got %+v, err %v", stream.Event(), stream.Err())
+	}
+	// A change outside the group sends nothing; adding a server does
+	if _, err := c.PatchScene(ctx, "prod", []byte(`{"metadata": {"author": "alice"}}`), 1); err != nil {
+		t.Fatalf("PatchScene failed: %v", err)
+	}
+	sf.AddNode(starfleet.SceneNode{ID: "web", Type: "server", Name: "Web", Transform: starfleet.NewTransform()})
+	sf.Metadata.Author = "alice"
+	if _, err := c.PutScene(ctx, "prod", sf, 2); err != nil {
+		t.Fatalf("PutScene failed: %v", err)
+	}
+	if !stream.Next() {
+		t.Fatalf("expected membership event, err %v", stream.Err())
+	}
+	want := []starfleet.MembershipChange{{Group: "servers", AddedNodes: []string{"web"}}}
+	if e := stream.Event(); e.Revision != 3 || !reflect.DeepEqual(e.Membership, want) {
+		t.Errorf("membership mismatch: got %+v", e)
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// SceneStream reads change events for one scene. The first event is a
+// snapshot of the current revision. The stream ends when the scene is
+// deleted, the context is canceled or the connection drops; callers that
+// want to follow a scene indefinitely reopen it, receiving a fresh snapshot.
+// Presence events of other viewers are interleaved with the changes.
+// Streams opened with StreamMembership carry membership events instead.
+type SceneStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	event   starfleet.SceneEvent
+	err     error
+	closed  atomic.Bool
+}
+
+// StreamScene opens a change stream for a scene
+func (c *Client) StreamScene(ctx context.Context, id string) (*SceneStream, error) {
+	return c.openStream(ctx, scenePath(id)+"/events")
+}
+
+// StreamMembership opens a stream of membership events for the smart
+// groups of a scene, or only those named in groups. The first event adds
+// the current members of every group.
+func (c *Client) StreamMembership(ctx context.Context, id string, groups ...string) (*SceneStream, error) {
+	path := scenePath(id) + "/membership"
+	if len(groups) > 0 {
+		path += "?" + url.Values{"group": groups}.Encode()
+	}
+	return c.openStream(ctx, path)
+}
+
+// openStream opens a server-sent event stream of scene events
+func (c *Client) openStream(ctx context.Context, path string) (*SceneStream, error) {
+	resp, err := c.send(ctx, request{
+		method: http.MethodGet,
+		path:   path,
+		header: http.Header{"Accept": {"text/event-stream"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, decodeAPIError(resp.StatusCode, data)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	// Events carry whole scenes
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	return &SceneStream{body: resp.Body, scanner: scanner}, nil
+}
+
+// Next blocks until the next event arrives and reports whether there is one
+func (s *SceneStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var event starfleet.SceneEvent
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				s.err = fmt.Errorf("decode scene event: %w", err)
+				return false
+			}
+			s.event = event
+			return true
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// Comments, event names and IDs are implied by the JSON payload
+	}
+	if !s.closed.Load() {
+		s.err = s.scanner.Err()
+	}
+	return false
+}
+
+// Event returns the current event
+func (s *SceneStream) Event() starfleet.SceneEvent {
+	return s.event
+}
+
+// Err returns the error that ended the stream, if any. A stream closed by
+// the server or by Close reports nil.
+func (s *SceneStream) Err() error {
+	return s.err
+}
+
+// Close releases the connection
+func (s *SceneStream) Close() error {
+	s.closed.Store(true)
+	return s.body.Close()
+}
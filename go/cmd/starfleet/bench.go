@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hyperdrive-technology/starfleet-sdk-go/bench"
+)
+
+// benchmark measures scene operations with package bench and writes the
+// report as JSON, summarizing each result on standard error
+func benchmark(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	scales := flags.String("scales", "100,1000,10000", "comma-separated node counts of generated scenes, or none")
+	ops := flags.String("ops", "", "comma-separated operations to run (default all)")
+	iterations := flags.Int("n", bench.DefaultIterations, "timed iterations per operation")
+	seed := flags.Int64("seed", 0, "seed for generated scenes")
+	out := flags.String("out", "", "write the report to this file instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	opts := bench.Options{Files: flags.Args(), Iterations: *iterations, Seed: *seed, Scales: []int{}}
+	if *scales != "" && *scales != "none" {
+		for _, s := range strings.Split(*scales, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				fmt.Fprintf(stderr, "bench: invalid scale %q\n", s)
+				return exitUsage
+			}
+			opts.Scales = append(opts.Scales, n)
+		}
+	}
+	if *ops != "" {
+		opts.Operations = strings.Split(*ops, ",")
+	}
+	report, err := bench.Run(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, r := range report.Results {
+		if r.Error != "" {
+			fmt.Fprintf(stderr, "%s %s: error: %s\n", r.Scene, r.Operation, r.Error)
+			code = exitFailed
+			continue
+		}
+		fmt.Fprintf(stderr, "%s %s: median %s, p95 %s, %d allocs/op\n", r.Scene, r.Operation, r.Median, r.P95, r.AllocsPerOp)
+	}
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(stderr, "bench: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(stderr, "bench: %v\n", err)
+		return exitUsage
+	}
+	return code
+}
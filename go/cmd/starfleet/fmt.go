@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// formatScene formats scene DSL files, or converts scenes in other formats
+// to the DSL, and writes them to standard output or, with -w, back to DSL
+// files that changed
+func formatScene(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result back to the DSL files")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: starfleet fmt [-w] file...")
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, path := range flags.Args() {
+		var data []byte
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "fmt: %v\n", err)
+			return exitUsage
+		}
+
+		var formatted []byte
+		dsl := starfleet.DetectSceneFormat(path, data) == starfleet.SceneFormatDSL
+		if dsl {
+			formatted, err = starfleet.FormatSceneDSL(data)
+		} else {
+			var sf starfleet.SceneFile
+			if sf, err = starfleet.OpenReader(bytes.NewReader(data), path, starfleet.DefaultOpenOptions()); err == nil {
+				var buf bytes.Buffer
+				err = starfleet.EncodeSceneDSL(&buf, &sf)
+				formatted = buf.Bytes()
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "fmt: %s: %v\n", path, err)
+			code = exitFailed
+			continue
+		}
+
+		switch {
+		case !*write:
+			_, _ = stdout.Write(formatted)
+		case !dsl || path == "-":
+			// Converted scenes are never written over their source
+			fmt.Fprintf(stderr, "fmt: %s: -w only rewrites .sfd files\n", path)
+			code = exitFailed
+		case !bytes.Equal(formatted, data):
+			if err := os.WriteFile(path, formatted, 0o644); err != nil {
+				fmt.Fprintf(stderr, "fmt: %v\n", err)
+				return exitUsage
+			}
+			fmt.Fprintln(stdout, path)
+		}
+	}
+	return code
+}
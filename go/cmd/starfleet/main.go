@@ -0,0 +1,187 @@
+// Command starfleet works with scene files from the command line.
+//
+// Usage:
+//
+//	starfleet validate [flags] file...
+//	starfleet pipeline -config file [flags] [scene]
+//	starfleet fmt [-w] file...
+//	starfleet bench [flags] [scene...]
+//
+// validate checks scene files the way the scene service does before storing
+// them and exits non-zero when any has errors, so scene changes can be gated
+// in CI. Flags:
+//
+//	-format text|json|sarif|junit   output format (default text)
+//	-strict                         fail on warnings too
+//	-server URL                     validate with a scene service instead of locally
+//
+// Problems are reported with the line, column and JSONPath of the element
+// they are about. A file named "-" is read from standard input. The exit
+// code is 0 when all files pass, 1 when any fails and 2 on usage or read
+// errors.
+//
+// pipeline runs the stages declared in a starfleet.PipelineConfig file over
+// a scene, or over an empty scene when none is given, and writes the result
+// to standard output. Stage timings, notes and errors are reported on
+// standard error. The input scene may be in any format starfleet.Open
+// reads, such as gzipped JSON or NDJSON. Import stages can run the "scene"
+// importer, which reads a scene file the same way, and the "hubble"
+// importer, which reads Hubble flows; both take the file from the "path"
+// config value. Flags:
+//
+//	-config file   pipeline configuration (required)
+//	-out file      write the scene to a file instead
+//	-json          report stages as JSON
+//	-progress      print progress lines while the stages run
+//	-non-finite reject|clamp|drop
+//	               handle NaN and infinite numbers in the result
+//	               (default reject)
+//
+// The exit code is 1 when a stage fails or the result holds a NaN or
+// infinite number that -non-finite rejects.
+//
+// fmt prints scene DSL files (.sfd) in canonical form, and scenes in any
+// other format starfleet.Open reads as DSL, so JSON scenes can be moved to
+// the DSL. With -w it rewrites DSL files whose formatting changed instead
+// and lists them. The exit code is 1 when a file does not parse.
+//
+// bench times parsing, validation, layout, diffing and serving on generated
+// scenes and on any scene files given, using package bench, and writes the
+// report as JSON so runs of different releases can be compared. Flags:
+//
+//	-scales list   node counts of generated scenes, or none (default 100,1000,10000)
+//	-ops list      operations to run: parse, validate, layout, diff, serve
+//	-n count       timed iterations per operation (default 5)
+//	-seed n        seed for generated scenes
+//	-out file      write the report to a file instead
+//
+// The exit code is 1 when an operation fails.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+	"github.com/hyperdrive-technology/starfleet-sdk-go/client"
+)
+
+// Exit codes
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate":
+			return validate(ctx, args[1:], stdin, stdout, stderr)
+		case "pipeline":
+			return pipeline(ctx, args[1:], stdin, stdout, stderr)
+		case "fmt":
+			return formatScene(ctx, args[1:], stdin, stdout, stderr)
+		case "bench":
+			return benchmark(ctx, args[1:], stdin, stdout, stderr)
+		}
+	}
+	fmt.Fprintln(stderr, "usage: starfleet validate [-format text|json|sarif|junit] [-strict] [-server URL] file...")
+	fmt.Fprintln(stderr, "       starfleet pipeline -config file [-out file] [-json] [-progress] [-non-finite policy] [scene]")
+	fmt.Fprintln(stderr, "       starfleet fmt [-w] file...")
+	fmt.Fprintln(stderr, "       starfleet bench [-scales list] [-ops list] [-n count] [-seed n] [-out file] [scene...]")
+	return exitUsage
+}
+
+func validate(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: text, json, sarif or junit")
+	strict := flags.Bool("strict", false, "fail on warnings")
+	serverURL := flags.String("server", "", "validate with the scene service at this URL")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "validate: no files given")
+		return exitUsage
+	}
+	switch *format {
+	case "text", "json", "sarif", "junit":
+	default:
+		fmt.Fprintf(stderr, "validate: unknown format %q\n", *format)
+		return exitUsage
+	}
+
+	var c *client.Client
+	if *serverURL != "" {
+		c = client.New(*serverURL)
+	}
+	results := make([]starfleet.FileValidation, 0, flags.NArg())
+	for _, path := range flags.Args() {
+		result, err := validateFile(ctx, c, path, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "validate: %v\n", err)
+			return exitUsage
+		}
+		results = append(results, result)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(results)
+	case "sarif":
+		_ = starfleet.EncodeSARIF(stdout, results)
+	case "junit":
+		_ = starfleet.EncodeJUnit(stdout, results)
+	default:
+		for _, f := range results {
+			for _, d := range f.Diagnostics {
+				fmt.Fprintf(stdout, "%s: %s: %s\n", d.Location(f.Path), d.Severity, d.Message)
+			}
+		}
+	}
+
+	for _, f := range results {
+		if !f.Result.Valid || (*strict && len(f.Result.Warnings) > 0) {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// validateFile checks one file locally, or with the service when c is set.
+// Files that are not valid JSON scenes are reported as validation errors.
+func validateFile(ctx context.Context, c *client.Client, path string, stdin io.Reader) (starfleet.FileValidation, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return starfleet.FileValidation{}, err
+	}
+	var sf starfleet.SceneFile
+	if c == nil || json.Unmarshal(data, &sf) != nil {
+		return starfleet.NewFileValidation(path, data), nil
+	}
+	result, err := c.ValidateScene(ctx, &sf)
+	if err != nil {
+		return starfleet.FileValidation{}, err
+	}
+	diags := starfleet.Diagnose(&sf, result)
+	starfleet.LocateDiagnostics(data, diags)
+	return starfleet.FileValidation{Path: path, Result: result, Diagnostics: diags}, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+	"github.com/hyperdrive-technology/starfleet-sdk-go/bench"
+	"github.com/hyperdrive-technology/starfleet-sdk-go/server"
+)
+
+func writeScene(t *testing.T, dir, name string, sf starfleet.SceneFile) string {
+	t.Helper()
+	data, err := json.Marshal(sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestValidate tests exit codes and output formats of the validate command
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	good := starfleet.NewSceneFile("Good")
+	good.AddNode(starfleet.SceneNode{ID: "api", Type: "server", Name: "API", Transform: starfleet.NewTransform()})
+	warned := starfleet.NewSceneFile("Warned")
+	warned.AddNode(starfleet.SceneNode{ID: "api", Type: "server", Transform: starfleet.NewTransform()})
+	bad := starfleet.NewSceneFile("Bad")
+	bad.AddEdge(starfleet.SceneEdge{ID: "x-y", Source: "x", Target: "y"})
+	goodPath := writeScene(t, dir, "good.json", good)
+	warnedPath := writeScene(t, dir, "warned.json", warned)
+	badPath := writeScene(t, dir, "bad.json", bad)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"valid", []string{"validate", goodPath}, exitOK},
+		{"warnings", []string{"validate", goodPath, warnedPath}, exitOK},
+		{"strict", []string{"validate", "-strict", warnedPath}, exitFailed},
+		{"errors", []string{"validate", goodPath, badPath}, exitFailed},
+		{"missing file", []string{"validate", filepath.Join(dir, "nope.json")}, exitUsage},
+		{"no files", []string{"validate"}, exitUsage},
+		{"bad format", []string{"validate", "-format", "xml", goodPath}, exitUsage},
+		{"no command", nil, exitUsage},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if got := run(ctx, tt.args, nil, &stdout, &stderr); got != tt.want {
+			t.Errorf("%s: exit code mismatch: got %d, want %d (%s)", tt.name, got, tt.want, stderr.String())
+		}
+	}
+
+	var stdout bytes.Buffer
+	run(ctx, []string{"validate", badPath}, nil, &stdout, &bytes.Buffer{})
+	if !strings.Contains(stdout.String(), badPath+":1:") || !strings.Contains(stdout.String(), ": error: Edge x-y references non-existent source node: x") {
+		t.Errorf("text output mismatch: got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	run(ctx, []string{"validate", "-format", "sarif", "-"}, strings.NewReader(`{"version": 1}`), &stdout, &bytes.Buffer{})
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				Level string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &log); err != nil || log.Version != "2.1.0" || len(log.Runs[0].Results) != 1 || log.Runs[0].Results[0].Level != "error" {
+		t.Errorf("sarif output mismatch: got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	run(ctx, []string{"validate", "-format", "junit", goodPath, badPath}, nil, &stdout, &bytes.Buffer{})
+	if !strings.Contains(stdout.String(), `<testsuites name="starfleet validate" tests="3" failures="2">`) || !strings.Contains(stdout.String(), `<testcase name="valid" classname="`+goodPath+`">`) {
+		t.Errorf("junit output mismatch: got %s", stdout.String())
+	}
+}
+
+// TestValidate_Server tests validating with a scene service
+func TestValidate_Server(t *testing.T) {
+	ts := httptest.NewServer(server.New(starfleet.NewMemorySceneStore()))
+	defer ts.Close()
+	bad := starfleet.NewSceneFile("Bad")
+	bad.AddEdge(starfleet.SceneEdge{ID: "x-y", Source: "x", Target: "y"})
+	path := writeScene(t, t.TempDir(), "bad.json", bad)
+
+	var stdout, stderr bytes.Buffer
+	if got := run(context.Background(), []string{"validate", "-server", ts.URL, "-format", "json", path}, nil, &stdout, &stderr); got != exitFailed {
+		t.Errorf("exit code mismatch: got %d, want %d (%s)", got, exitFailed, stderr.String())
+	}
+	var results []starfleet.FileValidation
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil || len(results) != 1 || len(results[0].Result.Errors) != 2 {
+		t.Errorf("json output mismatch: got %s", stdout.String())
+	}
+}
+
+// TestPipeline tests running a configured pipeline over a scene file
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sf := starfleet.NewSceneFile("Scene")
+	sf.AddNode(starfleet.SceneNode{ID: "api", Type: "server", Name: "API", Transform: starfleet.NewTransform()})
+	scenePath := writeScene(t, dir, "scene.json", sf)
+	config := filepath.Join(dir, "pipeline.json")
+	os.WriteFile(config, []byte(`{"stages": [
+		{"type": "import", "options": {"importer": "scene", "config": {"path": "`+scenePath+`"}}},
+		{"type": "defaults", "options": {"tags": ["managed"]}},
+		{"type": "validate"}
+	]}`), 0o644)
+
+	var stdout, stderr bytes.Buffer
+	if got := run(ctx, []string{"pipeline", "-config", config}, nil, &stdout, &stderr); got != exitOK {
+		t.Fatalf("exit mismatch: got %d, want %d: %s", got, exitOK, stderr.String())
+	}
+	var out starfleet.SceneFile
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil || len(out.Scene.Nodes) != 1 || out.Scene.Nodes[0].Tags[0] != "managed" {
+		t.Errorf("scene mismatch: got %s, %v", stdout.String(), err)
+	}
+	if !strings.Contains(stderr.String(), "defaults (defaults):") {
+		t.Errorf("report mismatch: got %q", stderr.String())
+	}
+
+	// An empty input scene fails validation
+	strict := filepath.Join(dir, "strict.json")
+	os.WriteFile(strict, []byte(`{"stages": [{"type": "validate"}]}`), 0o644)
+	stdout.Reset()
+	stderr.Reset()
+	if got := run(ctx, []string{"pipeline", "-config", strict}, nil, &stdout, &stderr); got != exitFailed || stdout.Len() != 0 {
+		t.Errorf("exit mismatch: got %d, want %d", got, exitFailed)
+	}
+	if got := run(ctx, []string{"pipeline", "-config", strict, scenePath}, nil, &stdout, &stderr); got != exitOK {
+		t.Errorf("exit mismatch: got %d, want %d: %s", got, exitOK, stderr.String())
+	}
+	// Input scenes are read in any format starfleet.Open sniffs
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	starfleet.EncodeSceneNDJSON(zw, &sf)
+	zw.Close()
+	gzPath := filepath.Join(dir, "scene.gz")
+	os.WriteFile(gzPath, gz.Bytes(), 0o644)
+	if got := run(ctx, []string{"pipeline", "-config", strict, gzPath}, nil, &stdout, &stderr); got != exitOK {
+		t.Errorf("exit mismatch: got %d, want %d: %s", got, exitOK, stderr.String())
+	}
+	if got := run(ctx, []string{"pipeline"}, nil, &stdout, &stderr); got != exitUsage {
+		t.Errorf("exit mismatch: got %d, want %d", got, exitUsage)
+	}
+
+	// Progress lines name each stage with the share of the run done
+	stderr.Reset()
+	if got := run(ctx, []string{"pipeline", "-progress", "-config", config}, nil, &stdout, &stderr); got != exitOK {
+		t.Fatalf("exit mismatch: got %d, want %d: %s", got, exitOK, stderr.String())
+	}
+	for _, want := range []string{"[  0%] import\n", "[ 33%] defaults\n", "[100%] validate\n"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("progress mismatch: got %q, want %q", stderr.String(), want)
+		}
+	}
+}
+
+// TestFormat tests formatting DSL files and converting other scenes
+func TestFormat(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	messy := filepath.Join(dir, "scene.sfd")
+	os.WriteFile(messy, []byte("name=\"Scene\"\nnode \"api\" { type = \"server\" }\n"), 0o644)
+	want := "name = \"Scene\"\nnode \"api\" {\n  type = \"server\"\n}\n"
+
+	var stdout, stderr bytes.Buffer
+	if got := run(ctx, []string{"fmt", messy}, nil, &stdout, &stderr); got != exitOK || stdout.String() != want {
+		t.Fatalf("format mismatch: got %d %q, want %q: %s", got, stdout.String(), want, stderr.String())
+	}
+
+	// -w rewrites changed files and lists them
+	stdout.Reset()
+	if got := run(ctx, []string{"fmt", "-w", messy}, nil, &stdout, &stderr); got != exitOK || stdout.String() != messy+"\n" {
+		t.Errorf("rewrite mismatch: got %d %q", got, stdout.String())
+	}
+	if data, _ := os.ReadFile(messy); string(data) != want {
+		t.Errorf("file mismatch: got %q, want %q", data, want)
+	}
+	stdout.Reset()
+	if got := run(ctx, []string{"fmt", "-w", messy}, nil, &stdout, &stderr); got != exitOK || stdout.Len() != 0 {
+		t.Errorf("rewrite mismatch: got %d %q", got, stdout.String())
+	}
+
+	// Other formats convert to the DSL but are never rewritten
+	sf := starfleet.NewSceneFile("Scene")
+	sf.AddNode(starfleet.SceneNode{ID: "api", Type: "server", Name: "API", Transform: starfleet.NewTransform()})
+	scenePath := writeScene(t, dir, "scene.json", sf)
+	stdout.Reset()
+	if got := run(ctx, []string{"fmt", scenePath}, nil, &stdout, &stderr); got != exitOK || !strings.Contains(stdout.String(), "node \"api\" {") {
+		t.Errorf("convert mismatch: got %d %q: %s", got, stdout.String(), stderr.String())
+	}
+	if got := run(ctx, []string{"fmt", "-w", scenePath}, nil, &stdout, &stderr); got != exitFailed {
+		t.Errorf("exit mismatch: got %d, want %d", got, exitFailed)
+	}
+
+	os.WriteFile(messy, []byte("node \"api\" {"), 0o644)
+	stderr.Reset()
+	if got := run(ctx, []string{"fmt", messy}, nil, &stdout, &stderr); got != exitFailed || !strings.Contains(stderr.String(), "unclosed {") {
+		t.Errorf("exit mismatch: got %d %q, want %d", got, stderr.String(), exitFailed)
+	}
+	if got := run(ctx, []string{"fmt"}, nil, &stdout, &stderr); got != exitUsage {
+		t.Errorf("exit mismatch: got %d, want %d", got, exitUsage)
+	}
+}
+
+// TestBench tests the benchmark report and its exit codes
+func TestBench(t *testing.T) {
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "report.json")
+	var stdout, stderr bytes.Buffer
+	if got := run(ctx, []string{"bench", "-scales", "10", "-ops", "parse,diff", "-n", "1", "-out", out}, nil, &stdout, &stderr); got != exitOK {
+		t.Fatalf("exit mismatch: got %d, want %d: %s", got, exitOK, stderr.String())
+	}
+	var report bench.Report
+	data, _ := os.ReadFile(out)
+	if err := json.Unmarshal(data, &report); err != nil || len(report.Results) != 2 || report.Results[1].Operation != bench.OpDiff {
+		t.Errorf("report mismatch: got %s, %v", data, err)
+	}
+	if !strings.Contains(stderr.String(), "generated-10 parse: median ") {
+		t.Errorf("summary mismatch: got %q", stderr.String())
+	}
+
+	for _, args := range [][]string{{"bench", "-scales", "x"}, {"bench", "-ops", "render"}} {
+		if got := run(ctx, args, nil, &stdout, &stderr); got != exitUsage {
+			t.Errorf("exit mismatch for %v: got %d, want %d", args, got, exitUsage)
+		}
+	}
+}
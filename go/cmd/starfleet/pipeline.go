@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// cliImporters are the importers pipeline import stages can run. Both read
+// the file named by the "path" config value.
+var cliImporters = map[string]starfleet.Importer{
+	"scene": starfleet.ImporterFunc(func(ctx context.Context, config starfleet.ImporterConfig, progress starfleet.ProgressFunc) (starfleet.ImportResult, error) {
+		path, err := configPath(config)
+		if err != nil {
+			return starfleet.ImportResult{}, err
+		}
+		sf, err := starfleet.Open(path)
+		if err != nil {
+			return starfleet.ImportResult{}, err
+		}
+		return starfleet.ImportResult{Scene: sf}, nil
+	}),
+	"hubble": starfleet.ImporterFunc(func(ctx context.Context, config starfleet.ImporterConfig, progress starfleet.ProgressFunc) (starfleet.ImportResult, error) {
+		data, err := readConfigPath(config)
+		if err != nil {
+			return starfleet.ImportResult{}, err
+		}
+		var h starfleet.HubbleImporter
+		return h.Import(bytes.NewReader(data), config)
+	}),
+}
+
+// configPath returns an importer's "path" config value
+func configPath(config starfleet.ImporterConfig) (string, error) {
+	path, _ := config["path"].(string)
+	if path == "" {
+		return "", errors.New(`importer config needs a "path"`)
+	}
+	return path, nil
+}
+
+// readConfigPath reads the file named by an importer's "path" config value
+func readConfigPath(config starfleet.ImporterConfig) ([]byte, error) {
+	path, err := configPath(config)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func pipeline(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pipeline", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "", "pipeline configuration file")
+	out := flags.String("out", "", "write the scene to this file instead of standard output")
+	reportJSON := flags.Bool("json", false, "report stages as JSON")
+	showProgress := flags.Bool("progress", false, "report progress on standard error as the stages run")
+	nonFinite := flags.String("non-finite", string(starfleet.NonFiniteReject), "handle NaN and infinite numbers: reject, clamp or drop")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	policy, err := starfleet.ParseNonFinitePolicy(*nonFinite)
+	if *configPath == "" || flags.NArg() > 1 || err != nil {
+		fmt.Fprintln(stderr, "usage: starfleet pipeline -config file [-out file] [-json] [-progress] [-non-finite policy] [scene]")
+		return exitUsage
+	}
+
+	f, err := os.Open(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "pipeline: %v\n", err)
+		return exitUsage
+	}
+	cfg, err := starfleet.LoadPipelineConfig(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "pipeline: %v\n", err)
+		return exitUsage
+	}
+	p, err := starfleet.NewPipeline(cfg, starfleet.DefaultStageFactories(cliImporters))
+	if err != nil {
+		fmt.Fprintf(stderr, "pipeline: %v\n", err)
+		return exitUsage
+	}
+
+	// Without an input scene the pipeline starts from an empty one, as
+	// when its first stage imports
+	sf := starfleet.NewSceneFile("")
+	if flags.NArg() == 1 {
+		// Any format starfleet.Open reads, sniffed from the content
+		if path := flags.Arg(0); path == "-" {
+			sf, err = starfleet.OpenReader(stdin, "", starfleet.DefaultOpenOptions())
+		} else {
+			sf, err = starfleet.Open(path)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "pipeline: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	if *showProgress {
+		ctx = starfleet.WithProgress(ctx, starfleet.ProgressReporterFunc(func(pr starfleet.Progress) {
+			line := fmt.Sprintf("[%3.0f%%] %s", pr.Percent, pr.Stage)
+			if pr.Message != "" {
+				line += ": " + pr.Message
+				if pr.Total > 0 {
+					line += fmt.Sprintf(" %d/%d", pr.Processed, pr.Total)
+				}
+			}
+			fmt.Fprintln(stderr, line)
+		}))
+	}
+	report, runErr := p.Run(ctx, &sf)
+	if *reportJSON {
+		enc := json.NewEncoder(stderr)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		for _, s := range report.Stages {
+			fmt.Fprintf(stderr, "%s (%s): %s\n", s.Name, s.Type, s.Duration)
+			for _, note := range s.Notes {
+				fmt.Fprintf(stderr, "  %s\n", note)
+			}
+			if s.Error != "" {
+				fmt.Fprintf(stderr, "  error: %s\n", s.Error)
+			}
+		}
+	}
+	if runErr != nil {
+		return exitFailed
+	}
+
+	data, err := starfleet.MarshalScene(&sf, starfleet.MarshalOptions{NonFinite: policy, Indent: "  "})
+	if err != nil {
+		fmt.Fprintf(stderr, "pipeline: %v\n", err)
+		return exitFailed
+	}
+	data = append(data, '\n')
+	if *out != "" {
+		err = os.WriteFile(*out, data, 0o644)
+	} else {
+		_, err = stdout.Write(data)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "pipeline: %v\n", err)
+		return exitFailed
+	}
+	return exitOK
+}
@@ -0,0 +1,636 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// COMPATIBILITY
+// =============================================================================
+
+// SchemaVersion is the scene file format version written by this SDK
+const SchemaVersion = "0.2.0"
+
+// ErrInvalidVersion is returned for malformed versions and version ranges
+var ErrInvalidVersion = errors.New("invalid version")
+
+// Capability names an optional construct a reader must understand to render
+// a scene faithfully. Scenes list the capabilities they use in their header.
+type Capability string
+
+const (
+	CapabilityLabels            Capability = "labels"
+	CapabilityParticles         Capability = "particles"
+	CapabilityAttachments       Capability = "attachments"
+	CapabilityAccessibility     Capability = "accessibility"
+	CapabilityLocalization      Capability = "localization"
+	CapabilityPorts             Capability = "ports"
+	CapabilityEdgeSemantics     Capability = "edge-semantics"
+	CapabilityEdgeRouting       Capability = "edge-routing"
+	CapabilitySceneRefs         Capability = "scene-refs"
+	CapabilityResourceLibraries Capability = "resource-libraries"
+	CapabilityMeshes            Capability = "meshes"
+	CapabilityMeshCompression   Capability = "mesh-compression"
+	CapabilityLODs              Capability = "lods"
+	CapabilityTextureAtlas      Capability = "texture-atlas"
+	CapabilityPhysics           Capability = "physics"
+	CapabilityLifecycle         Capability = "lifecycle"
+	CapabilitySLOs              Capability = "slos"
+	CapabilityPanels            Capability = "panels"
+	CapabilityExpiry            Capability = "expiry"
+	CapabilityRevisions         Capability = "revisions"
+	CapabilityRequirements      Capability = "requirements"
+)
+
+// SchemaRelease describes a scene format version and the capabilities it
+// introduced
+type SchemaRelease struct {
+	Version string       `json:"version" validate:"required"`
+	Added   []Capability `json:"added,omitempty"`
+}
+
+// schemaReleases is the compatibility matrix, oldest first. Version 0.1.0
+// is the format shared with the TypeScript SDK.
+var schemaReleases = []SchemaRelease{
+	{Version: "0.1.0"},
+	{Version: "0.2.0", Added: []Capability{
+		CapabilityLabels, CapabilityParticles, CapabilityAttachments, CapabilityAccessibility,
+		CapabilityLocalization, CapabilityPorts, CapabilityEdgeSemantics, CapabilityEdgeRouting,
+		CapabilitySceneRefs, CapabilityResourceLibraries, CapabilityMeshes, CapabilityMeshCompression,
+		CapabilityLODs, CapabilityTextureAtlas, CapabilityPhysics, CapabilityLifecycle,
+		CapabilitySLOs, CapabilityPanels, CapabilityExpiry, CapabilityRevisions,
+		CapabilityRequirements,
+	}},
+}
+
+// SchemaReleases returns the compatibility matrix of known format versions,
+// oldest first
+func SchemaReleases() []SchemaRelease {
+	releases := make([]SchemaRelease, len(schemaReleases))
+	for i, r := range schemaReleases {
+		releases[i] = SchemaRelease{Version: r.Version, Added: slices.Clone(r.Added)}
+	}
+	return releases
+}
+
+// CapabilitiesFor returns every capability a reader of the given format
+// version understands. Versions between releases inherit the capabilities
+// of the latest release before them.
+func CapabilitiesFor(version string) ([]Capability, error) {
+	v, err := ParseSemVer(version)
+	if err != nil {
+		return nil, err
+	}
+	var caps []Capability
+	for _, r := range schemaReleases {
+		if mustSemVer(r.Version).Compare(v) > 0 {
+			break
+		}
+		caps = append(caps, r.Added...)
+	}
+	return caps, nil
+}
+
+// DetectCapabilities returns the capabilities a scene uses, sorted
+func DetectCapabilities(sf *SceneFile) []Capability {
+	used := make(map[Capability]bool)
+	geometry := func(g *Geometry) {
+		if g == nil {
+			return
+		}
+		if g.Mesh != nil {
+			used[CapabilityMeshes] = true
+			if g.Mesh.IsCompressed() {
+				used[CapabilityMeshCompression] = true
+			}
+		}
+		if len(g.LODs) > 0 {
+			used[CapabilityLODs] = true
+		}
+	}
+	material := func(m *Material) {
+		if m != nil && m.TextureRegion != nil {
+			used[CapabilityTextureAtlas] = true
+		}
+	}
+
+	md := &sf.Metadata
+	if md.Locale != "" || len(md.Localizations) > 0 {
+		used[CapabilityLocalization] = true
+	}
+	if md.Lifecycle != nil {
+		used[CapabilityLifecycle] = true
+	}
+	if sf.Requirements != nil {
+		used[CapabilityRequirements] = true
+	}
+	if len(sf.Materials) > 0 || len(sf.Geometries) > 0 {
+		used[CapabilityResourceLibraries] = true
+	}
+	for _, m := range sf.Materials {
+		material(&m)
+	}
+	for _, g := range sf.Geometries {
+		geometry(&g)
+	}
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		geometry(n.Geometry)
+		material(n.Material)
+		if n.GeometryRef != "" || n.MaterialRef != "" {
+			used[CapabilityResourceLibraries] = true
+		}
+		if n.Label != nil {
+			used[CapabilityLabels] = true
+		}
+		if len(n.Particles) > 0 {
+			used[CapabilityParticles] = true
+		}
+		if len(n.Attachments) > 0 {
+			used[CapabilityAttachments] = true
+		}
+		if n.Accessibility != nil {
+			used[CapabilityAccessibility] = true
+		}
+		if len(n.Localizations) > 0 {
+			used[CapabilityLocalization] = true
+		}
+		if len(n.Ports) > 0 {
+			used[CapabilityPorts] = true
+		}
+		if n.Physics != nil {
+			used[CapabilityPhysics] = true
+		}
+		if n.Ref != nil {
+			used[CapabilitySceneRefs] = true
+		}
+		if len(n.SLOs) > 0 {
+			used[CapabilitySLOs] = true
+		}
+		if len(n.Panels) > 0 {
+			used[CapabilityPanels] = true
+		}
+		if n.ExpiresAt != nil {
+			used[CapabilityExpiry] = true
+		}
+		if n.Revision != 0 {
+			used[CapabilityRevisions] = true
+		}
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		if e.Label != nil {
+			used[CapabilityLabels] = true
+		}
+		if len(e.Particles) > 0 {
+			used[CapabilityParticles] = true
+		}
+		if len(e.Localizations) > 0 {
+			used[CapabilityLocalization] = true
+		}
+		if e.SourcePort != "" || e.TargetPort != "" {
+			used[CapabilityPorts] = true
+		}
+		if e.Direction != "" || e.Key != "" || e.Weight != 0 {
+			used[CapabilityEdgeSemantics] = true
+		}
+		if len(e.Waypoints) > 0 {
+			used[CapabilityEdgeRouting] = true
+		}
+		if e.Joint != nil {
+			used[CapabilityPhysics] = true
+		}
+		if e.TargetScene != "" {
+			used[CapabilitySceneRefs] = true
+		}
+		if e.ExpiresAt != nil {
+			used[CapabilityExpiry] = true
+		}
+		if e.Revision != 0 {
+			used[CapabilityRevisions] = true
+		}
+	}
+
+	caps := make([]Capability, 0, len(used))
+	for c := range used {
+		caps = append(caps, c)
+	}
+	slices.Sort(caps)
+	return caps
+}
+
+// UpdateCapabilities sets the header's capability flags to those the scene
+// uses
+func (sf *SceneFile) UpdateCapabilities() {
+	sf.Capabilities = DetectCapabilities(sf)
+}
+
+// UnsupportedCapabilities returns the capabilities declared in the header
+// or used by the scene that a reader of the given format version does not
+// understand
+func UnsupportedCapabilities(sf *SceneFile, version string) ([]Capability, error) {
+	supported, err := CapabilitiesFor(version)
+	if err != nil {
+		return nil, err
+	}
+	var missing []Capability
+	for _, c := range slices.Concat(sf.Capabilities, DetectCapabilities(sf)) {
+		if !slices.Contains(supported, c) && !slices.Contains(missing, c) {
+			missing = append(missing, c)
+		}
+	}
+	slices.Sort(missing)
+	return missing, nil
+}
+
+// CompatibilityAction is what a reader should do with a scene
+type CompatibilityAction string
+
+const (
+	// CompatibilityAccept means the scene can be read as is
+	CompatibilityAccept CompatibilityAction = "accept"
+	// CompatibilityDowngrade means the scene is newer than the reader; it
+	// can be served after downgrading to Target
+	CompatibilityDowngrade CompatibilityAction = "downgrade"
+	// CompatibilityUpgrade means the scene is older than the reader accepts;
+	// it can be served after upgrading to Target
+	CompatibilityUpgrade CompatibilityAction = "upgrade"
+	// CompatibilityReject means no known format version satisfies the reader
+	CompatibilityReject CompatibilityAction = "reject"
+)
+
+// CompatibilityResult is the outcome of a compatibility check. Target is the
+// newest known format version within the range, and Unsupported lists the
+// capabilities a Target reader would not understand.
+type CompatibilityResult struct {
+	SceneVersion string              `json:"sceneVersion"`
+	Range        string              `json:"range"`
+	Action       CompatibilityAction `json:"action" validate:"required"`
+	Target       string              `json:"target,omitempty"`
+	Unsupported  []Capability        `json:"unsupported,omitempty"`
+	Reason       string              `json:"reason,omitempty"`
+}
+
+// Compatible reports whether the scene can be read without conversion
+func (r CompatibilityResult) Compatible() bool {
+	return r.Action == CompatibilityAccept
+}
+
+// CheckCompatibility decides whether a scene of sceneVersion can be given to
+// a reader accepting sdkRange, an npm-style range such as "^0.1.0",
+// ">=0.1.0 <0.3.0" or "0.1.x || 0.2.x".
+func CheckCompatibility(sceneVersion, sdkRange string) (CompatibilityResult, error) {
+	result := CompatibilityResult{SceneVersion: sceneVersion, Range: sdkRange}
+	v, err := ParseSemVer(sceneVersion)
+	if err != nil {
+		return result, err
+	}
+	r, err := ParseVersionRange(sdkRange)
+	if err != nil {
+		return result, err
+	}
+
+	var target *SemVer
+	for _, release := range schemaReleases {
+		if rv := mustSemVer(release.Version); r.Contains(rv) {
+			target = &rv
+		}
+	}
+	if target != nil {
+		result.Target = target.String()
+	}
+	switch {
+	case r.Contains(v):
+		result.Action = CompatibilityAccept
+	case target == nil:
+		result.Action = CompatibilityReject
+		result.Reason = fmt.Sprintf("no known format version satisfies %s", sdkRange)
+	case v.Compare(*target) > 0:
+		result.Action = CompatibilityDowngrade
+		result.Reason = fmt.Sprintf("scene version %s is newer than %s", sceneVersion, sdkRange)
+	default:
+		result.Action = CompatibilityUpgrade
+		result.Reason = fmt.Sprintf("scene version %s is older than %s", sceneVersion, sdkRange)
+	}
+	return result, nil
+}
+
+// CheckSceneCompatibility is CheckCompatibility for a scene that also takes
+// its capabilities into account: a scene in an accepted version that uses
+// constructs the newest accepted format lacks still needs a downgrade.
+func CheckSceneCompatibility(sf *SceneFile, sdkRange string) (CompatibilityResult, error) {
+	result, err := CheckCompatibility(sf.Version, sdkRange)
+	if err != nil || result.Target == "" {
+		return result, err
+	}
+	result.Unsupported, err = UnsupportedCapabilities(sf, result.Target)
+	if err != nil {
+		return result, err
+	}
+	if len(result.Unsupported) > 0 && result.Action == CompatibilityAccept {
+		result.Action = CompatibilityDowngrade
+		result.Reason = fmt.Sprintf("scene uses capabilities unknown to %s", result.Target)
+	}
+	return result, nil
+}
+
+// =============================================================================
+// SEMANTIC VERSIONS
+// =============================================================================
+
+// SemVer is a semantic version. Build metadata is discarded.
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// ParseSemVer parses versions such as "1.2.3", "v1.2.3" and "1.2.3-rc.1"
+func ParseSemVer(s string) (SemVer, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return SemVer{}, fmt.Errorf("%w: %q", ErrInvalidVersion, raw)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("%w: %q", ErrInvalidVersion, raw)
+		}
+		nums[i] = n
+	}
+	return SemVer{Major: nums[0], Minor: nums[1], Patch: nums[2], Prerelease: pre}, nil
+}
+
+func mustSemVer(s string) SemVer {
+	v, err := ParseSemVer(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String formats the version
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than o.
+// A prerelease sorts before its release.
+func (v SemVer) Compare(o SemVer) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease orders dot-separated prerelease identifiers; numeric
+// identifiers compare numerically and sort before alphanumeric ones
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// versionComparator is one bound of a range
+type versionComparator struct {
+	op      string
+	version SemVer
+}
+
+func (c versionComparator) matches(v SemVer) bool {
+	d := v.Compare(c.version)
+	switch c.op {
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	case "<":
+		return d < 0
+	case "<=":
+		return d <= 0
+	}
+	return d == 0
+}
+
+// VersionRange is a set of versions in npm range syntax: comparators
+// (>, >=, <, <=, =), caret and tilde ranges, x-ranges such as 1.2.x or *,
+// hyphen ranges and alternatives joined by ||. As in npm, prereleases only
+// match comparators that name a prerelease of the same version.
+type VersionRange struct {
+	raw  string
+	sets [][]versionComparator
+}
+
+// ParseVersionRange parses an npm-style version range
+func ParseVersionRange(s string) (VersionRange, error) {
+	r := VersionRange{raw: s}
+	for _, alt := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(strings.TrimSpace(alt))
+		if err != nil {
+			return VersionRange{}, fmt.Errorf("%w: range %q: %v", ErrInvalidVersion, s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// String returns the range as written
+func (r VersionRange) String() string {
+	return r.raw
+}
+
+// Contains reports whether v is in the range
+func (r VersionRange) Contains(v SemVer) bool {
+	for _, set := range r.sets {
+		if comparatorSetMatches(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func comparatorSetMatches(set []versionComparator, v SemVer) bool {
+	prereleaseAllowed := v.Prerelease == ""
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+		if c.version.Prerelease != "" && c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			prereleaseAllowed = true
+		}
+	}
+	return prereleaseAllowed
+}
+
+func parseComparatorSet(s string) ([]versionComparator, error) {
+	if s == "" || s == "*" || s == "x" {
+		return []versionComparator{{op: ">=", version: SemVer{}}}, nil
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 3 && fields[1] == "-" {
+		lo, _, err := parsePartial(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		hi, n, err := parsePartial(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		set := []versionComparator{{">=", lo}}
+		if n == 3 {
+			return append(set, versionComparator{"<=", hi}), nil
+		}
+		return append(set, versionComparator{"<", bumpPartial(hi, n)}), nil
+	}
+
+	var set []versionComparator
+	for _, f := range fields {
+		comparators, err := parseComparator(f)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+// parseComparator expands one range token into plain comparators
+func parseComparator(token string) ([]versionComparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(token, prefix) {
+			op, token = prefix, strings.TrimPrefix(token, prefix)
+			break
+		}
+	}
+	v, n, err := parsePartial(token)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "^":
+		// Allow changes that keep the leftmost non-zero component
+		upper := SemVer{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && v.Minor == 0 && n == 3:
+			upper = SemVer{Patch: v.Patch + 1}
+		case v.Major == 0 && n >= 2:
+			upper = SemVer{Minor: v.Minor + 1}
+		}
+		return []versionComparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		if n == 1 {
+			return []versionComparator{{">=", v}, {"<", SemVer{Major: v.Major + 1}}}, nil
+		}
+		return []versionComparator{{">=", v}, {"<", SemVer{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	case "", "=":
+		if n == 3 {
+			return []versionComparator{{"=", v}}, nil
+		}
+		return []versionComparator{{">=", v}, {"<", bumpPartial(v, n)}}, nil
+	case ">":
+		if n < 3 {
+			return []versionComparator{{">=", bumpPartial(v, n)}}, nil
+		}
+	case "<=":
+		if n < 3 {
+			return []versionComparator{{"<", bumpPartial(v, n)}}, nil
+		}
+	}
+	return []versionComparator{{op, v}}, nil
+}
+
+// parsePartial parses a possibly partial version such as 1, 1.2 or 1.2.x
+// and returns how many components were given
+func parsePartial(s string) (SemVer, int, error) {
+	s = strings.TrimPrefix(s, "v")
+	core, pre, _ := strings.Cut(s, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 || core == "" {
+		return SemVer{}, 0, fmt.Errorf("bad version %q", s)
+	}
+	var nums [3]int
+	n := 0
+	for _, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		num, err := strconv.Atoi(p)
+		if err != nil || num < 0 {
+			return SemVer{}, 0, fmt.Errorf("bad version %q", s)
+		}
+		nums[n] = num
+		n++
+	}
+	if n == 0 {
+		return SemVer{}, 0, fmt.Errorf("bad version %q", s)
+	}
+	v := SemVer{Major: nums[0], Minor: nums[1], Patch: nums[2]}
+	if n == 3 {
+		v.Prerelease = pre
+	}
+	return v, n, nil
+}
+
+// bumpPartial returns the first version after all versions matching a
+// partial version with n components
+func bumpPartial(v SemVer, n int) SemVer {
+	if n == 1 {
+		return SemVer{Major: v.Major + 1}
+	}
+	return SemVer{Major: v.Major, Minor: v.Minor + 1}
+}
@@ -0,0 +1,126 @@
+package starfleet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestSemVer_Compare tests version ordering including prereleases
+func TestSemVer_Compare(t *testing.T) {
+	ordered := []string{"0.1.0", "0.2.0-alpha", "0.2.0-alpha.2", "0.2.0-alpha.10", "0.2.0-beta", "0.2.0", "0.10.0", "1.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		a, err := ParseSemVer(ordered[i-1])
+		if err != nil {
+			t.Fatalf("ParseSemVer(%q) failed: %v", ordered[i-1], err)
+		}
+		b := mustSemVer(ordered[i])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+	if v := mustSemVer("v1.2.3+build.5"); v.String() != "1.2.3" {
+		t.Errorf("build metadata mismatch: got %s", v)
+	}
+	for _, bad := range []string{"", "1.2", "1.2.x", "a.b.c"} {
+		if _, err := ParseSemVer(bad); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("ParseSemVer(%q): expected ErrInvalidVersion, got %v", bad, err)
+		}
+	}
+}
+
+// TestVersionRange tests npm-style range matching
+func TestVersionRange(t *testing.T) {
+	tests := []struct {
+		rng string
+		in  []string
+		out []string
+	}{
+		{"^0.1.0", []string{"0.1.0", "0.1.9"}, []string{"0.2.0", "0.0.9"}},
+		{"^1.2.0", []string{"1.2.0", "1.9.0"}, []string{"2.0.0", "1.1.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{">=0.1.0 <0.3.0", []string{"0.1.0", "0.2.5"}, []string{"0.3.0", "0.0.1"}},
+		{"0.1.x || 0.3.x", []string{"0.1.4", "0.3.0"}, []string{"0.2.0"}},
+		{"1.0.0 - 1.2", []string{"1.0.0", "1.2.7"}, []string{"1.3.0"}},
+		{"*", []string{"0.0.0", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		{">=1.0.0-rc.1", []string{"1.0.0-rc.2", "1.0.0"}, []string{"1.1.0-rc.1"}},
+		{"<=0.2", []string{"0.2.9"}, []string{"0.3.0"}},
+	}
+	for _, tt := range tests {
+		r, err := ParseVersionRange(tt.rng)
+		if err != nil {
+			t.Fatalf("ParseVersionRange(%q) failed: %v", tt.rng, err)
+		}
+		for _, v := range tt.in {
+			if !r.Contains(mustSemVer(v)) {
+				t.Errorf("%q should contain %s", tt.rng, v)
+			}
+		}
+		for _, v := range tt.out {
+			if r.Contains(mustSemVer(v)) {
+				t.Errorf("%q should not contain %s", tt.rng, v)
+			}
+		}
+	}
+	if _, err := ParseVersionRange(">=banana"); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("expected ErrInvalidVersion, got %v", err)
+	}
+}
+
+// TestCheckCompatibility tests the action chosen for each relation between
+// scene version and reader range
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		scene  string
+		rng    string
+		action CompatibilityAction
+		target string
+	}{
+		{"0.2.0", "^0.2.0", CompatibilityAccept, "0.2.0"},
+		{"0.2.0", "^0.1.0", CompatibilityDowngrade, "0.1.0"},
+		{"0.1.0", ">=0.2.0", CompatibilityUpgrade, "0.2.0"},
+		{"0.2.0", "^5.0.0", CompatibilityReject, ""},
+	}
+	for _, tt := range tests {
+		got, err := CheckCompatibility(tt.scene, tt.rng)
+		if err != nil {
+			t.Fatalf("CheckCompatibility(%s, %s) failed: %v", tt.scene, tt.rng, err)
+		}
+		if got.Action != tt.action || got.Target != tt.target {
+			t.Errorf("CheckCompatibility(%s, %s) mismatch: got %s/%s, want %s/%s", tt.scene, tt.rng, got.Action, got.Target, tt.action, tt.target)
+		}
+	}
+}
+
+// TestCapabilities tests detection and capability-aware compatibility
+func TestCapabilities(t *testing.T) {
+	sf := NewSceneFile("Caps")
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "A", Transform: NewTransform(), Label: &Label{Text: "A"}})
+	sf.AddNode(SceneNode{ID: "b", Type: "server", Name: "B", Transform: NewTransform(), Physics: &PhysicsBody{Type: BodyStatic}})
+	sf.AddEdge(SceneEdge{ID: "e", Source: "a", Target: "b", Waypoints: []Vector3{{X: 1}}, Revision: 1})
+
+	sf.Require(FeatureRequirement{Feature: ViewerFeatureWebXR})
+
+	want := []Capability{CapabilityEdgeRouting, CapabilityLabels, CapabilityPhysics, CapabilityRequirements, CapabilityRevisions}
+	if got := DetectCapabilities(&sf); !reflect.DeepEqual(got, want) {
+		t.Errorf("capabilities mismatch: got %v, want %v", got, want)
+	}
+
+	// A 0.1.0 scene using newer constructs still needs a downgrade
+	sf.Version = "0.1.0"
+	result, err := CheckSceneCompatibility(&sf, "^0.1.0")
+	if err != nil {
+		t.Fatalf("CheckSceneCompatibility failed: %v", err)
+	}
+	if result.Action != CompatibilityDowngrade || !reflect.DeepEqual(result.Unsupported, want) {
+		t.Errorf("result mismatch: got %+v", result)
+	}
+
+	sf.Version = SchemaVersion
+	sf.Capabilities = []Capability{"holograms"}
+	result, _ = CheckSceneCompatibility(&sf, "<="+SchemaVersion)
+	if result.Compatible() || len(result.Unsupported) != 1 || result.Unsupported[0] != "holograms" {
+		t.Errorf("unknown capability should not be compatible: got %+v", result)
+	}
+}
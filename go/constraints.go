@@ -0,0 +1,319 @@
+package starfleet
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// LAYOUT CONSTRAINTS
+// =============================================================================
+
+// PinnedMetadataKey marks a node whose position layouts must preserve when
+// its metadata value is true
+const PinnedMetadataKey = "pinned"
+
+// Axis represents a coordinate axis
+type Axis string
+
+const (
+	AxisX Axis = "x"
+	AxisY Axis = "y"
+	AxisZ Axis = "z"
+)
+
+// component returns a pointer to the vector component for an axis
+func (a Axis) component(v *Vector3) *float64 {
+	switch a {
+	case AxisX:
+		return &v.X
+	case AxisY:
+		return &v.Y
+	case AxisZ:
+		return &v.Z
+	default:
+		return nil
+	}
+}
+
+// AlignmentGroup places a set of nodes on a common coordinate along an axis,
+// such as a row (same Z) or a column (same X)
+type AlignmentGroup struct {
+	Nodes []string `json:"nodes" validate:"required,min=2"`
+	Axis  Axis     `json:"axis" validate:"required,oneof=x y z"`
+}
+
+// LayoutRegion confines a set of nodes to an axis-aligned box. Node bounds,
+// not just centers, are kept inside when they fit.
+type LayoutRegion struct {
+	Name  string   `json:"name,omitempty"`
+	Nodes []string `json:"nodes" validate:"required"`
+	Min   Vector3  `json:"min"`
+	Max   Vector3  `json:"max"`
+}
+
+// LayoutConstraints represents restrictions applied on top of a layout
+type LayoutConstraints struct {
+	// Pinned lists nodes whose positions are preserved, in addition to nodes
+	// with PinnedMetadataKey set
+	Pinned []string `json:"pinned,omitempty"`
+	// MinSpacing is the minimum distance between node centers
+	MinSpacing float64          `json:"minSpacing,omitempty" validate:"omitempty,gt=0"`
+	Align      []AlignmentGroup `json:"align,omitempty"`
+	Regions    []LayoutRegion   `json:"regions,omitempty"`
+	Iterations int              `json:"iterations,omitempty" validate:"omitempty,gt=0"`
+}
+
+// isPinned reports whether a node's position must be preserved
+func (c *LayoutConstraints) isPinned(node *SceneNode) bool {
+	if pinned, _ := node.Metadata[PinnedMetadataKey].(bool); pinned {
+		return true
+	}
+	return c != nil && containsString(c.Pinned, node.ID)
+}
+
+// ConstrainedLayout runs a base layout and then enforces constraints on the
+// result. Pinned nodes keep the positions they had before the base layout
+// ran, so curated arrangements survive re-running layout after a reimport.
+type ConstrainedLayout struct {
+	// Base computes initial positions; nodes are left in place when nil
+	Base        Layout
+	Constraints LayoutConstraints
+}
+
+// Apply implements Layout
+func (l *ConstrainedLayout) Apply(sf *SceneFile) (LayoutResult, error) {
+	return l.ApplyContext(context.Background(), sf)
+}
+
+// ApplyContext implements ContextLayout, passing the context on to the
+// base layout
+func (l *ConstrainedLayout) ApplyContext(ctx context.Context, sf *SceneFile) (LayoutResult, error) {
+	pins := make(map[string]Vector3)
+	for i := range sf.Scene.Nodes {
+		if node := &sf.Scene.Nodes[i]; l.Constraints.isPinned(node) {
+			pins[node.ID] = node.Transform.Position
+		}
+	}
+
+	result := LayoutResult{}
+	if l.Base != nil {
+		var err error
+		if result, err = ApplyLayout(ctx, l.Base, sf); err != nil {
+			return result, err
+		}
+	}
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		if p, ok := pins[node.ID]; ok {
+			node.Transform.Position = p
+		}
+	}
+
+	constrained, err := ApplyConstraintsContext(ctx, sf, l.Constraints)
+	if err != nil {
+		return result, err
+	}
+	result.Positioned = max(result.Positioned, constrained.Positioned)
+	result.Warnings = append(result.Warnings, constrained.Warnings...)
+	return result, nil
+}
+
+// ApplyConstraints moves unpinned nodes the least amount needed to satisfy
+// regions, alignment groups and minimum spacing, in that order of priority.
+// Constraints are enforced by repeated projection, so conflicting
+// constraints settle on a compromise and are reported as warnings.
+func ApplyConstraints(sf *SceneFile, c LayoutConstraints) LayoutResult {
+	result, _ := ApplyConstraintsContext(context.Background(), sf, c)
+	return result
+}
+
+// ApplyConstraintsContext is ApplyConstraints checking the context before
+// each projection pass. A canceled run returns the context's error with
+// the nodes left where the last completed pass put them.
+func ApplyConstraintsContext(ctx context.Context, sf *SceneFile, c LayoutConstraints) (LayoutResult, error) {
+	result := LayoutResult{}
+	index := make(map[string]int, len(sf.Scene.Nodes))
+	pinned := make([]bool, len(sf.Scene.Nodes))
+	start := make([]Vector3, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		index[node.ID] = i
+		pinned[i] = c.isPinned(node)
+		start[i] = node.Transform.Position
+	}
+	members := func(ids []string) []int {
+		out := make([]int, 0, len(ids))
+		for _, id := range ids {
+			if i, ok := index[id]; ok {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+
+	iterations := c.Iterations
+	if iterations <= 0 {
+		iterations = 20
+	}
+	var violations int
+	progress := newProgressTracker(ctx, "constraints", iterations)
+	for iter := 0; iter < iterations; iter++ {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		// Later passes win conflicts, so the highest priority runs last
+		violations = enforceSpacing(sf, c.MinSpacing, pinned)
+		for _, g := range c.Align {
+			violations += enforceAlignment(sf, g.Axis, members(g.Nodes), pinned)
+		}
+		for _, r := range c.Regions {
+			violations += enforceRegion(sf, r, members(r.Nodes), pinned)
+		}
+		progress.step()
+		if violations == 0 {
+			break
+		}
+	}
+	progress.done()
+	if violations > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"constraints not fully satisfied after %d iterations (%d violations)", iterations, violations))
+	}
+	for i := range sf.Scene.Nodes {
+		if sf.Scene.Nodes[i].Transform.Position != start[i] {
+			result.Positioned++
+		}
+	}
+	return result, nil
+}
+
+// enforceAlignment moves group members onto a shared coordinate: that of the
+// first pinned member, or the mean of all members when none is pinned. It
+// returns the number of members that were off the line.
+func enforceAlignment(sf *SceneFile, axis Axis, group []int, pinned []bool) int {
+	if len(group) < 2 || axis.component(&Vector3{}) == nil {
+		return 0
+	}
+	target, sum := math.NaN(), 0.0
+	for _, i := range group {
+		v := *axis.component(&sf.Scene.Nodes[i].Transform.Position)
+		sum += v
+		if pinned[i] && math.IsNaN(target) {
+			target = v
+		}
+	}
+	if math.IsNaN(target) {
+		target = sum / float64(len(group))
+	}
+	moved := 0
+	for _, i := range group {
+		v := axis.component(&sf.Scene.Nodes[i].Transform.Position)
+		if math.Abs(*v-target) > 1e-9 {
+			if !pinned[i] {
+				*v = target
+			}
+			moved++
+		}
+	}
+	return moved
+}
+
+// enforceRegion clamps members inside the region box, accounting for their
+// extents, and returns the number of members found outside
+func enforceRegion(sf *SceneFile, r LayoutRegion, group []int, pinned []bool) int {
+	clamp := func(v, lo, hi, half float64) float64 {
+		if hi-lo < 2*half {
+			return (lo + hi) / 2
+		}
+		return math.Max(lo+half, math.Min(hi-half, v))
+	}
+	outside := 0
+	for _, i := range group {
+		node := &sf.Scene.Nodes[i]
+		h := nodeHalfExtents(node, sf.ResolveGeometry(node))
+		p := node.Transform.Position
+		q := Vector3{
+			X: clamp(p.X, r.Min.X, r.Max.X, h.X),
+			Y: clamp(p.Y, r.Min.Y, r.Max.Y, h.Y),
+			Z: clamp(p.Z, r.Min.Z, r.Max.Z, h.Z),
+		}
+		if q.Sub(p).Length() > 1e-9 {
+			outside++
+			if !pinned[i] {
+				node.Transform.Position = q
+			}
+		}
+	}
+	return outside
+}
+
+// enforceSpacing pushes apart node centers closer than the minimum spacing,
+// moving each unpinned node of a pair half the shortfall (or all of it when
+// the other is pinned). It returns the number of pairs too close.
+func enforceSpacing(sf *SceneFile, spacing float64, pinned []bool) int {
+	if spacing <= 0 {
+		return 0
+	}
+	type cell struct{ x, y, z int64 }
+	cellOf := func(p Vector3) cell {
+		return cell{int64(math.Floor(p.X / spacing)), int64(math.Floor(p.Y / spacing)), int64(math.Floor(p.Z / spacing))}
+	}
+	grid := make(map[cell][]int)
+	for i := range sf.Scene.Nodes {
+		k := cellOf(sf.Scene.Nodes[i].Transform.Position)
+		grid[k] = append(grid[k], i)
+	}
+
+	tooClose := 0
+	for i := range sf.Scene.Nodes {
+		k := cellOf(sf.Scene.Nodes[i].Transform.Position)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					for _, j := range grid[cell{k.x + dx, k.y + dy, k.z + dz}] {
+						if j <= i {
+							continue
+						}
+						if separate(&sf.Scene.Nodes[i].Transform.Position, &sf.Scene.Nodes[j].Transform.Position,
+							spacing, pinned[i], pinned[j], i) {
+							tooClose++
+						}
+					}
+				}
+			}
+		}
+	}
+	return tooClose
+}
+
+// separate pushes two points apart to the given distance, honoring pins. It
+// reports whether the points were too close. Coincident points are split
+// along a direction derived from seed so results are deterministic.
+func separate(a, b *Vector3, distance float64, pinnedA, pinnedB bool, seed int) bool {
+	d := b.Sub(*a)
+	length := d.Length()
+	if length >= distance-1e-9 {
+		return false
+	}
+	if pinnedA && pinnedB {
+		return true
+	}
+	dir := d.Normalize()
+	if length < 1e-12 {
+		angle := float64(seed) * 2.399963 // golden angle spreads repeated splits
+		dir = Vector3{X: math.Cos(angle), Z: math.Sin(angle)}
+	}
+	shortfall := distance - length
+	switch {
+	case pinnedA:
+		*b = b.Add(dir.Scale(shortfall))
+	case pinnedB:
+		*a = a.Sub(dir.Scale(shortfall))
+	default:
+		*a = a.Sub(dir.Scale(shortfall / 2))
+		*b = b.Add(dir.Scale(shortfall / 2))
+	}
+	return true
+}
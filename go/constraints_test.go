@@ -0,0 +1,93 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// newConstraintScene returns four nodes, one pinned through metadata
+func newConstraintScene() SceneFile {
+	sf := NewSceneFile("Constraints")
+	for _, id := range []string{"a", "b", "c", "d"} {
+		sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{ID: id, Transform: NewTransform()})
+	}
+	sf.Scene.Nodes[0].Transform.Position = Vector3{X: 5, Z: 5}
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{PinnedMetadataKey: true}
+	return sf
+}
+
+// TestConstrainedLayout_Pins tests that pinned nodes survive a re-layout
+func TestConstrainedLayout_Pins(t *testing.T) {
+	sf := newConstraintScene()
+	sf.FindNode("b").Transform.Position = Vector3{X: -3}
+	scatter := LayoutFunc(func(sf *SceneFile) (LayoutResult, error) {
+		for i := range sf.Scene.Nodes {
+			sf.Scene.Nodes[i].Transform.Position = Vector3{X: float64(i) * 10}
+		}
+		return LayoutResult{Positioned: len(sf.Scene.Nodes)}, nil
+	})
+
+	layout := &ConstrainedLayout{Base: scatter, Constraints: LayoutConstraints{Pinned: []string{"b"}}}
+	if _, err := layout.Apply(&sf); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := sf.FindNode("a").Transform.Position; got != (Vector3{X: 5, Z: 5}) {
+		t.Errorf("metadata pin mismatch: got %v", got)
+	}
+	if got := sf.FindNode("b").Transform.Position; got != (Vector3{X: -3}) {
+		t.Errorf("explicit pin mismatch: got %v", got)
+	}
+	if got := sf.FindNode("c").Transform.Position; got != (Vector3{X: 20}) {
+		t.Errorf("unpinned node should take the base layout, got %v", got)
+	}
+}
+
+// TestApplyConstraints tests spacing, alignment and regions together
+func TestApplyConstraints(t *testing.T) {
+	sf := newConstraintScene()
+	c := LayoutConstraints{
+		MinSpacing: 2,
+		Align:      []AlignmentGroup{{Nodes: []string{"a", "b", "c"}, Axis: AxisZ}},
+		Regions:    []LayoutRegion{{Nodes: []string{"d"}, Min: Vector3{X: -10, Y: -10, Z: -10}, Max: Vector3{X: -4, Y: 10, Z: 10}}},
+	}
+	result := ApplyConstraints(&sf, c)
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", result.Warnings)
+	}
+	if result.Positioned != 3 {
+		t.Errorf("positioned mismatch: got %d, want 3", result.Positioned)
+	}
+
+	a := sf.FindNode("a").Transform.Position
+	if a != (Vector3{X: 5, Z: 5}) {
+		t.Errorf("pinned node moved: got %v", a)
+	}
+	for _, id := range []string{"b", "c"} {
+		if z := sf.FindNode(id).Transform.Position.Z; math.Abs(z-5) > 1e-9 {
+			t.Errorf("node %s alignment mismatch: got z=%v, want 5", id, z)
+		}
+	}
+	if x := sf.FindNode("d").Transform.Position.X; x > -4.5+1e-9 {
+		t.Errorf("region mismatch: got x=%v, want <= -4.5", x)
+	}
+	for i, n := range sf.Scene.Nodes {
+		for _, m := range sf.Scene.Nodes[i+1:] {
+			if d := n.Transform.Position.Sub(m.Transform.Position).Length(); d < 2-1e-6 {
+				t.Errorf("nodes %s and %s too close: %v", n.ID, m.ID, d)
+			}
+		}
+	}
+}
+
+// TestApplyConstraints_Conflict tests that impossible constraints warn
+func TestApplyConstraints_Conflict(t *testing.T) {
+	sf := newConstraintScene()
+	c := LayoutConstraints{Pinned: []string{"a", "b", "c", "d"}, MinSpacing: 1}
+	result := ApplyConstraints(&sf, c)
+	if len(result.Warnings) != 1 {
+		t.Errorf("warning count mismatch: got %v, want 1", result.Warnings)
+	}
+	if result.Positioned != 0 {
+		t.Errorf("pinned nodes should not move, positioned %d", result.Positioned)
+	}
+}
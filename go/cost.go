@@ -0,0 +1,408 @@
+package starfleet
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// =============================================================================
+// COSTS
+// =============================================================================
+
+// Metric and metadata keys written by CostEnricher. Themes can color nodes
+// by MetricCostTotal to show where the money goes.
+const (
+	// MetricCost is the cost billed to a node itself
+	MetricCost = "cost"
+	// MetricCostTotal is the cost of a node and all its descendants
+	MetricCostTotal = "cost.total"
+	// MetricCostBudgetUsed is MetricCostTotal as a fraction of the node's
+	// MetadataCostBudget
+	MetricCostBudgetUsed = "cost.budgetUsed"
+	// MetadataResourceID holds the cloud resource ID or ARN a node stands
+	// for
+	MetadataResourceID = "resourceId"
+	// MetadataCostBudget holds a node's budget for the billing period, in
+	// the export's currency
+	MetadataCostBudget = "costBudget"
+	// MetadataCostCurrency records the currency of the cost metrics
+	MetadataCostCurrency = "costCurrency"
+)
+
+// CostLineItem is one charge from a billing export
+type CostLineItem struct {
+	ResourceID string            `json:"resourceId,omitempty"`
+	Account    string            `json:"account,omitempty"`
+	Service    string            `json:"service,omitempty"`
+	Amount     float64           `json:"amount"`
+	Currency   string            `json:"currency,omitempty"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// CostFormat identifies a billing export format
+type CostFormat string
+
+const (
+	// CostFormatAWSCUR is an AWS Cost and Usage Report in CSV, with either
+	// the legacy "lineItem/UnblendedCost" columns or the CUR 2.0
+	// "line_item_unblended_cost" ones
+	CostFormatAWSCUR CostFormat = "aws-cur"
+	// CostFormatGCP is a Google Cloud billing export as newline-delimited
+	// JSON rows, as written by a BigQuery export of the billing table
+	CostFormatGCP CostFormat = "gcp"
+)
+
+// ReadCostExport reads the line items of a billing export
+func ReadCostExport(r io.Reader, format CostFormat) ([]CostLineItem, error) {
+	switch format {
+	case CostFormatAWSCUR:
+		return ReadAWSCUR(r)
+	case CostFormatGCP:
+		return ReadGCPBilling(r)
+	}
+	return nil, fmt.Errorf("unknown cost format %q", format)
+}
+
+// ReadAWSCUR reads the line items of an AWS Cost and Usage Report. Resource
+// tags come from the "resourceTags/user:<key>" columns, or from the CUR 2.0
+// "resource_tags" map column.
+func ReadAWSCUR(r io.Reader) ([]CostLineItem, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read cur header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	tagColumns := make(map[int]string)
+	for i, h := range header {
+		if key, ok := strings.CutPrefix(h, "resourceTags/user:"); ok {
+			tagColumns[i] = key
+			continue
+		}
+		columns[curColumn(h)] = i
+	}
+	cost, ok := columns["line_item_unblended_cost"]
+	if !ok {
+		return nil, errors.New("read cur: no line_item_unblended_cost column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var items []CostLineItem
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read cur: %w", err)
+		}
+		amount, err := strconv.ParseFloat(record[cost], 64)
+		if err != nil {
+			return nil, fmt.Errorf("read cur line %d: cost %q: %w", line, record[cost], err)
+		}
+		item := CostLineItem{
+			ResourceID: field(record, "line_item_resource_id"),
+			Account:    field(record, "line_item_usage_account_id"),
+			Service:    field(record, "line_item_product_code"),
+			Amount:     amount,
+			Currency:   field(record, "line_item_currency_code"),
+		}
+		item.Start, _ = time.Parse(time.RFC3339, field(record, "line_item_usage_start_date"))
+		item.End, _ = time.Parse(time.RFC3339, field(record, "line_item_usage_end_date"))
+		for i, key := range tagColumns {
+			if i < len(record) && record[i] != "" {
+				if item.Tags == nil {
+					item.Tags = make(map[string]string)
+				}
+				item.Tags[key] = record[i]
+			}
+		}
+		if tags := field(record, "resource_tags"); tags != "" {
+			var m map[string]string
+			if err := json.Unmarshal([]byte(tags), &m); err != nil {
+				return nil, fmt.Errorf("read cur line %d: resource_tags: %w", line, err)
+			}
+			for k, v := range m {
+				if item.Tags == nil {
+					item.Tags = make(map[string]string, len(m))
+				}
+				item.Tags[strings.TrimPrefix(k, "user_")] = v
+			}
+		}
+		items = append(items, item)
+	}
+}
+
+// curColumn maps a legacy CUR column such as "lineItem/UnblendedCost" to its
+// CUR 2.0 name, "line_item_unblended_cost"; CUR 2.0 names are unchanged
+func curColumn(h string) string {
+	var b strings.Builder
+	prev := rune(0)
+	for _, c := range h {
+		switch {
+		case c == '/' || c == ':':
+			c = '_'
+		case unicode.IsUpper(c):
+			if prev != 0 && prev != '_' && !unicode.IsUpper(prev) {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+		prev = c
+	}
+	return b.String()
+}
+
+// gcpBillingRow is the subset of a Google Cloud billing export row the
+// reader uses
+type gcpBillingRow struct {
+	Service struct {
+		Description string `json:"description"`
+	} `json:"service"`
+	Project struct {
+		ID string `json:"id"`
+	} `json:"project"`
+	Resource struct {
+		Name       string `json:"name"`
+		GlobalName string `json:"global_name"`
+	} `json:"resource"`
+	Labels []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"labels"`
+	Cost           json.Number `json:"cost"`
+	Currency       string      `json:"currency"`
+	UsageStartTime time.Time   `json:"usage_start_time"`
+	UsageEndTime   time.Time   `json:"usage_end_time"`
+}
+
+// ReadGCPBilling reads the line items of a Google Cloud billing export.
+// Resource IDs are the resource's global name when the export is the
+// detailed one, and its name otherwise.
+func ReadGCPBilling(r io.Reader) ([]CostLineItem, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+	var items []CostLineItem
+	for row := 1; ; row++ {
+		var rec gcpBillingRow
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			return items, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read gcp billing row %d: %w", row, err)
+		}
+		amount, err := rec.Cost.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("read gcp billing row %d: cost %q: %w", row, rec.Cost, err)
+		}
+		item := CostLineItem{
+			ResourceID: rec.Resource.GlobalName,
+			Account:    rec.Project.ID,
+			Service:    rec.Service.Description,
+			Amount:     amount,
+			Currency:   rec.Currency,
+			Start:      rec.UsageStartTime,
+			End:        rec.UsageEndTime,
+		}
+		if item.ResourceID == "" {
+			item.ResourceID = rec.Resource.Name
+		}
+		for _, l := range rec.Labels {
+			if item.Tags == nil {
+				item.Tags = make(map[string]string, len(rec.Labels))
+			}
+			item.Tags[l.Key] = l.Value
+		}
+		items = append(items, item)
+	}
+}
+
+// CostReport describes what a CostEnricher attributed
+type CostReport struct {
+	Currency string `json:"currency,omitempty"`
+	// Nodes counts the nodes that were billed directly
+	Nodes int `json:"nodes"`
+	// Matched and Unmatched count line items by whether they were
+	// attributed to a node; UnmatchedCost is the sum of the unmatched ones
+	Matched       int     `json:"matched"`
+	Unmatched     int     `json:"unmatched"`
+	UnmatchedCost float64 `json:"unmatchedCost"`
+	// OverBudget lists the nodes whose total exceeds their budget
+	OverBudget []string `json:"overBudget,omitempty"`
+}
+
+// CostEnricher attributes billing line items to nodes and rolls the costs
+// up the node hierarchy. A line item belongs to the node whose ID or
+// MetadataResourceID is its resource ID or, when TagKey is set, to the node
+// named by that resource tag.
+type CostEnricher struct {
+	// TagKey names a resource tag holding node IDs, such as
+	// "starfleet-node"; it wins over resource IDs
+	TagKey string `json:"tagKey,omitempty"`
+	// Start and End, when set, keep only usage starting in [Start, End);
+	// line items without usage times are always kept
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+}
+
+func (c *CostEnricher) inPeriod(start time.Time) bool {
+	if start.IsZero() {
+		return true
+	}
+	return (c.Start.IsZero() || !start.Before(c.Start)) && (c.End.IsZero() || start.Before(c.End))
+}
+
+// Apply sets MetricCost and MetricCostTotal on every node of the scene,
+// billed or not, replacing earlier values, and MetricCostBudgetUsed on
+// nodes with a budget. Line items in more than one currency are rejected,
+// leaving the scene unchanged.
+func (c *CostEnricher) Apply(sf *SceneFile, items []CostLineItem) (CostReport, error) {
+	var report CostReport
+	byID := make(map[string]int, len(sf.Scene.Nodes))
+	byResource := make(map[string]int)
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		byID[n.ID] = i
+		if rid, ok := n.Metadata[MetadataResourceID].(string); ok && rid != "" {
+			byResource[rid] = i
+		}
+	}
+
+	own := make([]float64, len(sf.Scene.Nodes))
+	billed := make([]bool, len(sf.Scene.Nodes))
+	for _, item := range items {
+		if !isFinite(item.Amount) || !c.inPeriod(item.Start) {
+			continue
+		}
+		if item.Currency != "" {
+			if report.Currency == "" {
+				report.Currency = item.Currency
+			} else if item.Currency != report.Currency {
+				return CostReport{}, fmt.Errorf("cost export mixes currencies %s and %s", report.Currency, item.Currency)
+			}
+		}
+		i, ok := -1, false
+		if c.TagKey != "" {
+			i, ok = byID[item.Tags[c.TagKey]]
+		}
+		if !ok && item.ResourceID != "" {
+			if i, ok = byID[item.ResourceID]; !ok {
+				i, ok = byResource[item.ResourceID]
+			}
+		}
+		if !ok {
+			report.Unmatched++
+			report.UnmatchedCost += item.Amount
+			continue
+		}
+		report.Matched++
+		own[i] += item.Amount
+		if !billed[i] {
+			billed[i] = true
+			report.Nodes++
+		}
+	}
+
+	// Totals follow Parent links; a cycle would never finish, so nodes on
+	// one only count themselves
+	total := make([]float64, len(own))
+	copy(total, own)
+	for i := range sf.Scene.Nodes {
+		seen := map[int]bool{i: true}
+		for p := sf.Scene.Nodes[i].Parent; p != ""; {
+			j, ok := byID[p]
+			if !ok || seen[j] {
+				break
+			}
+			seen[j] = true
+			total[j] += own[i]
+			p = sf.Scene.Nodes[j].Parent
+		}
+	}
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Metrics == nil {
+			n.Metrics = make(map[string]interface{})
+		}
+		n.Metrics[MetricCost] = own[i]
+		n.Metrics[MetricCostTotal] = total[i]
+		delete(n.Metrics, MetricCostBudgetUsed)
+		if budget, ok := toFloat64(n.Metadata[MetadataCostBudget]); ok && budget > 0 {
+			n.Metrics[MetricCostBudgetUsed] = total[i] / budget
+			if total[i] > budget {
+				report.OverBudget = append(report.OverBudget, n.ID)
+			}
+		}
+		if report.Currency != "" {
+			if n.Metadata == nil {
+				n.Metadata = make(map[string]interface{})
+			}
+			n.Metadata[MetadataCostCurrency] = report.Currency
+		}
+	}
+	sort.Strings(report.OverBudget)
+	return report, nil
+}
+
+// costStage reads a billing export and applies a CostEnricher. Options are
+// the CostEnricher fields with "path", the export file, and "format".
+func costStage(options json.RawMessage) (PipelineStage, error) {
+	var opts struct {
+		CostEnricher
+		Path   string     `json:"path"`
+		Format CostFormat `json:"format"`
+	}
+	if err := decodeStageOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Path == "" {
+		return nil, errors.New(`cost stage needs a "path"`)
+	}
+	if opts.Format != CostFormatAWSCUR && opts.Format != CostFormatGCP {
+		return nil, fmt.Errorf("unknown cost format %q", opts.Format)
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		f, err := os.Open(opts.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		items, err := ReadCostExport(f, opts.Format)
+		if err != nil {
+			return nil, err
+		}
+		report, err := opts.CostEnricher.Apply(sf, items)
+		if err != nil {
+			return nil, err
+		}
+		notes := []string{fmt.Sprintf("attributed %d line items to %d nodes", report.Matched, report.Nodes)}
+		if report.Unmatched > 0 {
+			amount := strings.TrimSpace(fmt.Sprintf("%.2f %s", report.UnmatchedCost, report.Currency))
+			notes = append(notes, fmt.Sprintf("%d line items costing %s matched no node", report.Unmatched, amount))
+		}
+		if len(report.OverBudget) > 0 {
+			notes = append(notes, "over budget: "+strings.Join(report.OverBudget, ", "))
+		}
+		return notes, nil
+	}), nil
+}
@@ -0,0 +1,141 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCostScene returns a region holding a cluster of two instances and a
+// bucket, with budgets on the region and the cluster
+func newCostScene() SceneFile {
+	sf := NewSceneFile("Costs")
+	sf.AddNode(SceneNode{ID: "us-east-1", Type: "region", Metadata: map[string]interface{}{MetadataCostBudget: 100.0}})
+	sf.AddNode(SceneNode{ID: "cluster", Type: "cluster", Parent: "us-east-1", Metadata: map[string]interface{}{MetadataCostBudget: 10.0}})
+	sf.AddNode(SceneNode{ID: "web-1", Type: "server", Parent: "cluster", Metadata: map[string]interface{}{MetadataResourceID: "i-0abc"}})
+	sf.AddNode(SceneNode{ID: "web-2", Type: "server", Parent: "cluster"})
+	sf.AddNode(SceneNode{ID: "assets", Type: "bucket", Parent: "us-east-1"})
+	return sf
+}
+
+// TestReadAWSCUR tests reading legacy and CUR 2.0 reports
+func TestReadAWSCUR(t *testing.T) {
+	legacy := "identity/LineItemId,lineItem/UsageAccountId,lineItem/ProductCode,lineItem/ResourceId,lineItem/UsageStartDate,lineItem/UnblendedCost,lineItem/CurrencyCode,resourceTags/user:starfleet-node\n" +
+		"1,123,AmazonEC2,i-0abc,2026-01-01T00:00:00Z,4.5,USD,\n" +
+		"2,123,AmazonEC2,i-0def,2026-01-01T00:00:00Z,3,USD,web-2\n"
+	items, err := ReadAWSCUR(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("ReadAWSCUR failed: %v", err)
+	}
+	want := CostLineItem{
+		ResourceID: "i-0abc", Account: "123", Service: "AmazonEC2", Amount: 4.5, Currency: "USD",
+		Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if len(items) != 2 || !reflect.DeepEqual(items[0], want) || items[1].Tags["starfleet-node"] != "web-2" {
+		t.Errorf("items mismatch: got %+v", items)
+	}
+
+	cur2 := "line_item_resource_id,line_item_unblended_cost,resource_tags\n" +
+		`arn:aws:s3:::assets,1.25,"{""user_team"":""web""}"` + "\n"
+	items, err = ReadAWSCUR(strings.NewReader(cur2))
+	if err != nil {
+		t.Fatalf("ReadAWSCUR failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Amount != 1.25 || items[0].Tags["team"] != "web" {
+		t.Errorf("items mismatch: got %+v", items)
+	}
+
+	if _, err := ReadAWSCUR(strings.NewReader("a,b\n1,2\n")); err == nil {
+		t.Error("expected error for a report without costs")
+	}
+	if _, err := ReadAWSCUR(strings.NewReader("lineItem/UnblendedCost\nfree\n")); err == nil {
+		t.Error("expected error for a malformed cost")
+	}
+}
+
+// TestReadGCPBilling tests reading a billing export
+func TestReadGCPBilling(t *testing.T) {
+	export := `{"service":{"description":"Compute Engine"},"project":{"id":"p"},"resource":{"name":"web-1","global_name":"//compute.googleapis.com/web-1"},"labels":[{"key":"team","value":"web"}],"cost":2.5,"currency":"EUR","usage_start_time":"2026-01-01T00:00:00Z"}
+{"resource":{"name":"assets"},"cost":0.5,"currency":"EUR"}
+`
+	items, err := ReadCostExport(strings.NewReader(export), CostFormatGCP)
+	if err != nil {
+		t.Fatalf("ReadGCPBilling failed: %v", err)
+	}
+	if len(items) != 2 || items[0].ResourceID != "//compute.googleapis.com/web-1" || items[0].Tags["team"] != "web" || items[1].ResourceID != "assets" {
+		t.Errorf("items mismatch: got %+v", items)
+	}
+}
+
+// TestCostEnricher tests attribution, roll-ups and budgets
+func TestCostEnricher(t *testing.T) {
+	sf := newCostScene()
+	items := []CostLineItem{
+		{ResourceID: "i-0abc", Amount: 4.5, Currency: "USD"},
+		{ResourceID: "i-0def", Amount: 7, Currency: "USD", Tags: map[string]string{"starfleet-node": "web-2"}},
+		{ResourceID: "assets", Amount: 1, Currency: "USD"},
+		{ResourceID: "vol-9", Amount: 2, Currency: "USD"},
+		{ResourceID: "assets", Amount: 50, Currency: "USD", Start: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	c := CostEnricher{TagKey: "starfleet-node", Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	report, err := c.Apply(&sf, items)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := CostReport{Currency: "USD", Nodes: 3, Matched: 3, Unmatched: 1, UnmatchedCost: 2, OverBudget: []string{"cluster"}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report mismatch: got %+v, want %+v", report, want)
+	}
+	totals := map[string]float64{"us-east-1": 12.5, "cluster": 11.5, "web-1": 4.5, "web-2": 7, "assets": 1}
+	for id, total := range totals {
+		if got := sf.FindNode(id).Metrics[MetricCostTotal]; got != total {
+			t.Errorf("%s total mismatch: got %v, want %v", id, got, total)
+		}
+	}
+	if got := sf.FindNode("cluster").Metrics; got[MetricCost] != 0.0 || got[MetricCostBudgetUsed] != 1.15 {
+		t.Errorf("cluster metrics mismatch: got %v", got)
+	}
+	if got := sf.FindNode("web-1").Metadata[MetadataCostCurrency]; got != "USD" {
+		t.Errorf("currency mismatch: got %v, want USD", got)
+	}
+
+	before, _ := json.Marshal(sf)
+	if _, err := c.Apply(&sf, append(items, CostLineItem{ResourceID: "assets", Amount: 1, Currency: "EUR"})); err == nil {
+		t.Error("expected error for mixed currencies")
+	}
+	if after, _ := json.Marshal(sf); string(after) != string(before) {
+		t.Error("scene changed by a failed Apply")
+	}
+}
+
+// TestCostStage tests the cost pipeline stage
+func TestCostStage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cur.csv")
+	os.WriteFile(path, []byte("lineItem/ResourceId,lineItem/UnblendedCost\ni-0abc,3\nvol-9,1\n"), 0o644)
+	options, _ := json.Marshal(map[string]string{"path": path, "format": "aws-cur"})
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageCost, Options: options}}}, DefaultStageFactories(nil))
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+	sf := newCostScene()
+	report, err := p.Run(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	want := []string{"attributed 1 line items to 1 nodes", "1 line items costing 1.00 matched no node"}
+	if !reflect.DeepEqual(report.Stages[0].Notes, want) {
+		t.Errorf("notes mismatch: got %q, want %q", report.Stages[0].Notes, want)
+	}
+	if got := sf.FindNode("us-east-1").Metrics[MetricCostTotal]; got != 3.0 {
+		t.Errorf("total mismatch: got %v, want 3", got)
+	}
+
+	if _, err := costStage(json.RawMessage(`{"path": "x", "format": "azure"}`)); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
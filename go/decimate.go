@@ -0,0 +1,202 @@
+package starfleet
+
+import (
+	"errors"
+	"math"
+)
+
+// =============================================================================
+// MESH DECIMATION
+// =============================================================================
+
+// DecimateOptions represents the target of a mesh decimation. When both are
+// set the error bound takes precedence over the triangle target.
+type DecimateOptions struct {
+	// TargetTriangles is the maximum number of triangles to keep
+	TargetTriangles int
+	// MaxError is the maximum distance any vertex may move, in mesh units
+	MaxError float64
+}
+
+// DecimateResult represents a decimated mesh and its quality
+type DecimateResult struct {
+	Mesh      Mesh    `json:"mesh"`
+	Triangles int     `json:"triangles"`
+	MaxError  float64 `json:"maxError"`
+}
+
+// DecimateMesh simplifies a triangle mesh with vertex clustering: vertices
+// are snapped to a uniform grid, merged into the average of their cell, and
+// triangles that collapse are dropped. For a triangle target the grid size
+// is found by bisection. Clustering is robust on the non-manifold meshes
+// common in CAD exports, at the cost of less faithful silhouettes than
+// edge-collapse methods. Normals are recomputed.
+func DecimateMesh(m Mesh, opts DecimateOptions) (DecimateResult, error) {
+	if len(m.Positions)%3 != 0 {
+		return DecimateResult{}, ErrInvalidMesh
+	}
+	for _, i := range m.Indices {
+		if int(i) >= m.VertexCount() {
+			return DecimateResult{}, ErrInvalidMesh
+		}
+	}
+	if m.TriangleCount() == 0 || (opts.TargetTriangles <= 0 && opts.MaxError <= 0) {
+		return DecimateResult{Mesh: m, Triangles: m.TriangleCount()}, nil
+	}
+
+	lo, hi := m.bounds()
+	diagonal := hi.Sub(lo).Length()
+	if diagonal == 0 {
+		return DecimateResult{Mesh: m, Triangles: m.TriangleCount()}, nil
+	}
+
+	// A vertex moves at most one cell diagonal when merged
+	maxCell := diagonal
+	if opts.MaxError > 0 {
+		maxCell = math.Min(maxCell, opts.MaxError/math.Sqrt(3))
+	}
+	best := clusterMesh(&m, lo, maxCell)
+	if opts.TargetTriangles <= 0 || best.mesh.TriangleCount() > opts.TargetTriangles {
+		return finishDecimation(m, best), nil
+	}
+
+	// Bisect for the smallest cell meeting the triangle target; the error
+	// bound caps the cell size, so the target may be missed
+	low, high := diagonal*1e-6, maxCell
+	for i := 0; i < 32; i++ {
+		mid := (low + high) / 2
+		candidate := clusterMesh(&m, lo, mid)
+		if candidate.mesh.TriangleCount() <= opts.TargetTriangles {
+			best, high = candidate, mid
+		} else {
+			low = mid
+		}
+	}
+	return finishDecimation(m, best), nil
+}
+
+// finishDecimation recomputes normals when the original mesh carried them
+func finishDecimation(original Mesh, out clustered) DecimateResult {
+	if len(original.Normals) > 0 {
+		out.mesh.ComputeNormals()
+	}
+	return DecimateResult{Mesh: out.mesh, Triangles: out.mesh.TriangleCount(), MaxError: out.maxError}
+}
+
+// clustered represents the output of one clustering pass
+type clustered struct {
+	mesh     Mesh
+	maxError float64
+}
+
+// clusterMesh merges vertices sharing a grid cell of the given size
+func clusterMesh(m *Mesh, origin Vector3, cell float64) clustered {
+	type key struct{ x, y, z int64 }
+	cellOf := func(v Vector3) key {
+		return key{
+			int64(math.Floor((v.X - origin.X) / cell)),
+			int64(math.Floor((v.Y - origin.Y) / cell)),
+			int64(math.Floor((v.Z - origin.Z) / cell)),
+		}
+	}
+
+	clusters := make(map[key]uint32)
+	var sums []Vector3
+	var counts []float64
+	remap := make([]uint32, m.VertexCount())
+	for i := range remap {
+		v := m.vertex(uint32(i))
+		k := cellOf(v)
+		c, ok := clusters[k]
+		if !ok {
+			c = uint32(len(sums))
+			clusters[k] = c
+			sums = append(sums, Vector3{})
+			counts = append(counts, 0)
+		}
+		sums[c] = sums[c].Add(v)
+		counts[c]++
+		remap[i] = c
+	}
+
+	out := Mesh{Positions: make([]float64, 0, len(sums)*3)}
+	for i, s := range sums {
+		avg := s.Scale(1 / counts[i])
+		out.Positions = append(out.Positions, avg.X, avg.Y, avg.Z)
+	}
+	maxError := 0.0
+	for i, c := range remap {
+		maxError = math.Max(maxError, m.vertex(uint32(i)).Sub(out.vertex(c)).Length())
+	}
+
+	type tri struct{ a, b, c uint32 }
+	seen := make(map[tri]bool)
+	idx := m.indices()
+	for t := 0; t+2 < len(idx); t += 3 {
+		a, b, c := remap[idx[t]], remap[idx[t+1]], remap[idx[t+2]]
+		if a == b || b == c || a == c {
+			continue
+		}
+		// Canonical rotation keeps winding while detecting duplicates
+		k := tri{a, b, c}
+		if b < a && b < c {
+			k = tri{b, c, a}
+		} else if c < a && c < b {
+			k = tri{c, a, b}
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out.Indices = append(out.Indices, a, b, c)
+	}
+	return clustered{mesh: compactMesh(out), maxError: maxError}
+}
+
+// compactMesh removes vertices no longer referenced by any triangle
+func compactMesh(m Mesh) Mesh {
+	used := make([]int64, m.VertexCount())
+	for i := range used {
+		used[i] = -1
+	}
+	out := Mesh{Indices: make([]uint32, len(m.Indices))}
+	for i, v := range m.Indices {
+		if used[v] < 0 {
+			used[v] = int64(out.VertexCount())
+			out.Positions = append(out.Positions, m.Positions[3*v], m.Positions[3*v+1], m.Positions[3*v+2])
+		}
+		out.Indices[i] = uint32(used[v])
+	}
+	return out
+}
+
+// GeometryLOD represents a simplified geometry variant used beyond a
+// camera distance
+type GeometryLOD struct {
+	Distance float64 `json:"distance" validate:"required,gt=0"`
+	Mesh     *Mesh   `json:"mesh" validate:"required"`
+}
+
+// GenerateLODs decimates the mesh of custom geometry once per distance,
+// keeping ratios[i] of the original triangles for distances[i], and stores
+// the results as the geometry's levels of detail. Existing levels are
+// replaced.
+func GenerateLODs(g *Geometry, distances, ratios []float64) error {
+	if g.Mesh == nil {
+		return ErrInvalidMesh
+	}
+	if len(distances) != len(ratios) {
+		return errors.New("generate lods: distances and ratios differ in length")
+	}
+	g.LODs = make([]GeometryLOD, 0, len(distances))
+	for i, d := range distances {
+		target := int(math.Max(1, math.Round(float64(g.Mesh.TriangleCount())*ratios[i])))
+		result, err := DecimateMesh(*g.Mesh, DecimateOptions{TargetTriangles: target})
+		if err != nil {
+			return err
+		}
+		mesh := result.Mesh
+		g.LODs = append(g.LODs, GeometryLOD{Distance: d, Mesh: &mesh})
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package starfleet
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// newGridMesh builds an n×n grid of quads in the XY plane with a gentle
+// height field so decimation has something to flatten
+func newGridMesh(n int) Mesh {
+	m := Mesh{}
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			fx, fy := float64(x)/float64(n), float64(y)/float64(n)
+			m.Positions = append(m.Positions, fx, fy, 0.01*math.Sin(fx*math.Pi))
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			a := uint32(y*(n+1) + x)
+			b, c, d := a+1, a+uint32(n+1)+1, a+uint32(n+1)
+			m.Indices = append(m.Indices, a, b, c, a, c, d)
+		}
+	}
+	m.ComputeNormals()
+	return m
+}
+
+// TestDecimateMesh_TargetTriangles tests reduction to a triangle budget
+func TestDecimateMesh_TargetTriangles(t *testing.T) {
+	mesh := newGridMesh(40)
+	result, err := DecimateMesh(mesh, DecimateOptions{TargetTriangles: 400})
+	if err != nil {
+		t.Fatalf("DecimateMesh failed: %v", err)
+	}
+	if result.Triangles > 400 || result.Triangles < 100 {
+		t.Errorf("triangle count mismatch: got %d, want between 100 and 400", result.Triangles)
+	}
+	if result.Triangles != result.Mesh.TriangleCount() {
+		t.Errorf("reported triangles mismatch: got %d, want %d", result.Triangles, result.Mesh.TriangleCount())
+	}
+	if len(result.Mesh.Normals) != len(result.Mesh.Positions) {
+		t.Errorf("normal count mismatch: got %d, want %d", len(result.Mesh.Normals), len(result.Mesh.Positions))
+	}
+	for _, i := range result.Mesh.Indices {
+		if int(i) >= result.Mesh.VertexCount() {
+			t.Fatalf("index %d out of range for %d vertices", i, result.Mesh.VertexCount())
+		}
+	}
+}
+
+// TestDecimateMesh_MaxError tests that the error bound is respected
+func TestDecimateMesh_MaxError(t *testing.T) {
+	mesh := newGridMesh(40)
+	result, err := DecimateMesh(mesh, DecimateOptions{TargetTriangles: 2, MaxError: 0.05})
+	if err != nil {
+		t.Fatalf("DecimateMesh failed: %v", err)
+	}
+	if result.MaxError > 0.05 {
+		t.Errorf("max error mismatch: got %v, want <= 0.05", result.MaxError)
+	}
+	if result.Triangles >= mesh.TriangleCount() {
+		t.Errorf("expected fewer triangles than %d, got %d", mesh.TriangleCount(), result.Triangles)
+	}
+}
+
+// TestDecimateMesh_Invalid tests rejection of malformed meshes
+func TestDecimateMesh_Invalid(t *testing.T) {
+	mesh := Mesh{Positions: []float64{0, 0, 0, 1, 0, 0, 0, 1, 0}, Indices: []uint32{0, 1, 3}}
+	if _, err := DecimateMesh(mesh, DecimateOptions{TargetTriangles: 1}); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrInvalidMesh)
+	}
+}
+
+// TestGenerateLODs tests that each level keeps fewer triangles
+func TestGenerateLODs(t *testing.T) {
+	mesh := newGridMesh(30)
+	g := Geometry{Type: GeometryCustom, Mesh: &mesh}
+	if err := GenerateLODs(&g, []float64{50, 200}, []float64{0.5, 0.1}); err != nil {
+		t.Fatalf("GenerateLODs failed: %v", err)
+	}
+	if len(g.LODs) != 2 {
+		t.Fatalf("LOD count mismatch: got %d, want 2", len(g.LODs))
+	}
+	if g.LODs[1].Mesh.TriangleCount() >= g.LODs[0].Mesh.TriangleCount() {
+		t.Errorf("expected coarser second level: got %d and %d triangles",
+			g.LODs[0].Mesh.TriangleCount(), g.LODs[1].Mesh.TriangleCount())
+	}
+	if err := GenerateLODs(&Geometry{Type: GeometryBox}, []float64{1}, []float64{0.5}); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrInvalidMesh)
+	}
+}
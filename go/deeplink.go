@@ -0,0 +1,177 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// DEEP LINKS
+// =============================================================================
+
+// DeepLinkScheme is the URL scheme of viewer-independent deep links
+const DeepLinkScheme = "starfleet"
+
+// ErrInvalidDeepLink is returned when a deep link cannot be parsed
+var ErrInvalidDeepLink = errors.New("invalid deep link")
+
+// DeepLink represents a link into a specific view of a scene. Everything but
+// the scene ID is optional; a camera pose takes precedence over a named view
+// when both are set.
+type DeepLink struct {
+	SceneID string `json:"sceneId" validate:"required"`
+	// Node is the node to focus and select
+	Node string `json:"node,omitempty"`
+	// View names a saved view
+	View   string  `json:"view,omitempty"`
+	Camera *Camera `json:"camera,omitempty"`
+	// Filters maps filter names, such as "status" or "tag", to values
+	Filters map[string]string `json:"filters,omitempty"`
+	// Time is the moment to show for time-travel capable viewers
+	Time *time.Time `json:"time,omitempty"`
+}
+
+// Encode renders the link as a URL. With an empty base the result uses the
+// starfleet scheme, as in starfleet://scenes/prod#node=api; otherwise it is
+// a viewer URL such as https://viewer.example.com/scenes/prod#node=api. The
+// view state lives in the fragment so it never reaches servers or logs, and
+// fields appear in a fixed order so equal links encode identically.
+func (l DeepLink) Encode(base string) string {
+	var b strings.Builder
+	if base == "" {
+		b.WriteString(DeepLinkScheme + "://scenes/")
+	} else {
+		b.WriteString(strings.TrimRight(base, "/") + "/scenes/")
+	}
+	b.WriteString(url.PathEscape(l.SceneID))
+
+	var params []string
+	add := func(key, value string) {
+		params = append(params, key+"="+url.QueryEscape(value))
+	}
+	if l.Node != "" {
+		add("node", l.Node)
+	}
+	if l.View != "" {
+		add("view", l.View)
+	}
+	if c := l.Camera; c != nil {
+		values := []float64{c.Position.X, c.Position.Y, c.Position.Z, c.Target.X, c.Target.Y, c.Target.Z}
+		if c.FOV > 0 {
+			values = append(values, c.FOV)
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		// Commas are safe in fragments; keep them readable
+		params = append(params, "camera="+strings.Join(parts, ","))
+	}
+	keys := make([]string, 0, len(l.Filters))
+	for k := range l.Filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add("filter", k+":"+l.Filters[k])
+	}
+	if l.Time != nil {
+		add("t", l.Time.UTC().Format(time.RFC3339))
+	}
+	if len(params) > 0 {
+		b.WriteString("#" + strings.Join(params, "&"))
+	}
+	return b.String()
+}
+
+// ParseDeepLink parses a link produced by Encode, in either the starfleet
+// scheme or viewer URL form. Unknown fragment keys are ignored so newer
+// links still open in older viewers.
+func ParseDeepLink(raw string) (DeepLink, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return DeepLink{}, fmt.Errorf("%w: %v", ErrInvalidDeepLink, err)
+	}
+
+	link := DeepLink{}
+	var id string
+	if u.Scheme == DeepLinkScheme {
+		if u.Host != "scenes" {
+			return link, fmt.Errorf("%w: expected %s://scenes/<id>", ErrInvalidDeepLink, DeepLinkScheme)
+		}
+		id = strings.TrimPrefix(u.EscapedPath(), "/")
+	} else {
+		path := u.EscapedPath()
+		i := strings.LastIndex(path, "/scenes/")
+		if i < 0 {
+			return link, fmt.Errorf("%w: no /scenes/ path segment", ErrInvalidDeepLink)
+		}
+		id = path[i+len("/scenes/"):]
+	}
+	if link.SceneID, err = url.PathUnescape(strings.TrimSuffix(id, "/")); err != nil || link.SceneID == "" {
+		return link, fmt.Errorf("%w: missing scene id", ErrInvalidDeepLink)
+	}
+
+	params, err := url.ParseQuery(u.EscapedFragment())
+	if err != nil {
+		return link, fmt.Errorf("%w: %v", ErrInvalidDeepLink, err)
+	}
+	link.Node = params.Get("node")
+	link.View = params.Get("view")
+	if s := params.Get("camera"); s != "" {
+		parts := strings.Split(s, ",")
+		if len(parts) != 6 && len(parts) != 7 {
+			return link, fmt.Errorf("%w: camera needs 6 or 7 values", ErrInvalidDeepLink)
+		}
+		values := make([]float64, len(parts))
+		for i, p := range parts {
+			if values[i], err = strconv.ParseFloat(p, 64); err != nil {
+				return link, fmt.Errorf("%w: camera value %q", ErrInvalidDeepLink, p)
+			}
+		}
+		link.Camera = &Camera{
+			Position: Vector3{X: values[0], Y: values[1], Z: values[2]},
+			Target:   Vector3{X: values[3], Y: values[4], Z: values[5]},
+		}
+		if len(values) == 7 {
+			link.Camera.FOV = values[6]
+		}
+	}
+	for _, f := range params["filter"] {
+		name, value, ok := strings.Cut(f, ":")
+		if !ok || name == "" {
+			return link, fmt.Errorf("%w: filter %q is not name:value", ErrInvalidDeepLink, f)
+		}
+		if link.Filters == nil {
+			link.Filters = make(map[string]string)
+		}
+		link.Filters[name] = value
+	}
+	if s := params.Get("t"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return link, fmt.Errorf("%w: time %q", ErrInvalidDeepLink, s)
+		}
+		link.Time = &t
+	}
+	return link, nil
+}
+
+// LinkToNode returns a deep link focusing a node, with a camera framing it
+// from the scene camera's direction
+func LinkToNode(sf *SceneFile, sceneID, nodeID string) (DeepLink, error) {
+	camera := Camera{FOV: DefaultCameraFOV}
+	if sf.Scene.Camera != nil {
+		camera = *sf.Scene.Camera
+	}
+	framed, err := FrameNodes(camera, sf, []string{nodeID}, 0.5)
+	if err != nil {
+		return DeepLink{}, err
+	}
+	return DeepLink{SceneID: sceneID, Node: nodeID, Camera: &framed}, nil
+}
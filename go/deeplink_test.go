@@ -0,0 +1,72 @@
+package starfleet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestDeepLink_RoundTrip tests encoding and parsing in both forms
+func TestDeepLink_RoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	link := DeepLink{
+		SceneID: "prod/us east",
+		Node:    "api&gateway",
+		View:    "overview",
+		Camera:  &Camera{Position: Vector3{X: 1.5, Y: 10, Z: -3}, Target: Vector3{}, FOV: 60},
+		Filters: map[string]string{"status": "critical", "tag": "team:payments"},
+		Time:    &at,
+	}
+
+	for _, base := range []string{"", "https://viewer.example.com/app/"} {
+		raw := link.Encode(base)
+		got, err := ParseDeepLink(raw)
+		if err != nil {
+			t.Fatalf("ParseDeepLink(%q) failed: %v", raw, err)
+		}
+		if !reflect.DeepEqual(got, link) {
+			t.Errorf("round trip mismatch for %q:\ngot  %+v\nwant %+v", raw, got, link)
+		}
+	}
+
+	want := "starfleet://scenes/prod%2Fus%20east#node=api%26gateway&view=overview&camera=1.5,10,-3,0,0,0,60" +
+		"&filter=status%3Acritical&filter=tag%3Ateam%3Apayments&t=2024-03-01T12%3A30%3A00Z"
+	if got := link.Encode(""); got != want {
+		t.Errorf("canonical form mismatch:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+// TestParseDeepLink_Errors tests rejection of malformed links
+func TestParseDeepLink_Errors(t *testing.T) {
+	for _, raw := range []string{
+		"https://viewer.example.com/dashboards/1",
+		"starfleet://nodes/prod",
+		"starfleet://scenes/",
+		"starfleet://scenes/prod#camera=1,2,3",
+		"starfleet://scenes/prod#filter=nocolon",
+		"starfleet://scenes/prod#t=yesterday",
+	} {
+		if _, err := ParseDeepLink(raw); !errors.Is(err, ErrInvalidDeepLink) {
+			t.Errorf("ParseDeepLink(%q) error mismatch: got %v, want %v", raw, err, ErrInvalidDeepLink)
+		}
+	}
+
+	link, err := ParseDeepLink("starfleet://scenes/prod#node=db&future=1")
+	if err != nil || link.Node != "db" {
+		t.Errorf("unknown keys should be ignored, got %+v, %v", link, err)
+	}
+}
+
+// TestLinkToNode tests focused links with a framing camera
+func TestLinkToNode(t *testing.T) {
+	sf := NewSceneFile("Links")
+	sf.Scene.Nodes = []SceneNode{{ID: "db", Transform: NewTransformWithPosition(5, 0, 0)}}
+	link, err := LinkToNode(&sf, "prod", "db")
+	if err != nil {
+		t.Fatalf("LinkToNode failed: %v", err)
+	}
+	if link.Camera == nil || link.Camera.Target != (Vector3{X: 5}) {
+		t.Errorf("camera mismatch: got %+v", link.Camera)
+	}
+}
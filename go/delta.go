@@ -0,0 +1,306 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// SCENE DELTAS
+// =============================================================================
+
+// ElementDelta records how one node or edge changed. Added elements carry
+// their full encoding in Value, removed ones set Removed, and modified ones
+// list their changed properties in Fields, where null removes a property.
+type ElementDelta struct {
+	ID      string                     `json:"id" validate:"required"`
+	Removed bool                       `json:"removed,omitempty"`
+	Value   json.RawMessage            `json:"value,omitempty"`
+	Fields  map[string]json.RawMessage `json:"fields,omitempty"`
+}
+
+// SceneDelta is a replayable form of a SceneDiff: it carries the new values
+// of everything the diff reports. Fields is keyed by the diff's dotted
+// paths. Applying a delta appends added elements; when that does not
+// reproduce the changed order, NodeOrder or EdgeOrder lists every ID in
+// order. Scenes with duplicate IDs cannot be diffed by ID, so their deltas
+// carry the whole changed scene in Snapshot instead.
+type SceneDelta struct {
+	Fields    map[string]json.RawMessage `json:"fields,omitempty"`
+	Nodes     []ElementDelta             `json:"nodes,omitempty"`
+	Edges     []ElementDelta             `json:"edges,omitempty"`
+	NodeOrder []string                   `json:"nodeOrder,omitempty"`
+	EdgeOrder []string                   `json:"edgeOrder,omitempty"`
+	Snapshot  *SceneFile                 `json:"snapshot,omitempty"`
+}
+
+// Empty reports whether applying the delta changes nothing
+func (d *SceneDelta) Empty() bool {
+	return d.Snapshot == nil && len(d.Fields) == 0 && len(d.Nodes) == 0 && len(d.Edges) == 0 &&
+		len(d.NodeOrder) == 0 && len(d.EdgeOrder) == 0
+}
+
+// ComputeDelta returns the delta that turns base into changed. As with Diff,
+// properties are compared by their JSON encoding, so applying the delta
+// reproduces changed up to empty containers.
+func ComputeDelta(base, changed *SceneFile) SceneDelta {
+	if hasDuplicateIDs(base) || hasDuplicateIDs(changed) {
+		snapshot := *changed
+		return SceneDelta{Snapshot: &snapshot}
+	}
+
+	diff := Diff(base, changed)
+	var d SceneDelta
+	if len(diff.Fields) > 0 {
+		file := encodeObject(changed)
+		d.Fields = make(map[string]json.RawMessage, len(diff.Fields))
+		for _, path := range diff.Fields {
+			d.Fields[path] = lookupPath(file, path)
+		}
+	}
+
+	nodes := make([]elementJSON, len(changed.Scene.Nodes))
+	for i := range changed.Scene.Nodes {
+		nodes[i] = elementJSON{changed.Scene.Nodes[i].ID, encodeObject(&changed.Scene.Nodes[i])}
+	}
+	d.Nodes = elementDeltas(diff.Nodes, nodes)
+	edges := make([]elementJSON, len(changed.Scene.Edges))
+	for i := range changed.Scene.Edges {
+		edges[i] = elementJSON{changed.Scene.Edges[i].ID, encodeObject(&changed.Scene.Edges[i])}
+	}
+	d.Edges = elementDeltas(diff.Edges, edges)
+
+	baseNodes, changedNodes := nodeIDs(base), nodeIDs(changed)
+	if !slices.Equal(replayOrder(baseNodes, d.Nodes), changedNodes) {
+		d.NodeOrder = changedNodes
+	}
+	baseEdges, changedEdges := edgeIDs(base), edgeIDs(changed)
+	if !slices.Equal(replayOrder(baseEdges, d.Edges), changedEdges) {
+		d.EdgeOrder = changedEdges
+	}
+	return d
+}
+
+// ApplyDelta replays a delta onto base and returns the result; base is not
+// modified
+func ApplyDelta(base *SceneFile, d SceneDelta) (SceneFile, error) {
+	if d.Snapshot != nil {
+		data, err := json.Marshal(d.Snapshot)
+		if err != nil {
+			return SceneFile{}, fmt.Errorf("apply delta: %w", err)
+		}
+		var out SceneFile
+		if err := json.Unmarshal(data, &out); err != nil {
+			return SceneFile{}, fmt.Errorf("apply delta: %w", err)
+		}
+		return out, nil
+	}
+
+	file := encodeObject(base)
+	if file == nil {
+		return SceneFile{}, fmt.Errorf("apply delta: cannot encode base scene")
+	}
+	graph := decodeObject(file["scene"])
+	if graph == nil {
+		graph = make(map[string]json.RawMessage)
+	}
+	for path, value := range d.Fields {
+		if err := setPath(file, graph, path, value); err != nil {
+			return SceneFile{}, err
+		}
+	}
+
+	nodes, err := applyElementDeltas(graph["nodes"], d.Nodes, d.NodeOrder)
+	if err != nil {
+		return SceneFile{}, fmt.Errorf("apply delta: nodes: %w", err)
+	}
+	edges, err := applyElementDeltas(graph["edges"], d.Edges, d.EdgeOrder)
+	if err != nil {
+		return SceneFile{}, fmt.Errorf("apply delta: edges: %w", err)
+	}
+	graph["nodes"], graph["edges"] = nodes, edges
+
+	var out SceneFile
+	if file["scene"], err = json.Marshal(graph); err == nil {
+		var data []byte
+		if data, err = json.Marshal(file); err == nil {
+			err = json.Unmarshal(data, &out)
+		}
+	}
+	if err != nil {
+		return SceneFile{}, fmt.Errorf("apply delta: %w", err)
+	}
+	return out, nil
+}
+
+// elementDeltas turns diff changes into deltas using the changed encodings
+func elementDeltas(changes []ElementChange, changed []elementJSON) []ElementDelta {
+	byID := make(map[string]map[string]json.RawMessage, len(changed))
+	for _, e := range changed {
+		byID[e.id] = e.fields
+	}
+	var deltas []ElementDelta
+	for _, c := range changes {
+		delta := ElementDelta{ID: c.ID}
+		switch c.Kind {
+		case ChangeRemoved:
+			delta.Removed = true
+		case ChangeAdded:
+			delta.Value, _ = json.Marshal(byID[c.ID])
+		case ChangeModified:
+			delta.Fields = make(map[string]json.RawMessage, len(c.Fields))
+			for _, f := range c.Fields {
+				if v, ok := byID[c.ID][f]; ok {
+					delta.Fields[f] = v
+				} else {
+					delta.Fields[f] = json.RawMessage("null")
+				}
+			}
+		}
+		deltas = append(deltas, delta)
+	}
+	return deltas
+}
+
+// applyElementDeltas replays element deltas onto an encoded element list
+func applyElementDeltas(data json.RawMessage, deltas []ElementDelta, order []string) (json.RawMessage, error) {
+	var raw []json.RawMessage
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	}
+	ids := make([]string, len(raw))
+	elements := make(map[string]map[string]json.RawMessage, len(raw))
+	for i, r := range raw {
+		fields := decodeObject(r)
+		if err := json.Unmarshal(fields["id"], &ids[i]); err != nil {
+			return nil, fmt.Errorf("element %d has no id", i)
+		}
+		elements[ids[i]] = fields
+	}
+
+	for _, d := range deltas {
+		fields, exists := elements[d.ID]
+		switch {
+		case d.Removed:
+			delete(elements, d.ID)
+		case d.Value != nil:
+			if exists {
+				return nil, fmt.Errorf("added element %s already exists", d.ID)
+			}
+			elements[d.ID] = decodeObject(d.Value)
+			ids = append(ids, d.ID)
+		default:
+			if !exists {
+				return nil, fmt.Errorf("modified element %s does not exist", d.ID)
+			}
+			for k, v := range d.Fields {
+				if string(v) == "null" {
+					delete(fields, k)
+				} else {
+					fields[k] = v
+				}
+			}
+		}
+	}
+
+	if order != nil {
+		ids = order
+	}
+	out := make([]map[string]json.RawMessage, 0, len(elements))
+	for _, id := range ids {
+		if fields, ok := elements[id]; ok {
+			out = append(out, fields)
+			delete(elements, id)
+		}
+	}
+	if len(elements) > 0 {
+		return nil, fmt.Errorf("order omits %d elements", len(elements))
+	}
+	return json.Marshal(out)
+}
+
+// replayOrder returns the element order applying deltas to ids yields
+func replayOrder(ids []string, deltas []ElementDelta) []string {
+	removed := make(map[string]bool)
+	var added []string
+	for _, d := range deltas {
+		if d.Removed {
+			removed[d.ID] = true
+		} else if d.Value != nil {
+			added = append(added, d.ID)
+		}
+	}
+	out := make([]string, 0, len(ids)+len(added))
+	for _, id := range ids {
+		if !removed[id] {
+			out = append(out, id)
+		}
+	}
+	return append(out, added...)
+}
+
+// lookupPath returns the encoded value at a diff path, or null
+func lookupPath(file map[string]json.RawMessage, path string) json.RawMessage {
+	top, rest, nested := strings.Cut(path, ".")
+	value, ok := file[top]
+	if nested {
+		value, ok = decodeObject(value)[rest]
+	}
+	if !ok {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+// setPath writes a value at a diff path; scene-level paths go to graph
+func setPath(file, graph map[string]json.RawMessage, path string, value json.RawMessage) error {
+	top, rest, nested := strings.Cut(path, ".")
+	target := file
+	switch {
+	case !nested:
+	case top == "scene":
+		target = graph
+	default:
+		inner := decodeObject(file[top])
+		if inner == nil {
+			inner = make(map[string]json.RawMessage)
+		}
+		if err := setPath(inner, nil, rest, value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(inner)
+		if err != nil {
+			return fmt.Errorf("apply delta: %s: %w", path, err)
+		}
+		file[top] = data
+		return nil
+	}
+	key := path
+	if nested {
+		key = rest
+	}
+	if string(value) == "null" {
+		delete(target, key)
+	} else {
+		target[key] = value
+	}
+	return nil
+}
+
+// hasDuplicateIDs reports whether any node or edge ID repeats
+func hasDuplicateIDs(sf *SceneFile) bool {
+	for _, ids := range [][]string{nodeIDs(sf), edgeIDs(sf)} {
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if seen[id] {
+				return true
+			}
+			seen[id] = true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestComputeDelta tests that applying a delta reproduces the changed scene
+func TestComputeDelta(t *testing.T) {
+	base := newDiffScene()
+	base.Scene.Nodes[1].Metadata = map[string]interface{}{"team": "core"}
+	changed := newDiffScene()
+	changed.Metadata.Description = "updated"
+	changed.Scene.Camera = &Camera{Position: Vector3{Z: 10}}
+	changed.Scene.Nodes[0].Status = NodeStatusCritical
+	changed.Scene.Nodes = append(changed.Scene.Nodes[:2], SceneNode{ID: "d", Type: "db", Name: "D", Transform: NewTransform()})
+	changed.Scene.Edges[0].Weight = 2
+	changed.AddEdge(SceneEdge{ID: "b-d", Source: "b", Target: "d"})
+
+	d := ComputeDelta(&base, &changed)
+	if len(d.Fields) != 2 || len(d.Nodes) != 4 || d.NodeOrder != nil || d.Snapshot != nil {
+		t.Errorf("delta mismatch: got %+v", d)
+	}
+	if got := string(d.Nodes[1].Fields["metadata"]); got != "null" {
+		t.Errorf("removed field mismatch: got %s, want null", got)
+	}
+	got, err := ApplyDelta(&base, d)
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if diff := Diff(&got, &changed); !diff.Empty() {
+		t.Errorf("reconstruction differs: %+v", diff)
+	}
+	if base.Metadata.Description != "" || len(base.Scene.Nodes) != 3 {
+		t.Error("ApplyDelta modified base")
+	}
+
+	// Reordering is carried explicitly
+	reordered := newDiffScene()
+	reordered.Scene.Nodes[0], reordered.Scene.Nodes[2] = reordered.Scene.Nodes[2], reordered.Scene.Nodes[0]
+	d = ComputeDelta(&base, &reordered)
+	if !reflect.DeepEqual(d.NodeOrder, []string{"c", "b", "a"}) {
+		t.Errorf("node order mismatch: got %v", d.NodeOrder)
+	}
+	if got, _ := ApplyDelta(&base, d); !reflect.DeepEqual(nodeIDs(&got), d.NodeOrder) {
+		t.Errorf("reordered nodes mismatch: got %v", nodeIDs(&got))
+	}
+
+	if d := ComputeDelta(&base, &base); !d.Empty() {
+		t.Errorf("expected empty delta, got %+v", d)
+	}
+	duplicate := newDiffScene()
+	duplicate.AddNode(SceneNode{ID: "a", Type: "server", Name: "A2", Transform: NewTransform()})
+	if d := ComputeDelta(&base, &duplicate); d.Snapshot == nil {
+		t.Error("expected snapshot delta for duplicate IDs")
+	}
+}
+
+// TestMemorySceneStore_Deltas tests delta storage and reconstruction
+func TestMemorySceneStore_Deltas(t *testing.T) {
+	ctx := context.Background()
+	full, deltas := NewMemorySceneStore(), NewMemorySceneStore()
+	deltas.SnapshotEvery = 4
+
+	sf := newDiffScene()
+	for i := 0; i < 60; i++ {
+		sf.AddNode(SceneNode{ID: fmt.Sprintf("n%d", i), Type: "server", Name: "Node", Transform: NewTransform()})
+	}
+	var scenes []SceneFile
+	for i := 0; i < 10; i++ {
+		sf.Scene.Nodes[0].Metrics = map[string]interface{}{"rps": float64(i)}
+		for _, s := range []*MemorySceneStore{full, deltas} {
+			if _, err := s.Put(ctx, "live", sf, AnyRevision); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+		}
+		data, _ := json.Marshal(&sf)
+		var copied SceneFile
+		json.Unmarshal(data, &copied)
+		scenes = append(scenes, copied)
+	}
+
+	for i, want := range scenes {
+		rev, err := deltas.GetRevision(ctx, "live", int64(i+1))
+		if err != nil {
+			t.Fatalf("GetRevision(%d) failed: %v", i+1, err)
+		}
+		if diff := Diff(&rev.Scene, &want); !diff.Empty() {
+			t.Errorf("revision %d differs: %+v", i+1, diff)
+		}
+	}
+
+	stats, _ := deltas.Stats("live")
+	fullStats, _ := full.Stats("live")
+	if stats.Revisions != 10 || stats.Snapshots != 3 || fullStats.Snapshots != 10 {
+		t.Errorf("stats mismatch: got %+v and %+v", stats, fullStats)
+	}
+	if stats.Bytes*2 > fullStats.Bytes {
+		t.Errorf("delta storage too large: got %d bytes, full %d", stats.Bytes, fullStats.Bytes)
+	}
+
+	d, err := deltas.Delta(ctx, "live", 6)
+	if err != nil || len(d.Nodes) != 1 || d.Nodes[0].ID != "a" {
+		t.Errorf("Delta mismatch: got %+v, %v", d, err)
+	}
+	if d, err := full.Delta(ctx, "live", 1); err != nil || d.Snapshot == nil {
+		t.Errorf("first revision delta mismatch: got %+v, %v", d, err)
+	}
+}
@@ -0,0 +1,717 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// =============================================================================
+// BINARY DELTA ENCODING
+// =============================================================================
+
+// DeltaContentType is the media type of binary scene deltas
+const DeltaContentType = "application/vnd.starfleet.delta"
+
+// deltaMagic starts every binary delta, followed by the format version
+const (
+	deltaMagic   = "SFD"
+	deltaVersion = 1
+)
+
+// ErrMalformedDelta is returned when decoding a binary delta fails
+var ErrMalformedDelta = errors.New("malformed binary delta")
+
+// A binary delta is a sequence of fields, each a varint key holding the
+// field tag shifted left by three over a wire type, as in protocol
+// buffers. Tags not listed are skipped by decoders, so fields can be added
+// without breaking older readers.
+const (
+	deltaTagField     = 1 // path string, value
+	deltaTagNode      = 2 // length-delimited element
+	deltaTagEdge      = 3 // length-delimited element
+	deltaTagNodeOrder = 4 // id string
+	deltaTagEdgeOrder = 5 // id string
+	deltaTagSnapshot  = 6 // value
+	deltaTagString    = 7 // string appended to the string table
+
+	elementTagID      = 1 // string
+	elementTagRemoved = 2 // varint
+	elementTagValue   = 3 // value
+	elementTagField   = 4 // name string, value
+)
+
+// Wire types
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// Values are JSON values in a compact form: a kind byte and its payload.
+// Object keys and field names are written as a varint, where 0 ends an
+// object, 1 precedes an inline string, 2 precedes a string table index and
+// larger values index deltaNames.
+//
+// The string table holds strings used more than once in a delta, such as
+// metric names and statuses repeated across nodes. Its fields come before
+// any reference to them.
+const (
+	valueNull byte = iota
+	valueFalse
+	valueTrue
+	valueInt     // zigzag varint
+	valueFloat32 // 4 bytes, for floats that survive the narrowing
+	valueFloat64 // 8 bytes
+	valueString  // varint length and bytes
+	valueRef     // varint string table index
+	valueArray   // values up to valueEnd
+	valueObject  // keys and values up to key 0
+	valueEnd
+)
+
+// deltaNames are the property names given short codes. Codes are part of
+// the format: names may be appended but never reordered or removed.
+var deltaNames = []string{
+	"id", "revision", "type", "name", "transform", "position", "rotation", "scale",
+	"x", "y", "z", "w", "r", "g", "b", "a", "geometry", "geometryRef", "material",
+	"materialRef", "label", "visible", "metadata", "tags", "metrics", "status",
+	"parent", "children", "ports", "extensions", "source", "target", "targetScene",
+	"sourcePort", "targetPort", "direction", "key", "weight", "waypoints", "color",
+	"width", "style", "opacity", "animations", "particles", "attachments",
+	"accessibility", "localizations", "physics", "ref", "slos", "panels", "joint",
+	"version", "scene", "camera", "lighting", "environment", "nodes", "edges",
+	"description", "author", "created", "updated", "value", "unit", "text",
+}
+
+// deltaNameCodes maps names to their codes
+var deltaNameCodes = func() map[string]uint64 {
+	codes := make(map[string]uint64, len(deltaNames))
+	for i, name := range deltaNames {
+		codes[name] = uint64(i) + 3
+	}
+	return codes
+}()
+
+// MarshalBinary encodes the delta in the compact binary form sent on
+// update streams. Only what changed is written: paths and property names
+// use short codes, repeated strings are written once, and numbers are
+// written as varints or the narrowest float that holds them.
+func (d *SceneDelta) MarshalBinary() ([]byte, error) {
+	// A first pass counts the strings worth putting in the table
+	strs := &deltaStrings{counts: make(map[string]int)}
+	if err := (&deltaWriter{strings: strs}).delta(d); err != nil {
+		return nil, fmt.Errorf("encode delta: %w", err)
+	}
+	w := &deltaWriter{strings: strs}
+	w.buf.WriteString(deltaMagic)
+	w.buf.WriteByte(deltaVersion)
+	strs.index = make(map[string]uint64)
+	for _, s := range strs.order {
+		if strs.counts[s] > 1 && len(s) > 2 {
+			strs.index[s] = uint64(len(strs.index))
+			w.key(deltaTagString, wireBytes)
+			w.string(s)
+		}
+	}
+	if err := w.delta(d); err != nil {
+		return nil, fmt.Errorf("encode delta: %w", err)
+	}
+	return w.buf.Bytes(), nil
+}
+
+// delta writes the fields of a delta
+func (w *deltaWriter) delta(d *SceneDelta) error {
+	if d.Snapshot != nil {
+		data, err := json.Marshal(d.Snapshot)
+		if err != nil {
+			return err
+		}
+		if err := w.embed(deltaTagSnapshot, func(sub *deltaWriter) error { return sub.value(data) }); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+	}
+	for _, path := range sortedKeys(d.Fields) {
+		if err := w.embed(deltaTagField, func(sub *deltaWriter) error {
+			sub.string(path)
+			return sub.value(d.Fields[path])
+		}); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, list := range []struct {
+		tag    uint64
+		deltas []ElementDelta
+	}{{deltaTagNode, d.Nodes}, {deltaTagEdge, d.Edges}} {
+		for _, e := range list.deltas {
+			if err := w.embed(list.tag, func(sub *deltaWriter) error { return sub.element(e) }); err != nil {
+				return fmt.Errorf("%s: %w", e.ID, err)
+			}
+		}
+	}
+	for _, id := range d.NodeOrder {
+		w.key(deltaTagNodeOrder, wireBytes)
+		w.string(id)
+	}
+	for _, id := range d.EdgeOrder {
+		w.key(deltaTagEdgeOrder, wireBytes)
+		w.string(id)
+	}
+	return nil
+}
+
+// element writes the fields of one element delta
+func (w *deltaWriter) element(e ElementDelta) error {
+	w.key(elementTagID, wireBytes)
+	w.string(e.ID)
+	if e.Removed {
+		w.key(elementTagRemoved, wireVarint)
+		w.uvarint(1)
+	}
+	if e.Value != nil {
+		if err := w.embed(elementTagValue, func(sub *deltaWriter) error { return sub.value(e.Value) }); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedKeys(e.Fields) {
+		if err := w.embed(elementTagField, func(sub *deltaWriter) error {
+			sub.name(name)
+			return sub.value(e.Fields[name])
+		}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary decodes a delta written by MarshalBinary, replacing d
+func (d *SceneDelta) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(deltaMagic)) || len(data) < len(deltaMagic)+1 {
+		return fmt.Errorf("%w: missing header", ErrMalformedDelta)
+	}
+	if v := data[len(deltaMagic)]; v != deltaVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrMalformedDelta, v)
+	}
+	r := &deltaReader{data: data[len(deltaMagic)+1:], strings: new([]string)}
+	var out SceneDelta
+	for !r.done() {
+		tag, wire, err := r.key()
+		if err != nil {
+			return err
+		}
+		switch {
+		case tag == deltaTagString && wire == wireBytes:
+			s, err := r.string()
+			if err != nil {
+				return err
+			}
+			*r.strings = append(*r.strings, s)
+		case tag == deltaTagSnapshot && wire == wireBytes:
+			sub, err := r.embedded()
+			if err != nil {
+				return err
+			}
+			value, err := sub.lastValue()
+			if err != nil {
+				return err
+			}
+			var snapshot SceneFile
+			if err := json.Unmarshal(value, &snapshot); err != nil {
+				return fmt.Errorf("%w: snapshot: %v", ErrMalformedDelta, err)
+			}
+			out.Snapshot = &snapshot
+		case tag == deltaTagField && wire == wireBytes:
+			sub, err := r.embedded()
+			if err != nil {
+				return err
+			}
+			path, err := sub.string()
+			if err != nil {
+				return err
+			}
+			value, err := sub.lastValue()
+			if err != nil {
+				return err
+			}
+			if out.Fields == nil {
+				out.Fields = make(map[string]json.RawMessage)
+			}
+			out.Fields[path] = value
+		case (tag == deltaTagNode || tag == deltaTagEdge) && wire == wireBytes:
+			sub, err := r.embedded()
+			if err != nil {
+				return err
+			}
+			e, err := sub.element()
+			if err != nil {
+				return err
+			}
+			if tag == deltaTagNode {
+				out.Nodes = append(out.Nodes, e)
+			} else {
+				out.Edges = append(out.Edges, e)
+			}
+		case (tag == deltaTagNodeOrder || tag == deltaTagEdgeOrder) && wire == wireBytes:
+			id, err := r.string()
+			if err != nil {
+				return err
+			}
+			if tag == deltaTagNodeOrder {
+				out.NodeOrder = append(out.NodeOrder, id)
+			} else {
+				out.EdgeOrder = append(out.EdgeOrder, id)
+			}
+		default:
+			if err := r.skip(wire); err != nil {
+				return err
+			}
+		}
+	}
+	*d = out
+	return nil
+}
+
+// element reads the fields of one element delta
+func (r *deltaReader) element() (ElementDelta, error) {
+	var e ElementDelta
+	for !r.done() {
+		tag, wire, err := r.key()
+		if err != nil {
+			return e, err
+		}
+		switch {
+		case tag == elementTagID && wire == wireBytes:
+			if e.ID, err = r.string(); err != nil {
+				return e, err
+			}
+		case tag == elementTagRemoved && wire == wireVarint:
+			v, err := r.uvarint()
+			if err != nil {
+				return e, err
+			}
+			e.Removed = v != 0
+		case tag == elementTagValue && wire == wireBytes:
+			sub, err := r.embedded()
+			if err != nil {
+				return e, err
+			}
+			if e.Value, err = sub.lastValue(); err != nil {
+				return e, err
+			}
+		case tag == elementTagField && wire == wireBytes:
+			sub, err := r.embedded()
+			if err != nil {
+				return e, err
+			}
+			name, err := sub.name()
+			if err != nil {
+				return e, err
+			}
+			value, err := sub.lastValue()
+			if err != nil {
+				return e, err
+			}
+			if e.Fields == nil {
+				e.Fields = make(map[string]json.RawMessage)
+			}
+			e.Fields[name] = value
+		default:
+			if err := r.skip(wire); err != nil {
+				return e, err
+			}
+		}
+	}
+	return e, nil
+}
+
+// sortedKeys returns the keys of encoded properties in order, so equal
+// deltas encode to equal bytes
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deltaWriter appends binary delta fields to a buffer
+type deltaWriter struct {
+	buf     bytes.Buffer
+	strings *deltaStrings
+}
+
+// deltaStrings is the string table of a delta being written. While index
+// is nil, uses are counted instead.
+type deltaStrings struct {
+	counts map[string]int
+	order  []string
+	index  map[string]uint64
+}
+
+// ref returns the table index of s, if it has one
+func (w *deltaWriter) ref(s string) (uint64, bool) {
+	t := w.strings
+	if t.index == nil {
+		if t.counts[s] == 0 {
+			t.order = append(t.order, s)
+		}
+		t.counts[s]++
+		return 0, false
+	}
+	i, ok := t.index[s]
+	return i, ok
+}
+
+func (w *deltaWriter) uvarint(v uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *deltaWriter) key(tag, wire uint64) {
+	w.uvarint(tag<<3 | wire)
+}
+
+func (w *deltaWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// embed writes a length-delimited field with the content fill writes
+func (w *deltaWriter) embed(tag uint64, fill func(sub *deltaWriter) error) error {
+	sub := &deltaWriter{strings: w.strings}
+	if err := fill(sub); err != nil {
+		return err
+	}
+	w.key(tag, wireBytes)
+	w.uvarint(uint64(sub.buf.Len()))
+	w.buf.Write(sub.buf.Bytes())
+	return nil
+}
+
+// name writes a property name by code or reference when it has one
+func (w *deltaWriter) name(s string) {
+	if code, ok := deltaNameCodes[s]; ok {
+		w.uvarint(code)
+		return
+	}
+	if i, ok := w.ref(s); ok {
+		w.uvarint(2)
+		w.uvarint(i)
+		return
+	}
+	w.uvarint(1)
+	w.string(s)
+}
+
+// value writes a JSON value in compact form, keeping object key order
+func (w *deltaWriter) value(data json.RawMessage) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := w.token(dec); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("trailing data after value")
+	}
+	return nil
+}
+
+// token writes the next JSON value of dec
+func (w *deltaWriter) token(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := tok.(type) {
+	case nil:
+		w.buf.WriteByte(valueNull)
+	case bool:
+		if v {
+			w.buf.WriteByte(valueTrue)
+		} else {
+			w.buf.WriteByte(valueFalse)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			w.buf.WriteByte(valueInt)
+			w.buf.Write(binary.AppendVarint(nil, i))
+			break
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		if float64(float32(f)) == f {
+			w.buf.WriteByte(valueFloat32)
+			w.buf.Write(binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(f))))
+		} else {
+			w.buf.WriteByte(valueFloat64)
+			w.buf.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)))
+		}
+	case string:
+		if i, ok := w.ref(v); ok {
+			w.buf.WriteByte(valueRef)
+			w.uvarint(i)
+			break
+		}
+		w.buf.WriteByte(valueString)
+		w.string(v)
+	case json.Delim:
+		switch v {
+		case '[':
+			w.buf.WriteByte(valueArray)
+			for dec.More() {
+				if err := w.token(dec); err != nil {
+					return err
+				}
+			}
+			w.buf.WriteByte(valueEnd)
+		case '{':
+			w.buf.WriteByte(valueObject)
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				w.name(k.(string))
+				if err := w.token(dec); err != nil {
+					return err
+				}
+			}
+			w.uvarint(0)
+		}
+		// Consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deltaMaxDepth bounds the nesting of decoded values, so hostile input
+// cannot exhaust the stack
+const deltaMaxDepth = 1000
+
+// deltaReader reads binary delta fields, failing with ErrMalformedDelta
+type deltaReader struct {
+	data    []byte
+	pos     int
+	strings *[]string
+}
+
+func (r *deltaReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *deltaReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrMalformedDelta, fmt.Sprintf(format, args...))
+}
+
+func (r *deltaReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, r.errorf("bad varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *deltaReader) key() (tag, wire uint64, err error) {
+	k, err := r.uvarint()
+	return k >> 3, k & 7, err
+}
+
+func (r *deltaReader) take(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, r.errorf("length %d past end", n)
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *deltaReader) bytes() ([]byte, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	return r.take(n)
+}
+
+func (r *deltaReader) string() (string, error) {
+	b, err := r.bytes()
+	return string(b), err
+}
+
+// name reads a property name. Code 0, which ends objects, is an error
+// here.
+func (r *deltaReader) name() (string, error) {
+	code, err := r.uvarint()
+	switch {
+	case err != nil:
+		return "", err
+	case code == 0:
+		return "", r.errorf("unexpected end of object")
+	case code == 1:
+		return r.string()
+	case code == 2:
+		return r.interned()
+	case code-3 >= uint64(len(deltaNames)):
+		return "", r.errorf("unknown name code %d", code)
+	}
+	return deltaNames[code-3], nil
+}
+
+// interned reads a string table reference
+func (r *deltaReader) interned() (string, error) {
+	i, err := r.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if i >= uint64(len(*r.strings)) {
+		return "", r.errorf("unknown string %d", i)
+	}
+	return (*r.strings)[i], nil
+}
+
+// skip passes over a field of an unknown tag
+func (r *deltaReader) skip(wire uint64) error {
+	switch wire {
+	case wireVarint:
+		_, err := r.uvarint()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	}
+	return r.errorf("unknown wire type %d", wire)
+}
+
+// embedded returns a reader over the content of a length-delimited field
+func (r *deltaReader) embedded() (*deltaReader, error) {
+	b, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &deltaReader{data: b, strings: r.strings}, nil
+}
+
+// lastValue reads a compact value back into JSON, which must end the
+// field
+func (r *deltaReader) lastValue() (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if err := r.appendValue(&buf, 0); err != nil {
+		return nil, err
+	}
+	if !r.done() {
+		return nil, r.errorf("trailing data after value")
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *deltaReader) appendValue(buf *bytes.Buffer, depth int) error {
+	if depth > deltaMaxDepth {
+		return r.errorf("values nested too deeply")
+	}
+	if r.done() {
+		return r.errorf("missing value")
+	}
+	kind := r.data[r.pos]
+	r.pos++
+	switch kind {
+	case valueNull:
+		buf.WriteString("null")
+	case valueFalse:
+		buf.WriteString("false")
+	case valueTrue:
+		buf.WriteString("true")
+	case valueInt:
+		v, n := binary.Varint(r.data[r.pos:])
+		if n <= 0 {
+			return r.errorf("bad varint")
+		}
+		r.pos += n
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case valueFloat32, valueFloat64:
+		var f float64
+		if kind == valueFloat32 {
+			b, err := r.take(4)
+			if err != nil {
+				return err
+			}
+			f = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		} else {
+			b, err := r.take(8)
+			if err != nil {
+				return err
+			}
+			f = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return r.errorf("non-finite number")
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case valueString, valueRef:
+		read := r.string
+		if kind == valueRef {
+			read = r.interned
+		}
+		s, err := read()
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(s)
+		buf.Write(data)
+	case valueArray:
+		buf.WriteByte('[')
+		for first := true; ; first = false {
+			if r.done() {
+				return r.errorf("unterminated array")
+			}
+			if r.data[r.pos] == valueEnd {
+				r.pos++
+				break
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			if err := r.appendValue(buf, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case valueObject:
+		buf.WriteByte('{')
+		for first := true; ; first = false {
+			if r.done() {
+				return r.errorf("unterminated object")
+			}
+			if r.data[r.pos] == 0 {
+				r.pos++
+				break
+			}
+			name, err := r.name()
+			if err != nil {
+				return err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			data, _ := json.Marshal(name)
+			buf.Write(data)
+			buf.WriteByte(':')
+			if err := r.appendValue(buf, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return r.errorf("unknown value kind %d", kind)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// newDeltaPair returns a scene and a changed copy exercising every kind of
+// delta content
+func newDeltaPair() (SceneFile, SceneFile) {
+	base := newDiffScene()
+	base.Scene.Nodes[1].Metadata = map[string]interface{}{"team": "core"}
+	changed := newDiffScene()
+	changed.Metadata.Description = "updated"
+	changed.Scene.Camera = &Camera{Position: Vector3{Z: 10.25}}
+	changed.Scene.Nodes[0].Status = NodeStatusCritical
+	changed.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": 0.1, "rps": -12, "label": "hot"}
+	changed.Scene.Nodes = append(changed.Scene.Nodes[:2], SceneNode{ID: "d", Type: "db", Name: "D", Transform: NewTransform(), Tags: []string{"x", "ü"}})
+	changed.Scene.Edges[0].Weight = 2
+	changed.AddEdge(SceneEdge{ID: "b-d", Source: "b", Target: "d"})
+	changed.Scene.Nodes[0], changed.Scene.Nodes[1] = changed.Scene.Nodes[1], changed.Scene.Nodes[0]
+	return base, changed
+}
+
+// sameDelta reports whether two deltas carry the same JSON values
+func sameDelta(a, b SceneDelta) bool {
+	var va, vb interface{}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return json.Unmarshal(ja, &va) == nil && json.Unmarshal(jb, &vb) == nil && reflect.DeepEqual(va, vb)
+}
+
+// TestSceneDelta_Binary tests that binary deltas decode to deltas that
+// replay the same change
+func TestSceneDelta_Binary(t *testing.T) {
+	base, changed := newDeltaPair()
+	d := ComputeDelta(&base, &changed)
+	data, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var decoded SceneDelta
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if !sameDelta(decoded, d) {
+		t.Errorf("delta mismatch: got %+v, want %+v", decoded, d)
+	}
+	got, err := ApplyDelta(&base, decoded)
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	if diff := Diff(&got, &changed); !diff.Empty() {
+		t.Errorf("reconstruction differs: %+v", diff)
+	}
+
+	// Snapshots survive too
+	snapshot := SceneDelta{Snapshot: &changed}
+	if data, err = snapshot.MarshalBinary(); err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	if err := decoded.UnmarshalBinary(data); err != nil || decoded.Snapshot == nil || !Diff(decoded.Snapshot, &changed).Empty() {
+		t.Errorf("snapshot mismatch: got %+v, %v", decoded.Snapshot, err)
+	}
+}
+
+// TestSceneDelta_BinarySize tests that a live metrics update is much
+// smaller in binary than as JSON
+func TestSceneDelta_BinarySize(t *testing.T) {
+	base := newLargeScene(200)
+	changed := newLargeScene(200)
+	for i := range changed.Scene.Nodes {
+		changed.Scene.Nodes[i].Status = NodeStatusWarning
+		changed.Scene.Nodes[i].Metrics = map[string]interface{}{"cpu": float64(i%100) / 4, "requests": i * 10}
+	}
+	d := ComputeDelta(&base, &changed)
+	data, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var ops []PatchOp
+	for _, e := range d.Nodes {
+		for _, name := range sortedKeys(e.Fields) {
+			ops = append(ops, PatchOp{Op: "replace", Path: "/nodes/" + e.ID + "/" + name, Value: e.Fields[name]})
+		}
+	}
+	text, _ := json.Marshal(d)
+	patch, _ := json.Marshal(ops)
+	t.Logf("binary %d bytes, JSON delta %d bytes, JSON patch %d bytes", len(data), len(text), len(patch))
+	if len(data)*2 > len(text) || len(data)*4 > len(patch) {
+		t.Errorf("size mismatch: binary %d bytes, want under half of the JSON delta and a quarter of the JSON patch", len(data))
+	}
+}
+
+// TestSceneDelta_BinaryMalformed tests that damaged input is rejected
+func TestSceneDelta_BinaryMalformed(t *testing.T) {
+	base, changed := newDeltaPair()
+	d := ComputeDelta(&base, &changed)
+	data, _ := d.MarshalBinary()
+
+	tests := map[string][]byte{
+		"empty":     nil,
+		"header":    []byte("XYZ\x01"),
+		"version":   []byte("SFD\x09"),
+		"truncated": data[:len(data)-1],
+		"wire":      append([]byte("SFD\x01"), 1<<3|5),
+		"name code": append([]byte("SFD\x01"), deltaTagNode<<3|wireBytes, 4, elementTagField<<3|wireBytes, 2, 0x7f, valueNull),
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out SceneDelta
+			if err := out.UnmarshalBinary(input); !errors.Is(err, ErrMalformedDelta) {
+				t.Errorf("error mismatch: got %v, want %v", err, ErrMalformedDelta)
+			}
+		})
+	}
+
+	// Unknown fields are skipped
+	extended := append(append([]byte{}, data...), 15<<3|wireVarint, 1, 14<<3|wireBytes, 2, 'h', 'i')
+	var out SceneDelta
+	if err := out.UnmarshalBinary(extended); err != nil || !sameDelta(out, d) {
+		t.Errorf("extended delta mismatch: got %+v, %v", out, err)
+	}
+}
+
+// FuzzSceneDelta_Binary tests that decoding never panics and that every
+// delta it accepts round-trips
+func FuzzSceneDelta_Binary(f *testing.F) {
+	base, changed := newDeltaPair()
+	for _, d := range []SceneDelta{ComputeDelta(&base, &changed), ComputeDelta(&changed, &base), {Snapshot: &changed}} {
+		data, err := d.MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte("SFD\x01"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var d SceneDelta
+		if err := d.UnmarshalBinary(data); err != nil {
+			return
+		}
+		again, err := d.MarshalBinary()
+		if err != nil {
+			t.Fatalf("re-encoding a decoded delta failed: %v", err)
+		}
+		var round SceneDelta
+		if err := round.UnmarshalBinary(again); err != nil {
+			t.Fatalf("decoding a re-encoded delta failed: %v", err)
+		}
+		if !sameDelta(round, d) {
+			t.Errorf("round trip mismatch: got %s, want %s", fmt.Sprint(round), fmt.Sprint(d))
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// =============================================================================
+// DIAGNOSTICS
+// =============================================================================
+
+// Severity is the level of a diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a validation problem located in a scene file. Path is a
+// JSONPath such as "$.scene.nodes[2].ports[0]"; Line and Column are
+// one-based and only set when the file's source is known.
+type Diagnostic struct {
+	Severity Severity `json:"severity" validate:"required,oneof=error warning"`
+	Message  string   `json:"message" validate:"required"`
+	Path     string   `json:"path,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+}
+
+// Location formats where the diagnostic is in a file as
+// "file:line:column", falling back to the file and JSONPath
+func (d Diagnostic) Location(file string) string {
+	switch {
+	case d.Line > 0:
+		return fmt.Sprintf("%s:%d:%d", file, d.Line, d.Column)
+	case d.Path != "":
+		return fmt.Sprintf("%s (%s)", file, d.Path)
+	}
+	return file
+}
+
+var (
+	// "Attachment a1 on node web ...", "SLO latency on node web ..."
+	subElementMessage = regexp.MustCompile(`^(Attachment|Panel|Port|SLO) (\S+) on node (\S+)`)
+	// "Node web ...", "Edge web-db ..."
+	elementMessage = regexp.MustCompile(`^(Node|Edge) (\S+)`)
+	// "Duplicate node id: web"
+	duplicateMessage = regexp.MustCompile(`^Duplicate (node|edge) id: (.+)$`)
+)
+
+// sceneFieldMessages locates the scene-level messages of ValidateScene
+var sceneFieldMessages = map[string]string{
+	"Scene file must have a version": "$.version",
+	"Scene file must have a name":    "$.metadata.name",
+	"Scene file must have nodes":     "$.scene.nodes",
+}
+
+// Diagnose turns a validation result into diagnostics located by JSONPath.
+// Locations are derived from the element IDs the messages name; messages
+// that name none are located at the document root.
+func Diagnose(sf *SceneFile, result ValidationResult) []Diagnostic {
+	diags := make([]Diagnostic, 0, len(result.Errors)+len(result.Warnings))
+	for _, msg := range result.Errors {
+		diags = append(diags, Diagnostic{Severity: SeverityError, Message: msg, Path: locateMessage(sf, msg)})
+	}
+	for _, msg := range result.Warnings {
+		diags = append(diags, Diagnostic{Severity: SeverityWarning, Message: msg, Path: locateMessage(sf, msg)})
+	}
+	return diags
+}
+
+// locateMessage returns the JSONPath of the element a message is about
+func locateMessage(sf *SceneFile, msg string) string {
+	if path, ok := sceneFieldMessages[msg]; ok {
+		return path
+	}
+	if m := subElementMessage.FindStringSubmatch(msg); m != nil {
+		i := nodeIndex(sf, m[3], 0)
+		if i < 0 {
+			return "$"
+		}
+		node := &sf.Scene.Nodes[i]
+		path := fmt.Sprintf("$.scene.nodes[%d]", i)
+		var field string
+		var ids []string
+		switch m[1] {
+		case "Attachment":
+			field = "attachments"
+			for _, a := range node.Attachments {
+				ids = append(ids, a.ID)
+			}
+		case "Panel":
+			field = "panels"
+			for _, p := range node.Panels {
+				ids = append(ids, p.ID)
+			}
+		case "Port":
+			field = "ports"
+			for _, p := range node.Ports {
+				ids = append(ids, p.ID)
+			}
+		case "SLO":
+			field = "slos"
+			for _, s := range node.SLOs {
+				ids = append(ids, s.Name)
+			}
+		}
+		for j, id := range ids {
+			if id == m[2] {
+				return fmt.Sprintf("%s.%s[%d]", path, field, j)
+			}
+		}
+		return path
+	}
+	if m := duplicateMessage.FindStringSubmatch(msg); m != nil {
+		// The duplicate is the second element with the ID
+		if m[1] == "node" {
+			if i := nodeIndex(sf, m[2], 1); i >= 0 {
+				return fmt.Sprintf("$.scene.nodes[%d]", i)
+			}
+		} else if i := edgeIndex(sf, m[2], 1); i >= 0 {
+			return fmt.Sprintf("$.scene.edges[%d]", i)
+		}
+		return "$"
+	}
+	if m := elementMessage.FindStringSubmatch(msg); m != nil {
+		if m[1] == "Node" {
+			if i := nodeIndex(sf, m[2], 0); i >= 0 {
+				return fmt.Sprintf("$.scene.nodes[%d]", i)
+			}
+		} else if i := edgeIndex(sf, m[2], 0); i >= 0 {
+			return fmt.Sprintf("$.scene.edges[%d]", i)
+		}
+	}
+	return "$"
+}
+
+// nodeIndex returns the index of the node with an ID after skipping the
+// given number of earlier matches, or -1
+func nodeIndex(sf *SceneFile, id string, skip int) int {
+	for i := range sf.Scene.Nodes {
+		if sf.Scene.Nodes[i].ID == id {
+			if skip == 0 {
+				return i
+			}
+			skip--
+		}
+	}
+	return -1
+}
+
+// edgeIndex is nodeIndex for edges
+func edgeIndex(sf *SceneFile, id string, skip int) int {
+	for i := range sf.Scene.Edges {
+		if sf.Scene.Edges[i].ID == id {
+			if skip == 0 {
+				return i
+			}
+			skip--
+		}
+	}
+	return -1
+}
+
+// LocateDiagnostics sets the line and column of diagnostics from the JSON
+// source of the scene file. A path that does not occur in the source, as
+// for defaults the file omits, falls back to its closest ancestor.
+func LocateDiagnostics(data []byte, diags []Diagnostic) {
+	offsets := jsonOffsets(data)
+	for i := range diags {
+		path := diags[i].Path
+		for path != "" {
+			if off, ok := offsets[path]; ok {
+				diags[i].Line, diags[i].Column = lineColumn(data, off)
+				break
+			}
+			path = parentPath(path)
+		}
+	}
+}
+
+// jsonOffsets maps the JSONPath of every value in a document to the offset
+// where it starts. Parsing stops at the first syntax error, keeping the
+// paths seen so far.
+func jsonOffsets(data []byte) map[string]int {
+	offsets := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// The decoder's offset follows the previous token, so skip the
+	// separators before the value
+	start := func() int {
+		off := int(dec.InputOffset())
+		for off < len(data) && strings.IndexByte(" \t\r\n,:", data[off]) >= 0 {
+			off++
+		}
+		return off
+	}
+	var walk func(path string) error
+	walk = func(path string) error {
+		offsets[path] = start()
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := walk(fmt.Sprintf("%s.%v", path, key)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	_ = walk("$")
+	return offsets
+}
+
+// lineColumn converts a byte offset to a one-based line and column
+func lineColumn(data []byte, off int) (int, int) {
+	off = min(off, len(data))
+	line := bytes.Count(data[:off], []byte{'\n'}) + 1
+	return line, off - bytes.LastIndexByte(data[:off], '\n')
+}
+
+// parentPath returns the JSONPath of the enclosing value, or "" for the root
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i <= 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+// NewFileValidation validates a scene file's JSON source with CheckScene
+// and locates the problems found. Source that does not decode is reported
+// as an error at the position the decoder stopped.
+func NewFileValidation(path string, data []byte) FileValidation {
+	var sf SceneFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		result := ValidationResult{Errors: []string{fmt.Sprintf("Invalid scene JSON: %v", err)}, Warnings: []string{}}
+		diag := Diagnostic{Severity: SeverityError, Message: result.Errors[0], Path: "$"}
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			diag.Line, diag.Column = lineColumn(data, int(syntax.Offset))
+		}
+		return FileValidation{Path: path, Result: result, Diagnostics: []Diagnostic{diag}}
+	}
+	result := CheckScene(&sf)
+	diags := Diagnose(&sf, result)
+	LocateDiagnostics(data, diags)
+	return FileValidation{Path: path, Result: result, Diagnostics: diags}
+}
@@ -0,0 +1,96 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const diagnosticsSource = `{
+  "version": "1.0.0",
+  "metadata": {"name": "Diagnostics"},
+  "scene": {
+    "nodes": [
+      {"id": "web", "type": "server", "name": "Web"},
+      {"id": "db", "type": "database",
+       "ports": [{"id": "p1", "position": {"x": 0, "y": 0, "z": 0}}]}
+    ],
+    "edges": [
+      {"id": "web-cache", "source": "web", "target": "cache"}
+    ]
+  }
+}`
+
+// TestNewFileValidation tests locating validation problems in the source
+func TestNewFileValidation(t *testing.T) {
+	fv := NewFileValidation("scene.json", []byte(diagnosticsSource))
+	if fv.Result.Valid {
+		t.Fatalf("expected errors: got %+v", fv.Result)
+	}
+	byPath := make(map[string]Diagnostic)
+	for _, d := range fv.Diagnostics {
+		byPath[d.Path] = d
+	}
+	edge, ok := byPath["$.scene.edges[0]"]
+	if !ok || edge.Severity != SeverityError || edge.Line != 11 || edge.Column != 7 {
+		t.Errorf("edge diagnostic mismatch: got %+v", fv.Diagnostics)
+	}
+	node, ok := byPath["$.scene.nodes[1]"]
+	if !ok || node.Severity != SeverityWarning || node.Line != 7 {
+		t.Errorf("node diagnostic mismatch: got %+v", fv.Diagnostics)
+	}
+	if got, want := edge.Location("scene.json"), "scene.json:11:7"; got != want {
+		t.Errorf("location mismatch: got %v, want %v", got, want)
+	}
+
+	fv = NewFileValidation("broken.json", []byte("{\n  \"version\": ,\n}"))
+	if len(fv.Diagnostics) != 1 || fv.Diagnostics[0].Line != 2 || !strings.HasPrefix(fv.Diagnostics[0].Message, "Invalid scene JSON") {
+		t.Errorf("syntax error mismatch: got %+v", fv.Diagnostics)
+	}
+}
+
+// TestLocateDiagnostics tests falling back to the closest ancestor in the source
+func TestLocateDiagnostics(t *testing.T) {
+	diags := []Diagnostic{
+		{Path: "$.scene.nodes[0].transform"},
+		{Path: "$"},
+		{Path: "$.missing"},
+	}
+	LocateDiagnostics([]byte(diagnosticsSource), diags)
+	want := [][2]int{{6, 7}, {1, 1}, {1, 1}}
+	for i, d := range diags {
+		if d.Line != want[i][0] || d.Column != want[i][1] {
+			t.Errorf("%s: location mismatch: got %d:%d, want %d:%d", d.Path, d.Line, d.Column, want[i][0], want[i][1])
+		}
+	}
+}
+
+// TestEncodeJUnit tests JUnit XML reports
+func TestEncodeJUnit(t *testing.T) {
+	good := FileValidation{Path: "good.json", Result: ValidationResult{Valid: true}}
+	bad := NewFileValidation("bad.json", []byte(diagnosticsSource))
+	var buf bytes.Buffer
+	if err := EncodeJUnit(&buf, []FileValidation{good, bad}); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v\n%s", err, buf.String())
+	}
+	errs := len(bad.Result.Errors)
+	if len(doc.Suites) != 2 || doc.Failures != errs || doc.Tests != 1+len(bad.Diagnostics) {
+		t.Fatalf("report mismatch: got %s", buf.String())
+	}
+	if c := doc.Suites[0].Cases; len(c) != 1 || c[0].Name != "valid" || c[0].Failure != nil {
+		t.Errorf("valid suite mismatch: got %+v", c)
+	}
+	for _, c := range doc.Suites[1].Cases {
+		if c.Failure != nil && c.Failure.Text == "" {
+			t.Errorf("failure without location: got %+v", c)
+		}
+		if c.Failure == nil && !strings.Contains(c.SystemOut, "warning") {
+			t.Errorf("warning mismatch: got %+v", c)
+		}
+	}
+}
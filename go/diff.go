@@ -0,0 +1,212 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// =============================================================================
+// SCENE DIFF
+// =============================================================================
+
+// ChangeKind describes how an element differs between two scenes
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// ElementChange describes a node or edge that differs between two scenes.
+// Fields lists the JSON names of the properties that changed and is only
+// set for modified elements.
+type ElementChange struct {
+	ID     string     `json:"id" validate:"required"`
+	Kind   ChangeKind `json:"kind" validate:"required"`
+	Fields []string   `json:"fields,omitempty"`
+}
+
+// SceneDiff is a machine-readable description of the differences between two
+// scenes. Fields holds dotted paths of changed scene-level properties such as
+// "metadata.name" or "scene.camera"; nodes and edges are reported per ID.
+type SceneDiff struct {
+	Fields []string        `json:"fields,omitempty"`
+	Nodes  []ElementChange `json:"nodes,omitempty"`
+	Edges  []ElementChange `json:"edges,omitempty"`
+}
+
+// Empty reports whether the diff contains no changes
+func (d SceneDiff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Nodes) == 0 && len(d.Edges) == 0
+}
+
+// Count returns the total number of changed fields, nodes and edges
+func (d SceneDiff) Count() int {
+	return len(d.Fields) + len(d.Nodes) + len(d.Edges)
+}
+
+// Diff compares two scenes. Nodes and edges are matched by ID; added and
+// modified elements follow their order in changed, followed by removals in
+// base order. Properties are compared by their JSON encoding, so a nil map
+// and an empty one are considered equal.
+func Diff(base, changed *SceneFile) SceneDiff {
+	d, _ := DiffContext(context.Background(), base, changed)
+	return d
+}
+
+// DiffContext is Diff for scenes large enough that the caller may give up
+// on the comparison. It returns the context's error once it is done.
+func DiffContext(ctx context.Context, base, changed *SceneFile) (SceneDiff, error) {
+	var d SceneDiff
+	c := canceler{ctx: ctx}
+	if err := ctx.Err(); err != nil {
+		return d, err
+	}
+
+	// The graph is compared separately so nodes and edges can be matched by ID
+	baseFile, changedFile := encodeObject(base), encodeObject(changed)
+	delete(baseFile, "scene")
+	delete(changedFile, "scene")
+	d.Fields = append(d.Fields, diffObjects("", baseFile, changedFile, map[string]bool{"metadata": true})...)
+	baseGraph, changedGraph := encodeObject(base.Scene), encodeObject(changed.Scene)
+	delete(baseGraph, "nodes")
+	delete(baseGraph, "edges")
+	delete(changedGraph, "nodes")
+	delete(changedGraph, "edges")
+	d.Fields = append(d.Fields, diffObjects("scene.", baseGraph, changedGraph, nil)...)
+	sort.Strings(d.Fields)
+
+	baseNodes := make([]elementJSON, len(base.Scene.Nodes))
+	for i := range base.Scene.Nodes {
+		if err := c.check(); err != nil {
+			return SceneDiff{}, err
+		}
+		baseNodes[i] = elementJSON{base.Scene.Nodes[i].ID, encodeObject(&base.Scene.Nodes[i])}
+	}
+	changedNodes := make([]elementJSON, len(changed.Scene.Nodes))
+	for i := range changed.Scene.Nodes {
+		if err := c.check(); err != nil {
+			return SceneDiff{}, err
+		}
+		changedNodes[i] = elementJSON{changed.Scene.Nodes[i].ID, encodeObject(&changed.Scene.Nodes[i])}
+	}
+	var err error
+	if d.Nodes, err = diffElements(&c, baseNodes, changedNodes); err != nil {
+		return SceneDiff{}, err
+	}
+
+	baseEdges := make([]elementJSON, len(base.Scene.Edges))
+	for i := range base.Scene.Edges {
+		if err := c.check(); err != nil {
+			return SceneDiff{}, err
+		}
+		baseEdges[i] = elementJSON{base.Scene.Edges[i].ID, encodeObject(&base.Scene.Edges[i])}
+	}
+	changedEdges := make([]elementJSON, len(changed.Scene.Edges))
+	for i := range changed.Scene.Edges {
+		if err := c.check(); err != nil {
+			return SceneDiff{}, err
+		}
+		changedEdges[i] = elementJSON{changed.Scene.Edges[i].ID, encodeObject(&changed.Scene.Edges[i])}
+	}
+	if d.Edges, err = diffElements(&c, baseEdges, changedEdges); err != nil {
+		return SceneDiff{}, err
+	}
+
+	return d, nil
+}
+
+// elementJSON is a node or edge encoded as a JSON object
+type elementJSON struct {
+	id     string
+	fields map[string]json.RawMessage
+}
+
+// diffElements matches elements by ID and reports additions, removals and
+// per-field modifications. Element revisions only count changes and are
+// not compared.
+func diffElements(c *canceler, base, changed []elementJSON) ([]ElementChange, error) {
+	baseByID := make(map[string]map[string]json.RawMessage, len(base))
+	for _, e := range base {
+		delete(e.fields, "revision")
+		baseByID[e.id] = e.fields
+	}
+	for _, e := range changed {
+		delete(e.fields, "revision")
+	}
+	seen := make(map[string]bool, len(changed))
+	var changes []ElementChange
+	for _, e := range changed {
+		if err := c.check(); err != nil {
+			return nil, err
+		}
+		seen[e.id] = true
+		old, ok := baseByID[e.id]
+		if !ok {
+			changes = append(changes, ElementChange{ID: e.id, Kind: ChangeAdded})
+			continue
+		}
+		if fields := diffObjects("", old, e.fields, nil); len(fields) > 0 {
+			sort.Strings(fields)
+			changes = append(changes, ElementChange{ID: e.id, Kind: ChangeModified, Fields: fields})
+		}
+	}
+	for _, e := range base {
+		if !seen[e.id] {
+			seen[e.id] = true
+			changes = append(changes, ElementChange{ID: e.id, Kind: ChangeRemoved})
+		}
+	}
+	return changes, nil
+}
+
+// diffObjects returns the prefixed names of keys whose values differ. Keys
+// listed in descend are compared one level deeper.
+func diffObjects(prefix string, a, b map[string]json.RawMessage, descend map[string]bool) []string {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	var fields []string
+	for k := range keys {
+		if descend[k] {
+			fields = append(fields, diffObjects(prefix+k+".", decodeObject(a[k]), decodeObject(b[k]), nil)...)
+			continue
+		}
+		if !bytes.Equal(a[k], b[k]) {
+			fields = append(fields, prefix+k)
+		}
+	}
+	return fields
+}
+
+// encodeObject encodes v and splits the result into its top-level members.
+// Empty containers are dropped so they compare equal to absent ones.
+func encodeObject(v interface{}) map[string]json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return decodeObject(data)
+}
+
+// decodeObject splits a JSON object into its members
+func decodeObject(data json.RawMessage) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if len(data) == 0 || json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+	for k, v := range fields {
+		switch string(v) {
+		case "null", "{}", "[]", `""`:
+			delete(fields, k)
+		}
+	}
+	return fields
+}
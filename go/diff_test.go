@@ -0,0 +1,64 @@
+package starfleet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newDiffScene() SceneFile {
+	sf := NewSceneFile("Diff")
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sf.Metadata.Created, sf.Metadata.Updated = &at, &at
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "A", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "b", Type: "server", Name: "B", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "c", Type: "server", Name: "C", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "a-b", Source: "a", Target: "b"})
+	return sf
+}
+
+// TestDiff tests detection of added, removed and modified elements
+func TestDiff(t *testing.T) {
+	base := newDiffScene()
+	changed := newDiffScene()
+	changed.Metadata.Description = "updated"
+	changed.Scene.Camera = &Camera{Position: Vector3{Z: 10}}
+	changed.Scene.Nodes[0].Transform.Position.X = 5
+	changed.Scene.Nodes[0].Status = NodeStatusCritical
+	changed.Scene.Nodes = append(changed.Scene.Nodes[:2], SceneNode{ID: "d", Type: "db", Name: "D", Transform: NewTransform()})
+	changed.Scene.Edges[0].Weight = 2
+	changed.AddEdge(SceneEdge{ID: "b-d", Source: "b", Target: "d"})
+
+	d := Diff(&base, &changed)
+	if want := []string{"metadata.description", "scene.camera"}; !reflect.DeepEqual(d.Fields, want) {
+		t.Errorf("fields mismatch: got %v, want %v", d.Fields, want)
+	}
+	wantNodes := []ElementChange{
+		{ID: "a", Kind: ChangeModified, Fields: []string{"status", "transform"}},
+		{ID: "d", Kind: ChangeAdded},
+		{ID: "c", Kind: ChangeRemoved},
+	}
+	if !reflect.DeepEqual(d.Nodes, wantNodes) {
+		t.Errorf("nodes mismatch: got %+v, want %+v", d.Nodes, wantNodes)
+	}
+	wantEdges := []ElementChange{
+		{ID: "a-b", Kind: ChangeModified, Fields: []string{"weight"}},
+		{ID: "b-d", Kind: ChangeAdded},
+	}
+	if !reflect.DeepEqual(d.Edges, wantEdges) {
+		t.Errorf("edges mismatch: got %+v, want %+v", d.Edges, wantEdges)
+	}
+	if d.Count() != 7 {
+		t.Errorf("count mismatch: got %d, want 7", d.Count())
+	}
+}
+
+// TestDiff_Identical tests that equal scenes produce an empty diff
+func TestDiff_Identical(t *testing.T) {
+	base := newDiffScene()
+	changed := newDiffScene()
+	changed.Scene.Nodes[1].Metadata = map[string]interface{}{}
+	if d := Diff(&base, &changed); !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+}
@@ -0,0 +1,355 @@
+package starfleet
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// =============================================================================
+// DOWNGRADE
+// =============================================================================
+
+// DowngradeChange records one construct that was converted or removed while
+// downgrading a scene. Lossy changes drop information that cannot be
+// recovered from the downgraded scene.
+type DowngradeChange struct {
+	Capability Capability `json:"capability"`
+	Node       string     `json:"node,omitempty"`
+	Edge       string     `json:"edge,omitempty"`
+	Detail     string     `json:"detail"`
+	Lossy      bool       `json:"lossy,omitempty"`
+}
+
+// DowngradeReport describes what Downgrade did to a scene
+type DowngradeReport struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Changes []DowngradeChange `json:"changes,omitempty"`
+}
+
+// Lost returns the changes that dropped information
+func (r DowngradeReport) Lost() []DowngradeChange {
+	var lost []DowngradeChange
+	for _, c := range r.Changes {
+		if c.Lossy {
+			lost = append(lost, c)
+		}
+	}
+	return lost
+}
+
+// Downgrade returns a copy of the scene that a reader of targetVersion can
+// consume, along with a report of every construct that was converted or
+// removed. Where the older format has an equivalent the construct is
+// converted: library resources are inlined, localized text is resolved for
+// the scene locale, compressed meshes are expanded, and edge semantics and
+// ports move into edge metadata. Element revisions are dropped for the store
+// to renumber on the next write. Everything else the target does not
+// understand is removed. The input scene is not modified.
+func Downgrade(sf SceneFile, targetVersion string) (SceneFile, DowngradeReport, error) {
+	report := DowngradeReport{From: sf.Version, To: targetVersion}
+	target, err := ParseSemVer(targetVersion)
+	if err != nil {
+		return SceneFile{}, report, fmt.Errorf("downgrade: %w", err)
+	}
+	if current, err := ParseSemVer(sf.Version); err == nil && current.Compare(target) < 0 {
+		return SceneFile{}, report, fmt.Errorf("downgrade: target %s is newer than scene version %s", targetVersion, sf.Version)
+	}
+	supported, err := CapabilitiesFor(targetVersion)
+	if err != nil {
+		return SceneFile{}, report, fmt.Errorf("downgrade: %w", err)
+	}
+	d := downgrader{supported: supported, report: &report}
+
+	out := sf
+	if !d.supports(CapabilityLocalization) && hasLocalizations(&sf) {
+		out = Localize(sf, sf.Metadata.Locale)
+		out.Metadata.Locale = ""
+		d.lose(CapabilityLocalization, "", "", fmt.Sprintf("text resolved for locale %q; other translations removed", sf.Metadata.Locale))
+	}
+	if !d.supports(CapabilityLifecycle) && out.Metadata.Lifecycle != nil {
+		out.Metadata.Lifecycle = nil
+		d.lose(CapabilityLifecycle, "", "", "lifecycle state and approvals removed")
+	}
+	if !d.supports(CapabilityRequirements) && out.Requirements != nil {
+		out.Requirements = nil
+		d.lose(CapabilityRequirements, "", "", "viewer requirements and fallback removed")
+	}
+
+	libraries := d.supports(CapabilityResourceLibraries)
+	nodes := out.Scene.Nodes
+	out.Scene.Nodes = make([]SceneNode, len(nodes))
+	for i, node := range nodes {
+		out.Scene.Nodes[i] = d.node(&sf, node, libraries)
+	}
+	edges := out.Scene.Edges
+	out.Scene.Edges = make([]SceneEdge, 0, len(edges))
+	for _, edge := range edges {
+		if !d.supports(CapabilitySceneRefs) && edge.TargetScene != "" {
+			// The target node lives in another scene the reader cannot load
+			d.lose(CapabilitySceneRefs, "", edge.ID, "cross-scene edge removed")
+			continue
+		}
+		out.Scene.Edges = append(out.Scene.Edges, d.edge(edge))
+	}
+
+	if libraries {
+		out.Materials = mapValues(out.Materials, d.material)
+		out.Geometries = mapValues(out.Geometries, d.geometry)
+	} else if len(out.Materials) > 0 || len(out.Geometries) > 0 {
+		out.Materials, out.Geometries = nil, nil
+		d.convert(CapabilityResourceLibraries, "", "", "material and geometry libraries inlined into nodes")
+	}
+
+	for _, c := range sf.Capabilities {
+		if !d.supports(c) && !slices.Contains(schemaCapabilities(), c) {
+			d.lose(c, "", "", "capability unknown to this SDK; constructs using it may remain")
+		}
+	}
+	out.Version = target.String()
+	if len(out.Capabilities) > 0 {
+		out.UpdateCapabilities()
+	}
+	return out, report, nil
+}
+
+// downgrader applies the per-element conversions for a target format
+type downgrader struct {
+	supported []Capability
+	report    *DowngradeReport
+}
+
+func (d *downgrader) supports(c Capability) bool {
+	return slices.Contains(d.supported, c)
+}
+
+func (d *downgrader) convert(c Capability, node, edge, detail string) {
+	d.report.Changes = append(d.report.Changes, DowngradeChange{Capability: c, Node: node, Edge: edge, Detail: detail})
+}
+
+func (d *downgrader) lose(c Capability, node, edge, detail string) {
+	d.report.Changes = append(d.report.Changes, DowngradeChange{Capability: c, Node: node, Edge: edge, Detail: detail, Lossy: true})
+}
+
+func (d *downgrader) node(sf *SceneFile, n SceneNode, libraries bool) SceneNode {
+	if !libraries && (n.GeometryRef != "" || n.MaterialRef != "") {
+		n.Geometry, n.Material = sf.ResolveGeometry(&n), sf.ResolveMaterial(&n)
+		n.GeometryRef, n.MaterialRef = "", ""
+		d.convert(CapabilityResourceLibraries, n.ID, "", "library references inlined")
+	}
+	if n.Geometry != nil {
+		g := d.geometry(n.ID, *n.Geometry)
+		n.Geometry = &g
+	}
+	if n.Material != nil {
+		m := d.material(n.ID, *n.Material)
+		n.Material = &m
+	}
+	if !d.supports(CapabilityLabels) && n.Label != nil {
+		n.Label = nil
+		d.lose(CapabilityLabels, n.ID, "", "label removed")
+	}
+	if !d.supports(CapabilityParticles) && len(n.Particles) > 0 {
+		n.Particles = nil
+		d.lose(CapabilityParticles, n.ID, "", "particle systems removed")
+	}
+	if !d.supports(CapabilityAttachments) && len(n.Attachments) > 0 {
+		n.Attachments = nil
+		d.lose(CapabilityAttachments, n.ID, "", "attachments removed")
+	}
+	if !d.supports(CapabilityAccessibility) && n.Accessibility != nil {
+		n.Accessibility = nil
+		d.lose(CapabilityAccessibility, n.ID, "", "accessibility description removed")
+	}
+	if !d.supports(CapabilityPorts) && len(n.Ports) > 0 {
+		n.Ports = nil
+		d.lose(CapabilityPorts, n.ID, "", "ports removed")
+	}
+	if !d.supports(CapabilityPhysics) && n.Physics != nil {
+		n.Physics = nil
+		d.lose(CapabilityPhysics, n.ID, "", "physics body removed")
+	}
+	if !d.supports(CapabilitySceneRefs) && n.Ref != nil {
+		n.Ref = nil
+		d.lose(CapabilitySceneRefs, n.ID, "", "scene reference removed")
+	}
+	if !d.supports(CapabilitySLOs) && len(n.SLOs) > 0 {
+		n.SLOs = nil
+		d.lose(CapabilitySLOs, n.ID, "", "SLOs removed")
+	}
+	if !d.supports(CapabilityPanels) && len(n.Panels) > 0 {
+		n.Panels = nil
+		d.lose(CapabilityPanels, n.ID, "", "panels removed")
+	}
+	if !d.supports(CapabilityExpiry) && n.ExpiresAt != nil {
+		n.ExpiresAt = nil
+		d.lose(CapabilityExpiry, n.ID, "", "expiry removed")
+	}
+	if !d.supports(CapabilityRevisions) && n.Revision != 0 {
+		n.Revision = 0
+		d.convert(CapabilityRevisions, n.ID, "", "revision removed")
+	}
+	return n
+}
+
+func (d *downgrader) edge(e SceneEdge) SceneEdge {
+	if !d.supports(CapabilityLabels) && e.Label != nil {
+		e.Label = nil
+		d.lose(CapabilityLabels, "", e.ID, "label removed")
+	}
+	if !d.supports(CapabilityParticles) && len(e.Particles) > 0 {
+		e.Particles = nil
+		d.lose(CapabilityParticles, "", e.ID, "particle systems removed")
+	}
+	if !d.supports(CapabilityPhysics) && e.Joint != nil {
+		e.Joint = nil
+		d.lose(CapabilityPhysics, "", e.ID, "joint removed")
+	}
+	if !d.supports(CapabilityEdgeRouting) && len(e.Waypoints) > 0 {
+		e.Waypoints = nil
+		d.lose(CapabilityEdgeRouting, "", e.ID, "waypoints removed")
+	}
+	if !d.supports(CapabilityExpiry) && e.ExpiresAt != nil {
+		e.ExpiresAt = nil
+		d.lose(CapabilityExpiry, "", e.ID, "expiry removed")
+	}
+	if !d.supports(CapabilityRevisions) && e.Revision != 0 {
+		e.Revision = 0
+		d.convert(CapabilityRevisions, "", e.ID, "revision removed")
+	}
+
+	// Older readers keep unknown edge attributes as plain metadata
+	moved := map[Capability]map[string]interface{}{}
+	if !d.supports(CapabilityEdgeSemantics) && (e.Direction != "" || e.Key != "" || e.Weight != 0) {
+		moved[CapabilityEdgeSemantics] = nonZero(map[string]interface{}{"direction": string(e.Direction), "key": e.Key, "weight": e.Weight})
+		e.Direction, e.Key, e.Weight = "", "", 0
+	}
+	if !d.supports(CapabilityPorts) && (e.SourcePort != "" || e.TargetPort != "") {
+		moved[CapabilityPorts] = nonZero(map[string]interface{}{"sourcePort": e.SourcePort, "targetPort": e.TargetPort})
+		e.SourcePort, e.TargetPort = "", ""
+	}
+	if len(moved) > 0 {
+		e.Metadata = maps.Clone(e.Metadata)
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]interface{})
+		}
+	}
+	for _, c := range []Capability{CapabilityEdgeSemantics, CapabilityPorts} {
+		fields, ok := moved[c]
+		if !ok {
+			continue
+		}
+		lossy := false
+		for k, v := range fields {
+			if _, taken := e.Metadata[k]; taken {
+				lossy = true
+				continue
+			}
+			e.Metadata[k] = v
+		}
+		if lossy {
+			d.lose(c, "", e.ID, "moved to metadata; some keys were already taken and were dropped")
+		} else {
+			d.convert(c, "", e.ID, "moved to metadata")
+		}
+	}
+	return e
+}
+
+// geometry converts a node or library geometry; owner is the node ID or
+// empty for library entries
+func (d *downgrader) geometry(owner string, g Geometry) Geometry {
+	if !d.supports(CapabilityLODs) && len(g.LODs) > 0 {
+		g.LODs = nil
+		d.lose(CapabilityLODs, owner, "", "levels of detail removed")
+	}
+	if g.Mesh == nil {
+		return g
+	}
+	if !d.supports(CapabilityMeshes) {
+		mesh := geometryMesh(&g)
+		g.Mesh = nil
+		if g.Asset != "" {
+			d.convert(CapabilityMeshes, owner, "", "inline mesh dropped in favor of the geometry asset")
+			return g
+		}
+		lo, hi := mesh.bounds()
+		size := hi.Sub(lo)
+		g.Type = GeometryBox
+		g.Parameters = map[string]interface{}{"width": size.X, "height": size.Y, "depth": size.Z}
+		d.lose(CapabilityMeshes, owner, "", "inline mesh replaced by its bounding box")
+		return g
+	}
+	if !d.supports(CapabilityMeshCompression) && g.Mesh.IsCompressed() {
+		mesh := *g.Mesh
+		if err := mesh.Decompress(); err != nil {
+			g.Mesh = nil
+			d.lose(CapabilityMeshCompression, owner, "", fmt.Sprintf("undecodable compressed mesh removed: %v", err))
+			return g
+		}
+		g.Mesh = &mesh
+		d.convert(CapabilityMeshCompression, owner, "", "compressed mesh expanded")
+	}
+	return g
+}
+
+// material converts a node or library material; owner is the node ID or
+// empty for library entries
+func (d *downgrader) material(owner string, m Material) Material {
+	if !d.supports(CapabilityTextureAtlas) && m.TextureRegion != nil {
+		// Without the region the whole atlas would be drawn, so drop both
+		m.TextureRegion, m.Texture = nil, ""
+		d.lose(CapabilityTextureAtlas, owner, "", "atlas texture removed")
+	}
+	return m
+}
+
+// hasLocalizations reports whether any part of the scene is localized
+func hasLocalizations(sf *SceneFile) bool {
+	if sf.Metadata.Locale != "" || len(sf.Metadata.Localizations) > 0 {
+		return true
+	}
+	for i := range sf.Scene.Nodes {
+		if len(sf.Scene.Nodes[i].Localizations) > 0 {
+			return true
+		}
+	}
+	for i := range sf.Scene.Edges {
+		if len(sf.Scene.Edges[i].Localizations) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaCapabilities returns every capability of any known release
+func schemaCapabilities() []Capability {
+	var caps []Capability
+	for _, r := range schemaReleases {
+		caps = append(caps, r.Added...)
+	}
+	return caps
+}
+
+// mapValues returns a copy of m with every value converted by fn
+func mapValues[T any](m map[string]T, fn func(string, T) T) map[string]T {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]T, len(m))
+	for k, v := range m {
+		out[k] = fn("", v)
+	}
+	return out
+}
+
+// nonZero drops empty strings and zero numbers from fields
+func nonZero(fields map[string]interface{}) map[string]interface{} {
+	for k, v := range fields {
+		if v == "" || v == 0.0 {
+			delete(fields, k)
+		}
+	}
+	return fields
+}
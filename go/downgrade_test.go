@@ -0,0 +1,85 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestDowngrade tests conversion of newer constructs for a 0.1.0 reader
+func TestDowngrade(t *testing.T) {
+	mesh := newGridMesh(2)
+	if err := mesh.Compress(); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	sf := NewSceneFile("Downgrade")
+	sf.Metadata.Locale = "de"
+	sf.Geometries = map[string]Geometry{"rack": {Type: GeometryBox, Parameters: map[string]interface{}{"width": 2.0}}}
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "A", Transform: NewTransform(), GeometryRef: "rack",
+		Label: &Label{Text: "A"}, Localizations: LocalizationMap{"de": {Name: "A (de)"}}})
+	sf.AddNode(SceneNode{ID: "b", Type: "server", Name: "B", Transform: NewTransform(),
+		Geometry: &Geometry{Type: GeometryCustom, Mesh: &mesh}})
+	sf.AddEdge(SceneEdge{ID: "ab", Source: "a", Target: "b", Weight: 3, Waypoints: []Vector3{{X: 1}}})
+	sf.AddEdge(SceneEdge{ID: "remote", Source: "a", Target: "db", TargetScene: "backend"})
+	sf.Scene.Nodes[0].Revision, sf.Scene.Edges[0].Revision = 3, 2
+	sf.Require(FeatureRequirement{Feature: ViewerFeatureInstancing, Required: true})
+	sf.UpdateCapabilities()
+
+	out, report, err := Downgrade(sf, "0.1.0")
+	if err != nil {
+		t.Fatalf("Downgrade failed: %v", err)
+	}
+	if out.Version != "0.1.0" || len(out.Capabilities) != 0 || out.Requirements != nil {
+		t.Errorf("header mismatch: got %s %v", out.Version, out.Capabilities)
+	}
+	if caps := DetectCapabilities(&out); len(caps) != 0 {
+		t.Errorf("downgraded scene still uses %v", caps)
+	}
+
+	a, b := out.FindNode("a"), out.FindNode("b")
+	if a.Name != "A (de)" || a.Label != nil || a.Geometry == nil || a.Geometry.Parameters["width"] != 2.0 {
+		t.Errorf("node a mismatch: got %+v", a)
+	}
+	if b.Geometry.Type != GeometryBox || b.Geometry.Mesh != nil {
+		t.Errorf("node b geometry mismatch: got %+v", b.Geometry)
+	}
+	if a.Revision != 0 || out.Scene.Edges[0].Revision != 0 {
+		t.Errorf("revisions not removed: got %d, %d", a.Revision, out.Scene.Edges[0].Revision)
+	}
+	if len(out.Scene.Edges) != 1 || out.Scene.Edges[0].Metadata["weight"] != 3.0 || out.Scene.Edges[0].Weight != 0 {
+		t.Errorf("edges mismatch: got %+v", out.Scene.Edges)
+	}
+
+	lost := make(map[Capability]bool)
+	for _, c := range report.Lost() {
+		lost[c.Capability] = true
+	}
+	for _, c := range []Capability{CapabilityLabels, CapabilityLocalization, CapabilityMeshes, CapabilityEdgeRouting, CapabilitySceneRefs, CapabilityRequirements} {
+		if !lost[c] {
+			t.Errorf("expected %s to be reported lost", c)
+		}
+	}
+	if lost[CapabilityResourceLibraries] || lost[CapabilityEdgeSemantics] || lost[CapabilityRevisions] {
+		t.Errorf("lossless conversions reported as lost: %+v", report.Lost())
+	}
+
+	// The input is left untouched
+	if sf.FindNode("a").Label == nil || !sf.FindNode("b").Geometry.Mesh.IsCompressed() || sf.Scene.Edges[0].Metadata != nil || sf.Requirements == nil {
+		t.Error("Downgrade modified its input")
+	}
+}
+
+// TestDowngrade_Target tests target version checks
+func TestDowngrade_Target(t *testing.T) {
+	sf := NewSceneFile("Target")
+	sf.Version = "0.1.0"
+	if _, _, err := Downgrade(sf, SchemaVersion); err == nil {
+		t.Error("expected an error downgrading to a newer version")
+	}
+	if _, _, err := Downgrade(sf, "latest"); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("expected ErrInvalidVersion, got %v", err)
+	}
+	out, report, err := Downgrade(sf, "0.1.0")
+	if err != nil || out.Version != "0.1.0" || len(report.Changes) != 0 {
+		t.Errorf("no-op downgrade mismatch: got %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,359 @@
+package starfleet
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// DRAW.IO DIAGRAMS
+// =============================================================================
+
+// DrawioContentType is the media type of draw.io diagram files
+const DrawioContentType = "application/vnd.jgraph.mxfile"
+
+// Draw.io export defaults
+const (
+	// DefaultDrawioWidth is the width in pixels the scene is scaled to
+	DefaultDrawioWidth = 1600
+	// DefaultDrawioShapeSize is the smallest width and height of a shape
+	DefaultDrawioShapeSize = 40
+)
+
+// drawioGroupPadding is the space in pixels around the members of a group,
+// with drawioGroupHeader more above them for the group's label
+const (
+	drawioGroupPadding = 20
+	drawioGroupHeader  = 20
+)
+
+// DrawioStyles are the built-in draw.io styles of shapes by node type.
+// Nodes of other types are rounded rectangles.
+var DrawioStyles = map[string]string{
+	"database":     "shape=cylinder3;whiteSpace=wrap;html=1;boundedLbl=1;backgroundOutline=1;size=10;",
+	"db":           "shape=cylinder3;whiteSpace=wrap;html=1;boundedLbl=1;backgroundOutline=1;size=10;",
+	"sql":          "shape=cylinder3;whiteSpace=wrap;html=1;boundedLbl=1;backgroundOutline=1;size=10;",
+	"cache":        "shape=hexagon;perimeter=hexagonPerimeter2;whiteSpace=wrap;html=1;size=0.2;",
+	"queue":        "shape=process;whiteSpace=wrap;html=1;backgroundOutline=1;",
+	"loadbalancer": "rhombus;whiteSpace=wrap;html=1;",
+	"server":       "rounded=0;whiteSpace=wrap;html=1;",
+	"host":         "rounded=0;whiteSpace=wrap;html=1;",
+	"vm":           "rounded=0;whiteSpace=wrap;html=1;",
+	"service":      "rounded=1;whiteSpace=wrap;html=1;",
+}
+
+// drawioDefaultStyle is the style of nodes without a type style
+const drawioDefaultStyle = "rounded=1;whiteSpace=wrap;html=1;"
+
+// drawioContainerStyle is the style of nodes with children
+const drawioContainerStyle = "rounded=1;whiteSpace=wrap;html=1;container=1;collapsible=0;dashed=1;fillColor=none;verticalAlign=top;align=left;spacingLeft=8;"
+
+// DrawioOptions configures EncodeDrawio
+type DrawioOptions struct {
+	// Width is the width in pixels of the diagram; zero uses
+	// DefaultDrawioWidth
+	Width float64
+	// MinShapeSize is the smallest shape size in pixels, so small nodes
+	// stay readable; zero uses DefaultDrawioShapeSize
+	MinShapeSize float64
+	// Styles override DrawioStyles by node type
+	Styles map[string]string
+}
+
+type drawioFile struct {
+	XMLName xml.Name      `xml:"mxfile"`
+	Host    string        `xml:"host,attr"`
+	Diagram drawioDiagram `xml:"diagram"`
+}
+
+type drawioDiagram struct {
+	ID    string      `xml:"id,attr"`
+	Name  string      `xml:"name,attr"`
+	Model drawioModel `xml:"mxGraphModel"`
+}
+
+type drawioModel struct {
+	Grid  int          `xml:"grid,attr"`
+	Cells []drawioCell `xml:"root>mxCell"`
+}
+
+type drawioCell struct {
+	ID       string          `xml:"id,attr"`
+	Value    string          `xml:"value,attr,omitempty"`
+	Style    string          `xml:"style,attr,omitempty"`
+	Vertex   string          `xml:"vertex,attr,omitempty"`
+	Edge     string          `xml:"edge,attr,omitempty"`
+	Parent   string          `xml:"parent,attr,omitempty"`
+	Source   string          `xml:"source,attr,omitempty"`
+	Target   string          `xml:"target,attr,omitempty"`
+	Geometry *drawioGeometry `xml:"mxGeometry"`
+}
+
+type drawioGeometry struct {
+	X        float64       `xml:"x,attr,omitempty"`
+	Y        float64       `xml:"y,attr,omitempty"`
+	Width    float64       `xml:"width,attr,omitempty"`
+	Height   float64       `xml:"height,attr,omitempty"`
+	Relative string        `xml:"relative,attr,omitempty"`
+	As       string        `xml:"as,attr"`
+	Points   *drawioPoints `xml:"Array"`
+}
+
+type drawioPoints struct {
+	As     string        `xml:"as,attr"`
+	Points []drawioPoint `xml:"mxPoint"`
+}
+
+type drawioPoint struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+}
+
+// drawioBox is a shape's rectangle in diagram pixels
+type drawioBox struct {
+	x0, y0, x1, y1 float64
+}
+
+func (b drawioBox) union(o drawioBox) drawioBox {
+	return drawioBox{math.Min(b.x0, o.x0), math.Min(b.y0, o.y0), math.Max(b.x1, o.x1), math.Max(b.y1, o.y1)}
+}
+
+// EncodeDrawio writes a scene as a draw.io (diagrams.net) diagram, seen
+// from above like a minimap: world X runs to the right and world Z down.
+// Shapes are chosen by node type and filled with the node's material
+// color, and warning and critical nodes are outlined in amber and red.
+// Nodes with children become containers enclosing them, so groups such as
+// regions and clusters can be moved and folded as one. Edges are
+// connectors following their waypoints, dashed or dotted like the scene
+// and with arrows by direction; edges into other scenes are left out.
+func EncodeDrawio(w io.Writer, sf *SceneFile, opts DrawioOptions) error {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultDrawioWidth
+	}
+	minSize := opts.MinShapeSize
+	if minSize <= 0 {
+		minSize = DefaultDrawioShapeSize
+	}
+	lo, hi, ok := sceneBounds(sf)
+	if !ok {
+		lo, hi = Vector3{X: -0.5, Z: -0.5}, Vector3{X: 0.5, Z: 0.5}
+	}
+	proj := MinimapProjection{Origin: lo, Scale: width / math.Max(math.Max(hi.X-lo.X, hi.Z-lo.Z), 1e-9)}
+
+	nodes := sf.Scene.Nodes
+	index := make(map[string]int, len(nodes))
+	for i := range nodes {
+		index[nodes[i].ID] = i
+	}
+	// Parents outside the scene, or in a cycle, are dropped
+	parent := make([]int, len(nodes))
+	depth := make([]int, len(nodes))
+	for i := range nodes {
+		parent[i] = -1
+		if p, ok := index[nodes[i].Parent]; ok && p != i {
+			parent[i] = p
+		}
+	}
+	for i := range nodes {
+		for p, n := parent[i], 0; p >= 0; p, n = parent[p], n+1 {
+			if n >= len(nodes) {
+				parent[i], depth[i] = -1, 0
+				break
+			}
+			depth[i]++
+		}
+	}
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return depth[order[a]] < depth[order[b]] })
+
+	boxes := make([]drawioBox, len(nodes))
+	container := make([]bool, len(nodes))
+	for i := range nodes {
+		n := &nodes[i]
+		h := nodeHalfExtents(n, sf.ResolveGeometry(n))
+		x, y := proj.ToMap(n.Transform.Position)
+		hw, hh := math.Max(h.X*proj.Scale, minSize/2), math.Max(h.Z*proj.Scale, minSize/2)
+		boxes[i] = drawioBox{x - hw, y - hh, x + hw, y + hh}
+	}
+	// Grow containers around their members, deepest first
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		if p := parent[i]; p >= 0 {
+			b := boxes[i]
+			b = drawioBox{b.x0 - drawioGroupPadding, b.y0 - drawioGroupPadding - drawioGroupHeader, b.x1 + drawioGroupPadding, b.y1 + drawioGroupPadding}
+			if container[p] {
+				boxes[p] = boxes[p].union(b)
+			} else {
+				boxes[p], container[p] = b, true
+			}
+		}
+	}
+
+	cells := []drawioCell{{ID: "0"}, {ID: "1", Parent: "0"}}
+	for _, i := range order {
+		n := &nodes[i]
+		b := boxes[i]
+		cell := drawioCell{
+			ID:     drawioNodeID(n.ID),
+			Value:  n.Name,
+			Style:  drawioNodeStyle(sf, n, container[i], opts.Styles),
+			Vertex: "1",
+			Parent: "1",
+			Geometry: &drawioGeometry{
+				X: drawioRound(b.x0), Y: drawioRound(b.y0),
+				Width: drawioRound(b.x1 - b.x0), Height: drawioRound(b.y1 - b.y0),
+				As: "geometry",
+			},
+		}
+		if n.Label != nil && n.Label.Text != "" {
+			cell.Value = n.Label.Text
+		}
+		if p := parent[i]; p >= 0 {
+			// Children are placed relative to their container
+			cell.Parent = drawioNodeID(nodes[p].ID)
+			cell.Geometry.X = drawioRound(b.x0 - boxes[p].x0)
+			cell.Geometry.Y = drawioRound(b.y0 - boxes[p].y0)
+		}
+		cells = append(cells, cell)
+	}
+
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		if _, ok := index[e.Source]; !ok || e.TargetScene != "" {
+			continue
+		}
+		if _, ok := index[e.Target]; !ok {
+			continue
+		}
+		cell := drawioCell{
+			ID:       "e:" + e.ID,
+			Style:    drawioEdgeStyle(e),
+			Edge:     "1",
+			Parent:   "1",
+			Source:   drawioNodeID(e.Source),
+			Target:   drawioNodeID(e.Target),
+			Geometry: &drawioGeometry{Relative: "1", As: "geometry"},
+		}
+		if e.Label != nil {
+			cell.Value = e.Label.Text
+		}
+		if len(e.Waypoints) > 0 {
+			points := &drawioPoints{As: "points"}
+			for _, wp := range e.Waypoints {
+				x, y := proj.ToMap(wp)
+				points.Points = append(points.Points, drawioPoint{X: drawioRound(x), Y: drawioRound(y)})
+			}
+			cell.Geometry.Points = points
+		}
+		cells = append(cells, cell)
+	}
+
+	name := sf.Metadata.Name
+	if name == "" {
+		name = "Scene"
+	}
+	doc := drawioFile{
+		Host: "starfleet",
+		Diagram: drawioDiagram{
+			ID:    "scene",
+			Name:  name,
+			Model: drawioModel{Grid: 1, Cells: cells},
+		},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// drawioNodeID returns the cell ID of a node, prefixed so it cannot clash
+// with the root cells "0" and "1" or an edge
+func drawioNodeID(id string) string {
+	return "n:" + id
+}
+
+// drawioNodeStyle returns the style of a node's shape. Containers take
+// an override of their type but never a built-in shape.
+func drawioNodeStyle(sf *SceneFile, n *SceneNode, container bool, styles map[string]string) string {
+	style, ok := styles[n.Type]
+	switch {
+	case container && !ok:
+		style = drawioContainerStyle
+	case container:
+		if !hasStyleKey(style, "container") {
+			style += "container=1;"
+		}
+	case !ok:
+		if style, ok = DrawioStyles[n.Type]; !ok {
+			style = drawioDefaultStyle
+		}
+	}
+	if mat := sf.ResolveMaterial(n); !container && mat != nil && mat.Color != nil {
+		style += "fillColor=" + drawioColor(*mat.Color) + ";"
+	}
+	switch n.Status {
+	case NodeStatusWarning:
+		style += "strokeColor=#F9A825;strokeWidth=2;"
+	case NodeStatusCritical:
+		style += "strokeColor=#D32F2F;strokeWidth=2;"
+	}
+	return style
+}
+
+// hasStyleKey reports whether a draw.io style sets key
+func hasStyleKey(style, key string) bool {
+	for _, entry := range strings.Split(style, ";") {
+		if k, _, _ := strings.Cut(entry, "="); k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// drawioEdgeStyle returns the style of an edge's connector
+func drawioEdgeStyle(e *SceneEdge) string {
+	style := "edgeStyle=orthogonalEdgeStyle;rounded=1;html=1;"
+	switch e.Style {
+	case EdgeStyleDashed:
+		style += "dashed=1;"
+	case EdgeStyleDotted:
+		style += "dashed=1;dashPattern=1 4;"
+	}
+	switch e.Direction {
+	case EdgeUndirected:
+		style += "endArrow=none;"
+	case EdgeBidirectional:
+		style += "startArrow=classic;endArrow=classic;"
+	default:
+		style += "endArrow=classic;"
+	}
+	if e.Color != nil {
+		style += "strokeColor=" + drawioColor(*e.Color) + ";"
+	}
+	return style
+}
+
+// drawioColor formats a color as draw.io hex
+func drawioColor(c Color) string {
+	n := shadeColor(c, 1)
+	return fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B)
+}
+
+// drawioRound rounds pixel coordinates to hundredths to keep files small
+func drawioRound(f float64) float64 {
+	return math.Round(f*100) / 100
+}
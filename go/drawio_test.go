@@ -0,0 +1,94 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// TestEncodeDrawio tests shapes, containers and connectors of a draw.io
+// diagram
+func TestEncodeDrawio(t *testing.T) {
+	sf := newDiffScene()
+	sf.AddNode(SceneNode{ID: "rack", Type: "rack", Name: "Rack", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "db", Type: "database", Name: "DB", Transform: NewTransform(), Parent: "rack", Status: NodeStatusCritical})
+	sf.Scene.Nodes[0].Transform.Position = Vector3{X: -10, Z: -10}
+	sf.Scene.Nodes[1].Transform.Position = Vector3{X: 10, Z: 10}
+	sf.Scene.Nodes[1].Parent = "rack"
+	sf.Scene.Nodes[1].Material = &Material{Color: &Color{R: 1, A: 1}}
+	sf.Scene.Edges[0].Style = EdgeStyleDashed
+	sf.Scene.Edges[0].Waypoints = []Vector3{{X: 0, Z: -10}}
+	sf.AddEdge(SceneEdge{ID: "b-db", Source: "b", Target: "db", Direction: EdgeBidirectional})
+	sf.AddEdge(SceneEdge{ID: "remote", Source: "a", Target: "x", TargetScene: "other"})
+
+	var buf bytes.Buffer
+	if err := EncodeDrawio(&buf, &sf, DrawioOptions{Styles: map[string]string{"server": "shape=mxgraph.custom;"}}); err != nil {
+		t.Fatalf("EncodeDrawio failed: %v", err)
+	}
+	var doc drawioFile
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("diagram is not XML: %v", err)
+	}
+	cells := make(map[string]drawioCell)
+	position := make(map[string]int)
+	for i, c := range doc.Diagram.Model.Cells {
+		cells[c.ID] = c
+		position[c.ID] = i
+	}
+	if len(cells) != 2+5+2 {
+		t.Errorf("cell count mismatch: got %d, want 9", len(cells))
+	}
+	if _, ok := cells["e:remote"]; ok {
+		t.Error("edge into another scene was exported")
+	}
+
+	rack, db, b := cells["n:rack"], cells["n:db"], cells["n:b"]
+	if !strings.Contains(rack.Style, "container=1") || rack.Parent != "1" {
+		t.Errorf("rack cell mismatch: got %+v", rack)
+	}
+	if db.Parent != "n:rack" || b.Parent != "n:rack" || position["n:rack"] > position["n:db"] {
+		t.Errorf("members are not in the container: got %q and %q", db.Parent, b.Parent)
+	}
+	if !strings.HasPrefix(db.Style, "shape=cylinder3;") || !strings.Contains(db.Style, "strokeColor=#D32F2F") {
+		t.Errorf("db style mismatch: got %q", db.Style)
+	}
+	if !strings.HasPrefix(b.Style, "shape=mxgraph.custom;") || !strings.Contains(b.Style, "fillColor=#FF0000") {
+		t.Errorf("b style mismatch: got %q", b.Style)
+	}
+	// Member geometry is relative to the container, which encloses it
+	g, rg := b.Geometry, rack.Geometry
+	if g.X < 0 || g.Y < 0 || g.X+g.Width > rg.Width || g.Y+g.Height > rg.Height {
+		t.Errorf("member outside container: got %+v in %+v", g, rg)
+	}
+	if a := cells["n:a"].Geometry; a.Width < DefaultDrawioShapeSize || a.X+a.Width > rg.X {
+		t.Errorf("a geometry mismatch: got %+v", a)
+	}
+
+	ab, bdb := cells["e:a-b"], cells["e:b-db"]
+	if ab.Source != "n:a" || ab.Target != "n:b" || !strings.Contains(ab.Style, "dashed=1") || ab.Geometry.Points == nil || len(ab.Geometry.Points.Points) != 1 {
+		t.Errorf("a-b cell mismatch: got %+v", ab)
+	}
+	if !strings.Contains(bdb.Style, "startArrow=classic") {
+		t.Errorf("b-db style mismatch: got %q", bdb.Style)
+	}
+}
+
+// TestEncodeDrawio_ParentCycle tests that parent cycles do not nest
+// containers forever
+func TestEncodeDrawio_ParentCycle(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Parent = "b"
+	sf.Scene.Nodes[1].Parent = "a"
+	var buf bytes.Buffer
+	if err := EncodeDrawio(&buf, &sf, DrawioOptions{}); err != nil {
+		t.Fatalf("EncodeDrawio failed: %v", err)
+	}
+	var doc drawioFile
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("diagram is not XML: %v", err)
+	}
+	if got := len(doc.Diagram.Model.Cells); got != 2+3+1 {
+		t.Errorf("cell count mismatch: got %d, want 6", got)
+	}
+}
@@ -0,0 +1,628 @@
+package starfleet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// SCENE DSL SYNTAX
+// =============================================================================
+
+// ErrInvalidSceneDSL is returned when scene DSL source cannot be parsed
+var ErrInvalidSceneDSL = errors.New("invalid scene DSL")
+
+// The scene DSL is a small HCL-like language. A file is a body of items:
+// attributes (key = value) and blocks (key labels... { body }), one per
+// line or separated by commas. Values are strings, JSON numbers, true,
+// false, null, lists in brackets and objects in braces, which are bodies
+// too. Comments start with # or // and run to the end of the line. What
+// the items mean is up to dslscene.go; this file only reads and prints
+// them, keeping comments so the formatter does not drop them.
+
+// dslPos is a one-based line and column in the source
+type dslPos struct {
+	line, col int
+}
+
+func (p dslPos) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d, column %d: %s", ErrInvalidSceneDSL, p.line, p.col, fmt.Sprintf(format, args...))
+}
+
+// dslValueKind is the kind of a DSL value
+type dslValueKind int
+
+const (
+	dslString dslValueKind = iota
+	dslNumber
+	dslBool
+	dslNull
+	dslList
+	dslObject
+)
+
+// dslValue is a parsed value. text holds strings unquoted and numbers and
+// booleans as written.
+type dslValue struct {
+	pos    dslPos
+	kind   dslValueKind
+	text   string
+	list   []*dslValue
+	object *dslBody
+}
+
+// dslLabel is a block label: a string, a bare word or an edge arrow
+type dslLabel struct {
+	text  string
+	arrow bool
+}
+
+// dslItem is an attribute, when value is set, or a block
+type dslItem struct {
+	pos    dslPos
+	key    string
+	value  *dslValue
+	labels []dslLabel
+	// body is nil for a block written without braces
+	body *dslBody
+
+	// comments are the comment lines above the item, with "" for blank
+	// lines between them; blank is set when a blank line separates them
+	// from what comes before, and trailing is a comment after the item on
+	// the same line
+	comments []string
+	blank    bool
+	trailing string
+}
+
+// dslBody is the content of a file, block or object
+type dslBody struct {
+	pos   dslPos
+	items []*dslItem
+	// comments are those after the last item, marked like an item's
+	comments []string
+}
+
+// lookup returns the attribute or block with a key, or nil
+func (b *dslBody) lookup(key string) *dslItem {
+	for _, item := range b.items {
+		if item.key == key {
+			return item
+		}
+	}
+	return nil
+}
+
+// dslTokenKind is the kind of a lexical token
+type dslTokenKind int
+
+const (
+	dslEOF dslTokenKind = iota
+	dslNewline
+	dslComment
+	dslIdent
+	dslStringToken
+	dslNumberToken
+	dslArrow
+	dslPunct
+)
+
+type dslToken struct {
+	pos  dslPos
+	kind dslTokenKind
+	text string
+}
+
+// dslNumberPattern matches JSON numbers, so numbers pass through to scenes
+// as written
+var dslNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?`)
+
+// dslArrows are the edge arrows, longest first
+var dslArrows = []string{"<->", "->", "--"}
+
+// lexDSL splits source into tokens
+func lexDSL(src []byte) ([]dslToken, error) {
+	var tokens []dslToken
+	line, col := 1, 1
+	s := string(src)
+	for i := 0; i < len(s); {
+		pos := dslPos{line, col}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		advance := func(n int) {
+			col += utf8.RuneCountInString(s[i : i+n])
+			i += n
+		}
+		switch {
+		case r == '\n':
+			tokens = append(tokens, dslToken{pos, dslNewline, "\n"})
+			i, line, col = i+1, line+1, 1
+		case r == ' ' || r == '\t' || r == '\r' || r == '\ufeff':
+			advance(size)
+		case r == '#' || strings.HasPrefix(s[i:], "//"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				end = len(s) - i
+			}
+			tokens = append(tokens, dslToken{pos, dslComment, strings.TrimRight(s[i:i+end], " \t\r")})
+			advance(end)
+		case r == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' && s[end] != '\n' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) || s[end] != '"' {
+				return nil, pos.errorf("unterminated string")
+			}
+			text, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, pos.errorf("invalid string %s", s[i:end+1])
+			}
+			tokens = append(tokens, dslToken{pos, dslStringToken, text})
+			advance(end + 1 - i)
+		case strings.HasPrefix(s[i:], "<->") || strings.HasPrefix(s[i:], "->") || strings.HasPrefix(s[i:], "--"):
+			for _, arrow := range dslArrows {
+				if strings.HasPrefix(s[i:], arrow) {
+					tokens = append(tokens, dslToken{pos, dslArrow, arrow})
+					advance(len(arrow))
+					break
+				}
+			}
+		case r == '-' || (r >= '0' && r <= '9'):
+			text := dslNumberPattern.FindString(s[i:])
+			if text == "" {
+				return nil, pos.errorf("invalid number")
+			}
+			tokens = append(tokens, dslToken{pos, dslNumberToken, text})
+			advance(len(text))
+		case isDSLIdentStart(r):
+			end := i + size
+			for end < len(s) {
+				next, n := utf8.DecodeRuneInString(s[end:])
+				if next == '-' && (strings.HasPrefix(s[end:], "->") || strings.HasPrefix(s[end:], "--")) {
+					break
+				}
+				if !isDSLIdentPart(next) {
+					break
+				}
+				end += n
+			}
+			tokens = append(tokens, dslToken{pos, dslIdent, s[i:end]})
+			advance(end - i)
+		case strings.ContainsRune("{}[]=,", r):
+			tokens = append(tokens, dslToken{pos, dslPunct, string(r)})
+			advance(size)
+		default:
+			return nil, pos.errorf("unexpected %q", r)
+		}
+	}
+	return append(tokens, dslToken{dslPos{line, col}, dslEOF, ""}), nil
+}
+
+func isDSLIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isDSLIdentPart(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isDSLIdent reports whether s can be written as a bare word
+func isDSLIdent(s string) bool {
+	if s == "" || s == "true" || s == "false" || s == "null" {
+		return false
+	}
+	for i, r := range s {
+		if i == 0 && !isDSLIdentStart(r) || i > 0 && !isDSLIdentPart(r) {
+			return false
+		}
+	}
+	return !strings.Contains(s, "->") && !strings.Contains(s, "--")
+}
+
+// dslParser is a recursive descent parser over the tokens of a file
+type dslParser struct {
+	tokens []dslToken
+	next   int
+}
+
+// parseDSL parses a file into its top-level body
+func parseDSL(src []byte) (*dslBody, error) {
+	tokens, err := lexDSL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &dslParser{tokens: tokens}
+	body, err := p.body(dslPos{1, 1}, false)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != dslEOF {
+		return nil, t.pos.errorf("unexpected %s", t.describe())
+	}
+	return body, nil
+}
+
+func (p *dslParser) peek() dslToken {
+	return p.tokens[p.next]
+}
+
+func (p *dslParser) take() dslToken {
+	t := p.tokens[p.next]
+	if t.kind != dslEOF {
+		p.next++
+	}
+	return t
+}
+
+func (t dslToken) is(kind dslTokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t dslToken) describe() string {
+	switch t.kind {
+	case dslEOF:
+		return "end of file"
+	case dslNewline:
+		return "end of line"
+	case dslStringToken:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// body parses items up to a closing brace, when braced, or the end of
+// the file
+func (p *dslParser) body(pos dslPos, braced bool) (*dslBody, error) {
+	body := &dslBody{pos: pos}
+	// comments gathers the comments above the next item, with "" for a
+	// blank line between them; blank is a blank line above them all
+	var comments []string
+	blank, pending, lines := false, false, 0
+	separate := func() {
+		if pending {
+			if len(comments) == 0 {
+				blank = len(body.items) > 0
+			} else {
+				comments = append(comments, "")
+			}
+			pending = false
+		}
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == dslNewline:
+			p.take()
+			if lines++; lines > 1 {
+				pending = true
+			}
+			continue
+		case t.kind == dslComment:
+			p.take()
+			separate()
+			comments = append(comments, t.text)
+			lines = 0
+			continue
+		case t.is(dslPunct, ","):
+			p.take()
+			continue
+		case t.is(dslPunct, "}") && braced, t.kind == dslEOF:
+			if blank && len(comments) > 0 {
+				comments = append([]string{""}, comments...)
+			}
+			body.comments = comments
+			return body, nil
+		}
+
+		separate()
+		item, err := p.item()
+		if err != nil {
+			return nil, err
+		}
+		item.comments, item.blank = comments, blank
+		comments, blank, lines = nil, false, 0
+		if t := p.peek(); t.kind == dslComment {
+			item.trailing = p.take().text
+		}
+		switch t := p.peek(); {
+		case t.kind == dslNewline, t.kind == dslEOF, t.is(dslPunct, ","), t.is(dslPunct, "}") && braced:
+		default:
+			return nil, t.pos.errorf("expected end of line after %s, got %s", item.key, t.describe())
+		}
+		body.items = append(body.items, item)
+	}
+}
+
+// closedBody parses a braced body and its closing brace
+func (p *dslParser) closedBody(open dslPos) (*dslBody, error) {
+	body, err := p.body(open, true)
+	if err != nil {
+		return nil, err
+	}
+	if !p.take().is(dslPunct, "}") {
+		return nil, open.errorf("unclosed {")
+	}
+	return body, nil
+}
+
+// item parses an attribute or block
+func (p *dslParser) item() (*dslItem, error) {
+	t := p.take()
+	if t.kind != dslIdent && t.kind != dslStringToken {
+		return nil, t.pos.errorf("expected a name, got %s", t.describe())
+	}
+	item := &dslItem{pos: t.pos, key: t.text}
+	if p.peek().is(dslPunct, "=") {
+		p.take()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		item.value = value
+		return item, nil
+	}
+	for {
+		t := p.peek()
+		switch t.kind {
+		case dslStringToken, dslIdent, dslNumberToken:
+			p.take()
+			item.labels = append(item.labels, dslLabel{text: t.text})
+			continue
+		case dslArrow:
+			p.take()
+			item.labels = append(item.labels, dslLabel{text: t.text, arrow: true})
+			continue
+		}
+		if t.is(dslPunct, "{") {
+			p.take()
+			body, err := p.closedBody(t.pos)
+			if err != nil {
+				return nil, err
+			}
+			item.body = body
+		}
+		return item, nil
+	}
+}
+
+// value parses a value
+func (p *dslParser) value() (*dslValue, error) {
+	t := p.take()
+	v := &dslValue{pos: t.pos, text: t.text}
+	switch {
+	case t.kind == dslStringToken:
+		v.kind = dslString
+	case t.kind == dslNumberToken:
+		v.kind = dslNumber
+	case t.is(dslIdent, "true"), t.is(dslIdent, "false"):
+		v.kind = dslBool
+	case t.is(dslIdent, "null"):
+		v.kind = dslNull
+	case t.is(dslPunct, "["):
+		v.kind = dslList
+		v.list = []*dslValue{}
+		for {
+			for p.peek().kind == dslNewline || p.peek().kind == dslComment {
+				p.take()
+			}
+			if p.peek().is(dslPunct, "]") {
+				p.take()
+				return v, nil
+			}
+			elem, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, elem)
+			for p.peek().kind == dslNewline || p.peek().kind == dslComment {
+				p.take()
+			}
+			if t := p.peek(); t.is(dslPunct, ",") {
+				p.take()
+			} else if !t.is(dslPunct, "]") {
+				return nil, t.pos.errorf("expected , or ] in list, got %s", t.describe())
+			}
+		}
+	case t.is(dslPunct, "{"):
+		v.kind = dslObject
+		body, err := p.closedBody(t.pos)
+		if err != nil {
+			return nil, err
+		}
+		v.object = body
+	default:
+		return nil, t.pos.errorf("expected a value, got %s", t.describe())
+	}
+	return v, nil
+}
+
+// =============================================================================
+// SCENE DSL PRINTING
+// =============================================================================
+
+// dslLineWidth is the width up to which lists and objects stay on one line
+const dslLineWidth = 80
+
+// FormatSceneDSL parses scene DSL source and prints it in canonical form:
+// two-space indents, one item per line, short lists and objects on one
+// line and at most one blank line between items. Comments are kept,
+// except those inside lists.
+func FormatSceneDSL(src []byte) ([]byte, error) {
+	body, err := parseDSL(src)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	printDSLBody(&buf, body, 0)
+	return buf.Bytes(), nil
+}
+
+func printDSLBody(buf *bytes.Buffer, body *dslBody, depth int) {
+	indent := strings.Repeat("  ", depth)
+	// Runs of attributes not split by blank lines or comments align their
+	// equals signs
+	widths := make([]int, len(body.items))
+	for i := 0; i < len(body.items); {
+		j, width := i, 0
+		for ; j < len(body.items); j++ {
+			item := body.items[j]
+			if item.value == nil || j > i && (item.blank || len(item.comments) > 0) {
+				break
+			}
+			width = max(width, utf8.RuneCountInString(dslKey(item.key)))
+		}
+		for k := i; k < j; k++ {
+			widths[k] = width
+		}
+		i = max(j, i+1)
+	}
+	for i, item := range body.items {
+		if item.blank && i > 0 {
+			buf.WriteByte('\n')
+		}
+		printDSLComments(buf, item.comments, indent)
+		key := dslKey(item.key)
+		buf.WriteString(indent + key)
+		if item.value != nil {
+			buf.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(key)) + " = ")
+			printDSLValue(buf, item.value, depth)
+		} else {
+			for _, l := range item.labels {
+				buf.WriteByte(' ')
+				switch {
+				case l.arrow:
+					buf.WriteString(l.text)
+				default:
+					buf.WriteString(strconv.Quote(l.text))
+				}
+			}
+			if item.body != nil {
+				buf.WriteString(" ")
+				printDSLObject(buf, item.body, depth, false)
+			}
+		}
+		if item.trailing != "" {
+			buf.WriteString("  " + item.trailing)
+		}
+		buf.WriteByte('\n')
+	}
+	printDSLComments(buf, body.comments, indent)
+}
+
+// printDSLComments writes comment lines, where "" is a blank line
+func printDSLComments(buf *bytes.Buffer, comments []string, indent string) {
+	for _, c := range comments {
+		if c != "" {
+			buf.WriteString(indent + c)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// dslKey writes a key bare when it can be
+func dslKey(key string) string {
+	if isDSLIdent(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func printDSLValue(buf *bytes.Buffer, v *dslValue, depth int) {
+	switch v.kind {
+	case dslString:
+		buf.WriteString(strconv.Quote(v.text))
+	case dslList:
+		if inline, ok := inlineDSLList(v); ok {
+			buf.WriteString(inline)
+			return
+		}
+		indent := strings.Repeat("  ", depth+1)
+		buf.WriteString("[\n")
+		for _, elem := range v.list {
+			buf.WriteString(indent)
+			printDSLValue(buf, elem, depth+1)
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(strings.Repeat("  ", depth) + "]")
+	case dslObject:
+		printDSLObject(buf, v.object, depth, true)
+	default:
+		buf.WriteString(v.text)
+	}
+}
+
+// printDSLObject writes a braced body, on one line when it is short and
+// has only plain attributes, as inline objects in lists are
+func printDSLObject(buf *bytes.Buffer, body *dslBody, depth int, allowInline bool) {
+	if len(body.items) == 0 && len(body.comments) == 0 {
+		buf.WriteString("{}")
+		return
+	}
+	if allowInline {
+		if inline, ok := inlineDSLObject(body); ok {
+			buf.WriteString(inline)
+			return
+		}
+	}
+	buf.WriteString("{\n")
+	printDSLBody(buf, body, depth+1)
+	buf.WriteString(strings.Repeat("  ", depth) + "}")
+}
+
+// inlineDSLList returns a list on one line, when it fits
+func inlineDSLList(v *dslValue) (string, bool) {
+	parts := make([]string, len(v.list))
+	for i, elem := range v.list {
+		switch elem.kind {
+		case dslList:
+			inline, ok := inlineDSLList(elem)
+			if !ok {
+				return "", false
+			}
+			parts[i] = inline
+		case dslObject:
+			inline, ok := inlineDSLObject(elem.object)
+			if !ok {
+				return "", false
+			}
+			parts[i] = inline
+		case dslString:
+			parts[i] = strconv.Quote(elem.text)
+		default:
+			parts[i] = elem.text
+		}
+	}
+	s := "[" + strings.Join(parts, ", ") + "]"
+	return s, len(s) <= dslLineWidth
+}
+
+// inlineDSLObject returns an object on one line, when it fits and has no
+// comments or blocks
+func inlineDSLObject(body *dslBody) (string, bool) {
+	if len(body.comments) > 0 {
+		return "", false
+	}
+	parts := make([]string, len(body.items))
+	for i, item := range body.items {
+		if item.value == nil || len(item.comments) > 0 || item.trailing != "" || item.value.kind == dslObject {
+			return "", false
+		}
+		var buf bytes.Buffer
+		printDSLValue(&buf, item.value, 0)
+		if bytes.IndexByte(buf.Bytes(), '\n') >= 0 {
+			return "", false
+		}
+		parts[i] = dslKey(item.key) + " = " + buf.String()
+	}
+	s := "{ " + strings.Join(parts, ", ") + " }"
+	return s, len(s) <= dslLineWidth
+}
@@ -0,0 +1,91 @@
+package starfleet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestFormatSceneDSL tests canonical formatting with comments kept
+func TestFormatSceneDSL(t *testing.T) {
+	src := `# Reference scene
+name="Checkout"   # shown in the viewer
+tags = [ "prod",
+  "eu" ]
+
+
+// The API tier
+group "eu-west" { type = "region"
+  node "api" { type = "service", position = [0, 0, 4]
+    metadata { owner = "payments", "k8s.io/app" = "api" }
+  }
+  # trailing note
+}
+edge "api" -> "db"
+edge "db" <-> "api" { id = "repl" }
+`
+	want := `# Reference scene
+name = "Checkout"  # shown in the viewer
+tags = ["prod", "eu"]
+
+// The API tier
+group "eu-west" {
+  type = "region"
+  node "api" {
+    type     = "service"
+    position = [0, 0, 4]
+    metadata {
+      owner        = "payments"
+      "k8s.io/app" = "api"
+    }
+  }
+  # trailing note
+}
+edge "api" -> "db"
+edge "db" <-> "api" {
+  id = "repl"
+}
+`
+	got, err := FormatSceneDSL([]byte(src))
+	if err != nil {
+		t.Fatalf("FormatSceneDSL failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("format mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	again, err := FormatSceneDSL(got)
+	if err != nil || string(again) != want {
+		t.Errorf("format is not idempotent:\n%s", again)
+	}
+}
+
+// TestFormatSceneDSL_Long tests breaking long lists over lines
+func TestFormatSceneDSL_Long(t *testing.T) {
+	src := `tags = ["` + strings.Repeat("a", 40) + `", "` + strings.Repeat("b", 40) + `"]` + "\n"
+	got, err := FormatSceneDSL([]byte(src))
+	if err != nil {
+		t.Fatalf("FormatSceneDSL failed: %v", err)
+	}
+	want := "tags = [\n  \"" + strings.Repeat("a", 40) + "\",\n  \"" + strings.Repeat("b", 40) + "\",\n]\n"
+	if string(got) != want {
+		t.Errorf("format mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestParseDSL_Errors tests that syntax errors name their position
+func TestParseDSL_Errors(t *testing.T) {
+	for src, want := range map[string]string{
+		`name = "open`:                      "line 1, column 8: unterminated string",
+		"node \"a\" {\n  type = \n}":        "line 2, column 10: expected a value",
+		"tags = [1 2]":                      "line 1, column 11: expected , or ] in list",
+		"node \"a\" { type = \"x\" } extra": "line 1, column 25: expected end of line",
+		"x = 01":                            "line 1, column 6: expected end of line",
+		"x = @":                             "line 1, column 5: unexpected '@'",
+		"node \"a\" {":                      "line 1, column 10: unclosed {",
+	} {
+		_, err := parseDSL([]byte(src))
+		if !errors.Is(err, ErrInvalidSceneDSL) || !strings.Contains(err.Error(), want) {
+			t.Errorf("error mismatch for %q: got %v, want %q", src, err, want)
+		}
+	}
+}
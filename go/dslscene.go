@@ -0,0 +1,1034 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// SCENE DSL
+// =============================================================================
+
+// SceneDSLContentType is the media type of scene DSL files
+const SceneDSLContentType = "text/vnd.starfleet.scene"
+
+// ParseSceneDSL builds a scene from DSL source, a readable alternative to
+// JSON for scenes written by hand:
+//
+//	name = "Checkout"
+//
+//	group "eu-west" {
+//	  type = "region"
+//	  node "api" {
+//	    type     = "service"
+//	    position = [0, 0, 4]
+//	    color    = "#2e7d32"
+//	    metadata { owner = "payments" }
+//	  }
+//	}
+//
+//	edge "api" -> "db" { type = "calls" }
+//
+//	theme {
+//	  node "cpu" { property = "color", domain = [0, 1], colors = ["#00ff00", "#ff0000"] }
+//	}
+//
+// Top-level attributes are scene metadata, except schema, the scene format
+// version, and capabilities. node and group blocks declare nodes by ID;
+// blocks nested in them are their children, and a group's type defaults
+// to "group". A node's name defaults to its ID. edge blocks connect two
+// nodes with -> (directed), <-> (bidirectional) or -- (undirected), and
+// are IDed source-target unless they set id. theme holds style rules by
+// element kind and metric, stored under ThemeExtension. material and
+// geometry blocks declare shared resources by ID, and requirements, light,
+// camera, environment, bounds, assets and extensions fill the rest of the
+// scene.
+//
+// Inside elements, attributes and blocks are the JSON fields of nodes and
+// edges, with shorthands: position, rotation and scale as [x, y, z], color
+// as "#rrggbb" or "#rrggbbaa", label as its text, and waypoints as lists
+// of [x, y, z]. Unknown fields are errors, reported with their line and
+// column.
+func ParseSceneDSL(src []byte) (SceneFile, error) {
+	body, err := parseDSL(src)
+	if err != nil {
+		return SceneFile{}, err
+	}
+	e := &dslEvaluator{
+		sf: SceneFile{
+			Version: SchemaVersion,
+			Scene:   SceneGraph{Nodes: []SceneNode{}, Edges: []SceneEdge{}},
+		},
+		nodes: make(map[string]dslPos),
+		edges: make(map[string]dslPos),
+	}
+	if err := e.scene(body); err != nil {
+		return SceneFile{}, err
+	}
+	return e.sf, nil
+}
+
+// dslEvaluator builds a scene from a parsed file
+type dslEvaluator struct {
+	sf    SceneFile
+	nodes map[string]dslPos
+	edges map[string]dslPos
+}
+
+func (e *dslEvaluator) scene(body *dslBody) error {
+	metadata := make(map[string]interface{})
+	var metadataPos dslPos
+	for _, item := range body.items {
+		var err error
+		switch item.key {
+		case "node", "group":
+			_, err = e.node(item, "")
+		case "edge":
+			err = e.edge(item)
+		case "theme":
+			err = e.theme(item)
+		case "material", "geometry":
+			err = e.resource(item)
+		case "light":
+			var light Light
+			if err = dslDecodeBlock(item, dslMaterialColors, &light); err == nil {
+				e.sf.Scene.Lights = append(e.sf.Scene.Lights, light)
+			}
+		case "camera":
+			err = dslDecodeBlock(item, nil, &e.sf.Scene.Camera)
+		case "environment":
+			err = dslDecodeBlock(item, nil, &e.sf.Scene.Environment)
+		case "bounds":
+			err = dslDecodeBlock(item, nil, &e.sf.Scene.Bounds)
+		case "assets":
+			err = dslDecodeBlock(item, nil, &e.sf.Assets)
+		case "extensions":
+			err = dslDecodeBlock(item, nil, &e.sf.Extensions)
+		case "schema":
+			err = dslDecodeAttribute(item, &e.sf.Version)
+		case "capabilities":
+			err = dslDecodeAttribute(item, &e.sf.Capabilities)
+		case "requirements":
+			err = dslDecodeBlock(item, nil, &e.sf.Requirements)
+		default:
+			if len(metadata) == 0 {
+				metadataPos = item.pos
+			}
+			err = dslSetField(metadata, item)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return dslDecode(metadataPos, "metadata", metadata, &e.sf.Metadata)
+}
+
+// node adds a node and its nested children, returning its ID
+func (e *dslEvaluator) node(item *dslItem, parent string) (string, error) {
+	id, err := dslLabelID(item, item.key)
+	if err != nil {
+		return "", err
+	}
+	if pos, ok := e.nodes[id]; ok {
+		return "", item.pos.errorf("node %q is already declared on line %d", id, pos.line)
+	}
+	e.nodes[id] = item.pos
+
+	fields := map[string]interface{}{"id": id, "name": id}
+	transform := map[string]interface{}{
+		"position": map[string]interface{}{"x": 0, "y": 0, "z": 0},
+		"rotation": map[string]interface{}{"x": 0, "y": 0, "z": 0},
+		"scale":    map[string]interface{}{"x": 1, "y": 1, "z": 1},
+	}
+	if item.key == "group" {
+		fields["type"] = "group"
+	}
+	if parent != "" {
+		fields["parent"] = parent
+	}
+	// The node goes before its children, which are added as they are met
+	index := len(e.sf.Scene.Nodes)
+	e.sf.Scene.Nodes = append(e.sf.Scene.Nodes, SceneNode{})
+	var nested []string
+	var color interface{}
+	seen := make(map[string]bool)
+	for _, sub := range dslBodyItems(item) {
+		if seen[sub.key] && sub.key != "node" && sub.key != "group" {
+			return "", sub.pos.errorf("%s is set twice", sub.key)
+		}
+		seen[sub.key] = true
+		switch sub.key {
+		case "node", "group":
+			child, err := e.node(sub, id)
+			if err != nil {
+				return "", err
+			}
+			nested = append(nested, child)
+		case "id":
+			return "", sub.pos.errorf("node IDs are block labels: node %q { ... }", id)
+		case "name", "type":
+			if fields[sub.key], err = dslAttributeValue(sub); err != nil {
+				return "", err
+			}
+		case "parent":
+			if parent != "" {
+				return "", sub.pos.errorf("node %q is nested in %q and cannot set parent", id, parent)
+			}
+			if fields["parent"], err = dslAttributeValue(sub); err != nil {
+				return "", err
+			}
+		case "position", "rotation", "scale":
+			if transform[sub.key], err = dslVector(sub); err != nil {
+				return "", err
+			}
+		case "color":
+			if color, err = dslColorAttribute(sub); err != nil {
+				return "", err
+			}
+		case "label":
+			if fields["label"], err = dslLabelValue(sub); err != nil {
+				return "", err
+			}
+		case "material":
+			if err := dslSetField(fields, sub); err != nil {
+				return "", err
+			}
+			if err := dslMaterialColors(fields["material"]); err != nil {
+				return "", sub.pos.errorf("%v", err)
+			}
+		case "transform":
+			return "", sub.pos.errorf("set position, rotation and scale instead of transform")
+		default:
+			if err := dslSetField(fields, sub); err != nil {
+				return "", err
+			}
+		}
+	}
+	fields["transform"] = transform
+	if color != nil {
+		material, _ := fields["material"].(map[string]interface{})
+		if material == nil {
+			material = make(map[string]interface{})
+		} else if _, ok := material["color"]; ok {
+			return "", item.pos.errorf("node %q sets color twice, in color and material", id)
+		}
+		material["color"] = color
+		fields["material"] = material
+	}
+	if _, ok := fields["children"]; !ok && len(nested) > 0 {
+		fields["children"] = nested
+	}
+
+	var node SceneNode
+	if err := dslDecode(item.pos, fmt.Sprintf("node %q", id), fields, &node); err != nil {
+		return "", err
+	}
+	e.sf.Scene.Nodes[index] = node
+	return id, nil
+}
+
+// edge adds an edge
+func (e *dslEvaluator) edge(item *dslItem) error {
+	if item.value != nil || len(item.labels) != 3 || item.labels[0].arrow || !item.labels[1].arrow || item.labels[2].arrow {
+		return item.pos.errorf(`edges are declared as edge "source" -> "target" { ... }`)
+	}
+	source, target := item.labels[0].text, item.labels[2].text
+	fields := map[string]interface{}{
+		"id":     source + "-" + target,
+		"source": source,
+		"target": target,
+	}
+	switch item.labels[1].text {
+	case "<->":
+		fields["direction"] = EdgeBidirectional
+	case "--":
+		fields["direction"] = EdgeUndirected
+	}
+	seen := make(map[string]bool)
+	for _, sub := range dslBodyItems(item) {
+		if seen[sub.key] {
+			return sub.pos.errorf("%s is set twice", sub.key)
+		}
+		seen[sub.key] = true
+		var err error
+		switch sub.key {
+		case "source", "target":
+			return sub.pos.errorf("edge endpoints are block labels")
+		case "direction":
+			if item.labels[1].text != "->" {
+				return sub.pos.errorf("the %s arrow sets the direction already", item.labels[1].text)
+			}
+			fields["direction"], err = dslAttributeValue(sub)
+		case "id":
+			fields["id"], err = dslAttributeValue(sub)
+		case "color":
+			fields["color"], err = dslColorAttribute(sub)
+		case "label":
+			fields["label"], err = dslLabelValue(sub)
+		case "waypoints":
+			if sub.value == nil || sub.value.kind != dslList {
+				return sub.pos.errorf("waypoints must be a list of [x, y, z]")
+			}
+			points := make([]interface{}, len(sub.value.list))
+			for i, v := range sub.value.list {
+				if points[i], err = dslVectorValue(v); err != nil {
+					return err
+				}
+			}
+			fields["waypoints"] = points
+		default:
+			err = dslSetField(fields, sub)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var edge SceneEdge
+	if err := dslDecode(item.pos, fmt.Sprintf("edge %s %s %s", strconv.Quote(source), item.labels[1].text, strconv.Quote(target)), fields, &edge); err != nil {
+		return err
+	}
+	if pos, ok := e.edges[edge.ID]; ok {
+		return item.pos.errorf("edge %q is already declared on line %d; set id to tell them apart", edge.ID, pos.line)
+	}
+	e.edges[edge.ID] = item.pos
+	e.sf.Scene.Edges = append(e.sf.Scene.Edges, edge)
+	return nil
+}
+
+// theme stores the style rules of a theme block
+func (e *dslEvaluator) theme(item *dslItem) error {
+	if item.value != nil || len(item.labels) > 0 || item.body == nil {
+		return item.pos.errorf("theme is a block of node and edge rules")
+	}
+	var theme Theme
+	for _, sub := range item.body.items {
+		if sub.key != "node" && sub.key != "edge" {
+			return sub.pos.errorf("theme rules are node or edge blocks, not %s", sub.key)
+		}
+		metric, err := dslLabelID(sub, sub.key+" rule")
+		if err != nil {
+			return err
+		}
+		fields := map[string]interface{}{"metric": metric}
+		for _, field := range sub.body.items {
+			if field.key == "colors" && field.value != nil && field.value.kind == dslList {
+				colors := make([]interface{}, len(field.value.list))
+				for i, v := range field.value.list {
+					if colors[i], err = dslColorValue(v); err != nil {
+						return err
+					}
+				}
+				fields["colors"] = colors
+				continue
+			}
+			if err := dslSetField(fields, field); err != nil {
+				return err
+			}
+		}
+		var rule StyleRule
+		if err := dslDecode(sub.pos, fmt.Sprintf("%s rule %q", sub.key, metric), fields, &rule); err != nil {
+			return err
+		}
+		if sub.key == "node" {
+			theme.Nodes = append(theme.Nodes, rule)
+		} else {
+			theme.Edges = append(theme.Edges, rule)
+		}
+	}
+	if err := e.sf.SetTheme(&theme); err != nil {
+		return item.pos.errorf("%v", err)
+	}
+	return nil
+}
+
+// resource adds a shared material or geometry
+func (e *dslEvaluator) resource(item *dslItem) error {
+	id, err := dslLabelID(item, item.key)
+	if err != nil {
+		return err
+	}
+	if item.key == "material" {
+		var m Material
+		if err := dslDecodeBlock(item, dslMaterialColors, &m); err != nil {
+			return err
+		}
+		if e.sf.Materials == nil {
+			e.sf.Materials = make(map[string]Material)
+		}
+		e.sf.Materials[id] = m
+		return nil
+	}
+	var g Geometry
+	if err := dslDecodeBlock(item, nil, &g); err != nil {
+		return err
+	}
+	if e.sf.Geometries == nil {
+		e.sf.Geometries = make(map[string]Geometry)
+	}
+	e.sf.Geometries[id] = g
+	return nil
+}
+
+// dslLabelID returns the single label of a block declaring something by ID
+func dslLabelID(item *dslItem, what string) (string, error) {
+	if item.value != nil || len(item.labels) != 1 || item.labels[0].arrow || item.labels[0].text == "" {
+		return "", item.pos.errorf("%s needs an ID: %s %q { ... }", what, item.key, "id")
+	}
+	return item.labels[0].text, nil
+}
+
+// dslBodyItems returns the items of a block, which may have no body
+func dslBodyItems(item *dslItem) []*dslItem {
+	if item.body == nil {
+		return nil
+	}
+	return item.body.items
+}
+
+// dslSetField sets a JSON field from an attribute or an unlabeled block
+func dslSetField(fields map[string]interface{}, item *dslItem) error {
+	if _, ok := fields[item.key]; ok {
+		return item.pos.errorf("%s is set twice", item.key)
+	}
+	v, err := dslItemValue(item)
+	if err != nil {
+		return err
+	}
+	fields[item.key] = v
+	return nil
+}
+
+// dslItemValue returns the JSON value of an attribute or unlabeled block
+func dslItemValue(item *dslItem) (interface{}, error) {
+	if item.value != nil {
+		return item.value.json()
+	}
+	if len(item.labels) > 0 {
+		return nil, item.pos.errorf("unexpected block %s with labels", item.key)
+	}
+	if item.body == nil {
+		return nil, item.pos.errorf("%s needs a value: %s = ...", item.key, item.key)
+	}
+	return item.body.json()
+}
+
+// dslAttributeValue returns the JSON value of an attribute
+func dslAttributeValue(item *dslItem) (interface{}, error) {
+	if item.value == nil {
+		return nil, item.pos.errorf("%s needs a value: %s = ...", item.key, item.key)
+	}
+	return item.value.json()
+}
+
+// json returns the value as decoded JSON, with numbers as json.Number
+func (v *dslValue) json() (interface{}, error) {
+	switch v.kind {
+	case dslString:
+		return v.text, nil
+	case dslNumber:
+		return json.Number(v.text), nil
+	case dslBool:
+		return v.text == "true", nil
+	case dslList:
+		out := make([]interface{}, len(v.list))
+		for i, elem := range v.list {
+			var err error
+			if out[i], err = elem.json(); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case dslObject:
+		return v.object.json()
+	}
+	return nil, nil
+}
+
+// json returns a body as a JSON object
+func (b *dslBody) json() (interface{}, error) {
+	out := make(map[string]interface{}, len(b.items))
+	for _, item := range b.items {
+		if err := dslSetField(out, item); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// dslVector returns an [x, y, z] attribute as a vector object
+func dslVector(item *dslItem) (interface{}, error) {
+	if item.value == nil {
+		return nil, item.pos.errorf("%s needs a value: %s = [x, y, z]", item.key, item.key)
+	}
+	return dslVectorValue(item.value)
+}
+
+func dslVectorValue(v *dslValue) (interface{}, error) {
+	if v.kind != dslList || len(v.list) != 3 {
+		return nil, v.pos.errorf("expected [x, y, z]")
+	}
+	out := make(map[string]interface{}, 3)
+	for i, axis := range []string{"x", "y", "z"} {
+		if v.list[i].kind != dslNumber {
+			return nil, v.list[i].pos.errorf("expected a number")
+		}
+		out[axis] = json.Number(v.list[i].text)
+	}
+	return out, nil
+}
+
+// dslColorAttribute returns a color attribute as a color object
+func dslColorAttribute(item *dslItem) (interface{}, error) {
+	if item.value == nil {
+		return dslItemValue(item)
+	}
+	return dslColorValue(item.value)
+}
+
+// dslColorValue converts "#rrggbb" and "#rrggbbaa" to color objects and
+// passes other values through
+func dslColorValue(v *dslValue) (interface{}, error) {
+	if v.kind != dslString {
+		return v.json()
+	}
+	c, ok := parseHexColor(v.text)
+	if !ok {
+		return nil, v.pos.errorf("invalid color %q, want #rrggbb or #rrggbbaa", v.text)
+	}
+	return c, nil
+}
+
+// parseHexColor parses "#rrggbb" and "#rrggbbaa"
+func parseHexColor(s string) (Color, bool) {
+	if !strings.HasPrefix(s, "#") || (len(s) != 7 && len(s) != 9) {
+		return Color{}, false
+	}
+	n, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return Color{}, false
+	}
+	if len(s) == 7 {
+		n <<= 8
+	}
+	c := Color{R: float64(n>>24) / 255, G: float64(n>>16&0xff) / 255, B: float64(n>>8&0xff) / 255}
+	if len(s) == 9 {
+		c.A = float64(n&0xff) / 255
+	}
+	return c, true
+}
+
+// hexColor formats a color as "#rrggbb", or "#rrggbbaa" when it has an
+// alpha, when the hex form is exact
+func hexColor(c Color) (string, bool) {
+	channels := []float64{c.R, c.G, c.B}
+	if c.A != 0 {
+		channels = append(channels, c.A)
+	}
+	s := "#"
+	for _, v := range channels {
+		b := math.Round(v * 255)
+		if b < 0 || b > 255 || b/255 != v {
+			return "", false
+		}
+		s += fmt.Sprintf("%02x", int(b))
+	}
+	return s, true
+}
+
+// dslMaterialColors converts hex colors in a material or light object
+func dslMaterialColors(v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for _, key := range []string{"color", "emissive"} {
+		if s, ok := m[key].(string); ok {
+			c, ok := parseHexColor(s)
+			if !ok {
+				return fmt.Errorf("invalid %s %q, want #rrggbb or #rrggbbaa", key, s)
+			}
+			m[key] = c
+		}
+	}
+	return nil
+}
+
+// dslLabelValue returns a label attribute, where a string is its text
+func dslLabelValue(item *dslItem) (interface{}, error) {
+	if item.value != nil && item.value.kind == dslString {
+		return map[string]interface{}{"text": item.value.text}, nil
+	}
+	return dslItemValue(item)
+}
+
+// dslDecodeAttribute decodes an attribute into out
+func dslDecodeAttribute(item *dslItem, out interface{}) error {
+	v, err := dslAttributeValue(item)
+	if err != nil {
+		return err
+	}
+	return dslDecode(item.pos, item.key, v, out)
+}
+
+// dslDecodeBlock decodes an unlabeled block, or the one-labeled blocks of
+// shared resources, into out. convert rewrites the object first.
+func dslDecodeBlock(item *dslItem, convert func(interface{}) error, out interface{}) error {
+	if item.value != nil || item.body == nil {
+		return item.pos.errorf("%s is a block: %s { ... }", item.key, item.key)
+	}
+	v, err := item.body.json()
+	if err != nil {
+		return err
+	}
+	if convert != nil {
+		if err := convert(v); err != nil {
+			return item.pos.errorf("%v", err)
+		}
+	}
+	return dslDecode(item.pos, item.key, v, out)
+}
+
+// dslDecode decodes a JSON value into out, rejecting unknown fields
+func dslDecode(pos dslPos, what string, v interface{}, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return pos.errorf("%s: %v", what, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		return pos.errorf("%s: %v", what, err)
+	}
+	return nil
+}
+
+// =============================================================================
+// SCENE DSL ENCODING
+// =============================================================================
+
+// EncodeSceneDSL writes a scene as DSL source that ParseSceneDSL reads
+// back into the same scene, so existing JSON scenes can be converted.
+// Nodes are nested in their parents, which puts parents before their
+// children in the node order; parents missing from the scene or in a cycle
+// are kept as parent attributes instead.
+func EncodeSceneDSL(w io.Writer, sf *SceneFile) error {
+	body := &dslBody{}
+	add := func(item *dslItem, blank bool) {
+		item.blank = blank && len(body.items) > 0
+		body.items = append(body.items, item)
+	}
+
+	add(dslAttr("schema", sf.Version), false)
+	if len(sf.Capabilities) > 0 {
+		add(dslAttr("capabilities", sf.Capabilities), false)
+	}
+	metadata, err := dslJSONObject(sf.Metadata)
+	if err != nil {
+		return err
+	}
+	for _, item := range dslFields(metadata, []string{"name", "description", "author", "version"}).items {
+		add(item, false)
+	}
+
+	if len(sf.Assets) > 0 {
+		assets, err := dslJSONObject(sf.Assets)
+		if err != nil {
+			return err
+		}
+		add(&dslItem{key: "assets", body: dslFields(assets, nil)}, true)
+	}
+	for _, id := range dslSortedKeys(sf.Materials) {
+		m, err := dslJSONObject(sf.Materials[id])
+		if err != nil {
+			return err
+		}
+		dslHexColors(m)
+		add(&dslItem{key: "material", labels: []dslLabel{{text: id}}, body: dslFields(m, nil)}, true)
+	}
+	for _, id := range dslSortedKeys(sf.Geometries) {
+		g, err := dslJSONObject(sf.Geometries[id])
+		if err != nil {
+			return err
+		}
+		add(&dslItem{key: "geometry", labels: []dslLabel{{text: id}}, body: dslFields(g, []string{"type"})}, true)
+	}
+	block := func(key string, v interface{}) error {
+		fields, err := dslJSONObject(v)
+		if err != nil {
+			return err
+		}
+		add(&dslItem{key: key, body: dslFields(fields, nil)}, true)
+		return nil
+	}
+	if sf.Requirements != nil {
+		if err := block("requirements", sf.Requirements); err != nil {
+			return err
+		}
+	}
+	if sf.Scene.Camera != nil {
+		if err := block("camera", sf.Scene.Camera); err != nil {
+			return err
+		}
+	}
+	if sf.Scene.Environment != nil {
+		if err := block("environment", sf.Scene.Environment); err != nil {
+			return err
+		}
+	}
+	if sf.Scene.Bounds != nil {
+		if err := block("bounds", sf.Scene.Bounds); err != nil {
+			return err
+		}
+	}
+	for _, light := range sf.Scene.Lights {
+		v, err := dslJSONObject(light)
+		if err != nil {
+			return err
+		}
+		dslHexColors(v)
+		add(&dslItem{key: "light", body: dslFields(v, []string{"type"})}, true)
+	}
+
+	theme, err := sf.Theme()
+	if err != nil {
+		return err
+	}
+	if theme != nil {
+		rules := &dslBody{}
+		for _, kind := range []struct {
+			key   string
+			rules []StyleRule
+		}{{"node", theme.Nodes}, {"edge", theme.Edges}} {
+			for _, r := range kind.rules {
+				v, err := dslJSONObject(r)
+				if err != nil {
+					return err
+				}
+				delete(v, "metric")
+				if colors, ok := v["colors"].([]interface{}); ok {
+					for i, c := range colors {
+						colors[i] = dslHexColor(c)
+					}
+				}
+				rules.items = append(rules.items, &dslItem{key: kind.key, labels: []dslLabel{{text: r.Metric}}, body: dslFields(v, []string{"property"})})
+			}
+		}
+		add(&dslItem{key: "theme", body: rules}, true)
+	}
+	if len(sf.Extensions) > 0 {
+		ext, err := dslJSONObject(sf.Extensions)
+		if err != nil {
+			return err
+		}
+		delete(ext, ThemeExtension)
+		if len(ext) > 0 {
+			add(&dslItem{key: "extensions", body: dslFields(ext, nil)}, true)
+		}
+	}
+
+	nodes, err := dslNodes(sf)
+	if err != nil {
+		return err
+	}
+	for _, item := range nodes {
+		add(item, true)
+	}
+	// Edges with bodies are set apart; bare ones are listed together
+	spaced := true
+	for i := range sf.Scene.Edges {
+		item, err := dslEdge(&sf.Scene.Edges[i])
+		if err != nil {
+			return err
+		}
+		add(item, spaced || item.body != nil)
+		spaced = item.body != nil
+	}
+
+	var buf bytes.Buffer
+	printDSLBody(&buf, body, 0)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// dslNodes returns the node blocks of a scene, nesting children in their
+// parents
+func dslNodes(sf *SceneFile) ([]*dslItem, error) {
+	nodes := sf.Scene.Nodes
+	index := make(map[string]int, len(nodes))
+	for i := range nodes {
+		index[nodes[i].ID] = i
+	}
+	// A node nests when its ancestors reach a root without a cycle
+	nests := make([]bool, len(nodes))
+	for i := range nodes {
+		p, ok := index[nodes[i].Parent]
+		steps := 0
+		for ok && steps <= len(nodes) {
+			steps++
+			if nodes[p].Parent == "" {
+				break
+			}
+			p, ok = index[nodes[p].Parent]
+		}
+		nests[i] = nodes[i].Parent != "" && ok && steps <= len(nodes)
+	}
+	children := make(map[string][]int)
+	var roots []int
+	for i := range nodes {
+		if nests[i] {
+			children[nodes[i].Parent] = append(children[nodes[i].Parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	var block func(i int) (*dslItem, error)
+	block = func(i int) (*dslItem, error) {
+		n := &nodes[i]
+		fields, err := dslJSONObject(n)
+		if err != nil {
+			return nil, err
+		}
+		delete(fields, "id")
+		if fields["name"] == n.ID {
+			delete(fields, "name")
+		}
+		if nests[i] {
+			delete(fields, "parent")
+		}
+		nested := make([]string, len(children[n.ID]))
+		for j, c := range children[n.ID] {
+			nested[j] = nodes[c].ID
+		}
+		if len(n.Children) == len(nested) && strings.Join(n.Children, "\x00") == strings.Join(nested, "\x00") {
+			delete(fields, "children")
+		}
+		key := "node"
+		if len(nested) > 0 {
+			key = "group"
+			if n.Type == "group" {
+				delete(fields, "type")
+			}
+		}
+
+		// Shorthands for the transform, material color and label
+		var first []*dslItem
+		if t, ok := fields["transform"].(map[string]interface{}); ok && len(t) == 3 {
+			delete(fields, "transform")
+			for _, axis := range []struct {
+				key   string
+				empty string
+			}{{"position", "0"}, {"rotation", "0"}, {"scale", "1"}} {
+				v, _ := t[axis.key].(map[string]interface{})
+				list := []interface{}{v["x"], v["y"], v["z"]}
+				for _, c := range list {
+					if c != json.Number(axis.empty) {
+						first = append(first, dslAttr(axis.key, list))
+						break
+					}
+				}
+			}
+		}
+		if m, ok := fields["material"].(map[string]interface{}); ok {
+			dslHexColors(m)
+			if c, ok := m["color"].(string); ok && len(m) == 1 {
+				delete(fields, "material")
+				first = append(first, dslAttr("color", c))
+			}
+		}
+		if l, ok := fields["label"].(map[string]interface{}); ok && len(l) == 1 && l["text"] != nil {
+			delete(fields, "label")
+			first = append(first, dslAttr("label", l["text"]))
+		}
+
+		body := dslFields(fields, []string{"type", "name"})
+		lead := 0
+		for lead < len(body.items) && (body.items[lead].key == "type" || body.items[lead].key == "name") {
+			lead++
+		}
+		body.items = append(body.items[:lead], append(first, body.items[lead:]...)...)
+		for _, c := range children[n.ID] {
+			child, err := block(c)
+			if err != nil {
+				return nil, err
+			}
+			child.blank = true
+			body.items = append(body.items, child)
+		}
+		return &dslItem{key: key, labels: []dslLabel{{text: n.ID}}, body: body}, nil
+	}
+
+	items := make([]*dslItem, 0, len(roots))
+	for _, i := range roots {
+		item, err := block(i)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// dslEdge returns the block of an edge, without braces when it only
+// connects its endpoints
+func dslEdge(e *SceneEdge) (*dslItem, error) {
+	fields, err := dslJSONObject(e)
+	if err != nil {
+		return nil, err
+	}
+	arrow := "->"
+	switch e.Direction {
+	case EdgeBidirectional:
+		arrow = "<->"
+		delete(fields, "direction")
+	case EdgeUndirected:
+		arrow = "--"
+		delete(fields, "direction")
+	}
+	delete(fields, "source")
+	delete(fields, "target")
+	if e.ID == e.Source+"-"+e.Target {
+		delete(fields, "id")
+	}
+
+	var first []*dslItem
+	if c, ok := fields["color"]; ok {
+		delete(fields, "color")
+		first = append(first, dslAttr("color", dslHexColor(c)))
+	}
+	if l, ok := fields["label"].(map[string]interface{}); ok && len(l) == 1 && l["text"] != nil {
+		delete(fields, "label")
+		first = append(first, dslAttr("label", l["text"]))
+	}
+	if points, ok := fields["waypoints"].([]interface{}); ok {
+		for i, p := range points {
+			v, _ := p.(map[string]interface{})
+			points[i] = []interface{}{v["x"], v["y"], v["z"]}
+		}
+	}
+
+	item := &dslItem{key: "edge", labels: []dslLabel{{text: e.Source}, {text: arrow, arrow: true}, {text: e.Target}}}
+	body := dslFields(fields, []string{"id", "type"})
+	lead := 0
+	for lead < len(body.items) && (body.items[lead].key == "id" || body.items[lead].key == "type") {
+		lead++
+	}
+	body.items = append(body.items[:lead], append(first, body.items[lead:]...)...)
+	if len(body.items) > 0 {
+		item.body = body
+	}
+	return item, nil
+}
+
+// dslJSONObject returns v as a decoded JSON object, with numbers as written
+func dslJSONObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out map[string]interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// dslFields returns a body setting the fields of a JSON object: first in
+// order, then the other attributes and then the blocks, by key
+func dslFields(fields map[string]interface{}, first []string) *dslBody {
+	body := &dslBody{}
+	for _, key := range first {
+		if v, ok := fields[key]; ok {
+			body.items = append(body.items, dslAttr(key, v))
+		}
+	}
+	var blocks []*dslItem
+	for _, key := range dslSortedKeys(fields) {
+		if slices.Contains(first, key) {
+			continue
+		}
+		if m, ok := fields[key].(map[string]interface{}); ok {
+			blocks = append(blocks, &dslItem{key: key, body: dslFields(m, nil)})
+			continue
+		}
+		body.items = append(body.items, dslAttr(key, fields[key]))
+	}
+	body.items = append(body.items, blocks...)
+	return body
+}
+
+// dslAttr returns an attribute setting a JSON value
+func dslAttr(key string, v interface{}) *dslItem {
+	return &dslItem{key: key, value: dslValueOf(v)}
+}
+
+// dslValueOf converts decoded JSON, or a string slice, to a DSL value
+func dslValueOf(v interface{}) *dslValue {
+	switch v := v.(type) {
+	case string:
+		return &dslValue{kind: dslString, text: v}
+	case json.Number:
+		return &dslValue{kind: dslNumber, text: v.String()}
+	case bool:
+		return &dslValue{kind: dslBool, text: strconv.FormatBool(v)}
+	case []interface{}:
+		out := &dslValue{kind: dslList, list: make([]*dslValue, len(v))}
+		for i, elem := range v {
+			out.list[i] = dslValueOf(elem)
+		}
+		return out
+	case map[string]interface{}:
+		return &dslValue{kind: dslObject, object: dslFields(v, nil)}
+	case nil:
+		return &dslValue{kind: dslNull, text: "null"}
+	}
+	// Typed values, such as capability lists
+	data, _ := json.Marshal(v)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	_ = dec.Decode(&decoded)
+	return dslValueOf(decoded)
+}
+
+// dslHexColors writes the colors of a material or light object in hex
+// where that is exact
+func dslHexColors(m map[string]interface{}) {
+	for _, key := range []string{"color", "emissive"} {
+		if c, ok := m[key]; ok {
+			m[key] = dslHexColor(c)
+		}
+	}
+}
+
+// dslHexColor returns a decoded color object in hex, when exact
+func dslHexColor(v interface{}) interface{} {
+	var c Color
+	if roundTripJSON(v, &c) != nil {
+		return v
+	}
+	if s, ok := hexColor(c); ok {
+		return s
+	}
+	return v
+}
+
+// dslSortedKeys returns the keys of a string-keyed map in order
+func dslSortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
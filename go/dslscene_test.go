@@ -0,0 +1,160 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestParseSceneDSL tests building a scene from the DSL
+func TestParseSceneDSL(t *testing.T) {
+	src := `name = "Checkout"
+tags = ["prod"]
+
+group "eu-west" {
+  type = "region"
+  node "api" {
+    type     = "service"
+    name     = "API"
+    position = [1, 0, 4]
+    color    = "#ff000080"
+    label    = "API"
+    metrics { rps = 120 }
+  }
+  node "db" { type = "database", status = "critical" }
+}
+
+edge "api" -> "db" { type = "calls", waypoints = [[1, 0, 2]] }
+edge "db" <-> "api" { id = "repl" }
+edge "db" -- "api" { id = "peer", type = "peers" }
+
+theme {
+  node "cpu" { property = "color", domain = [0, 1], colors = ["#00ff00", "#ff0000"] }
+}
+`
+	sf, err := ParseSceneDSL([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseSceneDSL failed: %v", err)
+	}
+	if sf.Version != SchemaVersion || sf.Metadata.Name != "Checkout" || sf.Metadata.Tags[0] != "prod" {
+		t.Errorf("metadata mismatch: got %q %+v", sf.Version, sf.Metadata)
+	}
+	if r := ValidateScene(&sf); !r.Valid {
+		t.Errorf("scene is invalid: %v", r.Errors)
+	}
+
+	group, api, db := sf.FindNode("eu-west"), sf.FindNode("api"), sf.FindNode("db")
+	if group == nil || api == nil || db == nil {
+		t.Fatalf("nodes mismatch: got %+v", sf.Scene.Nodes)
+	}
+	if group.Type != "region" || group.Name != "eu-west" || strings.Join(group.Children, ",") != "api,db" {
+		t.Errorf("group mismatch: got %+v", group)
+	}
+	if api.Parent != "eu-west" || api.Name != "API" || api.Transform.Position != (Vector3{X: 1, Z: 4}) || api.Transform.Scale != (Scale3{X: 1, Y: 1, Z: 1}) {
+		t.Errorf("api mismatch: got %+v", api)
+	}
+	if c := api.Material.Color; c == nil || c.R != 1 || c.G != 0 || c.A != float64(0x80)/255 {
+		t.Errorf("color mismatch: got %+v", api.Material.Color)
+	}
+	if api.Label.Text != "API" || api.Metrics["rps"] != float64(120) || db.Status != NodeStatusCritical {
+		t.Errorf("fields mismatch: got %+v and %+v", api, db)
+	}
+
+	if len(sf.Scene.Edges) != 3 {
+		t.Fatalf("edge count mismatch: got %d, want 3", len(sf.Scene.Edges))
+	}
+	calls, repl, peer := sf.Scene.Edges[0], sf.Scene.Edges[1], sf.Scene.Edges[2]
+	if calls.ID != "api-db" || calls.Type != "calls" || !calls.IsDirected() || calls.Waypoints[0] != (Vector3{X: 1, Z: 2}) {
+		t.Errorf("edge mismatch: got %+v", calls)
+	}
+	if repl.Direction != EdgeBidirectional || peer.Direction != EdgeUndirected {
+		t.Errorf("direction mismatch: got %q and %q", repl.Direction, peer.Direction)
+	}
+
+	theme, err := sf.Theme()
+	if err != nil || theme == nil || len(theme.Nodes) != 1 || theme.Nodes[0].Metric != "cpu" || theme.Nodes[0].Colors[1].R != 1 {
+		t.Errorf("theme mismatch: got %+v, %v", theme, err)
+	}
+}
+
+// TestParseSceneDSL_Errors tests that scene errors name their position
+func TestParseSceneDSL_Errors(t *testing.T) {
+	for src, want := range map[string]string{
+		"node \"a\" { type = \"x\" }\nnode \"a\" { type = \"x\" }": `line 2, column 1: node "a" is already declared on line 1`,
+		"node \"a\" {\n  type = \"x\"\n  colour = \"red\"\n}":      `line 1, column 1: node "a": json: unknown field "colour"`,
+		"node \"a\" { position = [1, 2] }":                         "line 1, column 23: expected [x, y, z]",
+		"node \"a\" { color = \"red\" }":                           `line 1, column 20: invalid color "red"`,
+		"node \"a\" { type = \"x\", type = \"y\" }":                "line 1, column 24: type is set twice",
+		"edge \"a\" \"b\"":                                         "edges are declared as",
+		"edge \"a\" -> \"b\"\nedge \"a\" -> \"b\"":                 `line 2, column 1: edge "a-b" is already declared on line 1`,
+		"group \"g\" { node \"a\" { parent = \"h\" } }":            `node "a" is nested in "g" and cannot set parent`,
+		"theme { node \"cpu\" { property = \"glow\" } }":           "unknown property",
+		"nmae = \"x\"": `metadata: json: unknown field "nmae"`,
+	} {
+		_, err := ParseSceneDSL([]byte(src))
+		if !errors.Is(err, ErrInvalidSceneDSL) || !strings.Contains(err.Error(), want) {
+			t.Errorf("error mismatch for %q: got %v, want %q", src, err, want)
+		}
+	}
+}
+
+// TestEncodeSceneDSL tests that encoded scenes parse back unchanged and
+// are already formatted
+func TestEncodeSceneDSL(t *testing.T) {
+	sf := newDiffScene()
+	sf.Metadata.Description = "Round trip"
+	sf.Capabilities = []Capability{"ports"}
+	sf.Requirements = &SceneRequirements{MinViewerVersion: "1.2.0", Features: []FeatureRequirement{{Feature: ViewerFeatureCustomShaders, Extension: "custom"}}}
+	sf.Scene.Camera = &Camera{Position: Vector3{Z: 10}, FOV: 60}
+	sf.Scene.Lights = []Light{{Type: LightPoint, Color: &Color{R: 1, G: 1, B: 1}, Intensity: 0.5}}
+	sf.Materials = map[string]Material{"steel": {Color: &Color{R: 0.3, G: 0.3, B: 0.35}, Metalness: 0.9}}
+	sf.Assets = map[string]string{"logo": "https://example.com/logo.png"}
+	sf.Extensions = map[string]interface{}{"custom": map[string]interface{}{"k": []interface{}{1, "two", nil}}}
+	if err := sf.SetTheme(&Theme{Edges: []StyleRule{{Metric: "rps", Property: StyleWidth, Domain: [2]float64{0, 100}, Output: [2]float64{0.1, 1}}}}); err != nil {
+		t.Fatal(err)
+	}
+	sf.Scene.Nodes[0].Children = []string{"b"}
+	sf.Scene.Nodes[0].Type = "group"
+	sf.Scene.Nodes[1].Parent = "a"
+	sf.Scene.Nodes[1].Transform.Position = Vector3{X: 1.5, Y: -2, Z: 1e-7}
+	sf.Scene.Nodes[1].Material = &Material{Color: &Color{R: 0.2, G: 0.4, B: 1}}
+	sf.Scene.Nodes[1].Metadata = map[string]interface{}{"odd key": "v", "nested": map[string]interface{}{"n": 1.25}}
+	sf.Scene.Nodes[2].Parent = "missing"
+	sf.Scene.Nodes[2].MaterialRef = "steel"
+	sf.Scene.Nodes[2].Label = &Label{Text: "C", Priority: 2}
+	sf.Scene.Edges[0].Color = &Color{R: 0.123, G: 0, B: 0}
+	sf.Scene.Edges[0].Direction = EdgeDirected
+	sf.AddEdge(SceneEdge{ID: "b-c", Source: "b", Target: "c", Direction: EdgeUndirected})
+
+	var buf bytes.Buffer
+	if err := EncodeSceneDSL(&buf, &sf); err != nil {
+		t.Fatalf("EncodeSceneDSL failed: %v", err)
+	}
+	src := buf.String()
+	for _, want := range []string{"group \"a\" {\n  name = \"A\"\n\n  node \"b\" {", `color    = "#3366ff"`, `parent      = "missing"`, `edge "b" -- "c"`, `"odd key" = "v"`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("source lacks %q:\n%s", want, src)
+		}
+	}
+
+	back, err := ParseSceneDSL(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseSceneDSL failed: %v\n%s", err, src)
+	}
+	want, _ := json.Marshal(sf)
+	got, _ := json.Marshal(back)
+	if !bytes.Equal(got, want) {
+		t.Errorf("round trip mismatch:\ngot:  %s\nwant: %s\nsource:\n%s", got, want, src)
+	}
+	formatted, err := FormatSceneDSL(buf.Bytes())
+	if err != nil || string(formatted) != src {
+		t.Errorf("encoded source is not formatted:\n%s", formatted)
+	}
+
+	opened, err := OpenReader(bytes.NewReader(buf.Bytes()), "scene.sfd", DefaultOpenOptions())
+	if err != nil || len(opened.Scene.Nodes) != 3 {
+		t.Errorf("OpenReader mismatch: got %d nodes, %v", len(opened.Scene.Nodes), err)
+	}
+}
@@ -0,0 +1,280 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// =============================================================================
+// EDGE SEMANTICS
+// =============================================================================
+
+// EdgeDirection represents how an edge relates its endpoints
+type EdgeDirection string
+
+const (
+	// EdgeDirected flows from source to target; an empty direction is directed
+	EdgeDirected      EdgeDirection = "directed"
+	EdgeUndirected    EdgeDirection = "undirected"
+	EdgeBidirectional EdgeDirection = "bidirectional"
+)
+
+// PortDirection represents the direction of traffic a port accepts
+type PortDirection string
+
+const (
+	PortIn    PortDirection = "in"
+	PortOut   PortDirection = "out"
+	PortInOut PortDirection = "inout"
+)
+
+// Port represents a named connection point on a node, such as a listener or
+// a replication endpoint. Position is an offset in node-local space.
+type Port struct {
+	ID        string        `json:"id" validate:"required"`
+	Name      string        `json:"name,omitempty"`
+	Direction PortDirection `json:"direction,omitempty"`
+	Protocol  string        `json:"protocol,omitempty"`
+	Position  *Vector3      `json:"position,omitempty"`
+}
+
+// IsDirected reports whether the edge flows only from source to target
+func (e *SceneEdge) IsDirected() bool {
+	return e.Direction == "" || e.Direction == EdgeDirected
+}
+
+// Connects reports whether traffic can flow from one node to another over
+// the edge, honoring its direction
+func (e *SceneEdge) Connects(from, to string) bool {
+	if e.Source == from && e.Target == to {
+		return true
+	}
+	return !e.IsDirected() && e.Source == to && e.Target == from
+}
+
+// multiplicityKey identifies the set of parallel edges an edge belongs to.
+// Non-directed edges are keyed independent of endpoint order.
+func (e *SceneEdge) multiplicityKey() string {
+	source, target := e.Source, e.Target
+	sourcePort, targetPort := e.SourcePort, e.TargetPort
+	if !e.IsDirected() && source > target {
+		source, target = target, source
+		sourcePort, targetPort = targetPort, sourcePort
+	}
+	return fmt.Sprintf("%s:%s\x00%s:%s\x00%s\x00%s", source, sourcePort, target, targetPort, e.Type, e.Key)
+}
+
+// FindPort finds a port on a node by ID
+func (n *SceneNode) FindPort(id string) *Port {
+	for i := range n.Ports {
+		if n.Ports[i].ID == id {
+			return &n.Ports[i]
+		}
+	}
+	return nil
+}
+
+// ValidateEdgeSemantics checks edge direction values, port references and
+// parallel-edge keys. Parallel edges between the same endpoints, ports and
+// type must carry distinct keys.
+func ValidateEdgeSemantics(sf *SceneFile) []string {
+	var errs []string
+
+	nodes := make(map[string]*SceneNode, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		nodes[node.ID] = node
+		seen := make(map[string]bool, len(node.Ports))
+		for _, p := range node.Ports {
+			if p.ID == "" {
+				errs = append(errs, fmt.Sprintf("Node %s has a port without an id", node.ID))
+			} else if seen[p.ID] {
+				errs = append(errs, fmt.Sprintf("Node %s has duplicate port id: %s", node.ID, p.ID))
+			}
+			seen[p.ID] = true
+			switch p.Direction {
+			case "", PortIn, PortOut, PortInOut:
+			default:
+				errs = append(errs, fmt.Sprintf("Port %s on node %s has invalid direction: %s", p.ID, node.ID, p.Direction))
+			}
+		}
+	}
+
+	parallel := make(map[string]string, len(sf.Scene.Edges))
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		switch edge.Direction {
+		case "", EdgeDirected, EdgeUndirected, EdgeBidirectional:
+		default:
+			errs = append(errs, fmt.Sprintf("Edge %s has invalid direction: %s", edge.ID, edge.Direction))
+		}
+
+		errs = append(errs, checkEdgePort(edge, nodes[edge.Source], edge.SourcePort, "source", PortOut)...)
+		errs = append(errs, checkEdgePort(edge, nodes[edge.Target], edge.TargetPort, "target", PortIn)...)
+
+		key := edge.multiplicityKey()
+		if other, ok := parallel[key]; ok {
+			errs = append(errs, fmt.Sprintf("Edge %s duplicates edge %s; parallel edges need distinct keys", edge.ID, other))
+		} else {
+			parallel[key] = edge.ID
+		}
+	}
+
+	return errs
+}
+
+// checkEdgePort validates one endpoint port of an edge. Directed edges must
+// leave through a port that allows outgoing traffic and enter through one
+// that allows incoming traffic.
+func checkEdgePort(edge *SceneEdge, node *SceneNode, portID, end string, want PortDirection) []string {
+	if portID == "" || node == nil {
+		return nil
+	}
+	port := node.FindPort(portID)
+	if port == nil {
+		return []string{fmt.Sprintf("Edge %s references non-existent %s port: %s.%s", edge.ID, end, node.ID, portID)}
+	}
+	if port.Direction == "" || port.Direction == PortInOut {
+		return nil
+	}
+	if edge.IsDirected() && port.Direction != want {
+		return []string{fmt.Sprintf("Edge %s uses %s port %s.%s with direction %s", edge.ID, end, node.ID, portID, port.Direction)}
+	}
+	if !edge.IsDirected() {
+		return []string{fmt.Sprintf("Edge %s is %s but %s port %s.%s is one-way", edge.ID, edge.Direction, end, node.ID, portID)}
+	}
+	return nil
+}
+
+// =============================================================================
+// EDGE CREATION
+// =============================================================================
+
+// ErrDuplicateEdge is returned by ConnectNodes when the scene already has an
+// equivalent edge
+var ErrDuplicateEdge = errors.New("duplicate edge")
+
+// DuplicatePolicy says what ConnectNodes does when the scene already has an
+// edge with the same endpoints, ports, type and key, which validation
+// would reject as an unkeyed parallel edge
+type DuplicatePolicy string
+
+const (
+	// DuplicateReject fails with ErrDuplicateEdge; an empty policy rejects
+	DuplicateReject DuplicatePolicy = "reject"
+	// DuplicateReuse returns the existing edge unchanged
+	DuplicateReuse DuplicatePolicy = "reuse"
+	// DuplicateAllow adds the edge keyed by its ID, so both stay valid
+	DuplicateAllow DuplicatePolicy = "allow"
+)
+
+// ConnectOptions configures ConnectNodes
+type ConnectOptions struct {
+	// Edge holds the fields of the new edge. Its source and target are
+	// replaced by the nodes connected, and an empty ID is generated.
+	Edge SceneEdge
+	// TypeDefaults maps edge types to edges whose direction, weight, style,
+	// color, width, opacity, label and metadata fill in what Edge leaves
+	// unset
+	TypeDefaults map[string]SceneEdge
+	Duplicates   DuplicatePolicy
+}
+
+// ConnectNodes adds an edge from one node to another and returns it. Both
+// nodes must exist, except the target of an edge to another scene, and so
+// must any ports the edge names. Generated IDs are "source-target", with a
+// numeric suffix when taken. Prefer it to AddEdge, which checks nothing.
+// The returned pointer is valid until the scene's edges change.
+func ConnectNodes(sf *SceneFile, sourceID, targetID string, opts ConnectOptions) (*SceneEdge, error) {
+	edge := opts.Edge
+	edge.Source, edge.Target = sourceID, targetID
+	if d, ok := opts.TypeDefaults[edge.Type]; ok {
+		applyEdgeDefaults(&edge, d)
+	}
+
+	fail := func(err error) (*SceneEdge, error) {
+		return nil, fmt.Errorf("connect %s to %s: %w", sourceID, targetID, err)
+	}
+	source := sf.FindNode(sourceID)
+	if source == nil {
+		return fail(fmt.Errorf("%w: %s", ErrNodeNotFound, sourceID))
+	}
+	target := sf.FindNode(targetID)
+	if target == nil && !edge.IsExternal() {
+		return fail(fmt.Errorf("%w: %s", ErrNodeNotFound, targetID))
+	}
+	if edge.SourcePort != "" && source.FindPort(edge.SourcePort) == nil {
+		return fail(fmt.Errorf("node %s has no port %s", sourceID, edge.SourcePort))
+	}
+	if edge.TargetPort != "" && target != nil && target.FindPort(edge.TargetPort) == nil {
+		return fail(fmt.Errorf("node %s has no port %s", targetID, edge.TargetPort))
+	}
+
+	key, duplicate := edge.multiplicityKey(), false
+	for i := range sf.Scene.Edges {
+		if existing := &sf.Scene.Edges[i]; existing.multiplicityKey() == key {
+			switch opts.Duplicates {
+			case DuplicateReuse:
+				return existing, nil
+			case DuplicateAllow:
+				duplicate = true
+			default:
+				return fail(fmt.Errorf("%w: %s", ErrDuplicateEdge, existing.ID))
+			}
+			break
+		}
+	}
+
+	taken := make(map[string]bool, len(sf.Scene.Edges))
+	for _, e := range sf.Scene.Edges {
+		taken[e.ID] = true
+	}
+	switch {
+	case edge.ID == "":
+		edge.ID = sourceID + "-" + targetID
+		if taken[edge.ID] {
+			edge.ID = uniqueID(taken, edge.ID, "edge")
+		}
+	case taken[edge.ID]:
+		return fail(fmt.Errorf("%w: edge %s", ErrDuplicateID, edge.ID))
+	}
+	if duplicate {
+		edge.Key = edge.ID
+	}
+	sf.AddEdge(edge)
+	return &sf.Scene.Edges[len(sf.Scene.Edges)-1], nil
+}
+
+// applyEdgeDefaults fills in the unset style fields of an edge from a
+// type's defaults. Metadata keys the edge sets win.
+func applyEdgeDefaults(e *SceneEdge, d SceneEdge) {
+	if e.Direction == "" {
+		e.Direction = d.Direction
+	}
+	if e.Weight == 0 {
+		e.Weight = d.Weight
+	}
+	if e.Style == "" {
+		e.Style = d.Style
+	}
+	if e.Color == nil && d.Color != nil {
+		c := *d.Color
+		e.Color = &c
+	}
+	if e.Width == 0 {
+		e.Width = d.Width
+	}
+	if e.Opacity == 0 {
+		e.Opacity = d.Opacity
+	}
+	if e.Label == nil && d.Label != nil {
+		l := *d.Label
+		e.Label = &l
+	}
+	if len(d.Metadata) > 0 {
+		metadata := maps.Clone(d.Metadata)
+		maps.Copy(metadata, e.Metadata)
+		e.Metadata = metadata
+	}
+}
@@ -0,0 +1,146 @@
+package starfleet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newPortScene creates a primary/replica pair connected through ports
+func newPortScene() SceneFile {
+	sf := NewSceneFile("Replication")
+	sf.AddNode(SceneNode{
+		ID: "primary", Type: "database", Name: "Primary", Transform: NewTransform(),
+		Ports: []Port{{ID: "wal", Direction: PortOut}, {ID: "sql", Direction: PortIn}},
+	})
+	sf.AddNode(SceneNode{
+		ID: "replica", Type: "database", Name: "Replica", Transform: NewTransform(),
+		Ports: []Port{{ID: "wal", Direction: PortIn}, {ID: "peer", Direction: PortInOut}},
+	})
+	return sf
+}
+
+// TestSceneEdge_Connects tests direction-aware connectivity
+func TestSceneEdge_Connects(t *testing.T) {
+	directed := SceneEdge{Source: "a", Target: "b"}
+	if !directed.Connects("a", "b") || directed.Connects("b", "a") {
+		t.Errorf("Directed edge connectivity mismatch")
+	}
+
+	bidirectional := SceneEdge{Source: "a", Target: "b", Direction: EdgeBidirectional}
+	if !bidirectional.Connects("a", "b") || !bidirectional.Connects("b", "a") {
+		t.Errorf("Bidirectional edge connectivity mismatch")
+	}
+}
+
+// TestValidateEdgeSemantics tests ports, directions and parallel-edge keys
+func TestValidateEdgeSemantics(t *testing.T) {
+	sf := newPortScene()
+	sf.AddEdge(SceneEdge{ID: "wal-1", Source: "primary", Target: "replica", SourcePort: "wal", TargetPort: "wal", Key: "slot-1"})
+	sf.AddEdge(SceneEdge{ID: "wal-2", Source: "primary", Target: "replica", SourcePort: "wal", TargetPort: "wal", Key: "slot-2"})
+	sf.AddEdge(SceneEdge{ID: "peer", Source: "replica", Target: "replica", SourcePort: "peer", TargetPort: "peer", Direction: EdgeUndirected})
+
+	if errs := ValidateEdgeSemantics(&sf); len(errs) != 0 {
+		t.Fatalf("Valid edges reported errors: %v", errs)
+	}
+
+	sf.AddEdge(SceneEdge{ID: "wal-3", Source: "primary", Target: "replica", SourcePort: "wal", TargetPort: "wal", Key: "slot-1"})
+	sf.AddEdge(SceneEdge{ID: "bad-port", Source: "primary", Target: "replica", SourcePort: "sql", TargetPort: "nope"})
+	sf.AddEdge(SceneEdge{ID: "bad-dir", Source: "primary", Target: "replica", Direction: "sideways"})
+
+	errs := ValidateEdgeSemantics(&sf)
+	for _, want := range []string{
+		"Edge wal-3 duplicates edge wal-1",
+		"source port primary.sql with direction in",
+		"non-existent target port: replica.nope",
+		"Edge bad-dir has invalid direction",
+	} {
+		if !containsMessage(errs, want) {
+			t.Errorf("ValidateEdgeSemantics missing error %q: %v", want, errs)
+		}
+	}
+}
+
+// TestValidateEdgeSemantics_Undirected tests that undirected parallel edges
+// are detected regardless of endpoint order
+func TestValidateEdgeSemantics_Undirected(t *testing.T) {
+	sf := newPortScene()
+	sf.AddEdge(SceneEdge{ID: "x", Source: "primary", Target: "replica", Direction: EdgeUndirected})
+	sf.AddEdge(SceneEdge{ID: "y", Source: "replica", Target: "primary", Direction: EdgeUndirected})
+
+	if errs := ValidateEdgeSemantics(&sf); !containsMessage(errs, "Edge y duplicates edge x") {
+		t.Errorf("Undirected duplicate not detected: %v", errs)
+	}
+}
+
+// TestConnectNodes tests endpoint checks, generated IDs and type defaults
+func TestConnectNodes(t *testing.T) {
+	sf := newPortScene()
+	red := NewColor(1, 0, 0)
+	defaults := map[string]SceneEdge{
+		"replicates": {Direction: EdgeBidirectional, Style: EdgeStyleDashed, Color: &red, Metadata: map[string]interface{}{"async": true, "lag": 0}},
+	}
+	opts := ConnectOptions{
+		Edge:         SceneEdge{Type: "replicates", TargetPort: "peer", Metadata: map[string]interface{}{"lag": 5}},
+		TypeDefaults: defaults,
+	}
+	e, err := ConnectNodes(&sf, "primary", "replica", opts)
+	if err != nil {
+		t.Fatalf("ConnectNodes failed: %v", err)
+	}
+	if e.ID != "primary-replica" || e.Direction != EdgeBidirectional || e.Style != EdgeStyleDashed || *e.Color != red {
+		t.Errorf("edge mismatch: got %+v", e)
+	}
+	if e.Metadata["async"] != true || e.Metadata["lag"] != 5 || defaults["replicates"].Metadata["lag"] != 0 {
+		t.Errorf("metadata mismatch: got %v", e.Metadata)
+	}
+	e.Color.G = 1
+	if red.G != 0 {
+		t.Error("defaults should be copied, not shared")
+	}
+
+	// Equivalent edges are rejected, reused or keyed apart
+	if _, err := ConnectNodes(&sf, "replica", "primary", ConnectOptions{Edge: SceneEdge{Type: "replicates", SourcePort: "peer"}, TypeDefaults: defaults}); !errors.Is(err, ErrDuplicateEdge) {
+		t.Errorf("expected ErrDuplicateEdge, got %v", err)
+	}
+	opts.Duplicates = DuplicateReuse
+	if again, err := ConnectNodes(&sf, "primary", "replica", opts); err != nil || again != &sf.Scene.Edges[0] {
+		t.Errorf("reuse mismatch: got %+v, %v", again, err)
+	}
+	opts.Duplicates = DuplicateAllow
+	if again, err := ConnectNodes(&sf, "primary", "replica", opts); err != nil || again.ID != "primary-replica-2" || again.Key != again.ID {
+		t.Errorf("allow mismatch: got %+v, %v", again, err)
+	}
+	if len(sf.Scene.Edges) != 2 {
+		t.Errorf("edge count mismatch: got %d, want 2", len(sf.Scene.Edges))
+	}
+	if r := ValidateScene(&sf); !r.Valid {
+		t.Errorf("scene is invalid: %v", r.Errors)
+	}
+
+	for _, tc := range []struct {
+		source, target string
+		opts           ConnectOptions
+		want           error
+		message        string
+	}{
+		{"primary", "missing", ConnectOptions{}, ErrNodeNotFound, "connect primary to missing: node not found: missing"},
+		{"missing", "primary", ConnectOptions{}, ErrNodeNotFound, "node not found: missing"},
+		{"primary", "replica", ConnectOptions{Edge: SceneEdge{ID: "primary-replica", Type: "other"}}, ErrDuplicateID, "duplicate id: edge primary-replica"},
+		{"primary", "replica", ConnectOptions{Edge: SceneEdge{SourcePort: "nope"}}, nil, "node primary has no port nope"},
+		{"primary", "replica", ConnectOptions{Edge: SceneEdge{TargetPort: "nope"}}, nil, "node replica has no port nope"},
+	} {
+		_, err := ConnectNodes(&sf, tc.source, tc.target, tc.opts)
+		if err == nil || (tc.want != nil && !errors.Is(err, tc.want)) || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("error mismatch for %s to %s: got %v, want %q", tc.source, tc.target, err, tc.message)
+		}
+	}
+	if len(sf.Scene.Edges) != 2 {
+		t.Errorf("failed connections should add nothing: got %d edges", len(sf.Scene.Edges))
+	}
+
+	// Edges to other scenes only need their source here
+	if e, err := ConnectNodes(&sf, "primary", "billing-db", ConnectOptions{Edge: SceneEdge{TargetScene: "billing"}}); err != nil || e.ID != "primary-billing-db" {
+		t.Errorf("external edge mismatch: got %+v, %v", e, err)
+	}
+}
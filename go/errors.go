@@ -0,0 +1,10 @@
+package starfleet
+
+import "errors"
+
+// Sentinel errors returned by scene operations
+var (
+	ErrNodeNotFound = errors.New("node not found")
+	ErrEdgeNotFound = errors.New("edge not found")
+	ErrInvalidMesh  = errors.New("invalid mesh")
+)
@@ -0,0 +1,244 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// =============================================================================
+// EXPIRY
+// =============================================================================
+
+// Nodes and edges with ExpiresAt set are ephemeral: batch jobs, spot
+// instances, short-lived connections. They stay in the scene until an
+// ExpirySweeper, or a caller of RemoveExpired, takes them out, so viewers
+// should still hide elements that are past their expiry.
+
+// Expired reports whether the node has an expiry at or before now
+func (n *SceneNode) Expired(now time.Time) bool {
+	return n.ExpiresAt != nil && !n.ExpiresAt.After(now)
+}
+
+// SetTTL makes the node expire ttl after now
+func (n *SceneNode) SetTTL(ttl time.Duration, now time.Time) {
+	at := now.Add(ttl).UTC()
+	n.ExpiresAt = &at
+}
+
+// Expired reports whether the edge has an expiry at or before now
+func (e *SceneEdge) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !e.ExpiresAt.After(now)
+}
+
+// SetTTL makes the edge expire ttl after now
+func (e *SceneEdge) SetTTL(ttl time.Duration, now time.Time) {
+	at := now.Add(ttl).UTC()
+	e.ExpiresAt = &at
+}
+
+// ExpiredElements lists the nodes and edges removed as expired
+type ExpiredElements struct {
+	Nodes []string `json:"nodes,omitempty"`
+	Edges []string `json:"edges,omitempty"`
+}
+
+// Empty reports whether nothing was removed
+func (x ExpiredElements) Empty() bool {
+	return len(x.Nodes) == 0 && len(x.Edges) == 0
+}
+
+// RemoveExpired removes the nodes and edges that have expired by now. The
+// children of an expired node go with it, as do edges to or from removed
+// nodes, and removed nodes are dropped from the Children of the rest. The
+// node and edge slices are replaced rather than modified in place, so
+// scenes sharing them are not affected.
+func (sf *SceneFile) RemoveExpired(now time.Time) ExpiredElements {
+	var removed ExpiredElements
+	gone := make(map[string]bool)
+	for i := range sf.Scene.Nodes {
+		if sf.Scene.Nodes[i].Expired(now) {
+			gone[sf.Scene.Nodes[i].ID] = true
+		}
+	}
+	if len(gone) > 0 {
+		// Children of removed nodes, to any depth
+		for changed := true; changed; {
+			changed = false
+			for i := range sf.Scene.Nodes {
+				n := &sf.Scene.Nodes[i]
+				if !gone[n.ID] && n.Parent != "" && gone[n.Parent] {
+					gone[n.ID] = true
+					changed = true
+				}
+			}
+		}
+		nodes := make([]SceneNode, 0, len(sf.Scene.Nodes)-len(gone))
+		for _, n := range sf.Scene.Nodes {
+			if gone[n.ID] {
+				removed.Nodes = append(removed.Nodes, n.ID)
+				continue
+			}
+			if len(n.Children) > 0 {
+				children := make([]string, 0, len(n.Children))
+				for _, c := range n.Children {
+					if !gone[c] {
+						children = append(children, c)
+					}
+				}
+				n.Children = children
+			}
+			nodes = append(nodes, n)
+		}
+		sf.Scene.Nodes = nodes
+	}
+
+	var edges []SceneEdge
+	for i, e := range sf.Scene.Edges {
+		// Edges into other scenes name a node there as their target
+		expired := e.Expired(now) || gone[e.Source] || (e.TargetScene == "" && gone[e.Target])
+		if expired && edges == nil {
+			edges = make([]SceneEdge, i, len(sf.Scene.Edges))
+			copy(edges, sf.Scene.Edges[:i])
+		}
+		if expired {
+			removed.Edges = append(removed.Edges, e.ID)
+		} else if edges != nil {
+			edges = append(edges, e)
+		}
+	}
+	if edges != nil {
+		sf.Scene.Edges = edges
+	}
+	return removed
+}
+
+// NextExpiry returns the earliest expiry of a node or edge in the scene,
+// and false when nothing expires
+func (sf *SceneFile) NextExpiry() (time.Time, bool) {
+	var next time.Time
+	found := false
+	consider := func(at *time.Time) {
+		if at != nil && (!found || at.Before(next)) {
+			next, found = *at, true
+		}
+	}
+	for i := range sf.Scene.Nodes {
+		consider(sf.Scene.Nodes[i].ExpiresAt)
+	}
+	for i := range sf.Scene.Edges {
+		consider(sf.Scene.Edges[i].ExpiresAt)
+	}
+	return next, found
+}
+
+// ExpirySweep is a revision an ExpirySweeper wrote to remove expired
+// elements
+type ExpirySweep struct {
+	Revision SceneRevision
+	Expired  ExpiredElements
+}
+
+// Event returns the SceneEventExpired event announcing the sweep
+func (s ExpirySweep) Event() SceneEvent {
+	expired := s.Expired
+	return SceneEvent{
+		Type:     SceneEventExpired,
+		ID:       s.Revision.ID,
+		Revision: s.Revision.Revision,
+		Time:     s.Revision.Updated,
+		Scene:    &s.Revision.Scene,
+		Expired:  &expired,
+	}
+}
+
+// ExpirySweeper periodically removes expired nodes and edges from every
+// scene in a store
+type ExpirySweeper struct {
+	Store    SceneStore
+	Interval time.Duration
+	// OnSweep, when set, receives every revision the sweeper writes
+	OnSweep func(ExpirySweep)
+	// OnError, when set, receives the error of every failed run
+	OnError func(error)
+	// Now returns the current time; time.Now when nil
+	Now func() time.Time
+}
+
+// NewExpirySweeper creates a sweeper visiting store every interval
+func NewExpirySweeper(store SceneStore, interval time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{Store: store, Interval: interval}
+}
+
+// Sweep removes what has expired from every scene once. A scene written
+// concurrently is skipped and swept on the next run; other failures are
+// joined into the returned error after the remaining scenes are swept.
+func (s *ExpirySweeper) Sweep(ctx context.Context) ([]ExpirySweep, error) {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	summaries, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("expiry sweep: %w", err)
+	}
+	var sweeps []ExpirySweep
+	var errs []error
+	for _, summary := range summaries {
+		if err := ctx.Err(); err != nil {
+			return sweeps, err
+		}
+		rev, err := s.Store.Get(ctx, summary.ID)
+		if errors.Is(err, ErrSceneNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expiry sweep %s: %w", summary.ID, err))
+			continue
+		}
+		if next, ok := rev.Scene.NextExpiry(); !ok || next.After(now) {
+			continue
+		}
+		sf := rev.Scene
+		expired := sf.RemoveExpired(now)
+		if expired.Empty() {
+			continue
+		}
+		written, err := s.Store.Put(ctx, summary.ID, sf, rev.Revision)
+		if errors.Is(err, ErrRevisionConflict) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("expiry sweep %s: %w", summary.ID, err))
+			continue
+		}
+		sweep := ExpirySweep{Revision: written, Expired: expired}
+		sweeps = append(sweeps, sweep)
+		if s.OnSweep != nil {
+			s.OnSweep(sweep)
+		}
+	}
+	return sweeps, errors.Join(errs...)
+}
+
+// Run sweeps once immediately and then every Interval until ctx is done,
+// returning the context's error. Failed runs are reported to OnError and
+// retried at the next tick.
+func (s *ExpirySweeper) Run(ctx context.Context) error {
+	if s.Interval <= 0 {
+		return fmt.Errorf("expiry sweeper: interval must be positive")
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Sweep(ctx); err != nil && s.OnError != nil && ctx.Err() == nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
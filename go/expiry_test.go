@@ -0,0 +1,103 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRemoveExpired tests removing expired nodes with their children and
+// edges
+func TestRemoveExpired(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sf := newDiffScene()
+	sf.AddNode(SceneNode{ID: "task", Type: "job", Parent: "c"})
+	sf.Scene.Nodes[2].Children = []string{"task"}
+	sf.Scene.Nodes[2].SetTTL(time.Minute, now)
+	sf.Scene.Nodes[1].SetTTL(time.Hour, now)
+	sf.AddEdge(SceneEdge{ID: "b-task", Source: "b", Target: "task"})
+	sf.AddEdge(SceneEdge{ID: "a-b-2", Source: "a", Target: "b"})
+	sf.Scene.Edges[2].SetTTL(-time.Second, now)
+
+	if next, ok := sf.NextExpiry(); !ok || !next.Equal(now.Add(-time.Second)) {
+		t.Errorf("NextExpiry mismatch: got %v, %v", next, ok)
+	}
+	shared := sf.Scene.Edges
+
+	removed := sf.RemoveExpired(now)
+	want := ExpiredElements{Edges: []string{"a-b-2"}}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed mismatch: got %+v, want %+v", removed, want)
+	}
+	if len(shared) != 3 || shared[2].ID != "a-b-2" {
+		t.Error("edges were modified in place")
+	}
+
+	removed = sf.RemoveExpired(now.Add(2 * time.Minute))
+	want = ExpiredElements{Nodes: []string{"c", "task"}, Edges: []string{"b-task"}}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed mismatch: got %+v, want %+v", removed, want)
+	}
+	if sf.GetNodeCount() != 2 || sf.GetEdgeCount() != 1 {
+		t.Errorf("count mismatch: got %d nodes and %d edges", sf.GetNodeCount(), sf.GetEdgeCount())
+	}
+	if removed := sf.RemoveExpired(now.Add(2 * time.Minute)); !removed.Empty() {
+		t.Errorf("removed mismatch: got %+v, want none", removed)
+	}
+	if next, ok := sf.NextExpiry(); !ok || !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("NextExpiry mismatch: got %v, %v", next, ok)
+	}
+	if caps := DetectCapabilities(&sf); !reflect.DeepEqual(caps, []Capability{CapabilityExpiry}) {
+		t.Errorf("capabilities mismatch: got %v", caps)
+	}
+}
+
+// TestExpirySweeper tests sweeping a store and skipping scenes with
+// nothing expired
+func TestExpirySweeper(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemorySceneStore()
+	ephemeral := newDiffScene()
+	ephemeral.Scene.Nodes[0].SetTTL(time.Minute, now)
+	store.Put(ctx, "batch", ephemeral, 0)
+	store.Put(ctx, "static", newDiffScene(), 0)
+
+	sweeper := NewExpirySweeper(store, time.Minute)
+	sweeper.Now = func() time.Time { return now }
+	var swept []ExpirySweep
+	sweeper.OnSweep = func(s ExpirySweep) { swept = append(swept, s) }
+	if sweeps, err := sweeper.Sweep(ctx); err != nil || len(sweeps) != 0 {
+		t.Fatalf("Sweep mismatch: got %d sweeps, %v", len(sweeps), err)
+	}
+
+	sweeper.Now = func() time.Time { return now.Add(time.Minute) }
+	sweeps, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if len(sweeps) != 1 || len(swept) != 1 || sweeps[0].Revision.ID != "batch" || sweeps[0].Revision.Revision != 2 {
+		t.Fatalf("sweeps mismatch: got %+v", sweeps)
+	}
+	event := sweeps[0].Event()
+	if event.Type != SceneEventExpired || !reflect.DeepEqual(*event.Expired, ExpiredElements{Nodes: []string{"a"}, Edges: []string{"a-b"}}) {
+		t.Errorf("event mismatch: got %+v", event)
+	}
+	if rev, _ := store.Get(ctx, "batch"); rev.Scene.FindNode("a") != nil {
+		t.Error("expired node still stored")
+	}
+	if rev, _ := store.Get(ctx, "static"); rev.Revision != 1 {
+		t.Errorf("revision mismatch: got %d, want 1", rev.Revision)
+	}
+
+	if err := (&ExpirySweeper{Store: store}).Run(ctx); err == nil {
+		t.Error("expected error for zero interval")
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := sweeper.Run(cctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("error mismatch: got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,84 @@
+package starfleet
+
+import (
+	"context"
+	"fmt"
+)
+
+// =============================================================================
+// CROSS-SCENE EDGES
+// =============================================================================
+
+// IsExternal reports whether the edge targets a node in another scene. For
+// external edges Target is a node ID within TargetScene.
+func (e *SceneEdge) IsExternal() bool {
+	return e.TargetScene != ""
+}
+
+// ExternalEdges returns the edges of a scene that target other scenes
+func ExternalEdges(sf *SceneFile) []SceneEdge {
+	var out []SceneEdge
+	for i := range sf.Scene.Edges {
+		if sf.Scene.Edges[i].IsExternal() {
+			out = append(out, sf.Scene.Edges[i])
+		}
+	}
+	return out
+}
+
+// ValidateExternalEdges performs the deferred validation of cross-scene
+// edges: every target scene is loaded once and each edge target must exist
+// in it. Problems are returned as messages; a non-nil error is only returned
+// when the context is cancelled.
+func ValidateExternalEdges(ctx context.Context, sf *SceneFile, loader SceneLoader) ([]string, error) {
+	var errs []string
+	scenes := make(map[string]*SceneFile)
+	failed := make(map[string]bool)
+
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if !edge.IsExternal() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return errs, err
+		}
+
+		target, loaded := scenes[edge.TargetScene]
+		if !loaded && !failed[edge.TargetScene] {
+			var err error
+			target, err = loader.LoadScene(ctx, edge.TargetScene)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return errs, ctxErr
+				}
+				failed[edge.TargetScene] = true
+				errs = append(errs, fmt.Sprintf("Edge %s references unavailable scene %s: %v", edge.ID, edge.TargetScene, err))
+				continue
+			}
+			scenes[edge.TargetScene] = target
+		}
+		if target == nil {
+			continue
+		}
+		if target.FindNode(edge.Target) == nil {
+			errs = append(errs, fmt.Sprintf("Edge %s references non-existent node %s in scene %s", edge.ID, edge.Target, edge.TargetScene))
+		}
+	}
+	return errs, nil
+}
+
+// localizeExternalEdges turns cross-scene edges into local edges once the
+// target scene has been inlined under a namespace prefix
+func localizeExternalEdges(sf *SceneFile, uri, prefix string) {
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if edge.TargetScene != uri {
+			continue
+		}
+		if sf.FindNode(prefix+edge.Target) != nil {
+			edge.Target = prefix + edge.Target
+			edge.TargetScene = ""
+		}
+	}
+}
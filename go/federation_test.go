@@ -0,0 +1,53 @@
+package starfleet
+
+import (
+	"context"
+	"testing"
+)
+
+// TestValidateExternalEdges tests deferred validation of cross-scene edges
+func TestValidateExternalEdges(t *testing.T) {
+	payments := NewSceneFile("Payments")
+	payments.AddNode(SceneNode{ID: "ledger", Type: "service", Name: "Ledger", Transform: NewTransform()})
+
+	sf := NewSceneFile("Checkout")
+	sf.AddNode(SceneNode{ID: "cart", Type: "service", Name: "Cart", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "ok", Source: "cart", Target: "ledger", TargetScene: "payments"})
+	sf.AddEdge(SceneEdge{ID: "gone", Source: "cart", Target: "refunds", TargetScene: "payments"})
+	sf.AddEdge(SceneEdge{ID: "offline", Source: "cart", Target: "x", TargetScene: "billing"})
+
+	if result := ValidateScene(&sf); !result.Valid {
+		t.Fatalf("External edges should not fail local validation: %v", result.Errors)
+	}
+	if n := len(ExternalEdges(&sf)); n != 3 {
+		t.Errorf("ExternalEdges count mismatch: got %d, want 3", n)
+	}
+
+	errs, err := ValidateExternalEdges(context.Background(), &sf, mapLoader(map[string]*SceneFile{"payments": &payments}))
+	if err != nil {
+		t.Fatalf("ValidateExternalEdges failed: %v", err)
+	}
+	if len(errs) != 2 || !containsMessage(errs, "non-existent node refunds") || !containsMessage(errs, "unavailable scene billing") {
+		t.Errorf("ValidateExternalEdges errors mismatch: %v", errs)
+	}
+}
+
+// TestResolveRefs_LocalizesExternalEdges tests that inlining a scene turns
+// edges into it into local edges
+func TestResolveRefs_LocalizesExternalEdges(t *testing.T) {
+	payments := NewSceneFile("Payments")
+	payments.AddNode(SceneNode{ID: "ledger", Type: "service", Name: "Ledger", Transform: NewTransform()})
+
+	sf := NewSceneFile("Org")
+	sf.AddNode(SceneNode{ID: "cart", Type: "service", Name: "Cart", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "pay", Type: "group", Name: "Payments", Transform: NewTransform(), Ref: &SceneRef{URI: "payments"}})
+	sf.AddEdge(SceneEdge{ID: "charge", Source: "cart", Target: "ledger", TargetScene: "payments"})
+
+	if err := ResolveRefs(context.Background(), &sf, mapLoader(map[string]*SceneFile{"payments": &payments})); err != nil {
+		t.Fatalf("ResolveRefs failed: %v", err)
+	}
+	edge := sf.FindEdge("charge")
+	if edge.IsExternal() || edge.Target != "pay/ledger" {
+		t.Errorf("External edge not localized: %+v", edge)
+	}
+}
@@ -0,0 +1,296 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// NON-FINITE NUMBERS
+// =============================================================================
+
+// JSON has no NaN or infinity, so a scene holding one cannot be encoded,
+// and viewers given one by other means cannot place or color what it
+// belongs to. Scenes decoded from JSON never hold them; they come from code
+// that computes positions or copies metrics from a feed.
+
+// ErrNonFiniteNumber is returned when a scene holds NaN or an infinity
+// where it must be encoded
+var ErrNonFiniteNumber = errors.New("non-finite number")
+
+// NonFinitePolicy decides what happens to NaN and infinite numbers when a
+// scene is encoded
+type NonFinitePolicy string
+
+const (
+	// NonFiniteReject fails with ErrNonFiniteNumber, naming the first one
+	NonFiniteReject NonFinitePolicy = "reject"
+	// NonFiniteClamp replaces infinities with the largest finite number of
+	// the same sign and NaN with zero
+	NonFiniteClamp NonFinitePolicy = "clamp"
+	// NonFiniteDrop removes map entries and zeroes fields holding them, so
+	// a broken metric reads as missing rather than as a value
+	NonFiniteDrop NonFinitePolicy = "drop"
+)
+
+// ParseNonFinitePolicy parses a policy name, with the empty name meaning
+// NonFiniteReject
+func ParseNonFinitePolicy(s string) (NonFinitePolicy, error) {
+	switch p := NonFinitePolicy(s); p {
+	case "":
+		return NonFiniteReject, nil
+	case NonFiniteReject, NonFiniteClamp, NonFiniteDrop:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown non-finite policy %q", s)
+}
+
+// isFinite reports whether f is neither NaN nor infinite
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// ValidateFiniteNumbers reports every NaN or infinite number in a scene,
+// by its path: "scene.nodes[web].metrics.cpu"
+func ValidateFiniteNumbers(sf *SceneFile) []string {
+	var errs []string
+	_, _, _ = walkNonFinite(reflect.ValueOf(sf).Elem(), "", func(path string, f float64) (float64, bool, error) {
+		errs = append(errs, fmt.Sprintf("Non-finite number at %s: %v", path, f))
+		return f, true, nil
+	})
+	return errs
+}
+
+// ReplaceNonFinite returns sf with its NaN and infinite numbers handled by
+// policy. sf is not modified: the result shares everything that needed no
+// change. NonFiniteReject returns an error naming the first one instead.
+func ReplaceNonFinite(sf *SceneFile, policy NonFinitePolicy) (SceneFile, error) {
+	policy, err := ParseNonFinitePolicy(string(policy))
+	if err != nil {
+		return SceneFile{}, err
+	}
+	out, changed, err := walkNonFinite(reflect.ValueOf(sf).Elem(), "", func(path string, f float64) (float64, bool, error) {
+		switch policy {
+		case NonFiniteClamp:
+			if math.IsNaN(f) {
+				return 0, true, nil
+			}
+			return math.Copysign(math.MaxFloat64, f), true, nil
+		case NonFiniteDrop:
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("%w at %s: %v", ErrNonFiniteNumber, path, f)
+	})
+	if err != nil {
+		return SceneFile{}, err
+	}
+	if !changed {
+		return *sf, nil
+	}
+	return out.Interface().(SceneFile), nil
+}
+
+// MarshalOptions controls MarshalScene
+type MarshalOptions struct {
+	// NonFinite handles NaN and infinite numbers; empty rejects them
+	NonFinite NonFinitePolicy
+	// Indent, when set, indents nested values by it, one level per line
+	Indent string
+}
+
+// DefaultMarshalOptions returns compact encoding that rejects non-finite
+// numbers
+func DefaultMarshalOptions() MarshalOptions {
+	return MarshalOptions{NonFinite: NonFiniteReject}
+}
+
+// MarshalScene encodes a scene as JSON, handling NaN and infinite numbers
+// by opts.NonFinite instead of failing with encoding/json's generic
+// unsupported value error
+func MarshalScene(sf *SceneFile, opts MarshalOptions) ([]byte, error) {
+	clean, err := ReplaceNonFinite(sf, opts.NonFinite)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Indent != "" {
+		return json.MarshalIndent(clean, "", opts.Indent)
+	}
+	return json.Marshal(clean)
+}
+
+// walkNonFinite visits the non-finite numbers of v, following exported
+// fields, pointers, slices, maps and interfaces. fix returns the
+// replacement of each and whether to keep it at all. When anything
+// changed, walkNonFinite returns a copy of the parts of v on the way to
+// each change, or an invalid value when v itself is to be dropped.
+func walkNonFinite(v reflect.Value, path string, fix func(path string, f float64) (float64, bool, error)) (reflect.Value, bool, error) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if isFinite(f) {
+			return v, false, nil
+		}
+		nf, keep, err := fix(path, f)
+		if err != nil || !keep {
+			return reflect.Value{}, true, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetFloat(nf)
+		return out, true, nil
+
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return v, false, nil
+		}
+		inner, changed, err := walkNonFinite(v.Elem(), path, fix)
+		if err != nil || !changed || !inner.IsValid() {
+			return reflect.Value{}, changed, err
+		}
+		if v.Kind() == reflect.Pointer {
+			p := reflect.New(v.Type().Elem())
+			p.Elem().Set(inner)
+			return p, true, nil
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(inner)
+		return out, true, nil
+
+	case reflect.Struct:
+		var out reflect.Value
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := jsonFieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			fv, changed, err := walkNonFinite(v.Field(i), joinPath(path, name), fix)
+			if err != nil {
+				return v, false, err
+			}
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(t).Elem()
+				out.Set(v)
+			}
+			if fv.IsValid() {
+				out.Field(i).Set(fv)
+			} else {
+				out.Field(i).SetZero()
+			}
+		}
+		if out.IsValid() {
+			return out, true, nil
+		}
+		return v, false, nil
+
+	case reflect.Slice, reflect.Array:
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			ev, changed, err := walkNonFinite(v.Index(i), path+"["+elementKey(v.Index(i), i)+"]", fix)
+			if err != nil {
+				return v, false, err
+			}
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				if v.Kind() == reflect.Slice {
+					out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				} else {
+					out = reflect.New(v.Type()).Elem()
+				}
+				reflect.Copy(out, v)
+			}
+			if ev.IsValid() {
+				out.Index(i).Set(ev)
+			} else {
+				out.Index(i).SetZero()
+			}
+		}
+		if out.IsValid() {
+			return out, true, nil
+		}
+		return v, false, nil
+
+	case reflect.Map:
+		if v.Len() == 0 {
+			return v, false, nil
+		}
+		// Keys in order, so the first non-finite number found is stable
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return mapKeyName(keys[i]) < mapKeyName(keys[j]) })
+		var out reflect.Value
+		for _, k := range keys {
+			nv, changed, err := walkNonFinite(v.MapIndex(k), joinPath(path, mapKeyName(k)), fix)
+			if err != nil {
+				return v, false, err
+			}
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				for iter := v.MapRange(); iter.Next(); {
+					out.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			// An invalid value deletes the entry
+			out.SetMapIndex(k, nv)
+		}
+		if out.IsValid() {
+			return out, true, nil
+		}
+		return v, false, nil
+	}
+	return v, false, nil
+}
+
+// mapKeyName returns a map key as it appears in a path
+func mapKeyName(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// jsonFieldName returns the JSON name of an exported struct field, or ""
+// for fields that are not encoded
+func jsonFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// elementKey names a list element by its ID when it has one, so paths
+// point at nodes and edges rather than at positions
+func elementKey(v reflect.Value, i int) string {
+	if v.Kind() == reflect.Struct {
+		if id := v.FieldByName("ID"); id.IsValid() && id.Kind() == reflect.String && id.String() != "" {
+			return id.String()
+		}
+	}
+	return strconv.Itoa(i)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
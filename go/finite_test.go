@@ -0,0 +1,120 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newNonFiniteScene returns a scene with a NaN metric and an infinite
+// position
+func newNonFiniteScene() SceneFile {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": math.NaN(), "rps": 12.0}
+	sf.Scene.Nodes[1].Transform.Position.X = math.Inf(1)
+	return sf
+}
+
+// TestValidateFiniteNumbers tests that validation names every non-finite
+// number by path
+func TestValidateFiniteNumbers(t *testing.T) {
+	sf := newNonFiniteScene()
+	want := []string{
+		"Non-finite number at scene.nodes[a].metrics.cpu: NaN",
+		"Non-finite number at scene.nodes[b].transform.position.x: +Inf",
+	}
+	if got := ValidateFiniteNumbers(&sf); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors mismatch: got %q, want %q", got, want)
+	}
+	if result := ValidateScene(&sf); result.Valid {
+		t.Error("scene with non-finite numbers validated")
+	}
+	clean := newDiffScene()
+	if got := ValidateFiniteNumbers(&clean); len(got) != 0 {
+		t.Errorf("errors mismatch: got %q, want none", got)
+	}
+}
+
+// TestReplaceNonFinite tests each policy and that the input is left alone
+func TestReplaceNonFinite(t *testing.T) {
+	sf := newNonFiniteScene()
+
+	_, err := ReplaceNonFinite(&sf, NonFiniteReject)
+	if !errors.Is(err, ErrNonFiniteNumber) || !strings.Contains(err.Error(), "scene.nodes[a].metrics.cpu") {
+		t.Errorf("error mismatch: got %v, want %v at the cpu metric", err, ErrNonFiniteNumber)
+	}
+
+	clamped, err := ReplaceNonFinite(&sf, NonFiniteClamp)
+	if err != nil {
+		t.Fatalf("ReplaceNonFinite failed: %v", err)
+	}
+	if got := clamped.Scene.Nodes[0].Metrics["cpu"]; got != 0.0 {
+		t.Errorf("clamped metric mismatch: got %v, want 0", got)
+	}
+	if got := clamped.Scene.Nodes[1].Transform.Position.X; got != math.MaxFloat64 {
+		t.Errorf("clamped position mismatch: got %v, want %v", got, math.MaxFloat64)
+	}
+
+	dropped, err := ReplaceNonFinite(&sf, NonFiniteDrop)
+	if err != nil {
+		t.Fatalf("ReplaceNonFinite failed: %v", err)
+	}
+	if _, ok := dropped.Scene.Nodes[0].Metrics["cpu"]; ok || dropped.Scene.Nodes[0].Metrics["rps"] != 12.0 {
+		t.Errorf("dropped metrics mismatch: got %v", dropped.Scene.Nodes[0].Metrics)
+	}
+	if got := dropped.Scene.Nodes[1].Transform.Position.X; got != 0 {
+		t.Errorf("dropped position mismatch: got %v, want 0", got)
+	}
+
+	// The input keeps its values and unchanged parts are shared
+	if !math.IsNaN(sf.Scene.Nodes[0].Metrics["cpu"].(float64)) || !math.IsInf(sf.Scene.Nodes[1].Transform.Position.X, 1) {
+		t.Error("input scene was modified")
+	}
+	if &dropped.Scene.Edges[0] != &sf.Scene.Edges[0] {
+		t.Error("unchanged edges were copied")
+	}
+
+	if _, err := ReplaceNonFinite(&sf, "round"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+// TestMarshalScene tests encoding scenes under each policy
+func TestMarshalScene(t *testing.T) {
+	sf := newNonFiniteScene()
+	if _, err := MarshalScene(&sf, DefaultMarshalOptions()); !errors.Is(err, ErrNonFiniteNumber) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrNonFiniteNumber)
+	}
+	data, err := MarshalScene(&sf, MarshalOptions{NonFinite: NonFiniteDrop, Indent: "  "})
+	if err != nil {
+		t.Fatalf("MarshalScene failed: %v", err)
+	}
+	var decoded SceneFile
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded.Scene.Nodes) != 3 || !strings.Contains(string(data), "\n  ") {
+		t.Errorf("decoded mismatch: got %d nodes, %v", len(decoded.Scene.Nodes), err)
+	}
+}
+
+// TestNonFiniteMath tests the guards of the vector and bounds helpers
+func TestNonFiniteMath(t *testing.T) {
+	if got := (Vector3{X: math.Inf(1), Y: 1}).Normalize(); got != (Vector3{}) {
+		t.Errorf("Normalize mismatch: got %+v, want zero", got)
+	}
+	if (Vector3{Z: math.NaN()}).IsFinite() || !(Vector3{X: 1}).IsFinite() {
+		t.Error("IsFinite mismatch")
+	}
+
+	good := BoundingSphere{Center: Vector3{X: 1}, Radius: 2}
+	if got := UnionSpheres(BoundingSphere{Center: Vector3{X: math.NaN()}, Radius: 1}, good); got != good {
+		t.Errorf("UnionSpheres mismatch: got %+v, want %+v", got, good)
+	}
+
+	sf := newNonFiniteScene()
+	stats := CalculateSceneStats(&sf)
+	if stats.Bounds == nil || !stats.Bounds.Max.IsFinite() {
+		t.Errorf("bounds mismatch: got %+v", stats.Bounds)
+	}
+}
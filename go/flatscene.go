@@ -0,0 +1,751 @@
+package starfleet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// FLATBUFFERS SCENES
+// =============================================================================
+
+// FlatSceneContentType is the media type of scenes in the FlatBuffers
+// encoding of schema/scene.fbs
+const FlatSceneContentType = "application/vnd.starfleet.scene+flatbuffers"
+
+// flatSceneIdentifier is the file_identifier of schema/scene.fbs
+const flatSceneIdentifier = "SFFB"
+
+// ErrMalformedFlatScene is returned when a buffer is not a valid
+// FlatBuffers scene
+var ErrMalformedFlatScene = errors.New("malformed flatbuffers scene")
+
+// Table layouts. Offsets are from the start of each table, whose first
+// four bytes point at its vtable; slots follow the field order of the
+// schema. Structs and doubles come first so they stay 8-byte aligned.
+const (
+	flatNodeTransform = 8
+	flatNodeID        = 80
+	flatNodeType      = 84
+	flatNodeName      = 88
+	flatNodeStatus    = 92
+	flatNodeParent    = 96
+	flatNodeTags      = 100
+	flatNodeExtra     = 104
+	flatNodeVisible   = 108
+	flatNodeSize      = 112
+
+	flatEdgeWeight = 8
+	flatEdgeID     = 16
+	flatEdgeSource = 20
+	flatEdgeTarget = 24
+	flatEdgeType   = 28
+	flatEdgeExtra  = 32
+	flatEdgeSize   = 40
+
+	flatSceneVersion = 4
+	flatSceneName    = 8
+	flatSceneNodes   = 12
+	flatSceneEdges   = 16
+	flatSceneExtra   = 20
+	flatSceneSize    = 24
+)
+
+// Vtable slots, in schema field order
+const (
+	flatSlotNodeID = iota
+	flatSlotNodeType
+	flatSlotNodeName
+	flatSlotNodeStatus
+	flatSlotNodeParent
+	flatSlotNodeTransform
+	flatSlotNodeTags
+	flatSlotNodeVisible
+	flatSlotNodeExtra
+)
+
+const (
+	flatSlotEdgeID = iota
+	flatSlotEdgeSource
+	flatSlotEdgeTarget
+	flatSlotEdgeType
+	flatSlotEdgeWeight
+	flatSlotEdgeExtra
+)
+
+const (
+	flatSlotSceneVersion = iota
+	flatSlotSceneName
+	flatSlotSceneNodes
+	flatSlotSceneEdges
+	flatSlotSceneExtra
+)
+
+// Inline sizes of each slot, for verification
+var (
+	flatNodeSlotSizes  = []int{4, 4, 4, 4, 4, 72, 4, 1, 4}
+	flatEdgeSlotSizes  = []int{4, 4, 4, 4, 8, 4}
+	flatSceneSlotSizes = []int{4, 4, 4, 4, 4}
+)
+
+// Properties with typed fields, left out of the extra JSON
+var (
+	flatNodeProps  = []string{"id", "type", "name", "status", "parent", "transform", "tags", "visible"}
+	flatEdgeProps  = []string{"id", "source", "target", "type", "weight"}
+	flatSceneProps = []string{"nodes", "edges"}
+)
+
+// EncodeFlatScene encodes a scene in the FlatBuffers form of
+// schema/scene.fbs. Nodes and edges keep their order; properties without a
+// typed field are carried as JSON so FlatScene.SceneFile restores the
+// scene.
+func EncodeFlatScene(sf *SceneFile) ([]byte, error) {
+	b := &flatBuilder{buf: make([]byte, 8, 64*(len(sf.Scene.Nodes)+len(sf.Scene.Edges)+1))}
+	copy(b.buf[4:], flatSceneIdentifier)
+
+	rest := *sf
+	rest.Scene.Nodes, rest.Scene.Edges = nil, nil
+	extra, err := flatExtra(rest, flatSceneProps)
+	if err != nil {
+		return nil, fmt.Errorf("encode flat scene: %w", err)
+	}
+	t := b.table(flatSceneSize, []uint16{
+		flatSlot(sf.Version != "", flatSceneVersion),
+		flatSlot(sf.Metadata.Name != "", flatSceneName),
+		flatSlot(len(sf.Scene.Nodes) > 0, flatSceneNodes),
+		flatSlot(len(sf.Scene.Edges) > 0, flatSceneEdges),
+		flatSlot(extra != nil, flatSceneExtra),
+	})
+	binary.LittleEndian.PutUint32(b.buf, uint32(t))
+	b.string(t+flatSceneVersion, sf.Version)
+	b.string(t+flatSceneName, sf.Metadata.Name)
+	if err := b.tables(t+flatSceneNodes, len(sf.Scene.Nodes), func(i int) (int, error) { return b.node(&sf.Scene.Nodes[i]) }); err != nil {
+		return nil, fmt.Errorf("encode flat scene: %w", err)
+	}
+	if err := b.tables(t+flatSceneEdges, len(sf.Scene.Edges), func(i int) (int, error) { return b.edge(&sf.Scene.Edges[i]) }); err != nil {
+		return nil, fmt.Errorf("encode flat scene: %w", err)
+	}
+	b.bytes(t+flatSceneExtra, extra)
+	return b.buf, nil
+}
+
+// flatExtra returns the JSON of v without the given properties, or nil
+// when nothing is left
+func flatExtra(v interface{}, omit []string) ([]byte, error) {
+	var props map[string]json.RawMessage
+	if err := roundTripJSON(v, &props); err != nil {
+		return nil, err
+	}
+	for _, name := range omit {
+		delete(props, name)
+	}
+	if len(props) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(props)
+}
+
+// flatSlot returns the vtable entry of a field: its offset, or 0 when the
+// field is absent
+func flatSlot(present bool, offset uint16) uint16 {
+	if present {
+		return offset
+	}
+	return 0
+}
+
+// flatBuilder writes a FlatBuffer front to back. Objects are written after
+// the fields that refer to them, so every offset points forward as the
+// format requires.
+type flatBuilder struct {
+	buf []byte
+}
+
+func (b *flatBuilder) pad(n int) {
+	for len(b.buf)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *flatBuilder) uint16(v uint16) {
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, v)
+}
+
+func (b *flatBuilder) uint32(v uint32) {
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, v)
+}
+
+// table writes a vtable and a zeroed table of size bytes after it, and
+// returns the position of the table
+func (b *flatBuilder) table(size int, offsets []uint16) int {
+	b.pad(2)
+	vt := len(b.buf)
+	b.uint16(uint16(4 + 2*len(offsets)))
+	b.uint16(uint16(size))
+	for _, off := range offsets {
+		b.uint16(off)
+	}
+	b.pad(8)
+	t := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[t:], uint32(int32(t-vt)))
+	return t
+}
+
+// refer points the offset field at to the end of the buffer, where the
+// caller writes the object next
+func (b *flatBuilder) refer(at int) {
+	binary.LittleEndian.PutUint32(b.buf[at:], uint32(len(b.buf)-at))
+}
+
+// string writes s for the field at, unless it is empty
+func (b *flatBuilder) string(at int, s string) {
+	if s == "" {
+		return
+	}
+	b.pad(4)
+	b.refer(at)
+	b.uint32(uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+}
+
+// bytes writes a byte vector for the field at, unless it is empty
+func (b *flatBuilder) bytes(at int, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	b.pad(4)
+	b.refer(at)
+	b.uint32(uint32(len(data)))
+	b.buf = append(b.buf, data...)
+}
+
+// strings writes a string vector for the field at, unless it is empty
+func (b *flatBuilder) strings(at int, list []string) {
+	if len(list) == 0 {
+		return
+	}
+	b.pad(4)
+	b.refer(at)
+	vec := len(b.buf)
+	b.uint32(uint32(len(list)))
+	b.buf = append(b.buf, make([]byte, 4*len(list))...)
+	for i, s := range list {
+		elem := vec + 4 + 4*i
+		if s == "" {
+			// Vector elements cannot be absent, so empty strings are
+			// written out
+			b.pad(4)
+			b.refer(elem)
+			b.uint32(0)
+			b.buf = append(b.buf, 0)
+			continue
+		}
+		b.string(elem, s)
+	}
+}
+
+// tables writes a table vector of n elements for the field at, unless n
+// is zero. write writes the i-th table and returns its position.
+func (b *flatBuilder) tables(at, n int, write func(i int) (int, error)) error {
+	if n == 0 {
+		return nil
+	}
+	b.pad(4)
+	b.refer(at)
+	vec := len(b.buf)
+	b.uint32(uint32(n))
+	b.buf = append(b.buf, make([]byte, 4*n)...)
+	for i := 0; i < n; i++ {
+		t, err := write(i)
+		if err != nil {
+			return err
+		}
+		elem := vec + 4 + 4*i
+		binary.LittleEndian.PutUint32(b.buf[elem:], uint32(t-elem))
+	}
+	return nil
+}
+
+func (b *flatBuilder) float64(at int, f float64) {
+	binary.LittleEndian.PutUint64(b.buf[at:], math.Float64bits(f))
+}
+
+// node writes a node table and returns its position
+func (b *flatBuilder) node(n *SceneNode) (int, error) {
+	extra, err := flatExtra(n, flatNodeProps)
+	if err != nil {
+		return 0, fmt.Errorf("node %s: %w", n.ID, err)
+	}
+	t := b.table(flatNodeSize, []uint16{
+		flatSlot(n.ID != "", flatNodeID),
+		flatSlot(n.Type != "", flatNodeType),
+		flatSlot(n.Name != "", flatNodeName),
+		flatSlot(n.Status != "", flatNodeStatus),
+		flatSlot(n.Parent != "", flatNodeParent),
+		flatNodeTransform,
+		flatSlot(len(n.Tags) > 0, flatNodeTags),
+		flatSlot(n.Visible, flatNodeVisible),
+		flatSlot(extra != nil, flatNodeExtra),
+	})
+	tr := n.Transform
+	for i, f := range []float64{
+		tr.Position.X, tr.Position.Y, tr.Position.Z,
+		tr.Rotation.X, tr.Rotation.Y, tr.Rotation.Z,
+		tr.Scale.X, tr.Scale.Y, tr.Scale.Z,
+	} {
+		b.float64(t+flatNodeTransform+8*i, f)
+	}
+	if n.Visible {
+		b.buf[t+flatNodeVisible] = 1
+	}
+	b.string(t+flatNodeID, n.ID)
+	b.string(t+flatNodeType, n.Type)
+	b.string(t+flatNodeName, n.Name)
+	b.string(t+flatNodeStatus, string(n.Status))
+	b.string(t+flatNodeParent, n.Parent)
+	b.strings(t+flatNodeTags, n.Tags)
+	b.bytes(t+flatNodeExtra, extra)
+	return t, nil
+}
+
+// edge writes an edge table and returns its position
+func (b *flatBuilder) edge(e *SceneEdge) (int, error) {
+	extra, err := flatExtra(e, flatEdgeProps)
+	if err != nil {
+		return 0, fmt.Errorf("edge %s: %w", e.ID, err)
+	}
+	t := b.table(flatEdgeSize, []uint16{
+		flatSlot(e.ID != "", flatEdgeID),
+		flatSlot(e.Source != "", flatEdgeSource),
+		flatSlot(e.Target != "", flatEdgeTarget),
+		flatSlot(e.Type != "", flatEdgeType),
+		flatSlot(e.Weight != 0, flatEdgeWeight),
+		flatSlot(extra != nil, flatEdgeExtra),
+	})
+	b.float64(t+flatEdgeWeight, e.Weight)
+	b.string(t+flatEdgeID, e.ID)
+	b.string(t+flatEdgeSource, e.Source)
+	b.string(t+flatEdgeTarget, e.Target)
+	b.string(t+flatEdgeType, e.Type)
+	b.bytes(t+flatEdgeExtra, extra)
+	return t, nil
+}
+
+// =============================================================================
+// FLATBUFFERS READ PATH
+// =============================================================================
+
+// FlatScene is a read-only view of a scene encoded by EncodeFlatScene. Its
+// accessors read straight from the buffer: looking up a node costs no
+// allocation beyond the strings returned, however large the scene. The
+// buffer must not be modified while views of it are in use.
+type FlatScene struct {
+	t flatTable
+}
+
+// FlatNode is a read-only view of a node in a FlatScene
+type FlatNode struct {
+	t flatTable
+}
+
+// FlatEdge is a read-only view of an edge in a FlatScene
+type FlatEdge struct {
+	t flatTable
+}
+
+// OpenFlatScene checks that data is a well-formed FlatBuffers scene and
+// returns a view of it. Every offset is bounds checked once here, without
+// allocating, so the accessors cannot fail afterwards.
+func OpenFlatScene(data []byte) (FlatScene, error) {
+	if len(data) < 8 || string(data[4:8]) != flatSceneIdentifier {
+		return FlatScene{}, fmt.Errorf("%w: missing %s identifier", ErrMalformedFlatScene, flatSceneIdentifier)
+	}
+	v := flatVerifier{buf: data}
+	root, ok := v.table(0, flatSceneSlotSizes)
+	if !ok || !v.strings(root, flatSlotSceneVersion, flatSlotSceneName) || !v.bytes(root, flatSlotSceneExtra) ||
+		!v.tables(root, flatSlotSceneNodes, v.node) || !v.tables(root, flatSlotSceneEdges, v.edge) {
+		return FlatScene{}, fmt.Errorf("%w: %s", ErrMalformedFlatScene, v.err)
+	}
+	return FlatScene{t: root}, nil
+}
+
+// Version returns the scene file version
+func (s FlatScene) Version() string { return s.t.string(flatSlotSceneVersion) }
+
+// Name returns the scene name
+func (s FlatScene) Name() string { return s.t.string(flatSlotSceneName) }
+
+// NodeCount returns the number of nodes
+func (s FlatScene) NodeCount() int { return s.t.len(flatSlotSceneNodes) }
+
+// Node returns the i-th node; it panics when i is out of range
+func (s FlatScene) Node(i int) FlatNode { return FlatNode{s.t.elem(flatSlotSceneNodes, i)} }
+
+// EdgeCount returns the number of edges
+func (s FlatScene) EdgeCount() int { return s.t.len(flatSlotSceneEdges) }
+
+// Edge returns the i-th edge; it panics when i is out of range
+func (s FlatScene) Edge(i int) FlatEdge { return FlatEdge{s.t.elem(flatSlotSceneEdges, i)} }
+
+// FindNode returns the node with the given ID. It scans the node IDs in
+// place, without allocating.
+func (s FlatScene) FindNode(id string) (FlatNode, bool) {
+	for i, n := 0, s.NodeCount(); i < n; i++ {
+		if node := s.Node(i); string(node.t.raw(flatSlotNodeID)) == id {
+			return node, true
+		}
+	}
+	return FlatNode{}, false
+}
+
+// SceneFile converts the view back to a scene file
+func (s FlatScene) SceneFile() (SceneFile, error) {
+	var sf SceneFile
+	if extra := s.t.raw(flatSlotSceneExtra); extra != nil {
+		if err := json.Unmarshal(extra, &sf); err != nil {
+			return SceneFile{}, fmt.Errorf("%w: scene: %v", ErrMalformedFlatScene, err)
+		}
+	}
+	sf.Version = s.Version()
+	sf.Metadata.Name = s.Name()
+	if n := s.NodeCount(); n > 0 {
+		sf.Scene.Nodes = make([]SceneNode, n)
+		for i := range sf.Scene.Nodes {
+			node, err := s.Node(i).SceneNode()
+			if err != nil {
+				return SceneFile{}, err
+			}
+			sf.Scene.Nodes[i] = node
+		}
+	}
+	if n := s.EdgeCount(); n > 0 {
+		sf.Scene.Edges = make([]SceneEdge, n)
+		for i := range sf.Scene.Edges {
+			edge, err := s.Edge(i).SceneEdge()
+			if err != nil {
+				return SceneFile{}, err
+			}
+			sf.Scene.Edges[i] = edge
+		}
+	}
+	return sf, nil
+}
+
+// ID returns the node ID
+func (n FlatNode) ID() string { return n.t.string(flatSlotNodeID) }
+
+// Type returns the node type
+func (n FlatNode) Type() string { return n.t.string(flatSlotNodeType) }
+
+// Name returns the node name
+func (n FlatNode) Name() string { return n.t.string(flatSlotNodeName) }
+
+// Status returns the node status
+func (n FlatNode) Status() NodeStatus { return NodeStatus(n.t.string(flatSlotNodeStatus)) }
+
+// Parent returns the ID of the parent node
+func (n FlatNode) Parent() string { return n.t.string(flatSlotNodeParent) }
+
+// Visible reports whether the node is marked visible
+func (n FlatNode) Visible() bool { return n.t.bool(flatSlotNodeVisible) }
+
+// Position returns the node position
+func (n FlatNode) Position() Vector3 {
+	return Vector3{X: n.t.float64(flatSlotNodeTransform, 0), Y: n.t.float64(flatSlotNodeTransform, 8), Z: n.t.float64(flatSlotNodeTransform, 16)}
+}
+
+// Transform returns the node transform
+func (n FlatNode) Transform() Transform {
+	f := func(i int) float64 { return n.t.float64(flatSlotNodeTransform, 8*i) }
+	return Transform{
+		Position: Vector3{X: f(0), Y: f(1), Z: f(2)},
+		Rotation: Euler3{X: f(3), Y: f(4), Z: f(5)},
+		Scale:    Scale3{X: f(6), Y: f(7), Z: f(8)},
+	}
+}
+
+// TagCount returns the number of tags
+func (n FlatNode) TagCount() int { return n.t.len(flatSlotNodeTags) }
+
+// Tag returns the i-th tag; it panics when i is out of range
+func (n FlatNode) Tag(i int) string {
+	return string(n.t.stringAt(n.t.elemAt(flatSlotNodeTags, i)))
+}
+
+// SceneNode converts the view to a node
+func (n FlatNode) SceneNode() (SceneNode, error) {
+	var node SceneNode
+	if extra := n.t.raw(flatSlotNodeExtra); extra != nil {
+		if err := json.Unmarshal(extra, &node); err != nil {
+			return SceneNode{}, fmt.Errorf("%w: node %s: %v", ErrMalformedFlatScene, n.ID(), err)
+		}
+	}
+	node.ID, node.Type, node.Name = n.ID(), n.Type(), n.Name()
+	node.Status, node.Parent, node.Visible = n.Status(), n.Parent(), n.Visible()
+	node.Transform = n.Transform()
+	if count := n.TagCount(); count > 0 {
+		node.Tags = make([]string, count)
+		for i := range node.Tags {
+			node.Tags[i] = n.Tag(i)
+		}
+	}
+	return node, nil
+}
+
+// ID returns the edge ID
+func (e FlatEdge) ID() string { return e.t.string(flatSlotEdgeID) }
+
+// Source returns the ID of the source node
+func (e FlatEdge) Source() string { return e.t.string(flatSlotEdgeSource) }
+
+// Target returns the ID of the target node
+func (e FlatEdge) Target() string { return e.t.string(flatSlotEdgeTarget) }
+
+// Type returns the edge type
+func (e FlatEdge) Type() string { return e.t.string(flatSlotEdgeType) }
+
+// Weight returns the edge weight
+func (e FlatEdge) Weight() float64 { return e.t.float64(flatSlotEdgeWeight, 0) }
+
+// SceneEdge converts the view to an edge
+func (e FlatEdge) SceneEdge() (SceneEdge, error) {
+	var edge SceneEdge
+	if extra := e.t.raw(flatSlotEdgeExtra); extra != nil {
+		if err := json.Unmarshal(extra, &edge); err != nil {
+			return SceneEdge{}, fmt.Errorf("%w: edge %s: %v", ErrMalformedFlatScene, e.ID(), err)
+		}
+	}
+	edge.ID, edge.Source, edge.Target, edge.Type = e.ID(), e.Source(), e.Target(), e.Type()
+	edge.Weight = e.Weight()
+	return edge, nil
+}
+
+// flatTable is a table in a verified buffer
+type flatTable struct {
+	buf []byte
+	pos int
+}
+
+// field returns the position of a field, or -1 when it is absent
+func (t flatTable) field(slot int) int {
+	vt := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	entry := 4 + 2*slot
+	if entry+2 > int(binary.LittleEndian.Uint16(t.buf[vt:])) {
+		return -1
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vt+entry:]))
+	if off == 0 {
+		return -1
+	}
+	return t.pos + off
+}
+
+// deref follows the offset stored at at
+func (t flatTable) deref(at int) int {
+	return at + int(binary.LittleEndian.Uint32(t.buf[at:]))
+}
+
+// stringAt returns the bytes of the string or byte vector the offset at
+// refers to
+func (t flatTable) stringAt(at int) []byte {
+	p := t.deref(at)
+	n := int(binary.LittleEndian.Uint32(t.buf[p:]))
+	return t.buf[p+4 : p+4+n : p+4+n]
+}
+
+// raw returns the bytes of a string or byte vector field, or nil
+func (t flatTable) raw(slot int) []byte {
+	at := t.field(slot)
+	if at < 0 {
+		return nil
+	}
+	return t.stringAt(at)
+}
+
+func (t flatTable) string(slot int) string {
+	return string(t.raw(slot))
+}
+
+func (t flatTable) bool(slot int) bool {
+	at := t.field(slot)
+	return at >= 0 && t.buf[at] != 0
+}
+
+// float64 reads a double at offset off within a scalar or struct field
+func (t flatTable) float64(slot, off int) float64 {
+	at := t.field(slot)
+	if at < 0 {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(t.buf[at+off:]))
+}
+
+// len returns the length of a vector field
+func (t flatTable) len(slot int) int {
+	at := t.field(slot)
+	if at < 0 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint32(t.buf[t.deref(at):]))
+}
+
+// elemAt returns the position of the i-th offset in a vector field
+func (t flatTable) elemAt(slot, i int) int {
+	if i < 0 || i >= t.len(slot) {
+		panic(fmt.Sprintf("flat scene: index %d out of range", i))
+	}
+	return t.deref(t.field(slot)) + 4 + 4*i
+}
+
+// elem returns the i-th table of a vector field
+func (t flatTable) elem(slot, i int) flatTable {
+	return flatTable{buf: t.buf, pos: t.deref(t.elemAt(slot, i))}
+}
+
+// flatVerifier checks the offsets of a buffer, remembering the first
+// problem found. Each object of a well-formed buffer takes at least four
+// bytes and is referred to once, so following more offsets than a quarter
+// of the buffer means objects are shared; the limit keeps such buffers
+// from making verification quadratic.
+type flatVerifier struct {
+	buf    []byte
+	err    string
+	visits int
+}
+
+func (v *flatVerifier) fail(format string, args ...interface{}) bool {
+	if v.err == "" {
+		v.err = fmt.Sprintf(format, args...)
+	}
+	return false
+}
+
+// in reports whether n bytes from pos lie within the buffer
+func (v *flatVerifier) in(pos int, n uint64) bool {
+	return pos >= 0 && uint64(pos)+n <= uint64(len(v.buf))
+}
+
+// deref follows the offset at at, checking it lands in the buffer
+func (v *flatVerifier) deref(at int) (int, bool) {
+	if !v.in(at, 4) {
+		return 0, v.fail("offset at %d past end", at)
+	}
+	if v.visits++; v.visits > len(v.buf)/4 {
+		return 0, v.fail("too many objects")
+	}
+	p := uint64(at) + uint64(binary.LittleEndian.Uint32(v.buf[at:]))
+	if p+4 > uint64(len(v.buf)) {
+		return 0, v.fail("offset at %d points past end", at)
+	}
+	return int(p), true
+}
+
+// table checks the table the offset at at refers to, with fields of the
+// given sizes
+func (v *flatVerifier) table(at int, sizes []int) (flatTable, bool) {
+	pos, ok := v.deref(at)
+	if !ok {
+		return flatTable{}, false
+	}
+	vt := int64(pos) - int64(int32(binary.LittleEndian.Uint32(v.buf[pos:])))
+	if vt < 0 || !v.in(int(vt), 4) {
+		return flatTable{}, v.fail("vtable of table at %d out of range", pos)
+	}
+	vsize := int(binary.LittleEndian.Uint16(v.buf[vt:]))
+	tsize := int(binary.LittleEndian.Uint16(v.buf[vt+2:]))
+	if vsize < 4 || vsize%2 != 0 || !v.in(int(vt), uint64(vsize)) || tsize < 4 || !v.in(pos, uint64(tsize)) {
+		return flatTable{}, v.fail("table at %d out of range", pos)
+	}
+	for slot := 0; slot < len(sizes) && 4+2*slot < vsize; slot++ {
+		off := int(binary.LittleEndian.Uint16(v.buf[int(vt)+4+2*slot:]))
+		if off != 0 && (off < 4 || off+sizes[slot] > tsize) {
+			return flatTable{}, v.fail("field %d of table at %d out of range", slot, pos)
+		}
+	}
+	return flatTable{buf: v.buf, pos: pos}, true
+}
+
+// vector checks the vector the offset at at refers to, with elements of
+// size bytes, and returns its position and length
+func (v *flatVerifier) vector(at, size int) (int, int, bool) {
+	p, ok := v.deref(at)
+	if !ok {
+		return 0, 0, false
+	}
+	n := binary.LittleEndian.Uint32(v.buf[p:])
+	if !v.in(p+4, uint64(n)*uint64(size)) {
+		return 0, 0, v.fail("vector at %d past end", p)
+	}
+	return p, int(n), true
+}
+
+// string checks the string the offset at at refers to
+func (v *flatVerifier) string(at int) bool {
+	p, n, ok := v.vector(at, 1)
+	if !ok {
+		return false
+	}
+	if !v.in(p+4+n, 1) || v.buf[p+4+n] != 0 {
+		return v.fail("string at %d not terminated", p)
+	}
+	return true
+}
+
+// strings checks string fields of t
+func (v *flatVerifier) strings(t flatTable, slots ...int) bool {
+	for _, slot := range slots {
+		if at := t.field(slot); at >= 0 && !v.string(at) {
+			return false
+		}
+	}
+	return true
+}
+
+// bytes checks a byte vector field of t
+func (v *flatVerifier) bytes(t flatTable, slot int) bool {
+	if at := t.field(slot); at >= 0 {
+		_, _, ok := v.vector(at, 1)
+		return ok
+	}
+	return true
+}
+
+// tables checks a table vector field of t, each element with check
+func (v *flatVerifier) tables(t flatTable, slot int, check func(at int) bool) bool {
+	at := t.field(slot)
+	if at < 0 {
+		return true
+	}
+	p, n, ok := v.vector(at, 4)
+	for i := 0; ok && i < n; i++ {
+		ok = check(p + 4 + 4*i)
+	}
+	return ok
+}
+
+func (v *flatVerifier) node(at int) bool {
+	t, ok := v.table(at, flatNodeSlotSizes)
+	if !ok || !v.strings(t, flatSlotNodeID, flatSlotNodeType, flatSlotNodeName, flatSlotNodeStatus, flatSlotNodeParent) ||
+		!v.bytes(t, flatSlotNodeExtra) {
+		return false
+	}
+	if at := t.field(flatSlotNodeTags); at >= 0 {
+		p, n, ok := v.vector(at, 4)
+		for i := 0; ok && i < n; i++ {
+			ok = v.string(p + 4 + 4*i)
+		}
+		return ok
+	}
+	return true
+}
+
+func (v *flatVerifier) edge(at int) bool {
+	t, ok := v.table(at, flatEdgeSlotSizes)
+	return ok && v.strings(t, flatSlotEdgeID, flatSlotEdgeSource, flatSlotEdgeTarget, flatSlotEdgeType) &&
+		v.bytes(t, flatSlotEdgeExtra)
+}
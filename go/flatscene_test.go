@@ -0,0 +1,159 @@
+package starfleet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// newFlatScene returns a scene with typed and extra properties on every
+// kind of element
+func newFlatScene() SceneFile {
+	sf := newDiffScene()
+	sf.Metadata.Description = "flat"
+	sf.Scene.Nodes[0].Status = NodeStatusCritical
+	sf.Scene.Nodes[0].Tags = []string{"prod", "", "ü"}
+	sf.Scene.Nodes[0].Visible = true
+	sf.Scene.Nodes[0].Transform.Position = Vector3{X: 1.5, Y: -2, Z: 3}
+	sf.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": 0.5}
+	sf.Scene.Nodes[1].Parent = "a"
+	sf.Scene.Nodes[1].Material = &Material{Color: &Color{R: 1, A: 1}}
+	sf.Scene.Edges[0].Weight = 2.5
+	sf.Scene.Edges[0].Style = EdgeStyleDashed
+	return sf
+}
+
+// TestFlatScene tests reading nodes and edges in place and converting
+// back to a scene
+func TestFlatScene(t *testing.T) {
+	sf := newFlatScene()
+	data, err := EncodeFlatScene(&sf)
+	if err != nil {
+		t.Fatalf("EncodeFlatScene failed: %v", err)
+	}
+	if root := binary.LittleEndian.Uint32(data); root%8 != 0 || string(data[4:8]) != "SFFB" {
+		t.Errorf("header mismatch: root at %d, identifier %q", root, data[4:8])
+	}
+
+	flat, err := OpenFlatScene(data)
+	if err != nil {
+		t.Fatalf("OpenFlatScene failed: %v", err)
+	}
+	if flat.Version() != sf.Version || flat.Name() != sf.Metadata.Name {
+		t.Errorf("scene mismatch: got %q %q, want %q %q", flat.Version(), flat.Name(), sf.Version, sf.Metadata.Name)
+	}
+	if flat.NodeCount() != 3 || flat.EdgeCount() != 1 {
+		t.Fatalf("count mismatch: got %d nodes and %d edges", flat.NodeCount(), flat.EdgeCount())
+	}
+	a := flat.Node(0)
+	if a.ID() != "a" || a.Status() != NodeStatusCritical || !a.Visible() || a.Position() != (Vector3{X: 1.5, Y: -2, Z: 3}) {
+		t.Errorf("node mismatch: got %q %q %v %+v", a.ID(), a.Status(), a.Visible(), a.Position())
+	}
+	if a.TagCount() != 3 || a.Tag(1) != "" || a.Tag(2) != "ü" {
+		t.Errorf("tags mismatch: got %d tags", a.TagCount())
+	}
+	if b, ok := flat.FindNode("b"); !ok || b.Parent() != "a" || b.Visible() {
+		t.Errorf("FindNode mismatch: got %q, %v", b.Parent(), ok)
+	}
+	if _, ok := flat.FindNode("missing"); ok {
+		t.Error("FindNode found a missing node")
+	}
+	if e := flat.Edge(0); e.Source() != "a" || e.Target() != "b" || e.Weight() != 2.5 {
+		t.Errorf("edge mismatch: got %q %q %v", e.Source(), e.Target(), e.Weight())
+	}
+
+	got, err := flat.SceneFile()
+	if err != nil {
+		t.Fatalf("SceneFile failed: %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(sf)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("round trip mismatch: got %s, want %s", gotJSON, wantJSON)
+	}
+
+	empty := NewSceneFile("Empty")
+	if data, err = EncodeFlatScene(&empty); err != nil {
+		t.Fatalf("EncodeFlatScene failed: %v", err)
+	}
+	if flat, err = OpenFlatScene(data); err != nil || flat.NodeCount() != 0 {
+		t.Errorf("empty scene mismatch: got %d nodes, %v", flat.NodeCount(), err)
+	}
+}
+
+// TestOpenFlatScene_Malformed tests that damaged buffers are rejected
+func TestOpenFlatScene_Malformed(t *testing.T) {
+	sf := newFlatScene()
+	data, _ := EncodeFlatScene(&sf)
+	for n := 0; n < len(data); n++ {
+		if _, err := OpenFlatScene(data[:n]); !errors.Is(err, ErrMalformedFlatScene) {
+			t.Fatalf("truncated at %d: got %v, want %v", n, err, ErrMalformedFlatScene)
+		}
+	}
+	bad := append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(bad, uint32(len(bad)))
+	if _, err := OpenFlatScene(bad); !errors.Is(err, ErrMalformedFlatScene) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrMalformedFlatScene)
+	}
+}
+
+// FuzzOpenFlatScene tests that no buffer accepted by OpenFlatScene makes
+// an accessor panic
+func FuzzOpenFlatScene(f *testing.F) {
+	sf := newFlatScene()
+	data, _ := EncodeFlatScene(&sf)
+	f.Add(data)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		flat, err := OpenFlatScene(data)
+		if err != nil {
+			return
+		}
+		flat.Version()
+		flat.FindNode("a")
+		for i := 0; i < flat.NodeCount(); i++ {
+			n := flat.Node(i)
+			n.Transform()
+			n.Visible()
+			for j := 0; j < n.TagCount(); j++ {
+				n.Tag(j)
+			}
+		}
+		for i := 0; i < flat.EdgeCount(); i++ {
+			flat.Edge(i).Weight()
+		}
+		_, _ = flat.SceneFile()
+	})
+}
+
+// BenchmarkFlatScene_FindNode compares reading one node from a FlatBuffer
+// with decoding the JSON scene
+func BenchmarkFlatScene_FindNode(b *testing.B) {
+	sf := newLargeScene(5000)
+	flat, _ := EncodeFlatScene(&sf)
+	text, _ := json.Marshal(sf)
+	b.Run("flatbuffers", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			scene, err := OpenFlatScene(flat)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, ok := scene.FindNode("n4999"); !ok {
+				b.Fatal("node not found")
+			}
+		}
+	})
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var scene SceneFile
+			if err := json.Unmarshal(text, &scene); err != nil {
+				b.Fatal(err)
+			}
+			if scene.FindNode("n4999") == nil {
+				b.Fatal("node not found")
+			}
+		}
+	})
+}
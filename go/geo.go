@@ -0,0 +1,233 @@
+package starfleet
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// =============================================================================
+// GEO LAYOUT
+// =============================================================================
+
+// GeoProjection represents how latitude and longitude map to scene space
+type GeoProjection string
+
+const (
+	// GeoSphere places nodes on a globe centered on the origin with the
+	// north pole along +Y and longitude 0 facing +Z
+	GeoSphere GeoProjection = "sphere"
+	// GeoEquirectangular places nodes on the XZ plane with north toward -Z
+	GeoEquirectangular GeoProjection = "equirectangular"
+	// GeoMercator places nodes on the XZ plane using the web Mercator
+	// projection, clamped to ±85° latitude
+	GeoMercator GeoProjection = "mercator"
+)
+
+// LatLon represents a geographic position in degrees
+type LatLon struct {
+	Lat float64 `json:"lat" validate:"min=-90,max=90"`
+	Lon float64 `json:"lon" validate:"min=-180,max=180"`
+}
+
+// DefaultRegionLocations holds approximate locations of common cloud regions
+// for GeoLayout region lookups
+var DefaultRegionLocations = map[string]LatLon{
+	"us-east-1":      {38.9, -77.4},
+	"us-east-2":      {40.0, -83.0},
+	"us-west-1":      {37.4, -122.0},
+	"us-west-2":      {45.6, -121.2},
+	"ca-central-1":   {45.5, -73.6},
+	"sa-east-1":      {-23.5, -46.6},
+	"eu-west-1":      {53.3, -6.3},
+	"eu-west-2":      {51.5, -0.1},
+	"eu-central-1":   {50.1, 8.7},
+	"ap-south-1":     {19.1, 72.9},
+	"ap-southeast-1": {1.35, 103.8},
+	"ap-southeast-2": {-33.9, 151.2},
+	"ap-northeast-1": {35.7, 139.7},
+}
+
+// GeoLayout places nodes by geographic position, read from latitude and
+// longitude metadata or looked up from a region name. Nodes sharing a
+// location are spread on a small ring around it. Edges between different
+// locations are routed along great circles, lifted into an arc.
+type GeoLayout struct {
+	Projection GeoProjection
+	// Radius is the globe radius; flat maps are 2π·Radius wide
+	Radius float64
+
+	LatKey    string
+	LonKey    string
+	RegionKey string
+	// Regions maps region names to locations
+	Regions map[string]LatLon
+
+	// Spread is the ring radius in degrees for co-located nodes
+	Spread float64
+	// ArcSegments is the number of segments per routed edge
+	ArcSegments int
+	// ArcHeight is the peak lift of the longest possible arc as a fraction
+	// of Radius; shorter arcs are lifted proportionally less
+	ArcHeight float64
+}
+
+// NewGeoLayout creates a geo layout with a globe radius of 10 reading the
+// "lat", "lon" and "region" metadata keys
+func NewGeoLayout(projection GeoProjection) *GeoLayout {
+	regions := make(map[string]LatLon, len(DefaultRegionLocations))
+	for name, p := range DefaultRegionLocations {
+		regions[name] = p
+	}
+	return &GeoLayout{
+		Projection:  projection,
+		Radius:      10,
+		LatKey:      "lat",
+		LonKey:      "lon",
+		RegionKey:   "region",
+		Regions:     regions,
+		Spread:      1.5,
+		ArcSegments: 16,
+		ArcHeight:   0.25,
+	}
+}
+
+// Project maps a geographic position and an altitude above the surface to
+// scene space
+func (l *GeoLayout) Project(p LatLon, altitude float64) Vector3 {
+	lat, lon := p.Lat*math.Pi/180, p.Lon*math.Pi/180
+	switch l.Projection {
+	case GeoEquirectangular:
+		return Vector3{X: l.Radius * lon, Y: altitude, Z: -l.Radius * lat}
+	case GeoMercator:
+		lat = math.Max(-85, math.Min(85, p.Lat)) * math.Pi / 180
+		return Vector3{X: l.Radius * lon, Y: altitude, Z: -l.Radius * math.Log(math.Tan(math.Pi/4+lat/2))}
+	default:
+		r := l.Radius + altitude
+		return Vector3{
+			X: r * math.Cos(lat) * math.Sin(lon),
+			Y: r * math.Sin(lat),
+			Z: r * math.Cos(lat) * math.Cos(lon),
+		}
+	}
+}
+
+// locate returns the geographic position of a node
+func (l *GeoLayout) locate(node *SceneNode) (LatLon, bool) {
+	lat, okLat := toFloat64(node.Metadata[l.LatKey])
+	lon, okLon := toFloat64(node.Metadata[l.LonKey])
+	if okLat && okLon {
+		return LatLon{Lat: lat, Lon: lon}, true
+	}
+	if region, ok := toString(node.Metadata[l.RegionKey]); ok {
+		p, ok := l.Regions[region]
+		return p, ok
+	}
+	return LatLon{}, false
+}
+
+// Apply implements Layout
+func (l *GeoLayout) Apply(sf *SceneFile) (LayoutResult, error) {
+	return l.ApplyContext(context.Background(), sf)
+}
+
+// ApplyContext implements ContextLayout. Edge arcs, the bulk of the work on
+// large scenes, are routed in parallel.
+func (l *GeoLayout) ApplyContext(ctx context.Context, sf *SceneFile) (LayoutResult, error) {
+	result := LayoutResult{}
+	if l.Radius <= 0 {
+		return result, fmt.Errorf("geo layout: radius must be positive")
+	}
+
+	located := make(map[string]LatLon)
+	groups := make(map[LatLon][]int)
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		p, ok := l.locate(node)
+		if !ok {
+			if node.Metadata[l.RegionKey] != nil {
+				result.Skipped = append(result.Skipped, node.ID)
+				result.Warnings = append(result.Warnings, fmt.Sprintf(
+					"node %s: unknown region %v", node.ID, node.Metadata[l.RegionKey]))
+			}
+			continue
+		}
+		if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+			result.Skipped = append(result.Skipped, node.ID)
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"node %s: location %v,%v out of range", node.ID, p.Lat, p.Lon))
+			continue
+		}
+		groups[p] = append(groups[p], i)
+	}
+
+	for center, members := range groups {
+		sort.Slice(members, func(i, j int) bool {
+			return sf.Scene.Nodes[members[i]].ID < sf.Scene.Nodes[members[j]].ID
+		})
+		for k, i := range members {
+			p := center
+			if len(members) > 1 {
+				angle := 2 * math.Pi * float64(k) / float64(len(members))
+				p.Lat = math.Max(-90, math.Min(90, p.Lat+l.Spread*math.Sin(angle)))
+				// Widen the longitude offset so the ring stays round away
+				// from the equator
+				p.Lon += l.Spread * math.Cos(angle) / math.Max(math.Cos(center.Lat*math.Pi/180), 0.1)
+			}
+			node := &sf.Scene.Nodes[i]
+			node.Transform.Position = l.Project(p, 0)
+			located[node.ID] = p
+			result.Positioned++
+		}
+	}
+
+	err := parallelFor(ctx, len(sf.Scene.Edges), parallelBlock, func(i int) {
+		edge := &sf.Scene.Edges[i]
+		from, okFrom := located[edge.Source]
+		to, okTo := located[edge.Target]
+		if okFrom && okTo {
+			edge.Waypoints = l.arc(from, to)
+		}
+	})
+	return result, err
+}
+
+// arc returns the interior waypoints of a lifted great-circle route, or nil
+// for routes too short to need one
+func (l *GeoLayout) arc(from, to LatLon) []Vector3 {
+	a, b := unitVector(from), unitVector(to)
+	angle := math.Acos(math.Max(-1, math.Min(1, a.Dot(b))))
+	segments := l.ArcSegments
+	if segments < 2 || angle < 2*l.Spread*math.Pi/180 {
+		return nil
+	}
+	peak := l.ArcHeight * l.Radius * angle / math.Pi
+
+	waypoints := make([]Vector3, 0, segments-1)
+	for k := 1; k < segments; k++ {
+		t := float64(k) / float64(segments)
+		// Spherical linear interpolation between the two unit vectors
+		var p Vector3
+		if s := math.Sin(angle); s < 1e-9 {
+			p = a.Lerp(b, t).Normalize()
+		} else {
+			p = a.Scale(math.Sin((1-t)*angle) / s).Add(b.Scale(math.Sin(t*angle) / s))
+		}
+		ll := LatLon{Lat: math.Asin(math.Max(-1, math.Min(1, p.Y))) * 180 / math.Pi, Lon: math.Atan2(p.X, p.Z) * 180 / math.Pi}
+		// Flat maps cannot draw routes across the antimeridian, so those
+		// stay on the map with a straight latitude/longitude interpolation
+		if l.Projection != GeoSphere && math.Abs(to.Lon-from.Lon) > 180 {
+			ll = LatLon{Lat: from.Lat + (to.Lat-from.Lat)*t, Lon: from.Lon + (to.Lon-from.Lon)*t}
+		}
+		waypoints = append(waypoints, l.Project(ll, peak*math.Sin(math.Pi*t)))
+	}
+	return waypoints
+}
+
+// unitVector returns the direction of a geographic position on the unit
+// sphere, using the GeoSphere axis convention
+func unitVector(p LatLon) Vector3 {
+	lat, lon := p.Lat*math.Pi/180, p.Lon*math.Pi/180
+	return Vector3{X: math.Cos(lat) * math.Sin(lon), Y: math.Sin(lat), Z: math.Cos(lat) * math.Cos(lon)}
+}
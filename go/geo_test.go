@@ -0,0 +1,97 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// newGeoScene returns nodes in three regions with one inter-region edge
+func newGeoScene() SceneFile {
+	sf := NewSceneFile("Regions")
+	add := func(id string, meta map[string]interface{}) {
+		sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{ID: id, Transform: NewTransform(), Metadata: meta})
+	}
+	add("use1-a", map[string]interface{}{"region": "us-east-1"})
+	add("use1-b", map[string]interface{}{"region": "us-east-1"})
+	add("fra", map[string]interface{}{"region": "eu-central-1"})
+	add("pole", map[string]interface{}{"lat": 90.0, "lon": 0.0})
+	add("mars", map[string]interface{}{"region": "mars-north-1"})
+	add("plain", nil)
+	sf.Scene.Edges = []SceneEdge{
+		{ID: "transatlantic", Source: "use1-a", Target: "fra"},
+		{ID: "local", Source: "use1-a", Target: "use1-b"},
+	}
+	return sf
+}
+
+// TestGeoLayout_Sphere tests globe placement and great-circle routing
+func TestGeoLayout_Sphere(t *testing.T) {
+	sf := newGeoScene()
+	layout := NewGeoLayout(GeoSphere)
+	result, err := layout.Apply(&sf)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.Positioned != 4 {
+		t.Errorf("positioned mismatch: got %d, want 4", result.Positioned)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "mars" {
+		t.Errorf("skipped mismatch: got %v, want [mars]", result.Skipped)
+	}
+
+	for _, id := range []string{"use1-a", "use1-b", "fra", "pole"} {
+		if r := sf.FindNode(id).Transform.Position.Length(); math.Abs(r-layout.Radius) > 1e-9 {
+			t.Errorf("node %s radius mismatch: got %v, want %v", id, r, layout.Radius)
+		}
+	}
+	if p := sf.FindNode("pole").Transform.Position; math.Abs(p.Y-layout.Radius) > 1e-9 {
+		t.Errorf("north pole mismatch: got %v, want +Y", p)
+	}
+	a, b := sf.FindNode("use1-a").Transform.Position, sf.FindNode("use1-b").Transform.Position
+	if a.Sub(b).Length() < 0.1 {
+		t.Error("co-located nodes should be spread apart")
+	}
+
+	route := sf.FindEdge("transatlantic").Waypoints
+	if len(route) != layout.ArcSegments-1 {
+		t.Fatalf("waypoint count mismatch: got %d, want %d", len(route), layout.ArcSegments-1)
+	}
+	if mid := route[len(route)/2].Length(); mid <= layout.Radius {
+		t.Errorf("arc should be lifted above the globe, got radius %v", mid)
+	}
+	if sf.FindEdge("local").Waypoints != nil {
+		t.Error("intra-region edges should not be routed")
+	}
+}
+
+// TestGeoLayout_Flat tests map projections
+func TestGeoLayout_Flat(t *testing.T) {
+	layout := NewGeoLayout(GeoEquirectangular)
+	p := layout.Project(LatLon{Lat: 45, Lon: 90}, 0)
+	want := Vector3{X: layout.Radius * math.Pi / 2, Z: -layout.Radius * math.Pi / 4}
+	if p.Sub(want).Length() > 1e-9 {
+		t.Errorf("equirectangular mismatch: got %v, want %v", p, want)
+	}
+
+	layout = NewGeoLayout(GeoMercator)
+	if p := layout.Project(LatLon{Lat: 90}, 0); math.IsInf(p.Z, 0) || math.IsNaN(p.Z) {
+		t.Errorf("mercator pole should be clamped, got %v", p)
+	}
+
+	// Routes across the antimeridian stay on the map
+	sf := NewSceneFile("Pacific")
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "tokyo", Metadata: map[string]interface{}{"lat": 35.7, "lon": 139.7}},
+		{ID: "sf", Metadata: map[string]interface{}{"lat": 37.4, "lon": -122.0}},
+	}
+	sf.Scene.Edges = []SceneEdge{{ID: "pacific", Source: "tokyo", Target: "sf"}}
+	if _, err := NewGeoLayout(GeoEquirectangular).Apply(&sf); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	limit := NewGeoLayout(GeoEquirectangular).Radius * math.Pi
+	for _, w := range sf.FindEdge("pacific").Waypoints {
+		if math.Abs(w.X) > limit {
+			t.Errorf("waypoint %v outside the map", w)
+		}
+	}
+}
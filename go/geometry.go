@@ -0,0 +1,110 @@
+package starfleet
+
+import (
+	"context"
+	"math"
+)
+
+// =============================================================================
+// GEOMETRY EXTENTS
+// =============================================================================
+
+// parameter returns a numeric geometry parameter or the fallback value
+func (g *Geometry) parameter(key string, fallback float64) float64 {
+	if g == nil || g.Parameters == nil {
+		return fallback
+	}
+	if v, ok := toFloat64(g.Parameters[key]); ok {
+		return v
+	}
+	return fallback
+}
+
+// geometryHalfExtents returns the local-space half size of a geometry before
+// the node transform is applied. Nodes without geometry are treated as unit
+// cubes, matching the default viewer placeholder.
+func geometryHalfExtents(g *Geometry) Vector3 {
+	if g == nil {
+		return Vector3{X: 0.5, Y: 0.5, Z: 0.5}
+	}
+	switch g.Type {
+	case GeometryBox:
+		return Vector3{
+			X: g.parameter("width", 1) / 2,
+			Y: g.parameter("height", 1) / 2,
+			Z: g.parameter("depth", 1) / 2,
+		}
+	case GeometrySphere:
+		r := g.parameter("radius", 0.5)
+		return Vector3{X: r, Y: r, Z: r}
+	case GeometryCylinder:
+		r := g.parameter("radius", 0.5)
+		r = math.Max(g.parameter("radiusTop", r), g.parameter("radiusBottom", r))
+		return Vector3{X: r, Y: g.parameter("height", 1) / 2, Z: r}
+	case GeometryPlane:
+		return Vector3{X: g.parameter("width", 1) / 2, Y: g.parameter("height", 1) / 2}
+	default:
+		if g.Mesh != nil && g.Mesh.VertexCount() > 0 {
+			return meshHalfExtents(g.Mesh)
+		}
+		return Vector3{X: 0.5, Y: 0.5, Z: 0.5}
+	}
+}
+
+// meshHalfExtents returns the largest absolute coordinate of a mesh per axis
+func meshHalfExtents(m *Mesh) Vector3 {
+	h := Vector3{}
+	for i := 0; i+2 < len(m.Positions); i += 3 {
+		h.X = math.Max(h.X, math.Abs(m.Positions[i]))
+		h.Y = math.Max(h.Y, math.Abs(m.Positions[i+1]))
+		h.Z = math.Max(h.Z, math.Abs(m.Positions[i+2]))
+	}
+	return h
+}
+
+// rotationMatrix returns the rotation matrix for Euler angles applied in XYZ
+// order, matching the default order used by the viewer
+func rotationMatrix(e Euler3) [3][3]float64 {
+	a, b := math.Cos(e.X), math.Sin(e.X)
+	c, d := math.Cos(e.Y), math.Sin(e.Y)
+	f, g := math.Cos(e.Z), math.Sin(e.Z)
+	return [3][3]float64{
+		{c * f, -c * g, d},
+		{a*g + b*f*d, a*f - b*g*d, -b * c},
+		{b*g - a*f*d, b*f + a*g*d, a * c},
+	}
+}
+
+// nodeHalfExtents returns the world-space axis-aligned half size of a node
+// with the given (resolved) geometry, accounting for scale and rotation
+func nodeHalfExtents(node *SceneNode, geometry *Geometry) Vector3 {
+	local := geometryHalfExtents(geometry)
+	s := node.Transform.Scale
+	local = Vector3{X: local.X * math.Abs(s.X), Y: local.Y * math.Abs(s.Y), Z: local.Z * math.Abs(s.Z)}
+
+	m := rotationMatrix(node.Transform.Rotation)
+	axis := func(row [3]float64) float64 {
+		return math.Abs(row[0])*local.X + math.Abs(row[1])*local.Y + math.Abs(row[2])*local.Z
+	}
+	return Vector3{X: axis(m[0]), Y: axis(m[1]), Z: axis(m[2])}
+}
+
+// sceneBounds returns the axis-aligned world bounds of all nodes, or false
+// when the scene has no nodes
+func sceneBounds(sf *SceneFile) (Vector3, Vector3, bool) {
+	if len(sf.Scene.Nodes) == 0 {
+		return Vector3{}, Vector3{}, false
+	}
+	half := make([]Vector3, len(sf.Scene.Nodes))
+	_ = parallelFor(context.Background(), len(half), parallelBlock, func(i int) {
+		half[i] = nodeHalfExtents(&sf.Scene.Nodes[i], sf.ResolveGeometry(&sf.Scene.Nodes[i]))
+	})
+	inf := math.Inf(1)
+	lo, hi := Vector3{X: inf, Y: inf, Z: inf}, Vector3{X: -inf, Y: -inf, Z: -inf}
+	for i := range sf.Scene.Nodes {
+		p, h := sf.Scene.Nodes[i].Transform.Position, half[i]
+		lo = Vector3{X: math.Min(lo.X, p.X-h.X), Y: math.Min(lo.Y, p.Y-h.Y), Z: math.Min(lo.Z, p.Z-h.Z)}
+		hi = Vector3{X: math.Max(hi.X, p.X+h.X), Y: math.Max(hi.Y, p.Y+h.Y), Z: math.Max(hi.Z, p.Z+h.Z)}
+	}
+	return lo, hi, true
+}
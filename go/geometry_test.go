@@ -0,0 +1,37 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// TestNodeHalfExtents tests world-space extents for geometry, scale and rotation
+func TestNodeHalfExtents(t *testing.T) {
+	node := SceneNode{
+		Transform: NewTransform(),
+		Geometry: &Geometry{
+			Type:       GeometryBox,
+			Parameters: map[string]interface{}{"width": 4, "height": 2.0, "depth": 2},
+		},
+	}
+	if got := nodeHalfExtents(&node, node.Geometry); got != (Vector3{X: 2, Y: 1, Z: 1}) {
+		t.Errorf("Box extents mismatch: got %+v", got)
+	}
+
+	node.Transform.Scale = Scale3{X: 2, Y: 1, Z: 1}
+	if got := nodeHalfExtents(&node, node.Geometry); got.X != 4 {
+		t.Errorf("Scaled extents mismatch: got %+v", got)
+	}
+
+	node.Transform.Scale = Scale3{X: 1, Y: 1, Z: 1}
+	node.Transform.Rotation = Euler3{Z: math.Pi / 2}
+	got := nodeHalfExtents(&node, node.Geometry)
+	if math.Abs(got.X-1) > 1e-9 || math.Abs(got.Y-2) > 1e-9 {
+		t.Errorf("Rotated extents mismatch: got %+v", got)
+	}
+
+	sphere := SceneNode{Transform: NewTransform(), Geometry: &Geometry{Type: GeometrySphere}}
+	if got := nodeHalfExtents(&sphere, sphere.Geometry); got != (Vector3{X: 0.5, Y: 0.5, Z: 0.5}) {
+		t.Errorf("Default sphere extents mismatch: got %+v", got)
+	}
+}
@@ -0,0 +1,419 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// =============================================================================
+// GLTF EXPORT
+// =============================================================================
+
+// GLTFContentType is the media type of binary glTF files
+const GLTFContentType = "model/gltf-binary"
+
+// meshoptExtension is the glTF extension carrying meshopt-compressed buffer
+// views, encoded with the same bitstreams as CompressedMesh
+const meshoptExtension = "EXT_meshopt_compression"
+
+// glTF constants used by the exporter
+const (
+	glbMagic        = 0x46546c67 // "glTF"
+	glbVersion      = 2
+	glbChunkJSON    = 0x4e4f534a // "JSON"
+	glbChunkBIN     = 0x004e4942 // "BIN\0"
+	gltfFloat       = 5126
+	gltfUnsignedInt = 5125
+	gltfArrayBuffer = 34962
+	gltfIndexBuffer = 34963
+)
+
+// GLTFOptions configures EncodeGLB
+type GLTFOptions struct {
+	// MeshCompression encodes vertex and index data with the
+	// EXT_meshopt_compression extension. The extension is then required,
+	// so readers without meshopt support cannot open the file.
+	MeshCompression bool
+}
+
+type gltfDocument struct {
+	Asset              gltfAsset        `json:"asset"`
+	ExtensionsUsed     []string         `json:"extensionsUsed,omitempty"`
+	ExtensionsRequired []string         `json:"extensionsRequired,omitempty"`
+	Scene              int              `json:"scene"`
+	Scenes             []gltfScene      `json:"scenes"`
+	Nodes              []gltfNode       `json:"nodes,omitempty"`
+	Meshes             []gltfMesh       `json:"meshes,omitempty"`
+	Materials          []gltfMaterial   `json:"materials,omitempty"`
+	Accessors          []gltfAccessor   `json:"accessors,omitempty"`
+	BufferViews        []gltfBufferView `json:"bufferViews,omitempty"`
+	Buffers            []gltfBuffer     `json:"buffers,omitempty"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type gltfScene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name        string                 `json:"name,omitempty"`
+	Mesh        *int                   `json:"mesh,omitempty"`
+	Translation []float64              `json:"translation,omitempty"`
+	Rotation    []float64              `json:"rotation,omitempty"`
+	Scale       []float64              `json:"scale,omitempty"`
+	Extras      map[string]interface{} `json:"extras,omitempty"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+}
+
+type gltfMaterial struct {
+	Name           string    `json:"name,omitempty"`
+	PBR            gltfPBR   `json:"pbrMetallicRoughness"`
+	EmissiveFactor []float64 `json:"emissiveFactor,omitempty"`
+	AlphaMode      string    `json:"alphaMode,omitempty"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor []float64 `json:"baseColorFactor"`
+	MetallicFactor  float64   `json:"metallicFactor"`
+	RoughnessFactor float64   `json:"roughnessFactor"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int                    `json:"buffer"`
+	ByteOffset int                    `json:"byteOffset"`
+	ByteLength int                    `json:"byteLength"`
+	ByteStride int                    `json:"byteStride,omitempty"`
+	Target     int                    `json:"target,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int                    `json:"byteLength"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// gltfMeshopt is the EXT_meshopt_compression object of a buffer view
+type gltfMeshopt struct {
+	Buffer     int    `json:"buffer"`
+	ByteOffset int    `json:"byteOffset"`
+	ByteLength int    `json:"byteLength"`
+	ByteStride int    `json:"byteStride"`
+	Count      int    `json:"count"`
+	Mode       string `json:"mode"`
+}
+
+// gltfEncoder accumulates the document and its binary buffer
+type gltfEncoder struct {
+	sf        *SceneFile
+	opts      GLTFOptions
+	doc       gltfDocument
+	bin       []byte
+	fallback  int // length of the uncompressed fallback buffer
+	meshes    map[string]int
+	materials map[string]int
+}
+
+// EncodeGLB writes the scene as a binary glTF 2.0 file. Every node becomes a
+// glTF node with its transform and a mesh tessellated from its geometry, so
+// primitives come out as triangles; materials map to metallic-roughness
+// materials. Library geometries and materials are written once and shared.
+// Node IDs and types are kept in the node extras. Edges, labels and other
+// overlays are not exported.
+func EncodeGLB(w io.Writer, sf *SceneFile, opts GLTFOptions) error {
+	e := &gltfEncoder{
+		sf:        sf,
+		opts:      opts,
+		meshes:    map[string]int{},
+		materials: map[string]int{},
+	}
+	e.doc.Asset = gltfAsset{Version: "2.0", Generator: "Starfleet SDK"}
+	e.doc.Scenes = []gltfScene{{Name: sf.Metadata.Name, Nodes: []int{}}}
+
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		gn := gltfNode{
+			Name:   node.Name,
+			Extras: map[string]interface{}{"id": node.ID, "type": node.Type},
+		}
+		t := node.Transform
+		if p := t.Position; p != (Vector3{}) {
+			gn.Translation = []float64{p.X, p.Y, p.Z}
+		}
+		if r := t.Rotation; r != (Euler3{}) {
+			q := eulerQuaternion(r)
+			gn.Rotation = q[:]
+		}
+		if s := t.Scale; s != (Scale3{X: 1, Y: 1, Z: 1}) {
+			gn.Scale = []float64{s.X, s.Y, s.Z}
+		}
+		if mesh, ok := e.mesh(node); ok {
+			gn.Mesh = &mesh
+		}
+		e.doc.Scenes[0].Nodes = append(e.doc.Scenes[0].Nodes, len(e.doc.Nodes))
+		e.doc.Nodes = append(e.doc.Nodes, gn)
+	}
+
+	if len(e.bin) > 0 {
+		e.doc.Buffers = []gltfBuffer{{ByteLength: len(e.bin)}}
+		if opts.MeshCompression {
+			// Readers without the extension would read the uncompressed data
+			// from this buffer, which has no storage of its own
+			e.doc.Buffers = append(e.doc.Buffers, gltfBuffer{
+				ByteLength: e.fallback,
+				Extensions: map[string]interface{}{meshoptExtension: map[string]bool{"fallback": true}},
+			})
+			e.doc.ExtensionsUsed = []string{meshoptExtension}
+			e.doc.ExtensionsRequired = []string{meshoptExtension}
+		}
+	}
+	return e.write(w)
+}
+
+// mesh returns the glTF mesh index of a node, adding the mesh on first use
+func (e *gltfEncoder) mesh(node *SceneNode) (int, bool) {
+	key := "node:" + node.ID
+	if node.Geometry == nil && node.GeometryRef != "" {
+		key = "geometry:" + node.GeometryRef
+	}
+	material := e.material(node)
+	if material != nil {
+		key += fmt.Sprintf("|material:%d", *material)
+	}
+	if i, ok := e.meshes[key]; ok {
+		return i, true
+	}
+
+	m := geometryMesh(e.sf.ResolveGeometry(node))
+	count := m.VertexCount()
+	if count == 0 {
+		return 0, false
+	}
+	positions := float32Bytes(m.Positions)
+	minimum := []float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	maximum := []float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for i, v := range m.Positions {
+		v = float64(float32(v))
+		minimum[i%3] = math.Min(minimum[i%3], v)
+		maximum[i%3] = math.Max(maximum[i%3], v)
+	}
+	primitive := gltfPrimitive{
+		Attributes: map[string]int{"POSITION": e.accessor(gltfAccessor{
+			BufferView:    e.vertexView(positions, count),
+			ComponentType: gltfFloat,
+			Count:         count,
+			Type:          "VEC3",
+			Min:           minimum,
+			Max:           maximum,
+		})},
+		Material: material,
+	}
+	if len(m.Normals) == len(m.Positions) {
+		primitive.Attributes["NORMAL"] = e.accessor(gltfAccessor{
+			BufferView:    e.vertexView(float32Bytes(m.Normals), count),
+			ComponentType: gltfFloat,
+			Count:         count,
+			Type:          "VEC3",
+		})
+	}
+	if len(m.Indices) > 0 {
+		indices := e.accessor(gltfAccessor{
+			BufferView:    e.indexView(m.Indices),
+			ComponentType: gltfUnsignedInt,
+			Count:         len(m.Indices),
+			Type:          "SCALAR",
+		})
+		primitive.Indices = &indices
+	}
+
+	i := len(e.doc.Meshes)
+	e.doc.Meshes = append(e.doc.Meshes, gltfMesh{Primitives: []gltfPrimitive{primitive}})
+	e.meshes[key] = i
+	return i, true
+}
+
+// material returns the glTF material index of a node, or nil when it has
+// none
+func (e *gltfEncoder) material(node *SceneNode) *int {
+	m := e.sf.ResolveMaterial(node)
+	if m == nil {
+		return nil
+	}
+	key := "node:" + node.ID
+	if node.Material == nil {
+		key = "material:" + node.MaterialRef
+	}
+	if i, ok := e.materials[key]; ok {
+		return &i
+	}
+
+	gm := gltfMaterial{
+		PBR: gltfPBR{
+			BaseColorFactor: []float64{1, 1, 1, 1},
+			MetallicFactor:  m.Metalness,
+			RoughnessFactor: 1,
+		},
+	}
+	if node.Material == nil {
+		gm.Name = node.MaterialRef
+	}
+	if c := m.Color; c != nil {
+		gm.PBR.BaseColorFactor = []float64{c.R, c.G, c.B, 1}
+		if c.A > 0 {
+			gm.PBR.BaseColorFactor[3] = c.A
+		}
+	}
+	if m.Roughness > 0 {
+		gm.PBR.RoughnessFactor = m.Roughness
+	}
+	if m.Transparent && m.Opacity > 0 {
+		gm.PBR.BaseColorFactor[3] *= m.Opacity
+	}
+	if gm.PBR.BaseColorFactor[3] < 1 {
+		gm.AlphaMode = "BLEND"
+	}
+	if c := m.Emissive; c != nil {
+		gm.EmissiveFactor = []float64{c.R, c.G, c.B}
+	}
+
+	i := len(e.doc.Materials)
+	e.doc.Materials = append(e.doc.Materials, gm)
+	e.materials[key] = i
+	return &i
+}
+
+func (e *gltfEncoder) accessor(a gltfAccessor) int {
+	e.doc.Accessors = append(e.doc.Accessors, a)
+	return len(e.doc.Accessors) - 1
+}
+
+// vertexView adds a buffer view of tightly packed float32 triples
+func (e *gltfEncoder) vertexView(data []byte, count int) int {
+	var encoded []byte
+	if e.opts.MeshCompression {
+		encoded = encodeMeshoptVertices(data, meshVertexStride)
+	}
+	return e.view(data, encoded, meshVertexStride, count, "ATTRIBUTES", gltfArrayBuffer)
+}
+
+// indexView adds a buffer view of uint32 triangle indices. Compressed
+// indices use the INDICES mode: the TRIANGLES mode is a different codec.
+func (e *gltfEncoder) indexView(indices []uint32) int {
+	data := make([]byte, 4*len(indices))
+	for i, v := range indices {
+		binary.LittleEndian.PutUint32(data[4*i:], v)
+	}
+	var encoded []byte
+	if e.opts.MeshCompression {
+		encoded = encodeMeshoptIndices(indices)
+	}
+	return e.view(data, encoded, 4, len(indices), "INDICES", gltfIndexBuffer)
+}
+
+// view adds a buffer view. Uncompressed data is stored in the binary chunk;
+// compressed views store the encoded data there instead and point into the
+// fallback buffer for the decoded layout.
+func (e *gltfEncoder) view(data, encoded []byte, stride, count int, mode string, target int) int {
+	v := gltfBufferView{ByteLength: len(data), Target: target}
+	if target == gltfArrayBuffer {
+		v.ByteStride = stride
+	}
+	if encoded == nil {
+		v.ByteOffset = e.append(data)
+	} else {
+		v.Buffer = 1
+		v.ByteOffset = e.fallback
+		e.fallback += align4(len(data))
+		v.Extensions = map[string]interface{}{meshoptExtension: gltfMeshopt{
+			ByteOffset: e.append(encoded),
+			ByteLength: len(encoded),
+			ByteStride: stride,
+			Count:      count,
+			Mode:       mode,
+		}}
+	}
+	e.doc.BufferViews = append(e.doc.BufferViews, v)
+	return len(e.doc.BufferViews) - 1
+}
+
+// append adds data to the binary chunk at a 4-byte aligned offset, which it
+// returns
+func (e *gltfEncoder) append(data []byte) int {
+	offset := align4(len(e.bin))
+	e.bin = append(e.bin, make([]byte, offset-len(e.bin))...)
+	e.bin = append(e.bin, data...)
+	return offset
+}
+
+// write writes the GLB container: a header, the JSON chunk padded with
+// spaces and the binary chunk padded with zeros
+func (e *gltfEncoder) write(w io.Writer) error {
+	doc, err := json.Marshal(e.doc)
+	if err != nil {
+		return err
+	}
+	doc = append(doc, bytes.Repeat([]byte{' '}, align4(len(doc))-len(doc))...)
+	bin := append(e.bin, make([]byte, align4(len(e.bin))-len(e.bin))...)
+
+	length := 12 + 8 + len(doc)
+	if len(bin) > 0 {
+		length += 8 + len(bin)
+	}
+	var buf bytes.Buffer
+	buf.Grow(length)
+	for _, v := range []uint32{glbMagic, glbVersion, uint32(length), uint32(len(doc)), glbChunkJSON} {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.Write(doc)
+	if len(bin) > 0 {
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(len(bin)))
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(glbChunkBIN))
+		buf.Write(bin)
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// eulerQuaternion converts Euler angles applied in XYZ order, as in
+// rotationMatrix, to a glTF quaternion (x, y, z, w)
+func eulerQuaternion(r Euler3) [4]float64 {
+	c1, s1 := math.Cos(r.X/2), math.Sin(r.X/2)
+	c2, s2 := math.Cos(r.Y/2), math.Sin(r.Y/2)
+	c3, s3 := math.Cos(r.Z/2), math.Sin(r.Z/2)
+	return [4]float64{
+		s1*c2*c3 + c1*s2*s3,
+		c1*s2*c3 - s1*c2*s3,
+		c1*c2*s3 + s1*s2*c3,
+		c1*c2*c3 - s1*s2*s3,
+	}
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
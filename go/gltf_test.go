@@ -0,0 +1,148 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// readGLB splits a GLB file into its document and binary chunk
+func readGLB(t *testing.T, data []byte) (gltfDocument, []byte) {
+	t.Helper()
+	if len(data) < 20 || binary.LittleEndian.Uint32(data) != glbMagic || int(binary.LittleEndian.Uint32(data[8:])) != len(data) {
+		t.Fatalf("invalid GLB header: % x", data[:min(len(data), 20)])
+	}
+	n := int(binary.LittleEndian.Uint32(data[12:]))
+	var doc gltfDocument
+	if err := json.Unmarshal(data[20:20+n], &doc); err != nil {
+		t.Fatalf("invalid JSON chunk: %v", err)
+	}
+	var bin []byte
+	if rest := data[20+n:]; len(rest) > 0 {
+		bin = rest[8 : 8+binary.LittleEndian.Uint32(rest)]
+	}
+	return doc, bin
+}
+
+// readAccessor returns the bytes of an accessor, decoding compressed views
+// with the codec their mode declares
+func readAccessor(t *testing.T, doc gltfDocument, bin []byte, index int) []byte {
+	t.Helper()
+	a := doc.Accessors[index]
+	v := doc.BufferViews[a.BufferView]
+	ext, ok := v.Extensions[meshoptExtension].(map[string]interface{})
+	if !ok {
+		return bin[v.ByteOffset : v.ByteOffset+v.ByteLength]
+	}
+	offset, length := int(ext["byteOffset"].(float64)), int(ext["byteLength"].(float64))
+	data := bin[offset : offset+length]
+	count := int(ext["count"].(float64))
+	switch ext["mode"] {
+	case "INDICES":
+		indices, err := decodeMeshoptIndices(data, count)
+		if err != nil {
+			t.Fatalf("index view %d: %v", a.BufferView, err)
+		}
+		out := make([]byte, 4*len(indices))
+		for i, v := range indices {
+			binary.LittleEndian.PutUint32(out[4*i:], v)
+		}
+		return out
+	case "ATTRIBUTES":
+		out, err := decodeMeshoptVertices(data, count, int(ext["byteStride"].(float64)))
+		if err != nil {
+			t.Fatalf("vertex view %d: %v", a.BufferView, err)
+		}
+		return out
+	}
+	t.Fatalf("view %d has unsupported mode %v", a.BufferView, ext["mode"])
+	return nil
+}
+
+// TestEncodeGLB tests nodes, shared meshes and materials of a glTF export,
+// with and without meshopt compression
+func TestEncodeGLB(t *testing.T) {
+	sf := NewSceneFile("Plant")
+	grid := newGridMesh(16)
+	sf.Geometries = map[string]Geometry{"panel": {Type: GeometryCustom, Mesh: &grid}}
+	sf.Materials = map[string]Material{"steel": {Color: &Color{R: 0.5, G: 0.5, B: 0.6}, Metalness: 0.9, Roughness: 0.3}}
+	sf.AddNode(SceneNode{ID: "p1", Type: "panel", Name: "Panel 1", Transform: NewTransform(), GeometryRef: "panel", MaterialRef: "steel"})
+	sf.AddNode(SceneNode{ID: "p2", Type: "panel", Name: "Panel 2", Transform: NewTransform(), GeometryRef: "panel", MaterialRef: "steel"})
+	sf.AddNode(SceneNode{ID: "box", Type: "server", Name: "Box", Transform: NewTransform(),
+		Geometry: &Geometry{Type: GeometryBox}, Material: &Material{Color: &Color{R: 1, A: 1}, Opacity: 0.5, Transparent: true}})
+	sf.Scene.Nodes[1].Transform.Position = Vector3{X: 5}
+	sf.Scene.Nodes[2].Transform.Rotation = Euler3{Y: math.Pi / 2}
+
+	sizes := map[bool]int{}
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := EncodeGLB(&buf, &sf, GLTFOptions{MeshCompression: compress}); err != nil {
+			t.Fatalf("EncodeGLB failed: %v", err)
+		}
+		sizes[compress] = buf.Len()
+		doc, bin := readGLB(t, buf.Bytes())
+
+		if len(doc.Nodes) != 3 || len(doc.Scenes[0].Nodes) != 3 {
+			t.Fatalf("node count mismatch: got %d", len(doc.Nodes))
+		}
+		if len(doc.Meshes) != 2 || len(doc.Materials) != 2 {
+			t.Errorf("expected shared mesh and material: got %d meshes, %d materials", len(doc.Meshes), len(doc.Materials))
+		}
+		p1, p2, box := doc.Nodes[0], doc.Nodes[1], doc.Nodes[2]
+		if p1.Extras["id"] != "p1" || *p1.Mesh != *p2.Mesh || p2.Translation[0] != 5 {
+			t.Errorf("panel nodes mismatch: got %+v, %+v", p1, p2)
+		}
+		if math.Abs(box.Rotation[1]-math.Sqrt2/2) > 1e-12 || math.Abs(box.Rotation[3]-math.Sqrt2/2) > 1e-12 {
+			t.Errorf("box rotation mismatch: got %v", box.Rotation)
+		}
+		if m := doc.Materials[*doc.Meshes[*box.Mesh].Primitives[0].Material]; m.AlphaMode != "BLEND" || m.PBR.BaseColorFactor[3] != 0.5 {
+			t.Errorf("box material mismatch: got %+v", m)
+		}
+
+		primitive := doc.Meshes[*p1.Mesh].Primitives[0]
+		positions := float64Values(readAccessor(t, doc, bin, primitive.Attributes["POSITION"]))
+		for i := range positions {
+			if positions[i] != float64(float32(grid.Positions[i])) {
+				t.Fatalf("position %d mismatch: got %v, want %v", i, positions[i], grid.Positions[i])
+			}
+		}
+		indices := readAccessor(t, doc, bin, *primitive.Indices)
+		for i, want := range grid.Indices {
+			if got := binary.LittleEndian.Uint32(indices[4*i:]); got != want {
+				t.Fatalf("index %d mismatch: got %d, want %d", i, got, want)
+			}
+		}
+
+		if required := len(doc.ExtensionsRequired) == 1 && doc.ExtensionsRequired[0] == meshoptExtension; required != compress {
+			t.Errorf("extensionsRequired mismatch: got %v", doc.ExtensionsRequired)
+		}
+		if compress && (len(doc.Buffers) != 2 || doc.Buffers[1].Extensions[meshoptExtension] == nil) {
+			t.Errorf("expected a fallback buffer: got %+v", doc.Buffers)
+		}
+	}
+	if sizes[true] >= sizes[false]/2 {
+		t.Errorf("expected compressed export under half the size: got %d, plain %d", sizes[true], sizes[false])
+	}
+}
+
+// TestEulerQuaternion tests that quaternions rotate like rotationMatrix
+func TestEulerQuaternion(t *testing.T) {
+	e := Euler3{X: 0.3, Y: -1.1, Z: 2.4}
+	q := eulerQuaternion(e)
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	got := [3][3]float64{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+	want := rotationMatrix(e)
+	for i := range got {
+		for j := range got[i] {
+			if math.Abs(got[i][j]-want[i][j]) > 1e-12 {
+				t.Fatalf("matrix mismatch at %d,%d: got %v, want %v", i, j, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// =============================================================================
+// SAVED QUERIES AND SMART GROUPS
+// =============================================================================
+
+// SavedQueriesExtension is the scene extension key holding saved queries
+const SavedQueriesExtension = "savedQueries"
+
+// ErrQueryNotFound is returned for saved queries a scene does not have
+var ErrQueryNotFound = errors.New("query not found")
+
+// SavedQuery is a named selector expression stored with a scene. Its
+// matches form a smart group whose membership follows the scene.
+type SavedQuery struct {
+	Name        string `json:"name" validate:"required"`
+	Query       string `json:"query"`
+	Description string `json:"description,omitempty"`
+}
+
+// SmartGroup is the membership of a saved query in one revision of a scene
+type SmartGroup struct {
+	Name  string   `json:"name" validate:"required"`
+	Query string   `json:"query"`
+	Nodes []string `json:"nodes"`
+	Edges []string `json:"edges"`
+}
+
+// MembershipChange lists the elements that joined or left a smart group.
+// A group that was added or removed lists all its members.
+type MembershipChange struct {
+	Group        string   `json:"group" validate:"required"`
+	AddedNodes   []string `json:"addedNodes,omitempty"`
+	RemovedNodes []string `json:"removedNodes,omitempty"`
+	AddedEdges   []string `json:"addedEdges,omitempty"`
+	RemovedEdges []string `json:"removedEdges,omitempty"`
+}
+
+// SavedQueries returns the scene's saved queries ordered by name
+func (sf *SceneFile) SavedQueries() ([]SavedQuery, error) {
+	v, ok := sf.Extensions[SavedQueriesExtension]
+	if !ok {
+		return nil, nil
+	}
+	var queries []SavedQuery
+	if err := roundTripJSON(v, &queries); err != nil {
+		return nil, fmt.Errorf("saved queries: %w", err)
+	}
+	sort.Slice(queries, func(a, b int) bool { return queries[a].Name < queries[b].Name })
+	return queries, nil
+}
+
+// SaveQuery stores a query with the scene, replacing one with the same
+// name. The expression must parse with ParseSelector.
+func (sf *SceneFile) SaveQuery(q SavedQuery) error {
+	if q.Name == "" {
+		return errors.New("save query: name is required")
+	}
+	if _, err := ParseSelector(q.Query); err != nil {
+		return fmt.Errorf("save query %s: %w", q.Name, err)
+	}
+	queries, err := sf.SavedQueries()
+	if err != nil {
+		return err
+	}
+	queries = slices.DeleteFunc(queries, func(existing SavedQuery) bool { return existing.Name == q.Name })
+	queries = append(queries, q)
+	sort.Slice(queries, func(a, b int) bool { return queries[a].Name < queries[b].Name })
+	sf.Extensions = withExtension(sf.Extensions, SavedQueriesExtension, queries)
+	return nil
+}
+
+// DeleteQuery removes a saved query from the scene
+func (sf *SceneFile) DeleteQuery(name string) error {
+	queries, err := sf.SavedQueries()
+	if err != nil {
+		return err
+	}
+	n := len(queries)
+	queries = slices.DeleteFunc(queries, func(q SavedQuery) bool { return q.Name == name })
+	if len(queries) == n {
+		return fmt.Errorf("%w: %s", ErrQueryNotFound, name)
+	}
+	if len(queries) == 0 {
+		delete(sf.Extensions, SavedQueriesExtension)
+		return nil
+	}
+	sf.Extensions = withExtension(sf.Extensions, SavedQueriesExtension, queries)
+	return nil
+}
+
+// Select returns the IDs of the nodes and edges matching a selector, in
+// scene order
+func (sf *SceneFile) Select(sel Selector) (nodes, edges []string) {
+	nodes, edges = []string{}, []string{}
+	for i := range sf.Scene.Nodes {
+		if sel.MatchNode(&sf.Scene.Nodes[i]) {
+			nodes = append(nodes, sf.Scene.Nodes[i].ID)
+		}
+	}
+	for i := range sf.Scene.Edges {
+		if sel.MatchEdge(&sf.Scene.Edges[i]) {
+			edges = append(edges, sf.Scene.Edges[i].ID)
+		}
+	}
+	return nodes, edges
+}
+
+// SmartGroup evaluates a saved query of the scene
+func (sf *SceneFile) SmartGroup(name string) (SmartGroup, error) {
+	queries, err := sf.SavedQueries()
+	if err != nil {
+		return SmartGroup{}, err
+	}
+	for _, q := range queries {
+		if q.Name == name {
+			return sf.evaluateQuery(q)
+		}
+	}
+	return SmartGroup{}, fmt.Errorf("%w: %s", ErrQueryNotFound, name)
+}
+
+// SmartGroups evaluates every saved query of the scene, ordered by name
+func (sf *SceneFile) SmartGroups() ([]SmartGroup, error) {
+	queries, err := sf.SavedQueries()
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]SmartGroup, len(queries))
+	for i, q := range queries {
+		if groups[i], err = sf.evaluateQuery(q); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// evaluateQuery materializes a saved query as a smart group
+func (sf *SceneFile) evaluateQuery(q SavedQuery) (SmartGroup, error) {
+	sel, err := ParseSelector(q.Query)
+	if err != nil {
+		return SmartGroup{}, fmt.Errorf("query %s: %w", q.Name, err)
+	}
+	g := SmartGroup{Name: q.Name, Query: q.Query}
+	g.Nodes, g.Edges = sf.Select(sel)
+	return g, nil
+}
+
+// DiffSmartGroups returns the membership changes from one evaluation of a
+// scene's groups to the next, ordered by group name. Groups whose
+// membership is unchanged are left out.
+func DiffSmartGroups(before, after []SmartGroup) []MembershipChange {
+	prev := make(map[string]SmartGroup, len(before))
+	for _, g := range before {
+		prev[g.Name] = g
+	}
+	next := make(map[string]SmartGroup, len(after))
+	for _, g := range after {
+		next[g.Name] = g
+	}
+	names := make([]string, 0, len(prev)+len(next))
+	for name := range prev {
+		names = append(names, name)
+	}
+	for name := range next {
+		if _, ok := prev[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []MembershipChange
+	for _, name := range names {
+		a, b := prev[name], next[name]
+		c := MembershipChange{
+			Group:        name,
+			AddedNodes:   missingFrom(b.Nodes, a.Nodes),
+			RemovedNodes: missingFrom(a.Nodes, b.Nodes),
+			AddedEdges:   missingFrom(b.Edges, a.Edges),
+			RemovedEdges: missingFrom(a.Edges, b.Edges),
+		}
+		if len(c.AddedNodes)+len(c.RemovedNodes)+len(c.AddedEdges)+len(c.RemovedEdges) > 0 {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// missingFrom returns the IDs in ids that are not in other, in order
+func missingFrom(ids, other []string) []string {
+	set := make(map[string]bool, len(other))
+	for _, id := range other {
+		set[id] = true
+	}
+	var out []string
+	for _, id := range ids {
+		if !set[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// ValidateSavedQueries checks that saved queries have unique names and
+// expressions that parse
+func ValidateSavedQueries(sf *SceneFile) []string {
+	queries, err := sf.SavedQueries()
+	if err != nil {
+		return []string{fmt.Sprintf("Scene has invalid saved queries: %v", err)}
+	}
+	var errs []string
+	names := make(map[string]bool, len(queries))
+	for _, q := range queries {
+		switch {
+		case q.Name == "":
+			errs = append(errs, "All saved queries must have a name")
+		case names[q.Name]:
+			errs = append(errs, fmt.Sprintf("Duplicate saved query: %s", q.Name))
+		}
+		names[q.Name] = true
+		if _, err := ParseSelector(q.Query); err != nil {
+			errs = append(errs, fmt.Sprintf("Saved query %s is invalid: %v", q.Name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,96 @@
+package starfleet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestSmartGroups tests saving queries and evaluating them as groups
+func TestSmartGroups(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[2].Type = "db"
+	if err := sf.SaveQuery(SavedQuery{Name: "servers", Query: "kind=node type=server"}); err != nil {
+		t.Fatalf("SaveQuery failed: %v", err)
+	}
+	if err := sf.SaveQuery(SavedQuery{Name: "all", Query: ""}); err != nil {
+		t.Fatalf("SaveQuery failed: %v", err)
+	}
+	if err := sf.SaveQuery(SavedQuery{Name: "bad", Query: "color=red"}); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("expected ErrInvalidSelector, got %v", err)
+	}
+
+	// Queries survive a JSON round trip of the scene
+	var decoded SceneFile
+	if err := roundTripJSON(sf, &decoded); err != nil {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	groups, err := decoded.SmartGroups()
+	if err != nil {
+		t.Fatalf("SmartGroups failed: %v", err)
+	}
+	want := []SmartGroup{
+		{Name: "all", Query: "", Nodes: []string{"a", "b", "c"}, Edges: []string{"a-b"}},
+		{Name: "servers", Query: "kind=node type=server", Nodes: []string{"a", "b"}, Edges: []string{}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups mismatch: got %+v, want %+v", groups, want)
+	}
+	if _, err := sf.SmartGroup("missing"); !errors.Is(err, ErrQueryNotFound) {
+		t.Errorf("expected ErrQueryNotFound, got %v", err)
+	}
+
+	// Replacing a query keeps one entry under its name
+	sf.SaveQuery(SavedQuery{Name: "servers", Query: "type=db"})
+	if g, _ := sf.SmartGroup("servers"); !reflect.DeepEqual(g.Nodes, []string{"c"}) {
+		t.Errorf("replaced group mismatch: got %+v", g)
+	}
+	if queries, _ := sf.SavedQueries(); len(queries) != 2 {
+		t.Errorf("queries mismatch: got %+v", queries)
+	}
+	if err := sf.DeleteQuery("all"); err != nil {
+		t.Errorf("DeleteQuery failed: %v", err)
+	}
+	if err := sf.DeleteQuery("all"); !errors.Is(err, ErrQueryNotFound) {
+		t.Errorf("expected ErrQueryNotFound, got %v", err)
+	}
+}
+
+// TestDiffSmartGroups tests membership changes between evaluations
+func TestDiffSmartGroups(t *testing.T) {
+	before := []SmartGroup{
+		{Name: "servers", Nodes: []string{"a", "b"}},
+		{Name: "gone", Nodes: []string{"c"}},
+		{Name: "same", Nodes: []string{"a"}},
+	}
+	after := []SmartGroup{
+		{Name: "servers", Nodes: []string{"b", "d"}, Edges: []string{"b-d"}},
+		{Name: "new", Nodes: []string{"a"}},
+		{Name: "same", Nodes: []string{"a"}},
+	}
+	want := []MembershipChange{
+		{Group: "gone", RemovedNodes: []string{"c"}},
+		{Group: "new", AddedNodes: []string{"a"}},
+		{Group: "servers", AddedNodes: []string{"d"}, RemovedNodes: []string{"a"}, AddedEdges: []string{"b-d"}},
+	}
+	if got := DiffSmartGroups(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("changes mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+// TestValidateSavedQueries tests rejecting malformed saved queries
+func TestValidateSavedQueries(t *testing.T) {
+	sf := newDiffScene()
+	sf.Extensions = map[string]interface{}{SavedQueriesExtension: []SavedQuery{
+		{Name: "a", Query: "type=server"},
+		{Name: "a", Query: "type=db"},
+		{Query: "tag=prod"},
+		{Name: "b", Query: "bogus"},
+	}}
+	if errs := ValidateSavedQueries(&sf); len(errs) != 3 {
+		t.Errorf("errors mismatch: got %v", errs)
+	}
+	if result := ValidateScene(&sf); result.Valid {
+		t.Error("expected ValidateScene to check saved queries")
+	}
+}
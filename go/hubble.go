@@ -0,0 +1,378 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// HUBBLE FLOWS
+// =============================================================================
+
+// HubbleDiscoveredExtension is the extension key marking nodes created by
+// HubbleImporter for workloads seen in flows but missing from the scene
+const HubbleDiscoveredExtension = "hubbleDiscovered"
+
+// HubbleFlow is the subset of a Cilium Hubble flow the importer reads. Field
+// names follow the JSON encoding produced by `hubble observe -o json` and the
+// Hubble exporter.
+type HubbleFlow struct {
+	Time             time.Time       `json:"time"`
+	Verdict          string          `json:"verdict"`
+	DropReasonDesc   string          `json:"drop_reason_desc,omitempty"`
+	IP               *HubbleIP       `json:"IP,omitempty"`
+	L4               *HubbleL4       `json:"l4,omitempty"`
+	L7               *HubbleL7       `json:"l7,omitempty"`
+	Source           *HubbleEndpoint `json:"source,omitempty"`
+	Destination      *HubbleEndpoint `json:"destination,omitempty"`
+	DestinationNames []string        `json:"destination_names,omitempty"`
+	IsReply          bool            `json:"is_reply,omitempty"`
+}
+
+// HubbleIP holds the addresses of a flow
+type HubbleIP struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// HubbleEndpoint identifies one side of a flow
+type HubbleEndpoint struct {
+	Identity  uint32           `json:"identity,omitempty"`
+	Namespace string           `json:"namespace,omitempty"`
+	PodName   string           `json:"pod_name,omitempty"`
+	Labels    []string         `json:"labels,omitempty"`
+	Workloads []HubbleWorkload `json:"workloads,omitempty"`
+}
+
+// HubbleWorkload is the controller owning an endpoint's pod
+type HubbleWorkload struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// HubbleL4 holds the transport layer of a flow; at most one field is set
+type HubbleL4 struct {
+	TCP  *HubblePorts `json:"TCP,omitempty"`
+	UDP  *HubblePorts `json:"UDP,omitempty"`
+	SCTP *HubblePorts `json:"SCTP,omitempty"`
+}
+
+// HubblePorts holds the ports of a transport flow
+type HubblePorts struct {
+	SourcePort      uint32 `json:"source_port"`
+	DestinationPort uint32 `json:"destination_port"`
+}
+
+// HubbleL7 holds the application layer of a flow
+type HubbleL7 struct {
+	Type      string      `json:"type,omitempty"`
+	LatencyNs uint64      `json:"latency_ns,omitempty"`
+	HTTP      *HubbleHTTP `json:"http,omitempty"`
+	DNS       *HubbleDNS  `json:"dns,omitempty"`
+}
+
+// HubbleHTTP holds the HTTP details of an L7 flow
+type HubbleHTTP struct {
+	Code     uint32 `json:"code,omitempty"`
+	Method   string `json:"method,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// HubbleDNS holds the DNS details of an L7 flow
+type HubbleDNS struct {
+	Query string `json:"query,omitempty"`
+}
+
+// transport returns the flow's transport protocol and destination port
+func (f *HubbleFlow) transport() (string, uint32) {
+	if f.L4 == nil {
+		return "", 0
+	}
+	switch {
+	case f.L4.TCP != nil:
+		return "tcp", f.L4.TCP.DestinationPort
+	case f.L4.UDP != nil:
+		return "udp", f.L4.UDP.DestinationPort
+	case f.L4.SCTP != nil:
+		return "sctp", f.L4.SCTP.DestinationPort
+	}
+	return "", 0
+}
+
+// application returns the flow's L7 protocol, if any
+func (f *HubbleFlow) application() string {
+	switch {
+	case f.L7 == nil:
+		return ""
+	case f.L7.HTTP != nil:
+		return "http"
+	case f.L7.DNS != nil:
+		return "dns"
+	}
+	return ""
+}
+
+// HubbleFlowSource streams flows. It mirrors the Recv method of a Hubble
+// GetFlows client, so a gRPC stream can be adapted with a few lines. Recv
+// returns io.EOF when the stream ends.
+type HubbleFlowSource interface {
+	Recv() (*HubbleFlow, error)
+}
+
+// HubbleJSONSource reads flows from newline-delimited JSON. Each line may be
+// a bare flow or a GetFlows response wrapping one under "flow"; lines
+// without a flow, such as node status events, are skipped.
+type HubbleJSONSource struct {
+	dec *json.Decoder
+}
+
+// NewHubbleJSONSource creates a flow source reading JSON from r
+func NewHubbleJSONSource(r io.Reader) *HubbleJSONSource {
+	return &HubbleJSONSource{dec: json.NewDecoder(r)}
+}
+
+// Recv decodes the next flow
+func (s *HubbleJSONSource) Recv() (*HubbleFlow, error) {
+	for {
+		var raw struct {
+			Flow *HubbleFlow `json:"flow"`
+			HubbleFlow
+		}
+		if err := s.dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if raw.Flow != nil {
+			return raw.Flow, nil
+		}
+		if raw.Source != nil || raw.Destination != nil || raw.IP != nil {
+			return &raw.HubbleFlow, nil
+		}
+	}
+}
+
+// HubbleImporter turns Hubble flows into connection edges between workload
+// nodes. Each distinct source, destination, protocol and port gets its own
+// directed edge, keyed like "tcp/8080", whose metadata records the L4 and L7
+// protocol and the last verdict and whose metrics count flows.
+type HubbleImporter struct {
+	// NodeID maps a flow endpoint to a node ID; ip is the endpoint address.
+	// An empty result drops the flow. Defaults to HubbleNodeID.
+	NodeID func(ep *HubbleEndpoint, ip string) string
+	// EdgeType is the type of created edges; empty means "connection"
+	EdgeType string
+	// SkipDropped ignores flows whose verdict is DROPPED or ERROR
+	SkipDropped bool
+}
+
+// HubbleNodeID names an endpoint "namespace/workload", falling back to the
+// pod name, then to "world" or another reserved identity label, then to ip
+func HubbleNodeID(ep *HubbleEndpoint, ip string) string {
+	if ep != nil {
+		name := ep.PodName
+		if len(ep.Workloads) > 0 && ep.Workloads[0].Name != "" {
+			name = ep.Workloads[0].Name
+		}
+		if name != "" {
+			if ep.Namespace == "" {
+				return name
+			}
+			return ep.Namespace + "/" + name
+		}
+		for _, l := range ep.Labels {
+			if reserved, ok := strings.CutPrefix(l, "reserved:"); ok {
+				return reserved
+			}
+		}
+	}
+	return ip
+}
+
+// Import builds a new scene from a stream of JSON flows. The "name" config
+// value names the scene. Flows that cannot be mapped are reported as
+// warnings; a malformed stream is an error.
+func (h *HubbleImporter) Import(r io.Reader, config ImporterConfig) (ImportResult, error) {
+	name, _ := config["name"].(string)
+	if name == "" {
+		name = "Hubble Flows"
+	}
+	sf := NewSceneFile(name)
+	result := ImportResult{Scene: sf}
+
+	src := NewHubbleJSONSource(r)
+	for {
+		flow, err := src.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("hubble import: %w", err)
+		}
+		if report := h.Apply(&result.Scene, *flow); report.Skipped > 0 && !flow.IsReply {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("skipped %s flow at %s", flow.Verdict, flow.Time.Format(time.RFC3339)))
+		}
+	}
+	return result, nil
+}
+
+// Follow applies flows from src to the scene until the stream ends or ctx
+// is done, calling onUpdate after each flow that changed the scene. The
+// scene is modified in place and must not be used concurrently except from
+// onUpdate. A cleanly ended stream returns nil.
+func (h *HubbleImporter) Follow(ctx context.Context, src HubbleFlowSource, sf *SceneFile, onUpdate func(TrafficReport)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		flow, err := src.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("hubble follow: %w", err)
+		}
+		report := h.Apply(sf, *flow)
+		if onUpdate != nil && len(report.AddedEdges)+len(report.UpdatedEdges) > 0 {
+			onUpdate(report)
+		}
+	}
+}
+
+// Apply folds flows into the scene in place. Reply flows are skipped so
+// edges point from the initiating workload. Workloads missing from the
+// scene are added as nodes marked with HubbleDiscoveredExtension.
+func (h *HubbleImporter) Apply(sf *SceneFile, flows ...HubbleFlow) TrafficReport {
+	report := TrafficReport{AddedEdges: []string{}, UpdatedEdges: []string{}, Placeholders: []string{}}
+	nodeID := h.NodeID
+	if nodeID == nil {
+		nodeID = HubbleNodeID
+	}
+	edgeType := h.EdgeType
+	if edgeType == "" {
+		edgeType = "connection"
+	}
+
+	for i := range flows {
+		f := &flows[i]
+		dropped := f.Verdict == "DROPPED" || f.Verdict == "ERROR"
+		var srcIP, dstIP string
+		if f.IP != nil {
+			srcIP, dstIP = f.IP.Source, f.IP.Destination
+		}
+		source, target := nodeID(f.Source, srcIP), nodeID(f.Destination, dstIP)
+		if f.IsReply || (dropped && h.SkipDropped) || source == "" || target == "" || source == target {
+			report.Skipped++
+			continue
+		}
+		for _, ep := range []struct {
+			id string
+			ep *HubbleEndpoint
+		}{{source, f.Source}, {target, f.Destination}} {
+			if sf.FindNode(ep.id) == nil {
+				sf.AddNode(hubbleNode(ep.id, ep.ep))
+				report.Placeholders = append(report.Placeholders, ep.id)
+			}
+		}
+
+		proto, port := f.transport()
+		key := proto
+		if port > 0 {
+			key += "/" + strconv.FormatUint(uint64(port), 10)
+		}
+		e := hubbleEdge(sf, source, target, key)
+		if e == nil {
+			id := source + "-" + target
+			if key != "" {
+				id += "-" + key
+			}
+			sf.AddEdge(SceneEdge{ID: id, Source: source, Target: target, Type: edgeType, Direction: EdgeDirected, Key: key})
+			e = &sf.Scene.Edges[len(sf.Scene.Edges)-1]
+			report.AddedEdges = append(report.AddedEdges, id)
+		} else {
+			report.UpdatedEdges = append(report.UpdatedEdges, e.ID)
+		}
+		f.record(e, proto, port, dropped)
+	}
+	return report
+}
+
+// record writes a flow's protocol details and counters onto its edge
+func (f *HubbleFlow) record(e *SceneEdge, proto string, port uint32, dropped bool) {
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]interface{})
+	}
+	if e.Metrics == nil {
+		e.Metrics = make(map[string]interface{})
+	}
+	if proto != "" {
+		e.Metadata["protocol"] = proto
+	}
+	if port > 0 {
+		e.Metadata["port"] = port
+	}
+	if l7 := f.application(); l7 != "" {
+		e.Metadata["l7"] = l7
+	}
+	if f.Verdict != "" {
+		e.Metadata["verdict"] = f.Verdict
+	}
+	if !f.Time.IsZero() {
+		e.Metadata["lastSeen"] = f.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	count := func(name string) {
+		v, _ := toFloat64(e.Metrics[name])
+		e.Metrics[name] = v + 1
+	}
+	count("flows")
+	if dropped {
+		count("dropped")
+	}
+	if f.L7 != nil && f.L7.HTTP != nil && f.L7.HTTP.Code >= 500 {
+		count("errors")
+	}
+	if f.L7 != nil && f.L7.LatencyNs > 0 {
+		e.Metrics["latency_ms"] = float64(f.L7.LatencyNs) / 1e6
+	}
+}
+
+// hubbleEdge finds the edge for a connection
+func hubbleEdge(sf *SceneFile, source, target, key string) *SceneEdge {
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		if e.Source == source && e.Target == target && e.Key == key && e.TargetScene == "" {
+			return e
+		}
+	}
+	return nil
+}
+
+// hubbleNode creates a node for a workload first seen in a flow
+func hubbleNode(id string, ep *HubbleEndpoint) SceneNode {
+	n := SceneNode{
+		ID:         id,
+		Type:       "external",
+		Name:       id,
+		Transform:  NewTransform(),
+		Status:     NodeStatusUnknown,
+		Extensions: map[string]interface{}{HubbleDiscoveredExtension: true},
+	}
+	if ep == nil || ep.Namespace == "" {
+		return n
+	}
+	n.Type = "workload"
+	n.Metadata = map[string]interface{}{"namespace": ep.Namespace}
+	if len(ep.Workloads) > 0 {
+		n.Name = ep.Workloads[0].Name
+		n.Metadata["kind"] = ep.Workloads[0].Kind
+	} else if ep.PodName != "" {
+		n.Name = ep.PodName
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package starfleet
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const hubbleFlows = `{"flow":{"time":"2024-01-01T12:00:00Z","verdict":"FORWARDED","IP":{"source":"10.0.0.5","destination":"10.0.1.7"},"l4":{"TCP":{"source_port":40000,"destination_port":8080}},"source":{"namespace":"shop","pod_name":"web-7d9f-abc","workloads":[{"name":"web","kind":"Deployment"}]},"destination":{"namespace":"shop","pod_name":"api-0","workloads":[{"name":"api","kind":"StatefulSet"}]}},"node_name":"kind-worker"}
+{"node_status":{"state_change":"NODE_CONNECTED"}}
+{"time":"2024-01-01T12:00:01Z","verdict":"FORWARDED","IP":{"source":"10.0.1.7","destination":"10.0.0.5"},"l4":{"TCP":{"source_port":8080,"destination_port":40000}},"source":{"namespace":"shop","workloads":[{"name":"api"}]},"destination":{"namespace":"shop","workloads":[{"name":"web"}]},"is_reply":true}
+{"time":"2024-01-01T12:00:02Z","verdict":"FORWARDED","type":"L7","IP":{"source":"10.0.0.5","destination":"10.0.1.7"},"l4":{"TCP":{"destination_port":8080}},"l7":{"type":"RESPONSE","latency_ns":2500000,"http":{"code":503,"method":"GET","url":"/cart"}},"source":{"namespace":"shop","workloads":[{"name":"web"}]},"destination":{"namespace":"shop","workloads":[{"name":"api"}]}}
+{"time":"2024-01-01T12:00:03Z","verdict":"DROPPED","IP":{"source":"10.0.1.7","destination":"1.1.1.1"},"l4":{"UDP":{"destination_port":53}},"source":{"namespace":"shop","workloads":[{"name":"api"}]},"destination":{"labels":["reserved:world"]}}
+`
+
+// TestHubbleImporter_Import tests building connection edges from flows
+func TestHubbleImporter_Import(t *testing.T) {
+	h := &HubbleImporter{}
+	result, err := h.Import(strings.NewReader(hubbleFlows), ImporterConfig{"name": "Shop"})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	sf := result.Scene
+	if sf.Metadata.Name != "Shop" || len(sf.Scene.Nodes) != 3 || len(sf.Scene.Edges) != 2 {
+		t.Fatalf("scene mismatch: got %d nodes, %d edges", len(sf.Scene.Nodes), len(sf.Scene.Edges))
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", result.Warnings)
+	}
+
+	web := sf.FindNode("shop/web")
+	if web == nil || web.Type != "workload" || web.Name != "web" || web.Metadata["kind"] != "Deployment" || web.Extensions[HubbleDiscoveredExtension] != true {
+		t.Errorf("workload node mismatch: got %+v", web)
+	}
+	if world := sf.FindNode("world"); world == nil || world.Type != "external" {
+		t.Errorf("world node mismatch: got %+v", world)
+	}
+
+	e := sf.FindEdge("shop/web-shop/api-tcp/8080")
+	if e == nil {
+		t.Fatalf("missing connection edge: got %+v", sf.Scene.Edges)
+	}
+	if e.Key != "tcp/8080" || e.Metadata["l7"] != "http" || e.Metadata["port"] != uint32(8080) {
+		t.Errorf("edge metadata mismatch: got %+v", e.Metadata)
+	}
+	if e.Metrics["flows"] != 2.0 || e.Metrics["errors"] != 1.0 || e.Metrics["latency_ms"] != 2.5 {
+		t.Errorf("edge metrics mismatch: got %+v", e.Metrics)
+	}
+	if dns := sf.FindEdge("shop/api-world-udp/53"); dns == nil || dns.Metadata["verdict"] != "DROPPED" || dns.Metrics["dropped"] != 1.0 {
+		t.Errorf("dropped edge mismatch: got %+v", dns)
+	}
+	if v := ValidateScene(&sf); !v.Valid {
+		t.Errorf("imported scene invalid: %+v", v.Errors)
+	}
+}
+
+// TestHubbleImporter_Follow tests streaming updates into an existing scene
+func TestHubbleImporter_Follow(t *testing.T) {
+	sf := NewSceneFile("Live")
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "web", Type: "server", Name: "Web", Transform: NewTransform()})
+	h := &HubbleImporter{
+		SkipDropped: true,
+		NodeID: func(ep *HubbleEndpoint, ip string) string {
+			if ep != nil && len(ep.Workloads) > 0 {
+				return ep.Workloads[0].Name
+			}
+			return ""
+		},
+	}
+
+	var updates []TrafficReport
+	err := h.Follow(context.Background(), NewHubbleJSONSource(strings.NewReader(hubbleFlows)), &sf, func(r TrafficReport) {
+		updates = append(updates, r)
+	})
+	if err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+	if len(updates) != 2 || !reflect.DeepEqual(updates[0].AddedEdges, []string{"web-api-tcp/8080"}) || !reflect.DeepEqual(updates[1].UpdatedEdges, []string{"web-api-tcp/8080"}) {
+		t.Errorf("updates mismatch: got %+v", updates)
+	}
+	if len(sf.Scene.Nodes) != 2 || len(sf.Scene.Edges) != 1 {
+		t.Errorf("scene mismatch: got %d nodes, %d edges", len(sf.Scene.Nodes), len(sf.Scene.Edges))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := h.Follow(ctx, NewHubbleJSONSource(strings.NewReader(hubbleFlows)), &sf, nil); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
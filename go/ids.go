@@ -0,0 +1,130 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// =============================================================================
+// ID NAMESPACING AND REMAPPING
+// =============================================================================
+
+// ErrDuplicateID is returned when an operation would produce duplicate IDs
+var ErrDuplicateID = errors.New("duplicate id")
+
+// IDMapping represents old-to-new ID replacements for nodes and edges. IDs
+// missing from a map are left unchanged.
+type IDMapping struct {
+	Nodes map[string]string `json:"nodes,omitempty"`
+	Edges map[string]string `json:"edges,omitempty"`
+}
+
+// NamespaceIDs prefixes every node and edge ID in the scene, rewriting all
+// references consistently. It is used to merge scenes owned by different
+// teams without collisions.
+func NamespaceIDs(sf *SceneFile, prefix string) error {
+	mapping := IDMapping{
+		Nodes: make(map[string]string, len(sf.Scene.Nodes)),
+		Edges: make(map[string]string, len(sf.Scene.Edges)),
+	}
+	for i := range sf.Scene.Nodes {
+		mapping.Nodes[sf.Scene.Nodes[i].ID] = prefix + sf.Scene.Nodes[i].ID
+	}
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		mapping.Edges[edge.ID] = prefix + edge.ID
+		for _, id := range toStringSlice(edge.Extensions[AggregatedEdgesExtension]) {
+			mapping.Edges[id] = prefix + id
+		}
+	}
+	return RemapIDs(sf, mapping)
+}
+
+// RemapIDs replaces node and edge IDs according to the mapping and rewrites
+// every reference to them: edge endpoints, parent and children links, and
+// the aggregated edge lists of merged edges. Targets of cross-scene edges
+// name nodes of another scene and are kept. The scene is left unchanged
+// when the result would contain duplicate IDs.
+func RemapIDs(sf *SceneFile, mapping IDMapping) error {
+	if err := checkRemapUnique(nodeIDs(sf), mapping.Nodes, "node"); err != nil {
+		return err
+	}
+	if err := checkRemapUnique(edgeIDs(sf), mapping.Edges, "edge"); err != nil {
+		return err
+	}
+	node := func(id string) string { return remapID(mapping.Nodes, id) }
+	edge := func(id string) string { return remapID(mapping.Edges, id) }
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		n.ID = node(n.ID)
+		n.Parent = node(n.Parent)
+		if n.Children != nil {
+			children := make([]string, len(n.Children))
+			for j, c := range n.Children {
+				children[j] = node(c)
+			}
+			n.Children = children
+		}
+	}
+
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		e.ID = edge(e.ID)
+		e.Source = node(e.Source)
+		if !e.IsExternal() {
+			e.Target = node(e.Target)
+		}
+		if ids := toStringSlice(e.Extensions[AggregatedEdgesExtension]); len(ids) > 0 {
+			remapped := make([]string, len(ids))
+			for j, id := range ids {
+				remapped[j] = edge(id)
+			}
+			e.Extensions = copyMap(e.Extensions)
+			e.Extensions[AggregatedEdgesExtension] = remapped
+		}
+	}
+	return nil
+}
+
+// remapID returns the mapped ID, or the original when unmapped or empty
+func remapID(mapping map[string]string, id string) string {
+	if mapped, ok := mapping[id]; ok && id != "" {
+		return mapped
+	}
+	return id
+}
+
+// checkRemapUnique verifies that remapping keeps IDs unique
+func checkRemapUnique(ids []string, mapping map[string]string, kind string) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		mapped := remapID(mapping, id)
+		if seen[mapped] {
+			return fmt.Errorf("remap %s ids: %w: %s", kind, ErrDuplicateID, mapped)
+		}
+		seen[mapped] = true
+	}
+	return nil
+}
+
+// nodeIDs returns the IDs of all nodes in scene order
+func nodeIDs(sf *SceneFile) []string {
+	ids := make([]string, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		ids[i] = sf.Scene.Nodes[i].ID
+	}
+	return ids
+}
+
+// edgeIDs returns the IDs of all edges in scene order
+func edgeIDs(sf *SceneFile) []string {
+	ids := make([]string, len(sf.Scene.Edges))
+	for i := range sf.Scene.Edges {
+		ids[i] = sf.Scene.Edges[i].ID
+	}
+	return ids
+}
@@ -0,0 +1,68 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// newIDScene creates a small hierarchy with an aggregated edge
+func newIDScene() SceneFile {
+	sf := NewSceneFile("IDs")
+	sf.AddNode(SceneNode{ID: "group", Transform: NewTransform(), Children: []string{"a", "b"}})
+	sf.AddNode(SceneNode{ID: "a", Parent: "group", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "b", Parent: "group", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{
+		ID: "ab", Source: "a", Target: "b",
+		Extensions: map[string]interface{}{AggregatedEdgesExtension: []string{"ab", "ab-2"}},
+	})
+	return sf
+}
+
+// TestNamespaceIDs tests prefixing every ID and reference
+func TestNamespaceIDs(t *testing.T) {
+	sf := newIDScene()
+	sf.AddEdge(SceneEdge{ID: "a-ledger", Source: "a", Target: "b", TargetScene: "scenes/pay.json"})
+	if err := NamespaceIDs(&sf, "team-a/"); err != nil {
+		t.Fatalf("NamespaceIDs failed: %v", err)
+	}
+
+	group := sf.FindNode("team-a/group")
+	if group == nil || !equalStrings(group.Children, []string{"team-a/a", "team-a/b"}) {
+		t.Fatalf("Namespaced group mismatch: %+v", group)
+	}
+	if sf.FindNode("team-a/a").Parent != "team-a/group" {
+		t.Errorf("Namespaced parent mismatch")
+	}
+	edge := sf.FindEdge("team-a/ab")
+	if edge == nil || edge.Source != "team-a/a" || edge.Target != "team-a/b" {
+		t.Fatalf("Namespaced edge mismatch: %+v", edge)
+	}
+	if ids := toStringSlice(edge.Extensions[AggregatedEdgesExtension]); !equalStrings(ids, []string{"team-a/ab", "team-a/ab-2"}) {
+		t.Errorf("Aggregated edge ids mismatch: got %v", ids)
+	}
+	if external := sf.FindEdge("team-a/a-ledger"); external == nil || external.Source != "team-a/a" || external.Target != "b" {
+		t.Errorf("Cross-scene edge mismatch: got %+v", external)
+	}
+	if result := ValidateScene(&sf); !result.Valid {
+		t.Errorf("Namespaced scene invalid: %v", result.Errors)
+	}
+}
+
+// TestRemapIDs tests partial remapping and duplicate detection
+func TestRemapIDs(t *testing.T) {
+	sf := newIDScene()
+	if err := RemapIDs(&sf, IDMapping{Nodes: map[string]string{"a": "api"}}); err != nil {
+		t.Fatalf("RemapIDs failed: %v", err)
+	}
+	if sf.FindNode("api") == nil || sf.FindEdge("ab").Source != "api" || sf.FindNode("group").Children[0] != "api" {
+		t.Errorf("RemapIDs did not rewrite references")
+	}
+
+	err := RemapIDs(&sf, IDMapping{Nodes: map[string]string{"api": "b"}})
+	if !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("RemapIDs error mismatch: got %v, want ErrDuplicateID", err)
+	}
+	if sf.FindNode("api") == nil {
+		t.Errorf("Failed remap should leave the scene unchanged")
+	}
+}
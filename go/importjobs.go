@@ -0,0 +1,395 @@
+package starfleet
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// IMPORTERS
+// =============================================================================
+
+// ImportProgress reports how far an import has come. Importers that cannot
+// tell the total leave it zero.
+type ImportProgress struct {
+	Stage      string `json:"stage,omitempty"`
+	Discovered int    `json:"discovered"`
+	Built      int    `json:"built"`
+	Total      int    `json:"total,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ProgressFunc receives progress updates from a running import
+type ProgressFunc func(ImportProgress)
+
+// Importer builds a scene from an external system. Imports of large cloud
+// accounts take minutes, so importers report progress as they go and stop
+// when ctx is canceled.
+type Importer interface {
+	Import(ctx context.Context, config ImporterConfig, progress ProgressFunc) (ImportResult, error)
+}
+
+// ImporterFunc adapts a function to the Importer interface
+type ImporterFunc func(ctx context.Context, config ImporterConfig, progress ProgressFunc) (ImportResult, error)
+
+// Import calls f
+func (f ImporterFunc) Import(ctx context.Context, config ImporterConfig, progress ProgressFunc) (ImportResult, error) {
+	return f(ctx, config, progress)
+}
+
+// =============================================================================
+// IMPORT JOBS
+// =============================================================================
+
+// Defaults of ImportQueue
+const (
+	DefaultImportWorkers   = 2
+	DefaultImportQueueSize = 64
+	DefaultImportRetention = time.Hour
+)
+
+// Sentinel errors returned by ImportQueue
+var (
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrImportQueueFull   = errors.New("import queue full")
+	ErrImportQueueClosed = errors.New("import queue closed")
+)
+
+// ImportJobState is the lifecycle state of an import job
+type ImportJobState string
+
+const (
+	ImportJobQueued    ImportJobState = "queued"
+	ImportJobRunning   ImportJobState = "running"
+	ImportJobSucceeded ImportJobState = "succeeded"
+	ImportJobFailed    ImportJobState = "failed"
+	ImportJobCanceled  ImportJobState = "canceled"
+)
+
+// Done reports whether the state is final
+func (s ImportJobState) Done() bool {
+	return s == ImportJobSucceeded || s == ImportJobFailed || s == ImportJobCanceled
+}
+
+// ImportJob is a snapshot of an asynchronous import. Result is set once the
+// job has succeeded.
+type ImportJob struct {
+	ID        string         `json:"id" validate:"required"`
+	Importer  string         `json:"importer,omitempty"`
+	State     ImportJobState `json:"state" validate:"required"`
+	Progress  ImportProgress `json:"progress"`
+	Error     string         `json:"error,omitempty"`
+	Result    *ImportResult  `json:"result,omitempty"`
+	Submitted time.Time      `json:"submitted"`
+	Started   *time.Time     `json:"started,omitempty"`
+	Finished  *time.Time     `json:"finished,omitempty"`
+}
+
+// ImportQueue runs imports in the background on a fixed pool of workers so
+// callers such as HTTP handlers return immediately and poll or watch the
+// job instead. Finished jobs are kept for Retention.
+type ImportQueue struct {
+	// Workers bounds concurrent imports; it is read when the first job is
+	// submitted
+	Workers   int
+	QueueSize int
+	Retention time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*importJob
+	queue   chan *importJob
+	closed  bool
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// importJob is the mutable state behind an ImportJob
+type importJob struct {
+	job      ImportJob
+	importer Importer
+	config   ImporterConfig
+	cancel   context.CancelFunc
+	watchers map[chan ImportJob]struct{}
+	// done is closed when the job finishes
+	done chan struct{}
+}
+
+// NewImportQueue creates a queue running up to workers imports at once
+func NewImportQueue(workers int) *ImportQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ImportQueue{
+		Workers:   workers,
+		QueueSize: DefaultImportQueueSize,
+		Retention: DefaultImportRetention,
+		jobs:      make(map[string]*importJob),
+		ctx:       ctx,
+		cancel:    cancel,
+		now:       time.Now,
+	}
+}
+
+// Submit queues an import and returns the queued job. name identifies the
+// importer in job listings.
+func (q *ImportQueue) Submit(name string, importer Importer, config ImporterConfig) (ImportJob, error) {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	j := &importJob{
+		job: ImportJob{
+			ID:        hex.EncodeToString(id[:]),
+			Importer:  name,
+			State:     ImportJobQueued,
+			Submitted: q.now().UTC(),
+		},
+		importer: importer,
+		config:   config,
+		watchers: make(map[chan ImportJob]struct{}),
+		done:     make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ImportJob{}, ErrImportQueueClosed
+	}
+	if q.queue == nil {
+		q.queue = make(chan *importJob, max(q.QueueSize, 1))
+		for i := 0; i < max(q.Workers, 1); i++ {
+			q.workers.Add(1)
+			go q.work()
+		}
+	}
+	select {
+	case q.queue <- j:
+	default:
+		return ImportJob{}, ErrImportQueueFull
+	}
+	q.expire()
+	q.jobs[j.job.ID] = j
+	return j.job, nil
+}
+
+// Job returns the current state of a job
+func (q *ImportQueue) Job(id string) (ImportJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire()
+	j, ok := q.jobs[id]
+	if !ok {
+		return ImportJob{}, fmt.Errorf("%w: %s", ErrImportJobNotFound, id)
+	}
+	return j.job, nil
+}
+
+// Jobs returns all retained jobs, oldest first
+func (q *ImportQueue) Jobs() []ImportJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire()
+	jobs := make([]ImportJob, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		jobs = append(jobs, j.job)
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		if !jobs[a].Submitted.Equal(jobs[b].Submitted) {
+			return jobs[a].Submitted.Before(jobs[b].Submitted)
+		}
+		return jobs[a].ID < jobs[b].ID
+	})
+	return jobs
+}
+
+// Watch streams the state of a job, starting with the current one. Slow
+// readers skip intermediate progress but always see the final state; the
+// channel closes after it or when ctx is done.
+func (q *ImportQueue) Watch(ctx context.Context, id string) (<-chan ImportJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire()
+	j, ok := q.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrImportJobNotFound, id)
+	}
+	ch := make(chan ImportJob, 1)
+	ch <- j.job
+	if j.job.State.Done() {
+		close(ch)
+		return ch, nil
+	}
+	j.watchers[ch] = struct{}{}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-j.done:
+			// finish has closed the channel
+			return
+		}
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if _, ok := j.watchers[ch]; ok {
+			delete(j.watchers, ch)
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+// Wait blocks until a job finishes and returns its final state
+func (q *ImportQueue) Wait(ctx context.Context, id string) (ImportJob, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch, err := q.Watch(ctx, id)
+	if err != nil {
+		return ImportJob{}, err
+	}
+	var job ImportJob
+	for job = range ch {
+	}
+	if !job.State.Done() {
+		return job, ctx.Err()
+	}
+	return job, nil
+}
+
+// Cancel stops a job. Queued jobs never start; running jobs have their
+// context canceled and finish once the importer returns. Canceling a
+// finished job has no effect.
+func (q *ImportQueue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrImportJobNotFound, id)
+	}
+	switch j.job.State {
+	case ImportJobQueued:
+		j.job.Error = context.Canceled.Error()
+		q.finish(j, ImportJobCanceled)
+	case ImportJobRunning:
+		j.cancel()
+	}
+	return nil
+}
+
+// Close stops accepting jobs and waits for queued and running imports.
+// When ctx expires first, the remaining imports are canceled.
+func (q *ImportQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		if q.queue != nil {
+			close(q.queue)
+		}
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *ImportQueue) work() {
+	defer q.workers.Done()
+	for j := range q.queue {
+		q.run(j)
+	}
+}
+
+// run executes one job unless it was canceled while queued
+func (q *ImportQueue) run(j *importJob) {
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+	q.mu.Lock()
+	if j.job.State != ImportJobQueued {
+		q.mu.Unlock()
+		return
+	}
+	started := q.now().UTC()
+	j.job.State = ImportJobRunning
+	j.job.Started = &started
+	j.cancel = cancel
+	q.notify(j)
+	q.mu.Unlock()
+
+	result, err := j.importer.Import(ctx, j.config, func(p ImportProgress) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if !j.job.State.Done() {
+			j.job.Progress = p
+			q.notify(j)
+		}
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case err == nil:
+		j.job.Result = &result
+		q.finish(j, ImportJobSucceeded)
+	case ctx.Err() != nil:
+		j.job.Error = err.Error()
+		q.finish(j, ImportJobCanceled)
+	default:
+		j.job.Error = err.Error()
+		q.finish(j, ImportJobFailed)
+	}
+}
+
+// finish moves a job to a final state and closes its watchers. Callers hold
+// q.mu.
+func (q *ImportQueue) finish(j *importJob, state ImportJobState) {
+	finished := q.now().UTC()
+	j.job.State = state
+	j.job.Finished = &finished
+	q.notify(j)
+	for ch := range j.watchers {
+		delete(j.watchers, ch)
+		close(ch)
+	}
+	close(j.done)
+}
+
+// notify replaces any unread update of each watcher with the current
+// state. Callers hold q.mu.
+func (q *ImportQueue) notify(j *importJob) {
+	for ch := range j.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- j.job
+	}
+}
+
+// expire forgets jobs that finished more than Retention ago. Callers hold
+// q.mu.
+func (q *ImportQueue) expire() {
+	if q.Retention <= 0 {
+		return
+	}
+	cutoff := q.now().Add(-q.Retention)
+	for id, j := range q.jobs {
+		if j.job.Finished != nil && j.job.Finished.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestImportQueue tests progress, results and failures of background imports
+func TestImportQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewImportQueue(1)
+	release := make(chan struct{})
+	importer := ImporterFunc(func(ctx context.Context, config ImporterConfig, progress ProgressFunc) (ImportResult, error) {
+		progress(ImportProgress{Stage: "discover", Discovered: 3})
+		<-release
+		progress(ImportProgress{Stage: "build", Discovered: 3, Built: 3})
+		return ImportResult{Scene: newDiffScene(), Warnings: []string{config["account"].(string)}}, nil
+	})
+
+	job, err := q.Submit("aws", importer, ImporterConfig{"account": "prod"})
+	if err != nil || job.State != ImportJobQueued || job.Importer != "aws" {
+		t.Fatalf("Submit mismatch: got %+v, %v", job, err)
+	}
+	updates, err := q.Watch(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	for update := range updates {
+		if update.Progress.Discovered == 3 {
+			break
+		}
+	}
+	if got, _ := q.Job(job.ID); got.State != ImportJobRunning || got.Started == nil {
+		t.Errorf("running job mismatch: got %+v", got)
+	}
+	close(release)
+
+	done, err := q.Wait(ctx, job.ID)
+	if err != nil || done.State != ImportJobSucceeded || done.Progress.Built != 3 || done.Finished == nil {
+		t.Fatalf("Wait mismatch: got %+v, %v", done, err)
+	}
+	if done.Result == nil || len(done.Result.Scene.Scene.Nodes) != 3 || done.Result.Warnings[0] != "prod" {
+		t.Errorf("result mismatch: got %+v", done.Result)
+	}
+
+	failing := ImporterFunc(func(context.Context, ImporterConfig, ProgressFunc) (ImportResult, error) {
+		return ImportResult{}, errors.New("access denied")
+	})
+	job, _ = q.Submit("gcp", failing, nil)
+	if done, _ := q.Wait(ctx, job.ID); done.State != ImportJobFailed || done.Error != "access denied" {
+		t.Errorf("failed job mismatch: got %+v", done)
+	}
+	if jobs := q.Jobs(); len(jobs) != 2 || jobs[0].Importer != "aws" {
+		t.Errorf("jobs mismatch: got %+v", jobs)
+	}
+	if _, err := q.Job("missing"); !errors.Is(err, ErrImportJobNotFound) {
+		t.Errorf("expected ErrImportJobNotFound, got %v", err)
+	}
+
+	if err := q.Close(ctx); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := q.Submit("aws", importer, nil); !errors.Is(err, ErrImportQueueClosed) {
+		t.Errorf("expected ErrImportQueueClosed, got %v", err)
+	}
+}
+
+// TestImportQueue_Cancel tests canceling running and queued jobs
+func TestImportQueue_Cancel(t *testing.T) {
+	ctx := context.Background()
+	q := NewImportQueue(1)
+	started := make(chan struct{})
+	blocking := ImporterFunc(func(ctx context.Context, _ ImporterConfig, _ ProgressFunc) (ImportResult, error) {
+		close(started)
+		<-ctx.Done()
+		return ImportResult{}, ctx.Err()
+	})
+	running, _ := q.Submit("slow", blocking, nil)
+	<-started
+	queued, _ := q.Submit("slow", blocking, nil)
+
+	if err := q.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if got, _ := q.Job(queued.ID); got.State != ImportJobCanceled || got.Started != nil {
+		t.Errorf("queued job mismatch: got %+v", got)
+	}
+	q.Cancel(running.ID)
+	if got, _ := q.Wait(ctx, running.ID); got.State != ImportJobCanceled {
+		t.Errorf("running job mismatch: got %+v", got)
+	}
+	q.Close(ctx)
+}
+
+// TestImportQueue_Retention tests that finished jobs are forgotten
+func TestImportQueue_Retention(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewImportQueue(1)
+	q.now = func() time.Time { return at }
+	noop := ImporterFunc(func(context.Context, ImporterConfig, ProgressFunc) (ImportResult, error) {
+		return ImportResult{}, nil
+	})
+	old, _ := q.Submit("noop", noop, nil)
+	q.Wait(ctx, old.ID)
+	at = at.Add(2 * DefaultImportRetention)
+	if _, err := q.Job(old.ID); !errors.Is(err, ErrImportJobNotFound) {
+		t.Errorf("expected expired job without further submissions, got %v", err)
+	}
+	if _, err := q.Watch(ctx, old.ID); !errors.Is(err, ErrImportJobNotFound) {
+		t.Errorf("expected expired job to be unwatchable, got %v", err)
+	}
+	q.Close(ctx)
+}
+
+// TestImportQueue_WatchFinished tests that watchers with a context that is
+// never canceled are released when their job finishes
+func TestImportQueue_WatchFinished(t *testing.T) {
+	q := NewImportQueue(1)
+	release := make(chan struct{})
+	importer := ImporterFunc(func(context.Context, ImporterConfig, ProgressFunc) (ImportResult, error) {
+		<-release
+		return ImportResult{}, nil
+	})
+	job, _ := q.Submit("slow", importer, nil)
+	before := runtime.NumGoroutine()
+	var watches []<-chan ImportJob
+	for i := 0; i < 50; i++ {
+		ch, err := q.Watch(context.Background(), job.ID)
+		if err != nil {
+			t.Fatalf("Watch failed: %v", err)
+		}
+		watches = append(watches, ch)
+	}
+	close(release)
+	for _, ch := range watches {
+		for range ch {
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("watch goroutines leaked: %d running, %d before watching", n, before)
+	}
+	q.Close(context.Background())
+}
@@ -0,0 +1,362 @@
+package starfleet
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// =============================================================================
+// INDEXED SCENE CONTAINER
+// =============================================================================
+
+// The indexed container stores a scene as separately compressed chunks so
+// a reader can fetch one node or edge without inflating the rest:
+//
+//	"SFIX" | header chunk | node chunks | edge chunks | index |
+//	index offset (uint64) | index length (uint32) | "SFIX"
+//
+// Chunks are DEFLATE-compressed JSON: the header is the scene file without
+// nodes and edges, and node and edge chunks are arrays of up to
+// IndexedSceneOptions.ChunkSize elements. The index, found from the fixed
+// size footer, is compressed JSON listing the offset, length and element
+// IDs of every chunk. Integers are little-endian.
+
+// indexedSceneMagic opens and closes version 1 of the indexed container
+const indexedSceneMagic = "SFIX"
+
+// indexedSceneFooterSize is the size of the index offset, index length and
+// closing magic
+const indexedSceneFooterSize = 8 + 4 + len(indexedSceneMagic)
+
+// IndexedSceneContentType is the media type of indexed scene containers
+const IndexedSceneContentType = "application/vnd.starfleet.scene+indexed"
+
+// DefaultIndexedChunkSize is the number of nodes or edges per chunk when
+// IndexedSceneOptions leaves it unset
+const DefaultIndexedChunkSize = 256
+
+// ErrMalformedIndexedScene is returned for data that is not a valid indexed
+// scene container
+var ErrMalformedIndexedScene = errors.New("malformed indexed scene")
+
+// IndexedSceneOptions controls EncodeIndexedScene
+type IndexedSceneOptions struct {
+	// ChunkSize is the number of nodes or edges compressed together; zero
+	// means DefaultIndexedChunkSize. Smaller chunks make single lookups
+	// cheaper and the file larger.
+	ChunkSize int
+}
+
+// indexedChunk locates one compressed chunk
+type indexedChunk struct {
+	Offset int64    `json:"offset"`
+	Length int64    `json:"length"`
+	IDs    []string `json:"ids,omitempty"`
+}
+
+// indexedSceneIndex is the footer index of an indexed container
+type indexedSceneIndex struct {
+	Header indexedChunk   `json:"header"`
+	Nodes  []indexedChunk `json:"nodes"`
+	Edges  []indexedChunk `json:"edges"`
+}
+
+// countingWriter tracks the offset of the next byte written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// EncodeIndexedScene writes the scene as an indexed container
+func EncodeIndexedScene(w io.Writer, sf *SceneFile, opts IndexedSceneOptions) error {
+	size := opts.ChunkSize
+	if size <= 0 {
+		size = DefaultIndexedChunkSize
+	}
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, indexedSceneMagic); err != nil {
+		return err
+	}
+	chunk := func(v interface{}, ids []string) (indexedChunk, error) {
+		c := indexedChunk{Offset: cw.n, IDs: ids}
+		if err := writeCompressedJSON(cw, v); err != nil {
+			return c, err
+		}
+		c.Length = cw.n - c.Offset
+		return c, nil
+	}
+
+	var index indexedSceneIndex
+	header := *sf
+	header.Scene.Nodes, header.Scene.Edges = []SceneNode{}, []SceneEdge{}
+	var err error
+	if index.Header, err = chunk(&header, nil); err != nil {
+		return err
+	}
+	nodes := sf.Scene.Nodes
+	for start := 0; start < len(nodes); start += size {
+		part := nodes[start:min(start+size, len(nodes))]
+		ids := make([]string, len(part))
+		for i := range part {
+			ids[i] = part[i].ID
+		}
+		c, err := chunk(part, ids)
+		if err != nil {
+			return err
+		}
+		index.Nodes = append(index.Nodes, c)
+	}
+	edges := sf.Scene.Edges
+	for start := 0; start < len(edges); start += size {
+		part := edges[start:min(start+size, len(edges))]
+		ids := make([]string, len(part))
+		for i := range part {
+			ids[i] = part[i].ID
+		}
+		c, err := chunk(part, ids)
+		if err != nil {
+			return err
+		}
+		index.Edges = append(index.Edges, c)
+	}
+
+	at := cw.n
+	if err := writeCompressedJSON(cw, &index); err != nil {
+		return err
+	}
+	footer := make([]byte, 0, indexedSceneFooterSize)
+	footer = binary.LittleEndian.AppendUint64(footer, uint64(at))
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(cw.n-at))
+	footer = append(footer, indexedSceneMagic...)
+	_, err = cw.Write(footer)
+	return err
+}
+
+// writeCompressedJSON writes v as one DEFLATE stream of JSON
+func writeCompressedJSON(w io.Writer, v interface{}) error {
+	zw, err := flate.NewWriter(w, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(zw).Encode(v); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// IndexedScene reads an indexed container in place. Only the index is read
+// when it is opened; Node and Edge then inflate the one chunk holding the
+// element asked for. It is safe for concurrent use when its reader is, as
+// files are.
+type IndexedScene struct {
+	r      io.ReaderAt
+	size   int64
+	closer io.Closer
+	index  indexedSceneIndex
+	nodes  map[string]int
+	edges  map[string]int
+}
+
+// OpenIndexedScene reads the index of the container of the given size in r
+func OpenIndexedScene(r io.ReaderAt, size int64) (*IndexedScene, error) {
+	if size < int64(len(indexedSceneMagic)+indexedSceneFooterSize) {
+		return nil, fmt.Errorf("%w: too short", ErrMalformedIndexedScene)
+	}
+	head := make([]byte, len(indexedSceneMagic))
+	footer := make([]byte, indexedSceneFooterSize)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadAt(footer, size-int64(indexedSceneFooterSize)); err != nil {
+		return nil, err
+	}
+	if string(head) != indexedSceneMagic || string(footer[12:]) != indexedSceneMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrMalformedIndexedScene)
+	}
+
+	s := &IndexedScene{r: r, size: size}
+	at := indexedChunk{
+		Offset: int64(binary.LittleEndian.Uint64(footer)),
+		Length: int64(binary.LittleEndian.Uint32(footer[8:])),
+	}
+	if err := s.read(at, &s.index); err != nil {
+		return nil, fmt.Errorf("index: %w", err)
+	}
+	s.nodes = indexChunkIDs(s.index.Nodes)
+	s.edges = indexChunkIDs(s.index.Edges)
+	return s, nil
+}
+
+// OpenIndexedSceneFile opens an indexed container file; close it when done
+func OpenIndexedSceneFile(name string) (*IndexedScene, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s, err := OpenIndexedScene(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	s.closer = f
+	return s, nil
+}
+
+// indexChunkIDs maps every element ID to the chunk holding it
+func indexChunkIDs(chunks []indexedChunk) map[string]int {
+	ids := make(map[string]int)
+	for i, c := range chunks {
+		for _, id := range c.IDs {
+			ids[id] = i
+		}
+	}
+	return ids
+}
+
+// Close closes the file of a container opened with OpenIndexedSceneFile
+func (s *IndexedScene) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// read inflates and decodes one chunk
+func (s *IndexedScene) read(c indexedChunk, v interface{}) error {
+	if c.Offset < int64(len(indexedSceneMagic)) || c.Length <= 0 || c.Offset+c.Length > s.size-int64(indexedSceneFooterSize) {
+		return fmt.Errorf("%w: chunk out of range", ErrMalformedIndexedScene)
+	}
+	zr := flate.NewReader(io.NewSectionReader(s.r, c.Offset, c.Length))
+	defer zr.Close()
+	if err := json.NewDecoder(zr).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedIndexedScene, err)
+	}
+	return nil
+}
+
+// NodeCount returns the number of nodes in the container
+func (s *IndexedScene) NodeCount() int { return len(s.nodes) }
+
+// EdgeCount returns the number of edges in the container
+func (s *IndexedScene) EdgeCount() int { return len(s.edges) }
+
+// Header returns the scene file without its nodes and edges
+func (s *IndexedScene) Header() (SceneFile, error) {
+	var sf SceneFile
+	err := s.read(s.index.Header, &sf)
+	return sf, err
+}
+
+// Node returns one node, or ErrNodeNotFound
+func (s *IndexedScene) Node(id string) (SceneNode, error) {
+	i, ok := s.nodes[id]
+	if !ok {
+		return SceneNode{}, fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+	}
+	var nodes []SceneNode
+	if err := s.read(s.index.Nodes[i], &nodes); err != nil {
+		return SceneNode{}, err
+	}
+	for _, n := range nodes {
+		if n.ID == id {
+			return n, nil
+		}
+	}
+	return SceneNode{}, fmt.Errorf("%w: node %s missing from its chunk", ErrMalformedIndexedScene, id)
+}
+
+// Edge returns one edge, or ErrEdgeNotFound
+func (s *IndexedScene) Edge(id string) (SceneEdge, error) {
+	i, ok := s.edges[id]
+	if !ok {
+		return SceneEdge{}, fmt.Errorf("%w: %s", ErrEdgeNotFound, id)
+	}
+	var edges []SceneEdge
+	if err := s.read(s.index.Edges[i], &edges); err != nil {
+		return SceneEdge{}, err
+	}
+	for _, e := range edges {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return SceneEdge{}, fmt.Errorf("%w: edge %s missing from its chunk", ErrMalformedIndexedScene, id)
+}
+
+// SceneFile reads the whole scene
+func (s *IndexedScene) SceneFile() (SceneFile, error) {
+	sf, err := s.Header()
+	if err != nil {
+		return SceneFile{}, err
+	}
+	sf.Scene.Nodes = make([]SceneNode, 0, len(s.nodes))
+	for _, c := range s.index.Nodes {
+		var nodes []SceneNode
+		if err := s.read(c, &nodes); err != nil {
+			return SceneFile{}, err
+		}
+		sf.Scene.Nodes = append(sf.Scene.Nodes, nodes...)
+	}
+	sf.Scene.Edges = make([]SceneEdge, 0, len(s.edges))
+	for _, c := range s.index.Edges {
+		var edges []SceneEdge
+		if err := s.read(c, &edges); err != nil {
+			return SceneFile{}, err
+		}
+		sf.Scene.Edges = append(sf.Scene.Edges, edges...)
+	}
+	return sf, nil
+}
+
+// decodeIndexedScene reads a whole container held in memory
+func decodeIndexedScene(data []byte) (SceneFile, error) {
+	s, err := OpenIndexedScene(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return SceneFile{}, err
+	}
+	return s.SceneFile()
+}
+
+// SceneArchive holds cold scenes as indexed containers, for serving single
+// elements without loading the scene into a store
+type SceneArchive interface {
+	// OpenScene opens the container of a scene, or returns
+	// ErrSceneNotFound. The caller closes it.
+	OpenScene(ctx context.Context, id string) (*IndexedScene, error)
+}
+
+// DirSceneArchive keeps indexed containers in Root as <id>.sfi
+type DirSceneArchive struct {
+	Root string
+}
+
+// OpenScene implements SceneArchive
+func (a DirSceneArchive) OpenScene(ctx context.Context, id string) (*IndexedScene, error) {
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return nil, fmt.Errorf("%w: %s", ErrSceneNotFound, id)
+	}
+	s, err := OpenIndexedSceneFile(filepath.Join(a.Root, id+".sfi"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSceneNotFound, id)
+	}
+	return s, err
+}
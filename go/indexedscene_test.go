@@ -0,0 +1,112 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// countingReaderAt counts the bytes read through it
+type countingReaderAt struct {
+	r *bytes.Reader
+	n int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += n
+	return n, err
+}
+
+// TestIndexedScene tests reading single elements and whole scenes from an
+// indexed container
+func TestIndexedScene(t *testing.T) {
+	sf := newLargeScene(1000)
+	var buf bytes.Buffer
+	if err := EncodeIndexedScene(&buf, &sf, IndexedSceneOptions{ChunkSize: 64}); err != nil {
+		t.Fatalf("EncodeIndexedScene failed: %v", err)
+	}
+	r := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	s, err := OpenIndexedScene(r, int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenIndexedScene failed: %v", err)
+	}
+	if s.NodeCount() != len(sf.Scene.Nodes) || s.EdgeCount() != len(sf.Scene.Edges) {
+		t.Errorf("count mismatch: got %d nodes and %d edges", s.NodeCount(), s.EdgeCount())
+	}
+
+	r.n = 0
+	want := sf.Scene.Nodes[777]
+	node, err := s.Node(want.ID)
+	if err != nil {
+		t.Fatalf("Node failed: %v", err)
+	}
+	if !reflect.DeepEqual(node, want) {
+		t.Errorf("node mismatch: got %+v, want %+v", node, want)
+	}
+	if r.n*4 > buf.Len() {
+		t.Errorf("read %d of %d bytes for one node", r.n, buf.Len())
+	}
+	if len(sf.Scene.Edges) > 0 {
+		edge, err := s.Edge(sf.Scene.Edges[0].ID)
+		if err != nil || edge.ID != sf.Scene.Edges[0].ID {
+			t.Errorf("edge mismatch: got %+v, %v", edge, err)
+		}
+	}
+	if _, err := s.Node("missing"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrNodeNotFound)
+	}
+	if _, err := s.Edge("missing"); !errors.Is(err, ErrEdgeNotFound) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrEdgeNotFound)
+	}
+
+	whole, err := s.SceneFile()
+	if err != nil {
+		t.Fatalf("SceneFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(whole.Scene.Nodes, sf.Scene.Nodes) || !reflect.DeepEqual(whole.Scene.Edges, sf.Scene.Edges) || whole.Metadata.Name != sf.Metadata.Name {
+		t.Error("scene mismatch after round trip")
+	}
+	opened, err := OpenReader(bytes.NewReader(buf.Bytes()), "", DefaultOpenOptions())
+	if err != nil || len(opened.Scene.Nodes) != len(sf.Scene.Nodes) {
+		t.Errorf("OpenReader mismatch: got %d nodes, %v", len(opened.Scene.Nodes), err)
+	}
+
+	data := buf.Bytes()
+	if _, err := OpenIndexedScene(bytes.NewReader(data[:len(data)-1]), int64(len(data)-1)); !errors.Is(err, ErrMalformedIndexedScene) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrMalformedIndexedScene)
+	}
+}
+
+// TestDirSceneArchive tests opening archived containers by scene ID
+func TestDirSceneArchive(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sf := newDiffScene()
+	var buf bytes.Buffer
+	if err := EncodeIndexedScene(&buf, &sf, IndexedSceneOptions{}); err != nil {
+		t.Fatalf("EncodeIndexedScene failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cold.sfi"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := DirSceneArchive{Root: dir}
+	s, err := archive.OpenScene(ctx, "cold")
+	if err != nil {
+		t.Fatalf("OpenScene failed: %v", err)
+	}
+	defer s.Close()
+	if node, err := s.Node("b"); err != nil || node.ID != "b" {
+		t.Errorf("node mismatch: got %+v, %v", node, err)
+	}
+	for _, id := range []string{"warm", "../cold", ""} {
+		if _, err := archive.OpenScene(ctx, id); !errors.Is(err, ErrSceneNotFound) {
+			t.Errorf("error mismatch for %q: got %v, want %v", id, err, ErrSceneNotFound)
+		}
+	}
+}
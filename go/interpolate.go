@@ -0,0 +1,239 @@
+package starfleet
+
+import (
+	"math"
+	"reflect"
+)
+
+// =============================================================================
+// SNAPSHOT INTERPOLATION
+// =============================================================================
+
+// InterpolateScenes returns the frame at t between two snapshots of the same
+// scene, for smooth time-travel playback: t = 0 gives a and t = 1 gives b,
+// and t is clamped to that range. Nodes and edges are matched by ID.
+//
+// Matched elements have their transforms, colors, opacity, edge widths and
+// numeric metrics tweened; rotations take the shorter way round. Anything
+// that cannot be tweened, such as names, statuses and string metrics, comes
+// from a before the midpoint and from b from it on, as do the scene's other
+// fields. Elements only in b fade in and elements only in a fade out, with
+// inline materials so they need no library entries. Neither input is
+// modified.
+func InterpolateScenes(a, b *SceneFile, t float64) SceneFile {
+	t = math.Max(0, math.Min(1, t))
+	base := a
+	if t >= 0.5 {
+		base = b
+	}
+	out := *base
+
+	aNodes := make(map[string]*SceneNode, len(a.Scene.Nodes))
+	for i := range a.Scene.Nodes {
+		aNodes[a.Scene.Nodes[i].ID] = &a.Scene.Nodes[i]
+	}
+	bNodes := make(map[string]bool, len(b.Scene.Nodes))
+	out.Scene.Nodes = make([]SceneNode, 0, len(b.Scene.Nodes))
+	for i := range b.Scene.Nodes {
+		nb := &b.Scene.Nodes[i]
+		bNodes[nb.ID] = true
+		na, ok := aNodes[nb.ID]
+		switch {
+		case ok:
+			out.Scene.Nodes = append(out.Scene.Nodes, interpolateNode(a, b, na, nb, t))
+		case t > 0:
+			out.Scene.Nodes = append(out.Scene.Nodes, fadeNode(b, nb, t))
+		}
+	}
+	if t < 1 {
+		for i := range a.Scene.Nodes {
+			if na := &a.Scene.Nodes[i]; !bNodes[na.ID] {
+				out.Scene.Nodes = append(out.Scene.Nodes, fadeNode(a, na, 1-t))
+			}
+		}
+	}
+
+	aEdges := make(map[string]*SceneEdge, len(a.Scene.Edges))
+	for i := range a.Scene.Edges {
+		aEdges[a.Scene.Edges[i].ID] = &a.Scene.Edges[i]
+	}
+	bEdges := make(map[string]bool, len(b.Scene.Edges))
+	out.Scene.Edges = make([]SceneEdge, 0, len(b.Scene.Edges))
+	for i := range b.Scene.Edges {
+		eb := &b.Scene.Edges[i]
+		bEdges[eb.ID] = true
+		ea, ok := aEdges[eb.ID]
+		switch {
+		case ok:
+			out.Scene.Edges = append(out.Scene.Edges, interpolateEdge(ea, eb, t))
+		case t > 0:
+			out.Scene.Edges = append(out.Scene.Edges, fadeEdge(eb, t))
+		}
+	}
+	if t < 1 {
+		for i := range a.Scene.Edges {
+			if ea := &a.Scene.Edges[i]; !bEdges[ea.ID] {
+				out.Scene.Edges = append(out.Scene.Edges, fadeEdge(ea, 1-t))
+			}
+		}
+	}
+	return out
+}
+
+// interpolateNode tweens a node matched in both snapshots
+func interpolateNode(a, b *SceneFile, na, nb *SceneNode, t float64) SceneNode {
+	node := *na
+	if t >= 0.5 {
+		node = *nb
+	}
+	node.Transform = interpolateTransform(na.Transform, nb.Transform, t)
+	node.Metrics = interpolateMetrics(na.Metrics, nb.Metrics, t)
+
+	ma, mb := a.ResolveMaterial(na), b.ResolveMaterial(nb)
+	if ma == nil || mb == nil || (na.MaterialRef == nb.MaterialRef && reflect.DeepEqual(ma, mb)) {
+		return node
+	}
+	material := *ma
+	if t >= 0.5 {
+		material = *mb
+	}
+	material.Color = interpolateColor(ma.Color, mb.Color, t)
+	material.Emissive = interpolateColor(ma.Emissive, mb.Emissive, t)
+	material.Opacity = lerp(materialOpacity(ma), materialOpacity(mb), t)
+	material.Transparent = material.Opacity < 1
+	node.Material, node.MaterialRef = &material, ""
+	return node
+}
+
+// fadeNode returns a node present in only one snapshot, scaled to opacity
+// times its own, resolving library references in src
+func fadeNode(src *SceneFile, n *SceneNode, opacity float64) SceneNode {
+	node := *n
+	material := Material{}
+	if m := src.ResolveMaterial(n); m != nil {
+		material = *m
+	}
+	material.Opacity = materialOpacity(&material) * opacity
+	material.Transparent = material.Opacity < 1
+	if node.Geometry == nil {
+		node.Geometry = src.ResolveGeometry(n)
+	}
+	node.Material, node.MaterialRef, node.GeometryRef = &material, "", ""
+	return node
+}
+
+// interpolateEdge tweens an edge matched in both snapshots
+func interpolateEdge(ea, eb *SceneEdge, t float64) SceneEdge {
+	edge := *ea
+	if t >= 0.5 {
+		edge = *eb
+	}
+	edge.Color = interpolateColor(ea.Color, eb.Color, t)
+	if ea.Opacity != 0 || eb.Opacity != 0 {
+		edge.Opacity = lerp(edgeOpacity(ea), edgeOpacity(eb), t)
+	}
+	if ea.Width != 0 && eb.Width != 0 {
+		edge.Width = lerp(ea.Width, eb.Width, t)
+	}
+	edge.Metrics = interpolateMetrics(ea.Metrics, eb.Metrics, t)
+	return edge
+}
+
+// fadeEdge returns an edge present in only one snapshot, scaled to opacity
+// times its own
+func fadeEdge(e *SceneEdge, opacity float64) SceneEdge {
+	edge := *e
+	edge.Opacity = edgeOpacity(e) * opacity
+	return edge
+}
+
+// interpolateTransform tweens position, rotation and scale
+func interpolateTransform(a, b Transform, t float64) Transform {
+	return Transform{
+		Position: a.Position.Lerp(b.Position, t),
+		Rotation: Euler3{
+			X: lerpAngle(a.Rotation.X, b.Rotation.X, t),
+			Y: lerpAngle(a.Rotation.Y, b.Rotation.Y, t),
+			Z: lerpAngle(a.Rotation.Z, b.Rotation.Z, t),
+		},
+		Scale: Scale3{
+			X: lerp(a.Scale.X, b.Scale.X, t),
+			Y: lerp(a.Scale.Y, b.Scale.Y, t),
+			Z: lerp(a.Scale.Z, b.Scale.Z, t),
+		},
+	}
+}
+
+// interpolateColor tweens two optional colors. A color set on one side only
+// switches at the midpoint.
+func interpolateColor(a, b *Color, t float64) *Color {
+	if a == nil || b == nil {
+		if t >= 0.5 {
+			return b
+		}
+		return a
+	}
+	// An unset alpha means opaque
+	ca, cb := *a, *b
+	if ca.A == 0 && cb.A != 0 {
+		ca.A = 1
+	}
+	if cb.A == 0 && ca.A != 0 {
+		cb.A = 1
+	}
+	c := lerpColor(ca, cb, t)
+	return &c
+}
+
+// interpolateMetrics tweens metrics that are numeric in both snapshots and
+// takes the rest from the nearer snapshot
+func interpolateMetrics(a, b map[string]interface{}, t float64) map[string]interface{} {
+	base := a
+	if t >= 0.5 {
+		base = b
+	}
+	if len(base) == 0 {
+		return base
+	}
+	out := make(map[string]interface{}, len(base))
+	for name, v := range base {
+		fa, okA := toFloat64(a[name])
+		fb, okB := toFloat64(b[name])
+		if okA && okB {
+			v = lerp(fa, fb, t)
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// materialOpacity returns the effective opacity of a material; an unset
+// opacity means opaque
+func materialOpacity(m *Material) float64 {
+	if m.Opacity == 0 && !m.Transparent {
+		return 1
+	}
+	return m.Opacity
+}
+
+// edgeOpacity returns the effective opacity of an edge; an unset opacity
+// means opaque
+func edgeOpacity(e *SceneEdge) float64 {
+	if e.Opacity == 0 {
+		return 1
+	}
+	return e.Opacity
+}
+
+// lerp linearly interpolates between two numbers
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// lerpAngle interpolates between two angles in radians the shorter way round
+func lerpAngle(a, b, t float64) float64 {
+	if t >= 1 {
+		return b
+	}
+	return a + math.Remainder(b-a, 2*math.Pi)*t
+}
@@ -0,0 +1,76 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// TestInterpolateScenes tests tweening matched elements and fading the rest
+func TestInterpolateScenes(t *testing.T) {
+	a := newDiffScene()
+	a.Scene.Nodes[0].Transform.Rotation.Y = 170 * math.Pi / 180
+	a.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": 10, "zone": "east"}
+	a.Scene.Nodes[0].Material = &Material{Color: &Color{R: 1}}
+	a.Scene.Edges[0].Color = &Color{R: 1}
+	b := newDiffScene()
+	b.Scene.Nodes[0].Transform.Position.X = 10
+	b.Scene.Nodes[0].Transform.Rotation.Y = -170 * math.Pi / 180
+	b.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": 30.0, "zone": "west"}
+	b.Scene.Nodes[0].Material = &Material{Color: &Color{B: 1}, Opacity: 0.5, Transparent: true}
+	b.Scene.Nodes[0].Name = "A2"
+	b.Scene.Edges[0].Color = &Color{B: 1}
+	b.Scene.Edges[0].Width = 2
+	b.Scene.Nodes = b.Scene.Nodes[:2]
+	b.AddNode(SceneNode{ID: "d", Type: "db", Name: "D", Transform: NewTransform()})
+
+	frame := InterpolateScenes(&a, &b, 0.25)
+	node := frame.FindNode("a")
+	if node.Name != "A" {
+		t.Errorf("name mismatch: got %q, want %q", node.Name, "A")
+	}
+	if node.Transform.Position.X != 2.5 {
+		t.Errorf("position mismatch: got %v, want 2.5", node.Transform.Position.X)
+	}
+	// 170° to -170° goes through 180°, not back through 0°
+	if got, want := node.Transform.Rotation.Y, 175*math.Pi/180; math.Abs(got-want) > 1e-9 {
+		t.Errorf("rotation mismatch: got %v, want %v", got, want)
+	}
+	if node.Metrics["cpu"] != 15.0 || node.Metrics["zone"] != "east" {
+		t.Errorf("metrics mismatch: got %v", node.Metrics)
+	}
+	if c := node.Material.Color; c.R != 0.75 || c.B != 0.25 {
+		t.Errorf("color mismatch: got %+v", c)
+	}
+	if m := node.Material; m.Opacity != 0.875 || !m.Transparent {
+		t.Errorf("opacity mismatch: got %v, %v", m.Opacity, m.Transparent)
+	}
+	if e := frame.FindEdge("a-b"); e.Color.R != 0.75 || e.Width != 0 {
+		t.Errorf("edge mismatch: got %+v, width %v", e.Color, e.Width)
+	}
+	if d := frame.FindNode("d"); d == nil || d.Material.Opacity != 0.25 {
+		t.Errorf("fade in mismatch: got %+v", d)
+	}
+	if c := frame.FindNode("c"); c == nil || c.Material.Opacity != 0.75 {
+		t.Errorf("fade out mismatch: got %+v", c)
+	}
+	if a.Scene.Nodes[0].Transform.Position.X != 0 || a.Scene.Nodes[0].Material.Color.R != 1 {
+		t.Error("input modified")
+	}
+
+	frame = InterpolateScenes(&a, &b, 0.75)
+	if node := frame.FindNode("a"); node.Name != "A2" || node.Metrics["zone"] != "west" {
+		t.Errorf("late frame mismatch: got %q, %v", node.Name, node.Metrics)
+	}
+
+	frame = InterpolateScenes(&a, &b, 2)
+	if len(frame.Scene.Nodes) != 3 || frame.FindNode("c") != nil {
+		t.Errorf("end frame nodes mismatch: got %d", len(frame.Scene.Nodes))
+	}
+	if got := frame.FindNode("a").Transform.Rotation.Y; got != b.Scene.Nodes[0].Transform.Rotation.Y {
+		t.Errorf("end rotation mismatch: got %v", got)
+	}
+	frame = InterpolateScenes(&a, &b, 0)
+	if frame.FindNode("d") != nil || frame.FindNode("c") == nil {
+		t.Error("start frame mismatch")
+	}
+}
@@ -0,0 +1,301 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// JSON PATCH
+// =============================================================================
+
+// JSONPatchContentType is the media type of RFC 6902 JSON patches
+const JSONPatchContentType = "application/json-patch+json"
+
+var (
+	ErrInvalidPatch      = errors.New("invalid patch")
+	ErrPatchTestFailed   = errors.New("patch test failed")
+	ErrInconsistentScene = errors.New("patch leaves scene inconsistent")
+)
+
+// PatchOp is one operation of a JSON patch. Op is one of add, remove,
+// replace, move, copy and test; Path and From are JSON pointers.
+//
+// Besides the pointers of RFC 6902, such as "/scene/nodes/3/name", paths
+// may address nodes and edges by ID: "/nodes/web/name" is the name of the
+// node with ID web wherever it is in the node list, and "/edges/-" appends
+// an edge. IDs are resolved when their operation runs, so a patch can add a
+// node and then change it by ID.
+type PatchOp struct {
+	Op    string      `json:"op" validate:"required,oneof=add remove replace move copy test"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// ApplyPatch applies a JSON patch to a scene and returns the result; sf is
+// not modified. Operations apply in order and the patch applies atomically:
+// when any operation fails, ErrInvalidPatch or ErrPatchTestFailed is
+// returned and nothing changes. A result that fails ValidateScene with
+// errors the scene did not have before, such as an edge to a removed node,
+// is rejected with ErrInconsistentScene.
+func ApplyPatch(sf *SceneFile, ops []PatchOp) (SceneFile, error) {
+	var doc interface{}
+	if err := roundTripJSON(sf, &doc); err != nil {
+		return SceneFile{}, fmt.Errorf("json patch: %w", err)
+	}
+	for i, op := range ops {
+		var err error
+		if doc, err = applyPatchOp(doc, op); err != nil {
+			if errors.Is(err, ErrPatchTestFailed) {
+				return SceneFile{}, fmt.Errorf("%w: op %d (test %s)", err, i, op.Path)
+			}
+			return SceneFile{}, fmt.Errorf("%w: op %d (%s %s): %v", ErrInvalidPatch, i, op.Op, op.Path, err)
+		}
+	}
+	var result SceneFile
+	if err := roundTripJSON(doc, &result); err != nil {
+		return SceneFile{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	before := ValidateScene(sf).Errors
+	var introduced []string
+	for _, msg := range ValidateScene(&result).Errors {
+		if !slices.Contains(before, msg) {
+			introduced = append(introduced, msg)
+		}
+	}
+	if len(introduced) > 0 {
+		return SceneFile{}, fmt.Errorf("%w: %s", ErrInconsistentScene, strings.Join(introduced, "; "))
+	}
+	return result, nil
+}
+
+// roundTripJSON converts v to out through its JSON encoding
+func roundTripJSON(v, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// applyPatchOp applies one operation to a decoded JSON document
+func applyPatchOp(doc interface{}, op PatchOp) (interface{}, error) {
+	path, err := resolvePointer(doc, op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+		// Normalize Go values such as structs and ints to their JSON form
+		if err := roundTripJSON(op.Value, &value); err != nil {
+			return nil, err
+		}
+	}
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, value)
+	case "remove":
+		doc, _, err = pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, _, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, value)
+	case "move", "copy":
+		from, err := resolvePointer(doc, op.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		var moved interface{}
+		if op.Op == "move" {
+			if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+				return nil, fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			if doc, moved, err = pointerRemove(doc, from); err != nil {
+				return nil, fmt.Errorf("from: %w", err)
+			}
+			// Removing an earlier ID-addressed element shifts the target
+			if path, err = resolvePointer(doc, op.Path); err != nil {
+				return nil, err
+			}
+		} else {
+			found, err := pointerGet(doc, from)
+			if err != nil {
+				return nil, fmt.Errorf("from: %w", err)
+			}
+			if err := roundTripJSON(found, &moved); err != nil {
+				return nil, err
+			}
+		}
+		return pointerAdd(doc, path, moved)
+	case "test":
+		found, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(found, value) {
+			return nil, ErrPatchTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// resolvePointer splits a JSON pointer into its unescaped tokens, turning
+// the /nodes/{id} and /edges/{id} shorthands into list indexes
+func resolvePointer(doc interface{}, pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	if kind := tokens[0]; (kind == "nodes" || kind == "edges") && len(tokens) > 1 {
+		id := tokens[1]
+		if id != "-" {
+			list, _ := pointerGet(doc, []string{"scene", kind})
+			elements, _ := list.([]interface{})
+			index := slices.IndexFunc(elements, func(e interface{}) bool {
+				m, _ := e.(map[string]interface{})
+				return m["id"] == id
+			})
+			if index < 0 {
+				return nil, fmt.Errorf("no %s with id %q", strings.TrimSuffix(kind, "s"), id)
+			}
+			id = strconv.Itoa(index)
+		}
+		tokens = append([]string{"scene", kind, id}, tokens[2:]...)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses a token as an index into a list of length n; "-", the
+// position after the last element, is accepted when end is set
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if token == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid index %q", token)
+	}
+	if i > n || (i == n && !end) {
+		return 0, fmt.Errorf("index %d out of range", i)
+	}
+	return i, nil
+}
+
+// pointerGet returns the value at the tokens
+func pointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch c := doc.(type) {
+		case map[string]interface{}:
+			v, ok := c[t]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", t)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = c[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", doc, t)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate replaces the container holding the last token with the
+// result of fn, rebuilding the containers above it
+func pointerUpdate(doc interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	child, err := pointerGet(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := pointerUpdate(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		c[tokens[0]] = updated
+	case []interface{}:
+		i, _ := arrayIndex(tokens[0], len(c), false)
+		c[i] = updated
+	}
+	return doc, nil
+}
+
+// pointerAdd implements the add operation of RFC 6902 section 4.1
+func pointerAdd(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(c, i, value), nil
+		}
+		return nil, fmt.Errorf("cannot add %q to %T", token, parent)
+	})
+}
+
+// pointerRemove implements the remove operation of RFC 6902 section 4.2,
+// also returning the removed value
+func pointerRemove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the document")
+	}
+	var removed interface{}
+	doc, err := pointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			v, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			removed = v
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[i]
+			return slices.Delete(c, i, i+1), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from %T", token, parent)
+	})
+	return doc, removed, err
+}
@@ -0,0 +1,81 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestApplyPatch tests RFC 6902 operations and ID-addressed paths
+func TestApplyPatch(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Tags = []string{"x"}
+	ops := []PatchOp{
+		{Op: "test", Path: "/metadata/name", Value: sf.Metadata.Name},
+		{Op: "replace", Path: "/nodes/b/name", Value: "Bravo"},
+		{Op: "add", Path: "/nodes/c/metrics", Value: map[string]interface{}{"cpu": 10}},
+		{Op: "add", Path: "/nodes/-", Value: SceneNode{ID: "d", Type: "server", Name: "D", Transform: NewTransform()}},
+		{Op: "add", Path: "/edges/-", Value: map[string]interface{}{"id": "c-d", "source": "c", "target": "d"}},
+		{Op: "copy", From: "/nodes/c/metrics", Path: "/nodes/d/metrics"},
+		{Op: "move", From: "/nodes/a/tags", Path: "/nodes/b/tags"},
+		{Op: "remove", Path: "/scene/edges/0"},
+		{Op: "add", Path: "/metadata/description", Value: "a~/b"},
+		{Op: "test", Path: "/metadata/description", Value: "a~/b"},
+	}
+
+	out, err := ApplyPatch(&sf, ops)
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	if b := out.FindNode("b"); b.Name != "Bravo" || len(b.Tags) != 1 || b.Tags[0] != "x" {
+		t.Errorf("node b mismatch: got %+v", b)
+	}
+	if a := out.FindNode("a"); len(a.Tags) != 0 {
+		t.Errorf("moved tags still on a: got %v", a.Tags)
+	}
+	if d := out.FindNode("d"); d == nil || d.Metrics["cpu"] != 10.0 {
+		t.Errorf("node d mismatch: got %+v", d)
+	}
+	if len(out.Scene.Edges) != 1 || out.Scene.Edges[0].ID != "c-d" {
+		t.Errorf("edges mismatch: got %+v", out.Scene.Edges)
+	}
+	if out.Metadata.Description != "a~/b" {
+		t.Errorf("description mismatch: got %q", out.Metadata.Description)
+	}
+	if sf.FindNode("b").Name == "Bravo" || len(sf.Scene.Nodes) != 3 {
+		t.Error("input scene was modified")
+	}
+}
+
+// TestApplyPatch_Errors tests that failing patches change nothing
+func TestApplyPatch_Errors(t *testing.T) {
+	sf := newDiffScene()
+	tests := []struct {
+		name string
+		ops  []PatchOp
+		want error
+	}{
+		{"failed test", []PatchOp{{Op: "test", Path: "/nodes/a/name", Value: "nope"}}, ErrPatchTestFailed},
+		{"unknown id", []PatchOp{{Op: "remove", Path: "/nodes/zz"}}, ErrInvalidPatch},
+		{"missing member", []PatchOp{{Op: "replace", Path: "/metadata/nope", Value: 1}}, ErrInvalidPatch},
+		{"index out of range", []PatchOp{{Op: "add", Path: "/scene/nodes/9", Value: nil}}, ErrInvalidPatch},
+		{"leading zero", []PatchOp{{Op: "remove", Path: "/scene/nodes/01"}}, ErrInvalidPatch},
+		{"unknown op", []PatchOp{{Op: "frob", Path: "/version"}}, ErrInvalidPatch},
+		{"relative pointer", []PatchOp{{Op: "remove", Path: "version"}}, ErrInvalidPatch},
+		{"move into child", []PatchOp{{Op: "move", From: "/metadata", Path: "/metadata/x"}}, ErrInvalidPatch},
+		{"dangling edge", []PatchOp{{Op: "remove", Path: "/nodes/a"}}, ErrInconsistentScene},
+		{"duplicate id", []PatchOp{{Op: "copy", From: "/nodes/a", Path: "/nodes/-"}}, ErrInconsistentScene},
+	}
+	for _, tt := range tests {
+		if _, err := ApplyPatch(&sf, tt.ops); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	ops := []PatchOp{
+		{Op: "remove", Path: "/edges/a-b"},
+		{Op: "remove", Path: "/nodes/a"},
+	}
+	if out, err := ApplyPatch(&sf, ops); err != nil || out.FindNode("a") != nil {
+		t.Errorf("removing the node with its edge should succeed: got %v", err)
+	}
+}
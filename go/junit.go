@@ -0,0 +1,81 @@
+package starfleet
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// =============================================================================
+// JUNIT REPORTS
+// =============================================================================
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// EncodeJUnit writes validation results as JUnit XML for CI dashboards. Each
+// file is a test suite with a test case per diagnostic, named by its
+// location; errors fail their case and warnings pass with the message as
+// output. A file without problems has a single passing "valid" case.
+func EncodeJUnit(w io.Writer, files []FileValidation) error {
+	doc := junitSuites{Name: "starfleet validate", Suites: []junitSuite{}}
+	for _, f := range files {
+		suite := junitSuite{Name: f.Path}
+		for _, d := range f.diagnostics() {
+			c := junitCase{Name: d.Path, ClassName: f.Path}
+			if c.Name == "" {
+				c.Name = "$"
+			}
+			location := d.Location(f.Path)
+			if d.Severity == SeverityWarning {
+				c.SystemOut = fmt.Sprintf("%s: warning: %s", location, d.Message)
+			} else {
+				c.Failure = &junitFailure{Type: string(SeverityError), Message: d.Message, Text: location}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, junitCase{Name: "valid", ClassName: f.Path})
+		}
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,366 @@
+package starfleet
+
+import (
+	"math"
+	"sort"
+)
+
+// =============================================================================
+// LABELS
+// =============================================================================
+
+// LabelAnchor represents where a label sits relative to its element
+type LabelAnchor string
+
+const (
+	LabelAnchorTop         LabelAnchor = "top"
+	LabelAnchorBottom      LabelAnchor = "bottom"
+	LabelAnchorLeft        LabelAnchor = "left"
+	LabelAnchorRight       LabelAnchor = "right"
+	LabelAnchorTopLeft     LabelAnchor = "top-left"
+	LabelAnchorTopRight    LabelAnchor = "top-right"
+	LabelAnchorBottomLeft  LabelAnchor = "bottom-left"
+	LabelAnchorBottomRight LabelAnchor = "bottom-right"
+	LabelAnchorCenter      LabelAnchor = "center"
+)
+
+// Label represents a text annotation attached to a node or edge. Offset is
+// the world-space displacement from the element origin (node position or edge
+// midpoint) to the label center.
+type Label struct {
+	Text     string      `json:"text" validate:"required"`
+	Style    *TextStyle  `json:"style,omitempty"`
+	Anchor   LabelAnchor `json:"anchor,omitempty"`
+	Offset   *Vector3    `json:"offset,omitempty"`
+	Priority int         `json:"priority,omitempty"`
+	Hidden   bool        `json:"hidden,omitempty"`
+}
+
+// LabelPlacementOptions represents options for the label placement pass
+type LabelPlacementOptions struct {
+	// Camera defines the view direction; the scene camera is used when nil
+	Camera *Camera
+	// Margin is the gap between an element and its label in scene units
+	Margin float64
+	// Anchors lists candidate positions in order of preference
+	Anchors []LabelAnchor
+	// HideOverlapping hides labels that cannot be placed without overlap
+	HideOverlapping bool
+	// LabelNodeNames creates labels from node names for unlabeled nodes
+	LabelNodeNames bool
+}
+
+// LabelPlacementResult represents the outcome of a label placement pass
+type LabelPlacementResult struct {
+	Placed      int `json:"placed"`
+	Overlapping int `json:"overlapping"`
+	Hidden      int `json:"hidden"`
+}
+
+// DefaultLabelAnchors is the candidate order used when none is configured
+var DefaultLabelAnchors = []LabelAnchor{
+	LabelAnchorTop,
+	LabelAnchorBottom,
+	LabelAnchorRight,
+	LabelAnchorLeft,
+	LabelAnchorTopRight,
+	LabelAnchorTopLeft,
+	LabelAnchorBottomRight,
+	LabelAnchorBottomLeft,
+}
+
+// rect represents an axis-aligned rectangle in view space
+type rect struct {
+	minX, minY, maxX, maxY float64
+}
+
+// overlapArea returns the area shared by two rectangles
+func (r rect) overlapArea(o rect) float64 {
+	w := math.Min(r.maxX, o.maxX) - math.Max(r.minX, o.minX)
+	h := math.Min(r.maxY, o.maxY) - math.Max(r.minY, o.minY)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
+
+// maxGridSpan is the number of cells a rectangle may cover before a rectGrid
+// keeps it in its list of large rectangles instead
+const maxGridSpan = 64
+
+// rectGrid is a uniform spatial hash over view-space rectangles. Queries
+// visit only the rectangles sharing a cell with the query rectangle, so
+// placing a label does not test every node in the scene.
+type rectGrid struct {
+	cell  float64
+	rects []rect
+	cells map[[2]int][]int
+	large []int
+	seen  []int
+	stamp int
+}
+
+// newRectGrid creates an empty grid with square cells of the given size
+func newRectGrid(cell float64) *rectGrid {
+	if !isFinite(cell) || cell <= 0 {
+		cell = 1
+	}
+	return &rectGrid{cell: cell, cells: make(map[[2]int][]int)}
+}
+
+// span returns the cell range covered by r, or false when r is not finite
+// or covers more than maxGridSpan cells
+func (g *rectGrid) span(r rect) (x0, y0, x1, y1 int, ok bool) {
+	fx0, fy0 := math.Floor(r.minX/g.cell), math.Floor(r.minY/g.cell)
+	fx1, fy1 := math.Floor(r.maxX/g.cell), math.Floor(r.maxY/g.cell)
+	if !isFinite(fx0) || !isFinite(fy0) || !isFinite(fx1) || !isFinite(fy1) ||
+		(fx1-fx0+1)*(fy1-fy0+1) > maxGridSpan {
+		return 0, 0, 0, 0, false
+	}
+	return int(fx0), int(fy0), int(fx1), int(fy1), true
+}
+
+// insert adds a rectangle to the grid and returns its index
+func (g *rectGrid) insert(r rect) int {
+	i := len(g.rects)
+	g.rects = append(g.rects, r)
+	g.seen = append(g.seen, 0)
+	x0, y0, x1, y1, ok := g.span(r)
+	if !ok {
+		g.large = append(g.large, i)
+		return i
+	}
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			g.cells[[2]int{x, y}] = append(g.cells[[2]int{x, y}], i)
+		}
+	}
+	return i
+}
+
+// query calls fn once for every rectangle that may overlap r
+func (g *rectGrid) query(r rect, fn func(i int, o rect)) {
+	x0, y0, x1, y1, ok := g.span(r)
+	if !ok {
+		for i, o := range g.rects {
+			fn(i, o)
+		}
+		return
+	}
+	g.stamp++
+	visit := func(i int) {
+		if g.seen[i] != g.stamp {
+			g.seen[i] = g.stamp
+			fn(i, g.rects[i])
+		}
+	}
+	for _, i := range g.large {
+		visit(i)
+	}
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			for _, i := range g.cells[[2]int{x, y}] {
+				visit(i)
+			}
+		}
+	}
+}
+
+// viewBasis represents the right and up axes of a camera view plane
+type viewBasis struct {
+	right, up Vector3
+}
+
+// newViewBasis computes the view plane axes for a camera, defaulting to a
+// view along -Z when no camera is available
+func newViewBasis(camera *Camera) viewBasis {
+	if camera == nil {
+		return viewBasis{right: Vector3{X: 1}, up: Vector3{Y: 1}}
+	}
+	forward := camera.Target.Sub(camera.Position).Normalize()
+	if forward.Length() == 0 {
+		return viewBasis{right: Vector3{X: 1}, up: Vector3{Y: 1}}
+	}
+	worldUp := Vector3{Y: 1}
+	if math.Abs(forward.Dot(worldUp)) > 0.999 {
+		worldUp = Vector3{Z: -1}
+	}
+	right := forward.Cross(worldUp).Normalize()
+	return viewBasis{right: right, up: right.Cross(forward)}
+}
+
+// project maps a world-space point onto the view plane
+func (b viewBasis) project(p Vector3) (float64, float64) {
+	return p.Dot(b.right), p.Dot(b.up)
+}
+
+// projectExtents maps world-space half extents onto the view plane
+func (b viewBasis) projectExtents(h Vector3) (float64, float64) {
+	w := math.Abs(b.right.X)*h.X + math.Abs(b.right.Y)*h.Y + math.Abs(b.right.Z)*h.Z
+	v := math.Abs(b.up.X)*h.X + math.Abs(b.up.Y)*h.Y + math.Abs(b.up.Z)*h.Z
+	return w, v
+}
+
+// labelCandidate is a label waiting to be placed
+type labelCandidate struct {
+	label  *Label
+	cx, cy float64
+	hw, hh float64
+	owner  int
+}
+
+// PlaceLabels positions node and edge labels to minimize overlap with each
+// other and with node bounds as seen from the camera. Labels are placed
+// greedily in priority order; each takes the first candidate anchor that is
+// free of overlap, or the least-overlapping one otherwise. Node bounds and
+// placed labels are kept in a spatial grid, so each candidate is tested only
+// against its neighbours. The chosen anchor and offset are written back into
+// each label.
+func PlaceLabels(sf *SceneFile, opts LabelPlacementOptions) LabelPlacementResult {
+	camera := opts.Camera
+	if camera == nil {
+		camera = sf.Scene.Camera
+	}
+	basis := newViewBasis(camera)
+	anchors := opts.Anchors
+	if len(anchors) == 0 {
+		anchors = DefaultLabelAnchors
+	}
+
+	nodeIndex := make(map[string]int, len(sf.Scene.Nodes))
+	obstacles := make([]rect, len(sf.Scene.Nodes))
+	extent, sized := 0.0, 0
+	candidates := make([]labelCandidate, 0, len(sf.Scene.Nodes)+len(sf.Scene.Edges))
+
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		nodeIndex[node.ID] = i
+		cx, cy := basis.project(node.Transform.Position)
+		hw, hh := basis.projectExtents(nodeHalfExtents(node, sf.ResolveGeometry(node)))
+		obstacles[i] = rect{cx - hw, cy - hh, cx + hw, cy + hh}
+		if size := 2 * math.Max(hw, hh); isFinite(size) && size > 0 {
+			extent += size
+			sized++
+		}
+
+		if node.Label == nil && opts.LabelNodeNames && node.Name != "" {
+			node.Label = &Label{Text: node.Name}
+		}
+		if node.Label != nil {
+			candidates = append(candidates, labelCandidate{node.Label, cx, cy, hw, hh, i})
+		}
+	}
+
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if edge.Label == nil {
+			continue
+		}
+		si, okSource := nodeIndex[edge.Source]
+		ti, okTarget := nodeIndex[edge.Target]
+		if !okSource || !okTarget {
+			continue
+		}
+		mid := sf.Scene.Nodes[si].Transform.Position.Lerp(sf.Scene.Nodes[ti].Transform.Position, 0.5)
+		cx, cy := basis.project(mid)
+		candidates = append(candidates, labelCandidate{edge.Label, cx, cy, 0, 0, -1})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].label.Priority > candidates[j].label.Priority
+	})
+
+	// Cells about the size of an average node keep both the number of cells
+	// a label covers and the number of rectangles per cell small
+	cell := 1.0
+	if sized > 0 {
+		cell = extent / float64(sized)
+	}
+	obstacleGrid := newRectGrid(cell)
+	for _, o := range obstacles {
+		obstacleGrid.insert(o)
+	}
+	placed := newRectGrid(cell)
+
+	result := LabelPlacementResult{}
+	for _, c := range candidates {
+		style := TextStyle{}
+		if c.label.Style != nil {
+			style = *c.label.Style
+		}
+		metrics := MeasureText(c.label.Text, style)
+		lw, lh := metrics.Width/2, metrics.Height/2
+		margin := opts.Margin
+		if margin <= 0 {
+			margin = 0.25 * style.normalized().FontSize
+		}
+
+		bestCost := math.Inf(1)
+		var bestAnchor LabelAnchor
+		var bestRect rect
+		var bestX, bestY float64
+		for _, anchor := range anchors {
+			x, y := anchorPosition(anchor, c, lw, lh, margin)
+			r := rect{x - lw, y - lh, x + lw, y + lh}
+			cost := 0.0
+			placed.query(r, func(_ int, p rect) {
+				cost += r.overlapArea(p)
+			})
+			obstacleGrid.query(r, func(i int, o rect) {
+				if i != c.owner {
+					cost += 0.5 * r.overlapArea(o)
+				}
+			})
+			if cost < bestCost {
+				bestCost, bestAnchor, bestRect, bestX, bestY = cost, anchor, r, x, y
+			}
+			if cost == 0 {
+				break
+			}
+		}
+
+		offset := basis.right.Scale(bestX - c.cx).Add(basis.up.Scale(bestY - c.cy))
+		c.label.Anchor = bestAnchor
+		c.label.Offset = &offset
+		c.label.Hidden = false
+
+		if bestCost > 0 {
+			result.Overlapping++
+			if opts.HideOverlapping {
+				c.label.Hidden = true
+				result.Hidden++
+				continue
+			}
+		}
+		placed.insert(bestRect)
+		result.Placed++
+	}
+
+	return result
+}
+
+// anchorPosition returns the view-space label center for an anchor
+func anchorPosition(anchor LabelAnchor, c labelCandidate, lw, lh, margin float64) (float64, float64) {
+	dx := c.hw + margin + lw
+	dy := c.hh + margin + lh
+	switch anchor {
+	case LabelAnchorTop:
+		return c.cx, c.cy + dy
+	case LabelAnchorBottom:
+		return c.cx, c.cy - dy
+	case LabelAnchorLeft:
+		return c.cx - dx, c.cy
+	case LabelAnchorRight:
+		return c.cx + dx, c.cy
+	case LabelAnchorTopLeft:
+		return c.cx - dx, c.cy + dy
+	case LabelAnchorTopRight:
+		return c.cx + dx, c.cy + dy
+	case LabelAnchorBottomLeft:
+		return c.cx - dx, c.cy - dy
+	case LabelAnchorBottomRight:
+		return c.cx + dx, c.cy - dy
+	default:
+		return c.cx, c.cy
+	}
+}
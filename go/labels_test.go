@@ -0,0 +1,173 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// newLabelTestScene creates two adjacent nodes whose labels compete for space
+func newLabelTestScene() SceneFile {
+	sf := NewSceneFile("Labels")
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "alpha-server", Transform: NewTransformWithPosition(0, 0, 0)})
+	sf.AddNode(SceneNode{ID: "b", Type: "server", Name: "beta-server", Transform: NewTransformWithPosition(0, 2, 0)})
+	sf.AddEdge(SceneEdge{ID: "e", Source: "a", Target: "b", Label: &Label{Text: "tcp"}})
+	return sf
+}
+
+// TestPlaceLabels tests greedy label placement without overlap
+func TestPlaceLabels(t *testing.T) {
+	sf := newLabelTestScene()
+	result := PlaceLabels(&sf, LabelPlacementOptions{LabelNodeNames: true})
+
+	if result.Placed != 3 {
+		t.Errorf("PlaceLabels placed mismatch: got %d, want 3", result.Placed)
+	}
+	if result.Overlapping != 0 {
+		t.Errorf("PlaceLabels overlapping mismatch: got %d, want 0", result.Overlapping)
+	}
+
+	a := sf.FindNode("a")
+	b := sf.FindNode("b")
+	if a.Label == nil || a.Label.Offset == nil || b.Label == nil || b.Label.Offset == nil {
+		t.Fatalf("PlaceLabels did not write node label offsets")
+	}
+	if a.Label.Anchor == LabelAnchorTop && b.Label.Anchor == LabelAnchorBottom {
+		t.Errorf("Labels between adjacent nodes should not both face each other")
+	}
+	if sf.FindEdge("e").Label.Offset == nil {
+		t.Errorf("PlaceLabels did not write edge label offset")
+	}
+}
+
+// TestPlaceLabels_HideOverlapping tests hiding labels that cannot be placed
+func TestPlaceLabels_HideOverlapping(t *testing.T) {
+	sf := NewSceneFile("Crowded")
+	for _, id := range []string{"a", "b", "c"} {
+		sf.AddNode(SceneNode{ID: id, Type: "server", Name: "same-spot", Transform: NewTransform()})
+	}
+
+	result := PlaceLabels(&sf, LabelPlacementOptions{
+		LabelNodeNames:  true,
+		Anchors:         []LabelAnchor{LabelAnchorTop},
+		HideOverlapping: true,
+	})
+	if result.Placed != 1 || result.Hidden != 2 {
+		t.Errorf("PlaceLabels result mismatch: got %+v", result)
+	}
+	if !sf.Scene.Nodes[2].Label.Hidden {
+		t.Errorf("Overlapping label should be hidden")
+	}
+}
+
+// newDenseLabelScene creates a side x side grid of labeled nodes one unit
+// apart, so neighbouring labels compete for space
+func newDenseLabelScene(side int) SceneFile {
+	sf := NewSceneFile("Dense")
+	for i := 0; i < side*side; i++ {
+		sf.AddNode(SceneNode{
+			ID:        fmt.Sprintf("n%d", i),
+			Type:      "server",
+			Name:      fmt.Sprintf("node-%d", i),
+			Transform: NewTransformWithPosition(float64(i%side), float64(i/side), 0),
+		})
+	}
+	return sf
+}
+
+// TestRectGrid_Query tests that grid queries find every overlapping
+// rectangle, including ones too large or invalid for the cells
+func TestRectGrid_Query(t *testing.T) {
+	g := newRectGrid(1)
+	rects := []rect{
+		{0, 0, 1, 1},
+		{5, 5, 6, 6},
+		{-100, -100, 100, 100},
+		{math.NaN(), 0, 1, 1},
+		{2.5, 0.5, 3.5, 1.5},
+	}
+	for _, r := range rects {
+		g.insert(r)
+	}
+
+	for _, q := range []rect{{0.5, 0.5, 3, 1}, {5.5, 5.5, 5.6, 5.6}, {-1000, -1000, 1000, 1000}} {
+		found := make(map[int]int)
+		g.query(q, func(i int, _ rect) { found[i]++ })
+		for i, r := range rects {
+			if found[i] > 1 {
+				t.Errorf("query %v visited rect %d %d times", q, i, found[i])
+			}
+			if q.overlapArea(r) > 0 && found[i] == 0 {
+				t.Errorf("query %v missed overlapping rect %d", q, i)
+			}
+		}
+	}
+}
+
+// TestPlaceLabels_Dense tests that placement in a crowded grid still keeps
+// labels apart
+func TestPlaceLabels_Dense(t *testing.T) {
+	sf := newDenseLabelScene(10)
+	result := PlaceLabels(&sf, LabelPlacementOptions{LabelNodeNames: true, HideOverlapping: true})
+	if result.Placed+result.Hidden != 100 || result.Placed == 0 {
+		t.Fatalf("PlaceLabels result mismatch: got %+v", result)
+	}
+	basis := newViewBasis(nil)
+	var shown []rect
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Label.Hidden {
+			continue
+		}
+		m := MeasureText(n.Label.Text, TextStyle{})
+		x, y := basis.project(n.Transform.Position.Add(*n.Label.Offset))
+		shown = append(shown, rect{x - m.Width/2, y - m.Height/2, x + m.Width/2, y + m.Height/2})
+	}
+	for i := range shown {
+		for j := i + 1; j < len(shown); j++ {
+			if shown[i].overlapArea(shown[j]) > 1e-9 {
+				t.Fatalf("visible labels %d and %d overlap", i, j)
+			}
+		}
+	}
+}
+
+// BenchmarkPlaceLabels measures placement in dense scenes of growing size
+func BenchmarkPlaceLabels(b *testing.B) {
+	for _, side := range []int{10, 30, 100} {
+		b.Run(fmt.Sprintf("nodes=%d", side*side), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				sf := newDenseLabelScene(side)
+				b.StartTimer()
+				PlaceLabels(&sf, LabelPlacementOptions{LabelNodeNames: true})
+			}
+		})
+	}
+}
+
+// TestLabel_JSON tests Label JSON marshaling/unmarshaling
+func TestLabel_JSON(t *testing.T) {
+	original := Label{
+		Text:     "db-primary",
+		Style:    &TextStyle{FontSize: 0.5},
+		Anchor:   LabelAnchorRight,
+		Offset:   &Vector3{X: 1, Y: 0, Z: 0},
+		Priority: 2,
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Failed to marshal Label: %v", err)
+	}
+
+	var result Label
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal Label: %v", err)
+	}
+	if result.Text != original.Text || result.Anchor != original.Anchor || *result.Offset != *original.Offset {
+		t.Errorf("Label mismatch: got %+v, want %+v", result, original)
+	}
+}
@@ -0,0 +1,318 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// =============================================================================
+// LATENCY PATHS
+// =============================================================================
+
+// LatencyPathsExtension is the scene extension key holding the paths written
+// by AnalyzeLatency
+const LatencyPathsExtension = "latencyPaths"
+
+// LatencyOptions controls how AnalyzeLatency walks the scene
+type LatencyOptions struct {
+	// Metric is the edge metric holding hop latency; empty means "latency"
+	Metric string `json:"metric,omitempty"`
+	// NodeMetric, when set, is a node metric holding time spent inside a
+	// node, added for every node on a path
+	NodeMetric string `json:"nodeMetric,omitempty"`
+	// EdgeTypes restricts the dependencies followed when non-empty
+	EdgeTypes []string `json:"edgeTypes,omitempty"`
+	// Entries are the nodes paths start from; empty means every node that
+	// has dependencies and no dependents
+	Entries []string `json:"entries,omitempty"`
+	// MaxPaths keeps only the slowest paths when positive
+	MaxPaths int `json:"maxPaths,omitempty"`
+	// DominantShare is the share of a path's latency its dominant
+	// contributors account for together; zero means 0.8
+	DominantShare float64 `json:"dominantShare,omitempty" validate:"omitempty,gt=0,lte=1"`
+}
+
+// LatencyContributor is one hop, an edge or the time inside a node, of a
+// latency path
+type LatencyContributor struct {
+	Kind    string  `json:"kind" validate:"required,oneof=node edge"`
+	ID      string  `json:"id" validate:"required"`
+	Latency float64 `json:"latency"`
+	// Share is the fraction of the path latency spent here
+	Share float64 `json:"share"`
+	// Dominant marks the fewest, largest contributors that together reach
+	// the dominant share
+	Dominant bool `json:"dominant,omitempty"`
+}
+
+// LatencyPath is the slowest dependency path from an entry node to a node
+// with no dependencies of its own
+type LatencyPath struct {
+	Nodes   []string `json:"nodes"`
+	Edges   []string `json:"edges"`
+	Latency float64  `json:"latency"`
+	// Contributors lists the hops with latency, slowest first
+	Contributors []LatencyContributor `json:"contributors,omitempty"`
+	// Unmeasured lists edges on the path without a latency value, which
+	// count as zero
+	Unmeasured []string `json:"unmeasured,omitempty"`
+}
+
+// Path returns the latency path as a Path, for HighlightPath
+func (p LatencyPath) Path() Path {
+	return Path{Nodes: p.Nodes, Edges: p.Edges, Cost: p.Latency}
+}
+
+// Dominant returns the IDs of the path's dominant contributors
+func (p LatencyPath) Dominant() []string {
+	var ids []string
+	for _, c := range p.Contributors {
+		if c.Dominant {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// latencyArc is a dependency followed by AnalyzeLatency
+type latencyArc struct {
+	edge    int
+	to      int
+	latency float64
+	known   bool
+}
+
+// AnalyzeLatency computes end-to-end latency along dependency paths. A
+// directed edge makes its source depend on its target, as in
+// PropagateStatus, and a path's latency is the sum of its edge latencies
+// plus, with a NodeMetric, the time inside each of its nodes. For every
+// entry node it finds the slowest path to each dependency it reaches that
+// has none of its own, the critical path of a request from that entry.
+// Dependency cycles are cut at the edge closing them. Paths come slowest
+// first and are stored in the scene under LatencyPathsExtension, replacing
+// an earlier analysis.
+func AnalyzeLatency(sf *SceneFile, opts LatencyOptions) ([]LatencyPath, error) {
+	metric := opts.Metric
+	if metric == "" {
+		metric = "latency"
+	}
+	share := opts.DominantShare
+	if share == 0 {
+		share = 0.8
+	}
+	if share < 0 || share > 1 {
+		return nil, fmt.Errorf("analyze latency: dominant share %v is outside (0, 1]", share)
+	}
+	index := make(map[string]int, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		index[sf.Scene.Nodes[i].ID] = i
+	}
+	for _, id := range opts.Entries {
+		if _, ok := index[id]; !ok {
+			return nil, fmt.Errorf("analyze latency: %w: %s", ErrNodeNotFound, id)
+		}
+	}
+
+	types := make(map[string]bool, len(opts.EdgeTypes))
+	for _, t := range opts.EdgeTypes {
+		types[t] = true
+	}
+	adj := make([][]latencyArc, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		if !e.IsDirected() || (len(types) > 0 && !types[e.Type]) {
+			continue
+		}
+		s, okSource := index[e.Source]
+		t, okTarget := index[e.Target]
+		if !okSource || !okTarget || s == t {
+			continue
+		}
+		v, ok := toFloat64(e.Metrics[metric])
+		ok = ok && isFinite(v) && v >= 0
+		if !ok {
+			v = 0
+		}
+		adj[s] = append(adj[s], latencyArc{edge: i, to: t, latency: v, known: ok})
+	}
+	order := cutLatencyCycles(adj)
+
+	nodeLatency := make([]float64, len(sf.Scene.Nodes))
+	if opts.NodeMetric != "" {
+		for i := range sf.Scene.Nodes {
+			if v, ok := toFloat64(sf.Scene.Nodes[i].Metrics[opts.NodeMetric]); ok && isFinite(v) && v >= 0 {
+				nodeLatency[i] = v
+			}
+		}
+	}
+
+	entries := make([]int, 0, len(opts.Entries))
+	for _, id := range opts.Entries {
+		entries = append(entries, index[id])
+	}
+	if len(opts.Entries) == 0 {
+		dependent := make([]bool, len(adj))
+		for _, arcs := range adj {
+			for _, a := range arcs {
+				dependent[a.to] = true
+			}
+		}
+		for i := range adj {
+			if len(adj[i]) > 0 && !dependent[i] {
+				entries = append(entries, i)
+			}
+		}
+	}
+
+	paths := []LatencyPath{}
+	for _, entry := range entries {
+		// Nodes come in topological order, so each is settled before the
+		// arcs leaving it are relaxed
+		dist := make(map[int]float64, len(adj))
+		via := make(map[int]latencyArc, len(adj))
+		from := make(map[int]int, len(adj))
+		dist[entry] = nodeLatency[entry]
+		for _, n := range order {
+			d, reached := dist[n]
+			if !reached {
+				continue
+			}
+			for _, a := range adj[n] {
+				next := d + a.latency + nodeLatency[a.to]
+				if cur, ok := dist[a.to]; !ok || next > cur {
+					dist[a.to], via[a.to], from[a.to] = next, a, n
+				}
+			}
+		}
+		for _, n := range order {
+			if _, reached := dist[n]; !reached || n == entry || len(adj[n]) > 0 {
+				continue
+			}
+			paths = append(paths, latencyPath(sf, n, entry, dist[n], via, from, nodeLatency, opts.NodeMetric != "", share))
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return paths[i].Latency > paths[j].Latency })
+	if opts.MaxPaths > 0 && len(paths) > opts.MaxPaths {
+		paths = paths[:opts.MaxPaths]
+	}
+
+	if len(paths) == 0 {
+		delete(sf.Extensions, LatencyPathsExtension)
+	} else {
+		sf.Extensions = withExtension(sf.Extensions, LatencyPathsExtension, paths)
+	}
+	return paths, nil
+}
+
+// cutLatencyCycles drops the arcs closing dependency cycles, found by a
+// depth-first search in scene order, and returns the nodes in topological
+// order
+func cutLatencyCycles(adj [][]latencyArc) []int {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make([]int, len(adj))
+	post := make([]int, 0, len(adj))
+	var visit func(n int)
+	visit = func(n int) {
+		state[n] = active
+		kept := adj[n][:0]
+		for _, a := range adj[n] {
+			if state[a.to] == active {
+				continue
+			}
+			kept = append(kept, a)
+			if state[a.to] == unvisited {
+				visit(a.to)
+			}
+		}
+		adj[n] = kept
+		state[n] = done
+		post = append(post, n)
+	}
+	for n := range adj {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+	slices.Reverse(post)
+	return post
+}
+
+// latencyPath walks back from a sink to its entry and ranks the hops
+func latencyPath(sf *SceneFile, sink, entry int, total float64, via map[int]latencyArc, from map[int]int, nodeLatency []float64, withNodes bool, share float64) LatencyPath {
+	p := LatencyPath{Latency: total}
+	var hops []LatencyContributor
+	addNode := func(n int) {
+		p.Nodes = append(p.Nodes, sf.Scene.Nodes[n].ID)
+		if withNodes && nodeLatency[n] > 0 {
+			hops = append(hops, LatencyContributor{Kind: "node", ID: sf.Scene.Nodes[n].ID, Latency: nodeLatency[n]})
+		}
+	}
+	for n := sink; n != entry; n = from[n] {
+		addNode(n)
+		a := via[n]
+		e := &sf.Scene.Edges[a.edge]
+		p.Edges = append(p.Edges, e.ID)
+		switch {
+		case !a.known:
+			p.Unmeasured = append(p.Unmeasured, e.ID)
+		case a.latency > 0:
+			hops = append(hops, LatencyContributor{Kind: "edge", ID: e.ID, Latency: a.latency})
+		}
+	}
+	addNode(entry)
+	reverseStrings(p.Nodes)
+	reverseStrings(p.Edges)
+	reverseStrings(p.Unmeasured)
+
+	// hops were gathered sink first; reversing keeps path order among
+	// equal latencies
+	slices.Reverse(hops)
+	sort.SliceStable(hops, func(i, j int) bool { return hops[i].Latency > hops[j].Latency })
+	covered := 0.0
+	for i := range hops {
+		hops[i].Share = hops[i].Latency / total
+		if covered < share {
+			hops[i].Dominant = true
+			covered += hops[i].Share
+		}
+	}
+	p.Contributors = hops
+	return p
+}
+
+// LatencyPaths returns the paths stored by the last AnalyzeLatency
+func (sf *SceneFile) LatencyPaths() ([]LatencyPath, error) {
+	v, ok := sf.Extensions[LatencyPathsExtension]
+	if !ok {
+		return nil, nil
+	}
+	var paths []LatencyPath
+	if err := roundTripJSON(v, &paths); err != nil {
+		return nil, fmt.Errorf("latency paths: %w", err)
+	}
+	return paths, nil
+}
+
+// latencyStage runs AnalyzeLatency with LatencyOptions and notes the
+// slowest path
+func latencyStage(options json.RawMessage) (PipelineStage, error) {
+	var opts LatencyOptions
+	if err := decodeStageOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		paths, err := AnalyzeLatency(sf, opts)
+		if err != nil || len(paths) == 0 {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("%d latency paths, slowest %g from %s to %s",
+			len(paths), paths[0].Latency, paths[0].Nodes[0], paths[0].Nodes[len(paths[0].Nodes)-1])}, nil
+	}), nil
+}
@@ -0,0 +1,124 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newLatencyScene returns web -> api -> {db, cache} with a retry loop from
+// db back to api
+func newLatencyScene() SceneFile {
+	sf := SceneFile{Version: "0.2.0"}
+	for _, id := range []string{"web", "api", "db", "cache"} {
+		sf.AddNode(SceneNode{ID: id, Type: "service"})
+	}
+	edge := func(id, source, target string, latency interface{}) SceneEdge {
+		e := SceneEdge{ID: id, Source: source, Target: target, Direction: EdgeDirected}
+		if latency != nil {
+			e.Metrics = map[string]interface{}{"latency": latency}
+		}
+		return e
+	}
+	sf.AddEdge(edge("web-api", "web", "api", 10.0))
+	sf.AddEdge(edge("api-db", "api", "db", 80.0))
+	sf.AddEdge(edge("api-cache", "api", "cache", 2.0))
+	sf.AddEdge(edge("db-api", "db", "api", 500.0))
+	return sf
+}
+
+// TestAnalyzeLatency tests finding slowest paths and their dominant
+// contributors
+func TestAnalyzeLatency(t *testing.T) {
+	sf := newLatencyScene()
+	paths, err := AnalyzeLatency(&sf, LatencyOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeLatency failed: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("path count mismatch: got %d, want 2", len(paths))
+	}
+	slowest := paths[0]
+	if !reflect.DeepEqual(slowest.Nodes, []string{"web", "api", "db"}) || !reflect.DeepEqual(slowest.Edges, []string{"web-api", "api-db"}) {
+		t.Errorf("slowest path mismatch: got %v via %v", slowest.Nodes, slowest.Edges)
+	}
+	if slowest.Latency != 90 {
+		t.Errorf("latency mismatch: got %v, want 90", slowest.Latency)
+	}
+	if got := slowest.Dominant(); !reflect.DeepEqual(got, []string{"api-db"}) {
+		t.Errorf("dominant mismatch: got %v, want [api-db]", got)
+	}
+	if paths[1].Latency != 12 || !reflect.DeepEqual(paths[1].Dominant(), []string{"web-api"}) {
+		t.Errorf("second path mismatch: got %+v", paths[1])
+	}
+
+	stored, err := sf.LatencyPaths()
+	if err != nil || !reflect.DeepEqual(stored, paths) {
+		t.Errorf("stored paths mismatch: got %+v, %v", stored, err)
+	}
+	HighlightPath(&sf, slowest.Path(), "critical")
+	if sf.FindEdge("api-db").Extensions[HighlightExtension] == nil {
+		t.Error("critical path not highlighted")
+	}
+}
+
+// TestAnalyzeLatency_Options tests node latency, unmeasured edges, entries
+// and limits
+func TestAnalyzeLatency_Options(t *testing.T) {
+	sf := newLatencyScene()
+	delete(sf.FindEdge("api-db").Metrics, "latency")
+	sf.FindNode("cache").Metrics = map[string]interface{}{"self": 100.0}
+
+	paths, err := AnalyzeLatency(&sf, LatencyOptions{NodeMetric: "self", Entries: []string{"api"}, MaxPaths: 1, DominantShare: 1})
+	if err != nil {
+		t.Fatalf("AnalyzeLatency failed: %v", err)
+	}
+	want := LatencyPath{
+		Nodes:   []string{"api", "cache"},
+		Edges:   []string{"api-cache"},
+		Latency: 102,
+		Contributors: []LatencyContributor{
+			{Kind: "node", ID: "cache", Latency: 100, Share: 100.0 / 102, Dominant: true},
+			{Kind: "edge", ID: "api-cache", Latency: 2, Share: 2.0 / 102, Dominant: true},
+		},
+	}
+	if len(paths) != 1 || !reflect.DeepEqual(paths[0], want) {
+		t.Errorf("path mismatch: got %+v, want %+v", paths, want)
+	}
+
+	paths, _ = AnalyzeLatency(&sf, LatencyOptions{Entries: []string{"web"}})
+	if len(paths) != 2 || paths[1].Latency != 10 || !reflect.DeepEqual(paths[1].Unmeasured, []string{"api-db"}) {
+		t.Errorf("unmeasured mismatch: got %+v", paths)
+	}
+
+	if _, err := AnalyzeLatency(&sf, LatencyOptions{Entries: []string{"missing"}}); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrNodeNotFound)
+	}
+	if _, err := AnalyzeLatency(&sf, LatencyOptions{DominantShare: 2}); err == nil {
+		t.Error("expected error for dominant share above 1")
+	}
+	if _, err := AnalyzeLatency(&sf, LatencyOptions{EdgeTypes: []string{"rpc"}}); err != nil || sf.Extensions[LatencyPathsExtension] != nil {
+		t.Errorf("stale paths kept: %v", err)
+	}
+}
+
+// TestLatencyStage tests analyzing latency from a pipeline stage
+func TestLatencyStage(t *testing.T) {
+	sf := newLatencyScene()
+	stage, err := latencyStage(json.RawMessage(`{"maxPaths": 1}`))
+	if err != nil {
+		t.Fatalf("latencyStage failed: %v", err)
+	}
+	notes, err := stage.Run(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"1 latency paths, slowest 90 from web to db"}; !reflect.DeepEqual(notes, want) {
+		t.Errorf("notes mismatch: got %v, want %v", notes, want)
+	}
+	if _, err := latencyStage(json.RawMessage(`{"metrics": "x"}`)); err == nil {
+		t.Error("expected error for unknown option")
+	}
+}
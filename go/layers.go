@@ -0,0 +1,146 @@
+package starfleet
+
+import "sort"
+
+// =============================================================================
+// DEPENDENCY LAYERING
+// =============================================================================
+
+// LayerMetadataKey is the node metadata key holding the assigned layer index
+const LayerMetadataKey = "layer"
+
+// LayerAssignment represents the result of dependency layering
+type LayerAssignment struct {
+	// Layers maps node IDs to their layer index, starting at 0
+	Layers map[string]int `json:"layers"`
+	// Count is the number of distinct layers
+	Count int `json:"count"`
+	// Cycles lists groups of nodes that depend on each other; each group
+	// shares a single layer
+	Cycles [][]string `json:"cycles,omitempty"`
+}
+
+// AssignLayers computes topological layers along directed edges of the given
+// type (all directed edges when edgeType is empty) and writes each node's
+// layer index into its metadata under LayerMetadataKey. Nodes without
+// incoming dependencies are placed in layer 0 and every other node one layer
+// below its deepest predecessor, so a frontend -> backend -> data chain maps
+// to layers 0, 1 and 2. Dependency cycles are collapsed into one layer.
+func AssignLayers(sf *SceneFile, edgeType string) LayerAssignment {
+	index := make(map[string]int, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		index[sf.Scene.Nodes[i].ID] = i
+	}
+	adj := make([][]int, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if !edge.IsDirected() || (edgeType != "" && edge.Type != edgeType) {
+			continue
+		}
+		s, okSource := index[edge.Source]
+		t, okTarget := index[edge.Target]
+		if okSource && okTarget {
+			adj[s] = append(adj[s], t)
+		}
+	}
+
+	comp, comps := stronglyConnected(adj)
+
+	// Tarjan emits components in reverse topological order, so walking
+	// them backwards visits every component after all its predecessors.
+	compLayer := make([]int, len(comps))
+	for c := len(comps) - 1; c >= 0; c-- {
+		for _, n := range comps[c] {
+			for _, m := range adj[n] {
+				if comp[m] != c && compLayer[c]+1 > compLayer[comp[m]] {
+					compLayer[comp[m]] = compLayer[c] + 1
+				}
+			}
+		}
+	}
+
+	result := LayerAssignment{Layers: make(map[string]int, len(sf.Scene.Nodes))}
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		layer := compLayer[comp[i]]
+		result.Layers[node.ID] = layer
+		if layer+1 > result.Count {
+			result.Count = layer + 1
+		}
+		if node.Metadata == nil {
+			node.Metadata = make(map[string]interface{})
+		}
+		node.Metadata[LayerMetadataKey] = layer
+	}
+	for _, members := range comps {
+		if len(members) > 1 {
+			ids := make([]string, len(members))
+			for j, n := range members {
+				ids[j] = sf.Scene.Nodes[n].ID
+			}
+			sort.Strings(ids)
+			result.Cycles = append(result.Cycles, ids)
+		}
+	}
+	return result
+}
+
+// NodeLayer returns the layer index previously written by AssignLayers
+func NodeLayer(node *SceneNode) (int, bool) {
+	v, ok := toFloat64(node.Metadata[LayerMetadataKey])
+	return int(v), ok
+}
+
+// stronglyConnected computes strongly connected components with Tarjan's
+// algorithm. It returns the component index of every vertex and the members
+// of each component, in reverse topological order.
+func stronglyConnected(adj [][]int) ([]int, [][]int) {
+	n := len(adj)
+	index := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	comp := make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	var comps [][]int
+	counter := 0
+
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], low[v] = counter, counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adj[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] == index[v] {
+			var members []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp[w] = len(comps)
+				members = append(members, w)
+				if w == v {
+					break
+				}
+			}
+			comps = append(comps, members)
+		}
+	}
+
+	for v := 0; v < n; v++ {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	return comp, comps
+}
@@ -0,0 +1,44 @@
+package starfleet
+
+import "testing"
+
+// TestAssignLayers tests topological layering with cycles
+func TestAssignLayers(t *testing.T) {
+	sf := NewSceneFile("Layers")
+	for _, id := range []string{"web", "mobile", "api", "worker", "queue", "db"} {
+		sf.AddNode(SceneNode{ID: id, Type: "service", Name: id, Transform: NewTransform()})
+	}
+	dep := func(from, to string) {
+		sf.AddEdge(SceneEdge{ID: from + "-" + to, Source: from, Target: to, Type: "calls"})
+	}
+	dep("web", "api")
+	dep("mobile", "api")
+	dep("api", "queue")
+	dep("queue", "worker")
+	dep("worker", "queue")
+	dep("worker", "db")
+	dep("api", "db")
+	sf.AddEdge(SceneEdge{ID: "monitor", Source: "db", Target: "web", Type: "monitors"})
+
+	result := AssignLayers(&sf, "calls")
+	want := map[string]int{"web": 0, "mobile": 0, "api": 1, "queue": 2, "worker": 2, "db": 3}
+	for id, layer := range want {
+		if result.Layers[id] != layer {
+			t.Errorf("Layer mismatch for %s: got %d, want %d", id, result.Layers[id], layer)
+		}
+		if got, ok := NodeLayer(sf.FindNode(id)); !ok || got != layer {
+			t.Errorf("Layer metadata mismatch for %s: got %d", id, got)
+		}
+	}
+	if result.Count != 4 {
+		t.Errorf("Layer count mismatch: got %d, want 4", result.Count)
+	}
+	if len(result.Cycles) != 1 || !equalStrings(result.Cycles[0], []string{"queue", "worker"}) {
+		t.Errorf("Cycles mismatch: got %v", result.Cycles)
+	}
+
+	all := AssignLayers(&sf, "")
+	if all.Count != 2 || all.Layers["mobile"] != 0 || len(all.Cycles) != 1 {
+		t.Errorf("Untyped layering should collapse the monitoring cycle: got %+v", all)
+	}
+}
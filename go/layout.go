@@ -0,0 +1,92 @@
+package starfleet
+
+import "context"
+
+// =============================================================================
+// LAYOUT
+// =============================================================================
+
+// Layout computes positions for the nodes of a scene. Implementations write
+// transforms in place and may add group nodes to the scene.
+type Layout interface {
+	Apply(sf *SceneFile) (LayoutResult, error)
+}
+
+// ContextLayout is implemented by layouts whose work grows with the scene
+// enough to be worth abandoning. ApplyContext stops once the context is
+// done and returns its error, leaving the nodes it already moved.
+type ContextLayout interface {
+	Layout
+	ApplyContext(ctx context.Context, sf *SceneFile) (LayoutResult, error)
+}
+
+// ApplyLayout runs a layout, through ApplyContext when it is a
+// ContextLayout. Other layouts only see a context already done.
+func ApplyLayout(ctx context.Context, l Layout, sf *SceneFile) (LayoutResult, error) {
+	if cl, ok := l.(ContextLayout); ok {
+		return cl.ApplyContext(ctx, sf)
+	}
+	if err := ctx.Err(); err != nil {
+		return LayoutResult{}, err
+	}
+	return l.Apply(sf)
+}
+
+// LayoutFunc adapts a function to the Layout interface
+type LayoutFunc func(sf *SceneFile) (LayoutResult, error)
+
+// Apply implements Layout
+func (f LayoutFunc) Apply(sf *SceneFile) (LayoutResult, error) {
+	return f(sf)
+}
+
+// LayoutResult represents the outcome of a layout pass
+type LayoutResult struct {
+	// Positioned is the number of nodes whose transform was set
+	Positioned int `json:"positioned"`
+	// Created lists group nodes added by the layout
+	Created []string `json:"created,omitempty"`
+	// Skipped lists nodes the layout could not place
+	Skipped  []string `json:"skipped,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ensureGroupNode returns the index of the node with the given ID, creating
+// it with the given type and name when missing. Existing nodes are reused so
+// layouts can be re-run without duplicating groups.
+func ensureGroupNode(sf *SceneFile, id, nodeType, name string, result *LayoutResult) int {
+	for i := range sf.Scene.Nodes {
+		if sf.Scene.Nodes[i].ID == id {
+			return i
+		}
+	}
+	sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{
+		ID:        id,
+		Type:      nodeType,
+		Name:      name,
+		Transform: NewTransform(),
+	})
+	result.Created = append(result.Created, id)
+	return len(sf.Scene.Nodes) - 1
+}
+
+// setParent moves a node under a new parent, keeping both sides of the
+// hierarchy consistent
+func setParent(sf *SceneFile, child, parent int) {
+	c, p := &sf.Scene.Nodes[child], &sf.Scene.Nodes[parent]
+	if c.Parent != "" && c.Parent != p.ID {
+		if old := sf.FindNode(c.Parent); old != nil {
+			kept := old.Children[:0]
+			for _, id := range old.Children {
+				if id != c.ID {
+					kept = append(kept, id)
+				}
+			}
+			old.Children = kept
+		}
+	}
+	c.Parent = p.ID
+	if !containsString(p.Children, c.ID) {
+		p.Children = append(p.Children, c.ID)
+	}
+}
@@ -0,0 +1,129 @@
+package starfleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// =============================================================================
+// SHARED MATERIAL AND GEOMETRY LIBRARIES
+// =============================================================================
+
+// ResolveMaterial returns the material of a node: its inline definition when
+// present, otherwise the library entry named by MaterialRef
+func (sf *SceneFile) ResolveMaterial(node *SceneNode) *Material {
+	if node.Material != nil {
+		return node.Material
+	}
+	if m, ok := sf.Materials[node.MaterialRef]; ok && node.MaterialRef != "" {
+		return &m
+	}
+	return nil
+}
+
+// ResolveGeometry returns the geometry of a node: its inline definition when
+// present, otherwise the library entry named by GeometryRef
+func (sf *SceneFile) ResolveGeometry(node *SceneNode) *Geometry {
+	if node.Geometry != nil {
+		return node.Geometry
+	}
+	if g, ok := sf.Geometries[node.GeometryRef]; ok && node.GeometryRef != "" {
+		return &g
+	}
+	return nil
+}
+
+// HoistResources moves inline materials and geometries used by at least
+// minUses nodes (2 when minUses <= 0) into the scene libraries, replacing
+// them with references. Library keys are derived from the definition
+// content, so hoisting is deterministic and idempotent. It returns the
+// number of inline definitions replaced.
+func HoistResources(sf *SceneFile, minUses int) int {
+	if minUses <= 0 {
+		minUses = 2
+	}
+	materialUses := make(map[string]int)
+	geometryUses := make(map[string]int)
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if key, ok := resourceKey(n.Material); ok && n.Material != nil {
+			materialUses[key]++
+		}
+		if key, ok := resourceKey(n.Geometry); ok && n.Geometry != nil {
+			geometryUses[key]++
+		}
+	}
+
+	replaced := 0
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if key, ok := resourceKey(n.Material); ok && n.Material != nil && materialUses[key] >= minUses {
+			ref := "material-" + contentID(key)
+			if sf.Materials == nil {
+				sf.Materials = make(map[string]Material)
+			}
+			sf.Materials[ref] = *n.Material
+			n.Material, n.MaterialRef = nil, ref
+			replaced++
+		}
+		if key, ok := resourceKey(n.Geometry); ok && n.Geometry != nil && geometryUses[key] >= minUses {
+			ref := "geometry-" + contentID(key)
+			if sf.Geometries == nil {
+				sf.Geometries = make(map[string]Geometry)
+			}
+			sf.Geometries[ref] = *n.Geometry
+			n.Geometry, n.GeometryRef = nil, ref
+			replaced++
+		}
+	}
+	return replaced
+}
+
+// InlineResources replaces material and geometry references with inline
+// copies of the library entries and removes the libraries, producing a
+// scene readable by consumers that predate libraries. References to missing
+// entries are left in place.
+func InlineResources(sf *SceneFile) {
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Material == nil && n.MaterialRef != "" {
+			if m, ok := sf.Materials[n.MaterialRef]; ok {
+				n.Material, n.MaterialRef = &m, ""
+			}
+		}
+		if n.Geometry == nil && n.GeometryRef != "" {
+			if g, ok := sf.Geometries[n.GeometryRef]; ok {
+				n.Geometry, n.GeometryRef = &g, ""
+			}
+		}
+	}
+	sf.Materials = nil
+	sf.Geometries = nil
+}
+
+// ValidateResources checks that material and geometry references resolve
+// to library entries
+func ValidateResources(sf *SceneFile) []string {
+	var errs []string
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.MaterialRef != "" {
+			if _, ok := sf.Materials[n.MaterialRef]; !ok {
+				errs = append(errs, fmt.Sprintf("Node %s references non-existent material: %s", n.ID, n.MaterialRef))
+			}
+		}
+		if n.GeometryRef != "" {
+			if _, ok := sf.Geometries[n.GeometryRef]; !ok {
+				errs = append(errs, fmt.Sprintf("Node %s references non-existent geometry: %s", n.ID, n.GeometryRef))
+			}
+		}
+	}
+	return errs
+}
+
+// contentID returns a short stable identifier for content
+func contentID(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:6])
+}
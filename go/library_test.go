@@ -0,0 +1,75 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestHoistResources tests moving repeated definitions into libraries
+func TestHoistResources(t *testing.T) {
+	sf := newOptimizeScene()
+	sf.AddNode(SceneNode{ID: "unique", Type: "server", Name: "u", Transform: NewTransform(), Material: &Material{Wireframe: true}})
+
+	replaced := HoistResources(&sf, 0)
+	if replaced != 6 {
+		t.Errorf("HoistResources replaced mismatch: got %d, want 6", replaced)
+	}
+	if len(sf.Materials) != 1 || len(sf.Geometries) != 1 {
+		t.Fatalf("Library sizes mismatch: materials %d, geometries %d", len(sf.Materials), len(sf.Geometries))
+	}
+
+	a := sf.FindNode("a")
+	if a.Material != nil || a.MaterialRef == "" || sf.ResolveMaterial(a) == nil || sf.ResolveMaterial(a).Roughness != 0.5 {
+		t.Errorf("Hoisted material not resolvable: %+v", a)
+	}
+	if sf.ResolveGeometry(a) == nil || sf.ResolveGeometry(a).Type != GeometryBox {
+		t.Errorf("Hoisted geometry not resolvable")
+	}
+	if u := sf.FindNode("unique"); u.Material == nil || u.MaterialRef != "" {
+		t.Errorf("Single-use material should stay inline")
+	}
+	if errs := ValidateResources(&sf); len(errs) != 0 {
+		t.Errorf("ValidateResources errors: %v", errs)
+	}
+
+	if again := HoistResources(&sf, 0); again != 0 {
+		t.Errorf("HoistResources should be idempotent: replaced %d", again)
+	}
+
+	InlineResources(&sf)
+	if sf.Materials != nil || sf.FindNode("b").Material == nil || sf.FindNode("b").MaterialRef != "" {
+		t.Errorf("InlineResources did not restore inline definitions")
+	}
+}
+
+// TestHoistResources_Size tests that hoisting shrinks scenes with many
+// repeated definitions
+func TestHoistResources_Size(t *testing.T) {
+	sf := NewSceneFile("Fleet")
+	for i := 0; i < 50; i++ {
+		sf.AddNode(SceneNode{
+			ID:        "n" + string(rune('a'+i%26)) + string(rune('a'+i/26)),
+			Type:      "server",
+			Transform: NewTransform(),
+			Geometry:  &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{"width": 2, "height": 4, "depth": 1}},
+			Material:  &Material{Color: &Color{R: 0.25, G: 0.5, B: 0.75, A: 1}, Metalness: 0.9, Roughness: 0.1},
+		})
+	}
+	before, _ := json.Marshal(sf)
+	HoistResources(&sf, 0)
+	after, _ := json.Marshal(sf)
+	if len(after) >= len(before) {
+		t.Errorf("Hoisting did not shrink the scene: %d -> %d bytes", len(before), len(after))
+	}
+}
+
+// TestValidateResources tests detection of dangling library references
+func TestValidateResources(t *testing.T) {
+	sf := NewSceneFile("Refs")
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "A", Transform: NewTransform(), MaterialRef: "glass", GeometryRef: "rack"})
+
+	result := ValidateScene(&sf)
+	if result.Valid || !containsMessage(result.Errors, "non-existent material: glass") || !containsMessage(result.Errors, "non-existent geometry: rack") {
+		t.Errorf("ValidateScene resource errors mismatch: %v", result.Errors)
+	}
+}
@@ -0,0 +1,469 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// =============================================================================
+// LIFECYCLE
+// =============================================================================
+
+// LifecycleState is the change-control state of a scene
+type LifecycleState string
+
+const (
+	LifecycleDraft     LifecycleState = "draft"
+	LifecycleInReview  LifecycleState = "in-review"
+	LifecyclePublished LifecycleState = "published"
+	LifecycleArchived  LifecycleState = "archived"
+)
+
+// Sentinel errors returned by lifecycle operations
+var (
+	ErrInvalidTransition = errors.New("invalid lifecycle transition")
+	ErrApprovalRequired  = errors.New("approval required")
+	ErrChangeControlled  = errors.New("scene is change-controlled")
+)
+
+// lifecycleTransitions lists the states reachable from each state. Edits to
+// a published scene go through a new draft.
+var lifecycleTransitions = map[LifecycleState][]LifecycleState{
+	LifecycleDraft:     {LifecycleInReview, LifecycleArchived},
+	LifecycleInReview:  {LifecycleDraft, LifecyclePublished},
+	LifecyclePublished: {LifecycleDraft, LifecycleArchived},
+	LifecycleArchived:  {LifecycleDraft},
+}
+
+// Approval records a reviewer signing off on a scene under review
+type Approval struct {
+	Actor   string    `json:"actor" validate:"required"`
+	Time    time.Time `json:"time"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+// LifecycleTransition records a state change
+type LifecycleTransition struct {
+	From    LifecycleState `json:"from"`
+	To      LifecycleState `json:"to" validate:"required"`
+	Actor   string         `json:"actor,omitempty"`
+	Time    time.Time      `json:"time"`
+	Comment string         `json:"comment,omitempty"`
+}
+
+// Lifecycle holds a scene's workflow state. Publishing requires an approval
+// from every listed approver and at least MinApprovals approvals overall.
+// The actor who submitted the scene for review may not approve it unless
+// AllowSelfApproval is set. Scenes without a lifecycle are not
+// change-controlled.
+type Lifecycle struct {
+	State             LifecycleState        `json:"state" validate:"required,oneof=draft in-review published archived"`
+	RequiredApprovers []string              `json:"requiredApprovers,omitempty"`
+	MinApprovals      int                   `json:"minApprovals,omitempty" validate:"omitempty,min=0"`
+	AllowSelfApproval bool                  `json:"allowSelfApproval,omitempty"`
+	Approvals         []Approval            `json:"approvals,omitempty"`
+	History           []LifecycleTransition `json:"history,omitempty"`
+}
+
+// LifecycleState returns the scene's state; scenes without a lifecycle are
+// drafts
+func (sf *SceneFile) LifecycleState() LifecycleState {
+	if sf.Metadata.Lifecycle == nil || sf.Metadata.Lifecycle.State == "" {
+		return LifecycleDraft
+	}
+	return sf.Metadata.Lifecycle.State
+}
+
+// Transition moves the scene to another lifecycle state and records it in
+// the history. Publishing fails with ErrApprovalRequired until the approval
+// policy is met; approvals are cleared whenever the scene returns to draft
+// or is submitted for review.
+func (sf *SceneFile) Transition(to LifecycleState, actor, comment string) error {
+	from := sf.LifecycleState()
+	if !slices.Contains(lifecycleTransitions[from], to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	if sf.Metadata.Lifecycle == nil {
+		sf.Metadata.Lifecycle = &Lifecycle{State: LifecycleDraft}
+	}
+	lc := sf.Metadata.Lifecycle
+	if to == LifecyclePublished {
+		if missing := lc.MissingApprovals(); len(missing) > 0 {
+			return fmt.Errorf("%w: %v", ErrApprovalRequired, missing)
+		}
+	}
+	if to == LifecycleDraft || to == LifecycleInReview {
+		lc.Approvals = nil
+	}
+	lc.State = to
+	lc.History = append(lc.History, LifecycleTransition{
+		From:    from,
+		To:      to,
+		Actor:   actor,
+		Time:    time.Now().UTC(),
+		Comment: comment,
+	})
+	return nil
+}
+
+// Approve records an approval of a scene under review. When required
+// approvers are listed only they may approve; each actor counts once, and
+// the submitter only counts when the policy allows self-approval.
+func (sf *SceneFile) Approve(actor, comment string) error {
+	if state := sf.LifecycleState(); state != LifecycleInReview {
+		return fmt.Errorf("%w: cannot approve a scene in state %s", ErrInvalidTransition, state)
+	}
+	lc := sf.Metadata.Lifecycle
+	if actor == "" {
+		return fmt.Errorf("%w: approval requires an actor", ErrApprovalRequired)
+	}
+	if len(lc.RequiredApprovers) > 0 && !slices.Contains(lc.RequiredApprovers, actor) {
+		return fmt.Errorf("%w: %s is not an approver", ErrApprovalRequired, actor)
+	}
+	if !lc.AllowSelfApproval && actor == lc.submitter() {
+		return fmt.Errorf("%w: %s submitted the scene and may not approve it", ErrApprovalRequired, actor)
+	}
+	for _, a := range lc.Approvals {
+		if a.Actor == actor {
+			return nil
+		}
+	}
+	lc.Approvals = append(lc.Approvals, Approval{Actor: actor, Time: time.Now().UTC(), Comment: comment})
+	return nil
+}
+
+// SetApprovalPolicy replaces the approval policy of a draft. Policies are
+// fixed once the scene is submitted for review.
+func (sf *SceneFile) SetApprovalPolicy(requiredApprovers []string, minApprovals int, allowSelfApproval bool) error {
+	if state := sf.LifecycleState(); state != LifecycleDraft {
+		return fmt.Errorf("%w: the approval policy of a scene in state %s cannot change", ErrChangeControlled, state)
+	}
+	if minApprovals < 0 {
+		return fmt.Errorf("%w: negative minimum approvals %d", ErrInvalidTransition, minApprovals)
+	}
+	if sf.Metadata.Lifecycle == nil {
+		sf.Metadata.Lifecycle = &Lifecycle{State: LifecycleDraft}
+	}
+	lc := sf.Metadata.Lifecycle
+	lc.RequiredApprovers = slices.Clone(requiredApprovers)
+	lc.MinApprovals = minApprovals
+	lc.AllowSelfApproval = allowSelfApproval
+	return nil
+}
+
+// submitter returns the actor who last submitted the scene for review
+func (lc *Lifecycle) submitter() string {
+	for i := len(lc.History) - 1; i >= 0; i-- {
+		if lc.History[i].To == LifecycleInReview {
+			return lc.History[i].Actor
+		}
+	}
+	return ""
+}
+
+// samePolicy reports whether two lifecycles have the same approval policy
+func (lc *Lifecycle) samePolicy(other *Lifecycle) bool {
+	return slices.Equal(lc.RequiredApprovers, other.RequiredApprovers) &&
+		lc.MinApprovals == other.MinApprovals && lc.AllowSelfApproval == other.AllowSelfApproval
+}
+
+// MissingApprovals returns the required approvers who have not approved,
+// plus a placeholder entry for every approval still short of MinApprovals
+func (lc *Lifecycle) MissingApprovals() []string {
+	approved := make(map[string]bool, len(lc.Approvals))
+	for _, a := range lc.Approvals {
+		approved[a.Actor] = true
+	}
+	var missing []string
+	for _, actor := range lc.RequiredApprovers {
+		if !approved[actor] {
+			missing = append(missing, actor)
+		}
+	}
+	for i := len(lc.Approvals) + len(missing); i < lc.MinApprovals; i++ {
+		missing = append(missing, "(any approver)")
+	}
+	return missing
+}
+
+// =============================================================================
+// WRITE GUARDS
+// =============================================================================
+
+// lifecycleUpdateKey is the context key marking lifecycle updates
+type lifecycleUpdateKey struct{}
+
+// WithLifecycleUpdate marks a write as applying Transition or Approve to the
+// current revision. LifecycleGuard only accepts changes to the lifecycle
+// state, approvals and history in writes marked this way, so ordinary scene
+// writes cannot forge them.
+func WithLifecycleUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lifecycleUpdateKey{}, true)
+}
+
+// policyUpdateKey is the context key marking approval policy updates
+type policyUpdateKey struct{}
+
+// WithPolicyUpdate marks a write as applying SetApprovalPolicy.
+// LifecycleGuard only accepts approval policy changes to existing scenes
+// in writes marked this way, so only mark writes by principals entitled to
+// set policy.
+func WithPolicyUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, policyUpdateKey{}, true)
+}
+
+// WriteGuard inspects a write before a store commits it. prev is nil when a
+// scene is created and next is nil when it is deleted; returning an error
+// rejects the write.
+type WriteGuard func(ctx context.Context, id string, prev, next *SceneFile) error
+
+// LifecycleGuard enforces change control. Content may only change while a
+// scene is a draft, and published scenes cannot be deleted before they are
+// archived. Lifecycle state may only move along valid transitions with the
+// approval policy met, and state, approvals and history only change in
+// writes marked with WithLifecycleUpdate: history is append-only with one
+// entry per transition by the acting user, approvals are added one by one
+// by their approver while in review and cleared when the scene moves to
+// draft or review. The approval policy only changes in drafts, in writes
+// marked with WithPolicyUpdate.
+func LifecycleGuard(ctx context.Context, id string, prev, next *SceneFile) error {
+	if prev == nil {
+		if next != nil && next.LifecycleState() != LifecycleDraft {
+			return fmt.Errorf("%w: scene %s must be created as a draft", ErrInvalidTransition, id)
+		}
+		if next != nil && next.Metadata.Lifecycle != nil && len(next.Metadata.Lifecycle.Approvals) > 0 {
+			return fmt.Errorf("%w: scene %s must be created without approvals", ErrChangeControlled, id)
+		}
+		return nil
+	}
+	from := prev.LifecycleState()
+	if next == nil {
+		if from == LifecyclePublished || from == LifecycleInReview {
+			return fmt.Errorf("%w: archive scene %s before deleting it", ErrChangeControlled, id)
+		}
+		return nil
+	}
+
+	to := next.LifecycleState()
+	if from != to {
+		if !slices.Contains(lifecycleTransitions[from], to) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+		}
+		if to == LifecyclePublished {
+			if missing := next.Metadata.Lifecycle.MissingApprovals(); len(missing) > 0 {
+				return fmt.Errorf("%w: %v", ErrApprovalRequired, missing)
+			}
+		}
+	}
+	if from != LifecycleDraft && contentChanged(prev, next) {
+		return fmt.Errorf("%w: scene %s is %s; move it back to draft to edit", ErrChangeControlled, id, from)
+	}
+	if err := checkPolicy(ctx, prev, next); err != nil {
+		return fmt.Errorf("scene %s: %w", id, err)
+	}
+	if err := checkApprovals(ctx, prev, next); err != nil {
+		return fmt.Errorf("scene %s: %w", id, err)
+	}
+	if err := checkHistory(ctx, prev, next); err != nil {
+		return fmt.Errorf("scene %s: %w", id, err)
+	}
+	return nil
+}
+
+// lifecycleOf returns the lifecycle of a scene, empty when it has none
+func lifecycleOf(sf *SceneFile) Lifecycle {
+	if sf.Metadata.Lifecycle == nil {
+		return Lifecycle{}
+	}
+	return *sf.Metadata.Lifecycle
+}
+
+// isPolicyUpdate reports whether the write was marked by WithPolicyUpdate
+func isPolicyUpdate(ctx context.Context) bool {
+	marked, _ := ctx.Value(policyUpdateKey{}).(bool)
+	return marked
+}
+
+// isLifecycleUpdate reports whether the write was marked by
+// WithLifecycleUpdate
+func isLifecycleUpdate(ctx context.Context) bool {
+	marked, _ := ctx.Value(lifecycleUpdateKey{}).(bool)
+	return marked
+}
+
+func (a Approval) equal(b Approval) bool {
+	return a.Actor == b.Actor && a.Time.Equal(b.Time) && a.Comment == b.Comment
+}
+
+func (t LifecycleTransition) equal(u LifecycleTransition) bool {
+	return t.From == u.From && t.To == u.To && t.Actor == u.Actor && t.Time.Equal(u.Time) && t.Comment == u.Comment
+}
+
+// contentChanged reports changes other than to the lifecycle and update
+// timestamp
+func contentChanged(prev, next *SceneFile) bool {
+	d := Diff(prev, next)
+	for _, field := range d.Fields {
+		if field != "metadata.lifecycle" && field != "metadata.updated" {
+			return true
+		}
+	}
+	return len(d.Nodes) > 0 || len(d.Edges) > 0
+}
+
+// checkPolicy rejects approval policy changes outside drafts and outside
+// policy updates
+func checkPolicy(ctx context.Context, prev, next *SceneFile) error {
+	prevLC, nextLC := lifecycleOf(prev), lifecycleOf(next)
+	if prevLC.samePolicy(&nextLC) {
+		return nil
+	}
+	if !isPolicyUpdate(ctx) {
+		return fmt.Errorf("%w: the approval policy changes only through policy updates", ErrChangeControlled)
+	}
+	if prev.LifecycleState() != LifecycleDraft || next.LifecycleState() != LifecycleDraft {
+		return fmt.Errorf("%w: the approval policy only changes while the scene is a draft", ErrChangeControlled)
+	}
+	return nil
+}
+
+// checkApprovals rejects approvals that were not made by the acting user,
+// that were rewritten or kept across a return to draft or review, and
+// self-approvals the policy does not allow
+func checkApprovals(ctx context.Context, prev, next *SceneFile) error {
+	prevLC, nextLC := lifecycleOf(prev), lifecycleOf(next)
+	before, after := prevLC.Approvals, nextLC.Approvals
+	from, to := prev.LifecycleState(), next.LifecycleState()
+	if from != to && (to == LifecycleDraft || to == LifecycleInReview) && len(after) > 0 {
+		return fmt.Errorf("%w: approvals must be cleared when the scene moves to %s", ErrChangeControlled, to)
+	}
+	if slices.EqualFunc(before, after, Approval.equal) {
+		return nil
+	}
+	if !isLifecycleUpdate(ctx) {
+		return fmt.Errorf("%w: approvals change only through approvals and transitions", ErrChangeControlled)
+	}
+	if from != to && (to == LifecycleDraft || to == LifecycleInReview) {
+		return nil
+	}
+	if len(after) < len(before) || !slices.EqualFunc(before, after[:len(before)], Approval.equal) {
+		return fmt.Errorf("%w: approvals cannot be removed or rewritten", ErrChangeControlled)
+	}
+	if from != LifecycleInReview || to != LifecycleInReview {
+		return fmt.Errorf("%w: approvals are only recorded while the scene is in review", ErrInvalidTransition)
+	}
+	actor := ActorFromContext(ctx)
+	for i, a := range after[len(before):] {
+		if a.Actor != actor {
+			return fmt.Errorf("%w: approval by %s must be made by %s", ErrApprovalRequired, a.Actor, a.Actor)
+		}
+		if len(nextLC.RequiredApprovers) > 0 && !slices.Contains(nextLC.RequiredApprovers, a.Actor) {
+			return fmt.Errorf("%w: %s is not an approver", ErrApprovalRequired, a.Actor)
+		}
+		if !nextLC.AllowSelfApproval && a.Actor == nextLC.submitter() {
+			return fmt.Errorf("%w: %s submitted the scene and may not approve it", ErrApprovalRequired, a.Actor)
+		}
+		if slices.ContainsFunc(after[:len(before)+i], func(b Approval) bool { return b.Actor == a.Actor }) {
+			return fmt.Errorf("%w: %s has already approved", ErrApprovalRequired, a.Actor)
+		}
+	}
+	return nil
+}
+
+// checkHistory rejects rewritten lifecycle history and transitions that are
+// not recorded exactly once, by the acting user
+func checkHistory(ctx context.Context, prev, next *SceneFile) error {
+	before, after := lifecycleOf(prev).History, lifecycleOf(next).History
+	from, to := prev.LifecycleState(), next.LifecycleState()
+	if from == to && slices.EqualFunc(before, after, LifecycleTransition.equal) {
+		return nil
+	}
+	if !isLifecycleUpdate(ctx) {
+		return fmt.Errorf("%w: lifecycle state and history change only through transitions", ErrChangeControlled)
+	}
+	if len(after) < len(before) || !slices.EqualFunc(before, after[:len(before)], LifecycleTransition.equal) {
+		return fmt.Errorf("%w: lifecycle history cannot be rewritten", ErrChangeControlled)
+	}
+	added := after[len(before):]
+	if from == to {
+		return fmt.Errorf("%w: history records a transition that did not happen", ErrChangeControlled)
+	}
+	if len(added) != 1 || added[0].From != from || added[0].To != to || added[0].Actor != ActorFromContext(ctx) {
+		return fmt.Errorf("%w: the move from %s to %s must be recorded once by the acting user", ErrChangeControlled, from, to)
+	}
+	return nil
+}
+
+// GuardedStore wraps a SceneStore and runs guards before every write. Guards
+// see the revision the write is based on, and the write is committed only
+// if that revision is still current, so concurrent writers cannot slip past
+// a guard.
+type GuardedStore struct {
+	SceneStore
+	Guards []WriteGuard
+}
+
+// NewGuardedStore wraps store with the given guards
+func NewGuardedStore(store SceneStore, guards ...WriteGuard) *GuardedStore {
+	return &GuardedStore{SceneStore: store, Guards: guards}
+}
+
+// Put runs the guards and stores the scene
+func (s *GuardedStore) Put(ctx context.Context, id string, sf SceneFile, expected int64) (SceneRevision, error) {
+	prev, err := s.current(ctx, id)
+	if err != nil {
+		return SceneRevision{}, err
+	}
+	var prevScene *SceneFile
+	var current int64
+	if prev != nil {
+		prevScene, current = &prev.Scene, prev.Revision
+	}
+	if expected != AnyRevision && expected != current {
+		// Let the underlying store report the conflict with its diff
+		return s.SceneStore.Put(ctx, id, sf, expected)
+	}
+	if err := s.check(ctx, id, prevScene, &sf); err != nil {
+		return SceneRevision{}, err
+	}
+	return s.SceneStore.Put(ctx, id, sf, current)
+}
+
+// Delete runs the guards and deletes the scene
+func (s *GuardedStore) Delete(ctx context.Context, id string, expected int64) error {
+	prev, err := s.current(ctx, id)
+	if err != nil {
+		return err
+	}
+	if prev == nil || (expected != AnyRevision && expected != prev.Revision) {
+		return s.SceneStore.Delete(ctx, id, expected)
+	}
+	if err := s.check(ctx, id, &prev.Scene, nil); err != nil {
+		return err
+	}
+	return s.SceneStore.Delete(ctx, id, prev.Revision)
+}
+
+// current returns the latest revision, or nil when the scene does not exist
+func (s *GuardedStore) current(ctx context.Context, id string) (*SceneRevision, error) {
+	rev, err := s.SceneStore.Get(ctx, id)
+	if errors.Is(err, ErrSceneNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (s *GuardedStore) check(ctx context.Context, id string, prev, next *SceneFile) error {
+	for _, guard := range s.Guards {
+		if err := guard(ctx, id, prev, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
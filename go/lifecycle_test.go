@@ -0,0 +1,266 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func newReviewScene() SceneFile {
+	sf := newDiffScene()
+	sf.Metadata.Lifecycle = &Lifecycle{State: LifecycleDraft, RequiredApprovers: []string{"bob"}, MinApprovals: 2}
+	return sf
+}
+
+// TestLifecycle_Transitions tests the review and publish workflow
+func TestLifecycle_Transitions(t *testing.T) {
+	sf := newReviewScene()
+	if err := sf.Transition(LifecyclePublished, "alice", ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected draft to published to fail, got %v", err)
+	}
+	if err := sf.Approve("bob", ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected approval of a draft to fail, got %v", err)
+	}
+	if err := sf.Transition(LifecycleInReview, "alice", "ready"); err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if err := sf.Approve("bob", "lgtm"); err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	if err := sf.Approve("carol", ""); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("expected non-approver to be rejected, got %v", err)
+	}
+	if err := sf.Transition(LifecyclePublished, "alice", ""); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("expected publish with one of two approvals to fail, got %v", err)
+	}
+
+	sf.Metadata.Lifecycle.RequiredApprovers = nil
+	if err := sf.Approve("carol", ""); err != nil {
+		t.Fatalf("second approval failed: %v", err)
+	}
+	if err := sf.Transition(LifecyclePublished, "alice", ""); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if sf.LifecycleState() != LifecyclePublished || len(sf.Metadata.Lifecycle.History) != 2 {
+		t.Errorf("state mismatch: got %s with %d transitions", sf.LifecycleState(), len(sf.Metadata.Lifecycle.History))
+	}
+
+	if err := sf.Transition(LifecycleDraft, "alice", "hotfix"); err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if len(sf.Metadata.Lifecycle.Approvals) != 0 {
+		t.Errorf("approvals should be cleared on reopen, got %v", sf.Metadata.Lifecycle.Approvals)
+	}
+}
+
+// TestLifecycleGuard tests change-control enforcement through a guarded store
+func TestLifecycleGuard(t *testing.T) {
+	ctx := context.Background()
+	store := NewGuardedStore(NewMemorySceneStore(), LifecycleGuard)
+	sf := newReviewScene()
+	sf.Metadata.Lifecycle.MinApprovals = 1
+	if _, err := store.Put(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	// Skipping review is rejected even when written directly
+	skip := sf
+	skip.Metadata.Lifecycle = &Lifecycle{State: LifecyclePublished}
+	if _, err := store.Put(ctx, "prod", skip, 1); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected invalid transition, got %v", err)
+	}
+
+	if err := sf.Transition(LifecycleInReview, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(ctx, "prod", sf, 1); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected transition outside a lifecycle update to fail, got %v", err)
+	}
+	alice := WithLifecycleUpdate(WithActor(ctx, "alice"))
+	if _, err := store.Put(alice, "prod", sf, 1); err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+
+	edited := sf
+	edited.Scene.Nodes = append([]SceneNode{}, sf.Scene.Nodes...)
+	edited.Scene.Nodes[0].Name = "changed during review"
+	if _, err := store.Put(ctx, "prod", edited, 2); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected change-controlled error, got %v", err)
+	}
+
+	forged := sf
+	lc := *sf.Metadata.Lifecycle
+	forged.Metadata.Lifecycle = &lc
+	if err := forged.Approve("bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(WithLifecycleUpdate(WithActor(ctx, "mallory")), "prod", forged, 2); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("expected forged approval to be rejected, got %v", err)
+	}
+	if _, err := store.Put(WithActor(ctx, "bob"), "prod", forged, 2); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected approval outside a lifecycle update to be rejected, got %v", err)
+	}
+	if _, err := store.Put(WithLifecycleUpdate(WithActor(ctx, "bob")), "prod", forged, 2); err != nil {
+		t.Fatalf("approval failed: %v", err)
+	}
+
+	if err := forged.Transition(LifecyclePublished, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(alice, "prod", forged, 3); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if err := store.Delete(ctx, "prod", 4); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected deleting a published scene to fail, got %v", err)
+	}
+	if _, err := store.Put(ctx, "prod", forged, 3); !errors.Is(err, ErrRevisionConflict) {
+		t.Errorf("expected stale write to conflict, got %v", err)
+	}
+}
+
+// TestLifecycleGuard_ApprovalPolicy tests that the approval policy only
+// changes in drafts through policy updates, and that submitters cannot
+// approve their own changes unless the policy allows it
+func TestLifecycleGuard_ApprovalPolicy(t *testing.T) {
+	ctx := context.Background()
+	alice := WithLifecycleUpdate(WithActor(ctx, "alice"))
+	store := NewGuardedStore(NewMemorySceneStore(), LifecycleGuard)
+	sf := newReviewScene()
+	if _, err := store.Put(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	cleared := sf
+	clc := *sf.Metadata.Lifecycle
+	cleared.Metadata.Lifecycle = &clc
+	if err := cleared.SetApprovalPolicy(nil, 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(ctx, "prod", cleared, 1); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected a plain write clearing the policy to fail, got %v", err)
+	}
+	if _, err := store.Put(alice, "prod", cleared, 1); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected a lifecycle update clearing the policy to fail, got %v", err)
+	}
+	if err := cleared.SetApprovalPolicy(nil, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(WithPolicyUpdate(ctx), "prod", cleared, 1); err != nil {
+		t.Fatalf("policy update failed: %v", err)
+	}
+
+	if err := cleared.Transition(LifecycleInReview, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(alice, "prod", cleared, 2); err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if err := cleared.SetApprovalPolicy(nil, 0, true); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected setting the policy in review to fail, got %v", err)
+	}
+	if err := cleared.Approve("alice", ""); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("expected self-approval to fail, got %v", err)
+	}
+	self := cleared
+	slc := *cleared.Metadata.Lifecycle
+	slc.Approvals = []Approval{{Actor: "alice"}}
+	self.Metadata.Lifecycle = &slc
+	if _, err := store.Put(alice, "prod", self, 3); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("expected a stored self-approval to fail, got %v", err)
+	}
+	slc.AllowSelfApproval = true
+	if _, err := store.Put(WithPolicyUpdate(alice), "prod", self, 3); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected allowing self-approval in review to fail, got %v", err)
+	}
+
+	opted := newReviewScene()
+	opted.Metadata.Lifecycle.AllowSelfApproval = true
+	if err := opted.Transition(LifecycleInReview, "bob", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := opted.Approve("bob", ""); err != nil {
+		t.Errorf("expected opted-in self-approval to succeed, got %v", err)
+	}
+}
+
+// TestLifecycleGuard_ReopenKeepsApprovals tests that approvals cannot be
+// carried through a return to draft into a second review, and that history
+// cannot be rewritten
+func TestLifecycleGuard_ReopenKeepsApprovals(t *testing.T) {
+	ctx := context.Background()
+	alice := WithLifecycleUpdate(WithActor(ctx, "alice"))
+	bob := WithLifecycleUpdate(WithActor(ctx, "bob"))
+	store := NewGuardedStore(NewMemorySceneStore(), LifecycleGuard)
+	sf := newReviewScene()
+	sf.Metadata.Lifecycle.MinApprovals = 1
+	if _, err := store.Put(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := sf.Transition(LifecycleInReview, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(alice, "prod", sf, 1); err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	if err := sf.Approve("bob", "lgtm"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(bob, "prod", sf, 2); err != nil {
+		t.Fatalf("approval failed: %v", err)
+	}
+
+	// Back to draft with the approvals kept, by a plain write or a forged
+	// lifecycle update
+	reopened := sf
+	lc := *sf.Metadata.Lifecycle
+	lc.State = LifecycleDraft
+	reopened.Metadata.Lifecycle = &lc
+	if _, err := store.Put(WithActor(ctx, "alice"), "prod", reopened, 3); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected plain reopen to fail, got %v", err)
+	}
+	lc.History = append(slices.Clone(lc.History), LifecycleTransition{From: LifecycleInReview, To: LifecycleDraft, Actor: "alice"})
+	if _, err := store.Put(alice, "prod", reopened, 3); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected reopen keeping approvals to fail, got %v", err)
+	}
+
+	// A proper reopen clears them, and the edited scene needs a fresh
+	// approval to publish
+	proper := sf
+	plc := *sf.Metadata.Lifecycle
+	proper.Metadata.Lifecycle = &plc
+	if err := proper.Transition(LifecycleDraft, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(alice, "prod", proper, 3); err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	edited, _ := store.Get(ctx, "prod")
+	edited.Scene.Scene.Nodes[0].Name = "edited"
+	if _, err := store.Put(ctx, "prod", edited.Scene, 4); err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	if err := edited.Scene.Transition(LifecycleInReview, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(alice, "prod", edited.Scene, 5); err != nil {
+		t.Fatalf("resubmit failed: %v", err)
+	}
+	stale := edited.Scene
+	slc := *edited.Scene.Metadata.Lifecycle
+	slc.State = LifecyclePublished
+	slc.Approvals = sf.Metadata.Lifecycle.Approvals
+	slc.History = append(slices.Clone(slc.History), LifecycleTransition{From: LifecycleInReview, To: LifecyclePublished, Actor: "alice"})
+	stale.Metadata.Lifecycle = &slc
+	if _, err := store.Put(alice, "prod", stale, 6); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected publishing with approvals from the previous review to fail, got %v", err)
+	}
+
+	rewritten := edited.Scene
+	rlc := *edited.Scene.Metadata.Lifecycle
+	rlc.History = rlc.History[1:]
+	rewritten.Metadata.Lifecycle = &rlc
+	if _, err := store.Put(alice, "prod", rewritten, 6); !errors.Is(err, ErrChangeControlled) {
+		t.Errorf("expected rewritten history to fail, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package starfleet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// =============================================================================
+// ACCESSIBILITY AND LOCALIZATION
+// =============================================================================
+
+// Accessibility represents assistive-technology metadata for a node
+type Accessibility struct {
+	AltText     string `json:"altText,omitempty"`
+	Description string `json:"description,omitempty"`
+	Role        string `json:"role,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+}
+
+// Localization represents locale-specific display text. Empty fields fall
+// back to the next matching locale and finally to the authored values.
+type Localization struct {
+	Name        string `json:"name,omitempty"`
+	Label       string `json:"label,omitempty"`
+	AltText     string `json:"altText,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// LocalizationMap represents localized text keyed by BCP 47 language tag
+type LocalizationMap map[string]Localization
+
+// normalizeLocale lowercases a language tag and uses '-' as separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localeChain returns the lookup order for a locale, from most to least
+// specific ("pt-br-x" -> "pt-br-x", "pt-br", "pt")
+func localeChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return chain
+}
+
+// Resolve merges the localizations that apply to a locale, preferring the
+// most specific match for each field
+func (m LocalizationMap) Resolve(locale string) Localization {
+	if len(m) == 0 {
+		return Localization{}
+	}
+	normalized := make(map[string]Localization, len(m))
+	for k, v := range m {
+		normalized[normalizeLocale(k)] = v
+	}
+
+	var out Localization
+	for _, tag := range localeChain(locale) {
+		l, ok := normalized[tag]
+		if !ok {
+			continue
+		}
+		out.Name = firstNonEmpty(out.Name, l.Name)
+		out.Label = firstNonEmpty(out.Label, l.Label)
+		out.AltText = firstNonEmpty(out.AltText, l.AltText)
+		out.Description = firstNonEmpty(out.Description, l.Description)
+	}
+	return out
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Localize returns a copy of the scene with node names, labels, alt text and
+// descriptions resolved for a locale, along with the scene name and
+// description. Elements without a matching localization keep their authored
+// text. The input scene is not modified.
+func Localize(sf SceneFile, locale string) SceneFile {
+	out := sf
+	out.Metadata.Localizations = nil
+	l := sf.Metadata.Localizations.Resolve(locale)
+	out.Metadata.Name = firstNonEmpty(l.Name, sf.Metadata.Name)
+	out.Metadata.Description = firstNonEmpty(l.Description, sf.Metadata.Description)
+	if locale != "" {
+		out.Metadata.Locale = locale
+	}
+
+	out.Scene.Nodes = make([]SceneNode, len(sf.Scene.Nodes))
+	for i, node := range sf.Scene.Nodes {
+		l := node.Localizations.Resolve(locale)
+		node.Localizations = nil
+		node.Name = firstNonEmpty(l.Name, node.Name)
+		if node.Label != nil && l.Label != "" {
+			label := *node.Label
+			label.Text = l.Label
+			node.Label = &label
+		}
+		if l.AltText != "" || l.Description != "" {
+			a := Accessibility{}
+			if node.Accessibility != nil {
+				a = *node.Accessibility
+			}
+			a.AltText = firstNonEmpty(l.AltText, a.AltText)
+			a.Description = firstNonEmpty(l.Description, a.Description)
+			node.Accessibility = &a
+		}
+		out.Scene.Nodes[i] = node
+	}
+
+	out.Scene.Edges = make([]SceneEdge, len(sf.Scene.Edges))
+	for i, edge := range sf.Scene.Edges {
+		l := edge.Localizations.Resolve(locale)
+		edge.Localizations = nil
+		if edge.Label != nil && l.Label != "" {
+			label := *edge.Label
+			label.Text = l.Label
+			edge.Label = &label
+		}
+		out.Scene.Edges[i] = edge
+	}
+	return out
+}
+
+// AccessibilityWarnings reports visible nodes that assistive technology
+// cannot describe: nodes without alt text whose name is empty or equal to
+// their ID
+func AccessibilityWarnings(sf *SceneFile) []string {
+	var warnings []string
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		if node.Accessibility != nil && (node.Accessibility.Hidden || node.Accessibility.AltText != "") {
+			continue
+		}
+		if node.Name == "" || node.Name == node.ID {
+			warnings = append(warnings, fmt.Sprintf("Node %s has no alt text or descriptive name", node.ID))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,68 @@
+package starfleet
+
+import "testing"
+
+// newLocalizedScene creates a scene with German and Brazilian Portuguese text
+func newLocalizedScene() SceneFile {
+	sf := NewSceneFile("Payments")
+	sf.Metadata.Localizations = LocalizationMap{"de": {Name: "Zahlungen"}}
+	sf.AddNode(SceneNode{
+		ID:        "db",
+		Type:      "database",
+		Name:      "Primary database",
+		Transform: NewTransform(),
+		Label:     &Label{Text: "Primary"},
+		Localizations: LocalizationMap{
+			"de":    {Name: "Primäre Datenbank", Label: "Primär", AltText: "Datenbank-Zylinder"},
+			"pt":    {Name: "Banco de dados primário", AltText: "Cilindro"},
+			"pt-BR": {Label: "Primário"},
+		},
+	})
+	return sf
+}
+
+// TestLocalize tests locale resolution with fallback
+func TestLocalize(t *testing.T) {
+	sf := newLocalizedScene()
+
+	de := Localize(sf, "de_DE")
+	node := de.FindNode("db")
+	if node.Name != "Primäre Datenbank" || node.Label.Text != "Primär" {
+		t.Errorf("German node mismatch: got %s / %s", node.Name, node.Label.Text)
+	}
+	if node.Accessibility == nil || node.Accessibility.AltText != "Datenbank-Zylinder" {
+		t.Errorf("German alt text mismatch: got %+v", node.Accessibility)
+	}
+	if de.Metadata.Name != "Zahlungen" || de.Metadata.Locale != "de_DE" {
+		t.Errorf("German metadata mismatch: got %+v", de.Metadata)
+	}
+
+	pt := Localize(sf, "pt-BR")
+	node = pt.FindNode("db")
+	if node.Name != "Banco de dados primário" || node.Label.Text != "Primário" {
+		t.Errorf("Portuguese fallback mismatch: got %s / %s", node.Name, node.Label.Text)
+	}
+
+	fr := Localize(sf, "fr")
+	if fr.FindNode("db").Name != "Primary database" || fr.Metadata.Name != "Payments" {
+		t.Errorf("Unknown locale should keep authored text")
+	}
+
+	original := sf.FindNode("db")
+	if original.Name != "Primary database" || original.Label.Text != "Primary" || original.Accessibility != nil {
+		t.Errorf("Localize modified the input scene: %+v", original)
+	}
+}
+
+// TestAccessibilityWarnings tests detection of undescribed nodes
+func TestAccessibilityWarnings(t *testing.T) {
+	sf := NewSceneFile("A11y")
+	sf.AddNode(SceneNode{ID: "n1", Type: "server", Name: "n1", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "n2", Type: "server", Name: "Web server", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "n3", Type: "server", Transform: NewTransform(), Accessibility: &Accessibility{AltText: "Cache"}})
+
+	warnings := AccessibilityWarnings(&sf)
+	if len(warnings) != 1 {
+		t.Errorf("AccessibilityWarnings count mismatch: got %d, want 1: %v", len(warnings), warnings)
+	}
+}
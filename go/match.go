@@ -0,0 +1,310 @@
+package starfleet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// FUZZY SCENE MATCHING
+// =============================================================================
+
+// DefaultMatchThreshold is the similarity a fuzzy node match needs when
+// MatchOptions leaves it unset
+const DefaultMatchThreshold = 0.6
+
+// MatchOptions controls how MatchScenes pairs nodes whose IDs differ
+type MatchOptions struct {
+	// Threshold is the lowest similarity, from 0 to 1, accepted as a match;
+	// zero means DefaultMatchThreshold
+	Threshold float64 `json:"threshold,omitempty" validate:"omitempty,gt=0,lte=1"`
+	// IdentityKeys are metadata keys, such as a cloud resource ID, whose
+	// equal values identify the same node outright
+	IdentityKeys []string `json:"identityKeys,omitempty"`
+	// MetadataKeys limits the metadata compared to these keys; empty means
+	// all of it
+	MetadataKeys []string `json:"metadataKeys,omitempty"`
+}
+
+// SceneMatch is the correspondence between two scenes found by MatchScenes.
+// Mapping renames elements of the changed scene to the IDs of their base
+// counterparts and holds only IDs that differ; elements with equal IDs
+// always correspond.
+type SceneMatch struct {
+	Mapping IDMapping `json:"mapping"`
+	// Scores holds the similarity of every fuzzy node match by changed ID
+	Scores map[string]float64 `json:"scores,omitempty"`
+	// UnmatchedBase and UnmatchedChanged list nodes without a counterpart,
+	// which a diff reports as removed and added
+	UnmatchedBase    []string `json:"unmatchedBase,omitempty"`
+	UnmatchedChanged []string `json:"unmatchedChanged,omitempty"`
+}
+
+// MatchScenes aligns the nodes and edges of two versions of a scene whose
+// importer changed its ID scheme. Nodes sharing an ID correspond. The rest
+// are paired by similarity: an equal identity key decides outright, and
+// otherwise names, IDs and metadata are compared between nodes of the same
+// type, and a second round adds agreement between already matched
+// neighbours. Pairs at or above the threshold are taken best first, so each
+// node matches at most once. Edges whose IDs differ then correspond when
+// they join corresponding nodes with the same type.
+func MatchScenes(base, changed *SceneFile, opts MatchOptions) (SceneMatch, error) {
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = DefaultMatchThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return SceneMatch{}, fmt.Errorf("match scenes: threshold %v is outside (0, 1]", threshold)
+	}
+	m := SceneMatch{Mapping: IDMapping{Nodes: map[string]string{}, Edges: map[string]string{}}, Scores: map[string]float64{}}
+
+	baseIDs := make(map[string]bool, len(base.Scene.Nodes))
+	for i := range base.Scene.Nodes {
+		baseIDs[base.Scene.Nodes[i].ID] = true
+	}
+	changedIDs := make(map[string]bool, len(changed.Scene.Nodes))
+	for i := range changed.Scene.Nodes {
+		changedIDs[changed.Scene.Nodes[i].ID] = true
+	}
+	// Only nodes whose IDs the other scene lacks are candidates, so no
+	// rename can collide with a kept ID
+	var baseOpen, changedOpen []*SceneNode
+	for i := range base.Scene.Nodes {
+		if n := &base.Scene.Nodes[i]; !changedIDs[n.ID] {
+			baseOpen = append(baseOpen, n)
+		}
+	}
+	for i := range changed.Scene.Nodes {
+		if n := &changed.Scene.Nodes[i]; !baseIDs[n.ID] {
+			changedOpen = append(changedOpen, n)
+		}
+	}
+
+	matched := make(map[string]bool)
+	assign := func(scored []matchCandidate) {
+		sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+		for _, c := range scored {
+			if c.score < threshold || matched[c.base.ID] || matched[c.changed.ID] {
+				continue
+			}
+			matched[c.base.ID], matched[c.changed.ID] = true, true
+			m.Mapping.Nodes[c.changed.ID] = c.base.ID
+			m.Scores[c.changed.ID] = c.score
+		}
+	}
+	// corresponding maps a changed node ID to its base ID, or to itself
+	corresponding := func(id string) string { return remapID(m.Mapping.Nodes, id) }
+
+	candidates := func(structural bool) []matchCandidate {
+		var neighbours, changedNeighbours map[string]map[string]bool
+		if structural {
+			neighbours, changedNeighbours = matchNeighbours(base, nil), matchNeighbours(changed, corresponding)
+		}
+		var scored []matchCandidate
+		for _, c := range changedOpen {
+			if matched[c.ID] {
+				continue
+			}
+			for _, b := range baseOpen {
+				if matched[b.ID] {
+					continue
+				}
+				score, ok := nodeSimilarity(b, c, opts)
+				if !ok {
+					continue
+				}
+				if structural && score < 1 {
+					if agree, ok := setSimilarity(neighbours[b.ID], changedNeighbours[c.ID]); ok {
+						score = 0.7*score + 0.3*agree
+					}
+				}
+				scored = append(scored, matchCandidate{b, c, score})
+			}
+		}
+		return scored
+	}
+	assign(candidates(false))
+	assign(candidates(true))
+
+	for _, n := range baseOpen {
+		if !matched[n.ID] {
+			m.UnmatchedBase = append(m.UnmatchedBase, n.ID)
+		}
+	}
+	for _, n := range changedOpen {
+		if !matched[n.ID] {
+			m.UnmatchedChanged = append(m.UnmatchedChanged, n.ID)
+		}
+	}
+	matchEdges(base, changed, corresponding, m.Mapping.Edges)
+	return m, nil
+}
+
+// matchCandidate is a scored pairing of a base and a changed node
+type matchCandidate struct {
+	base, changed *SceneNode
+	score         float64
+}
+
+// nodeSimilarity scores how alike two nodes are, and reports false for
+// nodes that cannot correspond
+func nodeSimilarity(b, c *SceneNode, opts MatchOptions) (float64, bool) {
+	for _, key := range opts.IdentityKeys {
+		bv, bok := b.Metadata[key]
+		cv, cok := c.Metadata[key]
+		if bok && cok && fmt.Sprint(bv) != "" {
+			if fmt.Sprint(bv) == fmt.Sprint(cv) {
+				return 1, true
+			}
+			return 0, false
+		}
+	}
+	if b.Type != c.Type {
+		return 0, false
+	}
+
+	// Each signal counts only when both nodes carry it
+	var total, weight float64
+	if b.Name != "" && c.Name != "" {
+		total += 0.5 * textSimilarity(b.Name, c.Name)
+		weight += 0.5
+	}
+	total += 0.2 * textSimilarity(b.ID, c.ID)
+	weight += 0.2
+	if sim, ok := setSimilarity(metadataPairs(b, opts.MetadataKeys), metadataPairs(c, opts.MetadataKeys)); ok {
+		total += 0.3 * sim
+		weight += 0.3
+	}
+	return total / weight, true
+}
+
+// textSimilarity compares two labels by their lower-case words, taking the
+// better of word overlap and edit distance
+func textSimilarity(a, b string) float64 {
+	wa, wb := searchWords(a), searchWords(b)
+	ja, jb := strings.Join(wa, " "), strings.Join(wb, " ")
+	longest := max(len([]rune(ja)), len([]rune(jb)))
+	if longest == 0 {
+		return 0
+	}
+	edit := 1 - float64(editDistance(ja, jb, longest))/float64(longest)
+	words, _ := setSimilarity(stringSet(wa), stringSet(wb))
+	return max(edit, words)
+}
+
+// metadataPairs returns a node's metadata and tags as "key=value" strings
+func metadataPairs(n *SceneNode, keys []string) map[string]bool {
+	pairs := make(map[string]bool, len(n.Metadata)+len(n.Tags))
+	if len(keys) == 0 {
+		for k, v := range n.Metadata {
+			pairs[k+"="+fmt.Sprint(v)] = true
+		}
+		for _, tag := range n.Tags {
+			pairs["#"+tag] = true
+		}
+		return pairs
+	}
+	for _, k := range keys {
+		if v, ok := n.Metadata[k]; ok {
+			pairs[k+"="+fmt.Sprint(v)] = true
+		}
+	}
+	return pairs
+}
+
+// stringSet returns the distinct strings of a slice
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// setSimilarity returns the Jaccard index of two sets, and reports false
+// when either is empty
+func setSimilarity(a, b map[string]bool) (float64, bool) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, false
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared), true
+}
+
+// matchNeighbours returns the IDs adjacent to each node, renamed by rename
+// when given
+func matchNeighbours(sf *SceneFile, rename func(string) string) map[string]map[string]bool {
+	if rename == nil {
+		rename = func(id string) string { return id }
+	}
+	out := make(map[string]map[string]bool, len(sf.Scene.Nodes))
+	add := func(from, to string) {
+		if out[from] == nil {
+			out[from] = make(map[string]bool)
+		}
+		out[from][rename(to)] = true
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		add(e.Source, e.Target)
+		add(e.Target, e.Source)
+	}
+	return out
+}
+
+// matchEdges maps changed edges whose IDs the base lacks onto base edges
+// joining the corresponding endpoints with the same type, pairing parallel
+// edges in scene order
+func matchEdges(base, changed *SceneFile, corresponding func(string) string, mapping map[string]string) {
+	baseIDs := make(map[string]bool, len(base.Scene.Edges))
+	for i := range base.Scene.Edges {
+		baseIDs[base.Scene.Edges[i].ID] = true
+	}
+	changedIDs := make(map[string]bool, len(changed.Scene.Edges))
+	for i := range changed.Scene.Edges {
+		changedIDs[changed.Scene.Edges[i].ID] = true
+	}
+	type key struct{ source, target, typ string }
+	open := make(map[key][]string)
+	for i := range base.Scene.Edges {
+		if e := &base.Scene.Edges[i]; !changedIDs[e.ID] {
+			k := key{e.Source, e.Target, e.Type}
+			open[k] = append(open[k], e.ID)
+		}
+	}
+	for i := range changed.Scene.Edges {
+		e := &changed.Scene.Edges[i]
+		if baseIDs[e.ID] {
+			continue
+		}
+		k := key{corresponding(e.Source), corresponding(e.Target), e.Type}
+		if ids := open[k]; len(ids) > 0 {
+			mapping[e.ID] = ids[0]
+			open[k] = ids[1:]
+		}
+	}
+}
+
+// AlignScene returns a copy of changed with the IDs of the elements
+// MatchScenes pairs with base renamed to their base IDs, ready for Diff,
+// InterpolateScenes or Reconcile, which match by ID. Neither input is
+// modified.
+func AlignScene(base, changed *SceneFile, opts MatchOptions) (SceneFile, SceneMatch, error) {
+	m, err := MatchScenes(base, changed, opts)
+	if err != nil {
+		return SceneFile{}, SceneMatch{}, err
+	}
+	out := *changed
+	out.Scene.Nodes = append([]SceneNode(nil), changed.Scene.Nodes...)
+	out.Scene.Edges = append([]SceneEdge(nil), changed.Scene.Edges...)
+	if err := RemapIDs(&out, m.Mapping); err != nil {
+		return SceneFile{}, SceneMatch{}, fmt.Errorf("align scene: %w", err)
+	}
+	return out, m, nil
+}
@@ -0,0 +1,108 @@
+package starfleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newMatchScenes returns a scene and its re-import under a new ID scheme
+func newMatchScenes() (SceneFile, SceneFile) {
+	base := SceneFile{Version: "0.2.0"}
+	base.AddNode(SceneNode{ID: "svc-1", Type: "service", Name: "API Gateway"})
+	base.AddNode(SceneNode{ID: "svc-2", Type: "database", Name: "Orders DB", Metadata: map[string]interface{}{"engine": "postgres"}})
+	base.AddNode(SceneNode{ID: "svc-3", Type: "queue", Metadata: map[string]interface{}{"arn": "arn:aws:sqs:orders"}})
+	base.AddNode(SceneNode{ID: "svc-4", Type: "service", Name: "Billing"})
+	base.AddNode(SceneNode{ID: "shared", Type: "service", Name: "Shared"})
+	base.AddEdge(SceneEdge{ID: "e1", Source: "svc-1", Target: "svc-2", Type: "sql"})
+	base.AddEdge(SceneEdge{ID: "e2", Source: "svc-1", Target: "svc-3"})
+	base.AddEdge(SceneEdge{ID: "e3", Source: "shared", Target: "svc-1"})
+
+	changed := SceneFile{Version: "0.2.0"}
+	changed.AddNode(SceneNode{ID: "api-gateway", Type: "service", Name: "API gateway"})
+	changed.AddNode(SceneNode{ID: "orders-db", Type: "database", Name: "Orders DB", Metadata: map[string]interface{}{"engine": "postgres"}})
+	changed.AddNode(SceneNode{ID: "orders-queue", Type: "queue", Metadata: map[string]interface{}{"arn": "arn:aws:sqs:orders"}})
+	changed.AddNode(SceneNode{ID: "search", Type: "service", Name: "Search"})
+	changed.AddNode(SceneNode{ID: "shared", Type: "service", Name: "Shared"})
+	changed.AddEdge(SceneEdge{ID: "api-gateway->orders-db", Source: "api-gateway", Target: "orders-db", Type: "sql"})
+	changed.AddEdge(SceneEdge{ID: "api-gateway->orders-queue", Source: "api-gateway", Target: "orders-queue"})
+	changed.AddEdge(SceneEdge{ID: "e3", Source: "shared", Target: "api-gateway"})
+	return base, changed
+}
+
+// TestMatchScenes tests pairing nodes and edges across an ID scheme change
+func TestMatchScenes(t *testing.T) {
+	base, changed := newMatchScenes()
+	m, err := MatchScenes(&base, &changed, MatchOptions{IdentityKeys: []string{"arn"}})
+	if err != nil {
+		t.Fatalf("MatchScenes failed: %v", err)
+	}
+	want := IDMapping{
+		Nodes: map[string]string{"api-gateway": "svc-1", "orders-db": "svc-2", "orders-queue": "svc-3"},
+		Edges: map[string]string{"api-gateway->orders-db": "e1", "api-gateway->orders-queue": "e2"},
+	}
+	if !reflect.DeepEqual(m.Mapping, want) {
+		t.Errorf("mapping mismatch: got %+v, want %+v", m.Mapping, want)
+	}
+	if m.Scores["orders-queue"] != 1 || m.Scores["orders-db"] < m.Scores["api-gateway"] {
+		t.Errorf("scores mismatch: got %v", m.Scores)
+	}
+	if !reflect.DeepEqual(m.UnmatchedBase, []string{"svc-4"}) || !reflect.DeepEqual(m.UnmatchedChanged, []string{"search"}) {
+		t.Errorf("unmatched mismatch: got %v and %v", m.UnmatchedBase, m.UnmatchedChanged)
+	}
+
+	// A differing identity key rules a pair out however alike it looks
+	changed.Scene.Nodes[1].Metadata["arn"] = "arn:aws:rds:other"
+	base.Scene.Nodes[1].Metadata["arn"] = "arn:aws:rds:orders"
+	if m, _ := MatchScenes(&base, &changed, MatchOptions{IdentityKeys: []string{"arn"}}); m.Mapping.Nodes["orders-db"] != "" {
+		t.Errorf("mapping mismatch: got %v for a differing identity", m.Mapping.Nodes["orders-db"])
+	}
+	if _, err := MatchScenes(&base, &changed, MatchOptions{Threshold: 1.5}); err == nil {
+		t.Error("expected error for threshold above 1")
+	}
+}
+
+// TestMatchScenes_Structure tests neighbours deciding a match the node
+// alone cannot
+func TestMatchScenes_Structure(t *testing.T) {
+	base, changed := newMatchScenes()
+	base.AddNode(SceneNode{ID: "svc-5", Type: "worker", Metadata: map[string]interface{}{"pool": "a"}})
+	base.AddEdge(SceneEdge{ID: "e4", Source: "svc-5", Target: "svc-2"})
+	changed.AddNode(SceneNode{ID: "runner", Type: "worker", Metadata: map[string]interface{}{"pool": "a", "size": 3}})
+	changed.AddEdge(SceneEdge{ID: "runner->orders-db", Source: "runner", Target: "orders-db"})
+
+	m, err := MatchScenes(&base, &changed, MatchOptions{Threshold: 0.5})
+	if err != nil {
+		t.Fatalf("MatchScenes failed: %v", err)
+	}
+	if m.Mapping.Nodes["runner"] != "svc-5" || m.Mapping.Edges["runner->orders-db"] != "e4" {
+		t.Errorf("mapping mismatch: got %+v", m.Mapping)
+	}
+	score, _ := nodeSimilarity(&base.Scene.Nodes[5], &changed.Scene.Nodes[5], MatchOptions{})
+	if score >= 0.5 {
+		t.Errorf("similarity mismatch: got %v, want below 0.5 without neighbours", score)
+	}
+}
+
+// TestAlignScene tests diffing a re-import after aligning its IDs
+func TestAlignScene(t *testing.T) {
+	base, changed := newMatchScenes()
+	aligned, m, err := AlignScene(&base, &changed, MatchOptions{IdentityKeys: []string{"arn"}})
+	if err != nil {
+		t.Fatalf("AlignScene failed: %v", err)
+	}
+	if changed.Scene.Nodes[0].ID != "api-gateway" {
+		t.Error("changed scene was modified")
+	}
+	if aligned.FindEdge("e3").Target != "svc-1" || len(m.UnmatchedChanged) != 1 {
+		t.Errorf("aligned mismatch: got %+v", aligned.FindEdge("e3"))
+	}
+	d := Diff(&base, &aligned)
+	kinds := map[string]ChangeKind{}
+	for _, c := range d.Nodes {
+		kinds[c.ID] = c.Kind
+	}
+	want := map[string]ChangeKind{"svc-1": ChangeModified, "svc-4": ChangeRemoved, "search": ChangeAdded}
+	if !reflect.DeepEqual(kinds, want) || len(d.Edges) != 0 {
+		t.Errorf("diff mismatch: got %v and edges %v, want %v", kinds, d.Edges, want)
+	}
+}
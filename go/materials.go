@@ -0,0 +1,239 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// =============================================================================
+// MATERIAL PRESETS
+// =============================================================================
+
+// ErrUnknownPreset is returned for material presets that do not exist
+var ErrUnknownPreset = errors.New("unknown material preset")
+
+// Material preset names
+const (
+	PresetGlass          = "glass"
+	PresetFrostedGlass   = "frosted-glass"
+	PresetBrushedMetal   = "brushed-metal"
+	PresetPolishedMetal  = "polished-metal"
+	PresetMatte          = "matte"
+	PresetPlastic        = "plastic"
+	PresetHolographic    = "holographic"
+	PresetStatusHealthy  = "status-healthy"
+	PresetStatusWarning  = "status-warning"
+	PresetStatusCritical = "status-critical"
+	PresetStatusUnknown  = "status-unknown"
+)
+
+// Colors that mark node status, shared by the status presets and
+// WithStatusEmissive
+var (
+	StatusHealthyColor  = NewColor(0.2, 0.8, 0.3)
+	StatusWarningColor  = NewColor(0.98, 0.66, 0.15)
+	StatusCriticalColor = NewColor(0.83, 0.18, 0.18)
+	StatusUnknownColor  = NewColor(0.6, 0.6, 0.6)
+)
+
+// statusEmissiveIntensity is how strongly each status glows, so problems
+// stand out more the worse they are
+var statusEmissiveIntensity = map[NodeStatus]float64{
+	NodeStatusHealthy:  0.15,
+	NodeStatusWarning:  0.45,
+	NodeStatusCritical: 0.8,
+	NodeStatusUnknown:  0.1,
+}
+
+// materialPresets are the built-in presets; MaterialPreset hands out copies
+var materialPresets = map[string]Material{
+	PresetGlass: {
+		Color:       &Color{R: 0.85, G: 0.92, B: 1, A: 1},
+		Roughness:   0.05,
+		Opacity:     0.3,
+		Transparent: true,
+	},
+	PresetFrostedGlass: {
+		Color:       &Color{R: 0.9, G: 0.95, B: 1, A: 1},
+		Roughness:   0.6,
+		Opacity:     0.55,
+		Transparent: true,
+	},
+	PresetBrushedMetal: {
+		Color:     &Color{R: 0.7, G: 0.72, B: 0.75, A: 1},
+		Metalness: 1,
+		Roughness: 0.45,
+		Opacity:   1,
+	},
+	PresetPolishedMetal: {
+		Color:     &Color{R: 0.92, G: 0.92, B: 0.94, A: 1},
+		Metalness: 1,
+		Roughness: 0.1,
+		Opacity:   1,
+	},
+	PresetMatte: {
+		Color:     &Color{R: 0.8, G: 0.8, B: 0.8, A: 1},
+		Roughness: 1,
+		Opacity:   1,
+	},
+	PresetPlastic: {
+		Color:     &Color{R: 0.8, G: 0.8, B: 0.8, A: 1},
+		Roughness: 0.35,
+		Opacity:   1,
+	},
+	PresetHolographic: {
+		Color:       &Color{R: 0.3, G: 0.85, B: 1, A: 1},
+		Emissive:    &Color{R: 0.1, G: 0.45, B: 0.6, A: 1},
+		Metalness:   0.3,
+		Roughness:   0.2,
+		Opacity:     0.6,
+		Transparent: true,
+	},
+	PresetStatusHealthy:  statusPreset(NodeStatusHealthy),
+	PresetStatusWarning:  statusPreset(NodeStatusWarning),
+	PresetStatusCritical: statusPreset(NodeStatusCritical),
+	PresetStatusUnknown:  statusPreset(NodeStatusUnknown),
+}
+
+// statusPreset is a plastic material in a status color, glowing with it
+func statusPreset(status NodeStatus) Material {
+	c := StatusColor(status)
+	return Material{Color: &c, Roughness: 0.5, Opacity: 1}.WithStatusEmissive(status)
+}
+
+// MaterialPreset returns a copy of a built-in material preset, which the
+// caller may modify
+func MaterialPreset(name string) (Material, error) {
+	m, ok := materialPresets[name]
+	if !ok {
+		return Material{}, fmt.Errorf("%w: %s", ErrUnknownPreset, name)
+	}
+	return m.clone(), nil
+}
+
+// MaterialPresetNames lists the built-in material presets, sorted
+func MaterialPresetNames() []string {
+	names := make([]string, 0, len(materialPresets))
+	for name := range materialPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddMaterialPreset copies a preset into the scene's material library under
+// its name, so nodes can share it through MaterialRef. A library entry of
+// that name is replaced.
+func (sf *SceneFile) AddMaterialPreset(name string) error {
+	m, err := MaterialPreset(name)
+	if err != nil {
+		return err
+	}
+	if sf.Materials == nil {
+		sf.Materials = make(map[string]Material)
+	}
+	sf.Materials[name] = m
+	return nil
+}
+
+// StatusColor returns the color marking a node status; statuses other than
+// healthy, warning and critical get StatusUnknownColor
+func StatusColor(status NodeStatus) Color {
+	switch status {
+	case NodeStatusHealthy:
+		return StatusHealthyColor
+	case NodeStatusWarning:
+		return StatusWarningColor
+	case NodeStatusCritical:
+		return StatusCriticalColor
+	}
+	return StatusUnknownColor
+}
+
+// WithOpacity returns a copy of the material with the given opacity,
+// clamped to [0, 1], marked transparent when below 1
+func (m Material) WithOpacity(opacity float64) Material {
+	out := m.clone()
+	out.Opacity = max(0, min(1, opacity))
+	out.Transparent = out.Opacity < 1
+	return out
+}
+
+// Brighten returns a copy of the material with its color moved towards
+// white by amount, or towards black for a negative amount; 1 and -1 reach
+// them. A material without a color starts from the NewMaterial gray.
+func (m Material) Brighten(amount float64) Material {
+	out := m.clone()
+	c := NewColor(0.8, 0.8, 0.8)
+	if out.Color != nil {
+		c = *out.Color
+	}
+	amount = max(-1, min(1, amount))
+	shift := func(v float64) float64 {
+		if amount >= 0 {
+			return v + (1-v)*amount
+		}
+		return v * (1 + amount)
+	}
+	c.R, c.G, c.B = shift(c.R), shift(c.G), shift(c.B)
+	out.Color = &c
+	return out
+}
+
+// WithStatusEmissive returns a copy of the material glowing in the color of
+// a status, brighter for worse statuses. An empty status removes the glow.
+func (m Material) WithStatusEmissive(status NodeStatus) Material {
+	out := m.clone()
+	if status == "" {
+		out.Emissive = nil
+		return out
+	}
+	intensity, ok := statusEmissiveIntensity[status]
+	if !ok {
+		intensity = statusEmissiveIntensity[NodeStatusUnknown]
+	}
+	c := StatusColor(status)
+	c.R, c.G, c.B = c.R*intensity, c.G*intensity, c.B*intensity
+	out.Emissive = &c
+	return out
+}
+
+// ApplyStatusEmissive makes every node with a status glow in its status
+// color, as WithStatusEmissive does, and returns how many nodes it changed.
+// Node materials are replaced rather than modified, since they may be
+// shared through the library.
+func ApplyStatusEmissive(sf *SceneFile) int {
+	changed := 0
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Status == "" {
+			continue
+		}
+		m := Material{}
+		if resolved := sf.ResolveMaterial(n); resolved != nil {
+			m = *resolved
+		}
+		m = m.WithStatusEmissive(n.Status)
+		n.Material, n.MaterialRef = &m, ""
+		changed++
+	}
+	return changed
+}
+
+// clone copies a material and the colors it points to
+func (m Material) clone() Material {
+	if m.Color != nil {
+		c := *m.Color
+		m.Color = &c
+	}
+	if m.Emissive != nil {
+		c := *m.Emissive
+		m.Emissive = &c
+	}
+	if m.TextureRegion != nil {
+		r := *m.TextureRegion
+		m.TextureRegion = &r
+	}
+	return m
+}
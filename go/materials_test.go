@@ -0,0 +1,111 @@
+package starfleet
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestMaterialPreset tests that presets are valid copies
+func TestMaterialPreset(t *testing.T) {
+	names := MaterialPresetNames()
+	if len(names) != 11 || !slices.IsSorted(names) || !slices.Contains(names, PresetHolographic) {
+		t.Errorf("names mismatch: got %v", names)
+	}
+
+	sf := NewSceneFile("Presets")
+	for i, name := range names {
+		m, err := MaterialPreset(name)
+		if err != nil {
+			t.Fatalf("MaterialPreset(%s) failed: %v", name, err)
+		}
+		if m.Color == nil || m.Opacity <= 0 || m.Transparent != (m.Opacity < 1) {
+			t.Errorf("%s mismatch: got %+v", name, m)
+		}
+		sf.AddNode(SceneNode{ID: name, Type: "server", Name: name, Transform: NewTransformWithPosition(float64(i), 0, 0), Material: &m})
+	}
+	if r := ValidateScene(&sf); !r.Valid {
+		t.Errorf("scene is invalid: %v", r.Errors)
+	}
+
+	glass, _ := MaterialPreset(PresetGlass)
+	glass.Color.R = 0
+	if again, _ := MaterialPreset(PresetGlass); again.Color.R == 0 {
+		t.Error("presets should be handed out as copies")
+	}
+	if critical, _ := MaterialPreset(PresetStatusCritical); *critical.Color != StatusCriticalColor || critical.Emissive == nil {
+		t.Errorf("status preset mismatch: got %+v", critical)
+	}
+	if _, err := MaterialPreset("chrome"); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("expected ErrUnknownPreset, got %v", err)
+	}
+
+	if err := sf.AddMaterialPreset(PresetBrushedMetal); err != nil || sf.Materials[PresetBrushedMetal].Metalness != 1 {
+		t.Errorf("AddMaterialPreset mismatch: got %+v, %v", sf.Materials, err)
+	}
+	if err := sf.AddMaterialPreset("chrome"); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("expected ErrUnknownPreset, got %v", err)
+	}
+}
+
+// TestMaterial_Helpers tests the copy-returning material helpers
+func TestMaterial_Helpers(t *testing.T) {
+	base := Material{Color: &Color{R: 0.5, G: 0.25, B: 1, A: 1}, Opacity: 1}
+
+	if m := base.WithOpacity(0.4); m.Opacity != 0.4 || !m.Transparent || base.Opacity != 1 {
+		t.Errorf("WithOpacity mismatch: got %+v", m)
+	}
+	if m := base.WithOpacity(3); m.Opacity != 1 || m.Transparent {
+		t.Errorf("WithOpacity should clamp: got %+v", m)
+	}
+
+	bright := base.Brighten(0.5)
+	if want := (Color{R: 0.75, G: 0.625, B: 1, A: 1}); *bright.Color != want {
+		t.Errorf("Brighten mismatch: got %+v, want %+v", *bright.Color, want)
+	}
+	if dark := base.Brighten(-0.5); *dark.Color != (Color{R: 0.25, G: 0.125, B: 0.5, A: 1}) {
+		t.Errorf("darken mismatch: got %+v", *dark.Color)
+	}
+	if white := (Material{}).Brighten(2); *white.Color != NewColor(1, 1, 1) {
+		t.Errorf("Brighten should clamp: got %+v", *white.Color)
+	}
+	if base.Color.R != 0.5 {
+		t.Error("helpers should not modify the material")
+	}
+
+	warning := base.WithStatusEmissive(NodeStatusWarning)
+	critical := base.WithStatusEmissive(NodeStatusCritical)
+	if warning.Emissive == nil || critical.Emissive.R <= warning.Emissive.R || base.Emissive != nil {
+		t.Errorf("emissive mismatch: got %+v and %+v", warning.Emissive, critical.Emissive)
+	}
+	if odd := base.WithStatusEmissive("degraded"); odd.Emissive == nil || odd.Emissive.R != StatusUnknownColor.R*0.1 {
+		t.Errorf("unknown status emissive mismatch: got %+v", odd.Emissive)
+	}
+	if cleared := critical.WithStatusEmissive(""); cleared.Emissive != nil {
+		t.Errorf("empty status should clear emissive: got %+v", cleared.Emissive)
+	}
+}
+
+// TestApplyStatusEmissive tests restyling nodes by status without touching
+// shared library materials
+func TestApplyStatusEmissive(t *testing.T) {
+	sf := newDiffScene()
+	sf.AddMaterialPreset(PresetBrushedMetal)
+	sf.Scene.Nodes[0].Status = NodeStatusCritical
+	sf.Scene.Nodes[0].MaterialRef = PresetBrushedMetal
+	sf.Scene.Nodes[1].Status = NodeStatusHealthy
+
+	if got := ApplyStatusEmissive(&sf); got != 2 {
+		t.Errorf("changed mismatch: got %d, want 2", got)
+	}
+	a := sf.Scene.Nodes[0]
+	if a.MaterialRef != "" || a.Material == nil || a.Material.Metalness != 1 || a.Material.Emissive == nil {
+		t.Errorf("node a mismatch: got %+v", a.Material)
+	}
+	if sf.Materials[PresetBrushedMetal].Emissive != nil {
+		t.Error("library material should be left alone")
+	}
+	if c := sf.Scene.Nodes[2]; c.Material != nil {
+		t.Errorf("nodes without status should be left alone: got %+v", c.Material)
+	}
+}
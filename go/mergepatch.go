@@ -0,0 +1,64 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// =============================================================================
+// JSON MERGE PATCH
+// =============================================================================
+
+// MergePatchContentType is the media type of RFC 7386 merge patches
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies an RFC 7386 JSON merge patch to a scene and returns the
+// result; sf is not modified. Objects are merged recursively, null removes a
+// member and any other value, including arrays such as the node list,
+// replaces the target wholesale.
+func MergePatch(sf *SceneFile, patch []byte) (SceneFile, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return SceneFile{}, fmt.Errorf("merge patch: %w", err)
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return SceneFile{}, fmt.Errorf("merge patch: patch must be a JSON object")
+	}
+	data, err := json.Marshal(sf)
+	if err != nil {
+		return SceneFile{}, fmt.Errorf("merge patch: %w", err)
+	}
+	var target interface{}
+	if err := json.Unmarshal(data, &target); err != nil {
+		return SceneFile{}, fmt.Errorf("merge patch: %w", err)
+	}
+	merged, err := json.Marshal(mergePatchValue(target, patchValue))
+	if err != nil {
+		return SceneFile{}, fmt.Errorf("merge patch: %w", err)
+	}
+	var result SceneFile
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return SceneFile{}, fmt.Errorf("merge patch: %w", err)
+	}
+	return result, nil
+}
+
+// mergePatchValue implements the MergePatch algorithm of RFC 7386 section 2
+func mergePatchValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for k, v := range patchObject {
+		if v == nil {
+			delete(targetObject, k)
+			continue
+		}
+		targetObject[k] = mergePatchValue(targetObject[k], v)
+	}
+	return targetObject
+}
@@ -0,0 +1,42 @@
+package starfleet
+
+import "testing"
+
+// TestMergePatch tests merging, removal and array replacement
+func TestMergePatch(t *testing.T) {
+	sf := newDiffScene()
+	sf.Metadata.Description = "old"
+	patch := []byte(`{
+		"metadata": {"name": "Patched", "description": null},
+		"scene": {"camera": {"position": {"x": 0, "y": 5, "z": 10}, "target": {"x": 0, "y": 0, "z": 0}}}
+	}`)
+
+	got, err := MergePatch(&sf, patch)
+	if err != nil {
+		t.Fatalf("MergePatch failed: %v", err)
+	}
+	if got.Metadata.Name != "Patched" || got.Metadata.Description != "" {
+		t.Errorf("metadata mismatch: got %+v", got.Metadata)
+	}
+	if got.Scene.Camera == nil || got.Scene.Camera.Position.Z != 10 {
+		t.Errorf("camera mismatch: got %+v", got.Scene.Camera)
+	}
+	if len(got.Scene.Nodes) != 3 {
+		t.Errorf("node count mismatch: got %d, want 3", len(got.Scene.Nodes))
+	}
+	if sf.Metadata.Name != "Diff" {
+		t.Errorf("input was modified: name %q", sf.Metadata.Name)
+	}
+
+	got, err = MergePatch(&sf, []byte(`{"scene": {"edges": []}}`))
+	if err != nil {
+		t.Fatalf("MergePatch failed: %v", err)
+	}
+	if len(got.Scene.Edges) != 0 {
+		t.Errorf("edge count mismatch: got %d, want 0", len(got.Scene.Edges))
+	}
+
+	if _, err := MergePatch(&sf, []byte(`[1]`)); err == nil {
+		t.Error("expected error for non-object patch")
+	}
+}
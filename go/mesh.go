@@ -0,0 +1,195 @@
+package starfleet
+
+import "math"
+
+// =============================================================================
+// CUSTOM MESHES
+// =============================================================================
+
+// Mesh represents indexed triangle data for custom geometry. Positions are
+// flattened x, y, z triples; Indices reference vertices three per triangle.
+// A compressed mesh carries only Compressed until it is decompressed.
+type Mesh struct {
+	Positions  []float64       `json:"positions,omitempty" validate:"required_without=Compressed"`
+	Normals    []float64       `json:"normals,omitempty"`
+	Indices    []uint32        `json:"indices,omitempty"`
+	Compressed *CompressedMesh `json:"compressed,omitempty"`
+}
+
+// VertexCount returns the number of vertices in the mesh
+func (m *Mesh) VertexCount() int {
+	return len(m.Positions) / 3
+}
+
+// TriangleCount returns the number of triangles in the mesh
+func (m *Mesh) TriangleCount() int {
+	if len(m.Indices) == 0 {
+		return m.VertexCount() / 3
+	}
+	return len(m.Indices) / 3
+}
+
+// appendQuad appends an axis-aligned quad in the XY plane to the mesh
+func (m *Mesh) appendQuad(x0, y0, x1, y1 float64) {
+	base := uint32(m.VertexCount())
+	m.Positions = append(m.Positions,
+		x0, y0, 0,
+		x1, y0, 0,
+		x1, y1, 0,
+		x0, y1, 0,
+	)
+	m.Normals = append(m.Normals, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1)
+	m.Indices = append(m.Indices, base, base+1, base+2, base, base+2, base+3)
+}
+
+// indices returns the triangle indices of the mesh, synthesizing sequential
+// indices for non-indexed meshes
+func (m *Mesh) indices() []uint32 {
+	if len(m.Indices) > 0 {
+		return m.Indices
+	}
+	idx := make([]uint32, m.VertexCount()/3*3)
+	for i := range idx {
+		idx[i] = uint32(i)
+	}
+	return idx
+}
+
+// vertex returns the position of a vertex
+func (m *Mesh) vertex(i uint32) Vector3 {
+	return Vector3{X: m.Positions[3*i], Y: m.Positions[3*i+1], Z: m.Positions[3*i+2]}
+}
+
+// ComputeNormals replaces the mesh normals with area-weighted vertex normals
+func (m *Mesh) ComputeNormals() {
+	normals := make([]Vector3, m.VertexCount())
+	idx := m.indices()
+	for t := 0; t+2 < len(idx); t += 3 {
+		a, b, c := idx[t], idx[t+1], idx[t+2]
+		n := m.vertex(b).Sub(m.vertex(a)).Cross(m.vertex(c).Sub(m.vertex(a)))
+		normals[a] = normals[a].Add(n)
+		normals[b] = normals[b].Add(n)
+		normals[c] = normals[c].Add(n)
+	}
+	m.Normals = make([]float64, 0, len(normals)*3)
+	for _, n := range normals {
+		n = n.Normalize()
+		m.Normals = append(m.Normals, n.X, n.Y, n.Z)
+	}
+}
+
+// bounds returns the axis-aligned bounds of the mesh vertices
+func (m *Mesh) bounds() (Vector3, Vector3) {
+	if m.VertexCount() == 0 {
+		return Vector3{}, Vector3{}
+	}
+	lo, hi := m.vertex(0), m.vertex(0)
+	for i := uint32(1); int(i) < m.VertexCount(); i++ {
+		v := m.vertex(i)
+		lo = Vector3{X: math.Min(lo.X, v.X), Y: math.Min(lo.Y, v.Y), Z: math.Min(lo.Z, v.Z)}
+		hi = Vector3{X: math.Max(hi.X, v.X), Y: math.Max(hi.Y, v.Y), Z: math.Max(hi.Z, v.Z)}
+	}
+	return lo, hi
+}
+
+// primitiveSegments is the tessellation used for curved primitives
+const primitiveSegments = 16
+
+// geometryMesh tessellates a geometry in local space using the same
+// parameters and defaults as geometryHalfExtents. Custom geometry returns its
+// own mesh, decompressing a copy when needed.
+func geometryMesh(g *Geometry) Mesh {
+	if g == nil {
+		return boxMesh(1, 1, 1)
+	}
+	switch g.Type {
+	case GeometryBox:
+		return boxMesh(g.parameter("width", 1), g.parameter("height", 1), g.parameter("depth", 1))
+	case GeometrySphere:
+		return sphereMesh(g.parameter("radius", 0.5))
+	case GeometryCylinder:
+		r := g.parameter("radius", 0.5)
+		return cylinderMesh(g.parameter("radiusTop", r), g.parameter("radiusBottom", r), g.parameter("height", 1))
+	case GeometryPlane:
+		m := Mesh{}
+		w, h := g.parameter("width", 1)/2, g.parameter("height", 1)/2
+		m.appendQuad(-w, -h, w, h)
+		return m
+	default:
+		if g.Mesh == nil {
+			return boxMesh(1, 1, 1)
+		}
+		m := *g.Mesh
+		if m.IsCompressed() {
+			if err := m.Decompress(); err != nil {
+				return boxMesh(1, 1, 1)
+			}
+		}
+		return m
+	}
+}
+
+// boxMesh returns an axis-aligned box centered on the origin
+func boxMesh(w, h, d float64) Mesh {
+	x, y, z := w/2, h/2, d/2
+	m := Mesh{Positions: []float64{
+		-x, -y, -z, x, -y, -z, x, y, -z, -x, y, -z,
+		-x, -y, z, x, -y, z, x, y, z, -x, y, z,
+	}}
+	m.Indices = []uint32{
+		0, 2, 1, 0, 3, 2, // back
+		4, 5, 6, 4, 6, 7, // front
+		0, 1, 5, 0, 5, 4, // bottom
+		3, 7, 6, 3, 6, 2, // top
+		0, 4, 7, 0, 7, 3, // left
+		1, 2, 6, 1, 6, 5, // right
+	}
+	return m
+}
+
+// sphereMesh returns a UV sphere centered on the origin
+func sphereMesh(r float64) Mesh {
+	m := Mesh{}
+	rings, segments := primitiveSegments/2, primitiveSegments
+	for i := 0; i <= rings; i++ {
+		theta := math.Pi * float64(i) / float64(rings)
+		for j := 0; j <= segments; j++ {
+			phi := 2 * math.Pi * float64(j) / float64(segments)
+			m.Positions = append(m.Positions,
+				r*math.Sin(theta)*math.Cos(phi), r*math.Cos(theta), r*math.Sin(theta)*math.Sin(phi))
+		}
+	}
+	stride := uint32(segments + 1)
+	for i := uint32(0); i < uint32(rings); i++ {
+		for j := uint32(0); j < uint32(segments); j++ {
+			a, b := i*stride+j, (i+1)*stride+j
+			m.Indices = append(m.Indices, a, a+1, b, a+1, b+1, b)
+		}
+	}
+	return m
+}
+
+// cylinderMesh returns a capped cylinder along the Y axis centered on the
+// origin
+func cylinderMesh(top, bottom, h float64) Mesh {
+	m := Mesh{}
+	segments := primitiveSegments
+	for j := 0; j < segments; j++ {
+		phi := 2 * math.Pi * float64(j) / float64(segments)
+		c, s := math.Cos(phi), math.Sin(phi)
+		m.Positions = append(m.Positions, top*c, h/2, top*s, bottom*c, -h/2, bottom*s)
+	}
+	n := uint32(segments)
+	m.Positions = append(m.Positions, 0, h/2, 0, 0, -h/2, 0)
+	topCenter, bottomCenter := 2*n, 2*n+1
+	for j := uint32(0); j < n; j++ {
+		k := (j + 1) % n
+		t0, b0, t1, b1 := 2*j, 2*j+1, 2*k, 2*k+1
+		m.Indices = append(m.Indices,
+			t0, t1, b0, t1, b1, b0,
+			topCenter, t1, t0,
+			bottomCenter, b0, b1,
+		)
+	}
+	return m
+}
@@ -0,0 +1,441 @@
+package starfleet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+// =============================================================================
+// MESH COMPRESSION
+// =============================================================================
+
+// Compressed meshes use the meshoptimizer codecs defined by the glTF
+// EXT_meshopt_compression extension, so their streams can be written into a
+// glTF buffer view as is and decoded by any tool that supports it. Vertex
+// streams use the ATTRIBUTES mode (version 0) and index streams the INDICES
+// mode (version 1).
+
+// Meshopt stream parameters
+const (
+	meshoptVertexHeader   = 0xa0
+	meshoptIndexHeader    = 0xd1
+	meshoptByteGroupSize  = 16
+	meshoptBlockSizeBytes = 8192
+	meshoptBlockMaxSize   = 256
+	meshoptTailMinSize    = 32
+	meshoptIndexTailSize  = 4
+)
+
+// meshVertexStride is the byte size of one float32 x, y, z triple, the
+// layout of compressed position and normal streams
+const meshVertexStride = 12
+
+// CompressedMesh holds the streams of a compressed mesh. Positions and
+// Normals are float32 x, y, z triples encoded in the meshopt ATTRIBUTES mode
+// with a byte stride of 12; Indices are uint32 values encoded in the INDICES
+// mode with a byte stride of 4.
+type CompressedMesh struct {
+	VertexCount int    `json:"vertexCount" validate:"min=0"`
+	IndexCount  int    `json:"indexCount,omitempty" validate:"min=0"`
+	Positions   []byte `json:"positions"`
+	Normals     []byte `json:"normals,omitempty"`
+	Indices     []byte `json:"indices,omitempty"`
+}
+
+// IsCompressed reports whether the mesh holds encoded data only
+func (m *Mesh) IsCompressed() bool {
+	return m.Compressed != nil
+}
+
+// Compress encodes the mesh into Compressed and clears the raw streams.
+// Positions and normals are stored as float32, as glTF requires, so values
+// that need double precision are rounded.
+func (m *Mesh) Compress() error {
+	if m.IsCompressed() {
+		return nil
+	}
+	if len(m.Positions)%3 != 0 || len(m.Normals)%3 != 0 ||
+		(len(m.Normals) > 0 && len(m.Normals) != len(m.Positions)) {
+		return ErrInvalidMesh
+	}
+	for _, i := range m.Indices {
+		if int(i) >= m.VertexCount() {
+			return ErrInvalidMesh
+		}
+	}
+
+	c := &CompressedMesh{
+		VertexCount: m.VertexCount(),
+		IndexCount:  len(m.Indices),
+		Positions:   encodeMeshoptVertices(float32Bytes(m.Positions), meshVertexStride),
+	}
+	if len(m.Normals) > 0 {
+		c.Normals = encodeMeshoptVertices(float32Bytes(m.Normals), meshVertexStride)
+	}
+	if len(m.Indices) > 0 {
+		c.Indices = encodeMeshoptIndices(m.Indices)
+	}
+	m.Compressed = c
+	m.Positions, m.Normals, m.Indices = nil, nil, nil
+	return nil
+}
+
+// Decompress restores the raw streams from Compressed and clears it
+func (m *Mesh) Decompress() error {
+	c := m.Compressed
+	if c == nil {
+		return nil
+	}
+	if c.VertexCount < 0 || c.IndexCount < 0 || (c.IndexCount > 0) != (len(c.Indices) > 0) {
+		return ErrInvalidMesh
+	}
+	positions, err := decodeMeshoptVertices(c.Positions, c.VertexCount, meshVertexStride)
+	if err != nil {
+		return err
+	}
+	var normals []float64
+	if len(c.Normals) > 0 {
+		data, err := decodeMeshoptVertices(c.Normals, c.VertexCount, meshVertexStride)
+		if err != nil {
+			return err
+		}
+		normals = float64Values(data)
+	}
+	var indices []uint32
+	if c.IndexCount > 0 {
+		if indices, err = decodeMeshoptIndices(c.Indices, c.IndexCount); err != nil {
+			return err
+		}
+		for _, i := range indices {
+			if int(i) >= c.VertexCount {
+				return ErrInvalidMesh
+			}
+		}
+	}
+
+	m.Positions, m.Normals, m.Indices = float64Values(positions), normals, indices
+	m.Compressed = nil
+	return nil
+}
+
+// UnmarshalJSON decodes a mesh, transparently decompressing encoded data
+func (m *Mesh) UnmarshalJSON(data []byte) error {
+	type rawMesh Mesh
+	var raw rawMesh
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Mesh(raw)
+	return m.Decompress()
+}
+
+// CompressMeshes compresses every custom mesh in the scene, including the
+// geometry library, and returns the number of meshes encoded. Meshes are
+// decompressed automatically when the scene is decoded from JSON.
+func CompressMeshes(sf *SceneFile) (int, error) {
+	count := 0
+	compress := func(g *Geometry) error {
+		if g == nil {
+			return nil
+		}
+		meshes := []*Mesh{g.Mesh}
+		for i := range g.LODs {
+			meshes = append(meshes, g.LODs[i].Mesh)
+		}
+		for _, mesh := range meshes {
+			if mesh == nil || mesh.IsCompressed() {
+				continue
+			}
+			if err := mesh.Compress(); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}
+
+	for i := range sf.Scene.Nodes {
+		if err := compress(sf.Scene.Nodes[i].Geometry); err != nil {
+			return count, err
+		}
+	}
+	for key, g := range sf.Geometries {
+		if err := compress(&g); err != nil {
+			return count, err
+		}
+		sf.Geometries[key] = g
+	}
+	return count, nil
+}
+
+// float32Bytes packs values as little-endian float32
+func float32Bytes(values []float64) []byte {
+	out := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(float32(v)))
+	}
+	return out
+}
+
+// float64Values unpacks little-endian float32 values
+func float64Values(data []byte) []float64 {
+	out := make([]float64, len(data)/4)
+	for i := range out {
+		out[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+	return out
+}
+
+// =============================================================================
+// MESHOPT CODECS
+// =============================================================================
+
+// meshoptBlockSize returns the number of vertices encoded per block
+func meshoptBlockSize(stride int) int {
+	n := meshoptBlockSizeBytes / stride &^ (meshoptByteGroupSize - 1)
+	return min(n, meshoptBlockMaxSize)
+}
+
+// zigzag8 maps a signed byte delta onto small unsigned values
+func zigzag8(d byte) byte {
+	return byte(int8(d)>>7) ^ d<<1
+}
+
+// unzigzag8 reverses zigzag8
+func unzigzag8(v byte) byte {
+	return -(v & 1) ^ v>>1
+}
+
+// encodeMeshoptVertices encodes data, a sequence of stride-byte vertices,
+// in the ATTRIBUTES mode. Each byte of the vertex is delta encoded against
+// the previous vertex and the deltas are packed in groups of 16 with 0, 2,
+// 4 or 8 bits each. The first vertex, the initial baseline, is appended as
+// the tail.
+func encodeMeshoptVertices(data []byte, stride int) []byte {
+	count := len(data) / stride
+	out := []byte{meshoptVertexHeader}
+	last := make([]byte, stride)
+	if count > 0 {
+		copy(last, data[:stride])
+	}
+	blockSize := meshoptBlockSize(stride)
+	deltas := make([]byte, blockSize)
+	for start := 0; start < count; start += blockSize {
+		n := min(blockSize, count-start)
+		aligned := (n + meshoptByteGroupSize - 1) &^ (meshoptByteGroupSize - 1)
+		for k := 0; k < stride; k++ {
+			clear(deltas)
+			p := last[k]
+			for i := 0; i < n; i++ {
+				v := data[(start+i)*stride+k]
+				deltas[i] = zigzag8(v - p)
+				p = v
+			}
+			out = encodeMeshoptBytes(out, deltas[:aligned])
+		}
+		copy(last, data[(start+n-1)*stride:(start+n)*stride])
+	}
+
+	tail := max(stride, meshoptTailMinSize)
+	out = append(out, make([]byte, tail-stride)...)
+	if count > 0 {
+		return append(out, data[:stride]...)
+	}
+	return append(out, make([]byte, stride)...)
+}
+
+// encodeMeshoptBytes appends a header of 2-bit group modes followed by the
+// groups of buffer, each packed with the smallest bit width
+func encodeMeshoptBytes(out, buffer []byte) []byte {
+	groups := len(buffer) / meshoptByteGroupSize
+	header := len(out)
+	out = append(out, make([]byte, (groups+3)/4)...)
+	for g := 0; g < groups; g++ {
+		group := buffer[g*meshoptByteGroupSize : (g+1)*meshoptByteGroupSize]
+		best, bestSize := 3, meshoptByteGroupSize
+		for mode, bits := range []int{0, 2, 4} {
+			if size := meshoptGroupSize(group, bits); size < bestSize {
+				best, bestSize = mode, size
+			}
+		}
+		out[header+g/4] |= byte(best) << (g % 4 * 2)
+		out = appendMeshoptGroup(out, group, best)
+	}
+	return out
+}
+
+// meshoptGroupSize returns the encoded size of a group packed with bits per
+// value, where values that do not fit follow as literal bytes
+func meshoptGroupSize(group []byte, bits int) int {
+	if bits == 0 {
+		for _, v := range group {
+			if v != 0 {
+				return math.MaxInt
+			}
+		}
+		return 0
+	}
+	sentinel := byte(1<<bits - 1)
+	size := meshoptByteGroupSize * bits / 8
+	for _, v := range group {
+		if v >= sentinel {
+			size++
+		}
+	}
+	return size
+}
+
+// appendMeshoptGroup packs a group in the given mode: 0 writes nothing, 1
+// and 2 pack 2 or 4 bits per value, most significant first, followed by
+// the literals of values at or above the sentinel, and 3 copies the bytes
+func appendMeshoptGroup(out, group []byte, mode int) []byte {
+	switch mode {
+	case 0:
+		return out
+	case 3:
+		return append(out, group...)
+	}
+	bits := 2 * mode
+	sentinel := byte(1<<bits - 1)
+	perByte := 8 / bits
+	for i := 0; i < len(group); i += perByte {
+		var b byte
+		for _, v := range group[i : i+perByte] {
+			b = b<<bits | min(v, sentinel)
+		}
+		out = append(out, b)
+	}
+	for _, v := range group {
+		if v >= sentinel {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// decodeMeshoptVertices decodes count stride-byte vertices encoded in the
+// ATTRIBUTES mode
+func decodeMeshoptVertices(data []byte, count, stride int) ([]byte, error) {
+	tail := max(stride, meshoptTailMinSize)
+	if len(data) < 1+tail || data[0] != meshoptVertexHeader || count < 0 || count > len(data)*meshoptByteGroupSize {
+		return nil, ErrInvalidMesh
+	}
+	last := append([]byte(nil), data[len(data)-stride:]...)
+	body := data[1 : len(data)-tail]
+	out := make([]byte, count*stride)
+	blockSize := meshoptBlockSize(stride)
+	deltas := make([]byte, blockSize)
+	for start := 0; start < count; start += blockSize {
+		n := min(blockSize, count-start)
+		aligned := (n + meshoptByteGroupSize - 1) &^ (meshoptByteGroupSize - 1)
+		for k := 0; k < stride; k++ {
+			var ok bool
+			if body, ok = decodeMeshoptBytes(body, deltas[:aligned]); !ok {
+				return nil, ErrInvalidMesh
+			}
+			p := last[k]
+			for i := 0; i < n; i++ {
+				p += unzigzag8(deltas[i])
+				out[(start+i)*stride+k] = p
+			}
+		}
+		copy(last, out[(start+n-1)*stride:(start+n)*stride])
+	}
+	if len(body) != 0 {
+		return nil, ErrInvalidMesh
+	}
+	return out, nil
+}
+
+// decodeMeshoptBytes fills buffer from the groups at the start of data and
+// returns the rest of data
+func decodeMeshoptBytes(data, buffer []byte) ([]byte, bool) {
+	groups := len(buffer) / meshoptByteGroupSize
+	headerSize := (groups + 3) / 4
+	if len(data) < headerSize {
+		return nil, false
+	}
+	header, data := data[:headerSize], data[headerSize:]
+	for g := 0; g < groups; g++ {
+		group := buffer[g*meshoptByteGroupSize : (g+1)*meshoptByteGroupSize]
+		mode := int(header[g/4]>>(g%4*2)) & 3
+		switch mode {
+		case 0:
+			clear(group)
+		case 3:
+			if len(data) < len(group) {
+				return nil, false
+			}
+			copy(group, data)
+			data = data[len(group):]
+		default:
+			bits := 2 * mode
+			sentinel := byte(1<<bits - 1)
+			packed := meshoptByteGroupSize * bits / 8
+			if len(data) < packed {
+				return nil, false
+			}
+			literals := data[packed:]
+			for i := range group {
+				b := data[i*bits/8]
+				v := b >> (8 - bits - i*bits%8) & sentinel
+				if v == sentinel {
+					if len(literals) == 0 {
+						return nil, false
+					}
+					v, literals = literals[0], literals[1:]
+				}
+				group[i] = v
+			}
+			data = literals
+		}
+	}
+	return data, true
+}
+
+// encodeMeshoptIndices encodes indices in the INDICES mode: each index is a
+// zigzag varint delta from one of two baselines, switching baselines when
+// the delta grows large, followed by a 4-byte tail
+func encodeMeshoptIndices(indices []uint32) []byte {
+	out := []byte{meshoptIndexHeader}
+	var last [2]uint32
+	current := uint32(0)
+	for _, index := range indices {
+		cd := int32(index - last[current])
+		if cd >= 30 || cd <= -30 {
+			current ^= 1
+		}
+		d := index - last[current]
+		v := d<<1 ^ uint32(int32(d)>>31)
+		out = binary.AppendUvarint(out, uint64(v<<1|current))
+		last[current] = index
+	}
+	return append(out, make([]byte, meshoptIndexTailSize)...)
+}
+
+// decodeMeshoptIndices decodes count indices encoded in the INDICES mode
+func decodeMeshoptIndices(data []byte, count int) ([]uint32, error) {
+	if len(data) < 1+meshoptIndexTailSize || data[0] != meshoptIndexHeader || count < 0 || count > len(data) {
+		return nil, ErrInvalidMesh
+	}
+	body := data[1 : len(data)-meshoptIndexTailSize]
+	out := make([]uint32, count)
+	var last [2]uint32
+	for i := range out {
+		v, n := binary.Uvarint(body)
+		if n <= 0 || v > math.MaxUint32 {
+			return nil, ErrInvalidMesh
+		}
+		body = body[n:]
+		current := v & 1
+		w := uint32(v >> 1)
+		d := w>>1 ^ -(w & 1)
+		last[current] += d
+		out[i] = last[current]
+	}
+	if len(body) != 0 {
+		return nil, ErrInvalidMesh
+	}
+	return out, nil
+}
@@ -0,0 +1,159 @@
+package starfleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestMeshCompress_RoundTrip tests that decompression restores the mesh
+// within float32 precision
+func TestMeshCompress_RoundTrip(t *testing.T) {
+	original := newGridMesh(20)
+	mesh := original
+	if err := mesh.Compress(); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	if !mesh.IsCompressed() || mesh.Positions != nil || mesh.Indices != nil {
+		t.Fatal("expected only compressed data after Compress")
+	}
+	if err := mesh.Decompress(); err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+
+	if len(mesh.Positions) != len(original.Positions) || len(mesh.Normals) != len(original.Normals) {
+		t.Fatalf("stream length mismatch: got %d/%d, want %d/%d",
+			len(mesh.Positions), len(mesh.Normals), len(original.Positions), len(original.Normals))
+	}
+	for i := range mesh.Positions {
+		if mesh.Positions[i] != float64(float32(original.Positions[i])) {
+			t.Fatalf("position %d mismatch: got %v, want %v", i, mesh.Positions[i], original.Positions[i])
+		}
+	}
+	for i := range mesh.Normals {
+		if math.Abs(mesh.Normals[i]-original.Normals[i]) > 1e-6 {
+			t.Fatalf("normal %d mismatch: got %v, want %v", i, mesh.Normals[i], original.Normals[i])
+		}
+	}
+	if !reflect.DeepEqual(mesh.Indices, original.Indices) {
+		t.Errorf("indices mismatch: got %v, want %v", mesh.Indices, original.Indices)
+	}
+}
+
+// TestMeshCompress_JSON tests transparent decompression when decoding
+func TestMeshCompress_JSON(t *testing.T) {
+	sf := NewSceneFile("Mesh")
+	mesh := newGridMesh(30)
+	triangles := mesh.TriangleCount()
+	raw, _ := json.Marshal(sf)
+	sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{
+		ID:        "part",
+		Type:      "part",
+		Transform: NewTransform(),
+		Geometry:  &Geometry{Type: GeometryCustom, Mesh: &mesh},
+	})
+	plain, _ := json.Marshal(sf)
+
+	n, err := CompressMeshes(&sf)
+	if err != nil {
+		t.Fatalf("CompressMeshes failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("compressed count mismatch: got %d, want 1", n)
+	}
+	compressed, _ := json.Marshal(sf)
+	if len(compressed)-len(raw) >= (len(plain)-len(raw))/4 {
+		t.Errorf("expected at least 4x smaller mesh: got %d bytes, plain %d", len(compressed)-len(raw), len(plain)-len(raw))
+	}
+
+	var decoded SceneFile
+	if err := json.Unmarshal(compressed, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	got := decoded.Scene.Nodes[0].Geometry.Mesh
+	if got.IsCompressed() {
+		t.Error("expected mesh to be decompressed on load")
+	}
+	if got.TriangleCount() != triangles {
+		t.Errorf("triangle count mismatch: got %d, want %d", got.TriangleCount(), triangles)
+	}
+}
+
+// TestMeshDecompress_Corrupt tests rejection of damaged data
+func TestMeshDecompress_Corrupt(t *testing.T) {
+	mesh := newGridMesh(4)
+	if err := mesh.Compress(); err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	c := *mesh.Compressed
+	c.Positions = c.Positions[:len(c.Positions)/2]
+	truncated := Mesh{Compressed: &c}
+	if err := truncated.Decompress(); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("truncated error mismatch: got %v, want %v", err, ErrInvalidMesh)
+	}
+	foreign := Mesh{Compressed: &CompressedMesh{VertexCount: 3, Positions: []byte("DRACO")}}
+	if err := foreign.Decompress(); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("foreign error mismatch: got %v, want %v", err, ErrInvalidMesh)
+	}
+}
+
+// TestMeshoptVertices tests the ATTRIBUTES bitstream: the first byte of each
+// vertex needs 2-bit deltas with literals, the second plain 2-bit deltas,
+// and the unchanged bytes none, followed by the first vertex as the tail
+func TestMeshoptVertices(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 0,
+		44, 1, 0, 0,
+		0, 0, 0, 0,
+		44, 1, 0, 0,
+	}
+	want := []byte{
+		0xa0,
+		0x01, 0x3f, 0x00, 0x00, 0x00, 0x58, 0x57, 0x58,
+		0x01, 0x26, 0x00, 0x00, 0x00,
+		0x00,
+		0x00,
+	}
+	want = append(want, make([]byte, meshoptTailMinSize)...)
+
+	got := encodeMeshoptVertices(data, 4)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("encoding mismatch:\ngot  % x\nwant % x", got, want)
+	}
+	decoded, err := decodeMeshoptVertices(got, 4, 4)
+	if err != nil || !bytes.Equal(decoded, data) {
+		t.Errorf("decoding mismatch: got %v, %v", decoded, err)
+	}
+
+	// Several blocks with a non-zero first vertex
+	long := make([]byte, 1000*meshVertexStride)
+	for i := range long {
+		long[i] = byte(i*7 + i/meshVertexStride)
+	}
+	decoded, err = decodeMeshoptVertices(encodeMeshoptVertices(long, meshVertexStride), 1000, meshVertexStride)
+	if err != nil || !bytes.Equal(decoded, long) {
+		t.Errorf("multi-block round trip failed: %v", err)
+	}
+}
+
+// TestMeshoptIndices tests the INDICES bitstream, including baseline
+// switches on large deltas
+func TestMeshoptIndices(t *testing.T) {
+	indices := []uint32{0, 1, 51, 2, 49, 1000}
+	want := []byte{0xd1, 0x00, 0x04, 0xcd, 0x01, 0x04, 0x07, 0x98, 0x1f, 0x00, 0x00, 0x00, 0x00}
+
+	got := encodeMeshoptIndices(indices)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("encoding mismatch:\ngot  % x\nwant % x", got, want)
+	}
+	decoded, err := decodeMeshoptIndices(got, len(indices))
+	if err != nil || !reflect.DeepEqual(decoded, indices) {
+		t.Errorf("decoding mismatch: got %v, %v", decoded, err)
+	}
+	if _, err := decodeMeshoptIndices(got, len(indices)+1); !errors.Is(err, ErrInvalidMesh) {
+		t.Errorf("expected ErrInvalidMesh for a short stream, got %v", err)
+	}
+}
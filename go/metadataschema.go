@@ -0,0 +1,168 @@
+package starfleet
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// =============================================================================
+// METADATA SCHEMAS
+// =============================================================================
+
+// MetadataSchemasExtension is the scene extension key holding the metadata
+// schemas of node types
+const MetadataSchemasExtension = "metadataSchemas"
+
+// MetadataType is the JSON type a metadata value must have
+type MetadataType string
+
+const (
+	MetadataString  MetadataType = "string"
+	MetadataNumber  MetadataType = "number"
+	MetadataBoolean MetadataType = "boolean"
+	MetadataArray   MetadataType = "array"
+	MetadataObject  MetadataType = "object"
+)
+
+// MetadataField constrains one metadata key. Enum lists the accepted values
+// in string form, as compared by selectors; an empty Type accepts any type.
+type MetadataField struct {
+	Type     MetadataType `json:"type,omitempty" validate:"omitempty,oneof=string number boolean array object"`
+	Required bool         `json:"required,omitempty"`
+	Enum     []string     `json:"enum,omitempty"`
+}
+
+// MetadataSchema is the metadata profile of a node type, keyed by metadata
+// key. Keys it does not list are allowed unless Closed is set.
+type MetadataSchema struct {
+	Fields map[string]MetadataField `json:"fields"`
+	Closed bool                     `json:"closed,omitempty"`
+}
+
+// MetadataSchemas maps node types to their metadata schemas. Nodes of types
+// without a schema are not checked.
+type MetadataSchemas map[string]MetadataSchema
+
+// MetadataSchemas returns the schemas declared by the scene, or nil when it
+// declares none
+func (sf *SceneFile) MetadataSchemas() (MetadataSchemas, error) {
+	v, ok := sf.Extensions[MetadataSchemasExtension]
+	if !ok {
+		return nil, nil
+	}
+	var schemas MetadataSchemas
+	if err := roundTripJSON(v, &schemas); err != nil {
+		return nil, fmt.Errorf("metadata schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// SetMetadataSchemas declares the scene's schemas, replacing any it had.
+// Nil schemas remove the declaration.
+func (sf *SceneFile) SetMetadataSchemas(schemas MetadataSchemas) {
+	if schemas == nil {
+		delete(sf.Extensions, MetadataSchemasExtension)
+		return
+	}
+	sf.Extensions = withExtension(sf.Extensions, MetadataSchemasExtension, schemas)
+}
+
+// Check returns the problems with a node's metadata under the schema of its
+// type, in key order
+func (s MetadataSchemas) Check(node *SceneNode) []string {
+	schema, ok := s[node.Type]
+	if !ok {
+		return nil
+	}
+	var errs []string
+	keys := make([]string, 0, len(schema.Fields))
+	for k := range schema.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field := schema.Fields[key]
+		v, ok := node.Metadata[key]
+		if !ok || v == nil {
+			if field.Required {
+				errs = append(errs, fmt.Sprintf("Node %s of type %s is missing required metadata: %s", node.ID, node.Type, key))
+			}
+			continue
+		}
+		if field.Type != "" && metadataType(v) != field.Type {
+			errs = append(errs, fmt.Sprintf("Node %s metadata %s must be a %s", node.ID, key, field.Type))
+			continue
+		}
+		if len(field.Enum) > 0 {
+			if str, ok := metadataString(v); !ok || !slices.Contains(field.Enum, str) {
+				errs = append(errs, fmt.Sprintf("Node %s metadata %s must be one of: %v", node.ID, key, field.Enum))
+			}
+		}
+	}
+	if schema.Closed {
+		var unknown []string
+		for k := range node.Metadata {
+			if _, ok := schema.Fields[k]; !ok {
+				unknown = append(unknown, k)
+			}
+		}
+		sort.Strings(unknown)
+		for _, k := range unknown {
+			errs = append(errs, fmt.Sprintf("Node %s of type %s has unknown metadata: %s", node.ID, node.Type, k))
+		}
+	}
+	return errs
+}
+
+// ValidateMetadataSchemas checks node metadata against the schemas the scene
+// declares, and the schemas themselves
+func ValidateMetadataSchemas(sf *SceneFile) []string {
+	schemas, err := sf.MetadataSchemas()
+	if err != nil {
+		return []string{fmt.Sprintf("Scene has invalid metadata schemas: %v", err)}
+	}
+	var errs []string
+	for t, schema := range schemas {
+		for key, field := range schema.Fields {
+			switch field.Type {
+			case "", MetadataString, MetadataNumber, MetadataBoolean, MetadataArray, MetadataObject:
+			default:
+				errs = append(errs, fmt.Sprintf("Metadata schema for %s has unknown type for %s: %s", t, key, field.Type))
+			}
+		}
+	}
+	sort.Strings(errs)
+	for i := range sf.Scene.Nodes {
+		errs = append(errs, schemas.Check(&sf.Scene.Nodes[i])...)
+	}
+	return errs
+}
+
+// metadataType returns the JSON type of a metadata value
+func metadataType(v interface{}) MetadataType {
+	if _, ok := toFloat64(v); ok {
+		return MetadataNumber
+	}
+	switch v.(type) {
+	case string:
+		return MetadataString
+	case bool:
+		return MetadataBoolean
+	case []interface{}, []string:
+		return MetadataArray
+	case map[string]interface{}:
+		return MetadataObject
+	default:
+		return ""
+	}
+}
+
+// metadataString returns the string form of a scalar metadata value, as
+// selectors and schema enums compare them
+func metadataString(v interface{}) (string, bool) {
+	if b, ok := v.(bool); ok {
+		return fmt.Sprint(b), true
+	}
+	return toString(v)
+}
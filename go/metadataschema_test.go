@@ -0,0 +1,64 @@
+package starfleet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestValidateMetadataSchemas tests enforcing per-type metadata profiles
+func TestValidateMetadataSchemas(t *testing.T) {
+	sf := newDiffScene()
+	sf.SetMetadataSchemas(MetadataSchemas{
+		"server": {Fields: map[string]MetadataField{
+			"hostname": {Type: MetadataString, Required: true},
+			"owner":    {Type: MetadataString, Required: true},
+			"env":      {Required: true, Enum: []string{"prod", "staging"}},
+			"cores":    {Type: MetadataNumber},
+		}},
+	})
+	for i := range sf.Scene.Nodes {
+		sf.Scene.Nodes[i].Metadata = map[string]interface{}{"hostname": "h", "owner": "ops", "env": "prod", "cores": 8}
+	}
+	// Schemas survive a JSON round trip of the scene
+	var decoded SceneFile
+	if err := roundTripJSON(sf, &decoded); err != nil {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if errs := ValidateMetadataSchemas(&decoded); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{"hostname": 42, "env": "dev", "cores": "eight"}
+	want := []string{
+		"Node a metadata cores must be a number",
+		"Node a metadata env must be one of: [prod staging]",
+		"Node a metadata hostname must be a string",
+		"Node a of type server is missing required metadata: owner",
+	}
+	if errs := ValidateMetadataSchemas(&sf); !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors mismatch: got %v, want %v", errs, want)
+	}
+	if result := ValidateScene(&sf); result.Valid {
+		t.Error("expected ValidateScene to enforce schemas")
+	}
+
+	schemas, _ := sf.MetadataSchemas()
+	schema := schemas["server"]
+	schema.Closed = true
+	schemas["server"] = schema
+	sf.SetMetadataSchemas(schemas)
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{"hostname": "h", "owner": "ops", "env": "prod", "rack": "r1"}
+	if errs := ValidateMetadataSchemas(&sf); len(errs) != 1 || !strings.Contains(errs[0], "unknown metadata: rack") {
+		t.Errorf("closed schema mismatch: got %v", errs)
+	}
+
+	sf.Extensions[MetadataSchemasExtension] = "server"
+	if errs := ValidateMetadataSchemas(&sf); len(errs) != 1 || !strings.Contains(errs[0], "invalid metadata schemas") {
+		t.Errorf("malformed schemas mismatch: got %v", errs)
+	}
+	sf.SetMetadataSchemas(nil)
+	if errs := ValidateMetadataSchemas(&sf); len(errs) != 0 {
+		t.Errorf("expected no errors without schemas, got %v", errs)
+	}
+}
@@ -0,0 +1,229 @@
+package starfleet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// METRICS BINDING
+// =============================================================================
+
+// DefaultMetricsWindow is how far back MetricsBinder.Bind queries by default
+const DefaultMetricsWindow = 5 * time.Minute
+
+// MetricsBinder binds live metrics to a scene: it writes the latest value of
+// every metric into node Metrics and runs the optional anomaly Detector over
+// each series, flagging nodes with anomalous metrics under AnomalyExtension.
+// With a Rolling aggregator it also writes windowed statistics as derived
+// metrics, and with a Theme, or the theme stored with the scene, it
+// restyles the scene from the new values. Scenes storing roll-up rules have
+// their group metrics recomputed after every update. A binder remembers the
+// newest point it has seen per series, so overlapping queries and repeated
+// stream deliveries are counted only once.
+type MetricsBinder struct {
+	Source   MetricsSource
+	Metrics  []string
+	Window   time.Duration
+	Detector AnomalyDetector
+	Rolling  *RollingAggregator
+	Theme    *Theme
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+	now      func() time.Time
+}
+
+// NewMetricsBinder creates a binder querying source for the given metrics,
+// or for every metric the source offers when none are named
+func NewMetricsBinder(source MetricsSource, metrics ...string) *MetricsBinder {
+	return &MetricsBinder{
+		Source:   source,
+		Metrics:  metrics,
+		Window:   DefaultMetricsWindow,
+		lastSeen: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// Bind queries the source for the scene's nodes and edges over the binder
+// window and applies the results
+func (b *MetricsBinder) Bind(ctx context.Context, sf *SceneFile) ([]Anomaly, error) {
+	if b.Source == nil {
+		return nil, fmt.Errorf("bind metrics: no metrics source")
+	}
+	now := b.currentTime()
+	from := now.Add(-b.Window)
+	query := MetricsQuery{MetricNames: b.Metrics, From: &from, To: &now}
+	for i := range sf.Scene.Nodes {
+		query.NodeIDs = append(query.NodeIDs, sf.Scene.Nodes[i].ID)
+	}
+	for i := range sf.Scene.Edges {
+		query.EdgeIDs = append(query.EdgeIDs, sf.Scene.Edges[i].ID)
+	}
+	results, err := b.Source.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("bind metrics: %w", err)
+	}
+	return b.apply(ctx, sf, results), nil
+}
+
+// metricsTarget is the node or edge a metrics result is bound to
+type metricsTarget struct {
+	metrics    *map[string]interface{}
+	extensions *map[string]interface{}
+}
+
+// target returns the element a result belongs to and the key identifying
+// its series, or false when the element is not in the scene
+func (b *MetricsBinder) target(sf *SceneFile, r MetricsResult) (metricsTarget, string, bool) {
+	if r.EdgeID != "" {
+		if e := sf.FindEdge(r.EdgeID); e != nil {
+			return metricsTarget{&e.Metrics, &e.Extensions}, "edge:" + r.EdgeID, true
+		}
+		return metricsTarget{}, "", false
+	}
+	if n := sf.FindNode(r.NodeID); n != nil {
+		return metricsTarget{&n.Metrics, &n.Extensions}, "node:" + r.NodeID, true
+	}
+	return metricsTarget{}, "", false
+}
+
+// Apply writes metrics results into the nodes and edges of the scene,
+// restyles it with the binder Theme, and returns the anomalies found.
+// Results for unknown elements are ignored, as are NaN and infinite
+// points, which broken feeds send for missing data: the metric keeps its
+// last finite value. An element is flagged while the latest point of any
+// of its series is anomalous. Rolling statistics are kept for node metrics
+// only.
+func (b *MetricsBinder) Apply(sf *SceneFile, results []MetricsResult) []Anomaly {
+	return b.apply(context.Background(), sf, results)
+}
+
+// apply is Apply finding the elements of results and ordering their points
+// on the context's workers. Series state is updated serially, in result
+// order, so anomalies come out the same either way.
+func (b *MetricsBinder) apply(ctx context.Context, sf *SceneFile, results []MetricsResult) []Anomaly {
+	type prepared struct {
+		target  metricsTarget
+		element string
+		ok      bool
+		points  []MetricsDataPoint
+	}
+	ready := make([]prepared, len(results))
+	// Once the query has returned, binding runs to completion so series
+	// state stays consistent with the scene
+	_ = parallelFor(context.WithoutCancel(ctx), len(results), parallelBlock, func(i int) {
+		r := &results[i]
+		target, element, ok := b.target(sf, *r)
+		if !ok {
+			return
+		}
+		points := slices.DeleteFunc(slices.Clone(r.DataPoints), func(p MetricsDataPoint) bool {
+			f, ok := toFloat64(p.Value)
+			return ok && !isFinite(f)
+		})
+		if len(points) == 0 {
+			return
+		}
+		slices.SortStableFunc(points, func(a, b MetricsDataPoint) int { return a.Timestamp.Compare(b.Timestamp) })
+		ready[i] = prepared{target, element, true, points}
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.lastSeen == nil {
+		b.lastSeen = make(map[string]time.Time)
+	}
+
+	var anomalies []Anomaly
+	targets := make(map[string]metricsTarget)
+	flagged := make(map[string]map[string]bool)
+	for i, r := range results {
+		if !ready[i].ok {
+			continue
+		}
+		target, element, points := ready[i].target, ready[i].element, ready[i].points
+		series := element + "/" + r.MetricName
+		last, seen := b.lastSeen[series]
+		var fresh []MetricsDataPoint
+		for _, p := range points {
+			if !seen || p.Timestamp.After(last) {
+				fresh = append(fresh, p)
+				last, seen = p.Timestamp, true
+			}
+		}
+		if len(fresh) == 0 {
+			// A replayed or late result must not roll the live value back
+			continue
+		}
+		b.lastSeen[series] = last
+		if *target.metrics == nil {
+			*target.metrics = make(map[string]interface{})
+		}
+		(*target.metrics)[r.MetricName] = fresh[len(fresh)-1].Value
+		if b.Rolling != nil && r.EdgeID == "" {
+			b.Rolling.Add(r.NodeID, r.MetricName, fresh...)
+		}
+		if b.Detector == nil {
+			continue
+		}
+		targets[element] = target
+		if flagged[element] == nil {
+			flagged[element] = make(map[string]bool)
+		}
+		for _, p := range fresh {
+			v, ok := toFloat64(p.Value)
+			if !ok {
+				continue
+			}
+			score, anomalous := b.Detector.Observe(series, p.Timestamp, v)
+			flagged[element][r.MetricName] = anomalous
+			if anomalous {
+				anomalies = append(anomalies, Anomaly{NodeID: r.NodeID, EdgeID: r.EdgeID, Metric: r.MetricName, Time: p.Timestamp, Value: v, Score: score})
+			}
+		}
+	}
+
+	for element, metrics := range flagged {
+		ext := targets[element].extensions
+		names := toStringSlice((*ext)[AnomalyExtension])
+		for metric, anomalous := range metrics {
+			names = slices.DeleteFunc(names, func(n string) bool { return n == metric })
+			if anomalous {
+				names = append(names, metric)
+			}
+		}
+		slices.Sort(names)
+		if len(names) > 0 {
+			*ext = withExtension(*ext, AnomalyExtension, names)
+		} else {
+			*ext = withoutExtensions(*ext, []string{AnomalyExtension})
+		}
+	}
+	if b.Rolling != nil {
+		b.Rolling.Apply(sf, b.currentTime())
+	}
+	// Invalid stored rules are reported by validation; the bound values
+	// stand either way
+	ApplyRollups(sf)
+	theme := b.Theme
+	if theme == nil {
+		theme, _ = sf.Theme()
+	}
+	if theme != nil {
+		theme.Apply(sf)
+	}
+	return anomalies
+}
+
+// currentTime returns the binder clock, which tests replace
+func (b *MetricsBinder) currentTime() time.Time {
+	if b.now == nil {
+		return time.Now()
+	}
+	return b.now()
+}
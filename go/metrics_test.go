@@ -0,0 +1,124 @@
+package starfleet
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestEWMADetector tests warm-up, spike detection and baseline adaptation
+func TestEWMADetector(t *testing.T) {
+	d := NewEWMADetector(0.3, 3)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		v := 100.0 + float64(i%3)
+		if _, anomalous := d.Observe("cpu", at, v); anomalous {
+			t.Fatalf("steady value %d flagged", i)
+		}
+	}
+	score, anomalous := d.Observe("cpu", at, 180)
+	if !anomalous || score <= 3 {
+		t.Errorf("spike not flagged: score %v", score)
+	}
+	if _, anomalous := d.Observe("other", at, 180); anomalous {
+		t.Error("series should be scored independently")
+	}
+
+	// A lasting shift becomes the new normal
+	for i := 0; i < 30; i++ {
+		d.Observe("cpu", at, 180+float64(i%3))
+	}
+	if _, anomalous := d.Observe("cpu", at, 181); anomalous {
+		t.Error("shifted baseline still flagged")
+	}
+}
+
+// TestMetricsBinder tests binding values and flagging anomalies across
+// overlapping queries
+func TestMetricsBinder(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var queries []MetricsQuery
+	var spike bool
+	source := MetricsSourceFunc(func(ctx context.Context, q MetricsQuery) ([]MetricsResult, error) {
+		queries = append(queries, q)
+		var points []MetricsDataPoint
+		for i := 0; i < 12; i++ {
+			points = append(points, MetricsDataPoint{Timestamp: now.Add(time.Duration(i-12) * time.Second), Value: 50.0 + float64(i%2)})
+		}
+		if spike {
+			points = append(points, MetricsDataPoint{Timestamp: now, Value: 500.0})
+		}
+		return []MetricsResult{
+			{NodeID: "api", MetricName: "latency", DataPoints: points},
+			{NodeID: "ghost", MetricName: "latency", DataPoints: points},
+		}, nil
+	})
+
+	sf := NewSceneFile("Binder")
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform()})
+	b := NewMetricsBinder(source, "latency")
+	b.Detector = NewEWMADetector(0.2, 4)
+	b.now = func() time.Time { return now }
+
+	anomalies, err := b.Bind(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	q := queries[0]
+	if !reflect.DeepEqual(q.NodeIDs, []string{"api"}) || !q.To.Equal(now) || !q.From.Equal(now.Add(-DefaultMetricsWindow)) {
+		t.Errorf("query mismatch: got %+v", q)
+	}
+	api := sf.FindNode("api")
+	if len(anomalies) != 0 || api.Metrics["latency"] != 51.0 || api.Extensions != nil {
+		t.Errorf("first bind mismatch: anomalies %v, node %+v", anomalies, api)
+	}
+
+	spike = true
+	anomalies, _ = b.Bind(context.Background(), &sf)
+	if len(anomalies) != 1 || anomalies[0].Value != 500 || !anomalies[0].Time.Equal(now) {
+		t.Errorf("anomalies mismatch: got %+v", anomalies)
+	}
+	if got := api.Extensions[AnomalyExtension]; !reflect.DeepEqual(got, []string{"latency"}) {
+		t.Errorf("anomaly extension mismatch: got %v", got)
+	}
+
+	// Replaying the same window adds nothing new and keeps the flag
+	anomalies = b.Apply(&sf, []MetricsResult{{NodeID: "api", MetricName: "latency", DataPoints: []MetricsDataPoint{{Timestamp: now, Value: 500.0}}}})
+	if len(anomalies) != 0 || api.Extensions[AnomalyExtension] == nil {
+		t.Errorf("replay mismatch: anomalies %v, extensions %v", anomalies, api.Extensions)
+	}
+
+	// A normal point clears the flag without touching maps shared with
+	// other copies of the scene
+	shared := api.Extensions
+	b.Apply(&sf, []MetricsResult{{NodeID: "api", MetricName: "latency", DataPoints: []MetricsDataPoint{{Timestamp: now.Add(time.Second), Value: 60.0}}}})
+	if _, ok := api.Extensions[AnomalyExtension]; ok {
+		t.Errorf("anomaly flag not cleared: got %v", api.Extensions)
+	}
+	if shared[AnomalyExtension] == nil {
+		t.Errorf("shared extensions modified: got %v", shared)
+	}
+
+	// A late point is not written over the newer value
+	b.Apply(&sf, []MetricsResult{{NodeID: "api", MetricName: "latency", DataPoints: []MetricsDataPoint{{Timestamp: now.Add(-time.Minute), Value: 5.0}}}})
+	if got := api.Metrics["latency"]; got != 60.0 {
+		t.Errorf("metric mismatch after a late point: got %v, want 60", got)
+	}
+
+	// NaN from a broken feed leaves the last finite value in place
+	b.Apply(&sf, []MetricsResult{{NodeID: "api", MetricName: "latency", DataPoints: []MetricsDataPoint{{Timestamp: now.Add(2 * time.Second), Value: math.NaN()}}}})
+	if got := api.Metrics["latency"]; got != 60.0 {
+		t.Errorf("metric mismatch after NaN: got %v, want 60", got)
+	}
+
+	// Node series are kept apart from edge series with the same ID
+	sf.AddNode(SceneNode{ID: "edge:db", Type: "server", Name: "Odd", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "db", Source: "api", Target: "edge:db"})
+	point := []MetricsDataPoint{{Timestamp: now.Add(time.Second), Value: 1.0}}
+	b.Apply(&sf, []MetricsResult{{EdgeID: "db", MetricName: "rps", DataPoints: point}, {NodeID: "edge:db", MetricName: "rps", DataPoints: point}})
+	if sf.FindNode("edge:db").Metrics["rps"] != 1.0 || sf.FindEdge("db").Metrics["rps"] != 1.0 {
+		t.Errorf("colliding series mismatch: node %v, edge %v", sf.FindNode("edge:db").Metrics, sf.FindEdge("db").Metrics)
+	}
+}
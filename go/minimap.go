@@ -0,0 +1,170 @@
+package starfleet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// =============================================================================
+// MINIMAPS
+// =============================================================================
+
+// MinimapProjection maps the world XZ plane onto minimap pixels. World X
+// increases to the right and world Z increases downward, as seen from above.
+type MinimapProjection struct {
+	// Origin is the world position of the top-left pixel corner
+	Origin Vector3 `json:"origin"`
+	// Scale is the number of pixels per world unit
+	Scale  float64 `json:"scale"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+}
+
+// ToMap returns the pixel coordinates of a world position
+func (p MinimapProjection) ToMap(v Vector3) (float64, float64) {
+	return (v.X - p.Origin.X) * p.Scale, (v.Z - p.Origin.Z) * p.Scale
+}
+
+// ToWorld returns the world position on the ground plane (y = 0) under a
+// pixel coordinate
+func (p MinimapProjection) ToWorld(x, y float64) Vector3 {
+	return Vector3{X: p.Origin.X + x/p.Scale, Z: p.Origin.Z + y/p.Scale}
+}
+
+// MinimapNode represents the footprint of a node on the minimap in pixels
+type MinimapNode struct {
+	ID     string  `json:"id"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// MinimapEdge represents an edge on the minimap as a pixel polyline
+type MinimapEdge struct {
+	ID     string       `json:"id"`
+	Points [][2]float64 `json:"points"`
+}
+
+// Minimap represents a top-down overview of a scene as both a raster image
+// and vector data sharing one projection
+type Minimap struct {
+	Projection MinimapProjection `json:"projection"`
+	Nodes      []MinimapNode     `json:"nodes"`
+	Edges      []MinimapEdge     `json:"edges,omitempty"`
+	Image      *image.NRGBA      `json:"-"`
+}
+
+// minimapMargin is the fraction of the scene extent left empty around it
+const minimapMargin = 0.05
+
+// MaxMinimapResolution is the largest resolution GenerateMinimap accepts
+const MaxMinimapResolution = 4096
+
+// GenerateMinimap projects the scene onto the ground plane at the given
+// resolution, the length in pixels of the longer side. Node footprints are
+// filled with their material colors and edges drawn as thin lines. Nodes are
+// drawn at least one pixel wide so small nodes in huge scenes stay visible.
+// Resolutions above MaxMinimapResolution are refused.
+func GenerateMinimap(sf *SceneFile, resolution int) (*Minimap, error) {
+	if resolution <= 0 {
+		return nil, errors.New("minimap: resolution must be positive")
+	}
+	if resolution > MaxMinimapResolution {
+		return nil, fmt.Errorf("minimap: resolution %d exceeds %d", resolution, MaxMinimapResolution)
+	}
+	lo, hi, ok := sceneBounds(sf)
+	if !ok {
+		lo, hi = Vector3{X: -0.5, Z: -0.5}, Vector3{X: 0.5, Z: 0.5}
+	}
+	extent := math.Max(math.Max(hi.X-lo.X, hi.Z-lo.Z), 1e-9)
+	margin := extent * minimapMargin
+	lo = Vector3{X: lo.X - margin, Z: lo.Z - margin}
+	hi = Vector3{X: hi.X + margin, Z: hi.Z + margin}
+	scale := float64(resolution) / math.Max(hi.X-lo.X, hi.Z-lo.Z)
+
+	proj := MinimapProjection{
+		Origin: lo,
+		Scale:  scale,
+		Width:  max(1, int(math.Ceil((hi.X-lo.X)*scale))),
+		Height: max(1, int(math.Ceil((hi.Z-lo.Z)*scale))),
+	}
+	m := &Minimap{
+		Projection: proj,
+		Nodes:      make([]MinimapNode, 0, len(sf.Scene.Nodes)),
+		Image:      image.NewNRGBA(image.Rect(0, 0, proj.Width, proj.Height)),
+	}
+
+	nodes := make(map[string]*SceneNode, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodes[sf.Scene.Nodes[i].ID] = &sf.Scene.Nodes[i]
+	}
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		source, target := nodes[edge.Source], nodes[edge.Target]
+		if source == nil || target == nil {
+			continue
+		}
+		world := append([]Vector3{source.Transform.Position}, edge.Waypoints...)
+		world = append(world, target.Transform.Position)
+		e := MinimapEdge{ID: edge.ID, Points: make([][2]float64, len(world))}
+		for j, w := range world {
+			x, y := proj.ToMap(w)
+			e.Points[j] = [2]float64{x, y}
+			if j > 0 {
+				drawLine2D(m.Image, e.Points[j-1], e.Points[j], color.NRGBA{R: 150, G: 150, B: 150, A: 255})
+			}
+		}
+		m.Edges = append(m.Edges, e)
+	}
+
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		h := nodeHalfExtents(node, sf.ResolveGeometry(node))
+		x, y := proj.ToMap(node.Transform.Position.Sub(h))
+		n := MinimapNode{ID: node.ID, X: x, Y: y, Width: 2 * h.X * scale, Height: 2 * h.Z * scale}
+		m.Nodes = append(m.Nodes, n)
+
+		fill := color.NRGBA{R: 90, G: 90, B: 90, A: 255}
+		if mat := sf.ResolveMaterial(node); mat != nil && mat.Color != nil {
+			fill = shadeColor(*mat.Color, 1)
+		}
+		x0, y0 := int(math.Floor(n.X)), int(math.Floor(n.Y))
+		x1 := max(x0+1, int(math.Ceil(n.X+n.Width)))
+		y1 := max(y0+1, int(math.Ceil(n.Y+n.Height)))
+		for py := y0; py < y1; py++ {
+			for px := x0; px < x1; px++ {
+				if image.Pt(px, py).In(m.Image.Rect) {
+					m.Image.SetNRGBA(px, py, fill)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// PNG encodes the minimap image; transparent pixels are empty space
+func (m *Minimap) PNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m.Image); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawLine2D draws a one pixel wide line between pixel coordinates
+func drawLine2D(img *image.NRGBA, a, b [2]float64, c color.NRGBA) {
+	steps := int(math.Ceil(math.Max(math.Abs(b[0]-a[0]), math.Abs(b[1]-a[1])))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		p := image.Pt(int(a[0]+(b[0]-a[0])*t), int(a[1]+(b[1]-a[1])*t))
+		if p.In(img.Rect) {
+			img.SetNRGBA(p.X, p.Y, c)
+		}
+	}
+}
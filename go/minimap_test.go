@@ -0,0 +1,53 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// TestGenerateMinimap tests projection, footprints and the raster image
+func TestGenerateMinimap(t *testing.T) {
+	sf := NewSceneFile("Minimap")
+	green := NewColor(0, 1, 0)
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "a", Transform: NewTransformWithPosition(0, 0, 0), Material: &Material{Color: &green}},
+		{ID: "b", Transform: NewTransformWithPosition(99, 5, 49)},
+	}
+	sf.Scene.Edges = []SceneEdge{{ID: "ab", Source: "a", Target: "b"}}
+
+	m, err := GenerateMinimap(&sf, 200)
+	if err != nil {
+		t.Fatalf("GenerateMinimap failed: %v", err)
+	}
+	p := m.Projection
+	if p.Width != 200 || p.Height > 110 || p.Height < 100 {
+		t.Errorf("size mismatch: got %dx%d, want 200 wide and about 105 high", p.Width, p.Height)
+	}
+
+	x, y := p.ToMap(Vector3{X: 10, Z: 20})
+	back := p.ToWorld(x, y)
+	if math.Abs(back.X-10) > 1e-9 || math.Abs(back.Z-20) > 1e-9 {
+		t.Errorf("round trip mismatch: got %v, want (10, 0, 20)", back)
+	}
+
+	if len(m.Nodes) != 2 || len(m.Edges) != 1 {
+		t.Fatalf("vector data mismatch: got %d nodes and %d edges", len(m.Nodes), len(m.Edges))
+	}
+	a := m.Nodes[0]
+	if math.Abs(a.Width-p.Scale) > 1e-9 {
+		t.Errorf("footprint width mismatch: got %v, want %v", a.Width, p.Scale)
+	}
+	cx, cy := int(a.X+a.Width/2), int(a.Y+a.Height/2)
+	if c := m.Image.NRGBAAt(cx, cy); c.G != 255 || c.R != 0 {
+		t.Errorf("node pixel mismatch: got %v, want green", c)
+	}
+	if c := m.Image.NRGBAAt(p.Width-1, 0); c.A != 0 {
+		t.Errorf("empty pixel should be transparent, got %v", c)
+	}
+	if _, err := m.PNG(); err != nil {
+		t.Errorf("PNG failed: %v", err)
+	}
+	if _, err := GenerateMinimap(&sf, MaxMinimapResolution+1); err == nil {
+		t.Error("expected error for a resolution over MaxMinimapResolution")
+	}
+}
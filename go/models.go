@@ -47,14 +47,15 @@ type Color struct {
 
 // Material represents material properties for 3D rendering
 type Material struct {
-	Color       *Color  `json:"color,omitempty"`
-	Emissive    *Color  `json:"emissive,omitempty"`
-	Metalness   float64 `json:"metalness,omitempty" validate:"omitempty,min=0,max=1"`
-	Roughness   float64 `json:"roughness,omitempty" validate:"omitempty,min=0,max=1"`
-	Opacity     float64 `json:"opacity,omitempty" validate:"omitempty,min=0,max=1"`
-	Transparent bool    `json:"transparent,omitempty"`
-	Wireframe   bool    `json:"wireframe,omitempty"`
-	Texture     string  `json:"texture,omitempty"`
+	Color         *Color         `json:"color,omitempty"`
+	Emissive      *Color         `json:"emissive,omitempty"`
+	Metalness     float64        `json:"metalness,omitempty" validate:"omitempty,min=0,max=1"`
+	Roughness     float64        `json:"roughness,omitempty" validate:"omitempty,min=0,max=1"`
+	Opacity       float64        `json:"opacity,omitempty" validate:"omitempty,min=0,max=1"`
+	Transparent   bool           `json:"transparent,omitempty"`
+	Wireframe     bool           `json:"wireframe,omitempty"`
+	Texture       string         `json:"texture,omitempty"`
+	TextureRegion *TextureRegion `json:"textureRegion,omitempty"`
 }
 
 // GeometryType represents the type of geometry
@@ -73,6 +74,8 @@ type Geometry struct {
 	Type       GeometryType           `json:"type" validate:"required"`
 	Parameters map[string]interface{} `json:"parameters,omitempty"`
 	Asset      string                 `json:"asset,omitempty"`
+	Mesh       *Mesh                  `json:"mesh,omitempty"`
+	LODs       []GeometryLOD          `json:"lods,omitempty"`
 }
 
 // EasingType represents animation easing types
@@ -118,21 +121,35 @@ const (
 
 // SceneNode represents an individual node in the scene graph
 type SceneNode struct {
-	ID         string                 `json:"id" validate:"required"`
-	Type       string                 `json:"type" validate:"required"`
-	Name       string                 `json:"name" validate:"required"`
-	Transform  Transform              `json:"transform" validate:"required"`
-	Geometry   *Geometry              `json:"geometry,omitempty"`
-	Material   *Material              `json:"material,omitempty"`
-	Visible    bool                   `json:"visible,omitempty"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
-	Tags       []string               `json:"tags,omitempty"`
-	Metrics    map[string]interface{} `json:"metrics,omitempty"`
-	Status     NodeStatus             `json:"status,omitempty"`
-	Animations []Animation            `json:"animations,omitempty"`
-	Parent     string                 `json:"parent,omitempty"`
-	Children   []string               `json:"children,omitempty"`
-	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	ID            string                 `json:"id" validate:"required"`
+	Revision      int64                  `json:"revision,omitempty"`
+	Type          string                 `json:"type" validate:"required"`
+	Name          string                 `json:"name" validate:"required"`
+	Transform     Transform              `json:"transform" validate:"required"`
+	Geometry      *Geometry              `json:"geometry,omitempty"`
+	GeometryRef   string                 `json:"geometryRef,omitempty"`
+	Material      *Material              `json:"material,omitempty"`
+	MaterialRef   string                 `json:"materialRef,omitempty"`
+	Label         *Label                 `json:"label,omitempty"`
+	Visible       bool                   `json:"visible,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
+	Metrics       map[string]interface{} `json:"metrics,omitempty"`
+	Status        NodeStatus             `json:"status,omitempty"`
+	SLOs          []SLO                  `json:"slos,omitempty"`
+	Panels        []Panel                `json:"panels,omitempty"`
+	ExpiresAt     *time.Time             `json:"expiresAt,omitempty"`
+	Animations    []Animation            `json:"animations,omitempty"`
+	Particles     []ParticleSystem       `json:"particles,omitempty"`
+	Attachments   []Attachment           `json:"attachments,omitempty"`
+	Accessibility *Accessibility         `json:"accessibility,omitempty"`
+	Localizations LocalizationMap        `json:"localizations,omitempty"`
+	Ports         []Port                 `json:"ports,omitempty"`
+	Physics       *PhysicsBody           `json:"physics,omitempty"`
+	Ref           *SceneRef              `json:"ref,omitempty"`
+	Parent        string                 `json:"parent,omitempty"`
+	Children      []string               `json:"children,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // EdgeStyle represents the style of an edge
@@ -146,18 +163,31 @@ const (
 
 // SceneEdge represents a connection between two nodes
 type SceneEdge struct {
-	ID         string                 `json:"id" validate:"required"`
-	Source     string                 `json:"source" validate:"required"`
-	Target     string                 `json:"target" validate:"required"`
-	Type       string                 `json:"type,omitempty"`
-	Color      *Color                 `json:"color,omitempty"`
-	Width      float64                `json:"width,omitempty"`
-	Style      EdgeStyle              `json:"style,omitempty"`
-	Opacity    float64                `json:"opacity,omitempty" validate:"omitempty,min=0,max=1"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
-	Metrics    map[string]interface{} `json:"metrics,omitempty"`
-	Animations []Animation            `json:"animations,omitempty"`
-	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	ID            string                 `json:"id" validate:"required"`
+	Revision      int64                  `json:"revision,omitempty"`
+	Source        string                 `json:"source" validate:"required"`
+	Target        string                 `json:"target" validate:"required"`
+	TargetScene   string                 `json:"targetScene,omitempty"`
+	Type          string                 `json:"type,omitempty"`
+	Direction     EdgeDirection          `json:"direction,omitempty"`
+	Key           string                 `json:"key,omitempty"`
+	SourcePort    string                 `json:"sourcePort,omitempty"`
+	TargetPort    string                 `json:"targetPort,omitempty"`
+	Weight        float64                `json:"weight,omitempty" validate:"omitempty,min=0"`
+	Waypoints     []Vector3              `json:"waypoints,omitempty"`
+	Color         *Color                 `json:"color,omitempty"`
+	Width         float64                `json:"width,omitempty"`
+	Style         EdgeStyle              `json:"style,omitempty"`
+	Opacity       float64                `json:"opacity,omitempty" validate:"omitempty,min=0,max=1"`
+	Label         *Label                 `json:"label,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Metrics       map[string]interface{} `json:"metrics,omitempty"`
+	Animations    []Animation            `json:"animations,omitempty"`
+	Particles     []ParticleSystem       `json:"particles,omitempty"`
+	Joint         *Joint                 `json:"joint,omitempty"`
+	Localizations LocalizationMap        `json:"localizations,omitempty"`
+	ExpiresAt     *time.Time             `json:"expiresAt,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // LightType represents the type of light
@@ -219,26 +249,33 @@ type SceneGraph struct {
 
 // SceneMetadata represents scene metadata
 type SceneMetadata struct {
-	Name         string                 `json:"name" validate:"required"`
-	Description  string                 `json:"description,omitempty"`
-	Author       string                 `json:"author,omitempty"`
-	Version      string                 `json:"version,omitempty"`
-	Created      *time.Time             `json:"created,omitempty"`
-	Updated      *time.Time             `json:"updated,omitempty"`
-	Tags         []string               `json:"tags,omitempty"`
-	ImportSource string                 `json:"importSource,omitempty"`
-	ImportedAt   *time.Time             `json:"importedAt,omitempty"`
-	ImportedBy   string                 `json:"importedBy,omitempty"`
-	Extensions   map[string]interface{} `json:"extensions,omitempty"`
+	Name          string                 `json:"name" validate:"required"`
+	Description   string                 `json:"description,omitempty"`
+	Author        string                 `json:"author,omitempty"`
+	Version       string                 `json:"version,omitempty"`
+	Created       *time.Time             `json:"created,omitempty"`
+	Updated       *time.Time             `json:"updated,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
+	Locale        string                 `json:"locale,omitempty"`
+	Localizations LocalizationMap        `json:"localizations,omitempty"`
+	ImportSource  string                 `json:"importSource,omitempty"`
+	ImportedAt    *time.Time             `json:"importedAt,omitempty"`
+	ImportedBy    string                 `json:"importedBy,omitempty"`
+	Lifecycle     *Lifecycle             `json:"lifecycle,omitempty"`
+	Extensions    map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // SceneFile represents a complete scene file
 type SceneFile struct {
-	Version    string                 `json:"version" validate:"required"`
-	Metadata   SceneMetadata          `json:"metadata" validate:"required"`
-	Scene      SceneGraph             `json:"scene" validate:"required"`
-	Assets     map[string]string      `json:"assets,omitempty"`
-	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	Version      string                 `json:"version" validate:"required"`
+	Capabilities []Capability           `json:"capabilities,omitempty"`
+	Requirements *SceneRequirements     `json:"requirements,omitempty"`
+	Metadata     SceneMetadata          `json:"metadata" validate:"required"`
+	Scene        SceneGraph             `json:"scene" validate:"required"`
+	Assets       map[string]string      `json:"assets,omitempty"`
+	Materials    map[string]Material    `json:"materials,omitempty"`
+	Geometries   map[string]Geometry    `json:"geometries,omitempty"`
+	Extensions   map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // =============================================================================
@@ -261,6 +298,7 @@ type ProviderConfig map[string]interface{}
 // MetricsQuery represents a query for metrics data
 type MetricsQuery struct {
 	NodeIDs     []string               `json:"nodeIds,omitempty"`
+	EdgeIDs     []string               `json:"edgeIds,omitempty"`
 	MetricNames []string               `json:"metricNames,omitempty"`
 	From        *time.Time             `json:"from,omitempty"`
 	To          *time.Time             `json:"to,omitempty"`
@@ -275,9 +313,11 @@ type MetricsDataPoint struct {
 	Tags      map[string]string `json:"tags,omitempty"`
 }
 
-// MetricsResult represents the result of a metrics query
+// MetricsResult represents the result of a metrics query. Results for
+// edges set EdgeID instead of NodeID.
 type MetricsResult struct {
-	NodeID     string                 `json:"nodeId" validate:"required"`
+	NodeID     string                 `json:"nodeId,omitempty" validate:"required_without=EdgeID"`
+	EdgeID     string                 `json:"edgeId,omitempty"`
 	MetricName string                 `json:"metricName" validate:"required"`
 	DataPoints []MetricsDataPoint     `json:"dataPoints" validate:"required"`
 	Unit       string                 `json:"unit,omitempty"`
@@ -360,7 +400,7 @@ func NewColorWithAlpha(r, g, b, a float64) Color {
 func NewSceneFile(name string) SceneFile {
 	now := time.Now()
 	return SceneFile{
-		Version: "0.1.0",
+		Version: SchemaVersion,
 		Metadata: SceneMetadata{
 			Name:    name,
 			Created: &now,
@@ -379,7 +419,8 @@ func (sf *SceneFile) AddNode(node SceneNode) {
 	sf.Scene.Nodes = append(sf.Scene.Nodes, node)
 }
 
-// AddEdge adds an edge to the scene graph
+// AddEdge adds an edge to the scene graph as is, without checking its
+// endpoints; ConnectNodes checks them
 func (sf *SceneFile) AddEdge(edge SceneEdge) {
 	sf.Scene.Edges = append(sf.Scene.Edges, edge)
 }
@@ -0,0 +1,352 @@
+package starfleet
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// =============================================================================
+// OPENING SCENE FILES
+// =============================================================================
+
+// SceneFormat is an encoding a scene file can be stored in
+type SceneFormat string
+
+const (
+	SceneFormatUnknown     SceneFormat = ""
+	SceneFormatJSON        SceneFormat = "json"
+	SceneFormatNDJSON      SceneFormat = "ndjson"
+	SceneFormatYAML        SceneFormat = "yaml"
+	SceneFormatFlatBuffers SceneFormat = "flatbuffers"
+	SceneFormatIndexed     SceneFormat = "indexed"
+	SceneFormatDSL         SceneFormat = "dsl"
+	SceneFormatProtobuf    SceneFormat = "protobuf"
+	SceneFormatGzip        SceneFormat = "gzip"
+	SceneFormatZip         SceneFormat = "zip"
+)
+
+var (
+	// ErrUnknownFormat is returned when neither the content nor the name of
+	// a file says what format it is in
+	ErrUnknownFormat = errors.New("unknown scene format")
+	// ErrUnsupportedFormat is returned for a detected format there is no
+	// decoder for. The SDK defines no protobuf message for scenes, so
+	// callers with one set a decoder for it in OpenOptions.Decoders.
+	ErrUnsupportedFormat = errors.New("unsupported scene format")
+	// ErrSceneTooLarge is returned when a file, or what it decompresses to,
+	// exceeds OpenOptions.MaxSize
+	ErrSceneTooLarge = errors.New("scene file too large")
+)
+
+// SceneDecoder decodes a whole file in one format
+type SceneDecoder func(data []byte) (SceneFile, error)
+
+// OpenOptions controls OpenReader
+type OpenOptions struct {
+	// Decoders adds or replaces the decoder of a format. Containers are
+	// always unpacked by the SDK.
+	Decoders map[SceneFormat]SceneDecoder
+	// MaxSize bounds the bytes read, and the bytes a container unpacks to;
+	// unlimited when zero
+	MaxSize int64
+}
+
+// DefaultOpenOptions returns options that read files up to 256 MiB with the
+// built-in decoders
+func DefaultOpenOptions() OpenOptions {
+	return OpenOptions{MaxSize: 256 << 20}
+}
+
+// builtinDecoders decode the formats the SDK reads itself
+var builtinDecoders = map[SceneFormat]SceneDecoder{
+	SceneFormatJSON: func(data []byte) (SceneFile, error) {
+		var sf SceneFile
+		err := json.Unmarshal(data, &sf)
+		return sf, err
+	},
+	SceneFormatNDJSON: func(data []byte) (SceneFile, error) {
+		return DecodeSceneNDJSON(bytes.NewReader(data))
+	},
+	SceneFormatFlatBuffers: func(data []byte) (SceneFile, error) {
+		flat, err := OpenFlatScene(data)
+		if err != nil {
+			return SceneFile{}, err
+		}
+		return flat.SceneFile()
+	},
+	SceneFormatYAML:    DecodeSceneYAML,
+	SceneFormatIndexed: decodeIndexedScene,
+	SceneFormatDSL:     ParseSceneDSL,
+}
+
+// maxContainerDepth bounds containers nested in containers, such as a
+// gzipped zip
+const maxContainerDepth = 2
+
+// Open reads a scene file in any format DetectSceneFormat recognizes and
+// the SDK can decode, with DefaultOpenOptions
+func Open(name string) (SceneFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return SceneFile{}, err
+	}
+	defer f.Close()
+	return OpenReader(f, name, DefaultOpenOptions())
+}
+
+// OpenReader reads a scene file from r. name is used for its extension
+// when the content does not identify the format, and may be empty.
+func OpenReader(r io.Reader, name string, opts OpenOptions) (SceneFile, error) {
+	data, err := readLimited(r, opts.MaxSize)
+	if err != nil {
+		return SceneFile{}, err
+	}
+	return decodeScene(data, name, opts, 0)
+}
+
+func decodeScene(data []byte, name string, opts OpenOptions, depth int) (SceneFile, error) {
+	format := DetectSceneFormat(name, data)
+	switch format {
+	case SceneFormatUnknown:
+		return SceneFile{}, fmt.Errorf("%w: %s", ErrUnknownFormat, displayName(name))
+	case SceneFormatGzip, SceneFormatZip:
+		if depth == maxContainerDepth {
+			return SceneFile{}, fmt.Errorf("%w: containers nested too deep in %s", ErrUnsupportedFormat, displayName(name))
+		}
+		inner, innerName, err := unpackScene(format, data, name, opts.MaxSize)
+		if err != nil {
+			return SceneFile{}, fmt.Errorf("unpack %s: %w", displayName(name), err)
+		}
+		return decodeScene(inner, innerName, opts, depth+1)
+	}
+
+	decode, ok := opts.Decoders[format]
+	if !ok {
+		decode, ok = builtinDecoders[format]
+	}
+	if !ok {
+		return SceneFile{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	sf, err := decode(data)
+	if err != nil {
+		return SceneFile{}, fmt.Errorf("decode %s as %s: %w", displayName(name), format, err)
+	}
+	return sf, nil
+}
+
+// DetectSceneFormat names the format of a file from its first bytes, or
+// from its name when they are not conclusive. head may be a prefix of the
+// file; 512 bytes are enough.
+func DetectSceneFormat(name string, head []byte) SceneFormat {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return SceneFormatGzip
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return SceneFormatZip
+	case len(head) >= 8 && string(head[4:8]) == flatSceneIdentifier:
+		return SceneFormatFlatBuffers
+	case bytes.HasPrefix(head, []byte(indexedSceneMagic)):
+		return SceneFormatIndexed
+	}
+
+	text := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	ext := strings.ToLower(path.Ext(name))
+	if len(text) > 0 && text[0] == '{' {
+		// A complete object on the first line followed by another is a
+		// stream; indented JSON opens with a line of its own
+		first, rest, _ := bytes.Cut(text, []byte("\n"))
+		rest = bytes.TrimLeft(rest, " \t\r\n")
+		if ext == ".ndjson" || ext == ".jsonl" || (json.Valid(first) && len(rest) > 0 && rest[0] == '{') {
+			return SceneFormatNDJSON
+		}
+		return SceneFormatJSON
+	}
+
+	switch ext {
+	case ".json":
+		return SceneFormatJSON
+	case ".ndjson", ".jsonl":
+		return SceneFormatNDJSON
+	case ".yaml", ".yml":
+		return SceneFormatYAML
+	case ".sfb":
+		return SceneFormatFlatBuffers
+	case ".sfi":
+		return SceneFormatIndexed
+	case ".sfd":
+		return SceneFormatDSL
+	case ".pb", ".binpb":
+		return SceneFormatProtobuf
+	case ".gz":
+		return SceneFormatGzip
+	case ".zip":
+		return SceneFormatZip
+	}
+
+	switch {
+	case bytes.HasPrefix(text, []byte("---")), bytes.HasPrefix(text, []byte("%YAML")), looksLikeYAML(text):
+		return SceneFormatYAML
+	case len(head) > 0 && head[0] == 0x0a:
+		// Field 1 as a length-delimited value, as scene messages start
+		// with their version string
+		return SceneFormatProtobuf
+	}
+	return SceneFormatUnknown
+}
+
+// looksLikeYAML reports whether text opens with a "key:" mapping line
+func looksLikeYAML(text []byte) bool {
+	line, _, _ := bytes.Cut(text, []byte("\n"))
+	key, _, ok := bytes.Cut(line, []byte(":"))
+	if !ok || len(key) == 0 {
+		return false
+	}
+	for _, c := range key {
+		if !(c == '_' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// unpackScene returns the scene file a container holds and its name. A zip
+// holds it in the entry named scene.*, or else in the first entry whose
+// name has a scene format's extension.
+func unpackScene(format SceneFormat, data []byte, name string, limit int64) ([]byte, string, error) {
+	if format == SceneFormatGzip {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", err
+		}
+		defer zr.Close()
+		inner, err := readLimited(zr, limit)
+		innerName := strings.TrimSuffix(name, path.Ext(name))
+		if zr.Name != "" {
+			innerName = zr.Name
+		}
+		return inner, innerName, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", err
+	}
+	var entry *zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		base := path.Base(f.Name)
+		if strings.TrimSuffix(base, path.Ext(base)) == "scene" {
+			entry = f
+			break
+		}
+		if entry == nil && DetectSceneFormat(base, nil) != SceneFormatUnknown {
+			entry = f
+		}
+	}
+	if entry == nil {
+		return nil, "", errors.New("no scene file in archive")
+	}
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	inner, err := readLimited(rc, limit)
+	return inner, entry.Name, err
+}
+
+// readLimited reads r to the end, failing with ErrSceneTooLarge past limit
+// bytes unless limit is zero
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrSceneTooLarge, limit)
+	}
+	return data, nil
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "input"
+	}
+	return name
+}
+
+// =============================================================================
+// NDJSON
+// =============================================================================
+
+// ndjsonRecord is a line after the first of an NDJSON scene
+type ndjsonRecord struct {
+	Node *SceneNode `json:"node,omitempty"`
+	Edge *SceneEdge `json:"edge,omitempty"`
+}
+
+// EncodeSceneNDJSON writes a scene as newline-delimited JSON: the scene file
+// without its nodes and edges on the first line, then a {"node": ...} or
+// {"edge": ...} line for each. Large scenes can be appended to and read
+// line by line.
+func EncodeSceneNDJSON(w io.Writer, sf *SceneFile) error {
+	header := *sf
+	header.Scene.Nodes = []SceneNode{}
+	header.Scene.Edges = []SceneEdge{}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for i := range sf.Scene.Nodes {
+		if err := enc.Encode(ndjsonRecord{Node: &sf.Scene.Nodes[i]}); err != nil {
+			return err
+		}
+	}
+	for i := range sf.Scene.Edges {
+		if err := enc.Encode(ndjsonRecord{Edge: &sf.Scene.Edges[i]}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// DecodeSceneNDJSON reads a scene written by EncodeSceneNDJSON. Nodes and
+// edges on the first line are kept, ahead of those on later lines.
+func DecodeSceneNDJSON(r io.Reader) (SceneFile, error) {
+	dec := json.NewDecoder(r)
+	var sf SceneFile
+	if err := dec.Decode(&sf); err != nil {
+		return SceneFile{}, fmt.Errorf("scene header: %w", err)
+	}
+	for line := 2; ; line++ {
+		var rec ndjsonRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return sf, nil
+		} else if err != nil {
+			return SceneFile{}, fmt.Errorf("record %d: %w", line, err)
+		}
+		switch {
+		case rec.Node != nil && rec.Edge == nil:
+			sf.Scene.Nodes = append(sf.Scene.Nodes, *rec.Node)
+		case rec.Edge != nil && rec.Node == nil:
+			sf.Scene.Edges = append(sf.Scene.Edges, *rec.Edge)
+		default:
+			return SceneFile{}, fmt.Errorf("record %d: want one of node or edge", line)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package starfleet
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDetectSceneFormat tests sniffing formats from content and names
+func TestDetectSceneFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want SceneFormat
+	}{
+		{"scene.json", `{"version":"1.0"}`, SceneFormatJSON},
+		{"", "{\n  \"version\": \"1.0\"\n}\n{", SceneFormatJSON},
+		{"", "\xef\xbb\xbf {\"version\":\"1.0\"}\n", SceneFormatJSON},
+		{"", "{\"version\":\"1.0\"}\n{\"node\":{}}\n", SceneFormatNDJSON},
+		{"scene.jsonl", `{"version":"1.0"}`, SceneFormatNDJSON},
+		{"scene.yaml", "version: 1.0\n", SceneFormatYAML},
+		{"", "---\nversion: 1.0\n", SceneFormatYAML},
+		{"", "version: 1.0\n", SceneFormatYAML},
+		{"", "\x1f\x8b\x08", SceneFormatGzip},
+		{"scene.json", "PK\x03\x04", SceneFormatZip},
+		{"", "\x10\x00\x00\x00SFFB", SceneFormatFlatBuffers},
+		{"scene.pb", "\x12\x03abc", SceneFormatProtobuf},
+		{"", "\x0a\x031.0", SceneFormatProtobuf},
+		{"scene.txt", "hello world", SceneFormatUnknown},
+	}
+	for _, tt := range tests {
+		if got := DetectSceneFormat(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("DetectSceneFormat(%q, %q) mismatch: got %q, want %q", tt.name, tt.head, got, tt.want)
+		}
+	}
+}
+
+// TestOpen tests opening the same scene from each built-in format
+func TestOpen(t *testing.T) {
+	sf := newFlatScene()
+	want, _ := json.Marshal(sf)
+
+	var ndjson bytes.Buffer
+	if err := EncodeSceneNDJSON(&ndjson, &sf); err != nil {
+		t.Fatalf("EncodeSceneNDJSON failed: %v", err)
+	}
+	indented, _ := json.MarshalIndent(sf, "", "  ")
+	flat, _ := EncodeFlatScene(&sf)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(ndjson.Bytes())
+	zw.Close()
+
+	var archive bytes.Buffer
+	ar := zip.NewWriter(&archive)
+	readme, _ := ar.Create("README.txt")
+	readme.Write([]byte("not a scene"))
+	entry, _ := ar.Create("data/scene.sfb")
+	entry.Write(flat)
+	ar.Close()
+
+	var doc interface{}
+	json.Unmarshal(want, &doc)
+	var yaml strings.Builder
+	encodeTestYAML(&yaml, doc, 0)
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"scene.json":      indented,
+		"scene.yml":       []byte(yaml.String()),
+		"scene.ndjson":    ndjson.Bytes(),
+		"scene.sfb":       flat,
+		"scene.ndjson.gz": gz.Bytes(),
+		"bundle.zip":      archive.Bytes(),
+		"noext":           ndjson.Bytes(),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Open(path)
+		if err != nil {
+			t.Errorf("Open(%s) failed: %v", name, err)
+			continue
+		}
+		if gotJSON, _ := json.Marshal(got); string(gotJSON) != string(want) {
+			t.Errorf("Open(%s) mismatch: got %s, want %s", name, gotJSON, want)
+		}
+	}
+}
+
+// TestOpenReader_Errors tests unknown, unsupported, oversized and broken
+// input, and plugging in a decoder
+func TestOpenReader_Errors(t *testing.T) {
+	proto := []byte("\x0a\x031.0")
+	if _, err := OpenReader(bytes.NewReader(proto), "scene.pb", DefaultOpenOptions()); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrUnsupportedFormat)
+	}
+	opts := DefaultOpenOptions()
+	opts.Decoders = map[SceneFormat]SceneDecoder{
+		SceneFormatProtobuf: func(data []byte) (SceneFile, error) { return NewSceneFile("P"), nil },
+	}
+	if sf, err := OpenReader(bytes.NewReader(proto), "", opts); err != nil || sf.Metadata.Name != "P" {
+		t.Errorf("custom decoder mismatch: got %q, %v", sf.Metadata.Name, err)
+	}
+
+	if _, err := OpenReader(bytes.NewReader([]byte("plain text")), "", DefaultOpenOptions()); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrUnknownFormat)
+	}
+
+	// A small gzip that unpacks past the limit
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(bytes.Repeat([]byte(" "), 4096))
+	zw.Close()
+	if _, err := OpenReader(&gz, "", OpenOptions{MaxSize: 1024}); !errors.Is(err, ErrSceneTooLarge) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrSceneTooLarge)
+	}
+
+	bad := []byte("{\"version\":\"1.0\"}\n{\"node\":{\"id\":\"a\"},\"edge\":{\"id\":\"e\"}}\n")
+	if _, err := OpenReader(bytes.NewReader(bad), "", DefaultOpenOptions()); err == nil {
+		t.Error("expected error for a record with a node and an edge")
+	}
+}
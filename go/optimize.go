@@ -0,0 +1,335 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// =============================================================================
+// SCENE OPTIMIZATION
+// =============================================================================
+
+// OptimizeOptions represents the passes applied by Optimize
+type OptimizeOptions struct {
+	// Quantize rounds spatial and visual floats to Decimals decimal places
+	Quantize bool
+	Decimals int
+	// QuantizeMetrics also rounds numeric node and edge metrics
+	QuantizeMetrics bool
+	// StripDefaults clears values equal to the SDK defaults and empty
+	// collections so they are omitted from JSON
+	StripDefaults bool
+	// InternStrings makes repeated strings share one allocation
+	InternStrings bool
+	// ShareResources makes nodes with identical materials or geometries
+	// share a single definition
+	ShareResources bool
+	// HoistResources moves repeated materials and geometries into the scene
+	// libraries. Consumers must understand material and geometry references.
+	HoistResources bool
+}
+
+// DefaultOptimizeOptions returns options enabling every pass with three
+// decimal places, which is millimeter precision for scenes in meters
+func DefaultOptimizeOptions() OptimizeOptions {
+	return OptimizeOptions{
+		Quantize:       true,
+		Decimals:       3,
+		StripDefaults:  true,
+		InternStrings:  true,
+		ShareResources: true,
+	}
+}
+
+// OptimizeReport represents what Optimize changed
+type OptimizeReport struct {
+	QuantizedValues  int `json:"quantizedValues"`
+	StrippedFields   int `json:"strippedFields"`
+	InternedStrings  int `json:"internedStrings"`
+	SharedMaterials  int `json:"sharedMaterials"`
+	SharedGeometries int `json:"sharedGeometries"`
+	HoistedResources int `json:"hoistedResources"`
+}
+
+// Optimize reduces the size of a scene in place. Quantization and default
+// stripping shrink the serialized form; string interning and resource
+// sharing shrink the in-memory form of decoded scenes.
+func Optimize(sf *SceneFile, opts OptimizeOptions) OptimizeReport {
+	report := OptimizeReport{}
+	if opts.Quantize {
+		quantizeScene(sf, opts, &report)
+	}
+	if opts.StripDefaults {
+		stripDefaults(sf, &report)
+	}
+	if opts.InternStrings {
+		internStrings(sf, &report)
+	}
+	if opts.ShareResources {
+		shareResources(sf, &report)
+	}
+	if opts.HoistResources {
+		report.HoistedResources = HoistResources(sf, 2)
+	}
+	return report
+}
+
+// quantizer rounds floats to a fixed number of decimals and counts changes
+type quantizer struct {
+	scale  float64
+	report *OptimizeReport
+}
+
+// f rounds a value in place
+func (q quantizer) f(v *float64) {
+	if math.IsNaN(*v) || math.IsInf(*v, 0) {
+		return
+	}
+	r := math.Round(*v*q.scale) / q.scale
+	if r != *v {
+		*v = r
+		q.report.QuantizedValues++
+	}
+}
+
+// vec rounds a vector in place
+func (q quantizer) vec(v *Vector3) {
+	if v != nil {
+		q.f(&v.X)
+		q.f(&v.Y)
+		q.f(&v.Z)
+	}
+}
+
+// color rounds a color in place
+func (q quantizer) color(c *Color) {
+	if c != nil {
+		q.f(&c.R)
+		q.f(&c.G)
+		q.f(&c.B)
+		q.f(&c.A)
+	}
+}
+
+// transform rounds a transform in place
+func (q quantizer) transform(t *Transform) {
+	q.vec(&t.Position)
+	q.f(&t.Rotation.X)
+	q.f(&t.Rotation.Y)
+	q.f(&t.Rotation.Z)
+	q.f(&t.Scale.X)
+	q.f(&t.Scale.Y)
+	q.f(&t.Scale.Z)
+}
+
+// material rounds a material in place
+func (q quantizer) material(m *Material) {
+	if m != nil {
+		q.color(m.Color)
+		q.color(m.Emissive)
+		q.f(&m.Metalness)
+		q.f(&m.Roughness)
+		q.f(&m.Opacity)
+	}
+}
+
+// floats rounds every value of a slice in place
+func (q quantizer) floats(values []float64) {
+	for i := range values {
+		q.f(&values[i])
+	}
+}
+
+// metrics rounds numeric float metrics in place
+func (q quantizer) metrics(m map[string]interface{}) {
+	for k, v := range m {
+		if f, ok := v.(float64); ok {
+			q.f(&f)
+			m[k] = f
+		}
+	}
+}
+
+// quantizeScene rounds spatial and visual values across the scene
+func quantizeScene(sf *SceneFile, opts OptimizeOptions, report *OptimizeReport) {
+	q := quantizer{scale: math.Pow(10, float64(opts.Decimals)), report: report}
+	materials := make(map[*Material]bool)
+	geometries := make(map[*Geometry]bool)
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		q.transform(&n.Transform)
+		if n.Material != nil && !materials[n.Material] {
+			materials[n.Material] = true
+			q.material(n.Material)
+		}
+		if n.Geometry != nil && n.Geometry.Mesh != nil && !geometries[n.Geometry] {
+			geometries[n.Geometry] = true
+			q.floats(n.Geometry.Mesh.Positions)
+			q.floats(n.Geometry.Mesh.Normals)
+		}
+		if n.Label != nil {
+			q.vec(n.Label.Offset)
+		}
+		if opts.QuantizeMetrics {
+			q.metrics(n.Metrics)
+		}
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		q.color(e.Color)
+		q.f(&e.Width)
+		q.f(&e.Opacity)
+		q.f(&e.Weight)
+		for j := range e.Waypoints {
+			q.vec(&e.Waypoints[j])
+		}
+		if e.Label != nil {
+			q.vec(e.Label.Offset)
+		}
+		if opts.QuantizeMetrics {
+			q.metrics(e.Metrics)
+		}
+	}
+	for key, m := range sf.Materials {
+		q.material(&m)
+		sf.Materials[key] = m
+	}
+	for key, g := range sf.Geometries {
+		if g.Mesh != nil {
+			q.floats(g.Mesh.Positions)
+			q.floats(g.Mesh.Normals)
+		}
+		sf.Geometries[key] = g
+	}
+	if b := sf.Scene.Bounds; b != nil {
+		q.vec(&b.Min)
+		q.vec(&b.Max)
+	}
+	if c := sf.Scene.Camera; c != nil {
+		q.vec(&c.Position)
+		q.vec(&c.Target)
+	}
+}
+
+// stripDefaults clears values equal to SDK defaults and empty collections
+func stripDefaults(sf *SceneFile, report *OptimizeReport) {
+	strip := func(cond bool, clear func()) {
+		if cond {
+			clear()
+			report.StrippedFields++
+		}
+	}
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		strip(n.Metadata != nil && len(n.Metadata) == 0, func() { n.Metadata = nil })
+		strip(n.Metrics != nil && len(n.Metrics) == 0, func() { n.Metrics = nil })
+		strip(n.Extensions != nil && len(n.Extensions) == 0, func() { n.Extensions = nil })
+		strip(n.Tags != nil && len(n.Tags) == 0, func() { n.Tags = nil })
+		strip(n.Children != nil && len(n.Children) == 0, func() { n.Children = nil })
+		strip(n.Material != nil && *n.Material == Material{}, func() { n.Material = nil })
+		strip(n.Geometry != nil && len(n.Geometry.Parameters) == 0 && n.Geometry.Parameters != nil,
+			func() { n.Geometry.Parameters = nil })
+		if n.Label != nil {
+			strip(n.Label.Style != nil && *n.Label.Style == TextStyle{}, func() { n.Label.Style = nil })
+		}
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		strip(e.Direction == EdgeDirected, func() { e.Direction = "" })
+		strip(e.Weight == 1, func() { e.Weight = 0 })
+		strip(e.Metadata != nil && len(e.Metadata) == 0, func() { e.Metadata = nil })
+		strip(e.Metrics != nil && len(e.Metrics) == 0, func() { e.Metrics = nil })
+		strip(e.Extensions != nil && len(e.Extensions) == 0, func() { e.Extensions = nil })
+		strip(e.Waypoints != nil && len(e.Waypoints) == 0, func() { e.Waypoints = nil })
+	}
+	strip(sf.Assets != nil && len(sf.Assets) == 0, func() { sf.Assets = nil })
+	strip(sf.Extensions != nil && len(sf.Extensions) == 0, func() { sf.Extensions = nil })
+}
+
+// internStrings makes repeated strings share storage
+func internStrings(sf *SceneFile, report *OptimizeReport) {
+	table := make(map[string]string)
+	intern := func(s *string) {
+		if *s == "" {
+			return
+		}
+		if canonical, ok := table[*s]; ok {
+			*s = canonical
+			report.InternedStrings++
+			return
+		}
+		table[*s] = *s
+	}
+	internMap := func(m map[string]interface{}) {
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				intern(&s)
+				m[k] = s
+			}
+		}
+	}
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		intern(&n.ID)
+		intern(&n.Type)
+		intern(&n.Parent)
+		for j := range n.Tags {
+			intern(&n.Tags[j])
+		}
+		for j := range n.Children {
+			intern(&n.Children[j])
+		}
+		internMap(n.Metadata)
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		intern(&e.Type)
+		intern(&e.Source)
+		intern(&e.Target)
+		internMap(e.Metadata)
+	}
+}
+
+// shareResources points nodes with identical materials or geometries at a
+// single shared definition
+func shareResources(sf *SceneFile, report *OptimizeReport) {
+	materials := make(map[string]*Material)
+	geometries := make(map[string]*Geometry)
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Material != nil {
+			if key, ok := resourceKey(n.Material); ok {
+				if shared, exists := materials[key]; exists && shared != n.Material {
+					n.Material = shared
+					report.SharedMaterials++
+				} else if !exists {
+					materials[key] = n.Material
+				}
+			}
+		}
+		if n.Geometry != nil {
+			if key, ok := resourceKey(n.Geometry); ok {
+				if shared, exists := geometries[key]; exists && shared != n.Geometry {
+					n.Geometry = shared
+					report.SharedGeometries++
+				} else if !exists {
+					geometries[key] = n.Geometry
+				}
+			}
+		}
+	}
+}
+
+// resourceKey returns a canonical identity for a value based on its JSON
+// encoding, in which map keys are sorted
+func resourceKey(v interface{}) (string, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
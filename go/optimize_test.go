@@ -0,0 +1,75 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newOptimizeScene creates a scene with redundant precision and resources
+func newOptimizeScene() SceneFile {
+	sf := NewSceneFile("Optimize")
+	for _, id := range []string{"a", "b", "c"} {
+		sf.AddNode(SceneNode{
+			ID:        id,
+			Type:      "server",
+			Name:      id,
+			Transform: NewTransformWithPosition(1.23456789, 2.000000001, -3.14159265),
+			Geometry:  &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{"width": 2.0}},
+			Material:  &Material{Color: &Color{R: 0.2000001, G: 0.4, B: 0.6, A: 1}, Roughness: 0.5},
+			Metadata:  map[string]interface{}{},
+			Metrics:   map[string]interface{}{"cpu": 42.123456},
+			Tags:      []string{"prod"},
+		})
+	}
+	sf.AddEdge(SceneEdge{ID: "ab", Source: "a", Target: "b", Direction: EdgeDirected, Weight: 1})
+	return sf
+}
+
+// TestOptimize tests quantization, default stripping and resource sharing
+func TestOptimize(t *testing.T) {
+	sf := newOptimizeScene()
+	before, _ := json.Marshal(sf)
+
+	report := Optimize(&sf, DefaultOptimizeOptions())
+	after, _ := json.Marshal(sf)
+
+	if len(after) >= len(before) {
+		t.Errorf("Optimize did not shrink the scene: %d -> %d bytes", len(before), len(after))
+	}
+	a := sf.FindNode("a")
+	if a.Transform.Position != (Vector3{X: 1.235, Y: 2, Z: -3.142}) {
+		t.Errorf("Quantized position mismatch: got %+v", a.Transform.Position)
+	}
+	if a.Material.Color.R != 0.2 {
+		t.Errorf("Quantized color mismatch: got %f", a.Material.Color.R)
+	}
+	if a.Metrics["cpu"] != 42.123456 {
+		t.Errorf("Metrics should not be quantized by default: got %v", a.Metrics["cpu"])
+	}
+	if a.Metadata != nil {
+		t.Errorf("Empty metadata not stripped")
+	}
+	edge := sf.FindEdge("ab")
+	if edge.Direction != "" || edge.Weight != 0 || !edge.IsDirected() || edge.EffectiveWeight() != 1 {
+		t.Errorf("Edge defaults not stripped equivalently: %+v", edge)
+	}
+
+	if report.SharedMaterials != 2 || report.SharedGeometries != 2 {
+		t.Errorf("Shared resource counts mismatch: %+v", report)
+	}
+	if sf.FindNode("b").Material != a.Material || sf.FindNode("c").Geometry != a.Geometry {
+		t.Errorf("Identical resources should share one definition")
+	}
+	if report.QuantizedValues == 0 || report.StrippedFields == 0 || report.InternedStrings == 0 {
+		t.Errorf("Optimize report incomplete: %+v", report)
+	}
+}
+
+// TestOptimize_Metrics tests opt-in metric quantization
+func TestOptimize_Metrics(t *testing.T) {
+	sf := newOptimizeScene()
+	Optimize(&sf, OptimizeOptions{Quantize: true, Decimals: 1, QuantizeMetrics: true})
+	if got := sf.FindNode("a").Metrics["cpu"]; got != 42.1 {
+		t.Errorf("Quantized metric mismatch: got %v, want 42.1", got)
+	}
+}
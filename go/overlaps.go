@@ -0,0 +1,190 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// =============================================================================
+// OVERLAP RESOLUTION
+// =============================================================================
+
+// OverlapOptions represents the configuration of ResolveOverlaps
+type OverlapOptions struct {
+	// Padding is the minimum gap kept between node bounds
+	Padding float64
+	// Pinned lists nodes that must not move, in addition to nodes with
+	// PinnedMetadataKey set
+	Pinned []string
+	// KeepHeight only moves nodes horizontally, for scenes laid out on a
+	// ground plane
+	KeepHeight bool
+	// Iterations bounds the number of relaxation sweeps; 200 when zero
+	Iterations int
+}
+
+// overlapSlack is the extra separation, relative to the pair size, added
+// when pushing nodes apart
+const overlapSlack = 0.01
+
+// nodeBox is the axis-aligned bounds of a node during overlap resolution
+type nodeBox struct {
+	center, half Vector3
+	pinned       bool
+}
+
+// FindOverlaps returns the pairs of nodes whose bounds intersect, with at
+// least padding between them counted as overlap. Nodes in an ancestor and
+// descendant relationship, such as a rack and its servers, never overlap.
+func FindOverlaps(sf *SceneFile, padding float64) [][2]string {
+	boxes := overlapBoxes(sf, nil)
+	var pairs [][2]string
+	sweepOverlaps(sf, boxes, padding, func(i, j int, _ Vector3) {
+		pairs = append(pairs, [2]string{sf.Scene.Nodes[i].ID, sf.Scene.Nodes[j].ID})
+	})
+	return pairs
+}
+
+// ResolveOverlaps moves nodes apart until their bounds no longer intersect.
+// Each overlapping pair is separated along the axis of least penetration,
+// which is the smallest displacement that resolves it; unpinned nodes share
+// the move and a node paired with a pinned one takes all of it. Resolving
+// one pair can create another, so sweeps repeat until none remain.
+func ResolveOverlaps(sf *SceneFile, opts OverlapOptions) LayoutResult {
+	result := LayoutResult{}
+	boxes := overlapBoxes(sf, &LayoutConstraints{Pinned: opts.Pinned})
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = 200
+	}
+
+	remaining := 0
+	for iter := 0; iter < iterations; iter++ {
+		remaining = 0
+		// Moves are accumulated and applied after the sweep so the result
+		// does not depend on pair order and nodes keep their arrangement
+		moves := make([]Vector3, len(boxes))
+		sweepOverlaps(sf, boxes, opts.Padding, func(i, j int, depth Vector3) {
+			remaining++
+			a, b := &boxes[i], &boxes[j]
+			if a.pinned && b.pinned {
+				return
+			}
+			axis, amount := AxisX, depth.X
+			if depth.Z < amount {
+				axis, amount = AxisZ, depth.Z
+			}
+			if !opts.KeepHeight && depth.Y < amount {
+				axis, amount = AxisY, depth.Y
+			}
+			// A slight overshoot stops chains of shared moves from only
+			// converging asymptotically
+			amount += overlapSlack * (*axis.component(&a.half) + *axis.component(&b.half) + opts.Padding)
+			sign := 1.0
+			if ca, cb := *axis.component(&a.center), *axis.component(&b.center); cb < ca || (cb == ca && i%2 == 1) {
+				sign = -1
+			}
+			ma, mb := axis.component(&moves[i]), axis.component(&moves[j])
+			switch {
+			case a.pinned:
+				*mb += sign * amount
+			case b.pinned:
+				*ma -= sign * amount
+			default:
+				*ma -= sign * amount / 2
+				*mb += sign * amount / 2
+			}
+		})
+		for i := range boxes {
+			boxes[i].center = boxes[i].center.Add(moves[i])
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+
+	for i := range boxes {
+		node := &sf.Scene.Nodes[i]
+		if node.Transform.Position != boxes[i].center {
+			node.Transform.Position = boxes[i].center
+			result.Positioned++
+		}
+	}
+	if remaining > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%d overlaps remain after %d iterations", len(FindOverlaps(sf, opts.Padding)), iterations))
+	}
+	return result
+}
+
+// overlapBoxes returns the bounds of every node; pins are only evaluated
+// when constraints are given
+func overlapBoxes(sf *SceneFile, c *LayoutConstraints) []nodeBox {
+	boxes := make([]nodeBox, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		boxes[i] = nodeBox{
+			center: node.Transform.Position,
+			half:   nodeHalfExtents(node, sf.ResolveGeometry(node)),
+			pinned: c != nil && c.isPinned(node),
+		}
+	}
+	return boxes
+}
+
+// sweepOverlaps calls visit with the penetration depth per axis for every
+// pair of intersecting boxes, using sweep and prune along X
+func sweepOverlaps(sf *SceneFile, boxes []nodeBox, padding float64, visit func(i, j int, depth Vector3)) {
+	order := make([]int, len(boxes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return boxes[order[a]].center.X-boxes[order[a]].half.X < boxes[order[b]].center.X-boxes[order[b]].half.X
+	})
+
+	parents := make(map[string]string, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		parents[sf.Scene.Nodes[i].ID] = sf.Scene.Nodes[i].Parent
+	}
+	related := func(a, b string) bool {
+		return isAncestor(parents, a, b) || isAncestor(parents, b, a)
+	}
+
+	for k, i := range order {
+		maxX := boxes[i].center.X + boxes[i].half.X + padding
+		for _, j := range order[k+1:] {
+			if boxes[j].center.X-boxes[j].half.X >= maxX {
+				break
+			}
+			a, b := boxes[i], boxes[j]
+			depth := Vector3{
+				X: a.half.X + b.half.X + padding - math.Abs(a.center.X-b.center.X),
+				Y: a.half.Y + b.half.Y + padding - math.Abs(a.center.Y-b.center.Y),
+				Z: a.half.Z + b.half.Z + padding - math.Abs(a.center.Z-b.center.Z),
+			}
+			if depth.X <= 1e-9 || depth.Y <= 1e-9 || depth.Z <= 1e-9 {
+				continue
+			}
+			if related(sf.Scene.Nodes[i].ID, sf.Scene.Nodes[j].ID) {
+				continue
+			}
+			lo, hi := min(i, j), max(i, j)
+			visit(lo, hi, depth)
+		}
+	}
+}
+
+// isAncestor reports whether ancestor is above node in the Parent chain,
+// guarding against cycles
+func isAncestor(parents map[string]string, ancestor, node string) bool {
+	seen := 0
+	for p := parents[node]; p != "" && seen <= len(parents); p = parents[p] {
+		if p == ancestor {
+			return true
+		}
+		seen++
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package starfleet
+
+import "testing"
+
+// TestResolveOverlaps tests separation with a pinned node
+func TestResolveOverlaps(t *testing.T) {
+	sf := NewSceneFile("Overlaps")
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{
+			ID:        id,
+			Transform: NewTransformWithPosition(float64(i)*0.3, 0, 0),
+		})
+	}
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{PinnedMetadataKey: true}
+
+	if got := len(FindOverlaps(&sf, 0)); got != 9 {
+		t.Errorf("initial overlap count mismatch: got %d, want 9", got)
+	}
+
+	result := ResolveOverlaps(&sf, OverlapOptions{Padding: 0.1, KeepHeight: true})
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", result.Warnings)
+	}
+	if pairs := FindOverlaps(&sf, 0.1-1e-6); len(pairs) != 0 {
+		t.Errorf("overlaps remain: %v", pairs)
+	}
+	if got := sf.FindNode("a").Transform.Position; got != (Vector3{}) {
+		t.Errorf("pinned node moved to %v", got)
+	}
+	for _, n := range sf.Scene.Nodes {
+		if n.Transform.Position.Y != 0 {
+			t.Errorf("node %s moved vertically with KeepHeight", n.ID)
+		}
+	}
+	if result.Positioned != 4 {
+		t.Errorf("positioned mismatch: got %d, want 4", result.Positioned)
+	}
+}
+
+// TestFindOverlaps_Hierarchy tests that containers do not overlap children
+func TestFindOverlaps_Hierarchy(t *testing.T) {
+	sf := NewSceneFile("Rack")
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "rack", Transform: NewTransform(), Children: []string{"server"},
+			Geometry: &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{"width": 2.0, "height": 2.0, "depth": 2.0}}},
+		{ID: "server", Parent: "rack", Transform: NewTransform()},
+		{ID: "other", Transform: NewTransformWithPosition(0.5, 0, 0)},
+	}
+	pairs := FindOverlaps(&sf, 0)
+	if len(pairs) != 2 {
+		t.Fatalf("overlap count mismatch: got %v, want 2", pairs)
+	}
+	for _, p := range pairs {
+		if p == [2]string{"rack", "server"} {
+			t.Error("parent and child should not overlap")
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package starfleet
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// =============================================================================
+// OWNERSHIP
+// =============================================================================
+
+// Metadata keys written by OwnershipEnricher. Selectors such as
+// "metadata.team=payments" and ACLs build on them.
+const (
+	MetadataOwner  = "owner"
+	MetadataTeam   = "team"
+	MetadataOnCall = "oncall"
+	// MetadataSourcePath holds the repository path of a node's code, which
+	// CODEOWNERS rules are matched against
+	MetadataSourcePath = "sourcePath"
+	// MetadataCatalogEntity holds the Backstage entity reference of a node,
+	// such as "component:default/checkout"
+	MetadataCatalogEntity = "catalogEntity"
+	// MetadataOnCallSchedule holds the PagerDuty schedule ID of a node,
+	// overriding the one of its team
+	MetadataOnCallSchedule = "oncallSchedule"
+)
+
+// Ownership is who is responsible for a node. Empty fields are unknown.
+type Ownership struct {
+	Owner  string `json:"owner,omitempty"`
+	Team   string `json:"team,omitempty"`
+	OnCall string `json:"oncall,omitempty"`
+}
+
+// complete reports whether every field is known
+func (o Ownership) complete() bool {
+	return o.Owner != "" && o.Team != "" && o.OnCall != ""
+}
+
+// fill sets the fields of o that are empty from other
+func (o *Ownership) fill(other Ownership) {
+	if o.Owner == "" {
+		o.Owner = other.Owner
+	}
+	if o.Team == "" {
+		o.Team = other.Team
+	}
+	if o.OnCall == "" {
+		o.OnCall = other.OnCall
+	}
+}
+
+// OwnershipSource resolves the ownership of a node. known holds what is
+// already known, from the node's metadata and earlier sources, so a source
+// can build on it, as on-call schedules build on the team. Fields the
+// source does not know are left empty.
+type OwnershipSource interface {
+	Resolve(ctx context.Context, node *SceneNode, known Ownership) (Ownership, error)
+}
+
+// OwnershipSourceFunc adapts a function to the OwnershipSource interface
+type OwnershipSourceFunc func(ctx context.Context, node *SceneNode, known Ownership) (Ownership, error)
+
+// Resolve calls f
+func (f OwnershipSourceFunc) Resolve(ctx context.Context, node *SceneNode, known Ownership) (Ownership, error) {
+	return f(ctx, node, known)
+}
+
+// OwnershipReport describes what an OwnershipEnricher resolved
+type OwnershipReport struct {
+	// Updated counts the nodes whose ownership metadata changed
+	Updated int `json:"updated"`
+	// Unowned lists the nodes left without an owner or team
+	Unowned []string `json:"unowned,omitempty"`
+}
+
+// OwnershipEnricher writes the owner, team and on-call of nodes into their
+// metadata from a list of sources. Sources are asked in order and the first
+// to know a field wins; values already in the metadata win over all of
+// them unless Overwrite is set.
+type OwnershipEnricher struct {
+	Sources   []OwnershipSource
+	Overwrite bool
+	// GrantWrite gives the owner and team write access to nodes that have
+	// no ACL of their own
+	GrantWrite bool
+}
+
+// Apply resolves the ownership of every node
+func (e *OwnershipEnricher) Apply(ctx context.Context, sf *SceneFile) (OwnershipReport, error) {
+	report := OwnershipReport{}
+	cancel := canceler{ctx: ctx}
+	for i := range sf.Scene.Nodes {
+		if err := cancel.check(); err != nil {
+			return report, err
+		}
+		n := &sf.Scene.Nodes[i]
+		var known Ownership
+		if !e.Overwrite {
+			known = Ownership{
+				Owner:  stringMetadata(n.Metadata, MetadataOwner),
+				Team:   stringMetadata(n.Metadata, MetadataTeam),
+				OnCall: stringMetadata(n.Metadata, MetadataOnCall),
+			}
+		}
+		for _, src := range e.Sources {
+			if known.complete() {
+				break
+			}
+			found, err := src.Resolve(ctx, n, known)
+			if err != nil {
+				return report, fmt.Errorf("resolve ownership of %s: %w", n.ID, err)
+			}
+			known.fill(found)
+		}
+
+		changed := false
+		for key, value := range map[string]string{MetadataOwner: known.Owner, MetadataTeam: known.Team, MetadataOnCall: known.OnCall} {
+			if value == "" || stringMetadata(n.Metadata, key) == value {
+				continue
+			}
+			if n.Metadata == nil {
+				n.Metadata = make(map[string]interface{})
+			}
+			n.Metadata[key] = value
+			changed = true
+		}
+		if e.GrantWrite && (known.Owner != "" || known.Team != "") {
+			if _, ok := n.Extensions[ACLExtension]; !ok {
+				var write []string
+				for _, p := range []string{known.Owner, known.Team} {
+					if p != "" && (len(write) == 0 || write[0] != p) {
+						write = append(write, p)
+					}
+				}
+				if n.Extensions == nil {
+					n.Extensions = make(map[string]interface{})
+				}
+				n.Extensions[ACLExtension] = ACL{Write: write}
+				changed = true
+			}
+		}
+		if changed {
+			report.Updated++
+		}
+		if known.Owner == "" && known.Team == "" {
+			report.Unowned = append(report.Unowned, n.ID)
+		}
+	}
+	return report, nil
+}
+
+// stringMetadata returns a string metadata value, or ""
+func stringMetadata(metadata map[string]interface{}, key string) string {
+	s, _ := metadata[key].(string)
+	return s
+}
+
+// =============================================================================
+// CODEOWNERS
+// =============================================================================
+
+// CodeOwners holds the rules of a GitHub or GitLab CODEOWNERS file. It
+// resolves nodes by their MetadataSourcePath.
+type CodeOwners struct {
+	rules []codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// ParseCodeOwners reads a CODEOWNERS file. GitLab section headers are
+// skipped; their rules apply like any other.
+func ParseCodeOwners(r io.Reader) (*CodeOwners, error) {
+	co := &CodeOwners{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "[") || strings.HasPrefix(text, "^[") {
+			continue
+		}
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		pattern, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("codeowners line %d: %w", line, err)
+		}
+		co.rules = append(co.rules, codeOwnersRule{pattern: pattern, owners: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read codeowners: %w", err)
+	}
+	return co, nil
+}
+
+// codeOwnersPattern compiles a gitignore-style pattern. Patterns with a
+// slash other than a trailing one are anchored at the repository root;
+// others match at any depth. A match also covers everything below it.
+func codeOwnersPattern(p string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil, errors.New("empty pattern")
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	b.WriteString("(?:/.*)?$")
+	return regexp.Compile(b.String())
+}
+
+// Owners returns the owners of a repository path: those of the last
+// matching rule, as GitHub applies them. A rule without owners leaves the
+// path unowned.
+func (co *CodeOwners) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].pattern.MatchString(path) {
+			return co.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// Resolve makes the first owner listed for the node's source path its
+// owner, and the first team, written "@org/team", its team
+func (co *CodeOwners) Resolve(ctx context.Context, node *SceneNode, known Ownership) (Ownership, error) {
+	path := stringMetadata(node.Metadata, MetadataSourcePath)
+	if path == "" {
+		return Ownership{}, nil
+	}
+	var o Ownership
+	for _, owner := range co.Owners(path) {
+		owner = strings.TrimPrefix(owner, "@")
+		if o.Owner == "" {
+			o.Owner = owner
+		}
+		if o.Team == "" && strings.Contains(owner, "/") {
+			o.Team = owner
+		}
+	}
+	return o, nil
+}
+
+// =============================================================================
+// BACKSTAGE
+// =============================================================================
+
+// BackstageEntity is the subset of a Backstage catalog entity the catalog
+// source reads
+type BackstageEntity struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		Owner string `json:"owner,omitempty"`
+	} `json:"spec"`
+}
+
+// Ref returns the entity reference, "kind:namespace/name", in lower case
+func (e *BackstageEntity) Ref() string {
+	ns := e.Metadata.Namespace
+	if ns == "" {
+		ns = "default"
+	}
+	return strings.ToLower(e.Kind + ":" + ns + "/" + e.Metadata.Name)
+}
+
+// BackstageCatalog resolves nodes from Backstage catalog entities. A node
+// belongs to the entity named by its MetadataCatalogEntity, or else to the
+// entity whose name is the node's ID.
+type BackstageCatalog struct {
+	byRef  map[string]*BackstageEntity
+	byName map[string]*BackstageEntity
+}
+
+// ReadBackstageCatalog reads the JSON array of entities returned by the
+// catalog's /api/catalog/entities endpoint
+func ReadBackstageCatalog(r io.Reader) (*BackstageCatalog, error) {
+	var entities []BackstageEntity
+	if err := json.NewDecoder(r).Decode(&entities); err != nil {
+		return nil, fmt.Errorf("read backstage catalog: %w", err)
+	}
+	return NewBackstageCatalog(entities), nil
+}
+
+// NewBackstageCatalog indexes entities by reference and name
+func NewBackstageCatalog(entities []BackstageEntity) *BackstageCatalog {
+	c := &BackstageCatalog{byRef: make(map[string]*BackstageEntity), byName: make(map[string]*BackstageEntity)}
+	for i := range entities {
+		e := &entities[i]
+		c.byRef[e.Ref()] = e
+		if _, taken := c.byName[e.Metadata.Name]; !taken {
+			c.byName[e.Metadata.Name] = e
+		}
+	}
+	return c
+}
+
+// Resolve takes the owner from the entity's spec.owner. Groups are the
+// owning team; users own the node themselves.
+func (c *BackstageCatalog) Resolve(ctx context.Context, node *SceneNode, known Ownership) (Ownership, error) {
+	var entity *BackstageEntity
+	if ref := stringMetadata(node.Metadata, MetadataCatalogEntity); ref != "" {
+		// References may leave out the default namespace
+		ref = strings.ToLower(ref)
+		if kind, name, ok := strings.Cut(ref, ":"); ok && !strings.Contains(name, "/") {
+			ref = kind + ":default/" + name
+		}
+		entity = c.byRef[ref]
+	} else {
+		entity = c.byName[node.ID]
+	}
+	if entity == nil || entity.Spec.Owner == "" {
+		return Ownership{}, nil
+	}
+	kind, name := "group", entity.Spec.Owner
+	if k, rest, ok := strings.Cut(name, ":"); ok {
+		kind, name = strings.ToLower(k), rest
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if kind == "user" {
+		return Ownership{Owner: name}, nil
+	}
+	return Ownership{Owner: name, Team: name}, nil
+}
+
+// =============================================================================
+// PAGERDUTY
+// =============================================================================
+
+// PagerDutyOnCall is the subset of an entry of the PagerDuty /oncalls
+// response the on-call source reads
+type PagerDutyOnCall struct {
+	User struct {
+		Summary string `json:"summary"`
+	} `json:"user"`
+	Schedule *struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"schedule"`
+	EscalationLevel int `json:"escalation_level"`
+}
+
+// PagerDutySchedules resolves who is on call for a node from its schedule:
+// its MetadataOnCallSchedule, else the one Teams maps its team to, else the
+// schedule named like its team. The first escalation level answers.
+type PagerDutySchedules struct {
+	// Teams maps team names to schedule IDs
+	Teams   map[string]string
+	onCalls map[string]PagerDutyOnCall
+	byName  map[string]string
+}
+
+// ReadPagerDutyOnCalls reads a PagerDuty /oncalls response, as fetched for
+// the time the scene should show
+func ReadPagerDutyOnCalls(r io.Reader) (*PagerDutySchedules, error) {
+	var resp struct {
+		OnCalls []PagerDutyOnCall `json:"oncalls"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("read pagerduty on-calls: %w", err)
+	}
+	return NewPagerDutySchedules(resp.OnCalls), nil
+}
+
+// NewPagerDutySchedules indexes on-call entries by schedule
+func NewPagerDutySchedules(onCalls []PagerDutyOnCall) *PagerDutySchedules {
+	s := &PagerDutySchedules{onCalls: make(map[string]PagerDutyOnCall), byName: make(map[string]string)}
+	for _, oc := range onCalls {
+		if oc.Schedule == nil || oc.User.Summary == "" {
+			continue
+		}
+		if cur, ok := s.onCalls[oc.Schedule.ID]; !ok || oc.EscalationLevel < cur.EscalationLevel {
+			s.onCalls[oc.Schedule.ID] = oc
+		}
+		s.byName[strings.ToLower(oc.Schedule.Summary)] = oc.Schedule.ID
+	}
+	return s
+}
+
+// Resolve finds the node's on-call
+func (s *PagerDutySchedules) Resolve(ctx context.Context, node *SceneNode, known Ownership) (Ownership, error) {
+	schedule := stringMetadata(node.Metadata, MetadataOnCallSchedule)
+	if schedule == "" && known.Team != "" {
+		if schedule = s.Teams[known.Team]; schedule == "" {
+			schedule = s.byName[strings.ToLower(known.Team)]
+		}
+	}
+	oc, ok := s.onCalls[schedule]
+	if !ok {
+		return Ownership{}, nil
+	}
+	return Ownership{OnCall: oc.User.Summary}, nil
+}
+
+// ownershipStage resolves ownership from the files named in its options:
+// {"codeowners": path, "backstage": path, "pagerduty": path, "teams":
+// {...}} with the fields of OwnershipEnricher
+func ownershipStage(options json.RawMessage) (PipelineStage, error) {
+	var opts struct {
+		CodeOwners string            `json:"codeowners"`
+		Backstage  string            `json:"backstage"`
+		PagerDuty  string            `json:"pagerduty"`
+		Teams      map[string]string `json:"teams"`
+		Overwrite  bool              `json:"overwrite"`
+		GrantWrite bool              `json:"grantWrite"`
+	}
+	if err := decodeStageOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.CodeOwners == "" && opts.Backstage == "" && opts.PagerDuty == "" {
+		return nil, errors.New(`ownership stage needs "codeowners", "backstage" or "pagerduty"`)
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		enricher := OwnershipEnricher{Overwrite: opts.Overwrite, GrantWrite: opts.GrantWrite}
+		for _, src := range []struct {
+			path string
+			read func(io.Reader) (OwnershipSource, error)
+		}{
+			{opts.CodeOwners, func(r io.Reader) (OwnershipSource, error) { return ParseCodeOwners(r) }},
+			{opts.Backstage, func(r io.Reader) (OwnershipSource, error) { return ReadBackstageCatalog(r) }},
+			{opts.PagerDuty, func(r io.Reader) (OwnershipSource, error) {
+				s, err := ReadPagerDutyOnCalls(r)
+				if err == nil {
+					s.Teams = opts.Teams
+				}
+				return s, err
+			}},
+		} {
+			if src.path == "" {
+				continue
+			}
+			f, err := os.Open(src.path)
+			if err != nil {
+				return nil, err
+			}
+			source, err := src.read(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			enricher.Sources = append(enricher.Sources, source)
+		}
+		report, err := enricher.Apply(ctx, sf)
+		if err != nil {
+			return nil, err
+		}
+		notes := []string{fmt.Sprintf("updated ownership of %d nodes", report.Updated)}
+		if len(report.Unowned) > 0 {
+			notes = append(notes, "unowned: "+strings.Join(report.Unowned, ", "))
+		}
+		return notes, nil
+	}), nil
+}
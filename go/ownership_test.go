@@ -0,0 +1,131 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCodeOwners = `# Default owners
+*       @acme/platform
+/services/payments/   @ada @acme/payments  # money
+docs/** @acme/writers
+*.md
+[Section]
+/services/search/ @acme/search
+`
+
+// TestCodeOwners tests that the last matching rule wins
+func TestCodeOwners(t *testing.T) {
+	co, err := ParseCodeOwners(strings.NewReader(testCodeOwners))
+	if err != nil {
+		t.Fatalf("ParseCodeOwners failed: %v", err)
+	}
+	tests := map[string][]string{
+		"services/payments/api/main.go": {"@ada", "@acme/payments"},
+		"/services/payments":            {"@ada", "@acme/payments"},
+		"lib/payments/x.go":             {"@acme/platform"},
+		"docs/guide/intro.txt":          {"@acme/writers"},
+		"docs/guide/intro.md":           {},
+		"services/search/index.go":      {"@acme/search"},
+	}
+	for path, want := range tests {
+		if got := co.Owners(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("Owners(%q) mismatch: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+// TestOwnershipEnricher tests combining sources, keeping existing metadata
+// and granting write access
+func TestOwnershipEnricher(t *testing.T) {
+	co, _ := ParseCodeOwners(strings.NewReader(testCodeOwners))
+	catalog, err := ReadBackstageCatalog(strings.NewReader(`[
+		{"kind": "Component", "metadata": {"name": "search"}, "spec": {"owner": "group:default/search-team"}},
+		{"kind": "Component", "metadata": {"name": "checkout", "namespace": "shop"}, "spec": {"owner": "user:bob"}}
+	]`))
+	if err != nil {
+		t.Fatalf("ReadBackstageCatalog failed: %v", err)
+	}
+	pd, err := ReadPagerDutyOnCalls(strings.NewReader(`{"oncalls": [
+		{"user": {"summary": "Grace"}, "schedule": {"id": "P2", "summary": "Payments"}, "escalation_level": 2},
+		{"user": {"summary": "Linus"}, "schedule": {"id": "P2", "summary": "Payments"}, "escalation_level": 1},
+		{"user": {"summary": "Ken"}, "schedule": {"id": "P3", "summary": "Search primary"}, "escalation_level": 1},
+		{"user": {"summary": "Nobody"}, "schedule": null, "escalation_level": 1}
+	]}`))
+	if err != nil {
+		t.Fatalf("ReadPagerDutyOnCalls failed: %v", err)
+	}
+	pd.Teams = map[string]string{"search-team": "P3"}
+
+	sf := NewSceneFile("Owners")
+	sf.AddNode(SceneNode{ID: "pay", Metadata: map[string]interface{}{MetadataSourcePath: "services/payments/api"}})
+	sf.AddNode(SceneNode{ID: "search", Metadata: map[string]interface{}{MetadataOwner: "carol"}})
+	sf.AddNode(SceneNode{ID: "cart", Metadata: map[string]interface{}{MetadataCatalogEntity: "Component:shop/checkout"}})
+	sf.AddNode(SceneNode{ID: "lb", Extensions: map[string]interface{}{ACLExtension: map[string]interface{}{"write": []interface{}{"ops"}}}})
+
+	e := OwnershipEnricher{Sources: []OwnershipSource{co, catalog, pd}, GrantWrite: true}
+	report, err := e.Apply(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if want := (OwnershipReport{Updated: 3, Unowned: []string{"lb"}}); !reflect.DeepEqual(report, want) {
+		t.Errorf("report mismatch: got %+v, want %+v", report, want)
+	}
+	want := map[string]Ownership{
+		"pay":    {Owner: "ada", Team: "acme/payments"},
+		"search": {Owner: "carol", Team: "search-team", OnCall: "Ken"},
+		"cart":   {Owner: "bob"},
+	}
+	for id, o := range want {
+		n := sf.FindNode(id)
+		got := Ownership{Owner: stringMetadata(n.Metadata, MetadataOwner), Team: stringMetadata(n.Metadata, MetadataTeam), OnCall: stringMetadata(n.Metadata, MetadataOnCall)}
+		if got != o {
+			t.Errorf("%s ownership mismatch: got %+v, want %+v", id, got, o)
+		}
+	}
+	if acl := sf.NodeACL(sf.FindNode("search")); !reflect.DeepEqual(acl.Write, []string{"carol", "search-team"}) {
+		t.Errorf("ACL mismatch: got %+v", acl)
+	}
+	if acl := sf.NodeACL(sf.FindNode("lb")); !reflect.DeepEqual(acl.Write, []string{"ops"}) {
+		t.Errorf("existing ACL mismatch: got %+v", acl)
+	}
+
+	// The on-call schedule named like the team is found without a mapping
+	pd.Teams = nil
+	sf.FindNode("pay").Metadata[MetadataTeam] = "payments"
+	if _, err := e.Apply(context.Background(), &sf); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if got := sf.FindNode("pay").Metadata[MetadataOnCall]; got != "Linus" {
+		t.Errorf("on-call mismatch: got %v, want Linus", got)
+	}
+}
+
+// TestOwnershipStage tests the ownership pipeline stage
+func TestOwnershipStage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CODEOWNERS")
+	os.WriteFile(path, []byte(testCodeOwners), 0o644)
+	options, _ := json.Marshal(map[string]string{"codeowners": path})
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageOwnership, Options: options}}}, DefaultStageFactories(nil))
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+	sf := NewSceneFile("Owners")
+	sf.AddNode(SceneNode{ID: "pay", Metadata: map[string]interface{}{MetadataSourcePath: "services/payments"}})
+	sf.AddNode(SceneNode{ID: "lb"})
+	report, err := p.Run(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []string{"updated ownership of 1 nodes", "unowned: lb"}; !reflect.DeepEqual(report.Stages[0].Notes, want) {
+		t.Errorf("notes mismatch: got %q, want %q", report.Stages[0].Notes, want)
+	}
+	if _, err := ownershipStage(json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error for a stage without sources")
+	}
+}
@@ -0,0 +1,162 @@
+package starfleet
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// =============================================================================
+// PANELS
+// =============================================================================
+
+// ChartType is how a panel draws its data
+type ChartType string
+
+const (
+	ChartLine    ChartType = "line"
+	ChartArea    ChartType = "area"
+	ChartBar     ChartType = "bar"
+	ChartGauge   ChartType = "gauge"
+	ChartStat    ChartType = "stat"
+	ChartHeatmap ChartType = "heatmap"
+)
+
+// DefaultPanelRange is the time range, in seconds, of panels that set none
+const DefaultPanelRange = 3600
+
+// PanelQuery selects one metric for a panel. Without NodeIDs it reads the
+// node the panel is attached to.
+type PanelQuery struct {
+	Metric     string                 `json:"metric" validate:"required"`
+	Label      string                 `json:"label,omitempty"`
+	NodeIDs    []string               `json:"nodeIds,omitempty"`
+	Resolution int                    `json:"resolution,omitempty" validate:"omitempty,min=1"`
+	Filters    map[string]interface{} `json:"filters,omitempty"`
+}
+
+// PanelThreshold marks a value from which a panel shows a status. Panels
+// show the status of the highest threshold at or below the current value.
+type PanelThreshold struct {
+	Value  float64    `json:"value"`
+	Status NodeStatus `json:"status" validate:"required"`
+	Color  *Color     `json:"color,omitempty"`
+}
+
+// Panel is a chart attached to a node so every viewer shows the same
+// dashboard for it. Range is the time range in seconds.
+type Panel struct {
+	ID         string           `json:"id" validate:"required"`
+	Title      string           `json:"title,omitempty"`
+	Chart      ChartType        `json:"chart" validate:"required,oneof=line area bar gauge stat heatmap"`
+	Queries    []PanelQuery     `json:"queries" validate:"required,min=1"`
+	Range      float64          `json:"range,omitempty" validate:"omitempty,gt=0"`
+	Unit       string           `json:"unit,omitempty"`
+	Min        *float64         `json:"min,omitempty"`
+	Max        *float64         `json:"max,omitempty"`
+	Thresholds []PanelThreshold `json:"thresholds,omitempty"`
+}
+
+// MetricsQueries resolves the panel to the queries a viewer issues for the
+// node it is attached to, covering the panel range up to now
+func (p *Panel) MetricsQueries(nodeID string, now time.Time) []MetricsQuery {
+	rng := p.Range
+	if rng <= 0 {
+		rng = DefaultPanelRange
+	}
+	from := now.Add(-time.Duration(rng * float64(time.Second)))
+	to := now
+	queries := make([]MetricsQuery, len(p.Queries))
+	for i, q := range p.Queries {
+		nodes := q.NodeIDs
+		if len(nodes) == 0 {
+			nodes = []string{nodeID}
+		}
+		queries[i] = MetricsQuery{
+			NodeIDs:     slices.Clone(nodes),
+			MetricNames: []string{q.Metric},
+			From:        &from,
+			To:          &to,
+			Resolution:  q.Resolution,
+			Filters:     q.Filters,
+		}
+	}
+	return queries
+}
+
+// Status returns the status of the highest threshold at or below value, or
+// an empty status when value is below every threshold
+func (p *Panel) Status(value float64) NodeStatus {
+	var status NodeStatus
+	best := 0.0
+	for _, t := range p.Thresholds {
+		if value >= t.Value && (status == "" || t.Value >= best) {
+			status, best = t.Status, t.Value
+		}
+	}
+	return status
+}
+
+// PanelQueries resolves every panel of a node, keyed by panel ID
+func (sf *SceneFile) PanelQueries(nodeID string, now time.Time) (map[string][]MetricsQuery, error) {
+	node := sf.FindNode(nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("panel queries: %w: %s", ErrNodeNotFound, nodeID)
+	}
+	queries := make(map[string][]MetricsQuery, len(node.Panels))
+	for i := range node.Panels {
+		queries[node.Panels[i].ID] = node.Panels[i].MetricsQueries(nodeID, now)
+	}
+	return queries, nil
+}
+
+// ValidatePanels checks panel definitions: IDs unique per node, a known
+// chart type, at least one query naming a metric, queried nodes that exist,
+// a sensible value range and thresholds with a status
+func ValidatePanels(sf *SceneFile) []string {
+	var errs []string
+	nodeIDs := make(map[string]bool, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodeIDs[sf.Scene.Nodes[i].ID] = true
+	}
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		ids := make(map[string]bool, len(node.Panels))
+		for _, p := range node.Panels {
+			switch {
+			case p.ID == "":
+				errs = append(errs, fmt.Sprintf("Node %s has a panel without an id", node.ID))
+			case ids[p.ID]:
+				errs = append(errs, fmt.Sprintf("Node %s has duplicate panel: %s", node.ID, p.ID))
+			}
+			ids[p.ID] = true
+			switch p.Chart {
+			case ChartLine, ChartArea, ChartBar, ChartGauge, ChartStat, ChartHeatmap:
+			default:
+				errs = append(errs, fmt.Sprintf("Panel %s on node %s has unknown chart type: %s", p.ID, node.ID, p.Chart))
+			}
+			if len(p.Queries) == 0 {
+				errs = append(errs, fmt.Sprintf("Panel %s on node %s has no queries", p.ID, node.ID))
+			}
+			for _, q := range p.Queries {
+				if q.Metric == "" {
+					errs = append(errs, fmt.Sprintf("Panel %s on node %s has a query without a metric", p.ID, node.ID))
+				}
+				for _, id := range q.NodeIDs {
+					if !nodeIDs[id] {
+						errs = append(errs, fmt.Sprintf("Panel %s on node %s queries non-existent node: %s", p.ID, node.ID, id))
+					}
+				}
+			}
+			if p.Range < 0 || (p.Min != nil && p.Max != nil && *p.Min >= *p.Max) {
+				errs = append(errs, fmt.Sprintf("Panel %s on node %s has an invalid range", p.ID, node.ID))
+			}
+			for _, t := range p.Thresholds {
+				if t.Status == "" {
+					errs = append(errs, fmt.Sprintf("Panel %s on node %s has a threshold without a status", p.ID, node.ID))
+				}
+			}
+		}
+	}
+	return errs
+}
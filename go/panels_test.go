@@ -0,0 +1,72 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPanel_MetricsQueries tests resolving panels to metrics queries
+func TestPanel_MetricsQueries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sf := NewSceneFile("Panels")
+	sf.AddNode(SceneNode{ID: "db", Type: "database", Name: "DB", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform(), Panels: []Panel{
+		{ID: "latency", Chart: ChartLine, Range: 900, Queries: []PanelQuery{
+			{Metric: "p99_ms", Resolution: 60},
+			{Metric: "p99_ms", NodeIDs: []string{"db"}, Label: "database"},
+		}},
+		{ID: "cpu", Chart: ChartGauge, Queries: []PanelQuery{{Metric: "cpu"}}},
+	}})
+
+	queries, err := sf.PanelQueries("api", now)
+	if err != nil {
+		t.Fatalf("PanelQueries failed: %v", err)
+	}
+	latency := queries["latency"]
+	if len(latency) != 2 || latency[0].NodeIDs[0] != "api" || latency[1].NodeIDs[0] != "db" || latency[0].Resolution != 60 {
+		t.Errorf("latency queries mismatch: got %+v", latency)
+	}
+	if !latency[0].From.Equal(now.Add(-15*time.Minute)) || !latency[0].To.Equal(now) {
+		t.Errorf("latency range mismatch: got %v to %v", latency[0].From, latency[0].To)
+	}
+	if cpu := queries["cpu"]; !cpu[0].From.Equal(now.Add(-time.Hour)) {
+		t.Errorf("default range mismatch: got %v", cpu[0].From)
+	}
+	if _, err := sf.PanelQueries("nope", now); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+// TestPanel_Status tests threshold lookup
+func TestPanel_Status(t *testing.T) {
+	p := Panel{Thresholds: []PanelThreshold{
+		{Value: 90, Status: NodeStatusCritical},
+		{Value: 0, Status: NodeStatusHealthy},
+		{Value: 70, Status: NodeStatusWarning},
+	}}
+	tests := map[float64]NodeStatus{-1: "", 10: NodeStatusHealthy, 70: NodeStatusWarning, 95: NodeStatusCritical}
+	for value, want := range tests {
+		if got := p.Status(value); got != want {
+			t.Errorf("Status(%v) mismatch: got %q, want %q", value, got, want)
+		}
+	}
+}
+
+// TestValidatePanels tests panel validation
+func TestValidatePanels(t *testing.T) {
+	lo, hi := 10.0, 5.0
+	sf := NewSceneFile("Panels")
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform(), Panels: []Panel{
+		{ID: "a", Chart: ChartLine, Queries: []PanelQuery{{Metric: "rps"}}},
+		{ID: "a", Chart: "pie", Queries: []PanelQuery{{NodeIDs: []string{"ghost"}}}, Min: &lo, Max: &hi},
+		{ID: "b", Chart: ChartStat, Thresholds: []PanelThreshold{{Value: 1}}},
+	}})
+	errs := ValidatePanels(&sf)
+	if len(errs) != 7 {
+		t.Errorf("error count mismatch: got %d: %v", len(errs), errs)
+	}
+	if result := ValidateScene(&sf); result.Valid {
+		t.Error("ValidateScene should report panel errors")
+	}
+}
@@ -0,0 +1,78 @@
+package starfleet
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// =============================================================================
+// PARALLELISM
+// =============================================================================
+
+// parallelBlock is the number of consecutive nodes or edges a worker takes
+// at a time, enough to outweigh handing out the block
+const parallelBlock = 64
+
+type workersKey struct{}
+
+// WithWorkers returns a context bounding the goroutines that operations
+// such as validation, layout and bounds computation use per call. One
+// makes them serial; zero or less restores the default.
+func WithWorkers(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, workersKey{}, n)
+}
+
+// WorkersFromContext returns the bound set by WithWorkers, defaulting to
+// GOMAXPROCS
+func WorkersFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(workersKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parallelFor calls fn for every index below n on up to
+// WorkersFromContext(ctx) goroutines, handing out blocks of consecutive
+// indices. Fewer than two blocks of work run serially, where goroutines
+// would cost more than they save. Callers keep results deterministic by
+// writing them to slots by index; fn must not touch state shared with
+// other indices. It returns the context's error when it is done before
+// every index ran.
+func parallelFor(ctx context.Context, n, block int, fn func(i int)) error {
+	workers := min(WorkersFromContext(ctx), n/block)
+	if workers <= 1 {
+		c := canceler{ctx: ctx}
+		for i := 0; i < n; i++ {
+			if err := c.check(); err != nil {
+				return err
+			}
+			fn(i)
+		}
+		return nil
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := int(next.Add(int64(block))) - block
+				if start >= n {
+					return
+				}
+				for i := start; i < min(start+block, n); i++ {
+					fn(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if int(next.Load()) < n {
+		return ctx.Err()
+	}
+	return nil
+}
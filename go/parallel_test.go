@@ -0,0 +1,123 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// newLargeGeoScene returns n nodes spread over the globe, each linked to
+// the next
+func newLargeGeoScene(n int) SceneFile {
+	sf := NewSceneFile("Regions")
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("n%d", i)
+		sf.AddNode(SceneNode{ID: id, Type: "server", Name: id, Transform: NewTransform(),
+			Metadata: map[string]interface{}{"lat": float64(i%170 - 85), "lon": float64(i%350 - 175)}})
+		if i > 0 {
+			sf.AddEdge(SceneEdge{ID: fmt.Sprintf("e%d", i), Source: fmt.Sprintf("n%d", i-1), Target: id})
+		}
+	}
+	return sf
+}
+
+// TestParallelFor tests that every index runs exactly once and that
+// cancellation is reported
+func TestParallelFor(t *testing.T) {
+	ctx := WithWorkers(context.Background(), 8)
+	n := 10*parallelBlock + 3
+	counts := make([]int32, n)
+	if err := parallelFor(ctx, n, parallelBlock, func(i int) { atomic.AddInt32(&counts[i], 1) }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, c := range counts {
+		if c != 1 {
+			t.Fatalf("count mismatch at %d: got %d, want 1", i, c)
+		}
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	var ran atomic.Int32
+	err := parallelFor(canceled, n, parallelBlock, func(i int) {
+		if ran.Add(1) == 1 {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error mismatch: got %v, want %v", err, context.Canceled)
+	}
+	if int(ran.Load()) == n {
+		t.Errorf("ran mismatch: got all %d indices after cancel", n)
+	}
+
+	if got := WorkersFromContext(WithWorkers(ctx, 0)); got < 1 {
+		t.Errorf("workers mismatch: got %d, want at least 1", got)
+	}
+}
+
+// TestParallel_Deterministic tests that parallel layout, validation and
+// bounds match their serial results
+func TestParallel_Deterministic(t *testing.T) {
+	serial := WithWorkers(context.Background(), 1)
+	parallel := WithWorkers(context.Background(), 8)
+
+	a, b := newLargeGeoScene(2000), newLargeGeoScene(2000)
+	if _, err := ApplyLayout(serial, NewGeoLayout(GeoSphere), &a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := ApplyLayout(parallel, NewGeoLayout(GeoSphere), &b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(a.Scene, b.Scene) {
+		t.Error("layout mismatch between serial and parallel runs")
+	}
+
+	b.Scene.Edges = append(b.Scene.Edges, SceneEdge{ID: "dangling", Source: "n0", Target: "missing"})
+	want, _ := ValidateSceneContext(serial, &b)
+	got, err := ValidateSceneContext(parallel, &b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("validation mismatch: got %+v, want %+v", got, want)
+	}
+
+	if got, want := a.SceneBoundingSphere(), b.SceneBoundingSphere(); got != want {
+		t.Errorf("bounds mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+// BenchmarkGeoLayout_Workers shows layout scaling with the worker count
+func BenchmarkGeoLayout_Workers(b *testing.B) {
+	sf := newLargeGeoScene(20000)
+	layout := NewGeoLayout(GeoSphere)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			ctx := WithWorkers(context.Background(), workers)
+			for i := 0; i < b.N; i++ {
+				if _, err := ApplyLayout(ctx, layout, &sf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkValidateScene_Workers shows validation scaling with the worker
+// count
+func BenchmarkValidateScene_Workers(b *testing.B) {
+	sf := newLargeGeoScene(20000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			ctx := WithWorkers(context.Background(), workers)
+			for i := 0; i < b.N; i++ {
+				if _, err := ValidateSceneContext(ctx, &sf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package starfleet
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// =============================================================================
+// PARTICLE SYSTEMS
+// =============================================================================
+
+// EmitterShape represents the volume particles are spawned from
+type EmitterShape string
+
+const (
+	EmitterPoint  EmitterShape = "point"
+	EmitterSphere EmitterShape = "sphere"
+	EmitterBox    EmitterShape = "box"
+	EmitterCone   EmitterShape = "cone"
+	// EmitterPath spawns particles along an edge, travelling source to target
+	EmitterPath EmitterShape = "path"
+)
+
+// ParticleEmitter represents the emitter volume of a particle system
+type ParticleEmitter struct {
+	Shape  EmitterShape `json:"shape" validate:"required"`
+	Radius float64      `json:"radius,omitempty" validate:"omitempty,min=0"`
+	Size   *Vector3     `json:"size,omitempty"`
+	Angle  float64      `json:"angle,omitempty" validate:"omitempty,min=0"`
+}
+
+// ColorStop represents a color at a normalized point in a particle's life
+type ColorStop struct {
+	T     float64 `json:"t" validate:"min=0,max=1"`
+	Color Color   `json:"color" validate:"required"`
+}
+
+// ParticleMetricBinding scales a particle system's emission rate by a metric.
+// The metric value is mapped linearly from [Min, Max] onto [0, 1] and the
+// result multiplies the system rate.
+type ParticleMetricBinding struct {
+	Metric string  `json:"metric" validate:"required"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max" validate:"required"`
+}
+
+// ParticleSystem represents a declarative particle effect attached to a node
+// or edge, used to visualize traffic and event volume
+type ParticleSystem struct {
+	Name          string                 `json:"name,omitempty"`
+	Emitter       ParticleEmitter        `json:"emitter" validate:"required"`
+	Rate          float64                `json:"rate" validate:"required,gt=0"`
+	Lifetime      float64                `json:"lifetime" validate:"required,gt=0"`
+	Speed         float64                `json:"speed,omitempty"`
+	Size          float64                `json:"size,omitempty" validate:"omitempty,gt=0"`
+	MaxParticles  int                    `json:"maxParticles,omitempty" validate:"omitempty,gt=0"`
+	ColorOverLife []ColorStop            `json:"colorOverLife,omitempty"`
+	Binding       *ParticleMetricBinding `json:"binding,omitempty"`
+}
+
+// EffectiveRate returns the emission rate after applying the metric binding
+// to the given metrics. Without a binding, or when the metric is missing or
+// non-numeric, the base rate is returned unchanged.
+func (ps *ParticleSystem) EffectiveRate(metrics map[string]interface{}) float64 {
+	if ps.Binding == nil {
+		return ps.Rate
+	}
+	v, ok := toFloat64(metrics[ps.Binding.Metric])
+	if !ok || ps.Binding.Max <= ps.Binding.Min {
+		return ps.Rate
+	}
+	f := (v - ps.Binding.Min) / (ps.Binding.Max - ps.Binding.Min)
+	return ps.Rate * math.Max(0, math.Min(1, f))
+}
+
+// ParticleCount returns the number of live particles at steady state for an
+// emission rate, capped by MaxParticles
+func (ps *ParticleSystem) ParticleCount(rate float64) int {
+	n := int(math.Ceil(rate * ps.Lifetime))
+	if ps.MaxParticles > 0 && n > ps.MaxParticles {
+		return ps.MaxParticles
+	}
+	return n
+}
+
+// ColorAt returns the particle color at normalized age t in [0, 1],
+// interpolating between color stops. Systems without stops are white.
+func (ps *ParticleSystem) ColorAt(t float64) Color {
+	stops := ps.ColorOverLife
+	if len(stops) == 0 {
+		return NewColor(1, 1, 1)
+	}
+	if !sort.SliceIsSorted(stops, func(i, j int) bool { return stops[i].T < stops[j].T }) {
+		stops = append([]ColorStop(nil), stops...)
+		sort.Slice(stops, func(i, j int) bool { return stops[i].T < stops[j].T })
+	}
+
+	if t <= stops[0].T {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if t <= stops[i].T {
+			a, b := stops[i-1], stops[i]
+			span := b.T - a.T
+			if span <= 0 {
+				return b.Color
+			}
+			return lerpColor(a.Color, b.Color, (t-a.T)/span)
+		}
+	}
+	return stops[len(stops)-1].Color
+}
+
+// lerpColor linearly interpolates between two colors
+func lerpColor(a, b Color, t float64) Color {
+	return Color{
+		R: a.R + (b.R-a.R)*t,
+		G: a.G + (b.G-a.G)*t,
+		B: a.B + (b.B-a.B)*t,
+		A: a.A + (b.A-a.A)*t,
+	}
+}
+
+// SamplePosition returns a random spawn position in emitter-local space. For
+// path emitters the position is interpolated between from and to; other
+// shapes ignore the endpoints and are centered on the origin.
+func (ps *ParticleSystem) SamplePosition(rng *rand.Rand, from, to Vector3) Vector3 {
+	e := ps.Emitter
+	switch e.Shape {
+	case EmitterSphere:
+		return randomUnitVector(rng).Scale(e.Radius * math.Cbrt(rng.Float64()))
+	case EmitterBox:
+		size := Vector3{X: 1, Y: 1, Z: 1}
+		if e.Size != nil {
+			size = *e.Size
+		}
+		return Vector3{
+			X: (rng.Float64() - 0.5) * size.X,
+			Y: (rng.Float64() - 0.5) * size.Y,
+			Z: (rng.Float64() - 0.5) * size.Z,
+		}
+	case EmitterCone:
+		angle := rng.Float64() * 2 * math.Pi
+		r := e.Radius * math.Sqrt(rng.Float64())
+		return Vector3{X: r * math.Cos(angle), Z: r * math.Sin(angle)}
+	case EmitterPath:
+		return from.Lerp(to, rng.Float64())
+	default:
+		return Vector3{}
+	}
+}
+
+// SampleDirection returns a random initial unit direction for a particle.
+// Cone emitters spread around +Y up to the cone angle, path emitters travel
+// from the source toward the target, and other shapes emit radially.
+func (ps *ParticleSystem) SampleDirection(rng *rand.Rand, from, to Vector3) Vector3 {
+	switch ps.Emitter.Shape {
+	case EmitterCone:
+		theta := rng.Float64() * ps.Emitter.Angle
+		phi := rng.Float64() * 2 * math.Pi
+		return Vector3{
+			X: math.Sin(theta) * math.Cos(phi),
+			Y: math.Cos(theta),
+			Z: math.Sin(theta) * math.Sin(phi),
+		}
+	case EmitterPath:
+		return to.Sub(from).Normalize()
+	default:
+		return randomUnitVector(rng)
+	}
+}
+
+// randomUnitVector returns a uniformly distributed random unit vector
+func randomUnitVector(rng *rand.Rand) Vector3 {
+	z := rng.Float64()*2 - 1
+	phi := rng.Float64() * 2 * math.Pi
+	r := math.Sqrt(1 - z*z)
+	return Vector3{X: r * math.Cos(phi), Y: r * math.Sin(phi), Z: z}
+}
@@ -0,0 +1,112 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestParticleSystem_EffectiveRate tests metric-bound emission rates
+func TestParticleSystem_EffectiveRate(t *testing.T) {
+	ps := ParticleSystem{
+		Emitter:  ParticleEmitter{Shape: EmitterPath},
+		Rate:     100,
+		Lifetime: 2,
+		Binding:  &ParticleMetricBinding{Metric: "rps", Min: 0, Max: 1000},
+	}
+
+	tests := []struct {
+		metrics map[string]interface{}
+		want    float64
+	}{
+		{map[string]interface{}{"rps": 500}, 50},
+		{map[string]interface{}{"rps": 5000.0}, 100},
+		{map[string]interface{}{"rps": -1}, 0},
+		{map[string]interface{}{}, 100},
+	}
+	for _, tt := range tests {
+		if got := ps.EffectiveRate(tt.metrics); got != tt.want {
+			t.Errorf("EffectiveRate(%v) mismatch: got %f, want %f", tt.metrics, got, tt.want)
+		}
+	}
+
+	if got := ps.ParticleCount(50); got != 100 {
+		t.Errorf("ParticleCount mismatch: got %d, want 100", got)
+	}
+	ps.MaxParticles = 10
+	if got := ps.ParticleCount(50); got != 10 {
+		t.Errorf("ParticleCount cap mismatch: got %d, want 10", got)
+	}
+}
+
+// TestParticleSystem_ColorAt tests color-over-life interpolation
+func TestParticleSystem_ColorAt(t *testing.T) {
+	ps := ParticleSystem{
+		ColorOverLife: []ColorStop{
+			{T: 1, Color: NewColorWithAlpha(1, 0, 0, 0)},
+			{T: 0, Color: NewColor(0, 1, 0)},
+		},
+	}
+
+	if got := ps.ColorAt(0); got != NewColor(0, 1, 0) {
+		t.Errorf("ColorAt(0) mismatch: got %+v", got)
+	}
+	mid := ps.ColorAt(0.5)
+	if math.Abs(mid.R-0.5) > 1e-9 || math.Abs(mid.A-0.5) > 1e-9 {
+		t.Errorf("ColorAt(0.5) mismatch: got %+v", mid)
+	}
+	if got := ps.ColorAt(2); got != NewColorWithAlpha(1, 0, 0, 0) {
+		t.Errorf("ColorAt(2) mismatch: got %+v", got)
+	}
+}
+
+// TestParticleSystem_Sample tests emitter sampling stays within the shape
+func TestParticleSystem_Sample(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sphere := ParticleSystem{Emitter: ParticleEmitter{Shape: EmitterSphere, Radius: 2}}
+	path := ParticleSystem{Emitter: ParticleEmitter{Shape: EmitterPath}}
+	from, to := Vector3{}, Vector3{X: 10}
+
+	for i := 0; i < 100; i++ {
+		if p := sphere.SamplePosition(rng, from, to); p.Length() > 2+1e-9 {
+			t.Fatalf("Sphere sample outside radius: %+v", p)
+		}
+		p := path.SamplePosition(rng, from, to)
+		if p.X < 0 || p.X > 10 || p.Y != 0 || p.Z != 0 {
+			t.Fatalf("Path sample off edge: %+v", p)
+		}
+	}
+	if d := path.SampleDirection(rng, from, to); d != (Vector3{X: 1}) {
+		t.Errorf("Path direction mismatch: got %+v", d)
+	}
+}
+
+// TestParticleSystem_JSON tests ParticleSystem JSON marshaling/unmarshaling
+func TestParticleSystem_JSON(t *testing.T) {
+	original := SceneEdge{
+		ID:     "edge-1",
+		Source: "a",
+		Target: "b",
+		Particles: []ParticleSystem{{
+			Name:     "traffic",
+			Emitter:  ParticleEmitter{Shape: EmitterPath},
+			Rate:     20,
+			Lifetime: 1.5,
+			Binding:  &ParticleMetricBinding{Metric: "rps", Max: 100},
+		}},
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Failed to marshal SceneEdge: %v", err)
+	}
+
+	var result SceneEdge
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal SceneEdge: %v", err)
+	}
+	if len(result.Particles) != 1 || result.Particles[0].Binding == nil || result.Particles[0].Emitter.Shape != EmitterPath {
+		t.Errorf("ParticleSystem mismatch: got %+v", result.Particles)
+	}
+}
@@ -0,0 +1,362 @@
+package starfleet
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// PATH QUERIES
+// =============================================================================
+
+// PathConstraints represents the constraints applied by FindPaths
+type PathConstraints struct {
+	// MaxPaths is the number of ranked paths to return; defaults to 1
+	MaxPaths int
+	// MaxHops limits paths to this many edges when positive. The limit is
+	// applied during the search, so lower-ranked paths within it are found
+	// even when cheaper paths exceed it.
+	MaxHops int
+	// Metric ranks paths by the sum of this edge metric instead of hop
+	// count. Edges without a non-negative numeric value are not traversed.
+	Metric string
+	// EdgeTypes restricts traversal to the listed edge types when non-empty
+	EdgeTypes []string
+	// AvoidNodes lists nodes that paths may not pass through
+	AvoidNodes []string
+	// IgnoreDirection traverses directed edges in both directions
+	IgnoreDirection bool
+}
+
+// Path represents a route through the scene graph
+type Path struct {
+	Nodes []string `json:"nodes"`
+	Edges []string `json:"edges"`
+	Cost  float64  `json:"cost"`
+}
+
+// pathArc is a traversable edge in the search graph
+type pathArc struct {
+	edge string
+	to   string
+	cost float64
+}
+
+// FindPaths returns up to MaxPaths loopless paths from one node to another,
+// ranked by ascending cost (hop count, or the configured edge metric). It
+// uses Yen's algorithm on top of Dijkstra, so results are exact. An empty
+// slice is returned when the nodes are not connected.
+func FindPaths(sf *SceneFile, from, to string, c PathConstraints) ([]Path, error) {
+	if sf.FindNode(from) == nil {
+		return nil, fmt.Errorf("find paths: %w: %s", ErrNodeNotFound, from)
+	}
+	if sf.FindNode(to) == nil {
+		return nil, fmt.Errorf("find paths: %w: %s", ErrNodeNotFound, to)
+	}
+	maxPaths := c.MaxPaths
+	if maxPaths <= 0 {
+		maxPaths = 1
+	}
+
+	graph := buildPathGraph(sf, c)
+	avoid := make(map[string]bool, len(c.AvoidNodes))
+	for _, id := range c.AvoidNodes {
+		avoid[id] = true
+	}
+	if avoid[from] || avoid[to] {
+		return []Path{}, nil
+	}
+
+	first, ok := shortestPath(graph, from, to, avoid, nil, c.MaxHops)
+	if !ok {
+		return []Path{}, nil
+	}
+	found := []Path{first}
+	var candidates []Path
+	seen := map[string]bool{pathKey(first): true}
+
+	for len(found) < maxPaths {
+		prev := found[len(found)-1]
+		for i := 0; i < len(prev.Nodes)-1; i++ {
+			hops := 0
+			if c.MaxHops > 0 {
+				if hops = c.MaxHops - i; hops <= 0 {
+					break
+				}
+			}
+			spur := prev.Nodes[i]
+			rootNodes, rootEdges := prev.Nodes[:i+1], prev.Edges[:i]
+
+			removedEdges := make(map[string]bool)
+			for _, p := range found {
+				if len(p.Nodes) > i && equalStrings(p.Nodes[:i+1], rootNodes) {
+					removedEdges[p.Edges[i]] = true
+				}
+			}
+			blocked := make(map[string]bool, len(avoid)+i)
+			for id := range avoid {
+				blocked[id] = true
+			}
+			for _, id := range rootNodes[:i] {
+				blocked[id] = true
+			}
+
+			spurPath, ok := shortestPath(graph, spur, to, blocked, removedEdges, hops)
+			if !ok {
+				continue
+			}
+			total := Path{
+				Nodes: append(append([]string{}, rootNodes...), spurPath.Nodes[1:]...),
+				Edges: append(append([]string{}, rootEdges...), spurPath.Edges...),
+				Cost:  pathCost(graph, rootNodes, rootEdges) + spurPath.Cost,
+			}
+			if key := pathKey(total); !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, total)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Cost < candidates[j].Cost })
+		found = append(found, candidates[0])
+		candidates = candidates[1:]
+	}
+	return found, nil
+}
+
+// buildPathGraph builds the adjacency list used for path search
+func buildPathGraph(sf *SceneFile, c PathConstraints) map[string][]pathArc {
+	types := make(map[string]bool, len(c.EdgeTypes))
+	for _, t := range c.EdgeTypes {
+		types[t] = true
+	}
+
+	graph := make(map[string][]pathArc, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if len(types) > 0 && !types[edge.Type] {
+			continue
+		}
+		cost := 1.0
+		if c.Metric != "" {
+			v, ok := toFloat64(edge.Metrics[c.Metric])
+			if !ok || v < 0 || math.IsNaN(v) {
+				continue
+			}
+			cost = v
+		}
+		graph[edge.Source] = append(graph[edge.Source], pathArc{edge.ID, edge.Target, cost})
+		if c.IgnoreDirection || !edge.IsDirected() {
+			graph[edge.Target] = append(graph[edge.Target], pathArc{edge.ID, edge.Source, cost})
+		}
+	}
+	return graph
+}
+
+// pathCost sums the cost of a partial path
+func pathCost(graph map[string][]pathArc, nodes, edges []string) float64 {
+	total := 0.0
+	for i, e := range edges {
+		for _, arc := range graph[nodes[i]] {
+			if arc.edge == e && arc.to == nodes[i+1] {
+				total += arc.cost
+				break
+			}
+		}
+	}
+	return total
+}
+
+// pathKey identifies a path by its edge sequence
+func pathKey(p Path) string {
+	return strings.Join(p.Nodes, "\x00") + "\x01" + strings.Join(p.Edges, "\x00")
+}
+
+// equalStrings reports whether two string slices are identical
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathState is a node reached by the search. Hops is only counted when the
+// search is limited, since the same node reached in fewer hops may then
+// lead to paths a longer but cheaper arrival cannot.
+type pathState struct {
+	node string
+	hops int
+}
+
+// pathQueueItem is an entry in the Dijkstra priority queue
+type pathQueueItem struct {
+	state pathState
+	cost  float64
+}
+
+// pathQueue implements heap.Interface ordered by cost, then hops
+type pathQueue []pathQueueItem
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].state.hops < q[j].state.hops
+}
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathQueueItem)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// shortestPath runs Dijkstra from one node to another, skipping blocked
+// nodes and removed edges. A positive maxHops limits the path to that many
+// edges.
+func shortestPath(graph map[string][]pathArc, from, to string, blocked, removed map[string]bool, maxHops int) (Path, bool) {
+	start := pathState{node: from}
+	dist := map[pathState]float64{start: 0}
+	type step struct {
+		state pathState
+		edge  string
+	}
+	prev := make(map[pathState]step)
+	done := make(map[pathState]bool)
+	q := &pathQueue{{start, 0}}
+
+	end, found := pathState{}, false
+	for q.Len() > 0 {
+		item := heap.Pop(q).(pathQueueItem)
+		if done[item.state] {
+			continue
+		}
+		done[item.state] = true
+		if item.state.node == to {
+			end, found = item.state, true
+			break
+		}
+		next := pathState{}
+		if maxHops > 0 {
+			if next.hops = item.state.hops + 1; next.hops > maxHops {
+				continue
+			}
+		}
+		for _, arc := range graph[item.state.node] {
+			next.node = arc.to
+			if removed[arc.edge] || blocked[arc.to] || done[next] {
+				continue
+			}
+			d := item.cost + arc.cost
+			if old, ok := dist[next]; !ok || d < old {
+				dist[next] = d
+				prev[next] = step{item.state, arc.edge}
+				heap.Push(q, pathQueueItem{next, d})
+			}
+		}
+	}
+
+	if !found {
+		return Path{}, false
+	}
+	p := Path{Nodes: []string{to}, Cost: dist[end]}
+	for s := end; s != start; {
+		st := prev[s]
+		p.Nodes = append(p.Nodes, st.state.node)
+		p.Edges = append(p.Edges, st.edge)
+		s = st.state
+	}
+	reverseStrings(p.Nodes)
+	reverseStrings(p.Edges)
+	return p, true
+}
+
+// reverseStrings reverses a string slice in place
+func reverseStrings(s []string) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// HighlightExtension is the node and edge extension key holding the names of
+// highlights an element belongs to
+const HighlightExtension = "highlight"
+
+// HighlightPath marks the nodes and edges of a path with a named highlight
+// so viewers can emphasize the route
+func HighlightPath(sf *SceneFile, path Path, name string) {
+	nodes := make(map[string]bool, len(path.Nodes))
+	for _, id := range path.Nodes {
+		nodes[id] = true
+	}
+	edges := make(map[string]bool, len(path.Edges))
+	for _, id := range path.Edges {
+		edges[id] = true
+	}
+
+	for i := range sf.Scene.Nodes {
+		if n := &sf.Scene.Nodes[i]; nodes[n.ID] {
+			n.Extensions = addHighlight(n.Extensions, name)
+		}
+	}
+	for i := range sf.Scene.Edges {
+		if e := &sf.Scene.Edges[i]; edges[e.ID] {
+			e.Extensions = addHighlight(e.Extensions, name)
+		}
+	}
+}
+
+// ClearHighlight removes a named highlight from every node and edge
+func ClearHighlight(sf *SceneFile, name string) {
+	for i := range sf.Scene.Nodes {
+		removeHighlight(sf.Scene.Nodes[i].Extensions, name)
+	}
+	for i := range sf.Scene.Edges {
+		removeHighlight(sf.Scene.Edges[i].Extensions, name)
+	}
+}
+
+// addHighlight adds a highlight name to an extensions map
+func addHighlight(ext map[string]interface{}, name string) map[string]interface{} {
+	if ext == nil {
+		ext = make(map[string]interface{})
+	}
+	names := toStringSlice(ext[HighlightExtension])
+	for _, n := range names {
+		if n == name {
+			return ext
+		}
+	}
+	ext[HighlightExtension] = append(names, name)
+	return ext
+}
+
+// removeHighlight removes a highlight name from an extensions map
+func removeHighlight(ext map[string]interface{}, name string) {
+	if ext == nil {
+		return
+	}
+	names := toStringSlice(ext[HighlightExtension])
+	kept := make([]string, 0, len(names))
+	for _, n := range names {
+		if n != name {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) == 0 {
+		delete(ext, HighlightExtension)
+	} else {
+		ext[HighlightExtension] = kept
+	}
+}
@@ -0,0 +1,98 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// newPathScene creates lb -> {api1, api2} -> db with a direct slow link
+func newPathScene() SceneFile {
+	sf := NewSceneFile("Paths")
+	for _, id := range []string{"lb", "api1", "api2", "db"} {
+		sf.AddNode(SceneNode{ID: id, Type: "service", Name: id, Transform: NewTransform()})
+	}
+	link := func(id, from, to string, latency float64) {
+		sf.AddEdge(SceneEdge{ID: id, Source: from, Target: to, Metrics: map[string]interface{}{"latency": latency}})
+	}
+	link("lb-api1", "lb", "api1", 5)
+	link("lb-api2", "lb", "api2", 1)
+	link("api1-db", "api1", "db", 1)
+	link("api2-db", "api2", "db", 2)
+	link("lb-db", "lb", "db", 20)
+	return sf
+}
+
+// TestFindPaths tests ranked path search by hops and metric
+func TestFindPaths(t *testing.T) {
+	sf := newPathScene()
+
+	paths, err := FindPaths(&sf, "lb", "db", PathConstraints{MaxPaths: 5})
+	if err != nil {
+		t.Fatalf("FindPaths failed: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("FindPaths count mismatch: got %d, want 3", len(paths))
+	}
+	if paths[0].Cost != 1 || len(paths[0].Edges) != 1 || paths[0].Edges[0] != "lb-db" {
+		t.Errorf("Shortest hop path mismatch: got %+v", paths[0])
+	}
+
+	paths, err = FindPaths(&sf, "lb", "db", PathConstraints{MaxPaths: 5, Metric: "latency"})
+	if err != nil {
+		t.Fatalf("FindPaths failed: %v", err)
+	}
+	wantCosts := []float64{3, 6, 20}
+	for i, p := range paths {
+		if p.Cost != wantCosts[i] {
+			t.Errorf("Path %d cost mismatch: got %f, want %f", i, p.Cost, wantCosts[i])
+		}
+	}
+	if !equalStrings(paths[0].Nodes, []string{"lb", "api2", "db"}) {
+		t.Errorf("Lowest latency path mismatch: got %v", paths[0].Nodes)
+	}
+
+	paths, _ = FindPaths(&sf, "lb", "db", PathConstraints{MaxPaths: 5, AvoidNodes: []string{"api2"}, MaxHops: 1})
+	if len(paths) != 1 || paths[0].Edges[0] != "lb-db" {
+		t.Errorf("Constrained paths mismatch: got %+v", paths)
+	}
+	paths, _ = FindPaths(&sf, "lb", "db", PathConstraints{Metric: "latency", MaxHops: 1})
+	if len(paths) != 1 || paths[0].Edges[0] != "lb-db" || paths[0].Cost != 20 {
+		t.Errorf("Hop-limited path should skip cheaper longer paths: got %+v", paths)
+	}
+
+	paths, _ = FindPaths(&sf, "db", "lb", PathConstraints{})
+	if len(paths) != 0 {
+		t.Errorf("Directed edges should not be traversed backwards: got %+v", paths)
+	}
+	paths, _ = FindPaths(&sf, "db", "lb", PathConstraints{IgnoreDirection: true})
+	if len(paths) != 1 {
+		t.Errorf("IgnoreDirection should find a path: got %+v", paths)
+	}
+
+	if _, err := FindPaths(&sf, "lb", "nowhere", PathConstraints{}); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("FindPaths error mismatch: got %v", err)
+	}
+}
+
+// TestHighlightPath tests marking and clearing path highlights
+func TestHighlightPath(t *testing.T) {
+	sf := newPathScene()
+	paths, _ := FindPaths(&sf, "lb", "db", PathConstraints{Metric: "latency"})
+
+	HighlightPath(&sf, paths[0], "request")
+	HighlightPath(&sf, paths[0], "request")
+	if got := toStringSlice(sf.FindNode("api2").Extensions[HighlightExtension]); len(got) != 1 || got[0] != "request" {
+		t.Errorf("Node highlight mismatch: got %v", got)
+	}
+	if sf.FindNode("api1").Extensions != nil {
+		t.Errorf("Node off the path should not be highlighted")
+	}
+	if sf.FindEdge("api2-db").Extensions[HighlightExtension] == nil {
+		t.Errorf("Edge on the path should be highlighted")
+	}
+
+	ClearHighlight(&sf, "request")
+	if _, ok := sf.FindNode("api2").Extensions[HighlightExtension]; ok {
+		t.Errorf("ClearHighlight did not remove highlight")
+	}
+}
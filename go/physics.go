@@ -0,0 +1,276 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// PHYSICS
+// =============================================================================
+
+// BodyType represents how a physics body participates in simulation
+type BodyType string
+
+const (
+	// BodyStatic bodies never move and have infinite mass
+	BodyStatic BodyType = "static"
+	// BodyDynamic bodies are moved by forces, gravity and collisions
+	BodyDynamic BodyType = "dynamic"
+	// BodyKinematic bodies are moved by animation and push dynamic bodies
+	BodyKinematic BodyType = "kinematic"
+)
+
+// ColliderShape represents the collision volume of a physics body
+type ColliderShape string
+
+const (
+	ColliderBox      ColliderShape = "box"
+	ColliderSphere   ColliderShape = "sphere"
+	ColliderCapsule  ColliderShape = "capsule"
+	ColliderCylinder ColliderShape = "cylinder"
+	// ColliderConvex wraps the node's custom mesh in its convex hull
+	ColliderConvex ColliderShape = "convex"
+	// ColliderMesh uses the node's custom mesh as-is; static bodies only in
+	// most engines
+	ColliderMesh ColliderShape = "mesh"
+)
+
+// Collider represents the collision volume of a node in local space. Box
+// colliders use Size; spheres use Radius; capsules and cylinders use Radius
+// and Height along Y. Unset dimensions are derived from the geometry.
+type Collider struct {
+	Shape  ColliderShape `json:"shape" validate:"required"`
+	Size   *Vector3      `json:"size,omitempty"`
+	Radius float64       `json:"radius,omitempty" validate:"omitempty,gt=0"`
+	Height float64       `json:"height,omitempty" validate:"omitempty,gt=0"`
+	Offset *Vector3      `json:"offset,omitempty"`
+}
+
+// PhysicsBody represents the simulation properties of a node
+type PhysicsBody struct {
+	Type        BodyType  `json:"type" validate:"required"`
+	Mass        float64   `json:"mass,omitempty" validate:"omitempty,gt=0"`
+	Collider    *Collider `json:"collider,omitempty"`
+	Friction    float64   `json:"friction,omitempty" validate:"omitempty,min=0"`
+	Restitution float64   `json:"restitution,omitempty" validate:"omitempty,min=0,max=1"`
+	// LinearDamping and AngularDamping slow bodies down over time
+	LinearDamping  float64 `json:"linearDamping,omitempty" validate:"omitempty,min=0"`
+	AngularDamping float64 `json:"angularDamping,omitempty" validate:"omitempty,min=0"`
+}
+
+// JointType represents how an edge constrains its two endpoint bodies
+type JointType string
+
+const (
+	JointFixed  JointType = "fixed"
+	JointHinge  JointType = "hinge"
+	JointBall   JointType = "ball"
+	JointSlider JointType = "slider"
+	JointSpring JointType = "spring"
+)
+
+// JointLimits represents the allowed range of a hinge angle in radians or a
+// slider offset in scene units
+type JointLimits struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Joint represents a physical connection between the source and target
+// bodies of an edge. Anchors are in each body's local space and default to
+// the body origins. Hinges rotate about Axis and sliders move along it.
+type Joint struct {
+	Type         JointType    `json:"type" validate:"required"`
+	SourceAnchor *Vector3     `json:"sourceAnchor,omitempty"`
+	TargetAnchor *Vector3     `json:"targetAnchor,omitempty"`
+	Axis         *Vector3     `json:"axis,omitempty"`
+	Limits       *JointLimits `json:"limits,omitempty"`
+	// Stiffness and Damping apply to springs; RestLength defaults to the
+	// initial distance between the anchors
+	Stiffness  float64 `json:"stiffness,omitempty" validate:"omitempty,gt=0"`
+	Damping    float64 `json:"damping,omitempty" validate:"omitempty,min=0"`
+	RestLength float64 `json:"restLength,omitempty" validate:"omitempty,min=0"`
+}
+
+// colliderTolerance is the relative size difference between a collider and
+// its geometry reported as a mismatch
+const colliderTolerance = 0.1
+
+// ValidatePhysics checks physics bodies and joints for values simulation
+// engines reject: missing or non-positive mass on dynamic bodies, unknown
+// body, collider and joint types, mesh colliders without a mesh, and joints
+// whose endpoints are not bodies or cannot move.
+func ValidatePhysics(sf *SceneFile) []string {
+	var errs []string
+	bodies := make(map[string]*PhysicsBody)
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		body := node.Physics
+		if body == nil {
+			continue
+		}
+		bodies[node.ID] = body
+		switch body.Type {
+		case BodyStatic, BodyKinematic:
+		case BodyDynamic:
+			if body.Mass <= 0 {
+				errs = append(errs, fmt.Sprintf("Node %s is a dynamic body without a positive mass", node.ID))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("Node %s has unknown physics body type: %s", node.ID, body.Type))
+		}
+		if body.Restitution < 0 || body.Restitution > 1 || body.Friction < 0 {
+			errs = append(errs, fmt.Sprintf("Node %s has friction or restitution out of range", node.ID))
+		}
+
+		c := body.Collider
+		if c == nil {
+			continue
+		}
+		switch c.Shape {
+		case ColliderBox, ColliderSphere, ColliderCapsule, ColliderCylinder:
+		case ColliderConvex, ColliderMesh:
+			if g := sf.ResolveGeometry(node); g == nil || g.Mesh == nil {
+				errs = append(errs, fmt.Sprintf("Node %s has a %s collider but no custom mesh", node.ID, c.Shape))
+			}
+			if c.Shape == ColliderMesh && body.Type == BodyDynamic {
+				errs = append(errs, fmt.Sprintf("Node %s is a dynamic body with a mesh collider; use a convex collider", node.ID))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("Node %s has unknown collider shape: %s", node.ID, c.Shape))
+		}
+		if c.Radius < 0 || c.Height < 0 || (c.Size != nil && (c.Size.X < 0 || c.Size.Y < 0 || c.Size.Z < 0)) {
+			errs = append(errs, fmt.Sprintf("Node %s has a collider with negative dimensions", node.ID))
+		}
+	}
+
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		joint := edge.Joint
+		if joint == nil {
+			continue
+		}
+		switch joint.Type {
+		case JointFixed, JointBall, JointSpring:
+		case JointHinge, JointSlider:
+			if joint.Axis == nil || joint.Axis.Length() == 0 {
+				errs = append(errs, fmt.Sprintf("Edge %s has a %s joint without an axis", edge.ID, joint.Type))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("Edge %s has unknown joint type: %s", edge.ID, joint.Type))
+		}
+		if joint.Limits != nil && joint.Limits.Min > joint.Limits.Max {
+			errs = append(errs, fmt.Sprintf("Edge %s has joint limits with min above max", edge.ID))
+		}
+		source, target := bodies[edge.Source], bodies[edge.Target]
+		if source == nil || target == nil {
+			errs = append(errs, fmt.Sprintf("Edge %s has a joint but its endpoints are not both physics bodies", edge.ID))
+			continue
+		}
+		if source.Type != BodyDynamic && target.Type != BodyDynamic {
+			errs = append(errs, fmt.Sprintf("Edge %s joins two bodies that cannot move", edge.ID))
+		}
+	}
+	return errs
+}
+
+// PhysicsWarnings reports colliders that do not match the shape or size of
+// their node's geometry, which makes bodies collide with empty space or
+// sink into each other
+func PhysicsWarnings(sf *SceneFile) []string {
+	var warnings []string
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		if node.Physics == nil || node.Physics.Collider == nil {
+			continue
+		}
+		c := node.Physics.Collider
+		g := sf.ResolveGeometry(node)
+		if g == nil {
+			continue
+		}
+		if expected, ok := colliderForGeometry(g); ok && expected.Shape != c.Shape &&
+			c.Shape != ColliderConvex && c.Shape != ColliderMesh {
+			warnings = append(warnings, fmt.Sprintf(
+				"Node %s has a %s collider on %s geometry", node.ID, c.Shape, g.Type))
+			continue
+		}
+		want := geometryHalfExtents(g)
+		got, ok := colliderHalfExtents(c, want)
+		if !ok {
+			continue
+		}
+		if !withinTolerance(got.X, want.X) || !withinTolerance(got.Y, want.Y) || !withinTolerance(got.Z, want.Z) {
+			warnings = append(warnings, fmt.Sprintf(
+				"Node %s collider size differs from its geometry by more than %d%%", node.ID, int(colliderTolerance*100)))
+		}
+	}
+	return warnings
+}
+
+// colliderForGeometry returns the primitive collider that matches a
+// geometry exactly
+func colliderForGeometry(g *Geometry) (Collider, bool) {
+	h := geometryHalfExtents(g)
+	switch g.Type {
+	case GeometryBox, GeometryPlane:
+		size := h.Scale(2)
+		return Collider{Shape: ColliderBox, Size: &size}, true
+	case GeometrySphere:
+		return Collider{Shape: ColliderSphere, Radius: h.X}, true
+	case GeometryCylinder:
+		return Collider{Shape: ColliderCylinder, Radius: h.X, Height: 2 * h.Y}, true
+	default:
+		return Collider{}, false
+	}
+}
+
+// ColliderFromGeometry returns a collider fitted to a node's geometry: the
+// matching primitive for built-in geometry and a convex hull for custom
+// meshes
+func ColliderFromGeometry(g *Geometry) Collider {
+	if c, ok := colliderForGeometry(g); ok {
+		return c
+	}
+	if g != nil && g.Mesh != nil {
+		return Collider{Shape: ColliderConvex}
+	}
+	size := geometryHalfExtents(g).Scale(2)
+	return Collider{Shape: ColliderBox, Size: &size}
+}
+
+// colliderHalfExtents returns the half size of a collider, filling unset
+// dimensions from the geometry. Mesh-based colliders always match and
+// report false.
+func colliderHalfExtents(c *Collider, geometry Vector3) (Vector3, bool) {
+	switch c.Shape {
+	case ColliderBox:
+		if c.Size == nil {
+			return geometry, true
+		}
+		return c.Size.Scale(0.5), true
+	case ColliderSphere:
+		r := c.Radius
+		if r == 0 {
+			r = math.Max(geometry.X, math.Max(geometry.Y, geometry.Z))
+		}
+		return Vector3{X: r, Y: r, Z: r}, true
+	case ColliderCapsule, ColliderCylinder:
+		r, h := c.Radius, c.Height/2
+		if r == 0 {
+			r = geometry.X
+		}
+		if h == 0 {
+			h = geometry.Y
+		}
+		return Vector3{X: r, Y: h, Z: r}, true
+	default:
+		return Vector3{}, false
+	}
+}
+
+// withinTolerance reports whether two sizes agree within colliderTolerance
+func withinTolerance(got, want float64) bool {
+	return math.Abs(got-want) <= colliderTolerance*math.Max(math.Abs(want), 1e-9)
+}
@@ -0,0 +1,79 @@
+package starfleet
+
+import "testing"
+
+// newPhysicsScene returns a hinged door on a static frame
+func newPhysicsScene() SceneFile {
+	sf := NewSceneFile("Physics")
+	box := &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{"width": 2.0, "height": 1.0, "depth": 1.0}}
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "frame", Name: "Frame", Geometry: box, Physics: &PhysicsBody{Type: BodyStatic, Collider: &Collider{Shape: ColliderBox}}},
+		{ID: "door", Name: "Door", Geometry: box, Physics: &PhysicsBody{Type: BodyDynamic, Mass: 20,
+			Collider: &Collider{Shape: ColliderBox, Size: &Vector3{X: 2, Y: 1, Z: 1}}}},
+	}
+	sf.Scene.Edges = []SceneEdge{{ID: "hinge", Source: "frame", Target: "door",
+		Joint: &Joint{Type: JointHinge, Axis: &Vector3{Y: 1}, Limits: &JointLimits{Min: 0, Max: 1.5}}}}
+	return sf
+}
+
+// TestValidatePhysics tests detection of invalid bodies and joints
+func TestValidatePhysics(t *testing.T) {
+	sf := newPhysicsScene()
+	if errs := ValidatePhysics(&sf); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	sf.FindNode("door").Physics.Mass = 0
+	sf.FindNode("door").Physics.Collider = &Collider{Shape: ColliderMesh}
+	sf.FindEdge("hinge").Joint.Axis = nil
+	sf.Scene.Edges = append(sf.Scene.Edges, SceneEdge{ID: "rope", Source: "frame", Target: "frame",
+		Joint: &Joint{Type: JointSpring}})
+
+	errs := ValidatePhysics(&sf)
+	for _, want := range []string{
+		"Node door is a dynamic body without a positive mass",
+		"Node door has a mesh collider but no custom mesh",
+		"Node door is a dynamic body with a mesh collider; use a convex collider",
+		"Edge hinge has a hinge joint without an axis",
+		"Edge rope joins two bodies that cannot move",
+	} {
+		if !containsMessage(errs, want) {
+			t.Errorf("missing error %q in %v", want, errs)
+		}
+	}
+}
+
+// TestPhysicsWarnings tests collider and geometry agreement
+func TestPhysicsWarnings(t *testing.T) {
+	sf := newPhysicsScene()
+	if warnings := PhysicsWarnings(&sf); len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+
+	sf.FindNode("frame").Physics.Collider = &Collider{Shape: ColliderSphere}
+	sf.FindNode("door").Physics.Collider.Size = &Vector3{X: 3, Y: 1, Z: 1}
+	warnings := PhysicsWarnings(&sf)
+	if !containsMessage(warnings, "Node frame has a sphere collider on box geometry") {
+		t.Errorf("missing shape warning in %v", warnings)
+	}
+	if !containsMessage(warnings, "Node door collider size differs from its geometry by more than 10%") {
+		t.Errorf("missing size warning in %v", warnings)
+	}
+
+	result := ValidateScene(&sf)
+	if !result.Valid || len(result.Warnings) != 2 {
+		t.Errorf("ValidateScene mismatch: valid %v, warnings %v", result.Valid, result.Warnings)
+	}
+}
+
+// TestColliderFromGeometry tests fitted colliders
+func TestColliderFromGeometry(t *testing.T) {
+	c := ColliderFromGeometry(&Geometry{Type: GeometryCylinder, Parameters: map[string]interface{}{"radius": 2.0, "height": 4.0}})
+	if c.Shape != ColliderCylinder || c.Radius != 2 || c.Height != 4 {
+		t.Errorf("cylinder collider mismatch: got %+v", c)
+	}
+	mesh := newGridMesh(2)
+	if c := ColliderFromGeometry(&Geometry{Type: GeometryCustom, Mesh: &mesh}); c.Shape != ColliderConvex {
+		t.Errorf("mesh collider mismatch: got %s, want convex", c.Shape)
+	}
+}
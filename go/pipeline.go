@@ -0,0 +1,478 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// PIPELINES
+// =============================================================================
+
+// Built-in pipeline stage types
+const (
+	StageImport    = "import"
+	StageRepair    = "repair"
+	StageDefaults  = "defaults"
+	StageGroup     = "group"
+	StageLayout    = "layout"
+	StageTheme     = "theme"
+	StageValidate  = "validate"
+	StageCost      = "cost"
+	StageOwnership = "ownership"
+	StageRollup    = "rollup"
+	StageLatency   = "latency"
+)
+
+// ErrUnknownStage is returned for pipeline stages of a type no factory
+// builds
+var ErrUnknownStage = errors.New("unknown pipeline stage")
+
+// PipelineStage is one step of a pipeline. It transforms the scene in place
+// and returns notes worth reporting, such as warnings or repairs made.
+type PipelineStage interface {
+	Run(ctx context.Context, sf *SceneFile) ([]string, error)
+}
+
+// PipelineStageFunc adapts a function to the PipelineStage interface
+type PipelineStageFunc func(ctx context.Context, sf *SceneFile) ([]string, error)
+
+// Run calls f
+func (f PipelineStageFunc) Run(ctx context.Context, sf *SceneFile) ([]string, error) {
+	return f(ctx, sf)
+}
+
+// StageFactory builds a stage from the options given in its configuration
+type StageFactory func(options json.RawMessage) (PipelineStage, error)
+
+// StageConfig declares one stage of a pipeline. Name identifies it in
+// reports and defaults to Type. A failing stage stops the pipeline unless
+// ContinueOnError is set.
+type StageConfig struct {
+	Name            string          `json:"name,omitempty"`
+	Type            string          `json:"type" validate:"required"`
+	Options         json.RawMessage `json:"options,omitempty"`
+	ContinueOnError bool            `json:"continueOnError,omitempty"`
+}
+
+// PipelineConfig declares the stages of a pipeline, in order
+type PipelineConfig struct {
+	Stages []StageConfig `json:"stages" validate:"required,min=1"`
+}
+
+// LoadPipelineConfig reads a pipeline configuration from JSON, rejecting
+// unknown fields so misspelled settings are not silently ignored
+func LoadPipelineConfig(r io.Reader) (PipelineConfig, error) {
+	var cfg PipelineConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return PipelineConfig{}, fmt.Errorf("pipeline config: %w", err)
+	}
+	return cfg, nil
+}
+
+// StageReport describes one run of a stage. Duration is encoded in
+// nanoseconds.
+type StageReport struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Duration time.Duration `json:"duration"`
+	Notes    []string      `json:"notes,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// PipelineReport describes a pipeline run, with a report for every stage
+// that ran
+type PipelineReport struct {
+	Stages   []StageReport `json:"stages"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Failed returns the reports of the stages that failed
+func (r *PipelineReport) Failed() []StageReport {
+	var failed []StageReport
+	for _, s := range r.Stages {
+		if s.Error != "" {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
+
+// PipelineError reports the stage that stopped a pipeline
+type PipelineError struct {
+	Stage string
+	Err   error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline stage %s: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns the stage's error
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline runs a sequence of stages over a scene, such as import, repair,
+// defaults, grouping, layout, theme and validation, timing each one
+type Pipeline struct {
+	stages []pipelineStep
+}
+
+type pipelineStep struct {
+	config StageConfig
+	stage  PipelineStage
+}
+
+// NewPipeline builds the stages of a configuration with the factories for
+// their types; see DefaultStageFactories
+func NewPipeline(cfg PipelineConfig, factories map[string]StageFactory) (*Pipeline, error) {
+	if len(cfg.Stages) == 0 {
+		return nil, errors.New("pipeline: no stages")
+	}
+	p := &Pipeline{stages: make([]pipelineStep, 0, len(cfg.Stages))}
+	names := make(map[string]bool, len(cfg.Stages))
+	for i, sc := range cfg.Stages {
+		if sc.Name == "" {
+			sc.Name = sc.Type
+		}
+		if names[sc.Name] {
+			return nil, fmt.Errorf("pipeline: duplicate stage name %q; name repeated stages", sc.Name)
+		}
+		names[sc.Name] = true
+		factory, ok := factories[sc.Type]
+		if !ok {
+			return nil, fmt.Errorf("pipeline stage %d: %w: %q", i, ErrUnknownStage, sc.Type)
+		}
+		stage, err := factory(sc.Options)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline stage %s: %w", sc.Name, err)
+		}
+		p.stages = append(p.stages, pipelineStep{config: sc, stage: stage})
+	}
+	return p, nil
+}
+
+// Run runs the stages in order on sf, which is modified in place and left
+// as the last stage to run left it. The first failing stage without
+// ContinueOnError stops the run with a *PipelineError; the report covers
+// every stage that ran either way.
+//
+// With a reporter set by WithProgress, the run reports the start of each
+// stage and its own end, counting stages, and passes on what operations
+// within stages report. All reports name the pipeline stage and give the
+// percentage of the whole run; the ones passed on carry the operation's
+// stage as their message and its own item counts.
+func (p *Pipeline) Run(ctx context.Context, sf *SceneFile) (PipelineReport, error) {
+	var report PipelineReport
+	start := time.Now()
+	reporter := ProgressFromContext(ctx)
+	n := len(p.stages)
+	for i, step := range p.stages {
+		if err := ctx.Err(); err != nil {
+			report.Duration = time.Since(start)
+			return report, &PipelineError{Stage: step.config.Name, Err: err}
+		}
+		stageCtx := ctx
+		if reporter != nil {
+			progress := newProgress(step.config.Name, i, n)
+			reporter.ReportProgress(progress)
+			stageCtx = WithProgress(ctx, ProgressReporterFunc(func(inner Progress) {
+				reporter.ReportProgress(Progress{
+					Stage:     progress.Stage,
+					Percent:   progress.Percent + inner.Percent/float64(n),
+					Processed: inner.Processed,
+					Total:     inner.Total,
+					Message:   inner.Stage,
+				})
+			}))
+		}
+		stageStart := time.Now()
+		notes, err := step.stage.Run(stageCtx, sf)
+		sr := StageReport{Name: step.config.Name, Type: step.config.Type, Duration: time.Since(stageStart), Notes: notes}
+		if err != nil {
+			sr.Error = err.Error()
+		}
+		report.Stages = append(report.Stages, sr)
+		if err != nil && !step.config.ContinueOnError {
+			report.Duration = time.Since(start)
+			return report, &PipelineError{Stage: step.config.Name, Err: err}
+		}
+	}
+	if reporter != nil {
+		reporter.ReportProgress(newProgress(p.stages[n-1].config.Name, n, n))
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// decodeStageOptions decodes stage options over defaults, rejecting unknown
+// fields. Missing options keep the defaults.
+func decodeStageOptions(options json.RawMessage, v interface{}) error {
+	if len(bytes.TrimSpace(options)) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(options))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("options: %w", err)
+	}
+	return nil
+}
+
+// DefaultStageFactories returns factories for the built-in stage types.
+// Import stages run one of the given importers. Options are:
+//
+//   - import: {"importer": name, "config": {...}}; the imported scene
+//     replaces the input, and importer warnings become notes
+//   - repair: a RepairPolicy
+//   - defaults: NodeDefaults, filling unset fields of matching nodes
+//   - group: SummarizeOptions, defaulting to DefaultSummarizeOptions
+//   - layout: {"layout": "geo" or "racks", ...} with the fields of
+//     GeoLayout or RackLayout over their defaults
+//   - theme: a Theme
+//   - validate: {"strict": true} to fail on warnings; fails on errors
+//   - cost: {"path": file, "format": "aws-cur" or "gcp", ...} with the
+//     fields of CostEnricher, attributing a billing export to nodes
+//   - ownership: {"codeowners", "backstage", "pagerduty": file, "teams":
+//     {team: schedule ID}} with the fields of OwnershipEnricher
+//   - rollup: {"rules": [RollupRule...], "store": true} rolling metrics up
+//     onto groups by the given rules and those the scene stores
+//   - latency: LatencyOptions, storing the slowest dependency paths
+func DefaultStageFactories(importers map[string]Importer) map[string]StageFactory {
+	return map[string]StageFactory{
+		StageImport:    importStageFactory(importers),
+		StageRepair:    repairStage,
+		StageDefaults:  defaultsStage,
+		StageGroup:     groupStage,
+		StageLayout:    layoutStage,
+		StageTheme:     themeStage,
+		StageValidate:  validateStage,
+		StageCost:      costStage,
+		StageOwnership: ownershipStage,
+		StageRollup:    rollupStage,
+		StageLatency:   latencyStage,
+	}
+}
+
+func importStageFactory(importers map[string]Importer) StageFactory {
+	return func(options json.RawMessage) (PipelineStage, error) {
+		var opts struct {
+			Importer string         `json:"importer"`
+			Config   ImporterConfig `json:"config"`
+		}
+		if err := decodeStageOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		importer, ok := importers[opts.Importer]
+		if !ok {
+			return nil, fmt.Errorf("unknown importer %q", opts.Importer)
+		}
+		return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+			var progress ProgressFunc
+			if r := ProgressFromContext(ctx); r != nil {
+				progress = func(p ImportProgress) { r.ReportProgress(p.Progress()) }
+			}
+			result, err := importer.Import(ctx, maps.Clone(opts.Config), progress)
+			if err != nil {
+				return nil, err
+			}
+			if len(result.Errors) > 0 {
+				return result.Warnings, fmt.Errorf("import failed: %s", strings.Join(result.Errors, "; "))
+			}
+			*sf = result.Scene
+			return result.Warnings, nil
+		}), nil
+	}
+}
+
+func repairStage(options json.RawMessage) (PipelineStage, error) {
+	var policy RepairPolicy
+	if err := decodeStageOptions(options, &policy); err != nil {
+		return nil, err
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		report := Repair(sf, policy)
+		notes := make([]string, len(report.Actions))
+		for i, a := range report.Actions {
+			notes[i] = fmt.Sprintf("%s %s: %s", a.Kind, a.ID, a.Detail)
+		}
+		return notes, nil
+	}), nil
+}
+
+// NodeDefaults are values given to the nodes matching Selector, a selector
+// expression, that do not set them. Metadata keys and tags are added when
+// missing.
+type NodeDefaults struct {
+	Selector    string                 `json:"selector,omitempty"`
+	MaterialRef string                 `json:"materialRef,omitempty"`
+	GeometryRef string                 `json:"geometryRef,omitempty"`
+	Status      NodeStatus             `json:"status,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Apply fills in the defaults on matching nodes and returns how many nodes
+// changed
+func (d *NodeDefaults) Apply(sf *SceneFile) (int, error) {
+	sel, err := ParseSelector(d.Selector)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if !sel.MatchNode(n) {
+			continue
+		}
+		touched := false
+		if d.MaterialRef != "" && n.Material == nil && n.MaterialRef == "" {
+			n.MaterialRef, touched = d.MaterialRef, true
+		}
+		if d.GeometryRef != "" && n.Geometry == nil && n.GeometryRef == "" {
+			n.GeometryRef, touched = d.GeometryRef, true
+		}
+		if d.Status != "" && n.Status == "" {
+			n.Status, touched = d.Status, true
+		}
+		for _, tag := range d.Tags {
+			if !slices.Contains(n.Tags, tag) {
+				n.Tags, touched = append(n.Tags, tag), true
+			}
+		}
+		for k, v := range d.Metadata {
+			if _, ok := n.Metadata[k]; !ok {
+				if n.Metadata == nil {
+					n.Metadata = make(map[string]interface{}, len(d.Metadata))
+				}
+				n.Metadata[k], touched = v, true
+			}
+		}
+		if touched {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func defaultsStage(options json.RawMessage) (PipelineStage, error) {
+	var defaults NodeDefaults
+	if err := decodeStageOptions(options, &defaults); err != nil {
+		return nil, err
+	}
+	if _, err := ParseSelector(defaults.Selector); err != nil {
+		return nil, err
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		changed, err := defaults.Apply(sf)
+		if err != nil || changed == 0 {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("applied defaults to %d nodes", changed)}, nil
+	}), nil
+}
+
+func groupStage(options json.RawMessage) (PipelineStage, error) {
+	opts := DefaultSummarizeOptions()
+	if err := decodeStageOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		before := len(sf.Scene.Nodes)
+		*sf = Summarize(sf, opts)
+		if merged := before - len(sf.Scene.Nodes); merged > 0 {
+			return []string{fmt.Sprintf("grouped %d nodes into supernodes", merged)}, nil
+		}
+		return nil, nil
+	}), nil
+}
+
+func layoutStage(options json.RawMessage) (PipelineStage, error) {
+	var kind struct {
+		Layout string `json:"layout"`
+	}
+	if err := json.Unmarshal(options, &kind); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+	var layout Layout
+	var target interface{}
+	switch kind.Layout {
+	case "geo":
+		geo := NewGeoLayout(GeoSphere)
+		layout, target = geo, geo
+	case "racks":
+		racks := NewRackLayout()
+		layout, target = racks, racks
+	default:
+		return nil, fmt.Errorf("unknown layout %q", kind.Layout)
+	}
+	// Decode the layout's own fields over its defaults
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(options, &fields); err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+	delete(fields, "layout")
+	rest, _ := json.Marshal(fields)
+	if err := decodeStageOptions(rest, target); err != nil {
+		return nil, err
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		result, err := ApplyLayout(ctx, layout, sf)
+		if err != nil {
+			return nil, err
+		}
+		notes := slices.Clone(result.Warnings)
+		if len(result.Skipped) > 0 {
+			notes = append(notes, "could not place: "+strings.Join(result.Skipped, ", "))
+		}
+		return notes, nil
+	}), nil
+}
+
+func themeStage(options json.RawMessage) (PipelineStage, error) {
+	var theme Theme
+	if err := decodeStageOptions(options, &theme); err != nil {
+		return nil, err
+	}
+	if errs := theme.Validate(); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		theme.Apply(sf)
+		return nil, nil
+	}), nil
+}
+
+func validateStage(options json.RawMessage) (PipelineStage, error) {
+	var opts struct {
+		Strict bool `json:"strict"`
+	}
+	if err := decodeStageOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		result, err := ValidateSceneContext(ctx, sf)
+		if err != nil {
+			return nil, err
+		}
+		notes := append(slices.Clone(result.Errors), result.Warnings...)
+		switch {
+		case !result.Valid:
+			return notes, fmt.Errorf("scene has %d errors", len(result.Errors))
+		case opts.Strict && len(result.Warnings) > 0:
+			return notes, fmt.Errorf("scene has %d warnings", len(result.Warnings))
+		}
+		return notes, nil
+	}), nil
+}
@@ -0,0 +1,95 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestPipeline tests running configured stages with reports
+func TestPipeline(t *testing.T) {
+	importer := ImporterFunc(func(ctx context.Context, config ImporterConfig, progress ProgressFunc) (ImportResult, error) {
+		sf := newDiffScene()
+		sf.Materials = map[string]Material{"steel": {Color: &Color{R: 0.5, G: 0.5, B: 0.5}}}
+		sf.Scene.Edges = append(sf.Scene.Edges, SceneEdge{ID: "a-x", Source: "a", Target: "x"})
+		return ImportResult{Scene: sf, Warnings: []string{"partial"}}, nil
+	})
+	cfg, err := LoadPipelineConfig(strings.NewReader(`{"stages": [
+		{"type": "import", "options": {"importer": "test"}},
+		{"type": "repair", "options": {"missingEndpoints": "drop"}},
+		{"type": "defaults", "options": {"selector": "type=server", "materialRef": "steel", "metadata": {"owner": "ops"}}},
+		{"type": "theme", "options": {"nodes": [{"metric": "cpu", "property": "opacity", "domain": [0, 1], "output": [0.2, 1]}]}},
+		{"type": "validate"}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadPipelineConfig failed: %v", err)
+	}
+	p, err := NewPipeline(cfg, DefaultStageFactories(map[string]Importer{"test": importer}))
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+
+	var sf SceneFile
+	report, err := p.Run(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Run failed: %v (%+v)", err, report)
+	}
+	if len(report.Stages) != 5 || report.Stages[0].Notes[0] != "partial" || len(report.Stages[1].Notes) != 1 {
+		t.Errorf("report mismatch: got %+v", report)
+	}
+	if len(sf.Scene.Edges) != 1 || sf.Scene.Nodes[0].MaterialRef != "steel" || sf.Scene.Nodes[0].Metadata["owner"] != "ops" {
+		t.Errorf("scene mismatch: got %+v", sf.Scene)
+	}
+	if len(report.Failed()) != 0 {
+		t.Errorf("unexpected failures: %+v", report.Failed())
+	}
+}
+
+// TestPipeline_Errors tests configuration errors and failing stages
+func TestPipeline_Errors(t *testing.T) {
+	factories := DefaultStageFactories(nil)
+	if _, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: "transmogrify"}}}, factories); !errors.Is(err, ErrUnknownStage) {
+		t.Errorf("expected ErrUnknownStage, got %v", err)
+	}
+	if _, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: "validate"}, {Type: "validate"}}}, factories); err == nil {
+		t.Error("expected error for duplicate stage names")
+	}
+	if _, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: "layout", Options: []byte(`{"layout": "geo", "radius": 5, "bogus": 1}`)}}}, factories); err == nil {
+		t.Error("expected error for unknown layout option")
+	}
+	if _, err := LoadPipelineConfig(strings.NewReader(`{"steps": []}`)); err == nil {
+		t.Error("expected error for unknown config field")
+	}
+
+	failing := PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		return nil, errors.New("boom")
+	})
+	factories["fail"] = func(json.RawMessage) (PipelineStage, error) { return failing, nil }
+	cfg := PipelineConfig{Stages: []StageConfig{
+		{Name: "soft", Type: "fail", ContinueOnError: true},
+		{Name: "check", Type: "validate", Options: []byte(`{"strict": true}`)},
+		{Name: "never", Type: "fail"},
+	}}
+	p, err := NewPipeline(cfg, factories)
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Name = ""
+	report, err := p.Run(context.Background(), &sf)
+	var pe *PipelineError
+	if !errors.As(err, &pe) || pe.Stage != "check" {
+		t.Fatalf("expected PipelineError from check, got %v", err)
+	}
+	if len(report.Stages) != 2 || len(report.Failed()) != 2 || report.Stages[0].Error != "boom" {
+		t.Errorf("report mismatch: got %+v", report)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Run(ctx, &sf); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
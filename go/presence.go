@@ -0,0 +1,65 @@
+package starfleet
+
+import (
+	"slices"
+	"time"
+)
+
+// =============================================================================
+// PRESENCE
+// =============================================================================
+
+// Presence announces a viewer of a scene to the others: who they are, where
+// their camera is and what they have selected, for shared cursors and
+// "follow me" sessions where everyone tracks a presenter's camera.
+// Presence is keyed by Session, so one user on two devices shows twice.
+type Presence struct {
+	Session   string   `json:"session" validate:"required"`
+	User      string   `json:"user,omitempty"`
+	Camera    *Camera  `json:"camera,omitempty"`
+	Selection []string `json:"selection,omitempty"`
+	// Following is the session whose camera this viewer tracks
+	Following string `json:"following,omitempty"`
+	// Left is set on the last presence of a viewer that closed the scene or
+	// timed out
+	Left    bool            `json:"left,omitempty"`
+	Privacy PresencePrivacy `json:"privacy,omitempty"`
+	Time    time.Time       `json:"time"`
+}
+
+// PresencePrivacy limits what a viewer shares with the others. Viewers can
+// always see others; these settings only affect what is broadcast about
+// them.
+type PresencePrivacy struct {
+	// Hidden keeps the viewer out of presence entirely
+	Hidden bool `json:"hidden,omitempty"`
+	// Anonymous broadcasts the viewer without their user name
+	Anonymous bool `json:"anonymous,omitempty"`
+	// HideCamera and HideSelection withhold the camera pose and selection
+	HideCamera    bool `json:"hideCamera,omitempty"`
+	HideSelection bool `json:"hideSelection,omitempty"`
+}
+
+// Redacted returns the presence as others may see it, with everything its
+// privacy settings withhold removed. It reports false for hidden viewers,
+// who are not broadcast at all.
+func (p Presence) Redacted() (Presence, bool) {
+	if p.Privacy.Hidden {
+		return Presence{}, false
+	}
+	if p.Privacy.Anonymous {
+		p.User = ""
+	}
+	if p.Privacy.HideCamera || p.Camera == nil {
+		p.Camera = nil
+	} else {
+		camera := *p.Camera
+		p.Camera = &camera
+	}
+	if p.Privacy.HideSelection {
+		p.Selection = nil
+	} else {
+		p.Selection = slices.Clone(p.Selection)
+	}
+	return p, true
+}
@@ -0,0 +1,21 @@
+package starfleet
+
+import "testing"
+
+// TestPresence_Redacted tests applying privacy settings before broadcast
+func TestPresence_Redacted(t *testing.T) {
+	p := Presence{Session: "s1", User: "ada", Camera: &Camera{FOV: 60}, Selection: []string{"api"}}
+	got, ok := p.Redacted()
+	if !ok || got.User != "ada" || got.Camera == p.Camera || got.Camera.FOV != 60 || got.Selection[0] != "api" {
+		t.Errorf("redacted mismatch: got %+v, %v", got, ok)
+	}
+
+	p.Privacy = PresencePrivacy{Anonymous: true, HideCamera: true, HideSelection: true}
+	if got, ok := p.Redacted(); !ok || got.User != "" || got.Camera != nil || got.Selection != nil || got.Session != "s1" {
+		t.Errorf("redacted mismatch: got %+v, %v", got, ok)
+	}
+	p.Privacy = PresencePrivacy{Hidden: true}
+	if _, ok := p.Redacted(); ok {
+		t.Error("expected hidden presence not to be broadcast")
+	}
+}
@@ -0,0 +1,109 @@
+package starfleet
+
+import "context"
+
+// =============================================================================
+// PROGRESS REPORTING
+// =============================================================================
+
+// Progress is a snapshot of a long-running operation. Operations that
+// cannot tell how much work remains leave Total zero and Percent unset.
+type Progress struct {
+	Stage     string  `json:"stage,omitempty"`
+	Percent   float64 `json:"percent"`
+	Processed int     `json:"processed"`
+	Total     int     `json:"total,omitempty"`
+	Message   string  `json:"message,omitempty"`
+}
+
+// ProgressReporter receives progress from imports, rendering, layout and
+// pipelines. Reports arrive on the goroutine doing the work, so reporters
+// should return quickly.
+type ProgressReporter interface {
+	ReportProgress(Progress)
+}
+
+// ProgressReporterFunc adapts a function to the ProgressReporter interface
+type ProgressReporterFunc func(Progress)
+
+// ReportProgress calls f
+func (f ProgressReporterFunc) ReportProgress(p Progress) {
+	f(p)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose operations report progress to r
+func WithProgress(ctx context.Context, r ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, r)
+}
+
+// ProgressFromContext returns the reporter set by WithProgress, or nil
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+	r, _ := ctx.Value(progressKey{}).(ProgressReporter)
+	return r
+}
+
+// ReportProgress reports that processed of total items of a stage are done
+// to the context's reporter, if any. Custom importers and pipeline stages
+// use it to show up in the same progress bars as the built-in ones.
+func ReportProgress(ctx context.Context, stage string, processed, total int) {
+	if r := ProgressFromContext(ctx); r != nil {
+		r.ReportProgress(newProgress(stage, processed, total))
+	}
+}
+
+// newProgress fills in the percentage of a progress report
+func newProgress(stage string, processed, total int) Progress {
+	p := Progress{Stage: stage, Processed: processed, Total: total}
+	if total > 0 {
+		p.Percent = 100 * float64(min(processed, total)) / float64(total)
+	}
+	return p
+}
+
+// Progress converts an import progress report, counting built elements as
+// processed
+func (p ImportProgress) Progress() Progress {
+	progress := newProgress(p.Stage, p.Built, p.Total)
+	progress.Message = p.Message
+	return progress
+}
+
+// progressTracker reports the progress of a loop over a known number of
+// items about once per percent and when it completes, keeping reports from
+// hot loops cheap. Trackers of contexts without a reporter do nothing.
+type progressTracker struct {
+	r         ProgressReporter
+	stage     string
+	processed int
+	total     int
+	every     int
+}
+
+// newProgressTracker starts tracking a stage and reports it at zero
+func newProgressTracker(ctx context.Context, stage string, total int) *progressTracker {
+	t := &progressTracker{r: ProgressFromContext(ctx), stage: stage, total: total, every: max(1, total/100)}
+	if t.r != nil {
+		t.r.ReportProgress(newProgress(stage, 0, total))
+	}
+	return t
+}
+
+// step records one processed item
+func (t *progressTracker) step() {
+	if t.r == nil {
+		return
+	}
+	t.processed++
+	if t.processed%t.every == 0 && t.processed < t.total {
+		t.r.ReportProgress(newProgress(t.stage, t.processed, t.total))
+	}
+}
+
+// done reports the stage complete, including when it finished early
+func (t *progressTracker) done() {
+	if t.r != nil {
+		t.r.ReportProgress(newProgress(t.stage, t.total, t.total))
+	}
+}
@@ -0,0 +1,96 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// recordProgress returns a context reporting into the returned slice
+func recordProgress() (context.Context, *[]Progress) {
+	var reports []Progress
+	ctx := WithProgress(context.Background(), ProgressReporterFunc(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	return ctx, &reports
+}
+
+// TestReportProgress tests percentages and reporting without a reporter
+func TestReportProgress(t *testing.T) {
+	// Without a reporter nothing happens
+	ReportProgress(context.Background(), "import", 1, 2)
+
+	ctx, reports := recordProgress()
+	ReportProgress(ctx, "import", 1, 4)
+	ReportProgress(ctx, "import", 3, 0)
+	want := []Progress{
+		{Stage: "import", Percent: 25, Processed: 1, Total: 4},
+		{Stage: "import", Processed: 3},
+	}
+	if len(*reports) != len(want) || (*reports)[0] != want[0] || (*reports)[1] != want[1] {
+		t.Errorf("reports mismatch: got %+v, want %+v", *reports, want)
+	}
+
+	p := ImportProgress{Stage: "build", Discovered: 10, Built: 5, Total: 10, Message: "vpcs"}.Progress()
+	if want := (Progress{Stage: "build", Percent: 50, Processed: 5, Total: 10, Message: "vpcs"}); p != want {
+		t.Errorf("import progress mismatch: got %+v, want %+v", p, want)
+	}
+}
+
+// TestRenderImage_Progress tests that rendering reports its way to 100%
+func TestRenderImage_Progress(t *testing.T) {
+	sf := newLargeScene(300)
+	ctx, reports := recordProgress()
+	if _, err := RenderImageContext(ctx, &sf, 8, 8); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*reports) < 3 {
+		t.Fatalf("reports mismatch: got %d, want at least 3", len(*reports))
+	}
+	last := 0.0
+	for _, p := range *reports {
+		if p.Stage != "render" || p.Percent < last {
+			t.Errorf("report mismatch: got %+v after %v%%", p, last)
+		}
+		last = p.Percent
+	}
+	if last != 100 {
+		t.Errorf("final percent mismatch: got %v, want 100", last)
+	}
+}
+
+// TestPipeline_Progress tests that pipelines scale the progress of their
+// stages into the whole run
+func TestPipeline_Progress(t *testing.T) {
+	half := PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		ReportProgress(ctx, "work", 1, 2)
+		return nil, nil
+	})
+	factories := map[string]StageFactory{
+		"half": func(json.RawMessage) (PipelineStage, error) { return half, nil },
+	}
+	p, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Name: "one", Type: "half"}, {Name: "two", Type: "half"}}}, factories)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, reports := recordProgress()
+	sf := newDiffScene()
+	if _, err := p.Run(ctx, &sf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Progress{
+		{Stage: "one", Percent: 0, Processed: 0, Total: 2},
+		{Stage: "one", Percent: 25, Processed: 1, Total: 2, Message: "work"},
+		{Stage: "two", Percent: 50, Processed: 1, Total: 2},
+		{Stage: "two", Percent: 75, Processed: 1, Total: 2, Message: "work"},
+		{Stage: "two", Percent: 100, Processed: 2, Total: 2},
+	}
+	if len(*reports) != len(want) {
+		t.Fatalf("reports mismatch: got %+v, want %+v", *reports, want)
+	}
+	for i := range want {
+		if (*reports)[i] != want[i] {
+			t.Errorf("report %d mismatch: got %+v, want %+v", i, (*reports)[i], want[i])
+		}
+	}
+}
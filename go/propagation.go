@@ -0,0 +1,131 @@
+package starfleet
+
+import "slices"
+
+// =============================================================================
+// STATUS PROPAGATION
+// =============================================================================
+
+// StatusImpact describes a node whose status was raised by propagation.
+// Causes lists the dependencies responsible: node IDs of unhealthy
+// dependencies, or edge IDs of broken connections.
+type StatusImpact struct {
+	Status NodeStatus `json:"status" validate:"required"`
+	Causes []string   `json:"causes,omitempty"`
+}
+
+// PropagateStatus raises node statuses to reflect their dependencies and
+// returns the nodes it changed. A directed edge makes its source depend on
+// its target, a bidirectional edge makes both ends depend on each other, and
+// undirected edges carry no dependency. Dependencies are grouped by node type
+// as redundant replicas: a node goes critical when every replica of some
+// dependency is critical or reached only over a broken edge, and warning
+// when only some are, or when a dependency is itself in warning. Statuses
+// are never lowered.
+func PropagateStatus(sf *SceneFile, brokenEdges ...string) map[string]StatusImpact {
+	broken := make(map[string]bool, len(brokenEdges))
+	for _, id := range brokenEdges {
+		broken[id] = true
+	}
+	index := make(map[string]int, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		index[sf.Scene.Nodes[i].ID] = i
+	}
+
+	// deps[node][type] lists the edges to that node's dependencies of a type
+	deps := make(map[string]map[string][]*SceneEdge)
+	addDep := func(from, to string, e *SceneEdge) {
+		j, ok := index[to]
+		if _, known := index[from]; !ok || !known {
+			return
+		}
+		if deps[from] == nil {
+			deps[from] = make(map[string][]*SceneEdge)
+		}
+		typ := sf.Scene.Nodes[j].Type
+		deps[from][typ] = append(deps[from][typ], e)
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		switch {
+		case e.IsDirected():
+			addDep(e.Source, e.Target, e)
+		case e.Direction == EdgeBidirectional:
+			addDep(e.Source, e.Target, e)
+			addDep(e.Target, e.Source, e)
+		}
+	}
+
+	impacts := make(map[string]StatusImpact)
+	// Statuses only rise, so this reaches a fixed point
+	for changed := true; changed; {
+		changed = false
+		for i := range sf.Scene.Nodes {
+			n := &sf.Scene.Nodes[i]
+			status, causes := dependencyStatus(sf, n.ID, deps[n.ID], index, broken)
+			if statusSeverity(status) <= statusSeverity(n.Status) {
+				continue
+			}
+			n.Status = status
+			impacts[n.ID] = StatusImpact{Status: status, Causes: causes}
+			changed = true
+		}
+	}
+	return impacts
+}
+
+// dependencyStatus derives the status a node's dependencies imply for it
+func dependencyStatus(sf *SceneFile, id string, groups map[string][]*SceneEdge, index map[string]int, broken map[string]bool) (NodeStatus, []string) {
+	var status NodeStatus
+	var causes []string
+	for _, edges := range groups {
+		down := 0
+		var groupCauses []string
+		degraded := false
+		for _, e := range edges {
+			dep := e.Target
+			if dep == id {
+				dep = e.Source
+			}
+			switch {
+			case broken[e.ID]:
+				down++
+				groupCauses = append(groupCauses, e.ID)
+			case sf.Scene.Nodes[index[dep]].Status == NodeStatusCritical:
+				down++
+				groupCauses = append(groupCauses, dep)
+			case sf.Scene.Nodes[index[dep]].Status == NodeStatusWarning:
+				degraded = true
+				groupCauses = append(groupCauses, dep)
+			}
+		}
+		var s NodeStatus
+		switch {
+		case down == len(edges):
+			s = NodeStatusCritical
+		case down > 0 || degraded:
+			s = NodeStatusWarning
+		default:
+			continue
+		}
+		if statusSeverity(s) > statusSeverity(status) {
+			status, causes = s, groupCauses
+		} else if s == status {
+			causes = append(causes, groupCauses...)
+		}
+	}
+	slices.Sort(causes)
+	return status, slices.Compact(causes)
+}
+
+// statusSeverity orders statuses for propagation; unknown counts as healthy
+func statusSeverity(s NodeStatus) int {
+	switch s {
+	case NodeStatusCritical:
+		return 2
+	case NodeStatusWarning:
+		return 1
+	default:
+		return 0
+	}
+}
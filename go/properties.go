@@ -0,0 +1,300 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// PROPERTY PATHS
+// =============================================================================
+
+var (
+	ErrPropertyNotFound = errors.New("property not found")
+	ErrPropertyType     = errors.New("property type mismatch")
+	ErrInvalidPath      = errors.New("invalid property path")
+)
+
+// Property paths address values inside scene elements by their JSON names,
+// e.g. "material.color.r", "metrics.cpu_usage" or "ports[0].position.x".
+// Segments are separated by dots; list elements are indexed with [i] or a
+// numeric segment. Map keys containing dots or brackets cannot be addressed.
+
+// parsePropertyPath splits a property path into its segments
+func parsePropertyPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+	var keys []string
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && (rest == "" || len(keys) == 0) {
+			return nil, fmt.Errorf("%w: %q has an empty segment", ErrInvalidPath, path)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if _, err := strconv.Atoi(index); !ok || err != nil {
+				return nil, fmt.Errorf("%w: %q has a malformed index", ErrInvalidPath, path)
+			}
+			keys = append(keys, index)
+			if after == "" {
+				break
+			}
+			if after[0] != '[' {
+				return nil, fmt.Errorf("%w: %q has a malformed index", ErrInvalidPath, path)
+			}
+			rest = after[1:]
+		}
+	}
+	return keys, nil
+}
+
+// GetProperty returns the value at a property path in v, which is typically
+// a *SceneNode or *SceneEdge. Pointer fields are returned as pointers.
+func GetProperty(v interface{}, path string) (interface{}, error) {
+	keys, err := parsePropertyPath(path)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(v)
+	for i, key := range keys {
+		rv = indirectValue(rv)
+		if !rv.IsValid() {
+			return nil, fmt.Errorf("%w: %s", ErrPropertyNotFound, strings.Join(keys[:i], "."))
+		}
+		next, ok := childValue(rv, key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPropertyNotFound, path)
+		}
+		rv = next
+	}
+	if !rv.IsValid() || (rv.Kind() == reflect.Interface && rv.IsNil()) {
+		return nil, nil
+	}
+	return rv.Interface(), nil
+}
+
+// GetFloat64Property returns the numeric value at a property path in v
+func GetFloat64Property(v interface{}, path string) (float64, error) {
+	value, err := GetProperty(v, path)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := toFloat64(value)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is %T, not a number", ErrPropertyType, path, value)
+	}
+	return f, nil
+}
+
+// SetProperty sets the value at a property path in v, which must be a
+// pointer. Nil pointers and maps along the path are allocated and missing
+// map keys are added, but list indexes must exist. Numbers convert to any
+// numeric field they fit exactly, strings to string-typed fields such as
+// NodeStatus, and JSON-decoded objects and arrays to struct and slice
+// fields. v is left unchanged when the path or value does not fit.
+func SetProperty(v interface{}, path string, value interface{}) error {
+	keys, err := parsePropertyPath(path)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: SetProperty needs a non-nil pointer, got %T", ErrPropertyType, v)
+	}
+	return setValue(rv.Elem(), keys, path, value)
+}
+
+// indirectValue follows pointers and interfaces, returning the zero Value
+// for nil ones
+func indirectValue(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// childValue returns the field, map value or list element named by key
+func childValue(rv reflect.Value, key string) (reflect.Value, bool) {
+	switch rv.Kind() {
+	case reflect.Struct:
+		if i, ok := fieldIndex(rv.Type(), key); ok {
+			return rv.Field(i), true
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		value := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		return value, value.IsValid()
+	case reflect.Slice, reflect.Array:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < rv.Len() {
+			return rv.Index(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// fieldIndex returns the index of the exported struct field with a JSON
+// name, matching case-insensitively like encoding/json when no field
+// matches exactly
+func fieldIndex(t reflect.Type, name string) (int, bool) {
+	folded := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return i, true
+		}
+		if folded < 0 && strings.EqualFold(tag, name) {
+			folded = i
+		}
+	}
+	return folded, folded >= 0
+}
+
+// setValue sets the value at keys below the settable rv. Containers are
+// modified through copies that are stored back only on success.
+func setValue(rv reflect.Value, keys []string, path string, value interface{}) error {
+	if len(keys) == 0 {
+		return assignValue(rv, path, value)
+	}
+	switch rv.Kind() {
+	case reflect.Pointer:
+		target := reflect.New(rv.Type().Elem())
+		if !rv.IsNil() {
+			target.Elem().Set(rv.Elem())
+		}
+		if err := setValue(target.Elem(), keys, path, value); err != nil {
+			return err
+		}
+		if rv.IsNil() {
+			rv.Set(target)
+		} else {
+			rv.Elem().Set(target.Elem())
+		}
+		return nil
+	case reflect.Interface:
+		var target reflect.Value
+		if rv.IsNil() {
+			target = reflect.ValueOf(map[string]interface{}{})
+		} else {
+			target = reflect.New(rv.Elem().Type()).Elem()
+			target.Set(rv.Elem())
+		}
+		if err := setValue(target, keys, path, value); err != nil {
+			return err
+		}
+		rv.Set(target)
+		return nil
+	case reflect.Struct:
+		i, ok := fieldIndex(rv.Type(), keys[0])
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrPropertyNotFound, path)
+		}
+		return setValue(rv.Field(i), keys[1:], path, value)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: %s", ErrPropertyNotFound, path)
+		}
+		key := reflect.ValueOf(keys[0]).Convert(rv.Type().Key())
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if existing := rv.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := setValue(elem, keys[1:], path, value); err != nil {
+			return err
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		rv.SetMapIndex(key, elem)
+		return nil
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(keys[0])
+		if err != nil || i < 0 || i >= rv.Len() {
+			return fmt.Errorf("%w: %s", ErrPropertyNotFound, path)
+		}
+		return setValue(rv.Index(i), keys[1:], path, value)
+	}
+	return fmt.Errorf("%w: %s", ErrPropertyNotFound, path)
+}
+
+// assignValue stores value in rv, converting it when it fits the type
+func assignValue(rv reflect.Value, path string, value interface{}) error {
+	t := rv.Type()
+	if value == nil {
+		rv.Set(reflect.Zero(t))
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		rv.Set(v)
+		return nil
+	}
+	mismatch := fmt.Errorf("%w: %s is %s, not %T", ErrPropertyType, path, t, value)
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat64(value)
+		if !ok || rv.OverflowFloat(f) {
+			return mismatch
+		}
+		rv.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := toFloat64(value)
+		if !ok || f != math.Trunc(f) || rv.OverflowInt(int64(f)) {
+			return mismatch
+		}
+		rv.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := toFloat64(value)
+		if !ok || f < 0 || f != math.Trunc(f) || rv.OverflowUint(uint64(f)) {
+			return mismatch
+		}
+		rv.SetUint(uint64(f))
+	case reflect.String, reflect.Bool:
+		if v.Kind() != t.Kind() {
+			return mismatch
+		}
+		rv.Set(v.Convert(t))
+	case reflect.Struct, reflect.Slice, reflect.Map, reflect.Pointer, reflect.Array:
+		// Values decoded from JSON, as in patches, go through JSON again
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			return mismatch
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return mismatch
+		}
+		target := reflect.New(t)
+		if err := json.Unmarshal(data, target.Interface()); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrPropertyType, path, err)
+		}
+		rv.Set(target.Elem())
+	default:
+		return mismatch
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package starfleet
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestGetProperty tests resolving property paths
+func TestGetProperty(t *testing.T) {
+	node := &SceneNode{
+		ID:        "web",
+		Transform: NewTransform(),
+		Material:  &Material{Color: &Color{R: 0.5, G: 0.25, B: 1}},
+		Metrics:   map[string]interface{}{"cpu_usage": 42.5, "disks": []interface{}{map[string]interface{}{"free": 10}}},
+		Ports:     []Port{{ID: "p1", Position: &Vector3{X: 2}}},
+	}
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"id", "web"},
+		{"material.color.r", 0.5},
+		{"metrics.cpu_usage", 42.5},
+		{"metrics.disks[0].free", 10},
+		{"ports[0].position.x", 2.0},
+		{"ports.0.id", "p1"},
+		{"transform.scale.x", 1.0},
+		{"Material.Color.G", 0.25},
+	}
+	for _, tt := range tests {
+		got, err := GetProperty(node, tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: mismatch: got %v (%v), want %v", tt.path, got, err, tt.want)
+		}
+	}
+	if got, err := GetFloat64Property(node, "metrics.cpu_usage"); err != nil || got != 42.5 {
+		t.Errorf("GetFloat64Property mismatch: got %v, %v", got, err)
+	}
+	if _, err := GetFloat64Property(node, "id"); !errors.Is(err, ErrPropertyType) {
+		t.Errorf("expected ErrPropertyType, got %v", err)
+	}
+	for _, path := range []string{"label.text", "metrics.missing", "ports[3]", "nope", "id.x"} {
+		if _, err := GetProperty(node, path); !errors.Is(err, ErrPropertyNotFound) {
+			t.Errorf("%s: expected ErrPropertyNotFound, got %v", path, err)
+		}
+	}
+	for _, path := range []string{"", "a..b", "ports[x]", "ports[0", "[0]"} {
+		if _, err := GetProperty(node, path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%q: expected ErrInvalidPath, got %v", path, err)
+		}
+	}
+}
+
+// TestSetProperty tests setting values at property paths
+func TestSetProperty(t *testing.T) {
+	node := &SceneNode{ID: "web", Transform: NewTransform(), Ports: []Port{{ID: "p1"}}}
+	sets := []struct {
+		path  string
+		value interface{}
+	}{
+		{"material.color.r", 1},
+		{"transform.rotation.y", 1.5},
+		{"metrics.cpu_usage", 80},
+		{"metadata.owner.team", "core"},
+		{"status", "critical"},
+		{"ports[0].position", map[string]interface{}{"x": 1.0, "y": 2.0, "z": 3.0}},
+		{"tags", []interface{}{"edge", "prod"}},
+	}
+	for _, s := range sets {
+		if err := SetProperty(node, s.path, s.value); err != nil {
+			t.Fatalf("%s: %v", s.path, err)
+		}
+	}
+	if node.Material == nil || node.Material.Color.R != 1 || node.Transform.Rotation.Y != 1.5 {
+		t.Errorf("struct fields mismatch: got %+v %+v", node.Material, node.Transform)
+	}
+	if node.Metrics["cpu_usage"] != 80 || node.Status != NodeStatusCritical || len(node.Tags) != 2 {
+		t.Errorf("fields mismatch: got %+v", node)
+	}
+	if owner, _ := node.Metadata["owner"].(map[string]interface{}); owner["team"] != "core" {
+		t.Errorf("nested metadata mismatch: got %v", node.Metadata)
+	}
+	if *node.Ports[0].Position != (Vector3{X: 1, Y: 2, Z: 3}) {
+		t.Errorf("port position mismatch: got %+v", node.Ports[0].Position)
+	}
+
+	fails := []struct {
+		path  string
+		value interface{}
+		err   error
+	}{
+		{"transform.position.x", "high", ErrPropertyType},
+		{"visible", 1, ErrPropertyType},
+		{"geometry.nope", 1.5, ErrPropertyNotFound},
+		{"ports[4].id", "p5", ErrPropertyNotFound},
+		{"label.nope", "x", ErrPropertyNotFound},
+	}
+	for _, f := range fails {
+		if err := SetProperty(node, f.path, f.value); !errors.Is(err, f.err) {
+			t.Errorf("%s: expected %v, got %v", f.path, f.err, err)
+		}
+	}
+	if node.Label != nil || node.Geometry != nil {
+		t.Errorf("failed sets must not allocate: got %+v %+v", node.Label, node.Geometry)
+	}
+	query := &MetricsQuery{}
+	if err := SetProperty(query, "resolution", 60.0); err != nil || query.Resolution != 60 {
+		t.Errorf("int field mismatch: got %d, %v", query.Resolution, err)
+	}
+	if err := SetProperty(query, "resolution", 1.5); !errors.Is(err, ErrPropertyType) {
+		t.Errorf("expected ErrPropertyType for a fraction, got %v", err)
+	}
+	if err := SetProperty(*node, "id", "x"); !errors.Is(err, ErrPropertyType) {
+		t.Errorf("expected ErrPropertyType for a non-pointer, got %v", err)
+	}
+}
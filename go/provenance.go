@@ -0,0 +1,146 @@
+package starfleet
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// IMPORT PROVENANCE
+// =============================================================================
+
+// ImportResourceExtension is the extension key under which importers record
+// the external resource an element was built from, such as an ARN or a
+// Kubernetes UID. Provenance falls back to the element ID without it.
+const ImportResourceExtension = "importResource"
+
+// Element kinds of a ProvenanceChange
+const (
+	ElementNode = "node"
+	ElementEdge = "edge"
+)
+
+// ProvenanceChange explains one change made by applying an import, such as
+// a node added because a resource appeared or an edge removed because a
+// dependency disappeared
+type ProvenanceChange struct {
+	Kind     ChangeKind `json:"kind" validate:"required"`
+	Element  string     `json:"element" validate:"required,oneof=node edge"`
+	ID       string     `json:"id" validate:"required"`
+	Source   string     `json:"source,omitempty"`
+	Resource string     `json:"resource,omitempty"`
+	Fields   []string   `json:"fields,omitempty"`
+	Reason   string     `json:"reason"`
+}
+
+// Provenance explains the changes from before to after made by importing
+// from source, as returned by Reconcile. Nodes come first, in diff order.
+func Provenance(before, after *SceneFile, d SceneDiff, source string) []ProvenanceChange {
+	changes := make([]ProvenanceChange, 0, len(d.Nodes)+len(d.Edges))
+	removedNodes := make(map[string]bool)
+	for _, c := range d.Nodes {
+		node := after.FindNode(c.ID)
+		if c.Kind == ChangeRemoved {
+			node = before.FindNode(c.ID)
+			removedNodes[c.ID] = true
+		}
+		resource := resourceOf(node.Extensions, node.ID)
+		var reason string
+		switch c.Kind {
+		case ChangeAdded:
+			reason = fmt.Sprintf("resource %s appeared", resource)
+		case ChangeRemoved:
+			reason = fmt.Sprintf("resource %s disappeared", resource)
+		default:
+			reason = fmt.Sprintf("resource %s changed %s", resource, strings.Join(c.Fields, ", "))
+		}
+		changes = append(changes, ProvenanceChange{
+			Kind: c.Kind, Element: ElementNode, ID: c.ID, Source: source,
+			Resource: resource, Fields: c.Fields, Reason: reason,
+		})
+	}
+	for _, c := range d.Edges {
+		edge := after.FindEdge(c.ID)
+		if c.Kind == ChangeRemoved {
+			edge = before.FindEdge(c.ID)
+		}
+		resource := resourceOf(edge.Extensions, edge.ID)
+		dependency := edge.Source + " -> " + edge.Target
+		var reason string
+		switch {
+		case c.Kind == ChangeAdded:
+			reason = fmt.Sprintf("dependency %s appeared", dependency)
+		case c.Kind == ChangeRemoved && removedNodes[edge.Source]:
+			reason = fmt.Sprintf("endpoint %s disappeared", edge.Source)
+		case c.Kind == ChangeRemoved && removedNodes[edge.Target]:
+			reason = fmt.Sprintf("endpoint %s disappeared", edge.Target)
+		case c.Kind == ChangeRemoved:
+			reason = fmt.Sprintf("dependency %s disappeared", dependency)
+		default:
+			reason = fmt.Sprintf("dependency %s changed %s", dependency, strings.Join(c.Fields, ", "))
+		}
+		changes = append(changes, ProvenanceChange{
+			Kind: c.Kind, Element: ElementEdge, ID: c.ID, Source: source,
+			Resource: resource, Fields: c.Fields, Reason: reason,
+		})
+	}
+	return changes
+}
+
+// resourceOf returns the external resource recorded on an element
+func resourceOf(ext map[string]interface{}, id string) string {
+	if resource, ok := ext[ImportResourceExtension].(string); ok && resource != "" {
+		return resource
+	}
+	return id
+}
+
+// =============================================================================
+// AUDIT LOG
+// =============================================================================
+
+// AuditEntry records a change to a scene and who made it
+type AuditEntry struct {
+	Time     time.Time          `json:"time"`
+	Actor    string             `json:"actor,omitempty"`
+	SceneID  string             `json:"sceneId" validate:"required"`
+	Revision int64              `json:"revision,omitempty"`
+	Action   SceneEventType     `json:"action" validate:"required"`
+	Changes  []ProvenanceChange `json:"changes,omitempty"`
+}
+
+// AuditLog is an append-only record of scene changes
+type AuditLog interface {
+	Append(ctx context.Context, entry AuditEntry) error
+}
+
+// MemoryAuditLog is an in-memory AuditLog
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// Append records an entry
+func (l *MemoryAuditLog) Append(ctx context.Context, entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns the entries of a scene, oldest first, or all entries when
+// sceneID is empty
+func (l *MemoryAuditLog) Entries(sceneID string) []AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []AuditEntry
+	for _, e := range l.entries {
+		if sceneID == "" || e.SceneID == sceneID {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
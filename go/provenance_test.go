@@ -0,0 +1,81 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestProvenance tests the reasons given for reconciled changes
+func TestProvenance(t *testing.T) {
+	current := newDiffScene()
+	imported := NewSceneFile("Import")
+	imported.AddNode(SceneNode{
+		ID: "d", Type: "db", Name: "D", Transform: NewTransform(),
+		Extensions: map[string]interface{}{ImportResourceExtension: "arn:aws:rds:db/d"},
+	})
+	imported.AddEdge(SceneEdge{ID: "a-d", Source: "a", Target: "d"})
+	first, changes := Reconcile(&current, &imported, ReconcileOptions{Source: "aws"})
+	got := Provenance(&current, &first, changes, "aws")
+	if len(got) != 2 || got[0].Reason != "resource arn:aws:rds:db/d appeared" || got[0].Resource != "arn:aws:rds:db/d" ||
+		got[1].Element != ElementEdge || got[1].Reason != "dependency a -> d appeared" || got[1].Source != "aws" {
+		t.Errorf("added provenance mismatch: got %+v", got)
+	}
+
+	imported.Scene.Nodes[0].Name = "Orders"
+	imported.Scene.Edges = nil
+	second, changes := Reconcile(&first, &imported, ReconcileOptions{Source: "aws"})
+	got = Provenance(&first, &second, changes, "aws")
+	if len(got) != 2 || got[0].Reason != "resource arn:aws:rds:db/d changed name" || got[1].Reason != "dependency a -> d disappeared" {
+		t.Errorf("changed provenance mismatch: got %+v", got)
+	}
+
+	imported.Scene.Nodes = nil
+	third, changes := Reconcile(&first, &imported, ReconcileOptions{Source: "aws"})
+	got = Provenance(&first, &third, changes, "aws")
+	if len(got) != 2 || got[0].Kind != ChangeRemoved || got[1].Reason != "endpoint d disappeared" {
+		t.Errorf("removed provenance mismatch: got %+v", got)
+	}
+}
+
+// TestImportScheduler_Provenance tests that imported changes reach webhooks
+// and the audit log
+func TestImportScheduler_Provenance(t *testing.T) {
+	ctx := context.Background()
+	payloads := make(chan WebhookPayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads <- payload
+	}))
+	defer srv.Close()
+
+	queue := NewImportQueue(1)
+	defer queue.Close(ctx)
+	scheduler := NewImportScheduler(NewMemorySceneStore(), queue)
+	scheduler.Webhooks = NewWebhookDispatcher(WebhookEndpoint{URL: srv.URL, Events: []SceneEventType{SceneEventImported}})
+	defer scheduler.Webhooks.Close(ctx)
+	audit := &MemoryAuditLog{}
+	scheduler.Audit = audit
+	importer := ImporterFunc(func(context.Context, ImporterConfig, ProgressFunc) (ImportResult, error) {
+		sf := NewSceneFile("Cloud")
+		sf.AddNode(SceneNode{ID: "vm", Type: "vm", Name: "VM", Transform: NewTransform()})
+		return ImportResult{Scene: sf}, nil
+	})
+	scheduler.Add(ImportSchedule{Name: "aws", Scene: "cloud", Importer: importer, Schedule: IntervalSchedule(0)})
+
+	run, err := scheduler.RunNow(ctx, "aws")
+	if err != nil || len(run.Provenance) != 1 || run.Provenance[0].Reason != "resource vm appeared" {
+		t.Fatalf("run mismatch: got %+v, %v", run, err)
+	}
+	entries := audit.Entries("cloud")
+	if len(entries) != 1 || entries[0].Actor != "import:aws" || entries[0].Revision != 1 || len(entries[0].Changes) != 1 {
+		t.Errorf("audit mismatch: got %+v", entries)
+	}
+	payload := <-payloads
+	if payload.Event != SceneEventImported || payload.SceneID != "cloud" || payload.Diff.NodesAdded != 1 || len(payload.Changes) != 1 {
+		t.Errorf("webhook payload mismatch: got %+v", payload)
+	}
+}
@@ -0,0 +1,30 @@
+package starfleet
+
+import "context"
+
+// =============================================================================
+// PROVIDERS
+// =============================================================================
+
+// MetricsSource answers metrics queries. It is the part of Provider that
+// consumers such as the scene server need.
+type MetricsSource interface {
+	Query(ctx context.Context, query MetricsQuery) ([]MetricsResult, error)
+}
+
+// Provider supplies live data for scene nodes. It mirrors the Provider
+// interface of the TypeScript SDK.
+type Provider interface {
+	MetricsSource
+	Connect(ctx context.Context, config ProviderConfig) error
+	Disconnect(ctx context.Context) error
+	IsConnected() bool
+}
+
+// MetricsSourceFunc adapts a function to the MetricsSource interface
+type MetricsSourceFunc func(ctx context.Context, query MetricsQuery) ([]MetricsResult, error)
+
+// Query calls f
+func (f MetricsSourceFunc) Query(ctx context.Context, query MetricsQuery) ([]MetricsResult, error) {
+	return f(ctx, query)
+}
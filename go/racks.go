@@ -0,0 +1,180 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// =============================================================================
+// RACK LAYOUT
+// =============================================================================
+
+// Physical rack dimensions in meters
+const (
+	// RackUnitHeight is the height of one rack unit (1U, 1.75 in)
+	RackUnitHeight = 0.04445
+	// RackMountWidth is the width of 19 in rack-mounted equipment
+	RackMountWidth = 0.4826
+)
+
+// Node types of the group nodes created by RackLayout
+const (
+	RackRowNodeType = "row"
+	RackNodeType    = "rack"
+)
+
+// RackLayout arranges equipment into datacenter racks and rows from node
+// metadata. Each node names its row, its rack within the row, and its lowest
+// occupied rack unit counting from 1 at the bottom; an optional size gives
+// its height in units. Racks are placed side by side along X and rows are
+// separated by aisles along Z. A row node and a rack node are created (or
+// reused) per row and rack, and equipment is parented to its rack.
+type RackLayout struct {
+	RowKey  string
+	RackKey string
+	UnitKey string
+	SizeKey string
+
+	// RackUnits is the usable height of each rack in units
+	RackUnits int
+	// RackWidth and RackDepth are the outer rack dimensions
+	RackWidth float64
+	RackDepth float64
+	// RackSpacing is the gap between neighboring racks in a row
+	RackSpacing float64
+	// AisleWidth is the gap between rows
+	AisleWidth float64
+	// AlternateRows turns every other row around so fronts face each other
+	// across cold aisles
+	AlternateRows bool
+}
+
+// NewRackLayout creates a rack layout for standard 42U racks (600 mm wide,
+// 1070 mm deep) with 1.2 m aisles, reading the "row", "rack", "unit" and
+// "units" metadata keys
+func NewRackLayout() *RackLayout {
+	return &RackLayout{
+		RowKey:        "row",
+		RackKey:       "rack",
+		UnitKey:       "unit",
+		SizeKey:       "units",
+		RackUnits:     42,
+		RackWidth:     0.6,
+		RackDepth:     1.07,
+		AisleWidth:    1.2,
+		AlternateRows: true,
+	}
+}
+
+// rackDevice is a node waiting to be mounted
+type rackDevice struct {
+	index      int
+	unit, size int
+}
+
+// Apply implements Layout
+func (l *RackLayout) Apply(sf *SceneFile) (LayoutResult, error) {
+	result := LayoutResult{}
+	if l.RackUnits <= 0 || l.RackWidth <= 0 || l.RackDepth <= 0 {
+		return result, fmt.Errorf("rack layout: rack dimensions must be positive")
+	}
+
+	racks := make(map[string]map[string][]rackDevice)
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		if node.Type == RackRowNodeType || node.Type == RackNodeType {
+			continue
+		}
+		row, okRow := toString(node.Metadata[l.RowKey])
+		rack, okRack := toString(node.Metadata[l.RackKey])
+		if !okRow || !okRack {
+			continue
+		}
+		unit, okUnit := toFloat64(node.Metadata[l.UnitKey])
+		size := 1.0
+		if v, ok := toFloat64(node.Metadata[l.SizeKey]); ok {
+			size = v
+		}
+		if !okUnit || unit < 1 || size < 1 || unit+size-1 > float64(l.RackUnits) ||
+			unit != math.Trunc(unit) || size != math.Trunc(size) {
+			result.Skipped = append(result.Skipped, node.ID)
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"node %s: invalid rack position %v/%v in a %dU rack",
+				node.ID, node.Metadata[l.UnitKey], node.Metadata[l.SizeKey], l.RackUnits))
+			continue
+		}
+		if racks[row] == nil {
+			racks[row] = make(map[string][]rackDevice)
+		}
+		racks[row][rack] = append(racks[row][rack], rackDevice{i, int(unit), int(size)})
+	}
+
+	rows := make([]string, 0, len(racks))
+	for row := range racks {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return naturalLess(rows[i], rows[j]) })
+
+	rackHeight := float64(l.RackUnits) * RackUnitHeight
+	pitch := l.RackWidth + l.RackSpacing
+	for r, row := range rows {
+		names := make([]string, 0, len(racks[row]))
+		for rack := range racks[row] {
+			names = append(names, rack)
+		}
+		sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+
+		z := float64(r) * (l.RackDepth + l.AisleWidth)
+		facing := 0.0
+		if l.AlternateRows && r%2 == 1 {
+			facing = math.Pi
+		}
+		rowLength := float64(len(names))*pitch - l.RackSpacing
+
+		rowIndex := ensureGroupNode(sf, "row-"+row, RackRowNodeType, "Row "+row, &result)
+		rowNode := &sf.Scene.Nodes[rowIndex]
+		rowNode.Transform = NewTransformWithPosition((rowLength-l.RackWidth)/2, 0, z)
+		rowNode.Geometry = &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{
+			"width": rowLength, "height": 0.01, "depth": l.RackDepth,
+		}}
+
+		for k, rack := range names {
+			x := float64(k) * pitch
+			rackIndex := ensureGroupNode(sf, "rack-"+row+"-"+rack, RackNodeType, "Rack "+rack, &result)
+			rackNode := &sf.Scene.Nodes[rackIndex]
+			rackNode.Transform = NewTransformWithPosition(x, rackHeight/2, z)
+			rackNode.Transform.Rotation.Y = facing
+			rackNode.Geometry = &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{
+				"width": l.RackWidth, "height": rackHeight, "depth": l.RackDepth,
+			}}
+			rackNode.Material = &Material{Color: &Color{R: 0.15, G: 0.15, B: 0.17, A: 1}, Wireframe: true}
+			setParent(sf, rackIndex, rowIndex)
+
+			devices := racks[row][rack]
+			sort.SliceStable(devices, func(i, j int) bool { return devices[i].unit < devices[j].unit })
+			top := 0
+			for d, dev := range devices {
+				node := &sf.Scene.Nodes[dev.index]
+				if dev.unit <= top {
+					prev := sf.Scene.Nodes[devices[d-1].index].ID
+					result.Warnings = append(result.Warnings, fmt.Sprintf(
+						"node %s: unit %d in rack %s/%s overlaps %s", node.ID, dev.unit, row, rack, prev))
+				}
+				top = max(top, dev.unit+dev.size-1)
+
+				height := float64(dev.size) * RackUnitHeight
+				node.Transform.Position = Vector3{X: x, Y: float64(dev.unit-1)*RackUnitHeight + height/2, Z: z}
+				node.Transform.Rotation = Euler3{Y: facing}
+				if node.Geometry == nil && node.GeometryRef == "" {
+					node.Geometry = &Geometry{Type: GeometryBox, Parameters: map[string]interface{}{
+						"width": RackMountWidth, "height": height, "depth": l.RackDepth * 0.8,
+					}}
+				}
+				setParent(sf, dev.index, rackIndex)
+				result.Positioned++
+			}
+		}
+	}
+	return result, nil
+}
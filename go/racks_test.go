@@ -0,0 +1,97 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// newRackScene returns equipment spread over two rows
+func newRackScene() SceneFile {
+	sf := NewSceneFile("Datacenter")
+	add := func(id string, row, rack interface{}, unit, units float64) {
+		meta := map[string]interface{}{"row": row, "rack": rack, "unit": unit}
+		if units > 0 {
+			meta["units"] = units
+		}
+		sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{ID: id, Type: "server", Transform: NewTransform(), Metadata: meta})
+	}
+	add("web-1", "A", "1", 1, 2)
+	add("web-2", "A", "1", 3, 0)
+	add("db-1", "A", "2", 10, 4)
+	add("switch", "B", 1.0, 42, 0)
+	add("too-tall", "B", 1.0, 41, 4)
+	sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{ID: "floating", Type: "service", Transform: NewTransform()})
+	return sf
+}
+
+// TestRackLayout tests rack elevations, rows and group nodes
+func TestRackLayout(t *testing.T) {
+	sf := newRackScene()
+	result, err := NewRackLayout().Apply(&sf)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if result.Positioned != 4 {
+		t.Errorf("positioned mismatch: got %d, want 4", result.Positioned)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "too-tall" {
+		t.Errorf("skipped mismatch: got %v, want [too-tall]", result.Skipped)
+	}
+	if len(result.Created) != 5 {
+		t.Errorf("created mismatch: got %v, want 2 rows and 3 racks", result.Created)
+	}
+
+	web1, web2 := sf.FindNode("web-1"), sf.FindNode("web-2")
+	if got, want := web1.Transform.Position.Y, RackUnitHeight; math.Abs(got-want) > 1e-9 {
+		t.Errorf("2U device center mismatch: got %v, want %v", got, want)
+	}
+	if got, want := web2.Transform.Position.Y, 2.5*RackUnitHeight; math.Abs(got-want) > 1e-9 {
+		t.Errorf("1U device center mismatch: got %v, want %v", got, want)
+	}
+	if web1.Parent != "rack-A-1" || sf.FindNode("rack-A-1").Parent != "row-A" {
+		t.Errorf("hierarchy mismatch: device parent %q", web1.Parent)
+	}
+	if db := sf.FindNode("db-1"); math.Abs(db.Transform.Position.X-0.6) > 1e-9 {
+		t.Errorf("second rack offset mismatch: got %v, want 0.6", db.Transform.Position.X)
+	}
+
+	sw := sf.FindNode("switch")
+	if sw.Transform.Position.Z <= web1.Transform.Position.Z {
+		t.Error("expected row B behind row A")
+	}
+	if sw.Transform.Rotation.Y != math.Pi {
+		t.Errorf("alternate row rotation mismatch: got %v, want pi", sw.Transform.Rotation.Y)
+	}
+	if sf.FindNode("floating").Parent != "" {
+		t.Error("nodes without rack metadata should be untouched")
+	}
+
+	// Re-running reuses the group nodes
+	count := sf.GetNodeCount()
+	again, err := NewRackLayout().Apply(&sf)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(again.Created) != 0 || sf.GetNodeCount() != count {
+		t.Errorf("expected idempotent re-run, created %v", again.Created)
+	}
+	if len(sf.FindNode("rack-A-1").Children) != 2 {
+		t.Errorf("children mismatch: got %v", sf.FindNode("rack-A-1").Children)
+	}
+}
+
+// TestRackLayout_Overlap tests that clashing units are reported
+func TestRackLayout_Overlap(t *testing.T) {
+	sf := NewSceneFile("Overlap")
+	for _, id := range []string{"a", "b"} {
+		sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{ID: id, Transform: NewTransform(),
+			Metadata: map[string]interface{}{"row": "1", "rack": "1", "unit": 5.0, "units": 2.0}})
+	}
+	result, err := NewRackLayout().Apply(&sf)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("warning count mismatch: got %v, want 1", result.Warnings)
+	}
+}
@@ -0,0 +1,130 @@
+package starfleet
+
+import "slices"
+
+// =============================================================================
+// RECONCILIATION
+// =============================================================================
+
+// ImportSourceExtension is the extension key recording which importer owns
+// a node or edge. Reconcile only removes elements owned by the importer
+// being applied, so hand-drawn elements and those of other importers
+// survive.
+const ImportSourceExtension = "importSource"
+
+// ReconcileOptions configures Reconcile
+type ReconcileOptions struct {
+	// Source names the importer and is stamped on the elements it creates
+	Source string
+	// KeepMissing leaves owned elements that are absent from the import in
+	// place instead of removing them
+	KeepMissing bool
+	// Arena, when set, holds the nodes and edges of the result, which is
+	// then only valid until the arena is reset
+	Arena *SceneArena
+}
+
+// Reconcile applies an import to the current scene and returns the result
+// with the changes made. Imported nodes and edges are matched by ID. The
+// importer owns the topology and live data of an element: type, name,
+// metadata, tags, metrics and status, plus hierarchy and ports for nodes
+// and endpoints for edges. Presentation such as transforms, materials,
+// labels and edge styling is left as arranged in the current scene. Edges
+// whose endpoints are removed go with them. Neither input is modified.
+func Reconcile(current, imported *SceneFile, opts ReconcileOptions) (SceneFile, SceneDiff) {
+	out := *current
+	// Room for every import keeps appends inside an arena's slab
+	out.Scene.Nodes = append(opts.Arena.Nodes(len(current.Scene.Nodes)+len(imported.Scene.Nodes)), current.Scene.Nodes...)
+	out.Scene.Edges = append(opts.Arena.Edges(len(current.Scene.Edges)+len(imported.Scene.Edges)), current.Scene.Edges...)
+
+	nodes := make(map[string]int, len(out.Scene.Nodes))
+	for i, n := range out.Scene.Nodes {
+		nodes[n.ID] = i
+	}
+	seen := make(map[string]bool, len(imported.Scene.Nodes))
+	for _, in := range imported.Scene.Nodes {
+		seen[in.ID] = true
+		i, ok := nodes[in.ID]
+		if !ok {
+			in.Extensions = withExtension(in.Extensions, ImportSourceExtension, opts.Source)
+			nodes[in.ID] = len(out.Scene.Nodes)
+			out.Scene.Nodes = append(out.Scene.Nodes, in)
+			continue
+		}
+		n := &out.Scene.Nodes[i]
+		n.Type, n.Name, n.Status = in.Type, in.Name, in.Status
+		n.Metadata, n.Tags, n.Metrics = in.Metadata, in.Tags, in.Metrics
+		n.Ports, n.Parent, n.Children = in.Ports, in.Parent, in.Children
+		n.Extensions = mergeExtensions(n.Extensions, in.Extensions)
+	}
+
+	removed := make(map[string]bool)
+	if !opts.KeepMissing {
+		out.Scene.Nodes = slices.DeleteFunc(out.Scene.Nodes, func(n SceneNode) bool {
+			if !seen[n.ID] && ownedBy(n.Extensions, opts.Source) {
+				removed[n.ID] = true
+			}
+			return removed[n.ID]
+		})
+		for i := range out.Scene.Nodes {
+			n := &out.Scene.Nodes[i]
+			if removed[n.Parent] {
+				n.Parent = ""
+			}
+			if slices.ContainsFunc(n.Children, func(id string) bool { return removed[id] }) {
+				n.Children = slices.DeleteFunc(slices.Clone(n.Children), func(id string) bool { return removed[id] })
+			}
+		}
+	}
+
+	edges := make(map[string]int, len(out.Scene.Edges))
+	for i, e := range out.Scene.Edges {
+		edges[e.ID] = i
+	}
+	seenEdges := make(map[string]bool, len(imported.Scene.Edges))
+	for _, in := range imported.Scene.Edges {
+		seenEdges[in.ID] = true
+		i, ok := edges[in.ID]
+		if !ok {
+			in.Extensions = withExtension(in.Extensions, ImportSourceExtension, opts.Source)
+			edges[in.ID] = len(out.Scene.Edges)
+			out.Scene.Edges = append(out.Scene.Edges, in)
+			continue
+		}
+		e := &out.Scene.Edges[i]
+		e.Source, e.Target, e.TargetScene = in.Source, in.Target, in.TargetScene
+		e.SourcePort, e.TargetPort = in.SourcePort, in.TargetPort
+		e.Type, e.Direction, e.Key, e.Weight = in.Type, in.Direction, in.Key, in.Weight
+		e.Metadata, e.Metrics = in.Metadata, in.Metrics
+		e.Extensions = mergeExtensions(e.Extensions, in.Extensions)
+	}
+	out.Scene.Edges = slices.DeleteFunc(out.Scene.Edges, func(e SceneEdge) bool {
+		if removed[e.Source] || (e.TargetScene == "" && removed[e.Target]) {
+			return true
+		}
+		return !opts.KeepMissing && !seenEdges[e.ID] && ownedBy(e.Extensions, opts.Source)
+	})
+
+	return out, Diff(current, &out)
+}
+
+// ownedBy reports whether an element was created by the named importer
+func ownedBy(ext map[string]interface{}, source string) bool {
+	owner, ok := ext[ImportSourceExtension].(string)
+	return ok && owner == source
+}
+
+// mergeExtensions returns a copy of current with the imported extensions
+// laid over it, keeping the element's original owner
+func mergeExtensions(current, imported map[string]interface{}) map[string]interface{} {
+	if len(imported) == 0 {
+		return current
+	}
+	out := copyMap(current)
+	for k, v := range imported {
+		if k != ImportSourceExtension {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package starfleet
+
+import "testing"
+
+// TestReconcile tests merging imports while keeping layout and hand-drawn
+// elements
+func TestReconcile(t *testing.T) {
+	current := newDiffScene()
+	current.Scene.Nodes[0].Transform.Position = Vector3{X: 5}
+
+	imported := NewSceneFile("Import")
+	imported.AddNode(SceneNode{ID: "a", Type: "vm", Name: "A2", Transform: NewTransform(), Tags: []string{"prod"}})
+	imported.AddNode(SceneNode{ID: "d", Type: "db", Name: "D", Transform: NewTransform(), Parent: "a"})
+	imported.AddEdge(SceneEdge{ID: "a-d", Source: "a", Target: "d"})
+
+	merged, changes := Reconcile(&current, &imported, ReconcileOptions{Source: "aws"})
+	a := merged.FindNode("a")
+	if a.Name != "A2" || a.Type != "vm" || a.Transform.Position.X != 5 || a.Extensions[ImportSourceExtension] != nil {
+		t.Errorf("updated node mismatch: got %+v", a)
+	}
+	if d := merged.FindNode("d"); d == nil || d.Extensions[ImportSourceExtension] != "aws" {
+		t.Errorf("added node mismatch: got %+v", d)
+	}
+	if len(changes.Nodes) != 2 || len(changes.Edges) != 1 || current.FindNode("d") != nil || current.Scene.Nodes[0].Name != "A" {
+		t.Errorf("changes mismatch: got %+v", changes)
+	}
+
+	// Only owned elements that disappear are removed
+	imported.Scene.Nodes, imported.Scene.Edges = imported.Scene.Nodes[:1], nil
+	kept, changes := Reconcile(&merged, &imported, ReconcileOptions{Source: "aws", KeepMissing: true})
+	if !changes.Empty() || kept.GetNodeCount() != 4 {
+		t.Errorf("KeepMissing mismatch: got %+v", changes)
+	}
+	pruned, changes := Reconcile(&merged, &imported, ReconcileOptions{Source: "aws"})
+	if pruned.FindNode("d") != nil || pruned.FindEdge("a-d") != nil || pruned.FindNode("c") == nil || pruned.FindEdge("a-b") == nil {
+		t.Errorf("pruned scene mismatch: got %+v", pruned.Scene)
+	}
+	if len(changes.Nodes) != 1 || changes.Nodes[0].Kind != ChangeRemoved || len(changes.Edges) != 1 {
+		t.Errorf("changes mismatch: got %+v", changes)
+	}
+	if other, _ := Reconcile(&merged, &imported, ReconcileOptions{Source: "gcp"}); other.FindNode("d") == nil {
+		t.Error("another source must not remove nodes it does not own")
+	}
+}
@@ -0,0 +1,281 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// SCENE REFERENCES
+// =============================================================================
+
+// ErrSceneRefCycle is returned when scene references form a cycle
+var ErrSceneRefCycle = errors.New("scene reference cycle")
+
+// MaxSceneRefDepth bounds how deeply nested scene references are resolved
+const MaxSceneRefDepth = 16
+
+// SceneRefExtension is the node extension key recording the URI of the
+// scene a node was inlined from, or that a mount node resolved
+const SceneRefExtension = "sceneRef"
+
+// SceneRefFilter selects the nodes of a referenced scene to include. A node
+// is included when it matches every non-empty criterion.
+type SceneRefFilter struct {
+	NodeIDs   []string `json:"nodeIds,omitempty"`
+	NodeTypes []string `json:"nodeTypes,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// SceneRef represents a reference from a node to another scene file. The
+// referencing node acts as the mount point: inlined root nodes become its
+// children and are placed relative to Transform (or the mount node's own
+// transform when unset).
+type SceneRef struct {
+	URI       string          `json:"uri" validate:"required"`
+	Transform *Transform      `json:"transform,omitempty"`
+	Filter    *SceneRefFilter `json:"filter,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+}
+
+// SceneLoader loads scene files by URI
+type SceneLoader interface {
+	LoadScene(ctx context.Context, uri string) (*SceneFile, error)
+}
+
+// SceneLoaderFunc adapts a function to the SceneLoader interface
+type SceneLoaderFunc func(ctx context.Context, uri string) (*SceneFile, error)
+
+// LoadScene calls f(ctx, uri)
+func (f SceneLoaderFunc) LoadScene(ctx context.Context, uri string) (*SceneFile, error) {
+	return f(ctx, uri)
+}
+
+// ResolveRefs inlines every scene referenced by a node of sf, recursively.
+// Inlined node and edge IDs are namespaced with NamespaceIDs using the
+// reference namespace (defaulting to the mount node ID) and a "/" separator
+// so that scenes owned by different teams can be merged without collisions.
+// Resolved mount nodes have their Ref cleared and the URI recorded under
+// SceneRefExtension, and cross-scene edges into the inlined scene become
+// local edges.
+func ResolveRefs(ctx context.Context, sf *SceneFile, loader SceneLoader) error {
+	return resolveRefs(ctx, sf, loader, map[string]bool{}, 0)
+}
+
+// resolveRefs resolves references with cycle and depth tracking
+func resolveRefs(ctx context.Context, sf *SceneFile, loader SceneLoader, active map[string]bool, depth int) error {
+	if depth > MaxSceneRefDepth {
+		return fmt.Errorf("resolve refs: nesting exceeds %d levels", MaxSceneRefDepth)
+	}
+
+	mounts := make([]int, 0)
+	for i := range sf.Scene.Nodes {
+		if sf.Scene.Nodes[i].Ref != nil {
+			mounts = append(mounts, i)
+		}
+	}
+
+	for _, i := range mounts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		mount := sf.Scene.Nodes[i]
+		ref := *mount.Ref
+		if active[ref.URI] {
+			return fmt.Errorf("resolve ref %s: %w", ref.URI, ErrSceneRefCycle)
+		}
+
+		loaded, err := loader.LoadScene(ctx, ref.URI)
+		if err != nil {
+			return fmt.Errorf("resolve ref %s: %w", ref.URI, err)
+		}
+		child := *loaded
+		child.Scene.Nodes = append([]SceneNode(nil), loaded.Scene.Nodes...)
+		child.Scene.Edges = append([]SceneEdge(nil), loaded.Scene.Edges...)
+
+		active[ref.URI] = true
+		err = resolveRefs(ctx, &child, loader, active, depth+1)
+		delete(active, ref.URI)
+		if err != nil {
+			return err
+		}
+
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = mount.ID
+		}
+		transform := mount.Transform
+		if ref.Transform != nil {
+			transform = *ref.Transform
+		}
+		if err := inlineScene(sf, &child, mount.ID, namespace, transform, ref); err != nil {
+			return err
+		}
+		localizeExternalEdges(sf, ref.URI, namespace+"/")
+
+		node := &sf.Scene.Nodes[i]
+		node.Ref = nil
+		if node.Extensions == nil {
+			node.Extensions = make(map[string]interface{})
+		}
+		node.Extensions[SceneRefExtension] = ref.URI
+	}
+	return nil
+}
+
+// inlineScene appends the filtered, namespaced and transformed contents of
+// child to sf under the mount node
+func inlineScene(sf, child *SceneFile, mountID, namespace string, transform Transform, ref SceneRef) error {
+	part := SceneFile{}
+	kept := make(map[string]bool, len(child.Scene.Nodes))
+	for i := range child.Scene.Nodes {
+		if ref.Filter.matches(&child.Scene.Nodes[i]) {
+			part.Scene.Nodes = append(part.Scene.Nodes, child.Scene.Nodes[i])
+			kept[child.Scene.Nodes[i].ID] = true
+		}
+	}
+	for _, edge := range child.Scene.Edges {
+		if kept[edge.Source] && kept[edge.Target] {
+			part.Scene.Edges = append(part.Scene.Edges, edge)
+		}
+	}
+	for i := range part.Scene.Nodes {
+		n := &part.Scene.Nodes[i]
+		if !kept[n.Parent] {
+			n.Parent = ""
+		}
+		children := make([]string, 0, len(n.Children))
+		for _, c := range n.Children {
+			if kept[c] {
+				children = append(children, c)
+			}
+		}
+		n.Children = children
+	}
+
+	if err := NamespaceIDs(&part, namespace+"/"); err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(sf.Scene.Nodes)+len(sf.Scene.Edges))
+	for _, id := range nodeIDs(sf) {
+		existing["n:"+id] = true
+	}
+	for _, id := range edgeIDs(sf) {
+		existing["e:"+id] = true
+	}
+	for _, id := range nodeIDs(&part) {
+		if existing["n:"+id] {
+			return fmt.Errorf("inline %s: %w: node %s", ref.URI, ErrDuplicateID, id)
+		}
+	}
+	for _, id := range edgeIDs(&part) {
+		if existing["e:"+id] {
+			return fmt.Errorf("inline %s: %w: edge %s", ref.URI, ErrDuplicateID, id)
+		}
+	}
+
+	mountIndex := -1
+	for i := range sf.Scene.Nodes {
+		if sf.Scene.Nodes[i].ID == mountID {
+			mountIndex = i
+			break
+		}
+	}
+	for _, node := range part.Scene.Nodes {
+		if node.Parent == "" {
+			node.Parent = mountID
+			if mountIndex >= 0 {
+				sf.Scene.Nodes[mountIndex].Children = append(sf.Scene.Nodes[mountIndex].Children, node.ID)
+			}
+		}
+		node.Transform = composeTransform(transform, node.Transform)
+		node.Extensions = copyMap(node.Extensions)
+		if _, ok := node.Extensions[SceneRefExtension]; !ok {
+			node.Extensions[SceneRefExtension] = ref.URI
+		}
+		sf.Scene.Nodes = append(sf.Scene.Nodes, node)
+	}
+
+	for _, edge := range part.Scene.Edges {
+		if len(edge.Waypoints) > 0 {
+			waypoints := make([]Vector3, len(edge.Waypoints))
+			for j, w := range edge.Waypoints {
+				waypoints[j] = transformPoint(transform, w)
+			}
+			edge.Waypoints = waypoints
+		}
+		sf.Scene.Edges = append(sf.Scene.Edges, edge)
+	}
+
+	for key, uri := range child.Assets {
+		if _, exists := sf.Assets[key]; !exists {
+			if sf.Assets == nil {
+				sf.Assets = make(map[string]string)
+			}
+			sf.Assets[key] = uri
+		}
+	}
+	return nil
+}
+
+// matches reports whether a node passes the filter; a nil filter matches all
+func (f *SceneRefFilter) matches(node *SceneNode) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.NodeIDs) > 0 && !containsString(f.NodeIDs, node.ID) {
+		return false
+	}
+	if len(f.NodeTypes) > 0 && !containsString(f.NodeTypes, node.Type) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !containsString(node.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString reports whether a slice contains a string
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// transformPoint maps a point through a transform (scale, rotate, translate)
+func transformPoint(t Transform, p Vector3) Vector3 {
+	s := Vector3{X: p.X * t.Scale.X, Y: p.Y * t.Scale.Y, Z: p.Z * t.Scale.Z}
+	m := rotationMatrix(t.Rotation)
+	r := Vector3{
+		X: m[0][0]*s.X + m[0][1]*s.Y + m[0][2]*s.Z,
+		Y: m[1][0]*s.X + m[1][1]*s.Y + m[1][2]*s.Z,
+		Z: m[2][0]*s.X + m[2][1]*s.Y + m[2][2]*s.Z,
+	}
+	return r.Add(t.Position)
+}
+
+// composeTransform places a child transform inside a parent transform.
+// Rotations are composed by adding Euler angles, which is exact for
+// rotations about a single axis, the common case for mounted scenes.
+func composeTransform(parent, child Transform) Transform {
+	return Transform{
+		Position: transformPoint(parent, child.Position),
+		Rotation: Euler3{
+			X: math.Remainder(parent.Rotation.X+child.Rotation.X, 2*math.Pi),
+			Y: math.Remainder(parent.Rotation.Y+child.Rotation.Y, 2*math.Pi),
+			Z: math.Remainder(parent.Rotation.Z+child.Rotation.Z, 2*math.Pi),
+		},
+		Scale: Scale3{
+			X: parent.Scale.X * child.Scale.X,
+			Y: parent.Scale.Y * child.Scale.Y,
+			Z: parent.Scale.Z * child.Scale.Z,
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// mapLoader serves scenes from memory for reference resolution tests
+func mapLoader(scenes map[string]*SceneFile) SceneLoader {
+	return SceneLoaderFunc(func(_ context.Context, uri string) (*SceneFile, error) {
+		sf, ok := scenes[uri]
+		if !ok {
+			return nil, fmt.Errorf("scene %s not found", uri)
+		}
+		return sf, nil
+	})
+}
+
+// TestResolveRefs tests inlining referenced scenes with namespacing
+func TestResolveRefs(t *testing.T) {
+	team := NewSceneFile("Team")
+	team.AddNode(SceneNode{ID: "svc", Type: "service", Name: "Service", Transform: NewTransformWithPosition(1, 0, 0), Children: []string{"db"}})
+	team.AddNode(SceneNode{ID: "db", Type: "database", Name: "DB", Parent: "svc", Transform: NewTransformWithPosition(2, 0, 0)})
+	team.AddNode(SceneNode{ID: "tmp", Type: "job", Name: "Job", Transform: NewTransform()})
+	team.AddEdge(SceneEdge{ID: "e", Source: "svc", Target: "db"})
+	team.AddEdge(SceneEdge{ID: "j", Source: "tmp", Target: "db"})
+
+	org := NewSceneFile("Org")
+	org.AddNode(SceneNode{
+		ID: "payments", Type: "group", Name: "Payments",
+		Transform: NewTransformWithPosition(10, 0, 0),
+		Ref:       &SceneRef{URI: "team.json", Filter: &SceneRefFilter{NodeTypes: []string{"service", "database"}}},
+	})
+
+	if err := ResolveRefs(context.Background(), &org, mapLoader(map[string]*SceneFile{"team.json": &team})); err != nil {
+		t.Fatalf("ResolveRefs failed: %v", err)
+	}
+
+	if org.GetNodeCount() != 3 || org.GetEdgeCount() != 1 {
+		t.Fatalf("Resolved counts mismatch: nodes %d, edges %d", org.GetNodeCount(), org.GetEdgeCount())
+	}
+	svc := org.FindNode("payments/svc")
+	db := org.FindNode("payments/db")
+	if svc == nil || db == nil {
+		t.Fatalf("Namespaced nodes missing")
+	}
+	if svc.Parent != "payments" || db.Parent != "payments/svc" || svc.Children[0] != "payments/db" {
+		t.Errorf("Hierarchy mismatch: svc parent %s, db parent %s, svc children %v", svc.Parent, db.Parent, svc.Children)
+	}
+	if svc.Transform.Position.X != 11 {
+		t.Errorf("Transform mismatch: got %+v", svc.Transform.Position)
+	}
+	edge := org.FindEdge("payments/e")
+	if edge == nil || edge.Source != "payments/svc" || edge.Target != "payments/db" {
+		t.Errorf("Namespaced edge mismatch: got %+v", edge)
+	}
+
+	mount := org.FindNode("payments")
+	if mount.Ref != nil || mount.Extensions[SceneRefExtension] != "team.json" {
+		t.Errorf("Mount node not marked resolved: %+v", mount)
+	}
+	if team.GetNodeCount() != 3 || team.FindNode("svc").Parent != "" {
+		t.Errorf("ResolveRefs modified the referenced scene")
+	}
+}
+
+// TestResolveRefs_Cycle tests that reference cycles are rejected
+func TestResolveRefs_Cycle(t *testing.T) {
+	a := NewSceneFile("A")
+	a.AddNode(SceneNode{ID: "to-b", Transform: NewTransform(), Ref: &SceneRef{URI: "b"}})
+	b := NewSceneFile("B")
+	b.AddNode(SceneNode{ID: "to-a", Transform: NewTransform(), Ref: &SceneRef{URI: "a"}})
+
+	root := NewSceneFile("Root")
+	root.AddNode(SceneNode{ID: "mount", Transform: NewTransform(), Ref: &SceneRef{URI: "a"}})
+
+	err := ResolveRefs(context.Background(), &root, mapLoader(map[string]*SceneFile{"a": &a, "b": &b}))
+	if !errors.Is(err, ErrSceneRefCycle) {
+		t.Errorf("ResolveRefs error mismatch: got %v, want ErrSceneRefCycle", err)
+	}
+}
+
+// TestComposeTransform tests placing a child transform inside a parent
+func TestComposeTransform(t *testing.T) {
+	parent := Transform{
+		Position: Vector3{X: 10},
+		Rotation: Euler3{Y: math.Pi / 2},
+		Scale:    Scale3{X: 2, Y: 2, Z: 2},
+	}
+	got := composeTransform(parent, NewTransformWithPosition(1, 0, 0))
+	if math.Abs(got.Position.X-10) > 1e-9 || math.Abs(got.Position.Z+2) > 1e-9 {
+		t.Errorf("Composed position mismatch: got %+v", got.Position)
+	}
+	if got.Scale.X != 2 || math.Abs(got.Rotation.Y-math.Pi/2) > 1e-9 {
+		t.Errorf("Composed scale/rotation mismatch: got %+v", got)
+	}
+}
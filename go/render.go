@@ -0,0 +1,347 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// SOFTWARE RENDERING
+// =============================================================================
+
+// DefaultCameraFOV is the vertical field of view in degrees used when a
+// camera does not set one, matching the viewer default
+const DefaultCameraFOV = 75.0
+
+// renderSupersample is the per-axis supersampling factor used to smooth
+// edges in rendered images
+const renderSupersample = 2
+
+// MaxRenderPixels is the largest image, in pixels, RenderImage draws: a 4K
+// frame. Supersampling and the depth buffer make larger renders cost
+// hundreds of megabytes.
+const MaxRenderPixels = 3840 * 2160
+
+// RenderImage rasterizes the scene from its camera into an image of the
+// given size. Nodes are drawn as flat-shaded solids with their material
+// colors and edges as lines; textures, transparency and shadows are not
+// rendered. Scenes without a camera are framed from above and to the side.
+// Triangles crossing the near plane are dropped rather than clipped, which
+// only matters for cameras placed inside the scene. Elements the scene's
+// visibility rules hide from that camera are left out. Images larger than
+// MaxRenderPixels are refused.
+func RenderImage(sf *SceneFile, width, height int) (*image.NRGBA, error) {
+	return RenderImageContext(context.Background(), sf, width, height)
+}
+
+// RenderImageContext is RenderImage checking the context while it draws,
+// so renders of huge scenes stop when their caller goes away. Visibility
+// rules see the filters set by WithVisibilityFilters as active.
+func RenderImageContext(ctx context.Context, sf *SceneFile, width, height int) (*image.NRGBA, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("render: image size must be positive")
+	}
+	if width > MaxRenderPixels/height {
+		return nil, fmt.Errorf("render: image size %dx%d exceeds %d pixels", width, height, MaxRenderPixels)
+	}
+	camera := sf.Scene.Camera
+	if camera == nil {
+		fitted := framingCamera(sf, float64(width)/float64(height))
+		camera = &fitted
+	}
+	visible, err := ApplyVisibility(sf, VisibilityContext{Camera: camera, Filters: VisibilityFiltersFromContext(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("render: %w", err)
+	}
+	sf = &visible
+	r := newRasterizer(camera, width*renderSupersample, height*renderSupersample)
+	r.clear(sceneBackground(sf))
+
+	c := canceler{ctx: ctx}
+	progress := newProgressTracker(ctx, "render", len(sf.Scene.Nodes)+len(sf.Scene.Edges))
+	light, ambient := sceneLighting(sf)
+	for i := range sf.Scene.Nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.step()
+		node := &sf.Scene.Nodes[i]
+		base := NewColor(0.8, 0.8, 0.8)
+		if m := sf.ResolveMaterial(node); m != nil && m.Color != nil {
+			base = *m.Color
+		}
+		mesh := geometryMesh(sf.ResolveGeometry(node))
+		idx := mesh.indices()
+		for t := 0; t+2 < len(idx); t += 3 {
+			// Imported meshes can carry millions of triangles
+			if err := c.check(); err != nil {
+				return nil, err
+			}
+			v0 := transformPoint(node.Transform, mesh.vertex(idx[t]))
+			v1 := transformPoint(node.Transform, mesh.vertex(idx[t+1]))
+			v2 := transformPoint(node.Transform, mesh.vertex(idx[t+2]))
+			normal := v1.Sub(v0).Cross(v2.Sub(v0)).Normalize()
+			// Shade both sides so meshes with inconsistent winding still read
+			shade := ambient + (1-ambient)*math.Abs(normal.Dot(light))
+			r.triangle(v0, v1, v2, shadeColor(base, shade))
+		}
+	}
+
+	nodes := make(map[string]*SceneNode, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodes[sf.Scene.Nodes[i].ID] = &sf.Scene.Nodes[i]
+	}
+	for i := range sf.Scene.Edges {
+		if err := c.check(); err != nil {
+			return nil, err
+		}
+		progress.step()
+		edge := &sf.Scene.Edges[i]
+		source, target := nodes[edge.Source], nodes[edge.Target]
+		if source == nil || target == nil {
+			continue
+		}
+		lineColor := NewColor(0.6, 0.6, 0.6)
+		if edge.Color != nil {
+			lineColor = *edge.Color
+		}
+		points := append([]Vector3{source.Transform.Position}, edge.Waypoints...)
+		points = append(points, target.Transform.Position)
+		for j := 1; j < len(points); j++ {
+			r.line(points[j-1], points[j], lineColor)
+		}
+	}
+	progress.done()
+	return r.downsample(width, height), nil
+}
+
+// RenderThumbnail renders the scene from its camera and encodes it as PNG
+func RenderThumbnail(sf *SceneFile, width, height int) ([]byte, error) {
+	return RenderThumbnailContext(context.Background(), sf, width, height)
+}
+
+// RenderThumbnailContext is RenderThumbnail with the cancellation of
+// RenderImageContext
+func RenderThumbnailContext(ctx context.Context, sf *SceneFile, width, height int) ([]byte, error) {
+	img, err := RenderImageContext(ctx, sf, width, height)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// framingCamera returns a camera fitting the whole scene at the given
+// aspect ratio, looking from the default direction
+func framingCamera(sf *SceneFile, aspect float64) Camera {
+	camera := Camera{Position: defaultViewDirection, FOV: DefaultCameraFOV}
+	return frameSphere(camera, sf.SceneBoundingSphere(), 0, aspect)
+}
+
+// sceneBackground returns the environment background color, accepting a
+// Color, its decoded JSON object form, or a "#rrggbb" string
+func sceneBackground(sf *SceneFile) Color {
+	fallback := NewColor(1, 1, 1)
+	if sf.Scene.Environment == nil {
+		return fallback
+	}
+	switch bg := sf.Scene.Environment.Background.(type) {
+	case Color:
+		return bg
+	case *Color:
+		if bg != nil {
+			return *bg
+		}
+	case map[string]interface{}:
+		r, okR := toFloat64(bg["r"])
+		g, okG := toFloat64(bg["g"])
+		b, okB := toFloat64(bg["b"])
+		if okR && okG && okB {
+			return NewColor(r, g, b)
+		}
+	case string:
+		hex := strings.TrimPrefix(bg, "#")
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && len(hex) == 6 {
+			return NewColor(float64(v>>16&0xff)/255, float64(v>>8&0xff)/255, float64(v&0xff)/255)
+		}
+	}
+	return fallback
+}
+
+// sceneLighting returns the direction toward the key light and the ambient
+// intensity, taken from the first directional and ambient lights
+func sceneLighting(sf *SceneFile) (Vector3, float64) {
+	light := Vector3{X: 0.4, Y: 1, Z: 0.6}.Normalize()
+	ambient := 0.35
+	for _, l := range sf.Scene.Lights {
+		switch {
+		case l.Type == LightDirectional && l.Direction != nil && l.Direction.Length() > 0:
+			light = l.Direction.Scale(-1).Normalize()
+		case l.Type == LightAmbient && l.Intensity > 0:
+			ambient = math.Min(l.Intensity, 1) * 0.5
+		}
+	}
+	return light, ambient
+}
+
+// shadeColor scales a color by a light intensity
+func shadeColor(c Color, shade float64) color.NRGBA {
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v*shade)) * 255))
+	}
+	return color.NRGBA{R: channel(c.R), G: channel(c.G), B: channel(c.B), A: 255}
+}
+
+// rasterizer draws depth-tested triangles and lines into a color buffer
+type rasterizer struct {
+	width, height int
+	pixels        []color.NRGBA
+	// depth holds 1/z per pixel, so larger values are closer
+	depth       []float64
+	position    Vector3
+	basis       viewBasis
+	forward     Vector3
+	focal, near float64
+}
+
+// newRasterizer creates a rasterizer for a perspective camera
+func newRasterizer(camera *Camera, width, height int) *rasterizer {
+	fov := camera.FOV
+	if fov <= 0 {
+		fov = DefaultCameraFOV
+	}
+	near := camera.Near
+	if near <= 0 {
+		near = 0.1
+	}
+	return &rasterizer{
+		width:    width,
+		height:   height,
+		pixels:   make([]color.NRGBA, width*height),
+		depth:    make([]float64, width*height),
+		position: camera.Position,
+		basis:    newViewBasis(camera),
+		forward:  camera.Target.Sub(camera.Position).Normalize(),
+		focal:    float64(height) / 2 / math.Tan(fov*math.Pi/360),
+		near:     near,
+	}
+}
+
+// clear fills the color buffer and resets depth
+func (r *rasterizer) clear(c Color) {
+	fill := shadeColor(c, 1)
+	for i := range r.pixels {
+		r.pixels[i] = fill
+		r.depth[i] = 0
+	}
+}
+
+// project maps a world point to screen coordinates and inverse depth,
+// reporting false for points behind the near plane
+func (r *rasterizer) project(p Vector3) (float64, float64, float64, bool) {
+	d := p.Sub(r.position)
+	z := d.Dot(r.forward)
+	if z < r.near {
+		return 0, 0, 0, false
+	}
+	x, y := r.basis.project(d)
+	return float64(r.width)/2 + x/z*r.focal, float64(r.height)/2 - y/z*r.focal, 1 / z, true
+}
+
+// plot writes a pixel when it is closer than what is already drawn
+func (r *rasterizer) plot(x, y int, w float64, c color.NRGBA) {
+	if x < 0 || y < 0 || x >= r.width || y >= r.height {
+		return
+	}
+	i := y*r.width + x
+	if w > r.depth[i] {
+		r.depth[i] = w
+		r.pixels[i] = c
+	}
+}
+
+// triangle rasterizes a world-space triangle with a flat color
+func (r *rasterizer) triangle(a, b, c Vector3, col color.NRGBA) {
+	ax, ay, aw, okA := r.project(a)
+	bx, by, bw, okB := r.project(b)
+	cx, cy, cw, okC := r.project(c)
+	if !okA || !okB || !okC {
+		return
+	}
+	area := (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+	if area == 0 {
+		return
+	}
+	minX := max(0, int(math.Floor(math.Min(ax, math.Min(bx, cx)))))
+	maxX := min(r.width-1, int(math.Ceil(math.Max(ax, math.Max(bx, cx)))))
+	minY := max(0, int(math.Floor(math.Min(ay, math.Min(by, cy)))))
+	maxY := min(r.height-1, int(math.Ceil(math.Max(ay, math.Max(by, cy)))))
+	for y := minY; y <= maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float64(x) + 0.5
+			w0 := ((bx-px)*(cy-py) - (by-py)*(cx-px)) / area
+			w1 := ((cx-px)*(ay-py) - (cy-py)*(ax-px)) / area
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+			r.plot(x, y, w0*aw+w1*bw+w2*cw, col)
+		}
+	}
+}
+
+// line draws a world-space segment roughly one output pixel wide. Lines are
+// pulled slightly toward the camera so edges touching a surface stay visible.
+func (r *rasterizer) line(a, b Vector3, c Color) {
+	ax, ay, aw, okA := r.project(a)
+	bx, by, bw, okB := r.project(b)
+	if !okA || !okB {
+		return
+	}
+	col := shadeColor(c, 1)
+	steps := int(math.Ceil(math.Max(math.Abs(bx-ax), math.Abs(by-ay)))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		x, y := ax+(bx-ax)*t, ay+(by-ay)*t
+		w := (aw + (bw-aw)*t) * 1.001
+		for dy := 0; dy < renderSupersample; dy++ {
+			for dx := 0; dx < renderSupersample; dx++ {
+				r.plot(int(x)+dx, int(y)+dy, w, col)
+			}
+		}
+	}
+}
+
+// downsample averages supersampled pixels into the output image
+func (r *rasterizer) downsample(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	n := renderSupersample * renderSupersample
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sr, sg, sb int
+			for dy := 0; dy < renderSupersample; dy++ {
+				for dx := 0; dx < renderSupersample; dx++ {
+					p := r.pixels[(y*renderSupersample+dy)*r.width+x*renderSupersample+dx]
+					sr, sg, sb = sr+int(p.R), sg+int(p.G), sb+int(p.B)
+				}
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(sr / n), G: uint8(sg / n), B: uint8(sb / n), A: 255})
+		}
+	}
+	return img
+}
@@ -0,0 +1,85 @@
+package starfleet
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+// TestRenderThumbnail tests that a node renders in its material color over
+// the background
+func TestRenderThumbnail(t *testing.T) {
+	sf := NewSceneFile("Render")
+	red := NewColor(1, 0, 0)
+	sf.Scene.Nodes = []SceneNode{{
+		ID:        "box",
+		Type:      "server",
+		Transform: NewTransform(),
+		Geometry:  &Geometry{Type: GeometryBox},
+		Material:  &Material{Color: &red},
+	}}
+	sf.Scene.Camera = &Camera{Position: Vector3{Z: 3}, Target: Vector3{}}
+	sf.Scene.Environment = &Environment{Background: "#000080"}
+
+	data, err := RenderThumbnail(&sf, 64, 48)
+	if err != nil {
+		t.Fatalf("RenderThumbnail failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode failed: %v", err)
+	}
+	if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 48 {
+		t.Errorf("image size mismatch: got %v, want 64x48", img.Bounds())
+	}
+
+	r, g, b, _ := img.At(32, 24).RGBA()
+	if r>>8 < 100 || g>>8 > 10 || b>>8 > 10 {
+		t.Errorf("center pixel should be red, got %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+	r, g, b, _ = img.At(1, 1).RGBA()
+	if r>>8 != 0 || g>>8 != 0 || b>>8 != 0x80 {
+		t.Errorf("corner pixel should be background, got %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestRenderImage_Framing tests that scenes without a camera are framed
+func TestRenderImage_Framing(t *testing.T) {
+	sf := NewSceneFile("Framing")
+	for i, x := range []float64{-20, 20} {
+		sf.Scene.Nodes = append(sf.Scene.Nodes, SceneNode{
+			ID:        string(rune('a' + i)),
+			Transform: NewTransformWithPosition(x, 0, 0),
+			Geometry:  &Geometry{Type: GeometrySphere, Parameters: map[string]interface{}{"radius": 2.0}},
+		})
+	}
+	sf.Scene.Edges = []SceneEdge{{ID: "ab", Source: "a", Target: "b"}}
+
+	img, err := RenderImage(&sf, 80, 80)
+	if err != nil {
+		t.Fatalf("RenderImage failed: %v", err)
+	}
+	drawn := 0
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 80; x++ {
+			if c := img.NRGBAAt(x, y); c.R != 255 || c.G != 255 || c.B != 255 {
+				drawn++
+			}
+		}
+	}
+	if drawn == 0 {
+		t.Error("expected framed scene to be visible")
+	}
+	for _, p := range [][2]int{{0, 0}, {79, 0}, {0, 79}, {79, 79}} {
+		if c := img.NRGBAAt(p[0], p[1]); c.R != 255 {
+			t.Errorf("corner %v should be background, got %v", p, c)
+		}
+	}
+
+	if _, err := RenderImage(&sf, 0, 10); err == nil {
+		t.Error("expected error for empty image size")
+	}
+	if _, err := RenderImage(&sf, 1<<20, 1<<20); err == nil {
+		t.Error("expected error for an image over MaxRenderPixels")
+	}
+}
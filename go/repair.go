@@ -0,0 +1,262 @@
+package starfleet
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// =============================================================================
+// REFERENTIAL INTEGRITY REPAIR
+// =============================================================================
+
+// MissingEndpointPolicy represents how Repair handles edges to missing nodes
+type MissingEndpointPolicy string
+
+const (
+	// RepairDropEdges removes edges whose endpoints do not exist
+	RepairDropEdges MissingEndpointPolicy = "drop"
+	// RepairCreatePlaceholders creates placeholder nodes for missing endpoints
+	RepairCreatePlaceholders MissingEndpointPolicy = "placeholder"
+)
+
+// HierarchySource represents which side wins when Parent and Children
+// links disagree
+type HierarchySource string
+
+const (
+	HierarchyFromParent   HierarchySource = "parent"
+	HierarchyFromChildren HierarchySource = "children"
+)
+
+// RepairPolicy represents the choices Repair makes when fixing a scene
+type RepairPolicy struct {
+	MissingEndpoints MissingEndpointPolicy
+	Hierarchy        HierarchySource
+	// PlaceholderType is the node type given to placeholders; defaults to
+	// "placeholder"
+	PlaceholderType string
+}
+
+// RepairActionKind represents the kind of fix applied by Repair
+type RepairActionKind string
+
+const (
+	RepairRenamedNode      RepairActionKind = "renamed-node"
+	RepairRenamedEdge      RepairActionKind = "renamed-edge"
+	RepairDroppedEdge      RepairActionKind = "dropped-edge"
+	RepairAddedPlaceholder RepairActionKind = "added-placeholder"
+	RepairClearedParent    RepairActionKind = "cleared-parent"
+	RepairSetParent        RepairActionKind = "set-parent"
+	RepairAddedChild       RepairActionKind = "added-child"
+	RepairRemovedChild     RepairActionKind = "removed-child"
+)
+
+// RepairPlaceholderExtension is the extension key marking nodes created by
+// Repair as placeholders for missing edge endpoints
+const RepairPlaceholderExtension = "repairPlaceholder"
+
+// RepairAction represents a single fix applied to a scene
+type RepairAction struct {
+	Kind   RepairActionKind `json:"kind"`
+	ID     string           `json:"id"`
+	Detail string           `json:"detail"`
+}
+
+// RepairReport represents every action taken by Repair
+type RepairReport struct {
+	Actions []RepairAction `json:"actions"`
+}
+
+// add records an action in the report
+func (r *RepairReport) add(kind RepairActionKind, id, format string, args ...interface{}) {
+	r.Actions = append(r.Actions, RepairAction{Kind: kind, ID: id, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Changed reports whether Repair modified the scene
+func (r *RepairReport) Changed() bool {
+	return len(r.Actions) > 0
+}
+
+// Repair fixes common referential integrity problems in place: empty and
+// duplicate IDs are replaced with unique suffixed IDs, edges to missing
+// nodes are dropped or given placeholder endpoints, and Parent/Children
+// links are reconciled (dangling references and cycles removed). Every fix
+// is reported. References to a duplicated ID keep pointing at its first
+// occurrence. Cross-scene edge targets are left alone.
+func Repair(sf *SceneFile, policy RepairPolicy) RepairReport {
+	report := RepairReport{Actions: []RepairAction{}}
+
+	nodeSeen := make(map[string]bool, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.ID == "" || nodeSeen[n.ID] {
+			old := n.ID
+			n.ID = uniqueID(nodeSeen, old, "node")
+			report.add(RepairRenamedNode, n.ID, "renamed duplicate or empty node id %q", old)
+		}
+		nodeSeen[n.ID] = true
+	}
+
+	edgeSeen := make(map[string]bool, len(sf.Scene.Edges))
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		if e.ID == "" || edgeSeen[e.ID] {
+			old := e.ID
+			e.ID = uniqueID(edgeSeen, old, "edge")
+			report.add(RepairRenamedEdge, e.ID, "renamed duplicate or empty edge id %q", old)
+		}
+		edgeSeen[e.ID] = true
+	}
+
+	repairEndpoints(sf, policy, nodeSeen, &report)
+	repairHierarchy(sf, policy, &report)
+	return report
+}
+
+// uniqueID returns base suffixed with the smallest counter not yet taken
+func uniqueID(taken map[string]bool, base, fallback string) string {
+	if base == "" {
+		base = fallback
+	}
+	for n := 2; ; n++ {
+		candidate := base + "-" + strconv.Itoa(n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// repairEndpoints drops edges to missing nodes or creates placeholders
+func repairEndpoints(sf *SceneFile, policy RepairPolicy, nodes map[string]bool, report *RepairReport) {
+	placeholderType := policy.PlaceholderType
+	if placeholderType == "" {
+		placeholderType = "placeholder"
+	}
+
+	kept := sf.Scene.Edges[:0]
+	for _, e := range sf.Scene.Edges {
+		missing := []string{}
+		if !nodes[e.Source] {
+			missing = append(missing, e.Source)
+		}
+		if !nodes[e.Target] && !e.IsExternal() {
+			missing = append(missing, e.Target)
+		}
+		if len(missing) == 0 {
+			kept = append(kept, e)
+			continue
+		}
+
+		if policy.MissingEndpoints != RepairCreatePlaceholders || containsString(missing, "") {
+			report.add(RepairDroppedEdge, e.ID, "dropped edge %s -> %s with missing endpoint %v", e.Source, e.Target, missing)
+			continue
+		}
+		for _, id := range missing {
+			if nodes[id] {
+				continue
+			}
+			sf.AddNode(SceneNode{
+				ID:         id,
+				Type:       placeholderType,
+				Name:       id,
+				Transform:  NewTransform(),
+				Status:     NodeStatusUnknown,
+				Extensions: map[string]interface{}{RepairPlaceholderExtension: true},
+			})
+			nodes[id] = true
+			report.add(RepairAddedPlaceholder, id, "created placeholder node for edge %s", e.ID)
+		}
+		kept = append(kept, e)
+	}
+	sf.Scene.Edges = kept
+}
+
+// repairHierarchy reconciles Parent and Children links
+func repairHierarchy(sf *SceneFile, policy RepairPolicy, report *RepairReport) {
+	index := make(map[string]int, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		index[sf.Scene.Nodes[i].ID] = i
+	}
+
+	if policy.Hierarchy == HierarchyFromChildren {
+		claimed := make(map[string]string, len(sf.Scene.Nodes))
+		for i := range sf.Scene.Nodes {
+			p := &sf.Scene.Nodes[i]
+			for _, c := range p.Children {
+				if _, ok := index[c]; ok && c != p.ID && claimed[c] == "" {
+					claimed[c] = p.ID
+				}
+			}
+		}
+		for i := range sf.Scene.Nodes {
+			n := &sf.Scene.Nodes[i]
+			if parent := claimed[n.ID]; parent != n.Parent {
+				report.add(RepairSetParent, n.ID, "set parent from %q to %q to match children lists", n.Parent, parent)
+				n.Parent = parent
+			}
+		}
+	}
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Parent == "" {
+			continue
+		}
+		if _, ok := index[n.Parent]; !ok || n.Parent == n.ID {
+			report.add(RepairClearedParent, n.ID, "cleared invalid parent %q", n.Parent)
+			n.Parent = ""
+		}
+	}
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		seen := map[string]bool{n.ID: true}
+		for p := n.Parent; p != ""; p = sf.Scene.Nodes[index[p]].Parent {
+			if seen[p] {
+				if p == n.ID {
+					report.add(RepairClearedParent, n.ID, "cleared parent %q to break hierarchy cycle", n.Parent)
+					n.Parent = ""
+				}
+				break
+			}
+			seen[p] = true
+		}
+	}
+
+	children := make(map[string][]string, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		if n.Parent != "" {
+			children[n.Parent] = append(children[n.Parent], n.ID)
+		}
+	}
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		want := children[n.ID]
+		wantSet := make(map[string]bool, len(want))
+		for _, c := range want {
+			wantSet[c] = true
+		}
+
+		kept := make([]string, 0, len(want))
+		have := make(map[string]bool, len(n.Children))
+		for _, c := range n.Children {
+			if wantSet[c] && !have[c] {
+				kept = append(kept, c)
+				have[c] = true
+			} else {
+				report.add(RepairRemovedChild, n.ID, "removed child %q whose parent does not match", c)
+			}
+		}
+		for _, c := range want {
+			if !have[c] {
+				kept = append(kept, c)
+				report.add(RepairAddedChild, n.ID, "added missing child %q", c)
+			}
+		}
+		if len(kept) == 0 && n.Children == nil {
+			continue
+		}
+		n.Children = kept
+	}
+}
@@ -0,0 +1,81 @@
+package starfleet
+
+import "testing"
+
+// newBrokenScene creates a scene with typical third-party export defects
+func newBrokenScene() SceneFile {
+	sf := NewSceneFile("Broken")
+	sf.AddNode(SceneNode{ID: "a", Transform: NewTransform(), Children: []string{"b", "ghost"}})
+	sf.AddNode(SceneNode{ID: "b", Parent: "a", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "b", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "c", Parent: "a", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "d", Parent: "missing", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "x", Parent: "y", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "y", Parent: "x", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "e", Source: "a", Target: "b"})
+	sf.AddEdge(SceneEdge{ID: "e", Source: "a", Target: "nowhere"})
+	return sf
+}
+
+// TestRepair_Drop tests repairing with dropped edges
+func TestRepair_Drop(t *testing.T) {
+	sf := newBrokenScene()
+	report := Repair(&sf, RepairPolicy{MissingEndpoints: RepairDropEdges})
+	if !report.Changed() {
+		t.Fatalf("Repair reported no actions")
+	}
+
+	if result := ValidateScene(&sf); !result.Valid {
+		t.Errorf("Repaired scene invalid: %v", result.Errors)
+	}
+	if sf.FindNode("b-2") == nil {
+		t.Errorf("Duplicate node not renamed")
+	}
+	if sf.GetEdgeCount() != 1 {
+		t.Errorf("Edge count mismatch: got %d, want 1", sf.GetEdgeCount())
+	}
+	if got := sf.FindNode("a").Children; !equalStrings(got, []string{"b", "c"}) {
+		t.Errorf("Children mismatch: got %v", got)
+	}
+	if sf.FindNode("d").Parent != "" {
+		t.Errorf("Dangling parent not cleared")
+	}
+	if sf.FindNode("x").Parent != "" && sf.FindNode("y").Parent != "" {
+		t.Errorf("Hierarchy cycle not broken")
+	}
+
+	kinds := map[RepairActionKind]int{}
+	for _, a := range report.Actions {
+		kinds[a.Kind]++
+	}
+	for _, kind := range []RepairActionKind{RepairRenamedNode, RepairRenamedEdge, RepairDroppedEdge, RepairClearedParent, RepairRemovedChild, RepairAddedChild} {
+		if kinds[kind] == 0 {
+			t.Errorf("Repair report missing %s action: %+v", kind, report.Actions)
+		}
+	}
+
+	if again := Repair(&sf, RepairPolicy{}); again.Changed() {
+		t.Errorf("Repair should be idempotent: %+v", again.Actions)
+	}
+}
+
+// TestRepair_Placeholders tests placeholder creation and children-first
+// hierarchy reconciliation
+func TestRepair_Placeholders(t *testing.T) {
+	sf := newBrokenScene()
+	Repair(&sf, RepairPolicy{MissingEndpoints: RepairCreatePlaceholders, Hierarchy: HierarchyFromChildren})
+
+	placeholder := sf.FindNode("nowhere")
+	if placeholder == nil || placeholder.Type != "placeholder" || placeholder.Extensions[RepairPlaceholderExtension] != true {
+		t.Fatalf("Placeholder mismatch: %+v", placeholder)
+	}
+	if sf.GetEdgeCount() != 2 {
+		t.Errorf("Edge count mismatch: got %d, want 2", sf.GetEdgeCount())
+	}
+	if sf.FindNode("c").Parent != "" {
+		t.Errorf("Children-first hierarchy should clear unclaimed parent")
+	}
+	if result := ValidateScene(&sf); !result.Valid {
+		t.Errorf("Repaired scene invalid: %v", result.Errors)
+	}
+}
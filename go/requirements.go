@@ -0,0 +1,228 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// VIEWER REQUIREMENTS
+// =============================================================================
+
+// ErrUnsupportedViewer is returned when a viewer cannot render a scene
+var ErrUnsupportedViewer = errors.New("unsupported viewer")
+
+// ViewerFeature names a rendering feature a viewer may or may not have, as
+// opposed to a Capability of the scene format
+type ViewerFeature string
+
+const (
+	ViewerFeatureInstancing     ViewerFeature = "instancing"
+	ViewerFeatureCustomShaders  ViewerFeature = "custom-shaders"
+	ViewerFeaturePostProcessing ViewerFeature = "post-processing"
+	ViewerFeatureWebXR          ViewerFeature = "webxr"
+)
+
+// FeatureRequirement declares that a scene uses a viewer feature. Scenes
+// cannot be shown without their required features; optional ones are
+// stripped for viewers that lack them by removing Extension, the extension
+// key holding the feature's data on the scene, its nodes and its edges.
+type FeatureRequirement struct {
+	Feature   ViewerFeature `json:"feature" validate:"required"`
+	Required  bool          `json:"required,omitempty"`
+	Extension string        `json:"extension,omitempty"`
+}
+
+// SceneRequirements describes what a viewer needs to render a scene.
+// Fallback is shown instead of the scene by viewers that cannot meet them.
+type SceneRequirements struct {
+	MinViewerVersion string               `json:"minViewerVersion,omitempty"`
+	Features         []FeatureRequirement `json:"features,omitempty"`
+	Fallback         string               `json:"fallback,omitempty"`
+}
+
+// Require declares that the scene uses a viewer feature, replacing an
+// earlier declaration of it
+func (sf *SceneFile) Require(req FeatureRequirement) {
+	if sf.Requirements == nil {
+		sf.Requirements = &SceneRequirements{}
+	}
+	for i, f := range sf.Requirements.Features {
+		if f.Feature == req.Feature {
+			sf.Requirements.Features[i] = req
+			return
+		}
+	}
+	sf.Requirements.Features = append(sf.Requirements.Features, req)
+}
+
+// ValidateRequirements checks the scene's viewer requirements
+func ValidateRequirements(sf *SceneFile) []string {
+	req := sf.Requirements
+	if req == nil {
+		return nil
+	}
+	var errs []string
+	if req.MinViewerVersion != "" {
+		if _, err := ParseSemVer(req.MinViewerVersion); err != nil {
+			errs = append(errs, fmt.Sprintf("Scene has an invalid minimum viewer version: %v", err))
+		}
+	}
+	seen := make(map[ViewerFeature]bool, len(req.Features))
+	for _, f := range req.Features {
+		switch {
+		case f.Feature == "":
+			errs = append(errs, "All feature requirements must name a feature")
+		case seen[f.Feature]:
+			errs = append(errs, fmt.Sprintf("Duplicate feature requirement: %s", f.Feature))
+		case !f.Required && f.Extension == "":
+			errs = append(errs, fmt.Sprintf("Optional feature %s must name the extension to strip without it", f.Feature))
+		}
+		seen[f.Feature] = true
+	}
+	return errs
+}
+
+// ViewerInfo describes a viewer asking for a scene
+type ViewerInfo struct {
+	Version  string          `json:"version"`
+	Features []ViewerFeature `json:"features,omitempty"`
+}
+
+// ParseViewerInfo reads a viewer's description from the values of the
+// ViewerVersionHeader and ViewerFeaturesHeader headers
+func ParseViewerInfo(version, features string) (ViewerInfo, error) {
+	info := ViewerInfo{Version: strings.TrimSpace(version)}
+	if _, err := ParseSemVer(info.Version); err != nil {
+		return info, err
+	}
+	for _, f := range strings.Split(features, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			info.Features = append(info.Features, ViewerFeature(f))
+		}
+	}
+	return info, nil
+}
+
+// ViewerNegotiation is the outcome of matching a scene against a viewer.
+// The action is accept when the viewer meets every requirement, downgrade
+// when the optional features in Disabled must be stripped first, and reject
+// when the viewer is too old or lacks the required features in Missing.
+type ViewerNegotiation struct {
+	Action   CompatibilityAction `json:"action" validate:"required"`
+	Missing  []ViewerFeature     `json:"missing,omitempty"`
+	Disabled []ViewerFeature     `json:"disabled,omitempty"`
+	Reason   string              `json:"reason,omitempty"`
+	Fallback string              `json:"fallback,omitempty"`
+}
+
+// NegotiateViewer decides how a scene can be given to a viewer. Scenes
+// without requirements are accepted by every viewer.
+func NegotiateViewer(sf *SceneFile, viewer ViewerInfo) (ViewerNegotiation, error) {
+	result := ViewerNegotiation{Action: CompatibilityAccept}
+	req := sf.Requirements
+	if req == nil {
+		return result, nil
+	}
+	v, err := ParseSemVer(viewer.Version)
+	if err != nil {
+		return result, err
+	}
+	var tooOld bool
+	if req.MinViewerVersion != "" {
+		minimum, err := ParseSemVer(req.MinViewerVersion)
+		if err != nil {
+			return result, err
+		}
+		tooOld = v.Compare(minimum) < 0
+	}
+	for _, f := range req.Features {
+		if slices.Contains(viewer.Features, f.Feature) {
+			continue
+		}
+		if f.Required {
+			result.Missing = append(result.Missing, f.Feature)
+		} else {
+			result.Disabled = append(result.Disabled, f.Feature)
+		}
+	}
+
+	switch {
+	case tooOld:
+		result.Action = CompatibilityReject
+		result.Reason = fmt.Sprintf("viewer %s is older than %s", viewer.Version, req.MinViewerVersion)
+	case len(result.Missing) > 0:
+		result.Action = CompatibilityReject
+		result.Reason = fmt.Sprintf("viewer lacks required features: %s", joinFeatures(result.Missing))
+	case len(result.Disabled) > 0:
+		result.Action = CompatibilityDowngrade
+		result.Reason = fmt.Sprintf("viewer lacks optional features: %s", joinFeatures(result.Disabled))
+	}
+	if result.Action == CompatibilityReject {
+		result.Fallback = req.Fallback
+	}
+	return result, nil
+}
+
+// StripFeatures returns a copy of the scene without the given optional
+// features: their extensions are removed from the scene, its nodes and its
+// edges, and so are their requirements. Required features are left alone.
+// The input scene is not modified.
+func StripFeatures(sf SceneFile, features []ViewerFeature) SceneFile {
+	if sf.Requirements == nil {
+		return sf
+	}
+	var drop []string
+	req := *sf.Requirements
+	req.Features = nil
+	for _, f := range sf.Requirements.Features {
+		if !f.Required && slices.Contains(features, f.Feature) {
+			drop = append(drop, f.Extension)
+			continue
+		}
+		req.Features = append(req.Features, f)
+	}
+	if len(drop) == 0 {
+		return sf
+	}
+
+	out := sf
+	out.Requirements = &req
+	out.Extensions = withoutExtensions(sf.Extensions, drop)
+	out.Scene.Nodes = slices.Clone(sf.Scene.Nodes)
+	for i := range out.Scene.Nodes {
+		out.Scene.Nodes[i].Extensions = withoutExtensions(out.Scene.Nodes[i].Extensions, drop)
+	}
+	out.Scene.Edges = slices.Clone(sf.Scene.Edges)
+	for i := range out.Scene.Edges {
+		out.Scene.Edges[i].Extensions = withoutExtensions(out.Scene.Edges[i].Extensions, drop)
+	}
+	return out
+}
+
+// withoutExtensions returns the extensions without the given keys, copying
+// the map only when a key is present
+func withoutExtensions(ext map[string]interface{}, keys []string) map[string]interface{} {
+	if !slices.ContainsFunc(keys, func(k string) bool { _, ok := ext[k]; return ok }) {
+		return ext
+	}
+	out := maps.Clone(ext)
+	for _, k := range keys {
+		delete(out, k)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func joinFeatures(features []ViewerFeature) string {
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
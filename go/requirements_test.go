@@ -0,0 +1,106 @@
+package starfleet
+
+import (
+	"slices"
+	"testing"
+)
+
+func newRequirementsScene() SceneFile {
+	sf := newDiffScene()
+	sf.Requirements = &SceneRequirements{MinViewerVersion: "1.2.0", Fallback: "Open the static preview"}
+	sf.Require(FeatureRequirement{Feature: ViewerFeatureInstancing, Required: true})
+	sf.Require(FeatureRequirement{Feature: ViewerFeatureCustomShaders, Extension: "shaders"})
+	sf.Extensions = map[string]interface{}{"shaders": []interface{}{"glow"}, "theme": "dark"}
+	sf.Scene.Nodes[0].Extensions = map[string]interface{}{"shaders": "glow"}
+	sf.Scene.Edges[0].Extensions = map[string]interface{}{"shaders": "pulse", "owner": "net"}
+	return sf
+}
+
+// TestValidateRequirements tests checking declared viewer requirements
+func TestValidateRequirements(t *testing.T) {
+	sf := newRequirementsScene()
+	if errs := ValidateRequirements(&sf); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	sf.Require(FeatureRequirement{Feature: ViewerFeatureInstancing})
+	if got := sf.Requirements.Features; len(got) != 2 || got[0].Required {
+		t.Errorf("Require should replace earlier declarations: got %+v", got)
+	}
+
+	sf.Requirements.MinViewerVersion = "two"
+	sf.Requirements.Features = append(sf.Requirements.Features, FeatureRequirement{Feature: ViewerFeatureCustomShaders, Required: true}, FeatureRequirement{})
+	if errs := ValidateRequirements(&sf); len(errs) != 4 {
+		t.Errorf("error count mismatch: got %v, want 4", errs)
+	}
+	if r := ValidateScene(&sf); r.Valid {
+		t.Error("scene with invalid requirements should be invalid")
+	}
+}
+
+// TestNegotiateViewer tests matching scenes against viewers
+func TestNegotiateViewer(t *testing.T) {
+	sf := newRequirementsScene()
+	tests := []struct {
+		name     string
+		viewer   ViewerInfo
+		action   CompatibilityAction
+		missing  []ViewerFeature
+		disabled []ViewerFeature
+	}{
+		{"capable", ViewerInfo{Version: "1.2.0", Features: []ViewerFeature{ViewerFeatureInstancing, ViewerFeatureCustomShaders}}, CompatibilityAccept, nil, nil},
+		{"no shaders", ViewerInfo{Version: "2.0.0", Features: []ViewerFeature{ViewerFeatureInstancing}}, CompatibilityDowngrade, nil, []ViewerFeature{ViewerFeatureCustomShaders}},
+		{"no instancing", ViewerInfo{Version: "2.0.0"}, CompatibilityReject, []ViewerFeature{ViewerFeatureInstancing}, []ViewerFeature{ViewerFeatureCustomShaders}},
+		{"too old", ViewerInfo{Version: "1.2.0-beta", Features: []ViewerFeature{ViewerFeatureInstancing, ViewerFeatureCustomShaders}}, CompatibilityReject, nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := NegotiateViewer(&sf, tt.viewer)
+		if err != nil {
+			t.Fatalf("%s: NegotiateViewer failed: %v", tt.name, err)
+		}
+		if got.Action != tt.action || !slices.Equal(got.Missing, tt.missing) || !slices.Equal(got.Disabled, tt.disabled) {
+			t.Errorf("%s: mismatch: got %+v", tt.name, got)
+		}
+		if rejected := got.Action == CompatibilityReject; rejected != (got.Fallback == "Open the static preview") {
+			t.Errorf("%s: fallback mismatch: got %q", tt.name, got.Fallback)
+		}
+	}
+
+	if _, err := NegotiateViewer(&sf, ViewerInfo{Version: "latest"}); err == nil {
+		t.Error("expected error for invalid viewer version")
+	}
+	plain := newDiffScene()
+	if got, err := NegotiateViewer(&plain, ViewerInfo{}); err != nil || got.Action != CompatibilityAccept {
+		t.Errorf("scenes without requirements should be accepted: got %+v, %v", got, err)
+	}
+
+	info, err := ParseViewerInfo(" 1.4.0 ", "instancing, webxr,")
+	if err != nil || info.Version != "1.4.0" || !slices.Equal(info.Features, []ViewerFeature{ViewerFeatureInstancing, ViewerFeatureWebXR}) {
+		t.Errorf("ParseViewerInfo mismatch: got %+v, %v", info, err)
+	}
+	if _, err := ParseViewerInfo("1.4", ""); err == nil {
+		t.Error("expected error for invalid viewer version")
+	}
+}
+
+// TestStripFeatures tests removing optional features a viewer lacks
+func TestStripFeatures(t *testing.T) {
+	sf := newRequirementsScene()
+	out := StripFeatures(sf, []ViewerFeature{ViewerFeatureCustomShaders, ViewerFeatureInstancing})
+
+	if got := out.Requirements.Features; len(got) != 1 || got[0].Feature != ViewerFeatureInstancing {
+		t.Errorf("requirements mismatch: got %+v", got)
+	}
+	if _, ok := out.Extensions["shaders"]; ok || out.Extensions["theme"] != "dark" {
+		t.Errorf("scene extensions mismatch: got %v", out.Extensions)
+	}
+	if out.Scene.Nodes[0].Extensions != nil || len(out.Scene.Edges[0].Extensions) != 1 {
+		t.Errorf("element extensions mismatch: got %v and %v", out.Scene.Nodes[0].Extensions, out.Scene.Edges[0].Extensions)
+	}
+	if len(sf.Requirements.Features) != 2 || sf.Scene.Nodes[0].Extensions["shaders"] != "glow" || sf.Extensions["shaders"] == nil {
+		t.Error("the input scene should not be modified")
+	}
+	if same := StripFeatures(sf, []ViewerFeature{ViewerFeatureWebXR}); same.Requirements != sf.Requirements {
+		t.Error("scenes without the features should be returned as is")
+	}
+}
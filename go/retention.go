@@ -0,0 +1,208 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// =============================================================================
+// RETENTION
+// =============================================================================
+
+// RetentionPolicy decides which revisions of a scene survive compaction. A
+// revision is kept when any rule selects it: KeepLast keeps the newest
+// revisions, and KeepHourly, KeepDaily and KeepWeekly keep the newest
+// revision of each of that many most recent hours, days and ISO weeks that
+// have revisions. The latest revision is always kept, and the zero policy
+// keeps everything.
+type RetentionPolicy struct {
+	KeepLast   int `json:"keepLast,omitempty" validate:"omitempty,min=0"`
+	KeepHourly int `json:"keepHourly,omitempty" validate:"omitempty,min=0"`
+	KeepDaily  int `json:"keepDaily,omitempty" validate:"omitempty,min=0"`
+	KeepWeekly int `json:"keepWeekly,omitempty" validate:"omitempty,min=0"`
+}
+
+// IsZero reports whether the policy keeps every revision
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepLast <= 0 && p.KeepHourly <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0
+}
+
+// Retain reports which revisions to keep, given their update times oldest
+// first. Periods are taken in UTC.
+func (p RetentionPolicy) Retain(updated []time.Time) []bool {
+	keep := make([]bool, len(updated))
+	if len(updated) == 0 {
+		return keep
+	}
+	if p.IsZero() {
+		for i := range keep {
+			keep[i] = true
+		}
+		return keep
+	}
+	keep[len(keep)-1] = true
+
+	rules := []struct {
+		count  int
+		period func(time.Time) string
+	}{
+		{p.KeepHourly, func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{p.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+	}
+	for _, rule := range rules {
+		seen := make(map[string]bool, rule.count)
+		for i := len(updated) - 1; i >= 0 && len(seen) < rule.count; i-- {
+			if period := rule.period(updated[i].UTC()); !seen[period] {
+				seen[period] = true
+				keep[i] = true
+			}
+		}
+	}
+	for i := len(updated) - 1; i >= 0 && i >= len(updated)-p.KeepLast; i-- {
+		keep[i] = true
+	}
+	return keep
+}
+
+// CompactionReport summarizes a compaction run
+type CompactionReport struct {
+	Scenes   int            `json:"scenes"`
+	Pruned   int            `json:"pruned"`
+	Retained int            `json:"retained"`
+	Details  map[string]int `json:"details,omitempty"`
+}
+
+// Compactor is implemented by stores that can prune revision history
+type Compactor interface {
+	// Compact applies a retention policy to every scene. Details maps the
+	// IDs of compacted scenes to the number of revisions pruned.
+	Compact(ctx context.Context, policy RetentionPolicy) (CompactionReport, error)
+}
+
+// Compact prunes the revisions of every scene that the policy does not
+// retain. Remaining revisions keep their numbers and are re-encoded so the
+// snapshot and delta layout holds. Reading a pruned revision returns
+// ErrRevisionNotFound.
+func (s *MemorySceneStore) Compact(ctx context.Context, policy RetentionPolicy) (CompactionReport, error) {
+	report := CompactionReport{Details: make(map[string]int)}
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.scenes))
+	for id := range s.scenes {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		pruned, retained, err := s.compactScene(id, policy)
+		if err != nil {
+			return report, err
+		}
+		report.Scenes++
+		report.Pruned += pruned
+		report.Retained += retained
+		if pruned > 0 {
+			report.Details[id] = pruned
+		}
+	}
+	return report, nil
+}
+
+// compactScene prunes one scene under the write lock
+func (s *MemorySceneStore) compactScene(id string, policy RetentionPolicy) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.scenes[id]
+	if !ok {
+		// Deleted since the scene list was taken
+		return 0, 0, nil
+	}
+	updated := make([]time.Time, len(stored.revisions))
+	for i, r := range stored.revisions {
+		updated[i] = r.updated
+	}
+	keep := policy.Retain(updated)
+	var retained []int64
+	for i, k := range keep {
+		if k {
+			retained = append(retained, stored.revisions[i].revision)
+		}
+	}
+	if len(retained) == len(stored.revisions) {
+		return 0, len(retained), nil
+	}
+
+	// Materialize survivors first, since deltas depend on pruned revisions
+	compacted := make([]storedRevision, 0, len(retained))
+	var prev *SceneFile
+	run := 0
+	for _, number := range retained {
+		rev, err := stored.revision(id, number)
+		if err != nil {
+			return 0, 0, fmt.Errorf("compact scene %s: %w", id, err)
+		}
+		r := storedRevision{revision: number, updated: rev.Updated}
+		if prev != nil && s.SnapshotEvery > 1 && run < s.SnapshotEvery-1 {
+			r.data, err = json.Marshal(ComputeDelta(prev, &rev.Scene))
+			r.delta = true
+			run++
+		} else {
+			r.data, err = json.Marshal(&rev.Scene)
+			run = 0
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("compact scene %s: %w", id, err)
+		}
+		compacted = append(compacted, r)
+		prev = &rev.Scene
+	}
+	pruned := len(stored.revisions) - len(compacted)
+	stored.revisions = compacted
+	return pruned, len(compacted), nil
+}
+
+// CompactionJob periodically applies a retention policy to a store
+type CompactionJob struct {
+	Store    Compactor
+	Policy   RetentionPolicy
+	Interval time.Duration
+	// OnRun, when set, receives the outcome of every run
+	OnRun func(CompactionReport, error)
+}
+
+// NewCompactionJob creates a job compacting store every interval
+func NewCompactionJob(store Compactor, policy RetentionPolicy, interval time.Duration) *CompactionJob {
+	return &CompactionJob{Store: store, Policy: policy, Interval: interval}
+}
+
+// Run compacts once immediately and then every Interval until ctx is done,
+// returning the context's error. Failed runs are reported to OnRun and
+// retried at the next tick.
+func (j *CompactionJob) Run(ctx context.Context) error {
+	if j.Interval <= 0 {
+		return fmt.Errorf("compaction job: interval must be positive")
+	}
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		report, err := j.Store.Compact(ctx, j.Policy)
+		if j.OnRun != nil && ctx.Err() == nil {
+			j.OnRun(report, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
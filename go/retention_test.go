@@ -0,0 +1,113 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRetentionPolicy_Retain tests the keep-last and periodic rules
+func TestRetentionPolicy_Retain(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		start,                       // day 1, 10:00
+		start.Add(30 * time.Minute), // day 1, 10:30
+		start.Add(90 * time.Minute), // day 1, 11:30
+		start.Add(24 * time.Hour),   // day 2, 10:00
+		start.Add(25 * time.Hour),   // day 2, 11:00
+		start.Add(26 * time.Hour),   // day 2, 12:00
+	}
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []bool
+	}{
+		{"zero", RetentionPolicy{}, []bool{true, true, true, true, true, true}},
+		{"last", RetentionPolicy{KeepLast: 2}, []bool{false, false, false, false, true, true}},
+		{"hourly", RetentionPolicy{KeepHourly: 4}, []bool{false, false, true, true, true, true}},
+		{"daily", RetentionPolicy{KeepDaily: 2}, []bool{false, false, true, false, false, true}},
+		{"weekly", RetentionPolicy{KeepWeekly: 5}, []bool{false, false, false, false, false, true}},
+		{"combined", RetentionPolicy{KeepLast: 1, KeepDaily: 2, KeepHourly: 1}, []bool{false, false, true, false, false, true}},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Retain(times); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s mismatch: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestMemorySceneStore_Compact tests pruning with delta-encoded history
+func TestMemorySceneStore_Compact(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySceneStore()
+	store.SnapshotEvery = 3
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return at }
+
+	sf := newDiffScene()
+	for i := 1; i <= 8; i++ {
+		sf.Metadata.Description = fmt.Sprintf("rev %d", i)
+		if _, err := store.Put(ctx, "live", sf, AnyRevision); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		at = at.Add(20 * time.Minute)
+	}
+	store.Put(ctx, "static", newDiffScene(), 0)
+
+	report, err := store.Compact(ctx, RetentionPolicy{KeepLast: 2, KeepHourly: 3})
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	// Hours 00, 01 and 02 keep revisions 3, 6 and 8; KeepLast adds 7
+	if report.Scenes != 2 || report.Pruned != 4 || report.Retained != 5 || !reflect.DeepEqual(report.Details, map[string]int{"live": 4}) {
+		t.Errorf("report mismatch: got %+v", report)
+	}
+	for rev, want := range map[int64]string{3: "rev 3", 6: "rev 6", 7: "rev 7", 8: "rev 8"} {
+		got, err := store.GetRevision(ctx, "live", rev)
+		if err != nil || got.Scene.Metadata.Description != want {
+			t.Errorf("revision %d mismatch: got %q, %v", rev, got.Scene.Metadata.Description, err)
+		}
+	}
+	if _, err := store.GetRevision(ctx, "live", 5); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("expected ErrRevisionNotFound for pruned revision, got %v", err)
+	}
+	if stats, _ := store.Stats("live"); stats.Revisions != 4 || stats.Snapshots != 2 {
+		t.Errorf("stats mismatch: got %+v", stats)
+	}
+
+	// Writes continue from the latest revision number
+	rev, err := store.Put(ctx, "live", sf, 8)
+	if err != nil || rev.Revision != 9 {
+		t.Errorf("Put after compaction mismatch: got %d, %v", rev.Revision, err)
+	}
+}
+
+// TestCompactionJob tests periodic compaction until cancellation
+func TestCompactionJob(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := NewMemorySceneStore()
+	sf := newDiffScene()
+	store.Put(ctx, "live", sf, 0)
+	store.Put(ctx, "live", sf, 1)
+
+	runs := 0
+	job := NewCompactionJob(store, RetentionPolicy{KeepLast: 1}, time.Millisecond)
+	job.OnRun = func(report CompactionReport, err error) {
+		runs++
+		if runs == 1 && (err != nil || report.Pruned != 1) {
+			t.Errorf("first run mismatch: got %+v, %v", report, err)
+		}
+		if runs == 2 {
+			cancel()
+		}
+	}
+	if err := job.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("run count mismatch: got %d, want 2", runs)
+	}
+}
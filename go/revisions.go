@@ -0,0 +1,189 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// =============================================================================
+// ELEMENT REVISIONS
+// =============================================================================
+
+// ErrStaleElement is returned when a write changes an element based on an
+// outdated element revision
+var ErrStaleElement = errors.New("stale element")
+
+// StaleElement is an element a write changed based on an outdated revision.
+// Removed is set for elements a write left out although they were added or
+// changed after the scene it was based on; Revision is then the one the
+// writer saw, zero when it never saw the element.
+type StaleElement struct {
+	Kind     string `json:"kind" validate:"required,oneof=node edge"`
+	ID       string `json:"id" validate:"required"`
+	Revision int64  `json:"revision"`
+	Current  int64  `json:"current"`
+	Removed  bool   `json:"removed,omitempty"`
+}
+
+// StaleElementError lists the elements of a scene a write was rejected for
+type StaleElementError struct {
+	ID       string         `json:"id"`
+	Elements []StaleElement `json:"elements"`
+}
+
+func (e *StaleElementError) Error() string {
+	parts := make([]string, len(e.Elements))
+	for i, el := range e.Elements {
+		if el.Removed {
+			parts[i] = fmt.Sprintf("%s %s is at revision %d, removed by a write based on %d", el.Kind, el.ID, el.Current, el.Revision)
+			continue
+		}
+		parts[i] = fmt.Sprintf("%s %s is at revision %d, write based on %d", el.Kind, el.ID, el.Current, el.Revision)
+	}
+	return fmt.Sprintf("%s: scene %s: %s", ErrStaleElement, e.ID, strings.Join(parts, "; "))
+}
+
+// Unwrap returns ErrStaleElement so callers can use errors.Is
+func (e *StaleElementError) Unwrap() error {
+	return ErrStaleElement
+}
+
+// HasRemovals reports whether any of the stale elements was removed
+func (e *StaleElementError) HasRemovals() bool {
+	for _, el := range e.Elements {
+		if el.Removed {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateRevisions numbers the nodes and edges of next, the scene about to
+// replace prev. New elements get revision 1, changed ones the previous
+// revision plus one and unchanged ones keep theirs, whatever next carried.
+//
+// An element of next that carries a revision older than its current one in
+// prev was edited from a stale copy. Unless it is unchanged, the write is
+// rejected with a *StaleElementError naming the scene id and next is left
+// partly numbered. Elements without a revision are never stale, so writers
+// that do not track revisions overwrite as before.
+//
+// Elements of prev that next leaves out are removed whatever their revision,
+// since next does not say which scene it was edited from. Writers that skip
+// the scene revision check should run CheckRemovals first, or a stale copy
+// drops elements added since it was read.
+func UpdateRevisions(id string, prev, next *SceneFile) error {
+	var stale []StaleElement
+	prevNodes := make(map[string]*SceneNode, len(prev.Scene.Nodes))
+	for i := range prev.Scene.Nodes {
+		prevNodes[prev.Scene.Nodes[i].ID] = &prev.Scene.Nodes[i]
+	}
+	for i := range next.Scene.Nodes {
+		node := &next.Scene.Nodes[i]
+		old, ok := prevNodes[node.ID]
+		if !ok {
+			node.Revision = 1
+			continue
+		}
+		current := old.Revision
+		changed := elementChanged(old, node)
+		if changed && node.Revision != 0 && node.Revision < current {
+			stale = append(stale, StaleElement{Kind: ElementNode, ID: node.ID, Revision: node.Revision, Current: current})
+		}
+		node.Revision = nextRevision(current, changed)
+	}
+	prevEdges := make(map[string]*SceneEdge, len(prev.Scene.Edges))
+	for i := range prev.Scene.Edges {
+		prevEdges[prev.Scene.Edges[i].ID] = &prev.Scene.Edges[i]
+	}
+	for i := range next.Scene.Edges {
+		edge := &next.Scene.Edges[i]
+		old, ok := prevEdges[edge.ID]
+		if !ok {
+			edge.Revision = 1
+			continue
+		}
+		current := old.Revision
+		changed := elementChanged(old, edge)
+		if changed && edge.Revision != 0 && edge.Revision < current {
+			stale = append(stale, StaleElement{Kind: ElementEdge, ID: edge.ID, Revision: edge.Revision, Current: current})
+		}
+		edge.Revision = nextRevision(current, changed)
+	}
+	if len(stale) > 0 {
+		return &StaleElementError{ID: id, Elements: stale}
+	}
+	return nil
+}
+
+// CheckRemovals returns a *StaleElementError listing the nodes and edges of
+// current that next leaves out although they were added or changed after
+// base, the scene next was edited from. With a nil base, as when the
+// writer's revision is unknown or no longer retained, every removal is
+// reported.
+func CheckRemovals(id string, base, current, next *SceneFile) error {
+	var stale []StaleElement
+	keptNodes := make(map[string]bool, len(next.Scene.Nodes))
+	for _, node := range next.Scene.Nodes {
+		keptNodes[node.ID] = true
+	}
+	for _, node := range current.Scene.Nodes {
+		if keptNodes[node.ID] {
+			continue
+		}
+		var seen *SceneNode
+		if base != nil {
+			seen = base.FindNode(node.ID)
+		}
+		if base == nil || seen == nil || seen.Revision != node.Revision {
+			var revision int64
+			if seen != nil {
+				revision = seen.Revision
+			}
+			stale = append(stale, StaleElement{Kind: ElementNode, ID: node.ID, Revision: revision, Current: node.Revision, Removed: true})
+		}
+	}
+	keptEdges := make(map[string]bool, len(next.Scene.Edges))
+	for _, edge := range next.Scene.Edges {
+		keptEdges[edge.ID] = true
+	}
+	for _, edge := range current.Scene.Edges {
+		if keptEdges[edge.ID] {
+			continue
+		}
+		var seen *SceneEdge
+		if base != nil {
+			seen = base.FindEdge(edge.ID)
+		}
+		if base == nil || seen == nil || seen.Revision != edge.Revision {
+			var revision int64
+			if seen != nil {
+				revision = seen.Revision
+			}
+			stale = append(stale, StaleElement{Kind: ElementEdge, ID: edge.ID, Revision: revision, Current: edge.Revision, Removed: true})
+		}
+	}
+	if len(stale) > 0 {
+		return &StaleElementError{ID: id, Elements: stale}
+	}
+	return nil
+}
+
+// elementChanged reports whether two encodings of an element differ in
+// anything but their revision
+func elementChanged(old, new interface{}) bool {
+	a, b := encodeObject(old), encodeObject(new)
+	delete(a, "revision")
+	delete(b, "revision")
+	return len(diffObjects("", a, b, nil)) > 0
+}
+
+// nextRevision returns an element's revision after a write. Elements
+// written before revisions were tracked start counting at 1.
+func nextRevision(current int64, changed bool) int64 {
+	if changed || current == 0 {
+		return current + 1
+	}
+	return current
+}
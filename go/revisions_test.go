@@ -0,0 +1,139 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestUpdateRevisions tests numbering new, changed and unchanged elements
+func TestUpdateRevisions(t *testing.T) {
+	var empty SceneFile
+	prev := newDiffScene()
+	if err := UpdateRevisions("s", &empty, &prev); err != nil {
+		t.Fatalf("UpdateRevisions failed: %v", err)
+	}
+	if prev.Scene.Nodes[0].Revision != 1 || prev.Scene.Edges[0].Revision != 1 {
+		t.Fatalf("new elements should start at 1: got %+v", prev.Scene.Nodes[0])
+	}
+
+	next := prev
+	next.Scene.Nodes = append([]SceneNode(nil), prev.Scene.Nodes...)
+	next.Scene.Nodes[1].Name = "Bravo"
+	next.Scene.Nodes[2].Revision = 0
+	next.AddNode(SceneNode{ID: "d", Type: "server", Name: "D", Transform: NewTransform()})
+	if err := UpdateRevisions("s", &prev, &next); err != nil {
+		t.Fatalf("UpdateRevisions failed: %v", err)
+	}
+	want := map[string]int64{"a": 1, "b": 2, "c": 1, "d": 1}
+	for _, node := range next.Scene.Nodes {
+		if node.Revision != want[node.ID] {
+			t.Errorf("node %s revision mismatch: got %d, want %d", node.ID, node.Revision, want[node.ID])
+		}
+	}
+
+	stale := next
+	stale.Scene.Nodes = append([]SceneNode(nil), next.Scene.Nodes...)
+	stale.Scene.Nodes[1].Revision = 1
+	stale.Scene.Nodes[1].Name = "Beta"
+	err := UpdateRevisions("s", &next, &stale)
+	var staleErr *StaleElementError
+	if !errors.As(err, &staleErr) || !errors.Is(err, ErrStaleElement) {
+		t.Fatalf("expected StaleElementError, got %v", err)
+	}
+	if len(staleErr.Elements) != 1 || staleErr.Elements[0] != (StaleElement{Kind: ElementNode, ID: "b", Revision: 1, Current: 2}) {
+		t.Errorf("stale elements mismatch: got %+v", staleErr.Elements)
+	}
+}
+
+// TestCheckRemovals tests that only elements the writer saw at their
+// current revision may be removed
+func TestCheckRemovals(t *testing.T) {
+	var empty SceneFile
+	base := newDiffScene()
+	if err := UpdateRevisions("s", &empty, &base); err != nil {
+		t.Fatalf("UpdateRevisions failed: %v", err)
+	}
+	current := base
+	current.Scene.Nodes = append([]SceneNode(nil), base.Scene.Nodes...)
+	current.Scene.Nodes[1].Revision = 2
+	current.AddNode(SceneNode{ID: "d", Type: "server", Name: "D", Transform: NewTransform(), Revision: 1})
+
+	next := base
+	next.Scene.Nodes = []SceneNode{base.Scene.Nodes[2]}
+	next.Scene.Edges = nil
+	err := CheckRemovals("s", &base, &current, &next)
+	var stale *StaleElementError
+	if !errors.As(err, &stale) || !stale.HasRemovals() {
+		t.Fatalf("expected StaleElementError with removals, got %v", err)
+	}
+	want := []StaleElement{
+		{Kind: ElementNode, ID: "b", Revision: 1, Current: 2, Removed: true},
+		{Kind: ElementNode, ID: "d", Revision: 0, Current: 1, Removed: true},
+	}
+	if !reflect.DeepEqual(stale.Elements, want) {
+		t.Errorf("stale elements mismatch: got %+v, want %+v", stale.Elements, want)
+	}
+
+	if err := CheckRemovals("s", &current, &current, &next); err != nil {
+		t.Errorf("removal from the current revision failed: %v", err)
+	}
+	if err := CheckRemovals("s", nil, &current, &current); err != nil {
+		t.Errorf("write without removals failed: %v", err)
+	}
+	if err := CheckRemovals("s", nil, &current, &next); err == nil {
+		t.Error("expected removals without a base to be rejected")
+	}
+}
+
+// TestMemorySceneStore_ElementRevisions tests that stored scenes carry
+// element revisions and stale element writes are rejected
+func TestMemorySceneStore_ElementRevisions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySceneStore()
+	sf := newDiffScene()
+	created, err := store.Put(ctx, "s", sf, 0)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if sf.Scene.Nodes[0].Revision != 0 {
+		t.Error("Put modified the caller's scene")
+	}
+	if created.Scene.Scene.Nodes[0].Revision != 1 {
+		t.Errorf("revision mismatch: got %d, want 1", created.Scene.Scene.Nodes[0].Revision)
+	}
+
+	// Alice and Bob edit separate nodes of the same scene without If-Match
+	alice, bob := created.Scene, created.Scene
+	alice.Scene.Nodes = append([]SceneNode(nil), created.Scene.Scene.Nodes...)
+	bob.Scene.Nodes = append([]SceneNode(nil), created.Scene.Scene.Nodes...)
+	alice.Scene.Nodes[0].Name = "Alpha"
+	bob.Scene.Nodes[1].Name = "Bravo"
+	if _, err := store.Put(ctx, "s", alice, AnyRevision); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	// Bob's copy would revert Alice's node
+	_, err = store.Put(ctx, "s", bob, AnyRevision)
+	var stale *StaleElementError
+	if !errors.As(err, &stale) || len(stale.Elements) != 1 || stale.Elements[0].ID != "a" {
+		t.Fatalf("expected node a to be stale, got %v", err)
+	}
+
+	// Patching only his node goes through
+	latest, _ := store.Get(ctx, "s")
+	patched, err := ApplyPatch(&latest.Scene, []PatchOp{
+		{Op: "test", Path: "/nodes/b/revision", Value: 1},
+		{Op: "replace", Path: "/nodes/b/name", Value: "Bravo"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	rev, err := store.Put(ctx, "s", patched, AnyRevision)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if a, b := rev.Scene.FindNode("a"), rev.Scene.FindNode("b"); a.Name != "Alpha" || a.Revision != 2 || b.Name != "Bravo" || b.Revision != 2 {
+		t.Errorf("merged nodes mismatch: got %+v, %+v", a, b)
+	}
+}
@@ -0,0 +1,216 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// ROLLING STATISTICS
+// =============================================================================
+
+// RollingWindow configures one window of a RollingAggregator. Name labels
+// the derived metrics and defaults to the duration, such as "5m".
+// Percentiles are in the range (0, 100].
+type RollingWindow struct {
+	Name        string
+	Duration    time.Duration
+	Percentiles []float64
+}
+
+// label returns the name used in derived metric keys
+func (w RollingWindow) label() string {
+	switch {
+	case w.Name != "":
+		return w.Name
+	case w.Duration%time.Hour == 0:
+		return fmt.Sprintf("%dh", w.Duration/time.Hour)
+	case w.Duration%time.Minute == 0:
+		return fmt.Sprintf("%dm", w.Duration/time.Minute)
+	default:
+		return strconv.FormatFloat(w.Duration.Seconds(), 'f', -1, 64) + "s"
+	}
+}
+
+// RollingStats summarizes the samples of a series within a window.
+// Percentiles are keyed by their rank, such as 95 for p95.
+type RollingStats struct {
+	Count       int
+	Min         float64
+	Max         float64
+	Avg         float64
+	Percentiles map[float64]float64
+}
+
+// RollingAggregator keeps recent samples of node metrics and summarizes them
+// over sliding windows. The summaries change slowly compared to raw samples,
+// which makes them suitable for driving node colors. It is safe for
+// concurrent use.
+type RollingAggregator struct {
+	Windows []RollingWindow
+
+	mu     sync.Mutex
+	series map[rollingKey][]rollingSample
+}
+
+type rollingKey struct {
+	node   string
+	metric string
+}
+
+type rollingSample struct {
+	at    time.Time
+	value float64
+}
+
+// NewRollingAggregator creates an aggregator over the given windows
+func NewRollingAggregator(windows ...RollingWindow) *RollingAggregator {
+	return &RollingAggregator{Windows: windows, series: make(map[rollingKey][]rollingSample)}
+}
+
+// Add records data points of a node metric. Non-numeric and non-finite
+// values are ignored; points may arrive out of order.
+func (a *RollingAggregator) Add(nodeID, metric string, points ...MetricsDataPoint) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.series == nil {
+		a.series = make(map[rollingKey][]rollingSample)
+	}
+	key := rollingKey{nodeID, metric}
+	samples := a.series[key]
+	for _, p := range points {
+		v, ok := toFloat64(p.Value)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		i, _ := slices.BinarySearchFunc(samples, p.Timestamp, func(s rollingSample, t time.Time) int {
+			return s.at.Compare(t)
+		})
+		samples = slices.Insert(samples, i, rollingSample{p.Timestamp, v})
+	}
+	a.series[key] = samples
+}
+
+// AddResults records every data point of metrics results
+func (a *RollingAggregator) AddResults(results []MetricsResult) {
+	for _, r := range results {
+		a.Add(r.NodeID, r.MetricName, r.DataPoints...)
+	}
+}
+
+// Stats summarizes a node metric over the window ending at now. It reports
+// false when the window holds no samples.
+func (a *RollingAggregator) Stats(nodeID, metric string, window RollingWindow, now time.Time) (RollingStats, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return rollingStats(a.series[rollingKey{nodeID, metric}], window, now)
+}
+
+// Apply prunes samples older than the longest window and writes the
+// statistics of every series into its node's Metrics under keys of the form
+// "<metric>.<window>.<stat>", for example "latency.5m.avg" or
+// "latency.5m.p95". Series of nodes not in the scene are left untouched.
+func (a *RollingAggregator) Apply(sf *SceneFile, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var longest time.Duration
+	for _, w := range a.Windows {
+		longest = max(longest, w.Duration)
+	}
+	cutoff := now.Add(-longest)
+	nodes := make(map[string]*SceneNode, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodes[sf.Scene.Nodes[i].ID] = &sf.Scene.Nodes[i]
+	}
+	for key, samples := range a.series {
+		i, _ := slices.BinarySearchFunc(samples, cutoff, func(s rollingSample, t time.Time) int {
+			return s.at.Compare(t)
+		})
+		if samples = samples[i:]; len(samples) == 0 {
+			delete(a.series, key)
+		} else {
+			a.series[key] = samples
+		}
+
+		node := nodes[key.node]
+		if node == nil {
+			continue
+		}
+		for _, w := range a.Windows {
+			prefix := key.metric + "." + w.label() + "."
+			stats, ok := rollingStats(samples, w, now)
+			if !ok {
+				for _, name := range rollingStatNames(w) {
+					delete(node.Metrics, prefix+name)
+				}
+				continue
+			}
+			if node.Metrics == nil {
+				node.Metrics = make(map[string]interface{})
+			}
+			node.Metrics[prefix+"min"] = stats.Min
+			node.Metrics[prefix+"max"] = stats.Max
+			node.Metrics[prefix+"avg"] = stats.Avg
+			node.Metrics[prefix+"count"] = stats.Count
+			for _, p := range w.Percentiles {
+				node.Metrics[prefix+percentileName(p)] = stats.Percentiles[p]
+			}
+		}
+	}
+}
+
+// rollingStats summarizes the samples in (now-window, now]
+func rollingStats(samples []rollingSample, w RollingWindow, now time.Time) (RollingStats, bool) {
+	from := now.Add(-w.Duration)
+	var values []float64
+	for _, s := range samples {
+		if s.at.After(from) && !s.at.After(now) {
+			values = append(values, s.value)
+		}
+	}
+	if len(values) == 0 {
+		return RollingStats{}, false
+	}
+	slices.Sort(values)
+	stats := RollingStats{Count: len(values), Min: values[0], Max: values[len(values)-1]}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	stats.Avg = sum / float64(len(values))
+	if len(w.Percentiles) > 0 {
+		stats.Percentiles = make(map[float64]float64, len(w.Percentiles))
+		for _, p := range w.Percentiles {
+			stats.Percentiles[p] = percentile(values, p)
+		}
+	}
+	return stats, true
+}
+
+// percentile returns the p-th percentile of sorted values by linear
+// interpolation between closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	rank := math.Max(0, math.Min(100, p)) / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// percentileName returns the derived metric suffix of a percentile, such as
+// "p95" or "p99.9"
+func percentileName(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// rollingStatNames lists the derived metric suffixes of a window
+func rollingStatNames(w RollingWindow) []string {
+	names := []string{"min", "max", "avg", "count"}
+	for _, p := range w.Percentiles {
+		names = append(names, percentileName(p))
+	}
+	return names
+}
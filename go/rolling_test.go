@@ -0,0 +1,76 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestRollingAggregator tests windowed statistics and pruning
+func TestRollingAggregator(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := NewRollingAggregator(
+		RollingWindow{Duration: time.Minute, Percentiles: []float64{50, 95}},
+		RollingWindow{Name: "long", Duration: 10 * time.Minute},
+	)
+	// Ten samples 1..10 over the last minute, delivered out of order, and
+	// one old outlier that only the long window sees
+	for i := 10; i >= 1; i-- {
+		a.Add("api", "latency", MetricsDataPoint{Timestamp: now.Add(-time.Duration(10-i) * 5 * time.Second), Value: float64(i)})
+	}
+	a.Add("api", "latency", MetricsDataPoint{Timestamp: now.Add(-5 * time.Minute), Value: 100.0}, MetricsDataPoint{Timestamp: now, Value: "n/a"})
+
+	stats, ok := a.Stats("api", "latency", a.Windows[0], now)
+	if !ok || stats.Count != 10 || stats.Min != 1 || stats.Max != 10 || stats.Avg != 5.5 {
+		t.Errorf("stats mismatch: got %+v", stats)
+	}
+	if stats.Percentiles[50] != 5.5 || math.Abs(stats.Percentiles[95]-9.55) > 1e-9 {
+		t.Errorf("percentiles mismatch: got %v", stats.Percentiles)
+	}
+
+	sf := NewSceneFile("Rolling")
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform()})
+	a.Apply(&sf, now)
+	m := sf.FindNode("api").Metrics
+	if m["latency.1m.avg"] != 5.5 || m["latency.1m.p95"] == nil || m["latency.long.max"] != 100.0 || m["latency.long.count"] != 11 {
+		t.Errorf("derived metrics mismatch: got %v", m)
+	}
+
+	// Once every sample is past the longest window the series is dropped
+	// and its derived metrics removed
+	later := now.Add(time.Hour)
+	a.Apply(&sf, later)
+	if _, ok := a.Stats("api", "latency", a.Windows[1], now); ok {
+		t.Error("expected samples to be pruned")
+	}
+	if len(m) != 0 {
+		t.Errorf("expected derived metrics of the dropped series to be removed: got %v", m)
+	}
+
+	a.Add("api", "latency", MetricsDataPoint{Timestamp: later, Value: 7.0})
+	a.Apply(&sf, later.Add(5*time.Minute))
+	if _, ok := m["latency.1m.avg"]; ok || m["latency.long.avg"] != 7.0 {
+		t.Errorf("stale window metrics mismatch: got %v", m)
+	}
+}
+
+// TestMetricsBinder_Rolling tests that overlapping deliveries are aggregated
+// once
+func TestMetricsBinder_Rolling(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sf := NewSceneFile("Binder")
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform()})
+	b := NewMetricsBinder(nil)
+	b.Rolling = NewRollingAggregator(RollingWindow{Duration: time.Minute})
+	b.now = func() time.Time { return now }
+
+	result := MetricsResult{NodeID: "api", MetricName: "rps", DataPoints: []MetricsDataPoint{
+		{Timestamp: now.Add(-time.Second), Value: 10.0},
+		{Timestamp: now, Value: 20.0},
+	}}
+	b.Apply(&sf, []MetricsResult{result})
+	b.Apply(&sf, []MetricsResult{result})
+	if m := sf.FindNode("api").Metrics; m["rps.1m.count"] != 2 || m["rps.1m.avg"] != 15.0 || m["rps"] != 20.0 {
+		t.Errorf("metrics mismatch: got %v", m)
+	}
+}
@@ -0,0 +1,268 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// METRIC ROLL-UPS
+// =============================================================================
+
+// RollupsExtension is the scene extension key holding roll-up rules
+const RollupsExtension = "rollups"
+
+// RollupFunc is how a roll-up combines the values of a group's leaves
+type RollupFunc string
+
+const (
+	RollupSum RollupFunc = "sum"
+	RollupAvg RollupFunc = "avg"
+	RollupMin RollupFunc = "min"
+	RollupMax RollupFunc = "max"
+	// RollupWeighted averages the values weighted by another metric of the
+	// same leaves, such as utilization weighted by capacity
+	RollupWeighted RollupFunc = "weighted"
+)
+
+// RollupRule aggregates a metric of the leaf nodes under each group node,
+// a node with children, onto the group. Leaves are nodes without children
+// at any depth below the group, so a region sees its hosts rather than the
+// roll-ups of its clusters, and averages stay true averages. Groups
+// without leaves holding the metric lose a value from an earlier run.
+type RollupRule struct {
+	Metric string     `json:"metric" validate:"required"`
+	Func   RollupFunc `json:"func" validate:"required,oneof=sum avg min max weighted"`
+	// Weight is the weight metric of RollupWeighted; leaves without it are
+	// left out
+	Weight string `json:"weight,omitempty"`
+	// As names the metric written on groups; empty means Metric
+	As string `json:"as,omitempty"`
+	// Selector, a selector expression, limits the groups rolled up to
+	Selector string `json:"selector,omitempty"`
+}
+
+// output returns the metric the rule writes
+func (r RollupRule) output() string {
+	if r.As != "" {
+		return r.As
+	}
+	return r.Metric
+}
+
+// validate checks a rule and returns its selector
+func (r RollupRule) validate() (Selector, error) {
+	if r.Metric == "" {
+		return Selector{}, fmt.Errorf("roll-up has no metric")
+	}
+	switch r.Func {
+	case RollupSum, RollupAvg, RollupMin, RollupMax:
+	case RollupWeighted:
+		if r.Weight == "" {
+			return Selector{}, fmt.Errorf("roll-up of %s is weighted but has no weight metric", r.Metric)
+		}
+	default:
+		return Selector{}, fmt.Errorf("roll-up of %s has unknown function %q", r.Metric, r.Func)
+	}
+	sel, err := ParseSelector(r.Selector)
+	if err != nil {
+		return Selector{}, fmt.Errorf("roll-up of %s: %w", r.Metric, err)
+	}
+	return sel, nil
+}
+
+// Rollups returns the scene's roll-up rules
+func (sf *SceneFile) Rollups() ([]RollupRule, error) {
+	v, ok := sf.Extensions[RollupsExtension]
+	if !ok {
+		return nil, nil
+	}
+	var rules []RollupRule
+	if err := roundTripJSON(v, &rules); err != nil {
+		return nil, fmt.Errorf("roll-ups: %w", err)
+	}
+	return rules, nil
+}
+
+// SetRollups stores roll-up rules with the scene, replacing its rules;
+// none removes them
+func (sf *SceneFile) SetRollups(rules ...RollupRule) error {
+	for _, r := range rules {
+		if _, err := r.validate(); err != nil {
+			return err
+		}
+	}
+	if len(rules) == 0 {
+		delete(sf.Extensions, RollupsExtension)
+		return nil
+	}
+	sf.Extensions = withExtension(sf.Extensions, RollupsExtension, rules)
+	return nil
+}
+
+// ApplyRollups computes the scene's roll-up rules and returns how many
+// group metrics it wrote. Call it whenever leaf metrics change;
+// MetricsBinder does after every update.
+func ApplyRollups(sf *SceneFile) (int, error) {
+	rules, err := sf.Rollups()
+	if err != nil {
+		return 0, err
+	}
+	return ApplyRollupRules(sf, rules...)
+}
+
+// ApplyRollupRules computes roll-up rules over the scene, whether or not it
+// stores them. Rules apply in order, so a later rule writing the same
+// metric wins.
+func ApplyRollupRules(sf *SceneFile, rules ...RollupRule) (int, error) {
+	if len(rules) == 0 {
+		return 0, nil
+	}
+	selectors := make([]Selector, len(rules))
+	for i, r := range rules {
+		sel, err := r.validate()
+		if err != nil {
+			return 0, err
+		}
+		selectors[i] = sel
+	}
+
+	// leaves lists the leaf indexes under each group, found by walking up
+	// from every leaf; a cycle stops the walk where it closes
+	index := make(map[string]int, len(sf.Scene.Nodes))
+	hasChildren := make([]bool, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		index[sf.Scene.Nodes[i].ID] = i
+	}
+	for i := range sf.Scene.Nodes {
+		if j, ok := index[sf.Scene.Nodes[i].Parent]; ok && j != i {
+			hasChildren[j] = true
+		}
+	}
+	leaves := make(map[int][]int)
+	for i := range sf.Scene.Nodes {
+		if hasChildren[i] {
+			continue
+		}
+		seen := map[int]bool{i: true}
+		for j, ok := index[sf.Scene.Nodes[i].Parent]; ok && !seen[j]; j, ok = index[sf.Scene.Nodes[j].Parent] {
+			seen[j] = true
+			leaves[j] = append(leaves[j], i)
+		}
+	}
+
+	written := 0
+	for g, members := range leaves {
+		group := &sf.Scene.Nodes[g]
+		for k, r := range rules {
+			if !selectors[k].MatchNode(group) {
+				continue
+			}
+			value, ok := r.aggregate(sf.Scene.Nodes, members)
+			if !ok {
+				delete(group.Metrics, r.output())
+				continue
+			}
+			if group.Metrics == nil {
+				group.Metrics = make(map[string]interface{})
+			}
+			group.Metrics[r.output()] = value
+			written++
+		}
+	}
+	return written, nil
+}
+
+// aggregate combines the rule's metric over the given leaves, and reports
+// false when none has a finite value for it
+func (r RollupRule) aggregate(nodes []SceneNode, members []int) (float64, bool) {
+	var sum, weights float64
+	lo, hi := math.Inf(1), math.Inf(-1)
+	n := 0
+	for _, i := range members {
+		v, ok := toFloat64(nodes[i].Metrics[r.Metric])
+		if !ok || !isFinite(v) {
+			continue
+		}
+		if r.Func == RollupWeighted {
+			w, ok := toFloat64(nodes[i].Metrics[r.Weight])
+			if !ok || !isFinite(w) || w < 0 {
+				continue
+			}
+			sum += v * w
+			weights += w
+		} else {
+			sum += v
+		}
+		lo, hi = min(lo, v), max(hi, v)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	switch r.Func {
+	case RollupAvg:
+		return sum / float64(n), true
+	case RollupMin:
+		return lo, true
+	case RollupMax:
+		return hi, true
+	case RollupWeighted:
+		if weights == 0 {
+			return 0, false
+		}
+		return sum / weights, true
+	}
+	return sum, true
+}
+
+// ValidateRollups checks the scene's roll-up rules
+func ValidateRollups(sf *SceneFile) []string {
+	rules, err := sf.Rollups()
+	if err != nil {
+		return []string{fmt.Sprintf("Scene has invalid roll-ups: %v", err)}
+	}
+	var errs []string
+	for _, r := range rules {
+		if _, err := r.validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("Invalid %v", err))
+		}
+	}
+	return errs
+}
+
+// rollupStage applies roll-up rules: {"rules": [...]} adds rules to those
+// the scene stores, and {"store": true} also stores them with the scene
+func rollupStage(options json.RawMessage) (PipelineStage, error) {
+	var opts struct {
+		Rules []RollupRule `json:"rules"`
+		Store bool         `json:"store"`
+	}
+	if err := decodeStageOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	for _, r := range opts.Rules {
+		if _, err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return PipelineStageFunc(func(ctx context.Context, sf *SceneFile) ([]string, error) {
+		rules, err := sf.Rollups()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, opts.Rules...)
+		if opts.Store {
+			if err := sf.SetRollups(rules...); err != nil {
+				return nil, err
+			}
+		}
+		written, err := ApplyRollupRules(sf, rules...)
+		if err != nil || written == 0 {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("rolled up %d group metrics", written)}, nil
+	}), nil
+}
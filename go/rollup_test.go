@@ -0,0 +1,133 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// newRollupScene returns a region holding two clusters of hosts
+func newRollupScene() SceneFile {
+	sf := SceneFile{Version: "0.2.0"}
+	sf.AddNode(SceneNode{ID: "region", Type: "region"})
+	sf.AddNode(SceneNode{ID: "c1", Type: "cluster", Parent: "region"})
+	sf.AddNode(SceneNode{ID: "c2", Type: "cluster", Parent: "region"})
+	sf.AddNode(SceneNode{ID: "h1", Type: "host", Parent: "c1", Metrics: map[string]interface{}{"cpu": 0.2, "cores": 8}})
+	sf.AddNode(SceneNode{ID: "h2", Type: "host", Parent: "c1", Metrics: map[string]interface{}{"cpu": 0.8, "cores": 24}})
+	sf.AddNode(SceneNode{ID: "h3", Type: "host", Parent: "c2", Metrics: map[string]interface{}{"cpu": 0.5, "cores": 16}})
+	return sf
+}
+
+// TestApplyRollupRules tests aggregating leaf metrics onto every group
+func TestApplyRollupRules(t *testing.T) {
+	sf := newRollupScene()
+	rules := []RollupRule{
+		{Metric: "cores", Func: RollupSum},
+		{Metric: "cpu", Func: RollupAvg, As: "cpu.avg"},
+		{Metric: "cpu", Func: RollupMax, As: "cpu.max", Selector: "type=region"},
+		{Metric: "cpu", Func: RollupWeighted, Weight: "cores", As: "cpu.weighted"},
+	}
+	written, err := ApplyRollupRules(&sf, rules...)
+	if err != nil {
+		t.Fatalf("ApplyRollupRules failed: %v", err)
+	}
+	if written != 10 {
+		t.Errorf("written mismatch: got %d, want 10", written)
+	}
+	tests := []struct {
+		node, metric string
+		want         float64
+	}{
+		{"region", "cores", 48},
+		{"c1", "cores", 32},
+		{"region", "cpu.avg", 0.5},
+		{"c1", "cpu.avg", 0.5},
+		{"region", "cpu.max", 0.8},
+		{"c1", "cpu.weighted", (0.2*8 + 0.8*24) / 32},
+		{"region", "cpu.weighted", (0.2*8 + 0.8*24 + 0.5*16) / 48},
+	}
+	for _, tt := range tests {
+		got, _ := toFloat64(sf.FindNode(tt.node).Metrics[tt.metric])
+		if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s %s mismatch: got %v, want %v", tt.node, tt.metric, got, tt.want)
+		}
+	}
+	if _, ok := sf.FindNode("c1").Metrics["cpu.max"]; ok {
+		t.Error("selector did not limit groups")
+	}
+
+	// Recomputing must not count earlier roll-ups, and groups losing their
+	// values lose the roll-up
+	delete(sf.FindNode("h3").Metrics, "cores")
+	delete(sf.FindNode("h3").Metrics, "cpu")
+	if _, err := ApplyRollupRules(&sf, rules...); err != nil {
+		t.Fatalf("ApplyRollupRules failed: %v", err)
+	}
+	if got := sf.FindNode("region").Metrics["cores"]; got != 32.0 {
+		t.Errorf("region cores mismatch: got %v, want 32", got)
+	}
+	if _, ok := sf.FindNode("c2").Metrics["cores"]; ok {
+		t.Error("stale roll-up kept")
+	}
+
+	if _, err := ApplyRollupRules(&sf, RollupRule{Metric: "cpu", Func: RollupWeighted}); err == nil {
+		t.Error("expected error for missing weight")
+	}
+	if _, err := ApplyRollupRules(&sf, RollupRule{Metric: "cpu", Func: "median"}); err == nil {
+		t.Error("expected error for unknown function")
+	}
+}
+
+// TestRollups_Stored tests storing rules with the scene, validating them
+// and recomputing them when metrics are bound
+func TestRollups_Stored(t *testing.T) {
+	sf := newRollupScene()
+	if err := sf.SetRollups(RollupRule{Metric: "cpu", Func: RollupMax}); err != nil {
+		t.Fatalf("SetRollups failed: %v", err)
+	}
+	if err := sf.SetRollups(RollupRule{Metric: "cpu", Func: RollupAvg, Selector: "type="}); err == nil {
+		t.Error("expected error for invalid selector")
+	}
+	if errs := ValidateRollups(&sf); len(errs) != 0 {
+		t.Errorf("ValidateRollups mismatch: got %v, want none", errs)
+	}
+
+	now := time.Now()
+	b := NewMetricsBinder(nil)
+	b.Apply(&sf, []MetricsResult{{NodeID: "h3", MetricName: "cpu", DataPoints: []MetricsDataPoint{{Timestamp: now, Value: 0.9}}}})
+	if got := sf.FindNode("region").Metrics["cpu"]; got != 0.9 {
+		t.Errorf("region cpu mismatch: got %v, want 0.9", got)
+	}
+
+	sf.Extensions[RollupsExtension] = []interface{}{map[string]interface{}{"metric": "cpu", "func": "median"}}
+	if errs := ValidateRollups(&sf); len(errs) != 1 {
+		t.Errorf("ValidateRollups mismatch: got %v, want one error", errs)
+	}
+	if err := sf.SetRollups(); err != nil || sf.Extensions[RollupsExtension] != nil {
+		t.Errorf("SetRollups did not remove rules: %v", err)
+	}
+}
+
+// TestRollupStage tests rolling up from a pipeline stage
+func TestRollupStage(t *testing.T) {
+	sf := newRollupScene()
+	options := json.RawMessage(`{"rules": [{"metric": "cores", "func": "sum"}], "store": true}`)
+	stage, err := rollupStage(options)
+	if err != nil {
+		t.Fatalf("rollupStage failed: %v", err)
+	}
+	notes, err := stage.Run(context.Background(), &sf)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(notes) != 1 || sf.FindNode("region").Metrics["cores"] != 48.0 {
+		t.Errorf("stage mismatch: got notes %v, region %v", notes, sf.FindNode("region").Metrics)
+	}
+	if rules, _ := sf.Rollups(); len(rules) != 1 {
+		t.Errorf("stored rules mismatch: got %v", rules)
+	}
+	if _, err := rollupStage(json.RawMessage(`{"rules": [{"metric": "cpu"}]}`)); err == nil {
+		t.Error("expected error for missing function")
+	}
+}
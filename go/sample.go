@@ -0,0 +1,283 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+)
+
+// =============================================================================
+// SCENE SAMPLING
+// =============================================================================
+
+// SampleStrategy selects the nodes SampleScene keeps
+type SampleStrategy string
+
+const (
+	// SampleByCentrality keeps the best-connected nodes
+	SampleByCentrality SampleStrategy = "centrality"
+	// SampleByGroup keeps nodes from every group in proportion to its size,
+	// best-connected first
+	SampleByGroup SampleStrategy = "group"
+	// SampleRandom keeps a pseudo-random selection that is stable for a
+	// given set of node IDs
+	SampleRandom SampleStrategy = "random"
+)
+
+// SampleExtension is the scene extension key describing the scene a sample
+// was taken from, and the node extension key holding the number of nodes an
+// aggregate node stands for
+const SampleExtension = "sample"
+
+// SampleInfo describes the scene a sample was taken from
+type SampleInfo struct {
+	Strategy SampleStrategy `json:"strategy"`
+	Nodes    int            `json:"nodes"`
+	Edges    int            `json:"edges"`
+}
+
+// SampleAggregateType is the node type of aggregate nodes in samples
+const SampleAggregateType = "aggregate"
+
+// sampleOtherGroup collects the dropped nodes of groups beyond the
+// aggregate budget
+const sampleOtherGroup = "other"
+
+// SampleScene returns a reduced copy of the scene with at most maxNodes
+// nodes, for previews and thumbnails of scenes too large to render. The
+// strategy picks the nodes to keep; the ancestors of kept nodes are kept
+// too so the hierarchy stays intact. The other nodes are replaced by
+// aggregate nodes, one per parent or, for top-level nodes, per type: each
+// sits at the centroid of its members with their worst status and mean
+// numeric metrics, and records the member count under SampleExtension.
+// Edges to dropped nodes are rerouted to their aggregates and parallel
+// edges merged with AggregateParallelEdges. Scenes within the budget are
+// returned unchanged; sf is not modified.
+func SampleScene(sf *SceneFile, maxNodes int, strategy SampleStrategy) (SceneFile, error) {
+	if maxNodes <= 0 {
+		return SceneFile{}, errors.New("sample: maxNodes must be positive")
+	}
+	switch strategy {
+	case SampleByCentrality, SampleByGroup, SampleRandom:
+	default:
+		return SceneFile{}, fmt.Errorf("sample: unknown strategy %q", strategy)
+	}
+	out := *sf
+	if len(sf.Scene.Nodes) <= maxNodes {
+		return out, nil
+	}
+	nodes := sf.Scene.Nodes
+	index := make(map[string]int, len(nodes))
+	for i := range nodes {
+		index[nodes[i].ID] = i
+	}
+	degree := make([]int, len(nodes))
+	for _, e := range sf.Scene.Edges {
+		if i, ok := index[e.Source]; ok {
+			degree[i]++
+		}
+		if i, ok := index[e.Target]; ok {
+			degree[i]++
+		}
+	}
+	groups := make(map[string][]int)
+	for i := range nodes {
+		key := sampleGroup(&nodes[i])
+		groups[key] = append(groups[key], i)
+	}
+
+	// Reserve room for the aggregates before choosing nodes to keep
+	maxAggregates := max(1, maxNodes/4)
+	keep := make([]bool, len(nodes))
+	kept := 0
+	budget := maxNodes - min(len(groups), maxAggregates)
+	for _, i := range sampleOrder(nodes, degree, groups, strategy) {
+		if kept >= budget {
+			break
+		}
+		var chain []int
+		for j, ok := i, true; ok && !keep[j] && !slices.Contains(chain, j); j, ok = index[nodes[j].Parent] {
+			chain = append(chain, j)
+		}
+		if kept+len(chain) > budget {
+			continue
+		}
+		for _, j := range chain {
+			keep[j] = true
+		}
+		kept += len(chain)
+	}
+
+	// Group the dropped nodes, folding the smallest groups into one when
+	// there are more than the aggregate budget
+	dropped := make(map[string][]int)
+	for key, members := range groups {
+		for _, i := range members {
+			if !keep[i] {
+				dropped[key] = append(dropped[key], i)
+			}
+		}
+	}
+	keys := make([]string, 0, len(dropped))
+	for key := range dropped {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if len(dropped[keys[a]]) != len(dropped[keys[b]]) {
+			return len(dropped[keys[a]]) > len(dropped[keys[b]])
+		}
+		return keys[a] < keys[b]
+	})
+	if len(keys) > maxAggregates {
+		var other []int
+		for _, key := range keys[maxAggregates-1:] {
+			other = append(other, dropped[key]...)
+			delete(dropped, key)
+		}
+		keys = append(keys[:maxAggregates-1], sampleOtherGroup)
+		dropped[sampleOtherGroup] = other
+	}
+
+	// Kept nodes come first in their original order, then the aggregates
+	mapped := make(map[string]string, len(nodes))
+	out.Scene.Nodes = make([]SceneNode, 0, kept+len(keys))
+	for i := range nodes {
+		if keep[i] {
+			mapped[nodes[i].ID] = nodes[i].ID
+			out.Scene.Nodes = append(out.Scene.Nodes, nodes[i])
+		}
+	}
+	aggregates := make(map[string][]string)
+	for _, key := range keys {
+		agg := aggregateNode(nodes, dropped[key], "sample:"+key)
+		agg.Type = SampleAggregateType
+		agg.Extensions = map[string]interface{}{SampleExtension: len(dropped[key])}
+		if parent, ok := index[nodes[dropped[key][0]].Parent]; ok && key != sampleOtherGroup && keep[parent] {
+			agg.Parent = nodes[parent].ID
+			aggregates[agg.Parent] = append(aggregates[agg.Parent], agg.ID)
+		}
+		for _, i := range dropped[key] {
+			mapped[nodes[i].ID] = agg.ID
+		}
+		out.Scene.Nodes = append(out.Scene.Nodes, agg)
+	}
+	for i := range out.Scene.Nodes[:kept] {
+		n := &out.Scene.Nodes[i]
+		if len(n.Children) == 0 {
+			continue
+		}
+		children := make([]string, 0, len(n.Children))
+		for _, id := range n.Children {
+			if mapped[id] == id {
+				children = append(children, id)
+			}
+		}
+		n.Children = append(children, aggregates[n.ID]...)
+	}
+
+	out.Scene.Edges = make([]SceneEdge, 0, len(sf.Scene.Edges))
+	for _, e := range sf.Scene.Edges {
+		source, target := mapped[e.Source], mapped[e.Target]
+		if source == "" || target == "" || source == target {
+			continue
+		}
+		if source != e.Source || target != e.Target {
+			e.Source, e.Target = source, target
+			e.SourcePort, e.TargetPort, e.Waypoints = "", "", nil
+		}
+		out.Scene.Edges = append(out.Scene.Edges, e)
+	}
+	AggregateParallelEdges(&out)
+	out.Extensions = withExtension(sf.Extensions, SampleExtension, SampleInfo{
+		Strategy: strategy, Nodes: len(nodes), Edges: len(sf.Scene.Edges),
+	})
+	return out, nil
+}
+
+// sampleGroup returns the key of the group a node is aggregated into
+func sampleGroup(node *SceneNode) string {
+	if node.Parent != "" {
+		return "parent:" + node.Parent
+	}
+	return "type:" + node.Type
+}
+
+// sampleOrder returns node indexes in the order a strategy keeps them
+func sampleOrder(nodes []SceneNode, degree []int, groups map[string][]int, strategy SampleStrategy) []int {
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	byDegree := func(a, b int) bool {
+		if degree[a] != degree[b] {
+			return degree[a] > degree[b]
+		}
+		return nodes[a].ID < nodes[b].ID
+	}
+	switch strategy {
+	case SampleByGroup:
+		// A node's rank within its group as a fraction of the group size
+		// interleaves groups in proportion to their sizes
+		rank := make([]float64, len(nodes))
+		for _, members := range groups {
+			members = slices.Clone(members)
+			sort.Slice(members, func(a, b int) bool { return byDegree(members[a], members[b]) })
+			for r, i := range members {
+				rank[i] = float64(r) / float64(len(members))
+			}
+		}
+		sort.Slice(order, func(a, b int) bool {
+			i, j := order[a], order[b]
+			if rank[i] != rank[j] {
+				return rank[i] < rank[j]
+			}
+			return byDegree(i, j)
+		})
+	case SampleRandom:
+		hash := make([]uint64, len(nodes))
+		for i := range nodes {
+			h := fnv.New64a()
+			h.Write([]byte(nodes[i].ID))
+			hash[i] = h.Sum64()
+		}
+		sort.Slice(order, func(a, b int) bool { return hash[order[a]] < hash[order[b]] })
+	default:
+		sort.Slice(order, func(a, b int) bool { return byDegree(order[a], order[b]) })
+	}
+	return order
+}
+
+// aggregateNode builds a node standing for the given members, at their
+// centroid with their worst status and mean numeric metrics
+func aggregateNode(nodes []SceneNode, members []int, id string) SceneNode {
+	agg := SceneNode{
+		ID:        id,
+		Name:      fmt.Sprintf("%d nodes", len(members)),
+		Transform: NewTransform(),
+	}
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, i := range members {
+		n := &nodes[i]
+		agg.Transform.Position = agg.Transform.Position.Add(n.Transform.Position)
+		if statusSeverity(n.Status) > statusSeverity(agg.Status) || agg.Status == "" {
+			agg.Status = n.Status
+		}
+		for name, v := range n.Metrics {
+			if f, ok := toFloat64(v); ok {
+				sums[name] += f
+				counts[name]++
+			}
+		}
+	}
+	agg.Transform.Position = agg.Transform.Position.Scale(1 / float64(len(members)))
+	if len(sums) > 0 {
+		agg.Metrics = make(map[string]interface{}, len(sums))
+		for name, sum := range sums {
+			agg.Metrics[name] = sum / float64(counts[name])
+		}
+	}
+	return agg
+}
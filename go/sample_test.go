@@ -0,0 +1,101 @@
+package starfleet
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newSampleScene builds a hub-and-spoke scene: two racks of servers whose
+// first server talks to every other, and a flat group of clients
+func newSampleScene() SceneFile {
+	sf := NewSceneFile("Sample")
+	for r := 0; r < 2; r++ {
+		rack := fmt.Sprintf("rack%d", r)
+		var children []string
+		for i := 0; i < 10; i++ {
+			id := fmt.Sprintf("%s-s%d", rack, i)
+			children = append(children, id)
+			node := SceneNode{ID: id, Type: "server", Name: id, Parent: rack, Transform: NewTransformWithPosition(float64(i), 0, 0)}
+			node.Metrics = map[string]interface{}{"cpu": float64(i * 10)}
+			if i == 9 {
+				node.Status = NodeStatusCritical
+			}
+			sf.AddNode(node)
+			if i > 0 {
+				sf.AddEdge(SceneEdge{ID: id + "-hub", Source: id, Target: rack + "-s0"})
+			}
+		}
+		sf.AddNode(SceneNode{ID: rack, Type: "rack", Name: rack, Children: children, Transform: NewTransform()})
+	}
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("client%d", i)
+		sf.AddNode(SceneNode{ID: id, Type: "client", Name: id, Transform: NewTransform()})
+		sf.AddEdge(SceneEdge{ID: id + "-api", Source: id, Target: "rack0-s0"})
+	}
+	return sf
+}
+
+// TestSampleScene tests reducing a scene while keeping hubs and hierarchy
+func TestSampleScene(t *testing.T) {
+	sf := newSampleScene()
+	for _, strategy := range []SampleStrategy{SampleByCentrality, SampleByGroup, SampleRandom} {
+		out, err := SampleScene(&sf, 12, strategy)
+		if err != nil {
+			t.Fatalf("%s: SampleScene failed: %v", strategy, err)
+		}
+		if len(out.Scene.Nodes) > 12 {
+			t.Errorf("%s: node count mismatch: got %d, want at most 12", strategy, len(out.Scene.Nodes))
+		}
+		if result := ValidateScene(&out); !result.Valid {
+			t.Errorf("%s: sample is invalid: %v", strategy, result.Errors)
+		}
+		members := 0
+		for _, n := range out.Scene.Nodes {
+			if n.Type == SampleAggregateType {
+				members += n.Extensions[SampleExtension].(int)
+			} else {
+				members++
+			}
+			if n.Parent != "" && out.FindNode(n.Parent) == nil {
+				t.Errorf("%s: node %s lost its parent %s", strategy, n.ID, n.Parent)
+			}
+		}
+		if members != len(sf.Scene.Nodes) {
+			t.Errorf("%s: member count mismatch: got %d, want %d", strategy, members, len(sf.Scene.Nodes))
+		}
+	}
+
+	out, _ := SampleScene(&sf, 12, SampleByCentrality)
+	if out.FindNode("rack0-s0") == nil || out.FindNode("rack0") == nil {
+		t.Error("the hub and its rack should be kept")
+	}
+	agg := out.FindNode("sample:parent:rack1")
+	if agg == nil || agg.Status != NodeStatusCritical || agg.Name == "" {
+		t.Fatalf("rack1 aggregate mismatch: got %+v", agg)
+	}
+	clients := out.FindNode("sample:type:client")
+	if clients == nil || clients.Metrics != nil {
+		t.Fatalf("client aggregate mismatch: got %+v", clients)
+	}
+	for _, e := range out.Scene.Edges {
+		if e.Source == clients.ID && e.Weight != float64(clients.Extensions[SampleExtension].(int)) {
+			t.Errorf("rerouted client edges should merge: got %+v", e)
+		}
+	}
+	if info, ok := out.Extensions[SampleExtension].(SampleInfo); !ok || info.Nodes != 42 {
+		t.Errorf("sample info mismatch: got %v", out.Extensions[SampleExtension])
+	}
+	if len(sf.Scene.Nodes) != 42 || sf.Extensions != nil {
+		t.Error("input scene was modified")
+	}
+
+	if same, _ := SampleScene(&sf, 100, SampleByGroup); len(same.Scene.Nodes) != 42 {
+		t.Errorf("scene within budget should be unchanged: got %d nodes", len(same.Scene.Nodes))
+	}
+	if _, err := SampleScene(&sf, 0, SampleByGroup); err == nil {
+		t.Error("expected error for a zero budget")
+	}
+	if _, err := SampleScene(&sf, 10, "degree"); err == nil {
+		t.Error("expected error for an unknown strategy")
+	}
+}
@@ -0,0 +1,150 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// =============================================================================
+// SARIF REPORTS
+// =============================================================================
+
+// SARIFContentType is the media type of SARIF logs
+const SARIFContentType = "application/sarif+json"
+
+// SARIF rule IDs of validation results
+const (
+	SARIFRuleError   = "scene/error"
+	SARIFRuleWarning = "scene/warning"
+)
+
+// FileValidation is the validation result of one scene file. Diagnostics
+// locate the problems within the file; see NewFileValidation.
+type FileValidation struct {
+	Path        string           `json:"path" validate:"required"`
+	Result      ValidationResult `json:"result"`
+	Diagnostics []Diagnostic     `json:"diagnostics,omitempty"`
+}
+
+// diagnostics returns the file's diagnostics, unlocated ones when they
+// were not computed
+func (f FileValidation) diagnostics() []Diagnostic {
+	if f.Diagnostics != nil {
+		return f.Diagnostics
+	}
+	diags := make([]Diagnostic, 0, len(f.Result.Errors)+len(f.Result.Warnings))
+	for _, msg := range f.Result.Errors {
+		diags = append(diags, Diagnostic{Severity: SeverityError, Message: msg})
+	}
+	for _, msg := range f.Result.Warnings {
+		diags = append(diags, Diagnostic{Severity: SeverityWarning, Message: msg})
+	}
+	return diags
+}
+
+// sarifLog is the subset of SARIF 2.1.0 that validation reports use
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// EncodeSARIF writes validation results as a SARIF 2.1.0 log, the format
+// code review tools use to show problems inline. Errors and warnings are
+// reported under SARIFRuleError and SARIFRuleWarning against their file,
+// with the line and JSONPath of located diagnostics.
+func EncodeSARIF(w io.Writer, files []FileValidation) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "starfleet",
+			Version:        SchemaVersion,
+			InformationURI: "https://github.com/hyperdrive-technology/starfleet-sdk",
+			Rules: []sarifRule{
+				{ID: SARIFRuleError, ShortDescription: sarifMessage{Text: "Scene validation error"}},
+				{ID: SARIFRuleWarning, ShortDescription: sarifMessage{Text: "Scene validation warning"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, f := range files {
+		for _, d := range f.diagnostics() {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.Path}}}
+			if d.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+			}
+			if d.Path != "" {
+				location.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: d.Path, Kind: "object"}}
+			}
+			rule := SARIFRuleError
+			if d.Severity == SeverityWarning {
+				rule = SARIFRuleWarning
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID: rule, Level: string(d.Severity), Message: sarifMessage{Text: d.Message}, Locations: []sarifLocation{location},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
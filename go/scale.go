@@ -0,0 +1,70 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+)
+
+// =============================================================================
+// METRIC-DRIVEN SCALING
+// =============================================================================
+
+// ScaleMapping is how ScaleByMetric maps metric values onto scales
+type ScaleMapping string
+
+const (
+	// ScaleLinear makes scale proportional to the value
+	ScaleLinear ScaleMapping = "linear"
+	// ScaleSqrt makes scale proportional to the square root of the value,
+	// so a node's area rather than its width tracks the value
+	ScaleSqrt ScaleMapping = "sqrt"
+	// ScaleLog makes scale proportional to log(1+value), for values
+	// spanning orders of magnitude
+	ScaleLog ScaleMapping = "log"
+)
+
+// ScaleByMetric sets the uniform scale of every node with a numeric value
+// for the metric, mapping the smallest value in the scene to minScale and
+// the largest to maxScale. It returns the number of nodes scaled.
+//
+// Scales must be positive with minScale <= maxScale, so no node collapses
+// or flips. NaN and infinite values are skipped like missing ones, negative
+// values count as zero under the sqrt and log mappings, and when all values
+// are equal nodes get the midpoint of the range.
+func ScaleByMetric(sf *SceneFile, metric string, minScale, maxScale float64, mapping ScaleMapping) (int, error) {
+	if !(minScale > 0) || !(maxScale >= minScale) || math.IsInf(maxScale, 0) {
+		return 0, fmt.Errorf("scale by metric: invalid scale range [%g, %g]", minScale, maxScale)
+	}
+	var transform func(float64) float64
+	switch mapping {
+	case ScaleLinear:
+		transform = func(v float64) float64 { return v }
+	case ScaleSqrt:
+		transform = func(v float64) float64 { return math.Sqrt(math.Max(v, 0)) }
+	case ScaleLog:
+		transform = func(v float64) float64 { return math.Log1p(math.Max(v, 0)) }
+	default:
+		return 0, fmt.Errorf("scale by metric: unknown mapping %q", mapping)
+	}
+
+	values := make(map[int]float64)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := range sf.Scene.Nodes {
+		v, ok := toFloat64(sf.Scene.Nodes[i].Metrics[metric])
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		v = transform(v)
+		values[i] = v
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	for i, v := range values {
+		pos := 0.5
+		if hi > lo {
+			pos = (v - lo) / (hi - lo)
+		}
+		s := minScale + (maxScale-minScale)*pos
+		sf.Scene.Nodes[i].Transform.Scale = Scale3{X: s, Y: s, Z: s}
+	}
+	return len(values), nil
+}
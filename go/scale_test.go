@@ -0,0 +1,54 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// TestScaleByMetric tests the scale mappings and their guardrails
+func TestScaleByMetric(t *testing.T) {
+	newScene := func() SceneFile {
+		sf := newDiffScene()
+		sf.Scene.Nodes[0].Metrics = map[string]interface{}{"rps": 0}
+		sf.Scene.Nodes[1].Metrics = map[string]interface{}{"rps": 25.0}
+		sf.Scene.Nodes[2].Metrics = map[string]interface{}{"rps": 100}
+		return sf
+	}
+	tests := []struct {
+		mapping ScaleMapping
+		want    [3]float64
+	}{
+		{ScaleLinear, [3]float64{1, 1.5, 3}},
+		{ScaleSqrt, [3]float64{1, 2, 3}},
+		{ScaleLog, [3]float64{1, 1 + 2*math.Log1p(25)/math.Log1p(100), 3}},
+	}
+	for _, tt := range tests {
+		sf := newScene()
+		n, err := ScaleByMetric(&sf, "rps", 1, 3, tt.mapping)
+		if err != nil || n != 3 {
+			t.Fatalf("%s: ScaleByMetric mismatch: got %d, %v", tt.mapping, n, err)
+		}
+		for i, want := range tt.want {
+			if s := sf.Scene.Nodes[i].Transform.Scale; math.Abs(s.X-want) > 1e-9 || s.X != s.Y || s.Y != s.Z {
+				t.Errorf("%s: node %d scale mismatch: got %+v, want %v", tt.mapping, i, s, want)
+			}
+		}
+	}
+
+	sf := newScene()
+	sf.Scene.Nodes[0].Metrics["rps"] = math.NaN()
+	sf.Scene.Nodes[1].Metrics = nil
+	sf.Scene.Nodes[2].Metrics["rps"] = -5
+	if n, _ := ScaleByMetric(&sf, "rps", 1, 3, ScaleSqrt); n != 1 || sf.Scene.Nodes[2].Transform.Scale.X != 2 || sf.Scene.Nodes[0].Transform.Scale.X != 1 {
+		t.Errorf("guardrails mismatch: got %d nodes, scales %+v", n, sf.Scene.Nodes)
+	}
+
+	for _, r := range [][2]float64{{0, 1}, {-1, 1}, {2, 1}, {1, math.Inf(1)}, {math.NaN(), 1}} {
+		if _, err := ScaleByMetric(&sf, "rps", r[0], r[1], ScaleLinear); err == nil {
+			t.Errorf("expected error for range %v", r)
+		}
+	}
+	if _, err := ScaleByMetric(&sf, "rps", 1, 2, "cube"); err == nil {
+		t.Error("expected error for an unknown mapping")
+	}
+}
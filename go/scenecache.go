@@ -0,0 +1,246 @@
+package starfleet
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// =============================================================================
+// SCENE CACHE
+// =============================================================================
+
+// DefaultSceneCacheBytes bounds a SceneCache whose MaxBytes is unset
+const DefaultSceneCacheBytes = 1 << 30
+
+// RevisionReader is implemented by stores that can report the latest
+// revision of a scene and its encoded size without decoding it. SceneCache
+// uses it to notice writes made around the cache.
+type RevisionReader interface {
+	LatestRevision(ctx context.Context, id string) (revision, size int64, err error)
+}
+
+// SceneIndex is a decoded scene revision with its nodes and edges indexed
+// by ID. Indexes from a SceneCache are shared by every reader and must not
+// be modified.
+type SceneIndex struct {
+	Revision SceneRevision
+	// Size is the encoded size of the scene in bytes, which the cache
+	// accounts against its budget
+	Size int64
+
+	nodes map[string]int
+	edges map[string]int
+}
+
+// NewSceneIndex indexes a scene revision. A size of zero is measured by
+// encoding the scene.
+func NewSceneIndex(rev SceneRevision, size int64) *SceneIndex {
+	if size <= 0 {
+		cw := &countingWriter{w: io.Discard}
+		_ = json.NewEncoder(cw).Encode(&rev.Scene)
+		size = cw.n
+	}
+	x := &SceneIndex{
+		Revision: rev,
+		Size:     size,
+		nodes:    make(map[string]int, len(rev.Scene.Scene.Nodes)),
+		edges:    make(map[string]int, len(rev.Scene.Scene.Edges)),
+	}
+	for i := range rev.Scene.Scene.Nodes {
+		x.nodes[rev.Scene.Scene.Nodes[i].ID] = i
+	}
+	for i := range rev.Scene.Scene.Edges {
+		x.edges[rev.Scene.Scene.Edges[i].ID] = i
+	}
+	return x
+}
+
+// Node returns a node by ID, or nil
+func (x *SceneIndex) Node(id string) *SceneNode {
+	if i, ok := x.nodes[id]; ok {
+		return &x.Revision.Scene.Scene.Nodes[i]
+	}
+	return nil
+}
+
+// Edge returns an edge by ID, or nil
+func (x *SceneIndex) Edge(id string) *SceneEdge {
+	if i, ok := x.edges[id]; ok {
+		return &x.Revision.Scene.Scene.Edges[i]
+	}
+	return nil
+}
+
+// SceneCacheStats reports the effectiveness and size of a SceneCache
+type SceneCacheStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Scenes    int   `json:"scenes"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// SceneCache keeps the latest revisions of hot scenes decoded and indexed,
+// so bursts of reads stop decoding the same scene over and over. Entries
+// are evicted least recently used first once their sizes exceed MaxBytes;
+// a scene larger than that is served but not kept. Concurrent misses for
+// a scene share one load.
+//
+// When the store is a RevisionReader, every read checks the cached
+// revision is still the latest, so writes made directly to the store are
+// seen at once. Otherwise entries live until evicted or invalidated, and
+// writers must call Invalidate. It is safe for concurrent use.
+type SceneCache struct {
+	Store SceneStore
+	// MaxBytes is the budget of encoded scene bytes; zero uses
+	// DefaultSceneCacheBytes
+	MaxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List // of *SceneIndex, most recently used first
+	entries map[string]*list.Element
+	loading map[string]*sceneLoad
+	bytes   int64
+	stats   SceneCacheStats
+}
+
+// sceneLoad is a load in progress that concurrent misses wait for
+type sceneLoad struct {
+	done  chan struct{}
+	index *SceneIndex
+	err   error
+	// stale is set by Invalidate during the load, which may have read the
+	// revision being replaced
+	stale bool
+}
+
+// NewSceneCache creates a cache in front of store holding up to maxBytes
+// of scenes
+func NewSceneCache(store SceneStore, maxBytes int64) *SceneCache {
+	return &SceneCache{Store: store, MaxBytes: maxBytes}
+}
+
+// Get returns the index of the latest revision of a scene
+func (c *SceneCache) Get(ctx context.Context, id string) (*SceneIndex, error) {
+	latest, size := int64(0), int64(0)
+	if rr, ok := c.Store.(RevisionReader); ok {
+		var err error
+		if latest, size, err = rr.LatestRevision(ctx, id); err != nil {
+			if errors.Is(err, ErrSceneNotFound) {
+				c.Invalidate(id)
+			}
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[id]; ok {
+		x := e.Value.(*SceneIndex)
+		if latest == 0 || x.Revision.Revision == latest {
+			c.lru.MoveToFront(e)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return x, nil
+		}
+		c.remove(e)
+	}
+	c.stats.Misses++
+	if load, ok := c.loading[id]; ok {
+		c.mu.Unlock()
+		select {
+		case <-load.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if load.err != nil {
+			return nil, load.err
+		}
+		if latest == 0 || load.index.Revision.Revision >= latest {
+			return load.index, nil
+		}
+		return c.Get(ctx, id)
+	}
+	load := &sceneLoad{done: make(chan struct{})}
+	if c.loading == nil {
+		c.loading = make(map[string]*sceneLoad)
+	}
+	c.loading[id] = load
+	c.mu.Unlock()
+
+	rev, err := c.Store.Get(ctx, id)
+	if err == nil {
+		if rev.Revision != latest {
+			// Written since the check, so the probed size is stale
+			size = 0
+		}
+		load.index = NewSceneIndex(rev, size)
+	}
+	load.err = err
+
+	c.mu.Lock()
+	delete(c.loading, id)
+	if err == nil && !load.stale {
+		c.insert(load.index)
+	}
+	c.mu.Unlock()
+	close(load.done)
+	return load.index, err
+}
+
+// insert adds an index and evicts until the cache fits its budget. The
+// lock is held.
+func (c *SceneCache) insert(x *SceneIndex) {
+	budget := c.MaxBytes
+	if budget <= 0 {
+		budget = DefaultSceneCacheBytes
+	}
+	if x.Size > budget {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.lru = list.New()
+	}
+	if e, ok := c.entries[x.Revision.ID]; ok {
+		c.remove(e)
+	}
+	c.entries[x.Revision.ID] = c.lru.PushFront(x)
+	c.bytes += x.Size
+	for c.bytes > budget {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove drops an entry. The lock is held.
+func (c *SceneCache) remove(e *list.Element) {
+	x := c.lru.Remove(e).(*SceneIndex)
+	delete(c.entries, x.Revision.ID)
+	c.bytes -= x.Size
+}
+
+// Invalidate drops a scene from the cache, as after writing it
+func (c *SceneCache) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[id]; ok {
+		c.remove(e)
+	}
+	if load, ok := c.loading[id]; ok {
+		load.stale = true
+	}
+}
+
+// Stats returns the cache's counters and current size
+func (c *SceneCache) Stats() SceneCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Scenes = len(c.entries)
+	stats.Bytes = c.bytes
+	return stats
+}
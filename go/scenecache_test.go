@@ -0,0 +1,134 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingStore counts decoding reads and hides LatestRevision
+type countingStore struct {
+	SceneStore
+	gets atomic.Int64
+}
+
+func (s *countingStore) Get(ctx context.Context, id string) (SceneRevision, error) {
+	s.gets.Add(1)
+	return s.SceneStore.Get(ctx, id)
+}
+
+// revisionCountingStore is a countingStore that reports revisions
+type revisionCountingStore struct {
+	*countingStore
+	RevisionReader
+}
+
+// TestSceneCache tests hits, invalidation on store revisions and lookups
+func TestSceneCache(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemorySceneStore()
+	memory.Put(ctx, "a", newDiffScene(), 0)
+	counting := &countingStore{SceneStore: memory}
+	cache := NewSceneCache(revisionCountingStore{counting, memory}, 0)
+
+	for i := 0; i < 3; i++ {
+		x, err := cache.Get(ctx, "a")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if x.Revision.Revision != 1 || x.Node("b") == nil || x.Edge("a-b") == nil || x.Node("missing") != nil {
+			t.Errorf("index mismatch: got revision %d", x.Revision.Revision)
+		}
+	}
+	if got := counting.gets.Load(); got != 1 {
+		t.Errorf("gets mismatch: got %d, want 1", got)
+	}
+
+	// A write straight to the store is seen on the next read
+	memory.Put(ctx, "a", newDiffScene(), AnyRevision)
+	if x, _ := cache.Get(ctx, "a"); x.Revision.Revision != 2 {
+		t.Errorf("revision mismatch: got %d, want 2", x.Revision.Revision)
+	}
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Scenes != 1 || stats.Bytes <= 0 {
+		t.Errorf("stats mismatch: got %+v", stats)
+	}
+
+	memory.Delete(ctx, "a", AnyRevision)
+	if _, err := cache.Get(ctx, "a"); !errors.Is(err, ErrSceneNotFound) {
+		t.Errorf("error mismatch: got %v, want %v", err, ErrSceneNotFound)
+	}
+	if stats := cache.Stats(); stats.Scenes != 0 || stats.Bytes != 0 {
+		t.Errorf("stats mismatch after delete: got %+v", stats)
+	}
+}
+
+// TestSceneCache_Eviction tests evicting least recently used scenes to fit
+// the budget
+func TestSceneCache_Eviction(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySceneStore()
+	for _, id := range []string{"a", "b", "c"} {
+		store.Put(ctx, id, newDiffScene(), 0)
+	}
+	_, size, _ := store.LatestRevision(ctx, "a")
+	cache := NewSceneCache(store, 2*size)
+
+	cache.Get(ctx, "a")
+	cache.Get(ctx, "b")
+	cache.Get(ctx, "a")
+	cache.Get(ctx, "c")
+	stats := cache.Stats()
+	if stats.Scenes != 2 || stats.Evictions != 1 || stats.Bytes != 2*size {
+		t.Errorf("stats mismatch: got %+v", stats)
+	}
+	cache.Get(ctx, "a")
+	if stats := cache.Stats(); stats.Hits != 2 {
+		t.Errorf("a was evicted instead of b: got %+v", stats)
+	}
+
+	big := newLargeScene(200)
+	store.Put(ctx, "big", big, 0)
+	if x, err := cache.Get(ctx, "big"); err != nil || len(x.Revision.Scene.Scene.Nodes) != len(big.Scene.Nodes) {
+		t.Errorf("Get mismatch for oversized scene: %v", err)
+	}
+	if stats := cache.Stats(); stats.Scenes != 2 {
+		t.Errorf("oversized scene was cached: got %+v", stats)
+	}
+}
+
+// TestSceneCache_Invalidate tests stores without revision reads, where
+// writers invalidate, and concurrent misses sharing a load
+func TestSceneCache_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemorySceneStore()
+	memory.Put(ctx, "a", newDiffScene(), 0)
+	counting := &countingStore{SceneStore: memory}
+	cache := NewSceneCache(counting, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Get(ctx, "a"); err != nil {
+				t.Errorf("Get failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := counting.gets.Load(); got > 8 || cache.Stats().Scenes != 1 {
+		t.Errorf("gets mismatch: got %d", got)
+	}
+
+	memory.Put(ctx, "a", newDiffScene(), AnyRevision)
+	if x, _ := cache.Get(ctx, "a"); x.Revision.Revision != 1 {
+		t.Errorf("revision mismatch: got %d, want the cached 1", x.Revision.Revision)
+	}
+	cache.Invalidate("a")
+	if x, _ := cache.Get(ctx, "a"); x.Revision.Revision != 2 {
+		t.Errorf("revision mismatch: got %d, want 2", x.Revision.Revision)
+	}
+}
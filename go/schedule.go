@@ -0,0 +1,535 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// SCHEDULES
+// =============================================================================
+
+// Schedule decides when a periodic task runs next
+type Schedule interface {
+	// Next returns the first run time after t, or the zero time if there is
+	// none
+	Next(t time.Time) time.Time
+}
+
+// IntervalSchedule runs a task at a fixed interval
+type IntervalSchedule time.Duration
+
+// Next returns t plus the interval
+func (s IntervalSchedule) Next(t time.Time) time.Time {
+	if s <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(s))
+}
+
+// CronSchedule is a standard five-field cron expression: minute, hour, day
+// of month, month and day of week. Times are evaluated in the location of
+// the time passed to Next.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// A restricted day of month and day of week match either, as in cron
+	domAny, dowAny bool
+}
+
+// cronField describes the valid range of one cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronAliases are the named schedules cron accepts
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a cron expression, one of the aliases such as
+// "@hourly" or "@daily", or "@every <duration>" for an IntervalSchedule.
+// Cron fields accept "*", numbers, ranges "a-b", lists "a,b" and steps
+// "*/n" or "a-b/n"; both 0 and 7 are Sunday.
+func ParseSchedule(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("parse schedule %q: invalid interval", expr)
+		}
+		return IntervalSchedule(d), nil
+	}
+	if alias, ok := cronAliases[expr]; ok {
+		expr = alias
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("parse schedule %q: want %d fields, got %d", expr, len(cronFields), len(fields))
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("parse schedule %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// Sunday may be written as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &CronSchedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns the set of values a field matches as a bitmask
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepText, spec.name)
+			}
+			step = n
+		}
+		lo, hi := spec.min, spec.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", spec.name, part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", spec.name, part)
+				}
+			} else if hasStep {
+				hi = spec.max
+			}
+		}
+		if lo < spec.min || hi > spec.max || lo > hi {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", spec.name, part, spec.min, spec.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching minute after t. Fields are advanced from
+// the largest unit down, so finding a run a year away takes few steps.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, mo, d := t.Date()
+		switch {
+		case c.month&(1<<uint(mo)) == 0:
+			t = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(y, mo, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's rule for day of month and day of week
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// =============================================================================
+// IMPORT SCHEDULER
+// =============================================================================
+
+// DefaultImportHistory is the number of runs kept per schedule
+const DefaultImportHistory = 50
+
+// reconcileAttempts bounds retries of a reconciled write that lost a race
+// with another writer
+const reconcileAttempts = 3
+
+// Sentinel errors returned by ImportScheduler
+var (
+	ErrScheduleNotFound = errors.New("import schedule not found")
+	ErrScheduleRunning  = errors.New("import schedule already running")
+)
+
+// ImportSchedule runs an importer periodically and reconciles its result
+// into a stored scene. Name must be unique; it is also the Source of the
+// reconciliation, so each schedule owns the elements it imported.
+type ImportSchedule struct {
+	Name     string
+	Scene    string
+	Importer Importer
+	Config   ImporterConfig
+	Schedule Schedule
+	// KeepMissing keeps elements the importer no longer reports
+	KeepMissing bool
+}
+
+// ImportRun records one run of a schedule. Revision is the revision written,
+// and is zero when the import changed nothing or failed.
+type ImportRun struct {
+	Schedule string    `json:"schedule" validate:"required"`
+	Scene    string    `json:"scene" validate:"required"`
+	Job      string    `json:"job,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Revision int64     `json:"revision,omitempty"`
+	Changes  SceneDiff `json:"changes"`
+	// Provenance explains the changes of a run that wrote a revision
+	Provenance []ProvenanceChange `json:"provenance,omitempty"`
+	Warnings   []string           `json:"warnings,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// Failed reports whether the run failed
+func (r ImportRun) Failed() bool {
+	return r.Error != ""
+}
+
+// ScheduleStatus summarizes a schedule for monitoring
+type ScheduleStatus struct {
+	Name                string     `json:"name" validate:"required"`
+	Scene               string     `json:"scene" validate:"required"`
+	Next                time.Time  `json:"next"`
+	Running             bool       `json:"running,omitempty"`
+	LastRun             *ImportRun `json:"lastRun,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures,omitempty"`
+}
+
+// ImportScheduler keeps scenes in sync with external systems by running
+// importers on schedules through an ImportQueue and reconciling their
+// results into a store. Writes are made as the actor "import:<name>".
+type ImportScheduler struct {
+	Store SceneStore
+	Queue *ImportQueue
+	// Webhooks, when set, is notified with SceneEventImported for every
+	// revision written, listing the changes with their provenance
+	Webhooks *WebhookDispatcher
+	// Audit, when set, records the same changes
+	Audit AuditLog
+	// HistoryLimit bounds the runs kept per schedule; zero uses
+	// DefaultImportHistory
+	HistoryLimit int
+	// OnRun, when set, receives every finished run
+	OnRun func(ImportRun)
+
+	mu        sync.Mutex
+	schedules map[string]*scheduledImport
+	runs      sync.WaitGroup
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// scheduledImport is the state of one registered schedule
+type scheduledImport struct {
+	ImportSchedule
+	next    time.Time
+	running bool
+	history []ImportRun
+}
+
+// NewImportScheduler creates a scheduler writing to store and running
+// imports on queue
+func NewImportScheduler(store SceneStore, queue *ImportQueue) *ImportScheduler {
+	return &ImportScheduler{
+		Store:     store,
+		Queue:     queue,
+		schedules: make(map[string]*scheduledImport),
+		now:       time.Now,
+	}
+}
+
+// Add registers a schedule. Its first run is the schedule's next time after
+// now.
+func (s *ImportScheduler) Add(schedule ImportSchedule) error {
+	if schedule.Name == "" || schedule.Scene == "" || schedule.Importer == nil || schedule.Schedule == nil {
+		return fmt.Errorf("add import schedule: name, scene, importer and schedule are required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[schedule.Name]; ok {
+		return fmt.Errorf("add import schedule: %q already exists", schedule.Name)
+	}
+	s.schedules[schedule.Name] = &scheduledImport{
+		ImportSchedule: schedule,
+		next:           schedule.Schedule.Next(s.now()),
+	}
+	return nil
+}
+
+// Remove unregisters a schedule. A run in progress completes.
+func (s *ImportScheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	delete(s.schedules, name)
+	return nil
+}
+
+// History returns the recorded runs of a schedule, oldest first
+func (s *ImportScheduler) History(name string) ([]ImportRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sched, ok := s.schedules[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	return append([]ImportRun(nil), sched.history...), nil
+}
+
+// Status summarizes every schedule, ordered by name
+func (s *ImportScheduler) Status() []ScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]ScheduleStatus, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		status := ScheduleStatus{Name: sched.Name, Scene: sched.Scene, Next: sched.next, Running: sched.running}
+		if n := len(sched.history); n > 0 {
+			last := sched.history[n-1]
+			status.LastRun = &last
+		}
+		for i := len(sched.history) - 1; i >= 0 && sched.history[i].Failed(); i-- {
+			status.ConsecutiveFailures++
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Run starts due schedules until ctx is done, then waits for runs in
+// progress and returns the context's error. Runs that would overlap a
+// previous one still in progress are skipped.
+func (s *ImportScheduler) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.runs.Wait()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		now := s.now()
+		var wake time.Time
+		s.mu.Lock()
+		for _, sched := range s.schedules {
+			if sched.next.IsZero() {
+				continue
+			}
+			if !sched.next.After(now) {
+				sched.next = sched.Schedule.Next(now)
+				if !sched.running {
+					s.start(ctx, sched)
+				}
+			}
+			if !sched.next.IsZero() && (wake.IsZero() || sched.next.Before(wake)) {
+				wake = sched.next
+			}
+		}
+		s.mu.Unlock()
+
+		// Poll at least once a minute so schedules added meanwhile are
+		// picked up
+		delay := time.Minute
+		if !wake.IsZero() {
+			delay = min(max(wake.Sub(now), 0), delay)
+		}
+		timer.Reset(delay)
+	}
+}
+
+// RunNow runs a schedule immediately and waits for it. The returned error
+// is that of the run, which is also recorded in the history.
+func (s *ImportScheduler) RunNow(ctx context.Context, name string) (ImportRun, error) {
+	s.mu.Lock()
+	sched, ok := s.schedules[name]
+	if !ok {
+		s.mu.Unlock()
+		return ImportRun{}, fmt.Errorf("%w: %s", ErrScheduleNotFound, name)
+	}
+	if sched.running {
+		s.mu.Unlock()
+		return ImportRun{}, fmt.Errorf("%w: %s", ErrScheduleRunning, name)
+	}
+	sched.running = true
+	s.runs.Add(1)
+	s.mu.Unlock()
+	return s.execute(ctx, sched)
+}
+
+// start runs a schedule in the background. Callers hold s.mu.
+func (s *ImportScheduler) start(ctx context.Context, sched *scheduledImport) {
+	sched.running = true
+	s.runs.Add(1)
+	go func() {
+		_, _ = s.execute(ctx, sched)
+	}()
+}
+
+// execute performs a run and records it
+func (s *ImportScheduler) execute(ctx context.Context, sched *scheduledImport) (ImportRun, error) {
+	defer s.runs.Done()
+	run := ImportRun{Schedule: sched.Name, Scene: sched.Scene, Started: s.now().UTC()}
+	err := s.importAndReconcile(ctx, sched.ImportSchedule, &run)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.Finished = s.now().UTC()
+
+	s.mu.Lock()
+	sched.running = false
+	sched.history = append(sched.history, run)
+	limit := s.HistoryLimit
+	if limit <= 0 {
+		limit = DefaultImportHistory
+	}
+	if len(sched.history) > limit {
+		sched.history = append([]ImportRun(nil), sched.history[len(sched.history)-limit:]...)
+	}
+	s.mu.Unlock()
+
+	if s.OnRun != nil {
+		s.OnRun(run)
+	}
+	return run, err
+}
+
+// importAndReconcile runs the importer through the queue and writes the
+// reconciled scene, retrying when another writer got there first
+func (s *ImportScheduler) importAndReconcile(ctx context.Context, sched ImportSchedule, run *ImportRun) error {
+	job, err := s.Queue.Submit(sched.Name, sched.Importer, sched.Config)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", sched.Name, err)
+	}
+	run.Job = job.ID
+	job, err = s.Queue.Wait(ctx, job.ID)
+	if err != nil {
+		_ = s.Queue.Cancel(job.ID)
+		return fmt.Errorf("import %s: %w", sched.Name, err)
+	}
+	if job.State != ImportJobSucceeded {
+		return fmt.Errorf("import %s %s: %s", sched.Name, job.State, job.Error)
+	}
+	result := job.Result
+	run.Warnings = result.Warnings
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("import %s: %s", sched.Name, strings.Join(result.Errors, "; "))
+	}
+
+	opts := ReconcileOptions{Source: sched.Name, KeepMissing: sched.KeepMissing}
+	for attempt := 1; ; attempt++ {
+		var base SceneFile
+		var expected int64
+		current, err := s.Store.Get(ctx, sched.Scene)
+		switch {
+		case err == nil:
+			base, expected = current.Scene, current.Revision
+		case errors.Is(err, ErrSceneNotFound):
+			// A new scene takes its settings from the import
+			base = result.Scene
+			base.Scene.Nodes, base.Scene.Edges = nil, nil
+		default:
+			return fmt.Errorf("reconcile %s: %w", sched.Scene, err)
+		}
+
+		merged, changes := Reconcile(&base, &result.Scene, opts)
+		run.Changes = changes
+		if changes.Empty() && expected != 0 {
+			return nil
+		}
+		merged.UpdateTimestamp()
+		actor := "import:" + sched.Name
+		rev, err := s.Store.Put(WithActor(ctx, actor), sched.Scene, merged, expected)
+		if err == nil {
+			run.Revision = rev.Revision
+			run.Provenance = Provenance(&base, &merged, changes, sched.Name)
+			return s.publish(ctx, actor, rev, changes, run.Provenance)
+		}
+		if !errors.Is(err, ErrRevisionConflict) || attempt == reconcileAttempts {
+			return fmt.Errorf("reconcile %s: %w", sched.Scene, err)
+		}
+	}
+}
+
+// publish sends the changes of a written revision to the webhooks and the
+// audit log
+func (s *ImportScheduler) publish(ctx context.Context, actor string, rev SceneRevision, changes SceneDiff, provenance []ProvenanceChange) error {
+	if s.Webhooks != nil {
+		summary := changes.Summary()
+		s.Webhooks.Notify(WebhookPayload{
+			Event:    SceneEventImported,
+			SceneID:  rev.ID,
+			Revision: rev.Revision,
+			Actor:    actor,
+			Diff:     &summary,
+			Changes:  provenance,
+		})
+	}
+	if s.Audit == nil {
+		return nil
+	}
+	err := s.Audit.Append(ctx, AuditEntry{
+		Time:     rev.Updated,
+		Actor:    actor,
+		SceneID:  rev.ID,
+		Revision: rev.Revision,
+		Action:   SceneEventImported,
+		Changes:  provenance,
+	})
+	if err != nil {
+		return fmt.Errorf("audit %s revision %d: %w", rev.ID, rev.Revision, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestParseSchedule tests cron expressions, aliases and intervals
+func TestParseSchedule(t *testing.T) {
+	// A Monday
+	from := time.Date(2024, 1, 1, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 5", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"5,10 3 1 6 *", time.Date(2024, 6, 1, 3, 5, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q mismatch: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every soon"} {
+		if _, err := ParseSchedule(bad); err == nil {
+			t.Errorf("ParseSchedule(%q): expected error", bad)
+		}
+	}
+	never, _ := ParseSchedule("0 0 31 2 *")
+	if got := never.Next(from); !got.IsZero() {
+		t.Errorf("impossible schedule mismatch: got %v", got)
+	}
+}
+
+// TestImportScheduler tests reconciled runs, history and failures
+func TestImportScheduler(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySceneStore()
+	queue := NewImportQueue(1)
+	defer queue.Close(ctx)
+	scheduler := NewImportScheduler(store, queue)
+
+	var nodes atomic.Int32
+	nodes.Store(2)
+	var fail atomic.Bool
+	importer := ImporterFunc(func(context.Context, ImporterConfig, ProgressFunc) (ImportResult, error) {
+		if fail.Load() {
+			return ImportResult{}, errors.New("throttled")
+		}
+		sf := NewSceneFile("Cloud")
+		for _, id := range []string{"vpc", "vm", "db"}[:nodes.Load()] {
+			sf.AddNode(SceneNode{ID: id, Type: "resource", Name: id, Transform: NewTransform()})
+		}
+		return ImportResult{Scene: sf, Warnings: []string{"partial"}}, nil
+	})
+	every, _ := ParseSchedule("@hourly")
+	if err := scheduler.Add(ImportSchedule{Name: "aws", Scene: "cloud", Importer: importer, Schedule: every}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Add(ImportSchedule{Name: "aws", Scene: "cloud", Importer: importer, Schedule: every}); err == nil {
+		t.Error("expected error for duplicate schedule")
+	}
+
+	run, err := scheduler.RunNow(ctx, "aws")
+	if err != nil || run.Revision != 1 || len(run.Changes.Nodes) != 2 || run.Warnings[0] != "partial" || run.Job == "" {
+		t.Fatalf("first run mismatch: got %+v, %v", run, err)
+	}
+	got, _ := store.Get(ctx, "cloud")
+	if got.Scene.Metadata.Name != "Cloud" || got.Scene.FindNode("vm").Extensions[ImportSourceExtension] != "aws" {
+		t.Errorf("stored scene mismatch: got %+v", got.Scene)
+	}
+
+	// Unchanged imports do not write a revision
+	if run, _ := scheduler.RunNow(ctx, "aws"); run.Revision != 0 || !run.Changes.Empty() {
+		t.Errorf("unchanged run mismatch: got %+v", run)
+	}
+	nodes.Store(3)
+	if run, _ := scheduler.RunNow(ctx, "aws"); run.Revision != 2 || len(run.Changes.Nodes) != 1 {
+		t.Errorf("changed run mismatch: got %+v", run)
+	}
+	fail.Store(true)
+	scheduler.RunNow(ctx, "aws")
+	if _, err := scheduler.RunNow(ctx, "aws"); err == nil {
+		t.Error("expected error from failing import")
+	}
+
+	status := scheduler.Status()
+	if len(status) != 1 || status[0].ConsecutiveFailures != 2 || !status[0].LastRun.Failed() || status[0].Next.IsZero() {
+		t.Errorf("status mismatch: got %+v", status)
+	}
+	if history, _ := scheduler.History("aws"); len(history) != 5 {
+		t.Errorf("history length mismatch: got %d, want 5", len(history))
+	}
+	if _, err := scheduler.RunNow(ctx, "gcp"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, got %v", err)
+	}
+}
+
+// TestImportScheduler_Run tests that due schedules run until cancellation
+func TestImportScheduler_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := NewImportQueue(1)
+	defer queue.Close(context.Background())
+	scheduler := NewImportScheduler(NewMemorySceneStore(), queue)
+	scheduler.HistoryLimit = 1
+	var runs atomic.Int32
+	scheduler.OnRun = func(run ImportRun) {
+		if runs.Add(1) == 2 {
+			cancel()
+		}
+	}
+	importer := ImporterFunc(func(context.Context, ImporterConfig, ProgressFunc) (ImportResult, error) {
+		return ImportResult{Scene: NewSceneFile("Tick")}, nil
+	})
+	scheduler.Add(ImportSchedule{Name: "tick", Scene: "tick", Importer: importer, Schedule: IntervalSchedule(time.Millisecond)})
+
+	if err := scheduler.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if history, _ := scheduler.History("tick"); runs.Load() < 2 || len(history) != 1 {
+		t.Errorf("run mismatch: got %d runs, %d in history", runs.Load(), len(history))
+	}
+}
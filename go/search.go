@@ -0,0 +1,372 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// =============================================================================
+// SEARCH
+// =============================================================================
+
+// Search fields a query term can be scoped to with "field:term". Any other
+// field names a metadata key, as does "metadata.<key>".
+const (
+	SearchFieldID       = "id"
+	SearchFieldName     = "name"
+	SearchFieldType     = "type"
+	SearchFieldTag      = "tag"
+	SearchFieldMetadata = "metadata"
+)
+
+// searchWeights ranks matches by the field they are in; metadata fields
+// weigh 1
+var searchWeights = map[string]float64{
+	SearchFieldName: 4,
+	SearchFieldID:   3,
+	SearchFieldTag:  2,
+	SearchFieldType: 2,
+}
+
+// SearchOptions controls how query terms match indexed words
+type SearchOptions struct {
+	// Limit bounds the number of results; zero returns all
+	Limit int
+	// Prefix lets terms match words they are a prefix of, as in a search box
+	Prefix bool
+	// MaxEdits is the largest edit distance of fuzzy matches. Terms get one
+	// edit per four characters up to MaxEdits, so short terms match exactly.
+	MaxEdits int
+}
+
+// DefaultSearchOptions returns options suited to interactive search
+func DefaultSearchOptions() SearchOptions {
+	return SearchOptions{Limit: 20, Prefix: true, MaxEdits: 2}
+}
+
+// SearchResult is a node matching a query. Fields lists the fields the
+// terms matched in.
+type SearchResult struct {
+	NodeID string   `json:"nodeId"`
+	Score  float64  `json:"score"`
+	Fields []string `json:"fields"`
+}
+
+// searchPosting is an occurrence of a word in a field of a node
+type searchPosting struct {
+	node, field string
+}
+
+// SearchIndex is an inverted index over node IDs, names, types, tags and
+// scalar metadata values. It is safe for concurrent use, and can be saved
+// with Encode and restored with DecodeSearchIndex to skip rebuilding it.
+type SearchIndex struct {
+	mu sync.RWMutex
+	// docs holds the words of each node by field
+	docs     map[string]map[string][]string
+	postings map[string]map[searchPosting]struct{}
+	// words is the sorted vocabulary, for prefix lookups
+	words []string
+}
+
+// NewSearchIndex creates an empty search index
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{docs: make(map[string]map[string][]string), postings: make(map[string]map[searchPosting]struct{})}
+}
+
+// BuildSearchIndex indexes every node of a scene
+func BuildSearchIndex(sf *SceneFile) *SearchIndex {
+	idx := NewSearchIndex()
+	for i := range sf.Scene.Nodes {
+		idx.Add(&sf.Scene.Nodes[i])
+	}
+	return idx
+}
+
+// Add indexes a node, replacing what was indexed under its ID
+func (idx *SearchIndex) Add(node *SceneNode) {
+	fields := map[string][]string{
+		SearchFieldID:   searchWords(node.ID),
+		SearchFieldName: searchWords(node.Name),
+		SearchFieldType: searchWords(node.Type),
+	}
+	for _, tag := range node.Tags {
+		fields[SearchFieldTag] = append(fields[SearchFieldTag], searchWords(tag)...)
+	}
+	for key, v := range node.Metadata {
+		values := toStringSlice(v)
+		if str, ok := metadataString(v); ok {
+			values = []string{str}
+		}
+		field := SearchFieldMetadata + "." + key
+		for _, value := range values {
+			fields[field] = append(fields[field], searchWords(value)...)
+		}
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(node.ID)
+	idx.insert(node.ID, fields)
+}
+
+// Remove drops a node from the index
+func (idx *SearchIndex) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+// Len returns the number of indexed nodes
+func (idx *SearchIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// insert adds the postings of a node. Callers hold idx.mu.
+func (idx *SearchIndex) insert(id string, fields map[string][]string) {
+	idx.docs[id] = fields
+	for field, words := range fields {
+		for _, w := range words {
+			postings, ok := idx.postings[w]
+			if !ok {
+				postings = make(map[searchPosting]struct{})
+				idx.postings[w] = postings
+				i := sort.SearchStrings(idx.words, w)
+				idx.words = append(idx.words, "")
+				copy(idx.words[i+1:], idx.words[i:])
+				idx.words[i] = w
+			}
+			postings[searchPosting{id, field}] = struct{}{}
+		}
+	}
+}
+
+// remove drops the postings of a node. Callers hold idx.mu.
+func (idx *SearchIndex) remove(id string) {
+	for field, words := range idx.docs[id] {
+		for _, w := range words {
+			postings := idx.postings[w]
+			delete(postings, searchPosting{id, field})
+			if len(postings) == 0 {
+				delete(idx.postings, w)
+				if i := sort.SearchStrings(idx.words, w); i < len(idx.words) && idx.words[i] == w {
+					idx.words = append(idx.words[:i], idx.words[i+1:]...)
+				}
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// Search returns the nodes matching every term of the query, best first
+// and then by ID. Terms are words, optionally scoped to a field as in
+// "name:gateway", "tag:prod" or "env:staging"; "metadata:x" searches all
+// metadata. Exact matches rank above prefix matches, which rank above fuzzy
+// ones, and matches in names rank above those in IDs, then tags and types,
+// then metadata.
+func (idx *SearchIndex) Search(query string, opts SearchOptions) []SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	type hit struct {
+		score  float64
+		fields map[string]bool
+	}
+	var hits map[string]*hit
+	for _, term := range strings.Fields(query) {
+		scope, text, scoped := strings.Cut(term, ":")
+		if !scoped {
+			scope, text = "", term
+		}
+		scope = strings.ToLower(scope)
+		switch scope {
+		case "", SearchFieldID, SearchFieldName, SearchFieldType, SearchFieldTag, SearchFieldMetadata:
+		default:
+			if !strings.HasPrefix(scope, SearchFieldMetadata+".") {
+				scope = SearchFieldMetadata + "." + scope
+			}
+		}
+		for _, word := range searchWords(text) {
+			matched := idx.match(word, scope, opts)
+			next := make(map[string]*hit, len(matched))
+			for node, m := range matched {
+				h := &hit{fields: m.fields}
+				if hits != nil {
+					prev, ok := hits[node]
+					if !ok {
+						continue
+					}
+					h.score = prev.score
+					for f := range prev.fields {
+						h.fields[f] = true
+					}
+				}
+				h.score += m.score
+				next[node] = h
+			}
+			hits = next
+		}
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for node, h := range hits {
+		fields := make([]string, 0, len(h.fields))
+		for f := range h.fields {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		results = append(results, SearchResult{NodeID: node, Score: h.score, Fields: fields})
+	}
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].NodeID < results[b].NodeID
+	})
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results
+}
+
+// searchMatch is how well one query word matches a node
+type searchMatch struct {
+	score  float64
+	fields map[string]bool
+}
+
+// match scores the nodes containing words that match a query word in the
+// scope. Callers hold idx.mu.
+func (idx *SearchIndex) match(word, scope string, opts SearchOptions) map[string]searchMatch {
+	matches := make(map[string]searchMatch)
+	add := func(w string, quality float64) {
+		for p := range idx.postings[w] {
+			if !searchScopeMatches(scope, p.field) {
+				continue
+			}
+			weight, ok := searchWeights[p.field]
+			if !ok {
+				weight = 1
+			}
+			m, ok := matches[p.node]
+			if !ok {
+				m.fields = make(map[string]bool)
+			}
+			m.score = max(m.score, quality*weight)
+			m.fields[p.field] = true
+			matches[p.node] = m
+		}
+	}
+
+	add(word, 1)
+	if opts.Prefix {
+		for i := sort.SearchStrings(idx.words, word); i < len(idx.words) && strings.HasPrefix(idx.words[i], word); i++ {
+			if w := idx.words[i]; w != word {
+				// Completing more of the word ranks higher
+				add(w, 0.5+0.25*float64(len(word))/float64(len(w)))
+			}
+		}
+	}
+	if edits := min(opts.MaxEdits, len([]rune(word))/4); edits > 0 {
+		for _, w := range idx.words {
+			if w == word || (opts.Prefix && strings.HasPrefix(w, word)) {
+				continue
+			}
+			if d := editDistance(word, w, edits); d <= edits {
+				add(w, 0.4/float64(d))
+			}
+		}
+	}
+	return matches
+}
+
+// searchScopeMatches reports whether a field is within a term's scope
+func searchScopeMatches(scope, field string) bool {
+	switch scope {
+	case "":
+		return true
+	case SearchFieldMetadata:
+		return strings.HasPrefix(field, SearchFieldMetadata+".")
+	default:
+		return scope == field
+	}
+}
+
+// searchWords splits text into lower-case words of letters and digits
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// editDistance returns the Levenshtein distance between a and b, or a value
+// above limit once it is known to exceed it
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		best := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			best = min(best, cur[j])
+		}
+		if best > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// searchIndexFile is the persisted form of a search index
+type searchIndexFile struct {
+	Version int                            `json:"version"`
+	Docs    map[string]map[string][]string `json:"docs"`
+}
+
+// searchIndexVersion is the version of the persisted index format
+const searchIndexVersion = 1
+
+// Encode writes the index as JSON
+func (idx *SearchIndex) Encode(w io.Writer) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if err := json.NewEncoder(w).Encode(searchIndexFile{Version: searchIndexVersion, Docs: idx.docs}); err != nil {
+		return fmt.Errorf("encode search index: %w", err)
+	}
+	return nil
+}
+
+// DecodeSearchIndex reads an index written by Encode
+func DecodeSearchIndex(r io.Reader) (*SearchIndex, error) {
+	var file searchIndexFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("decode search index: %w", err)
+	}
+	if file.Version != searchIndexVersion {
+		return nil, fmt.Errorf("decode search index: unsupported version %d", file.Version)
+	}
+	idx := NewSearchIndex()
+	for id, fields := range file.Docs {
+		idx.insert(id, fields)
+	}
+	return idx, nil
+}
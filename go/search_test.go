@@ -0,0 +1,121 @@
+package starfleet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// newSearchScene creates a scene with names, tags and metadata to search
+func newSearchScene() SceneFile {
+	sf := newDiffScene()
+	sf.Scene.Nodes = []SceneNode{
+		{ID: "api", Name: "API Gateway", Type: "service", Tags: []string{"prod", "edge"}, Metadata: map[string]interface{}{"env": "production", "team": "platform"}},
+		{ID: "gw-legacy", Name: "Legacy router", Type: "service", Tags: []string{"deprecated"}, Metadata: map[string]interface{}{"env": "staging", "owners": []interface{}{"gateway-team"}}},
+		{ID: "db", Name: "Orders database", Type: "postgres", Metadata: map[string]interface{}{"env": "production", "replicas": 3.0}},
+	}
+	return sf
+}
+
+// TestSearchIndex tests exact, prefix, fuzzy and field-scoped search
+func TestSearchIndex(t *testing.T) {
+	sf := newSearchScene()
+	idx := BuildSearchIndex(&sf)
+	opts := DefaultSearchOptions()
+	ids := func(results []SearchResult) []string {
+		out := make([]string, len(results))
+		for i, r := range results {
+			out[i] = r.NodeID
+		}
+		return out
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Names rank above metadata
+		{"gateway", []string{"api", "gw-legacy"}},
+		{"gate", []string{"api", "gw-legacy"}},
+		{"gatewey", []string{"api", "gw-legacy"}},
+		{"databse", []string{"db"}},
+		{"name:gateway", []string{"api"}},
+		{"metadata:gateway", []string{"gw-legacy"}},
+		{"env:production", []string{"api", "db"}},
+		{"env:production orders", []string{"db"}},
+		{"tag:prod", []string{"api"}},
+		{"replicas:3", []string{"db"}},
+		{"type:postgres", []string{"db"}},
+		{"gw", []string{"gw-legacy"}},
+		{"nothing", []string{}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := ids(idx.Search(tt.query, opts)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: results mismatch: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	exact := SearchOptions{}
+	if got := ids(idx.Search("gate", exact)); len(got) != 0 {
+		t.Errorf("expected no prefix matches without Prefix, got %v", got)
+	}
+	if got := idx.Search("gateway", SearchOptions{Limit: 1}); len(got) != 1 || !reflect.DeepEqual(got[0].Fields, []string{"name"}) {
+		t.Errorf("limited results mismatch: got %+v", got)
+	}
+
+	idx.Remove("api")
+	sf.Scene.Nodes[2].Name = "Gateway cache"
+	idx.Add(&sf.Scene.Nodes[2])
+	if got := ids(idx.Search("gateway", opts)); !reflect.DeepEqual(got, []string{"db", "gw-legacy"}) {
+		t.Errorf("updated results mismatch: got %v", got)
+	}
+	if got := ids(idx.Search("orders", opts)); len(got) != 0 {
+		t.Errorf("expected replaced words removed, got %v", got)
+	}
+	if idx.Len() != 2 {
+		t.Errorf("len mismatch: got %d, want 2", idx.Len())
+	}
+}
+
+// TestSearchIndex_Encode tests persisting and restoring an index
+func TestSearchIndex_Encode(t *testing.T) {
+	sf := newSearchScene()
+	idx := BuildSearchIndex(&sf)
+	var buf bytes.Buffer
+	if err := idx.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	restored, err := DecodeSearchIndex(&buf)
+	if err != nil {
+		t.Fatalf("DecodeSearchIndex failed: %v", err)
+	}
+	opts := DefaultSearchOptions()
+	for _, q := range []string{"gate", "env:staging", "databse"} {
+		if got, want := restored.Search(q, opts), idx.Search(q, opts); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: results mismatch: got %v, want %v", q, got, want)
+		}
+	}
+	if _, err := DecodeSearchIndex(bytes.NewBufferString(`{"version":9}`)); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+// TestEditDistance tests bounded edit distances
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"gateway", "gateway", 2, 0},
+		{"gatewey", "gateway", 2, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2},
+		{"a", "abcdef", 2, 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("%s/%s: distance mismatch: got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
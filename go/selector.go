@@ -0,0 +1,161 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// SELECTORS
+// =============================================================================
+
+// ErrInvalidSelector is returned when a selector expression cannot be parsed
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Selector matches nodes and edges by their properties. Every criterion
+// that is set must match; within a list any value may match, except Tags,
+// which must all be present. An empty selector matches every element.
+type Selector struct {
+	// Kind restricts matches to ElementNode or ElementEdge
+	Kind   string       `json:"kind,omitempty" validate:"omitempty,oneof=node edge"`
+	IDs    []string     `json:"ids,omitempty"`
+	Types  []string     `json:"types,omitempty"`
+	Tags   []string     `json:"tags,omitempty"`
+	Status []NodeStatus `json:"status,omitempty"`
+	// Metadata maps metadata keys to accepted values, compared in string
+	// form; "*" accepts any value as long as the key is present
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+// ParseSelector parses a selector expression: whitespace-separated terms of
+// the form field=value, where the value may list alternatives separated by
+// commas. Fields are kind, id, type, tag, status and metadata.<key>, as in
+// "kind=node type=server,db tag=prod metadata.env=staging". Repeated tag
+// terms must all match.
+func ParseSelector(expr string) (Selector, error) {
+	var s Selector
+	for _, term := range strings.Fields(expr) {
+		field, value, ok := strings.Cut(term, "=")
+		if !ok || field == "" || value == "" {
+			return Selector{}, fmt.Errorf("%w: term %q is not field=value", ErrInvalidSelector, term)
+		}
+		values := strings.Split(value, ",")
+		if slices.Contains(values, "") {
+			return Selector{}, fmt.Errorf("%w: term %q has an empty value", ErrInvalidSelector, term)
+		}
+		switch {
+		case field == "kind":
+			if len(values) != 1 || (value != ElementNode && value != ElementEdge) {
+				return Selector{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidSelector, ElementNode, ElementEdge)
+			}
+			s.Kind = value
+		case field == "id":
+			s.IDs = append(s.IDs, values...)
+		case field == "type":
+			s.Types = append(s.Types, values...)
+		case field == "tag":
+			if len(values) != 1 {
+				return Selector{}, fmt.Errorf("%w: tag %q cannot list alternatives", ErrInvalidSelector, value)
+			}
+			s.Tags = append(s.Tags, value)
+		case field == "status":
+			for _, v := range values {
+				s.Status = append(s.Status, NodeStatus(v))
+			}
+		case strings.HasPrefix(field, "metadata.") && len(field) > len("metadata."):
+			if s.Metadata == nil {
+				s.Metadata = make(map[string][]string)
+			}
+			key := strings.TrimPrefix(field, "metadata.")
+			s.Metadata[key] = append(s.Metadata[key], values...)
+		default:
+			return Selector{}, fmt.Errorf("%w: unknown field %q", ErrInvalidSelector, field)
+		}
+	}
+	return s, nil
+}
+
+// String formats the selector as an expression ParseSelector accepts
+func (s Selector) String() string {
+	var terms []string
+	if s.Kind != "" {
+		terms = append(terms, "kind="+s.Kind)
+	}
+	if len(s.IDs) > 0 {
+		terms = append(terms, "id="+strings.Join(s.IDs, ","))
+	}
+	if len(s.Types) > 0 {
+		terms = append(terms, "type="+strings.Join(s.Types, ","))
+	}
+	for _, tag := range s.Tags {
+		terms = append(terms, "tag="+tag)
+	}
+	if len(s.Status) > 0 {
+		status := make([]string, len(s.Status))
+		for i, st := range s.Status {
+			status[i] = string(st)
+		}
+		terms = append(terms, "status="+strings.Join(status, ","))
+	}
+	keys := make([]string, 0, len(s.Metadata))
+	for k := range s.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		terms = append(terms, "metadata."+k+"="+strings.Join(s.Metadata[k], ","))
+	}
+	return strings.Join(terms, " ")
+}
+
+// MatchNode reports whether a node matches the selector
+func (s *Selector) MatchNode(node *SceneNode) bool {
+	if s.Kind == ElementEdge {
+		return false
+	}
+	if len(s.Status) > 0 && !slices.Contains(s.Status, node.Status) {
+		return false
+	}
+	for _, tag := range s.Tags {
+		if !slices.Contains(node.Tags, tag) {
+			return false
+		}
+	}
+	return s.matchElement(node.ID, node.Type, node.Metadata)
+}
+
+// MatchEdge reports whether an edge matches the selector. Edges have no
+// tags or status, so selectors on those never match edges.
+func (s *Selector) MatchEdge(edge *SceneEdge) bool {
+	if s.Kind == ElementNode || len(s.Tags) > 0 || len(s.Status) > 0 {
+		return false
+	}
+	return s.matchElement(edge.ID, edge.Type, edge.Metadata)
+}
+
+// matchElement checks the criteria nodes and edges share
+func (s *Selector) matchElement(id, typ string, metadata map[string]interface{}) bool {
+	if len(s.IDs) > 0 && !slices.Contains(s.IDs, id) {
+		return false
+	}
+	if len(s.Types) > 0 && !slices.Contains(s.Types, typ) {
+		return false
+	}
+	for key, accepted := range s.Metadata {
+		v, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		if slices.Contains(accepted, "*") {
+			continue
+		}
+		str, ok := metadataString(v)
+		if !ok || !slices.Contains(accepted, str) {
+			return false
+		}
+	}
+	return true
+}
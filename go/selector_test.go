@@ -0,0 +1,72 @@
+package starfleet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestParseSelector tests parsing selector expressions and formatting them
+// back
+func TestParseSelector(t *testing.T) {
+	expr := "kind=node id=a,b type=server tag=prod tag=eu status=critical,warning metadata.env=staging"
+	s, err := ParseSelector(expr)
+	if err != nil {
+		t.Fatalf("ParseSelector failed: %v", err)
+	}
+	want := Selector{
+		Kind:     ElementNode,
+		IDs:      []string{"a", "b"},
+		Types:    []string{"server"},
+		Tags:     []string{"prod", "eu"},
+		Status:   []NodeStatus{NodeStatusCritical, NodeStatusWarning},
+		Metadata: map[string][]string{"env": {"staging"}},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("selector mismatch: got %+v, want %+v", s, want)
+	}
+	if s.String() != expr {
+		t.Errorf("String mismatch: got %q, want %q", s.String(), expr)
+	}
+	for _, bad := range []string{"type", "color=red", "kind=rack", "tag=a,b", "type=a,,b", "metadata.=x"} {
+		if _, err := ParseSelector(bad); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("%q: expected ErrInvalidSelector, got %v", bad, err)
+		}
+	}
+}
+
+// TestSelector_Match tests matching nodes and edges
+func TestSelector_Match(t *testing.T) {
+	node := SceneNode{ID: "a", Type: "server", Tags: []string{"prod", "eu"}, Status: NodeStatusCritical,
+		Metadata: map[string]interface{}{"env": "staging", "replicas": 3.0, "managed": true}}
+	edge := SceneEdge{ID: "a-b", Type: "http", Metadata: map[string]interface{}{"env": "staging"}}
+	tests := []struct {
+		expr       string
+		node, edge bool
+	}{
+		{"", true, true},
+		{"kind=node", true, false},
+		{"kind=edge", false, true},
+		{"type=server,http", true, true},
+		{"tag=prod tag=eu", true, false},
+		{"tag=prod tag=us", false, false},
+		{"status=critical", true, false},
+		{"metadata.env=staging", true, true},
+		{"metadata.replicas=3 metadata.managed=true", true, false},
+		{"metadata.owner=*", false, false},
+		{"metadata.env=*", true, true},
+		{"id=b", false, false},
+	}
+	for _, tt := range tests {
+		s, err := ParseSelector(tt.expr)
+		if err != nil {
+			t.Fatalf("%q: ParseSelector failed: %v", tt.expr, err)
+		}
+		if got := s.MatchNode(&node); got != tt.node {
+			t.Errorf("%q: node match mismatch: got %v, want %v", tt.expr, got, tt.node)
+		}
+		if got := s.MatchEdge(&edge); got != tt.edge {
+			t.Errorf("%q: edge match mismatch: got %v, want %v", tt.expr, got, tt.edge)
+		}
+	}
+}
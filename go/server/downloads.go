@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// DefaultSignedURLTTL is the lifetime of signed URLs when the request sets
+// none
+const DefaultSignedURLTTL = 15 * time.Minute
+
+// handleBlob serves a content-addressed asset. Content never changes, so
+// responses are cacheable forever.
+func (s *Server) handleBlob(w http.ResponseWriter, r *http.Request) {
+	if s.Blobs == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no blob store configured")
+		return
+	}
+	d, err := starfleet.ParseDigest(r.PathValue("digest"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, err.Error())
+		return
+	}
+	tag := `"` + string(d) + `"`
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	data, err := s.Blobs.Get(r.Context(), d)
+	if err != nil {
+		w.Header().Del("Cache-Control")
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// handleSignURL mints a download URL for a scene revision the acting
+// principal may read, or for an asset
+func (s *Server) handleSignURL(w http.ResponseWriter, r *http.Request) {
+	if s.URLSigner == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "url signing is not configured")
+		return
+	}
+	var req starfleet.SignedURLRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if (req.Scene == "") == (req.Asset == "") || req.TTL < 0 || req.Revision < 0 {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, "set exactly one of scene and asset, with a non-negative ttl")
+		return
+	}
+	ttl := DefaultSignedURLTTL
+	if req.TTL > 0 {
+		ttl = time.Duration(req.TTL * float64(time.Second))
+	}
+	ttl = min(ttl, starfleet.MaxPresignTTL)
+
+	var signed starfleet.SignedURL
+	var err error
+	if req.Scene != "" {
+		// Check existence so callers learn about typos now rather than from
+		// the viewer
+		rev, getErr := s.Store.GetRevision(r.Context(), req.Scene, req.Revision)
+		if getErr != nil {
+			writeError(w, getErr)
+			return
+		}
+		if !rev.Scene.SceneACL().CanRead(starfleet.PrincipalFromContext(r.Context())) {
+			writeError(w, fmt.Errorf("%w: %s", starfleet.ErrSceneNotFound, req.Scene))
+			return
+		}
+		path := scenePath(req.Scene)
+		if req.Revision > 0 {
+			path += "?revision=" + strconv.FormatInt(req.Revision, 10)
+		}
+		signed, err = s.URLSigner.Sign(path, ttl)
+	} else {
+		d, parseErr := starfleet.ParseDigest(req.Asset)
+		if parseErr != nil {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, parseErr.Error())
+			return
+		}
+		if s.Presigner != nil {
+			signed, err = s.Presigner.PresignGet(d, ttl)
+		} else if s.Blobs != nil {
+			signed, err = s.URLSigner.Sign("/blobs/"+string(d), ttl)
+		} else {
+			writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no blob store configured")
+			return
+		}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, signed)
+}
+
+// scenePath returns the URL path of a scene
+func scenePath(id string) string {
+	return "/scenes/" + url.PathEscape(id)
+}
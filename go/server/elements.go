@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// elementSource finds elements in a stored scene or an archived container
+type elementSource struct {
+	scene    *starfleet.SceneFile
+	archived *starfleet.IndexedScene
+}
+
+// handleGetNode returns one node of a scene. Nodes the acting principal
+// may not read are reported as not found.
+func (s *Server) handleGetNode(w http.ResponseWriter, r *http.Request) {
+	s.serveElement(w, r, func(src elementSource) (interface{}, error) {
+		id := r.PathValue("node")
+		node := src.scene.FindNode(id)
+		if src.archived != nil {
+			found, err := src.archived.Node(id)
+			if err != nil {
+				return nil, err
+			}
+			node = &found
+		}
+		if node == nil || !src.scene.NodeACL(node).CanRead(starfleet.PrincipalFromContext(r.Context())) {
+			return nil, fmt.Errorf("%w: %s", starfleet.ErrNodeNotFound, id)
+		}
+		return node, nil
+	})
+}
+
+// handleGetEdge returns one edge of a scene, hidden like nodes
+func (s *Server) handleGetEdge(w http.ResponseWriter, r *http.Request) {
+	s.serveElement(w, r, func(src elementSource) (interface{}, error) {
+		id := r.PathValue("edge")
+		edge := src.scene.FindEdge(id)
+		if src.archived != nil {
+			found, err := src.archived.Edge(id)
+			if err != nil {
+				return nil, err
+			}
+			edge = &found
+		}
+		if edge == nil || !src.scene.EdgeACL(edge).CanRead(starfleet.PrincipalFromContext(r.Context())) {
+			return nil, fmt.Errorf("%w: %s", starfleet.ErrEdgeNotFound, id)
+		}
+		return edge, nil
+	})
+}
+
+// serveElement looks an element up in the stored scene, or in the archived
+// container when the store does not have the scene
+func (s *Server) serveElement(w http.ResponseWriter, r *http.Request, find func(elementSource) (interface{}, error)) {
+	id := r.PathValue("id")
+	var src elementSource
+	rev, err := s.revision(r.Context(), id, 0)
+	switch {
+	case err == nil:
+		src.scene = &rev.Scene
+		w.Header().Set("ETag", starfleet.RevisionTag(rev.Revision))
+	case !errors.Is(err, starfleet.ErrSceneNotFound) || s.Archive == nil:
+		writeError(w, err)
+		return
+	default:
+		if src.archived, err = s.Archive.OpenScene(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+		defer src.archived.Close()
+		// The header carries the scene ACL the element may inherit
+		header, err := src.archived.Header()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		src.scene = &header
+	}
+
+	element, err := find(src)
+	if err != nil {
+		w.Header().Del("ETag")
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, element)
+}
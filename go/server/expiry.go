@@ -0,0 +1,36 @@
+package server
+
+import (
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// ExpirySweeper returns a sweeper removing expired nodes and edges from the
+// server's store every interval. Each revision it writes is announced on
+// the scene's event stream as an expired event and sent to webhooks, as
+// writes made through the server are. Start it with Run.
+func (s *Server) ExpirySweeper(interval time.Duration) *starfleet.ExpirySweeper {
+	sweeper := starfleet.NewExpirySweeper(s.Store, interval)
+	sweeper.OnSweep = s.expired
+	return sweeper
+}
+
+// expired notifies stream subscribers and webhooks of an expiry sweep
+func (s *Server) expired(sweep starfleet.ExpirySweep) {
+	s.invalidate(sweep.Revision.ID)
+	s.hub.publish(sweep.Event())
+	if s.Webhooks == nil {
+		return
+	}
+	s.Webhooks.Notify(starfleet.WebhookPayload{
+		Event:    starfleet.SceneEventExpired,
+		SceneID:  sweep.Revision.ID,
+		Revision: sweep.Revision.Revision,
+		Time:     sweep.Revision.Updated,
+		Diff: &starfleet.DiffSummary{
+			NodesRemoved: len(sweep.Expired.Nodes),
+			EdgesRemoved: len(sweep.Expired.Edges),
+		},
+	})
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// visibleGroup removes the members of a group the principal may not read
+func visibleGroup(sf *starfleet.SceneFile, p starfleet.Principal, g starfleet.SmartGroup) starfleet.SmartGroup {
+	g.Nodes = slices.DeleteFunc(g.Nodes, func(id string) bool { return !sf.NodeACL(sf.FindNode(id)).CanRead(p) })
+	g.Edges = slices.DeleteFunc(g.Edges, func(id string) bool { return !sf.EdgeACL(sf.FindEdge(id)).CanRead(p) })
+	return g
+}
+
+// visibleGroups evaluates the saved queries of a scene for the principal,
+// keeping those named in only when it is not empty
+func visibleGroups(sf *starfleet.SceneFile, p starfleet.Principal, only []string) ([]starfleet.SmartGroup, error) {
+	groups, err := sf.SmartGroups()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]starfleet.SmartGroup, 0, len(groups))
+	for _, g := range groups {
+		if len(only) == 0 || slices.Contains(only, g.Name) {
+			out = append(out, visibleGroup(sf, p, g))
+		}
+	}
+	return out, nil
+}
+
+// handleListGroups evaluates every saved query of the latest revision of a
+// scene
+func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	rev, err := s.revision(r.Context(), r.PathValue("id"), 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	groups, err := visibleGroups(&rev.Scene, starfleet.PrincipalFromContext(r.Context()), nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", starfleet.RevisionTag(rev.Revision))
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// handleGetGroup evaluates one saved query of the latest revision of a
+// scene
+func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	rev, err := s.revision(r.Context(), r.PathValue("id"), 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	g, err := rev.Scene.SmartGroup(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", starfleet.RevisionTag(rev.Revision))
+	writeJSON(w, http.StatusOK, visibleGroup(&rev.Scene, starfleet.PrincipalFromContext(r.Context()), g))
+}
+
+// handleMembership streams membership changes of a scene's smart groups,
+// or of those named by ?group=, as server-sent membership events. The
+// first event adds the current members of every group; later events are
+// sent for revisions that change membership, including changes to the
+// saved queries themselves. The stream ends after the scene is deleted.
+func (s *Server) handleMembership(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	only := r.URL.Query()["group"]
+	p := starfleet.PrincipalFromContext(ctx)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "streaming is not supported by this connection")
+		return
+	}
+
+	ch := s.hub.subscribe(id)
+	defer s.hub.unsubscribe(id, ch)
+	current, err := s.Store.Get(ctx, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	groups, err := visibleGroups(&current.Scene, p, only)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	revision := current.Revision
+	initial := starfleet.SceneEvent{
+		Type:       starfleet.SceneEventMembership,
+		ID:         id,
+		Revision:   revision,
+		Time:       current.Updated,
+		Membership: starfleet.DiffSmartGroups(nil, groups),
+	}
+	if writeEvent(w, initial) != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-ch:
+			if !ok || event.Type == starfleet.SceneEventDeleted {
+				return
+			}
+			if event.Scene == nil || event.Revision <= revision {
+				continue
+			}
+			revision = event.Revision
+			next, err := visibleGroups(event.Scene, p, only)
+			if err != nil {
+				// Stored scenes are validated, so this is an old revision
+				// with malformed queries; wait for a fixed one
+				continue
+			}
+			changes := starfleet.DiffSmartGroups(groups, next)
+			groups = next
+			if len(changes) == 0 {
+				continue
+			}
+			membership := starfleet.SceneEvent{
+				Type:       starfleet.SceneEventMembership,
+				ID:         id,
+				Revision:   event.Revision,
+				Time:       event.Time,
+				Membership: changes,
+			}
+			if writeEvent(w, membership) != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
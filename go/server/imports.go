@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// handleSubmitImport queues an import with a registered importer and
+// answers 202 with the job, whose URL is in the Location header
+func (s *Server) handleSubmitImport(w http.ResponseWriter, r *http.Request) {
+	if s.Imports == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no import queue configured")
+		return
+	}
+	var req starfleet.ImportRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	importer, ok := s.Importers[req.Importer]
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("unknown importer %q", req.Importer))
+		return
+	}
+	job, err := s.Imports.Submit(req.Importer, importer, req.Config)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", importPath(job.ID))
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleListImports returns the retained import jobs, oldest first
+func (s *Server) handleListImports(w http.ResponseWriter, r *http.Request) {
+	if s.Imports == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no import queue configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Imports.Jobs())
+}
+
+// handleGetImport returns the state of an import job for polling
+func (s *Server) handleGetImport(w http.ResponseWriter, r *http.Request) {
+	if s.Imports == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no import queue configured")
+		return
+	}
+	job, err := s.Imports.Job(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleCancelImport cancels an import job and returns its state, which
+// stays running until the importer notices
+func (s *Server) handleCancelImport(w http.ResponseWriter, r *http.Request) {
+	if s.Imports == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no import queue configured")
+		return
+	}
+	id := r.PathValue("id")
+	if err := s.Imports.Cancel(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	job, err := s.Imports.Job(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleImportEvents streams the progress of an import job as server-sent
+// events named after the job state. The stream ends with the final state.
+func (s *Server) handleImportEvents(w http.ResponseWriter, r *http.Request) {
+	if s.Imports == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no import queue configured")
+		return
+	}
+	ctx := r.Context()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "streaming is not supported by this connection")
+		return
+	}
+	updates, err := s.Imports.Watch(ctx, r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case job, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(job)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", job.State, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// importPath returns the URL path of an import job
+func importPath(id string) string {
+	return "/imports/" + url.PathEscape(id)
+}
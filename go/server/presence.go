@@ -0,0 +1,212 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// Presence defaults
+const (
+	// DefaultPresenceInterval is the minimum interval between broadcasts of
+	// one viewer's presence
+	DefaultPresenceInterval = 100 * time.Millisecond
+	// DefaultPresenceTimeout is how long a viewer stays present without
+	// sending an update
+	DefaultPresenceTimeout = 30 * time.Second
+)
+
+// roster tracks the viewers present on each scene and throttles their
+// broadcasts. Updates arriving faster than the interval are coalesced: the
+// latest one is sent when the interval has passed, so the last camera
+// position is never lost.
+type roster struct {
+	mu     sync.Mutex
+	scenes map[string]map[string]*presenceEntry
+}
+
+type presenceEntry struct {
+	presence starfleet.Presence
+	// visible is set once the viewer has been broadcast without Hidden
+	visible bool
+	sent    time.Time
+	timer   *time.Timer
+}
+
+// update records a viewer's presence and broadcasts it, now or once the
+// throttle interval has passed. Sessions present under another user are
+// refused with starfleet.ErrAccessDenied.
+func (r *roster) update(scene string, p starfleet.Presence, interval, timeout time.Duration, publish func(starfleet.SceneEvent)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire(scene, p.Time, timeout, publish)
+	if entry := r.scenes[scene][p.Session]; entry != nil && entry.presence.User != p.User {
+		return fmt.Errorf("%w: %q may not update session %s", starfleet.ErrAccessDenied, p.User, p.Session)
+	}
+	if r.scenes == nil {
+		r.scenes = make(map[string]map[string]*presenceEntry)
+	}
+	if r.scenes[scene] == nil {
+		r.scenes[scene] = make(map[string]*presenceEntry)
+	}
+	entry := r.scenes[scene][p.Session]
+	if entry == nil {
+		entry = &presenceEntry{}
+		r.scenes[scene][p.Session] = entry
+	}
+	entry.presence = p
+	if entry.timer != nil {
+		return nil
+	}
+	if wait := interval - p.Time.Sub(entry.sent); wait > 0 {
+		entry.timer = time.AfterFunc(wait, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			entry.timer = nil
+			if r.scenes[scene][entry.presence.Session] == entry {
+				r.broadcast(scene, entry, publish)
+			}
+		})
+		return nil
+	}
+	r.broadcast(scene, entry, publish)
+	return nil
+}
+
+// leave removes a viewer on behalf of user, telling the others when they
+// could see it. Sessions of other users are refused with
+// starfleet.ErrAccessDenied.
+func (r *roster) leave(scene, session, user string, now time.Time, publish func(starfleet.SceneEvent)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := r.scenes[scene][session]
+	if entry == nil {
+		return nil
+	}
+	if entry.presence.User != user {
+		return fmt.Errorf("%w: %q may not end session %s", starfleet.ErrAccessDenied, user, session)
+	}
+	r.remove(scene, entry, now, publish)
+	return nil
+}
+
+// list returns the presence of the viewers others may see, by session
+func (r *roster) list(scene string, now time.Time, timeout time.Duration, publish func(starfleet.SceneEvent)) []starfleet.Presence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire(scene, now, timeout, publish)
+	present := []starfleet.Presence{}
+	for _, entry := range r.scenes[scene] {
+		if p, ok := entry.presence.Redacted(); ok {
+			present = append(present, p)
+		}
+	}
+	sort.Slice(present, func(a, b int) bool { return present[a].Session < present[b].Session })
+	return present
+}
+
+// broadcast publishes a viewer's latest presence. A viewer turning hidden
+// is announced as having left. Callers hold r.mu.
+func (r *roster) broadcast(scene string, entry *presenceEntry, publish func(starfleet.SceneEvent)) {
+	p, ok := entry.presence.Redacted()
+	if !ok {
+		if entry.visible {
+			entry.visible = false
+			publish(presenceEvent(scene, starfleet.Presence{Session: entry.presence.Session, Left: true, Time: entry.presence.Time}))
+		}
+		return
+	}
+	entry.visible = true
+	entry.sent = entry.presence.Time
+	publish(presenceEvent(scene, p))
+}
+
+// expire removes viewers not heard from within timeout. Callers hold r.mu.
+func (r *roster) expire(scene string, now time.Time, timeout time.Duration, publish func(starfleet.SceneEvent)) {
+	for _, entry := range r.scenes[scene] {
+		if now.Sub(entry.presence.Time) > timeout {
+			r.remove(scene, entry, now, publish)
+		}
+	}
+}
+
+// remove forgets a viewer and cancels its pending broadcast. Callers hold
+// r.mu.
+func (r *roster) remove(scene string, entry *presenceEntry, now time.Time, publish func(starfleet.SceneEvent)) {
+	if entry.timer != nil {
+		entry.timer.Stop()
+		entry.timer = nil
+	}
+	delete(r.scenes[scene], entry.presence.Session)
+	if len(r.scenes[scene]) == 0 {
+		delete(r.scenes, scene)
+	}
+	if entry.visible {
+		publish(presenceEvent(scene, starfleet.Presence{Session: entry.presence.Session, Left: true, Time: now}))
+	}
+}
+
+func presenceEvent(scene string, p starfleet.Presence) starfleet.SceneEvent {
+	return starfleet.SceneEvent{Type: starfleet.SceneEventPresence, ID: scene, Time: p.Time, Presence: &p}
+}
+
+// handlePresence records the acting viewer's presence on a scene and
+// broadcasts it on the scene's event stream, subject to its privacy
+// settings and throttling. The user is the acting user, whatever the body
+// names, and only they may update the session.
+func (s *Server) handlePresence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	var p starfleet.Presence
+	if !s.decodeBody(w, r, &p) {
+		return
+	}
+	if p.Session == "" {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, "presence requires a session")
+		return
+	}
+	if _, err := s.Store.Get(ctx, id); err != nil {
+		writeError(w, err)
+		return
+	}
+	p.User = starfleet.ActorFromContext(ctx)
+	p.Left, p.Time = false, time.Now().UTC()
+	if err := s.roster.update(id, p, s.presenceInterval(), s.presenceTimeout(), s.hub.publish); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, p)
+}
+
+// handleListPresence returns the viewers present on a scene, as others see
+// them
+func (s *Server) handleListPresence(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.roster.list(r.PathValue("id"), time.Now().UTC(), s.presenceTimeout(), s.hub.publish))
+}
+
+// handleLeave removes a viewer of the acting user from a scene
+func (s *Server) handleLeave(w http.ResponseWriter, r *http.Request) {
+	if err := s.roster.leave(r.PathValue("id"), r.PathValue("session"), starfleet.ActorFromContext(r.Context()), time.Now().UTC(), s.hub.publish); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) presenceInterval() time.Duration {
+	if s.PresenceInterval <= 0 {
+		return DefaultPresenceInterval
+	}
+	return s.PresenceInterval
+}
+
+func (s *Server) presenceTimeout() time.Duration {
+	if s.PresenceTimeout <= 0 {
+		return DefaultPresenceTimeout
+	}
+	return s.PresenceTimeout
+}
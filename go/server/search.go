@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// searchCache keeps the search index of the latest revision of each scene
+// searched, so repeated searches skip rebuilding it
+type searchCache struct {
+	mu      sync.Mutex
+	indexes map[string]cachedIndex
+}
+
+type cachedIndex struct {
+	revision int64
+	index    *starfleet.SearchIndex
+}
+
+// get returns the index of a scene revision, building it when the cached
+// one is for another revision
+func (c *searchCache) get(rev *starfleet.SceneRevision) *starfleet.SearchIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.indexes[rev.ID]; ok && cached.revision == rev.Revision {
+		return cached.index
+	}
+	if c.indexes == nil {
+		c.indexes = make(map[string]cachedIndex)
+	}
+	idx := starfleet.BuildSearchIndex(&rev.Scene)
+	c.indexes[rev.ID] = cachedIndex{rev.Revision, idx}
+	return idx
+}
+
+// drop forgets the index of a scene
+func (c *searchCache) drop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.indexes, id)
+}
+
+// handleSearch searches the nodes of the latest revision of a scene with
+// the query in ?q=, returning at most ?limit= results. Nodes the acting
+// principal may not read are left out.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := starfleet.DefaultSearchOptions()
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+		opts.Limit = min(parsed, MaxPageSize)
+	}
+	id := r.PathValue("id")
+	rev, err := s.revision(r.Context(), id, 0)
+	if err != nil {
+		s.searches.drop(id)
+		writeError(w, err)
+		return
+	}
+	// Filter before limiting so hidden nodes do not use up the page
+	limit := opts.Limit
+	opts.Limit = 0
+	p := starfleet.PrincipalFromContext(r.Context())
+	results := []starfleet.SearchResult{}
+	for _, result := range s.searches.get(&rev).Search(query.Get("q"), opts) {
+		if node := rev.Scene.FindNode(result.NodeID); node != nil && rev.Scene.NodeACL(node).CanRead(p) {
+			results = append(results, result)
+		}
+		if len(results) == limit {
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, starfleet.SearchResponse{Revision: rev.Revision, Results: results})
+}
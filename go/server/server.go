@@ -0,0 +1,1119 @@
+// Package server exposes a starfleet.SceneStore over HTTP.
+//
+// Scenes are read with GET /scenes/{id} and written with PUT (full
+// replacement) or PATCH (RFC 7386 merge patch, or RFC 6902 JSON patch with
+// Content-Type application/json-patch+json). Every response carries the
+// scene revision as an ETag; writes must send it back in If-Match, and a
+// write based on a stale revision fails with 409 Conflict and a JSON body
+// whose conflict.diff lists what changed since that revision. Nodes and
+// edges carry their own revision numbers, and a write that changes an
+// element based on an older one fails with 409 stale_element, so editors of
+// separate elements can write with If-Match: * without losing updates. A
+// full PUT with If-Match: * that leaves out nodes or edges must name the
+// revision it was edited from in X-Starfleet-Base-Revision; removing
+// elements added or changed since, or removing any without the header,
+// fails with 412 stale_element.
+// Viewers sending Accept: application/vnd.starfleet.scene+flatbuffers get
+// the scene as a FlatBuffer they can read with starfleet.OpenFlatScene.
+// Those accepting the XLSX, draw.io or binary glTF media types download it
+// as a spreadsheet workbook, a diagram or a meshopt-compressed 3D model.
+//
+// GET /scenes pages through the catalog, GET /scenes/{id}/events streams
+// changes as server-sent events, GET /scenes/{id}/search?q= searches node
+// names, tags and metadata, GET /scenes/{id}/stats reports the size and
+// shape of a scene, computed once per revision, and POST /metrics/query
+// answers metrics queries when a MetricsSource is configured. With a
+// starfleet.SceneCache set, these reads share decoded scenes instead of
+// decoding the stored scene on every request.
+//
+// GET /scenes/{id}/nodes/{node} and GET /scenes/{id}/edges/{edge} return
+// one element. Scenes missing from the store are looked up in the Archive,
+// whose indexed containers are read one chunk at a time, so a cold scene
+// serves single elements without being loaded whole.
+//
+// Saved queries are selector expressions stored in the scene's
+// savedQueries extension and written with the scene. GET
+// /scenes/{id}/groups evaluates them as smart groups, GET
+// /scenes/{id}/groups/{name} evaluates one, and GET
+// /scenes/{id}/membership streams membership changes as the scene is
+// written.
+//
+// Nodes and edges may carry an expiry. Run the sweeper from
+// Server.ExpirySweeper to remove them once expired; each sweep is streamed
+// as an expired event listing what was removed and sent to webhooks.
+//
+// Set Server.Authenticate to identify the principal behind each request
+// from its credentials; requests it rejects fail with 401. The principal is
+// recorded with starfleet.WithPrincipal and its ID as the actor with
+// starfleet.WithActor, which webhook notifications and lifecycle history
+// include. Without it, requests may name the acting user in the
+// X-Starfleet-Actor header, which is trusted for attribution only: such
+// requests are anonymous to ACLs.
+//
+// Change control is enforced by the store: wrap it with
+// starfleet.NewGuardedStore and starfleet.LifecycleGuard, and with
+// starfleet.ACLGuard to honor the ACLs of scenes, nodes and edges for the
+// authenticated principal. ACL enforcement requires Server.Authenticate:
+// ACLGuard refuses writes without an authenticated principal with 401
+// unauthenticated. Writes touching elements the principal may not write
+// fail with 403 access_denied. Scene reads and exports, event streams,
+// stats, search results, groups and conflict diffs only include what it
+// may read, and scenes it may not read at all are not found, nor signed for
+// it. Writes keep the elements hidden from it, and patches address the
+// scene as it sees it.
+//
+// POST /scenes/{id}/lifecycle moves a scene between lifecycle states and
+// POST /scenes/{id}/approvals records the approval of the authenticated
+// principal. POST /scenes/{id}/approval-policy sets the approvers a draft
+// needs and is open to Server.PolicyAdmins only. All three are refused
+// unless Server.Authenticate is set. Ordinary writes cannot change the
+// lifecycle state, approval policy, approvals or history of a guarded
+// scene.
+//
+// With a BlobStore configured, GET /blobs/{digest} serves content-addressed
+// assets. With a URLSigner, POST /signed-urls mints expiring download URLs
+// for scenes and assets, so viewers can fetch large content without API
+// credentials; asset URLs point straight at object storage when a
+// Presigner is set. Requests carrying a signature are verified and refused
+// with 403 when it is invalid or expired. Reads with a valid signature skip
+// Server.Authenticate and are served to the anonymous principal, so a
+// fronting proxy can let them through unauthenticated.
+//
+// With an ImportQueue, POST /imports runs one of the registered Importers
+// in the background and answers 202 with the job. Clients poll GET
+// /imports/{id}, follow GET /imports/{id}/events, or cancel with DELETE
+// /imports/{id}; the finished job carries the ImportResult.
+//
+// With a SessionStore, /scenes/{id}/sessions/{session} holds the view state
+// of a viewer session: camera, selection, filters and pinned panels. PUT
+// writes it, unconditionally or with If-Match for the session revision, and
+// GET /scenes/{id}/sessions/{session}/events streams it so devices sharing
+// the session follow each other. Sessions belong to the user who created
+// them; writes and deletes by anyone else fail with 403.
+//
+// Viewers announce themselves with POST /scenes/{id}/presence, sending their
+// camera and selection as they change, and DELETE
+// /scenes/{id}/presence/{session} when they leave. Presence is recorded
+// for the acting user, and only they may update or end it. It is broadcast
+// on the scene's event stream, at most every PresenceInterval per viewer
+// and redacted by each viewer's privacy settings; viewers that stop
+// updating drop out after PresenceTimeout. GET /scenes/{id}/presence lists
+// who is present, and streams opened with ?presence=false omit presence.
+//
+// POST /validate is a dry run of a write: it checks a scene the way PUT
+// does, stores nothing and reports the results, as SARIF with
+// ?format=sarif or JUnit XML with ?format=junit, so CI can gate scene
+// changes.
+//
+// GET /compatibility reports the supported scene format versions. Writes of
+// scenes this SDK cannot read are rejected, and readers may send
+// X-Starfleet-Accept-Version with the range they understand; scenes outside
+// it are downgraded with starfleet.Downgrade when possible, with the
+// capabilities that lost data listed in X-Starfleet-Downgrade-Lost, and
+// otherwise refused with 406 and a compatibility report. Viewers may also
+// send X-Starfleet-Viewer-Version and X-Starfleet-Viewer-Features to be
+// checked against a scene's requirements: optional features they lack are
+// stripped and listed in X-Starfleet-Viewer-Disabled, and viewers that
+// cannot render the scene get 406 with its fallback.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// DefaultMaxBodyBytes bounds the size of request bodies
+const DefaultMaxBodyBytes = 32 << 20
+
+// Page sizes of the scene catalog
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// Server serves scenes from a store
+type Server struct {
+	Store starfleet.SceneStore
+	// Authenticate identifies the principal making a request, returning an
+	// error when its credentials are missing or invalid. When set, the
+	// X-Starfleet-Actor header is ignored. Approvals and stores guarded by
+	// starfleet.ACLGuard require it. Reads of validly signed URLs skip it.
+	Authenticate func(r *http.Request) (starfleet.Principal, error)
+	// PolicyAdmins are the principal IDs and groups allowed to set the
+	// approval policy of scenes; empty disables the endpoint
+	PolicyAdmins []string
+	// Metrics answers metrics queries; nil disables the endpoint
+	Metrics starfleet.MetricsSource
+	// Webhooks is notified of every create, update and delete
+	Webhooks *starfleet.WebhookDispatcher
+	// MaxBodyBytes bounds request bodies; zero uses DefaultMaxBodyBytes
+	MaxBodyBytes int64
+	// KeepAlive is the idle interval of event streams; zero uses
+	// DefaultKeepAlive
+	KeepAlive time.Duration
+	// Cache, when set, serves reads of the latest revision of scenes from
+	// memory. Its store must be Store; the server invalidates it on writes.
+	Cache *starfleet.SceneCache
+	// Archive holds cold scenes the node and edge endpoints read when the
+	// store does not have them; nil serves stored scenes only
+	Archive starfleet.SceneArchive
+	// Blobs serves GET /blobs/{digest}; nil disables the endpoint
+	Blobs starfleet.BlobStore
+	// URLSigner mints and verifies signed URLs; nil disables POST
+	// /signed-urls and signature checks
+	URLSigner *starfleet.URLSigner
+	// Presigner, when set, signs asset URLs for object storage instead of
+	// the blob endpoint
+	Presigner starfleet.BlobPresigner
+	// Imports runs import jobs; nil disables the /imports endpoints
+	Imports *starfleet.ImportQueue
+	// Importers are the importers clients may run, by name
+	Importers map[string]starfleet.Importer
+	// Sessions keeps viewer session state; nil disables the
+	// /scenes/{id}/sessions endpoints
+	Sessions *starfleet.SessionStore
+	// PresenceInterval is the minimum interval between presence broadcasts
+	// of one viewer; zero uses DefaultPresenceInterval
+	PresenceInterval time.Duration
+	// PresenceTimeout is how long viewers stay present without an update;
+	// zero uses DefaultPresenceTimeout
+	PresenceTimeout time.Duration
+
+	mux      *http.ServeMux
+	hub      hub
+	roster   roster
+	searches searchCache
+	stats    statsCache
+}
+
+// New creates a server backed by store
+func New(store starfleet.SceneStore) *Server {
+	s := &Server{Store: store, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /scenes", s.handleList)
+	s.mux.HandleFunc("GET /scenes/{id}", s.handleGet)
+	s.mux.HandleFunc("PUT /scenes/{id}", s.handlePut)
+	s.mux.HandleFunc("PATCH /scenes/{id}", s.handlePatch)
+	s.mux.HandleFunc("DELETE /scenes/{id}", s.handleDelete)
+	s.mux.HandleFunc("GET /scenes/{id}/events", s.handleStream)
+	s.mux.HandleFunc("GET /scenes/{id}/search", s.handleSearch)
+	s.mux.HandleFunc("GET /scenes/{id}/stats", s.handleStats)
+	s.mux.HandleFunc("GET /scenes/{id}/nodes/{node}", s.handleGetNode)
+	s.mux.HandleFunc("GET /scenes/{id}/edges/{edge}", s.handleGetEdge)
+	s.mux.HandleFunc("GET /scenes/{id}/groups", s.handleListGroups)
+	s.mux.HandleFunc("GET /scenes/{id}/groups/{name}", s.handleGetGroup)
+	s.mux.HandleFunc("GET /scenes/{id}/membership", s.handleMembership)
+	s.mux.HandleFunc("POST /scenes/{id}/lifecycle", s.handleTransition)
+	s.mux.HandleFunc("POST /scenes/{id}/approvals", s.handleApprove)
+	s.mux.HandleFunc("POST /scenes/{id}/approval-policy", s.handleApprovalPolicy)
+	s.mux.HandleFunc("POST /metrics/query", s.handleMetrics)
+	s.mux.HandleFunc("GET /compatibility", s.handleCompatibility)
+	s.mux.HandleFunc("POST /validate", s.handleValidate)
+	s.mux.HandleFunc("GET /blobs/{digest}", s.handleBlob)
+	s.mux.HandleFunc("POST /signed-urls", s.handleSignURL)
+	s.mux.HandleFunc("POST /imports", s.handleSubmitImport)
+	s.mux.HandleFunc("GET /imports", s.handleListImports)
+	s.mux.HandleFunc("GET /imports/{id}", s.handleGetImport)
+	s.mux.HandleFunc("DELETE /imports/{id}", s.handleCancelImport)
+	s.mux.HandleFunc("GET /imports/{id}/events", s.handleImportEvents)
+	s.mux.HandleFunc("POST /scenes/{id}/presence", s.handlePresence)
+	s.mux.HandleFunc("GET /scenes/{id}/presence", s.handleListPresence)
+	s.mux.HandleFunc("DELETE /scenes/{id}/presence/{session}", s.handleLeave)
+	s.mux.HandleFunc("GET /scenes/{id}/sessions", s.handleListSessions)
+	s.mux.HandleFunc("GET /scenes/{id}/sessions/{session}", s.handleGetSession)
+	s.mux.HandleFunc("PUT /scenes/{id}/sessions/{session}", s.handlePutSession)
+	s.mux.HandleFunc("DELETE /scenes/{id}/sessions/{session}", s.handleDeleteSession)
+	s.mux.HandleFunc("GET /scenes/{id}/sessions/{session}/events", s.handleSessionEvents)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	signed, ok := s.signedRead(w, r)
+	if !ok {
+		return
+	}
+	if s.Authenticate != nil && !signed {
+		p, err := s.Authenticate(r)
+		if err != nil {
+			writeAPIError(w, http.StatusUnauthorized, starfleet.APIErrorUnauthenticated, err.Error())
+			return
+		}
+		r = r.WithContext(starfleet.WithPrincipal(starfleet.WithActor(r.Context(), p.ID), p))
+	} else if actor := r.Header.Get(starfleet.ActorHeader); actor != "" {
+		r = r.WithContext(starfleet.WithActor(r.Context(), actor))
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// signedRead reports whether r reads a URL carrying a valid signature,
+// which stands in for credentials: such requests are not authenticated and
+// are served to the anonymous principal. It reports false as its second
+// result when it has refused an invalid signature.
+func (s *Server) signedRead(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	if s.URLSigner == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) || !starfleet.IsSignedURL(r.URL) {
+		return false, true
+	}
+	if err := s.URLSigner.Verify(r.URL); err != nil {
+		writeAPIError(w, http.StatusForbidden, starfleet.APIErrorInvalidSignature, err.Error())
+		return false, false
+	}
+	return true, true
+}
+
+// revision returns a revision of a scene, zero meaning the latest, which
+// comes from the cache when one is configured. The scene is shared and
+// must not be modified in place.
+func (s *Server) revision(ctx context.Context, id string, revision int64) (starfleet.SceneRevision, error) {
+	if s.Cache == nil || revision != 0 {
+		return s.Store.GetRevision(ctx, id, revision)
+	}
+	x, err := s.Cache.Get(ctx, id)
+	if err != nil {
+		return starfleet.SceneRevision{}, err
+	}
+	return x.Revision, nil
+}
+
+// invalidate drops a written scene from the cache
+func (s *Server) invalidate(id string) {
+	if s.Cache != nil {
+		s.Cache.Invalidate(id)
+	}
+}
+
+// visibleRevision narrows a revision to what the acting principal may
+// read. It reports false, with the scene left empty, when the principal may
+// not read the scene at all.
+func visibleRevision(ctx context.Context, rev starfleet.SceneRevision) (starfleet.SceneRevision, bool) {
+	var ok bool
+	rev.Scene, ok = rev.Scene.ReadableBy(starfleet.PrincipalFromContext(ctx))
+	return rev, ok
+}
+
+// withHidden adds the elements of the current revision hidden from the
+// acting principal back to a scene it writes, so writes from clients that
+// see part of a scene keep the rest
+func (s *Server) withHidden(ctx context.Context, id string, sf starfleet.SceneFile) (starfleet.SceneFile, error) {
+	current, err := s.Store.Get(ctx, id)
+	if errors.Is(err, starfleet.ErrSceneNotFound) {
+		return sf, nil
+	}
+	if err != nil {
+		return sf, err
+	}
+	return starfleet.RestoreHidden(&current.Scene, &sf, starfleet.PrincipalFromContext(ctx)), nil
+}
+
+// handleList returns a page of scene summaries ordered by ID. The cursor
+// is opaque to clients and encodes the last ID of the previous page.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := DefaultPageSize
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+		limit = min(parsed, MaxPageSize)
+	}
+	var after string
+	if v := query.Get("cursor"); v != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("invalid cursor %q", v))
+			return
+		}
+		after = string(decoded)
+	}
+
+	summaries, err := s.Store.List(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	start := 0
+	if after != "" {
+		start = sort.Search(len(summaries), func(i int) bool { return summaries[i].ID > after })
+	}
+	end := min(start+limit, len(summaries))
+	page := starfleet.ScenePage{Scenes: summaries[start:end]}
+	if end < len(summaries) {
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(summaries[end-1].ID))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var revision int64
+	if v := r.URL.Query().Get("revision"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 1 {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("invalid revision %q", v))
+			return
+		}
+		revision = parsed
+	}
+	rev, err := s.revision(r.Context(), id, revision)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rev, ok := visibleRevision(r.Context(), rev)
+	if !ok {
+		writeError(w, fmt.Errorf("%w: %s", starfleet.ErrSceneNotFound, id))
+		return
+	}
+	vary := []string{"Accept", starfleet.AcceptVersionHeader, starfleet.ViewerVersionHeader, starfleet.ViewerFeaturesHeader}
+	if s.Authenticate != nil {
+		// What the scene shows depends on who asks
+		vary = append(vary, "Authorization")
+	}
+	w.Header().Set("Vary", strings.Join(vary, ", "))
+	if accept := r.Header.Get(starfleet.AcceptVersionHeader); accept != "" {
+		result, err := starfleet.CheckSceneCompatibility(&rev.Scene, accept)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, err.Error())
+			return
+		}
+		if result.Action == starfleet.CompatibilityDowngrade {
+			if rev.Scene, err = s.downgrade(w, rev.Scene, result.Target); err != nil {
+				writeError(w, err)
+				return
+			}
+		} else if !result.Compatible() {
+			writeJSON(w, http.StatusNotAcceptable, &starfleet.APIError{
+				Code:          starfleet.APIErrorIncompatibleVersion,
+				Message:       result.Reason,
+				Compatibility: &result,
+			})
+			return
+		}
+	}
+	if version := r.Header.Get(starfleet.ViewerVersionHeader); version != "" {
+		viewer, err := starfleet.ParseViewerInfo(version, r.Header.Get(starfleet.ViewerFeaturesHeader))
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, err.Error())
+			return
+		}
+		var ok bool
+		if rev.Scene, ok = s.negotiateViewer(w, rev.Scene, viewer); !ok {
+			return
+		}
+	}
+	tag := starfleet.RevisionTag(rev.Revision)
+	w.Header().Set("ETag", tag)
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if accepts(r, starfleet.FlatSceneContentType) {
+		// The FlatBuffer holds the scene alone; the revision is in the ETag
+		data, err := starfleet.EncodeFlatScene(&rev.Scene)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", starfleet.FlatSceneContentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+		return
+	}
+	for _, export := range sceneExports {
+		if !accepts(r, export.mediaType) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := export.encode(&buf, &rev.Scene); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", export.mediaType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": id + export.extension}))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+	writeJSON(w, http.StatusOK, rev)
+}
+
+// sceneExports are the formats GET /scenes/{id} downloads scenes in when
+// the client accepts them
+var sceneExports = []struct {
+	mediaType string
+	extension string
+	encode    func(io.Writer, *starfleet.SceneFile) error
+}{
+	{starfleet.WorkbookContentType, ".xlsx", func(w io.Writer, sf *starfleet.SceneFile) error {
+		return starfleet.EncodeWorkbook(w, sf, starfleet.WorkbookOptions{})
+	}},
+	{starfleet.DrawioContentType, ".drawio", func(w io.Writer, sf *starfleet.SceneFile) error {
+		return starfleet.EncodeDrawio(w, sf, starfleet.DrawioOptions{})
+	}},
+	{starfleet.GLTFContentType, ".glb", func(w io.Writer, sf *starfleet.SceneFile) error {
+		return starfleet.EncodeGLB(w, sf, starfleet.GLTFOptions{MeshCompression: true})
+	}},
+}
+
+// accepts reports whether the Accept header of r lists mediaType
+func accepts(r *http.Request, mediaType string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if t, params, err := mime.ParseMediaType(part); err == nil && t == mediaType && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// handlePut replaces a scene. Creating a scene needs no precondition (or
+// If-None-Match: *); replacing one requires If-Match.
+func (s *Server) handlePut(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	expected, ok := s.putPrecondition(w, r, id)
+	if !ok {
+		return
+	}
+	var base int64
+	if header := r.Header.Get(starfleet.BaseRevisionHeader); header != "" && expected == starfleet.AnyRevision {
+		var err error
+		if base, err = starfleet.ParseRevisionTag(header); err != nil || base == starfleet.AnyRevision {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest,
+				fmt.Sprintf("%s must name a revision", starfleet.BaseRevisionHeader))
+			return
+		}
+	}
+	var sf starfleet.SceneFile
+	if !s.decodeBody(w, r, &sf) {
+		return
+	}
+	sf, err := s.withHidden(ctx, id, sf)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !validate(w, r, &sf) {
+		return
+	}
+	var rev starfleet.SceneRevision
+	if expected == starfleet.AnyRevision {
+		rev, err = s.putAnyRevision(ctx, id, sf, base)
+	} else {
+		rev, err = s.Store.Put(ctx, id, sf, expected)
+	}
+	if err != nil {
+		writeError(w, s.visibleConflict(ctx, id, err))
+		return
+	}
+	s.published(ctx, rev)
+	w.Header().Set("ETag", starfleet.RevisionTag(rev.Revision))
+	status := http.StatusOK
+	if rev.Revision == 1 {
+		w.Header().Set("Location", "/scenes/"+url.PathEscape(id))
+		status = http.StatusCreated
+	}
+	rev, _ = visibleRevision(ctx, rev)
+	writeJSON(w, status, rev)
+}
+
+// handlePatch applies a merge patch or, by Content-Type, a JSON patch to
+// the revision named by If-Match
+func (s *Server) handlePatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	expected, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != starfleet.MergePatchContentType && mediaType != starfleet.JSONPatchContentType && mediaType != "application/json" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, starfleet.APIErrorUnsupportedMedia,
+			fmt.Sprintf("PATCH requires %s or %s", starfleet.MergePatchContentType, starfleet.JSONPatchContentType))
+		return
+	}
+	patch, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	current, err := s.Store.Get(ctx, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if expected == starfleet.AnyRevision {
+		expected = current.Revision
+	} else if expected != current.Revision {
+		writeError(w, s.conflict(ctx, id, expected, current))
+		return
+	}
+	// Patches address the scene as the principal sees it
+	p := starfleet.PrincipalFromContext(ctx)
+	view, ok := current.Scene.ReadableBy(p)
+	if !ok {
+		writeError(w, fmt.Errorf("%w: %s", starfleet.ErrSceneNotFound, id))
+		return
+	}
+	var patched starfleet.SceneFile
+	if mediaType == starfleet.JSONPatchContentType {
+		var ops []starfleet.PatchOp
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("decode body: %v", err))
+			return
+		}
+		patched, err = starfleet.ApplyPatch(&view, ops)
+	} else {
+		patched, err = starfleet.MergePatch(&view, patch)
+	}
+	switch {
+	case errors.Is(err, starfleet.ErrPatchTestFailed):
+		writeAPIError(w, http.StatusConflict, starfleet.APIErrorPatchTestFailed, err.Error())
+		return
+	case errors.Is(err, starfleet.ErrInconsistentScene):
+		writeAPIError(w, http.StatusUnprocessableEntity, starfleet.APIErrorInvalidScene, err.Error())
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, err.Error())
+		return
+	}
+	patched = starfleet.RestoreHidden(&current.Scene, &patched, p)
+	if !validate(w, r, &patched) {
+		return
+	}
+	rev, err := s.Store.Put(ctx, id, patched, expected)
+	if err != nil {
+		writeError(w, s.visibleConflict(ctx, id, err))
+		return
+	}
+	s.published(ctx, rev)
+	w.Header().Set("ETag", starfleet.RevisionTag(rev.Revision))
+	rev, _ = visibleRevision(ctx, rev)
+	writeJSON(w, http.StatusOK, rev)
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	expected, ok := requireIfMatch(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	id := r.PathValue("id")
+	if err := s.Store.Delete(ctx, id, expected); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.invalidate(id)
+	now := time.Now().UTC()
+	s.hub.publish(starfleet.SceneEvent{Type: starfleet.SceneEventDeleted, ID: id, Time: now})
+	if s.Webhooks != nil {
+		s.Webhooks.Notify(starfleet.WebhookPayload{
+			Event:   starfleet.SceneEventDeleted,
+			SceneID: id,
+			Actor:   starfleet.ActorFromContext(ctx),
+			Time:    now,
+		})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTransition moves a scene to another lifecycle state on behalf of
+// the authenticated principal. If-Match is optional; without it the
+// transition applies to the current revision.
+func (s *Server) handleTransition(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.lifecyclePrincipal(w, r)
+	if !ok {
+		return
+	}
+	var req starfleet.TransitionRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	s.updateLifecycle(w, r, func(sf *starfleet.SceneFile) error {
+		return sf.Transition(req.State, p.ID, req.Comment)
+	})
+}
+
+// handleApprove records an approval by the authenticated principal
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.lifecyclePrincipal(w, r)
+	if !ok {
+		return
+	}
+	var req starfleet.ApprovalRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	s.updateLifecycle(w, r, func(sf *starfleet.SceneFile) error {
+		return sf.Approve(p.ID, req.Comment)
+	})
+}
+
+// handleApprovalPolicy replaces the approval policy of a draft. Only the
+// principals listed in PolicyAdmins may.
+func (s *Server) handleApprovalPolicy(w http.ResponseWriter, r *http.Request) {
+	if len(s.PolicyAdmins) == 0 {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no policy admins configured")
+		return
+	}
+	p, ok := s.lifecyclePrincipal(w, r)
+	if !ok {
+		return
+	}
+	if admins := (starfleet.ACL{Write: s.PolicyAdmins}); !admins.CanWrite(p) {
+		writeAPIError(w, http.StatusForbidden, starfleet.APIErrorAccessDenied, fmt.Sprintf("%q may not set approval policies", p.ID))
+		return
+	}
+	var req starfleet.ApprovalPolicyRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	r = r.WithContext(starfleet.WithPolicyUpdate(r.Context()))
+	s.updateLifecycle(w, r, func(sf *starfleet.SceneFile) error {
+		return sf.SetApprovalPolicy(req.RequiredApprovers, req.MinApprovals, req.AllowSelfApproval)
+	})
+}
+
+// lifecyclePrincipal returns the authenticated principal changing a
+// scene's lifecycle. Lifecycle changes are refused unless Authenticate is
+// set, as the X-Starfleet-Actor header is not trusted to name who
+// transitions or approves a scene. It reports false when it has written an
+// error response.
+func (s *Server) lifecyclePrincipal(w http.ResponseWriter, r *http.Request) (starfleet.Principal, bool) {
+	if s.Authenticate == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "lifecycle changes require authentication to be configured")
+		return starfleet.Principal{}, false
+	}
+	p := starfleet.PrincipalFromContext(r.Context())
+	if p.ID == "" {
+		writeAPIError(w, http.StatusUnauthorized, starfleet.APIErrorUnauthenticated, "lifecycle changes require an identified principal")
+		return starfleet.Principal{}, false
+	}
+	return p, true
+}
+
+// updateLifecycle applies a lifecycle change to the current revision and
+// stores the result as a lifecycle update
+func (s *Server) updateLifecycle(w http.ResponseWriter, r *http.Request, update func(*starfleet.SceneFile) error) {
+	ctx := starfleet.WithLifecycleUpdate(r.Context())
+	id := r.PathValue("id")
+	expected := starfleet.AnyRevision
+	if r.Header.Get("If-Match") != "" {
+		var ok bool
+		if expected, ok = requireIfMatch(w, r); !ok {
+			return
+		}
+	}
+	current, err := s.Store.Get(ctx, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if expected != starfleet.AnyRevision && expected != current.Revision {
+		writeError(w, s.conflict(ctx, id, expected, current))
+		return
+	}
+	if err := update(&current.Scene); err != nil {
+		writeError(w, err)
+		return
+	}
+	rev, err := s.Store.Put(ctx, id, current.Scene, current.Revision)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.published(ctx, rev)
+	w.Header().Set("ETag", starfleet.RevisionTag(rev.Revision))
+	rev, _ = visibleRevision(ctx, rev)
+	writeJSON(w, http.StatusOK, rev)
+}
+
+// downgrade converts a scene for an older reader and reports the
+// capabilities that lost data in a response header
+func (s *Server) downgrade(w http.ResponseWriter, sf starfleet.SceneFile, target string) (starfleet.SceneFile, error) {
+	out, report, err := starfleet.Downgrade(sf, target)
+	if err != nil {
+		return sf, err
+	}
+	var lost []string
+	for _, c := range report.Lost() {
+		if !slices.Contains(lost, string(c.Capability)) {
+			lost = append(lost, string(c.Capability))
+		}
+	}
+	if len(lost) > 0 {
+		w.Header().Set(starfleet.DowngradeLostHeader, strings.Join(lost, ","))
+	}
+	return out, nil
+}
+
+// negotiateViewer fits a scene to the viewer asking for it, stripping the
+// optional features it lacks and listing them in a response header. Viewers
+// that cannot render the scene at all are refused with 406 and the scene's
+// fallback. It reports false when it has written an error response.
+func (s *Server) negotiateViewer(w http.ResponseWriter, sf starfleet.SceneFile, viewer starfleet.ViewerInfo) (starfleet.SceneFile, bool) {
+	result, err := starfleet.NegotiateViewer(&sf, viewer)
+	if err != nil {
+		writeError(w, err)
+		return sf, false
+	}
+	switch result.Action {
+	case starfleet.CompatibilityReject:
+		writeJSON(w, http.StatusNotAcceptable, &starfleet.APIError{
+			Code:    starfleet.APIErrorUnsupportedViewer,
+			Message: result.Reason,
+			Viewer:  &result,
+		})
+		return sf, false
+	case starfleet.CompatibilityDowngrade:
+		disabled := make([]string, len(result.Disabled))
+		for i, f := range result.Disabled {
+			disabled[i] = string(f)
+		}
+		w.Header().Set(starfleet.ViewerDisabledHeader, strings.Join(disabled, ","))
+		return starfleet.StripFeatures(sf, result.Disabled), true
+	}
+	return sf, true
+}
+
+func (s *Server) handleCompatibility(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, starfleet.LocalCompatibility())
+}
+
+// handleValidate checks a scene without storing it and always answers 200
+// with the results, as SARIF or JUnit XML when the query has format=sarif
+// or format=junit. Reports locate problems in the request body and name it
+// by the path query parameter.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	data, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	var sf starfleet.SceneFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("decode body: %v", err))
+		return
+	}
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		path = "scene.json"
+	}
+	switch query.Get("format") {
+	case "", "json":
+		result, err := starfleet.CheckSceneContext(r.Context(), &sf)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	case "sarif":
+		w.Header().Set("Content-Type", starfleet.SARIFContentType)
+		w.WriteHeader(http.StatusOK)
+		_ = starfleet.EncodeSARIF(w, []starfleet.FileValidation{starfleet.NewFileValidation(path, data)})
+	case "junit":
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusOK)
+		_ = starfleet.EncodeJUnit(w, []starfleet.FileValidation{starfleet.NewFileValidation(path, data)})
+	default:
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("unknown format %q", query.Get("format")))
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.Metrics == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no metrics source configured")
+		return
+	}
+	var query starfleet.MetricsQuery
+	if !s.decodeBody(w, r, &query) {
+		return
+	}
+	results, err := s.Metrics.Query(r.Context(), query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if results == nil {
+		results = []starfleet.MetricsResult{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// published notifies stream subscribers and webhooks of a stored revision
+func (s *Server) published(ctx context.Context, rev starfleet.SceneRevision) {
+	s.invalidate(rev.ID)
+	event := starfleet.SceneEvent{
+		Type:     starfleet.SceneEventUpdated,
+		ID:       rev.ID,
+		Revision: rev.Revision,
+		Time:     rev.Updated,
+		Scene:    &rev.Scene,
+	}
+	if rev.Revision == 1 {
+		event.Type = starfleet.SceneEventCreated
+	}
+	s.hub.publish(event)
+
+	if s.Webhooks == nil {
+		return
+	}
+	payload := starfleet.WebhookPayload{
+		Event:    event.Type,
+		SceneID:  rev.ID,
+		Revision: rev.Revision,
+		Actor:    starfleet.ActorFromContext(ctx),
+		Time:     rev.Updated,
+	}
+	if rev.Revision > 1 {
+		if prev, err := s.Store.GetRevision(ctx, rev.ID, rev.Revision-1); err == nil {
+			summary := starfleet.Diff(&prev.Scene, &rev.Scene).Summary()
+			payload.Diff = &summary
+		}
+	}
+	s.Webhooks.Notify(payload)
+}
+
+// putPrecondition resolves the revision a PUT is based on. Without If-Match
+// the request may only create a scene.
+func (s *Server) putPrecondition(w http.ResponseWriter, r *http.Request, id string) (int64, bool) {
+	if r.Header.Get("If-Match") != "" {
+		return requireIfMatch(w, r)
+	}
+	if r.Header.Get("If-None-Match") == "*" {
+		return 0, true
+	}
+	_, err := s.Store.Get(r.Context(), id)
+	switch {
+	case errors.Is(err, starfleet.ErrSceneNotFound):
+		return 0, true
+	case err != nil:
+		writeError(w, err)
+		return 0, false
+	}
+	writeAPIError(w, http.StatusPreconditionRequired, starfleet.APIErrorPreconditionRequired,
+		"replacing an existing scene requires If-Match with its revision")
+	return 0, false
+}
+
+// maxAnyRevisionAttempts bounds the retries of an unconditional write that
+// keeps racing other writers
+const maxAnyRevisionAttempts = 3
+
+// putAnyRevision stores an unconditional write after checking the nodes and
+// edges it removes with starfleet.CheckRemovals against base, the revision
+// the writer edited (zero when unknown). The write is then based on the
+// checked revision, so nothing added in between is dropped unchecked.
+func (s *Server) putAnyRevision(ctx context.Context, id string, sf starfleet.SceneFile, base int64) (starfleet.SceneRevision, error) {
+	var err error
+	for attempt := 0; attempt < maxAnyRevisionAttempts; attempt++ {
+		current, getErr := s.Store.Get(ctx, id)
+		if errors.Is(getErr, starfleet.ErrSceneNotFound) {
+			return s.Store.Put(ctx, id, sf, starfleet.AnyRevision)
+		}
+		if getErr != nil {
+			return starfleet.SceneRevision{}, getErr
+		}
+		var edited *starfleet.SceneFile
+		if base == current.Revision {
+			edited = &current.Scene
+		} else if base > 0 {
+			if prev, err := s.Store.GetRevision(ctx, id, base); err == nil {
+				edited = &prev.Scene
+			}
+		}
+		// Elements hidden from the writer are kept, including any added
+		// since the scene was read
+		written := starfleet.RestoreHidden(&current.Scene, &sf, starfleet.PrincipalFromContext(ctx))
+		if err := starfleet.CheckRemovals(id, edited, &current.Scene, &written); err != nil {
+			return starfleet.SceneRevision{}, err
+		}
+		var rev starfleet.SceneRevision
+		rev, err = s.Store.Put(ctx, id, written, current.Revision)
+		if !errors.Is(err, starfleet.ErrRevisionConflict) {
+			return rev, err
+		}
+	}
+	return starfleet.SceneRevision{}, err
+}
+
+// conflict describes a stale write, including the changes made since the
+// expected revision that the acting principal may see, when that revision
+// is still available
+func (s *Server) conflict(ctx context.Context, id string, expected int64, current starfleet.SceneRevision) error {
+	conflict := &starfleet.RevisionConflictError{ID: id, Expected: expected, Current: current.Revision}
+	if base, err := s.Store.GetRevision(ctx, id, expected); err == nil {
+		if diff, err := starfleet.DiffContext(ctx, &base.Scene, &current.Scene); err == nil {
+			diff = diff.VisibleTo(starfleet.PrincipalFromContext(ctx), &base.Scene, &current.Scene)
+			conflict.Diff = &diff
+		}
+	}
+	return conflict
+}
+
+// visibleConflict narrows the diff of a conflict reported by the store to
+// what the acting principal may see
+func (s *Server) visibleConflict(ctx context.Context, id string, err error) error {
+	var conflict *starfleet.RevisionConflictError
+	if !errors.As(err, &conflict) || conflict.Diff == nil {
+		return err
+	}
+	base, baseErr := s.Store.GetRevision(ctx, id, conflict.Expected)
+	current, currentErr := s.Store.GetRevision(ctx, id, conflict.Current)
+	if baseErr != nil || currentErr != nil {
+		// Unchecked changes are left out rather than leaked
+		conflict.Diff = nil
+		return err
+	}
+	diff := conflict.Diff.VisibleTo(starfleet.PrincipalFromContext(ctx), &base.Scene, &current.Scene)
+	conflict.Diff = &diff
+	return err
+}
+
+// requireIfMatch parses the mandatory If-Match header
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		writeAPIError(w, http.StatusPreconditionRequired, starfleet.APIErrorPreconditionRequired,
+			"request requires If-Match with the scene revision")
+		return 0, false
+	}
+	revision, err := starfleet.ParseRevisionTag(header)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, err.Error())
+		return 0, false
+	}
+	return revision, true
+}
+
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	limit := s.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, starfleet.APIErrorBadRequest, err.Error())
+		} else {
+			writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, err.Error())
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	data, ok := s.readBody(w, r)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, starfleet.APIErrorBadRequest, fmt.Sprintf("decode body: %v", err))
+		return false
+	}
+	return true
+}
+
+// validate rejects scenes in formats this SDK cannot read and scenes that
+// fail ValidateScene with 422. Validation stops when the request is
+// canceled.
+func validate(w http.ResponseWriter, r *http.Request, sf *starfleet.SceneFile) bool {
+	compat, err := starfleet.CheckSceneCompatibility(sf, starfleet.LocalCompatibility().Accepts)
+	if err != nil || !compat.Compatible() {
+		apiErr := &starfleet.APIError{Code: starfleet.APIErrorUnsupportedVersion}
+		if err != nil {
+			apiErr.Message = err.Error()
+		} else {
+			apiErr.Message = compat.Reason
+			apiErr.Compatibility = &compat
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, apiErr)
+		return false
+	}
+	result, err := starfleet.ValidateSceneContext(r.Context(), sf)
+	if err != nil {
+		writeError(w, err)
+		return false
+	}
+	if result.Valid {
+		return true
+	}
+	writeJSON(w, http.StatusUnprocessableEntity, &starfleet.APIError{
+		Code:       starfleet.APIErrorInvalidScene,
+		Message:    fmt.Sprintf("scene has %d validation errors", len(result.Errors)),
+		Validation: &result,
+	})
+	return false
+}
+
+// writeError maps store errors onto HTTP responses
+func writeError(w http.ResponseWriter, err error) {
+	var conflict *starfleet.RevisionConflictError
+	var stale *starfleet.StaleElementError
+	switch {
+	case errors.As(err, &conflict):
+		writeJSON(w, http.StatusConflict, &starfleet.APIError{
+			Code:     starfleet.APIErrorRevisionConflict,
+			Message:  conflict.Error(),
+			Conflict: conflict,
+		})
+	case errors.As(err, &stale):
+		status := http.StatusConflict
+		if stale.HasRemovals() {
+			status = http.StatusPreconditionFailed
+		}
+		writeJSON(w, status, &starfleet.APIError{
+			Code:    starfleet.APIErrorStaleElement,
+			Message: stale.Error(),
+			Stale:   stale,
+		})
+	case errors.Is(err, starfleet.ErrSceneNotFound), errors.Is(err, starfleet.ErrRevisionNotFound), errors.Is(err, starfleet.ErrAssetNotFound),
+		errors.Is(err, starfleet.ErrImportJobNotFound), errors.Is(err, starfleet.ErrSessionNotFound),
+		errors.Is(err, starfleet.ErrQueryNotFound), errors.Is(err, starfleet.ErrNodeNotFound), errors.Is(err, starfleet.ErrEdgeNotFound):
+		writeAPIError(w, http.StatusNotFound, starfleet.APIErrorNotFound, err.Error())
+	case errors.Is(err, starfleet.ErrRevisionConflict):
+		writeAPIError(w, http.StatusConflict, starfleet.APIErrorRevisionConflict, err.Error())
+	case errors.Is(err, starfleet.ErrInvalidTransition):
+		writeAPIError(w, http.StatusConflict, starfleet.APIErrorInvalidTransition, err.Error())
+	case errors.Is(err, starfleet.ErrApprovalRequired):
+		writeAPIError(w, http.StatusForbidden, starfleet.APIErrorApprovalRequired, err.Error())
+	case errors.Is(err, starfleet.ErrChangeControlled):
+		writeAPIError(w, http.StatusForbidden, starfleet.APIErrorChangeControlled, err.Error())
+	case errors.Is(err, starfleet.ErrAccessDenied):
+		writeAPIError(w, http.StatusForbidden, starfleet.APIErrorAccessDenied, err.Error())
+	case errors.Is(err, starfleet.ErrUnauthenticated):
+		writeAPIError(w, http.StatusUnauthorized, starfleet.APIErrorUnauthenticated, err.Error())
+	case errors.Is(err, starfleet.ErrImportQueueFull), errors.Is(err, starfleet.ErrImportQueueClosed):
+		writeAPIError(w, http.StatusServiceUnavailable, starfleet.APIErrorUnavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		writeAPIError(w, http.StatusServiceUnavailable, starfleet.APIErrorInternal, err.Error())
+	default:
+		writeAPIError(w, http.StatusInternalServerError, starfleet.APIErrorInternal, err.Error())
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, &starfleet.APIError{Code: code, Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
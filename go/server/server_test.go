@@ -0,0 +1,1192 @@
+package server
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+func newTestScene() starfleet.SceneFile {
+	sf := starfleet.NewSceneFile("Test")
+	sf.AddNode(starfleet.SceneNode{ID: "api", Type: "server", Name: "API", Transform: starfleet.NewTransform()})
+	sf.AddNode(starfleet.SceneNode{ID: "db", Type: "database", Name: "DB", Transform: starfleet.NewTransform()})
+	sf.AddEdge(starfleet.SceneEdge{ID: "api-db", Source: "api", Target: "db"})
+	return sf
+}
+
+func request(t *testing.T, h http.Handler, method, path string, header map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// bearerAuth authenticates the principal named by a request's bearer token;
+// requests without one are anonymous
+func bearerAuth(r *http.Request) (starfleet.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return starfleet.Principal{}, nil
+	}
+	id, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || id == "" {
+		return starfleet.Principal{}, starfleet.ErrUnauthenticated
+	}
+	return starfleet.Principal{ID: id}, nil
+}
+
+func sceneJSON(t *testing.T, sf starfleet.SceneFile) string {
+	t.Helper()
+	data, err := json.Marshal(sf)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	return string(data)
+}
+
+// TestServer_PutAndPatch tests revision tokens across create, replace and patch
+func TestServer_PutAndPatch(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+
+	rec := request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status mismatch: got %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if tag := rec.Header().Get("ETag"); tag != `"1"` {
+		t.Errorf("ETag mismatch: got %s, want \"1\"", tag)
+	}
+
+	rec = request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("unconditional replace status mismatch: got %d, want %d", rec.Code, http.StatusPreconditionRequired)
+	}
+
+	sf.Metadata.Name = "Replaced"
+	rec = request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": `"1"`}, sceneJSON(t, sf))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("replace mismatch: got %d %s: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+
+	patchHeader := map[string]string{"If-Match": `"2"`, "Content-Type": starfleet.MergePatchContentType}
+	rec = request(t, srv, http.MethodPatch, "/scenes/prod", patchHeader, `{"metadata": {"description": "patched"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch status mismatch: got %d: %s", rec.Code, rec.Body)
+	}
+	var rev starfleet.SceneRevision
+	if err := json.Unmarshal(rec.Body.Bytes(), &rev); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if rev.Revision != 3 || rev.Scene.Metadata.Name != "Replaced" || rev.Scene.Metadata.Description != "patched" {
+		t.Errorf("patched revision mismatch: got %d %+v", rev.Revision, rev.Scene.Metadata)
+	}
+
+	rec = request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"If-None-Match": `"3"`}, "")
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET status mismatch: got %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
+
+// TestServer_Conflict tests the 409 body for writes based on a stale revision
+func TestServer_Conflict(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+
+	theirs := newTestScene()
+	theirs.Scene.Nodes[1].Status = starfleet.NodeStatusCritical
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": `"1"`}, sceneJSON(t, theirs))
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		header := map[string]string{"If-Match": `"1"`, "Content-Type": "application/json"}
+		body := sceneJSON(t, sf)
+		if method == http.MethodPatch {
+			body = `{"metadata": {"name": "Mine"}}`
+		}
+		rec := request(t, srv, method, "/scenes/prod", header, body)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("%s status mismatch: got %d, want %d: %s", method, rec.Code, http.StatusConflict, rec.Body)
+		}
+		var apiErr starfleet.APIError
+		if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		if apiErr.Code != starfleet.APIErrorRevisionConflict || apiErr.Conflict == nil || apiErr.Conflict.Current != 2 {
+			t.Fatalf("%s conflict body mismatch: %s", method, rec.Body)
+		}
+		want := starfleet.ElementChange{ID: "db", Kind: starfleet.ChangeModified, Fields: []string{"status"}}
+		if d := apiErr.Conflict.Diff; d == nil || len(d.Nodes) != 1 || d.Nodes[0].ID != want.ID || d.Nodes[0].Fields[0] != "status" {
+			t.Errorf("%s diff mismatch: got %+v, want node change %+v", method, d, want)
+		}
+	}
+}
+
+// TestServer_Errors tests rejection of invalid requests
+func TestServer_Errors(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+
+	invalid := newTestScene()
+	invalid.AddEdge(starfleet.SceneEdge{ID: "dangling", Source: "api", Target: "missing"})
+	future := newTestScene()
+	future.Version = "9.0.0"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+		body   string
+		want   int
+	}{
+		{"missing scene", http.MethodGet, "/scenes/none", nil, "", http.StatusNotFound},
+		{"bad revision query", http.MethodGet, "/scenes/prod?revision=x", nil, "", http.StatusBadRequest},
+		{"bad page limit", http.MethodGet, "/scenes?limit=0", nil, "", http.StatusBadRequest},
+		{"bad cursor", http.MethodGet, "/scenes?cursor=%25", nil, "", http.StatusBadRequest},
+		{"metrics without source", http.MethodPost, "/metrics/query", nil, `{}`, http.StatusNotImplemented},
+		{"stream missing scene", http.MethodGet, "/scenes/none/events", nil, "", http.StatusNotFound},
+		{"unsupported version", http.MethodPut, "/scenes/new", nil, sceneJSON(t, future), http.StatusUnprocessableEntity},
+		{"incompatible reader", http.MethodGet, "/scenes/prod", map[string]string{starfleet.AcceptVersionHeader: "^5.0.0"}, "", http.StatusNotAcceptable},
+		{"bad reader range", http.MethodGet, "/scenes/prod", map[string]string{starfleet.AcceptVersionHeader: ">=banana"}, "", http.StatusBadRequest},
+		{"compatibility", http.MethodGet, "/compatibility", nil, "", http.StatusOK},
+		{"invalid scene", http.MethodPut, "/scenes/new", nil, sceneJSON(t, invalid), http.StatusUnprocessableEntity},
+		{"patch without If-Match", http.MethodPatch, "/scenes/prod", map[string]string{"Content-Type": starfleet.MergePatchContentType}, `{}`, http.StatusPreconditionRequired},
+		{"patch wrong media type", http.MethodPatch, "/scenes/prod", map[string]string{"If-Match": `"1"`, "Content-Type": "text/plain"}, `{}`, http.StatusUnsupportedMediaType},
+		{"malformed If-Match", http.MethodDelete, "/scenes/prod", map[string]string{"If-Match": "1"}, "", http.StatusBadRequest},
+		{"delete", http.MethodDelete, "/scenes/prod", map[string]string{"If-Match": `"1"`}, "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		rec := request(t, srv, tt.method, tt.path, tt.header, tt.body)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status mismatch: got %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+// TestServer_Downgrade tests serving a newer scene to an older reader
+func TestServer_Downgrade(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	sf.Scene.Nodes[0].Label = &starfleet.Label{Text: "API"}
+	sf.Scene.Edges[0].Weight = 2
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{starfleet.AcceptVersionHeader: "^0.1.0"}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := rec.Header().Get(starfleet.DowngradeLostHeader); got != "labels" {
+		t.Errorf("lost header mismatch: got %q, want %q", got, "labels")
+	}
+	var rev starfleet.SceneRevision
+	if err := json.Unmarshal(rec.Body.Bytes(), &rev); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if rev.Scene.Version != "0.1.0" || rev.Scene.Scene.Nodes[0].Label != nil || rev.Scene.Scene.Edges[0].Metadata["weight"] != 2.0 {
+		t.Errorf("downgraded scene mismatch: got %+v", rev.Scene)
+	}
+}
+
+// TestServer_ViewerRequirements tests fitting scenes to the viewer asking
+// for them
+func TestServer_ViewerRequirements(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	sf.Requirements = &starfleet.SceneRequirements{MinViewerVersion: "2.0.0", Fallback: "Update your viewer"}
+	sf.Require(starfleet.FeatureRequirement{Feature: starfleet.ViewerFeatureCustomShaders, Extension: "shaders"})
+	sf.Scene.Nodes[0].Extensions = map[string]interface{}{"shaders": "glow"}
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+
+	viewer := func(version, features string) map[string]string {
+		return map[string]string{starfleet.ViewerVersionHeader: version, starfleet.ViewerFeaturesHeader: features}
+	}
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", viewer("2.1.0", ""), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := rec.Header().Get(starfleet.ViewerDisabledHeader); got != "custom-shaders" {
+		t.Errorf("disabled header mismatch: got %q, want %q", got, "custom-shaders")
+	}
+	var rev starfleet.SceneRevision
+	if err := json.Unmarshal(rec.Body.Bytes(), &rev); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if rev.Scene.Scene.Nodes[0].Extensions != nil || len(rev.Scene.Requirements.Features) != 0 {
+		t.Errorf("stripped scene mismatch: got %+v", rev.Scene)
+	}
+
+	rec = request(t, srv, http.MethodGet, "/scenes/prod", viewer("2.1.0", "custom-shaders"), "")
+	if rec.Code != http.StatusOK || rec.Header().Get(starfleet.ViewerDisabledHeader) != "" {
+		t.Errorf("capable viewer mismatch: got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = request(t, srv, http.MethodGet, "/scenes/prod", viewer("1.9.0", "custom-shaders"), "")
+	var apiErr starfleet.APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusNotAcceptable || apiErr.Code != starfleet.APIErrorUnsupportedViewer || apiErr.Viewer == nil || apiErr.Viewer.Fallback != "Update your viewer" {
+		t.Errorf("old viewer mismatch: got %d %s", rec.Code, rec.Body)
+	}
+	if rec = request(t, srv, http.MethodGet, "/scenes/prod", viewer("latest", ""), ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad viewer version mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// TestServer_FlatScene tests serving scenes as FlatBuffers on request
+func TestServer_FlatScene(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, newTestScene()))
+
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"Accept": starfleet.FlatSceneContentType + ", application/json;q=0.5"}, "")
+	if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != starfleet.FlatSceneContentType {
+		t.Fatalf("response mismatch: got %d %q, want %d %q", rec.Code, got, http.StatusOK, starfleet.FlatSceneContentType)
+	}
+	flat, err := starfleet.OpenFlatScene(rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("OpenFlatScene failed: %v", err)
+	}
+	if node, ok := flat.FindNode("db"); !ok || node.Name() != "DB" {
+		t.Errorf("node mismatch: got %q, %v", node.Name(), ok)
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("missing ETag")
+	}
+
+	rec = request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"Accept": "application/json"}, "")
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("content type mismatch: got %q, want %q", got, "application/json")
+	}
+}
+
+// TestServer_Workbook tests serving scenes as XLSX workbooks on request
+func TestServer_Workbook(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, newTestScene()))
+
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"Accept": starfleet.WorkbookContentType}, "")
+	if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != starfleet.WorkbookContentType {
+		t.Fatalf("response mismatch: got %d %q, want %d %q", rec.Code, got, http.StatusOK, starfleet.WorkbookContentType)
+	}
+	if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename=prod.xlsx`; got != want {
+		t.Errorf("disposition mismatch: got %q, want %q", got, want)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len())); err != nil {
+		t.Errorf("workbook is not a zip: %v", err)
+	}
+}
+
+// TestServer_Drawio tests serving scenes as draw.io diagrams on request
+func TestServer_Drawio(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, newTestScene()))
+
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"Accept": starfleet.DrawioContentType}, "")
+	if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != starfleet.DrawioContentType {
+		t.Fatalf("response mismatch: got %d %q, want %d %q", rec.Code, got, http.StatusOK, starfleet.DrawioContentType)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "<mxfile") || !strings.Contains(body, `source="n:api" target="n:db"`) {
+		t.Errorf("diagram mismatch: got %s", body)
+	}
+}
+
+// TestServer_GLB tests serving scenes as binary glTF on request
+func TestServer_GLB(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, newTestScene()))
+
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"Accept": starfleet.GLTFContentType}, "")
+	if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != starfleet.GLTFContentType {
+		t.Fatalf("response mismatch: got %d %q, want %d %q", rec.Code, got, http.StatusOK, starfleet.GLTFContentType)
+	}
+	if body := rec.Body.String(); !strings.HasPrefix(body, "glTF") || !strings.Contains(body, `"extensionsRequired":["EXT_meshopt_compression"]`) {
+		t.Errorf("model mismatch: got %.200q", body)
+	}
+}
+
+// TestServer_Webhooks tests that writes notify webhooks with the actor and
+// a diff summary
+func TestServer_Webhooks(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []starfleet.WebhookPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p starfleet.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode failed: %v", err)
+		}
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	srv := New(starfleet.NewMemorySceneStore())
+	srv.Webhooks = starfleet.NewWebhookDispatcher(starfleet.WebhookEndpoint{URL: hook.URL, Secret: "s"})
+	actor := map[string]string{starfleet.ActorHeader: "alice", "If-Match": `"1"`}
+
+	sf := newTestScene()
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{starfleet.ActorHeader: "alice"}, sceneJSON(t, sf))
+	sf.AddNode(starfleet.SceneNode{ID: "cache", Type: "cache", Name: "Cache", Transform: starfleet.NewTransform()})
+	request(t, srv, http.MethodPut, "/scenes/prod", actor, sceneJSON(t, sf))
+	request(t, srv, http.MethodDelete, "/scenes/prod", map[string]string{"If-Match": `"2"`}, "")
+	if err := srv.Webhooks.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(payloads) != 3 {
+		t.Fatalf("payload count mismatch: got %d, want 3", len(payloads))
+	}
+	wantEvents := []starfleet.SceneEventType{starfleet.SceneEventCreated, starfleet.SceneEventUpdated, starfleet.SceneEventDeleted}
+	for i, p := range payloads {
+		if p.Event != wantEvents[i] || p.SceneID != "prod" {
+			t.Errorf("payload %d mismatch: got %+v", i, p)
+		}
+	}
+	if payloads[1].Actor != "alice" || payloads[1].Diff == nil || payloads[1].Diff.NodesAdded != 1 {
+		t.Errorf("update payload mismatch: got %+v", payloads[1])
+	}
+}
+
+// TestServer_SignedURLs tests minting signed URLs and serving blobs
+func TestServer_SignedURLs(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	blobs := starfleet.NewMemoryBlobStore()
+	d, _ := blobs.Put(context.Background(), []byte("mesh data"))
+	srv.Blobs = blobs
+	srv.URLSigner = starfleet.NewURLSigner("https://scenes.example.com", "secret")
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, newTestScene()))
+
+	sign := func(body string) starfleet.SignedURL {
+		t.Helper()
+		rec := request(t, srv, http.MethodPost, "/signed-urls", nil, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var signed starfleet.SignedURL
+		json.Unmarshal(rec.Body.Bytes(), &signed)
+		return signed
+	}
+	scene := sign(`{"scene":"prod","revision":1,"ttl":60}`)
+	path := strings.TrimPrefix(scene.URL, "https://scenes.example.com")
+	if rec := request(t, srv, http.MethodGet, path, nil, ""); rec.Code != http.StatusOK {
+		t.Errorf("signed scene status mismatch: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, srv, http.MethodGet, strings.Replace(path, "revision=1", "revision=2", 1), nil, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("tampered url status mismatch: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	asset := sign(`{"asset":"` + string(d) + `"}`)
+	rec := request(t, srv, http.MethodGet, strings.TrimPrefix(asset.URL, "https://scenes.example.com"), nil, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "mesh data" || rec.Header().Get("ETag") != `"`+string(d)+`"` {
+		t.Errorf("blob response mismatch: got %d %q", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"scene":"missing"}`, http.StatusNotFound},
+		{`{"scene":"prod","asset":"` + string(d) + `"}`, http.StatusBadRequest},
+		{`{"asset":"sha256:nope"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := request(t, srv, http.MethodPost, "/signed-urls", nil, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: status mismatch: got %d, want %d", tt.body, rec.Code, tt.want)
+		}
+	}
+	if rec := request(t, srv, http.MethodGet, "/blobs/"+string(starfleet.DigestOf([]byte("x"))), nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing blob status mismatch: got %d", rec.Code)
+	}
+
+	// Signatures stand in for credentials an authenticator would require
+	srv.Authenticate = func(r *http.Request) (starfleet.Principal, error) {
+		if r.Header.Get("Authorization") == "" {
+			return starfleet.Principal{}, starfleet.ErrUnauthenticated
+		}
+		return bearerAuth(r)
+	}
+	if rec := request(t, srv, http.MethodGet, path, nil, ""); rec.Code != http.StatusOK {
+		t.Errorf("signed scene status mismatch with authentication: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, srv, http.MethodGet, strings.TrimPrefix(asset.URL, "https://scenes.example.com"), nil, ""); rec.Code != http.StatusOK {
+		t.Errorf("signed blob status mismatch with authentication: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, srv, http.MethodGet, strings.Replace(path, "revision=1", "revision=2", 1), nil, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("tampered url status mismatch with authentication: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	for _, tt := range []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/scenes/prod"},
+		{http.MethodPut, path},
+	} {
+		if rec := request(t, srv, tt.method, tt.path, nil, sceneJSON(t, newTestScene())); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: status mismatch without credentials: got %d, want %d", tt.method, tt.path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+// TestServer_Imports tests running, polling and streaming import jobs
+func TestServer_Imports(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	if rec := request(t, srv, http.MethodGet, "/imports", nil, ""); rec.Code != http.StatusNotImplemented {
+		t.Errorf("status mismatch without queue: got %d, want %d", rec.Code, http.StatusNotImplemented)
+	}
+	srv.Imports = starfleet.NewImportQueue(1)
+	srv.Importers = map[string]starfleet.Importer{
+		"static": starfleet.ImporterFunc(func(_ context.Context, _ starfleet.ImporterConfig, progress starfleet.ProgressFunc) (starfleet.ImportResult, error) {
+			progress(starfleet.ImportProgress{Stage: "build", Discovered: 2, Built: 2})
+			return starfleet.ImportResult{Scene: newTestScene()}, nil
+		}),
+	}
+
+	rec := request(t, srv, http.MethodPost, "/imports", nil, `{"importer":"static","config":{"region":"eu"}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	var job starfleet.ImportJob
+	json.Unmarshal(rec.Body.Bytes(), &job)
+	if loc := rec.Header().Get("Location"); loc != "/imports/"+job.ID {
+		t.Errorf("location mismatch: got %q", loc)
+	}
+	srv.Imports.Wait(context.Background(), job.ID)
+
+	rec = request(t, srv, http.MethodGet, "/imports/"+job.ID, nil, "")
+	json.Unmarshal(rec.Body.Bytes(), &job)
+	if rec.Code != http.StatusOK || job.State != starfleet.ImportJobSucceeded || job.Result == nil || len(job.Result.Scene.Scene.Nodes) != 2 {
+		t.Errorf("job mismatch: got %d %+v", rec.Code, job)
+	}
+	rec = request(t, srv, http.MethodGet, "/imports/"+job.ID+"/events", nil, "")
+	if !strings.Contains(rec.Body.String(), "event: succeeded\ndata: ") {
+		t.Errorf("event stream mismatch: got %s", rec.Body)
+	}
+	if rec := request(t, srv, http.MethodDelete, "/imports/"+job.ID, nil, ""); rec.Code != http.StatusAccepted {
+		t.Errorf("cancel status mismatch: got %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if rec := request(t, srv, http.MethodGet, "/imports/missing", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := request(t, srv, http.MethodPost, "/imports", nil, `{"importer":"nope"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// TestServer_Validate tests dry-run validation in JSON, SARIF and JUnit
+func TestServer_Validate(t *testing.T) {
+	store := starfleet.NewMemorySceneStore()
+	srv := New(store)
+	bad := newTestScene()
+	bad.AddEdge(starfleet.SceneEdge{ID: "api-cache", Source: "api", Target: "cache"})
+
+	rec := request(t, srv, http.MethodPost, "/validate", nil, sceneJSON(t, bad))
+	var result starfleet.ValidationResult
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if rec.Code != http.StatusOK || result.Valid || len(result.Errors) != 1 {
+		t.Errorf("validation mismatch: got %d %+v", rec.Code, result)
+	}
+	if summaries, _ := store.List(context.Background()); len(summaries) != 0 {
+		t.Errorf("validation must not store scenes: got %v", summaries)
+	}
+
+	rec = request(t, srv, http.MethodPost, "/validate?format=sarif&path=scenes/prod.json", nil, sceneJSON(t, bad))
+	if rec.Header().Get("Content-Type") != starfleet.SARIFContentType || !strings.Contains(rec.Body.String(), `"uri": "scenes/prod.json"`) {
+		t.Errorf("sarif mismatch: got %s", rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"fullyQualifiedName": "$.scene.edges[1]"`) || !strings.Contains(rec.Body.String(), `"startLine"`) {
+		t.Errorf("sarif location mismatch: got %s", rec.Body)
+	}
+	rec = request(t, srv, http.MethodPost, "/validate?format=junit", nil, sceneJSON(t, bad))
+	if rec.Header().Get("Content-Type") != "application/xml" || !strings.Contains(rec.Body.String(), `<testcase name="$.scene.edges[1]" classname="scene.json">`) {
+		t.Errorf("junit mismatch: got %s", rec.Body)
+	}
+	if rec := request(t, srv, http.MethodPost, "/validate?format=xml", nil, sceneJSON(t, bad)); rec.Code != http.StatusBadRequest {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// TestServer_JSONPatch tests PATCH with RFC 6902 JSON patches
+func TestServer_JSONPatch(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-None-Match": "*"}, sceneJSON(t, newTestScene()))
+	header := map[string]string{"If-Match": "*", "Content-Type": starfleet.JSONPatchContentType}
+
+	rec := request(t, srv, http.MethodPatch, "/scenes/prod", header, `[{"op": "replace", "path": "/nodes/db/name", "value": "Primary DB"}]`)
+	var rev starfleet.SceneRevision
+	json.Unmarshal(rec.Body.Bytes(), &rev)
+	if rec.Code != http.StatusOK || rev.Scene.FindNode("db").Name != "Primary DB" {
+		t.Fatalf("patch mismatch: got %d: %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"failed test", `[{"op": "test", "path": "/nodes/db/name", "value": "DB"}]`, http.StatusConflict},
+		{"dangling edge", `[{"op": "remove", "path": "/nodes/db"}]`, http.StatusUnprocessableEntity},
+		{"unknown node", `[{"op": "remove", "path": "/nodes/cache"}]`, http.StatusBadRequest},
+		{"not a list", `{"op": "remove"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := request(t, srv, http.MethodPatch, "/scenes/prod", header, tt.body); rec.Code != tt.want {
+			t.Errorf("%s: status mismatch: got %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
+
+// TestServer_StaleElement tests rejecting writes based on old element revisions
+func TestServer_StaleElement(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-None-Match": "*"}, sceneJSON(t, newTestScene()))
+	var created starfleet.SceneRevision
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Scene.FindNode("api").Revision != 1 {
+		t.Fatalf("element revision mismatch: got %s", rec.Body)
+	}
+
+	edited := created.Scene
+	edited.Scene.Nodes = append([]starfleet.SceneNode(nil), created.Scene.Scene.Nodes...)
+	edited.Scene.Nodes[0].Name = "Gateway"
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": "*"}, sceneJSON(t, edited))
+
+	stale := created.Scene
+	stale.Scene.Nodes = append([]starfleet.SceneNode(nil), created.Scene.Scene.Nodes...)
+	stale.Scene.Nodes[0].Name = "Edge"
+	rec = request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": "*"}, sceneJSON(t, stale))
+	var apiErr starfleet.APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusConflict || apiErr.Code != starfleet.APIErrorStaleElement || apiErr.Stale == nil || apiErr.Stale.Elements[0].Current != 2 {
+		t.Errorf("stale write mismatch: got %d: %s", rec.Code, rec.Body)
+	}
+
+	// A copy read before the cache node was added must not drop it
+	rec = request(t, srv, http.MethodGet, "/scenes/prod", nil, "")
+	var latest starfleet.SceneRevision
+	json.Unmarshal(rec.Body.Bytes(), &latest)
+	baseTag := starfleet.RevisionTag(latest.Revision)
+	latest.Scene.AddNode(starfleet.SceneNode{ID: "cache", Type: "cache", Name: "Cache", Transform: starfleet.NewTransform()})
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": "*"}, sceneJSON(t, latest.Scene))
+
+	old := created.Scene
+	old.Scene.Nodes = append([]starfleet.SceneNode(nil), created.Scene.Scene.Nodes...)
+	old.Scene.Nodes[0].Name = "Gateway"
+	for _, header := range []map[string]string{
+		{"If-Match": "*"},
+		{"If-Match": "*", starfleet.BaseRevisionHeader: baseTag},
+	} {
+		rec = request(t, srv, http.MethodPut, "/scenes/prod", header, sceneJSON(t, old))
+		apiErr = starfleet.APIError{}
+		json.Unmarshal(rec.Body.Bytes(), &apiErr)
+		if rec.Code != http.StatusPreconditionFailed || apiErr.Stale == nil || !apiErr.Stale.Elements[0].Removed || apiErr.Stale.Elements[0].ID != "cache" {
+			t.Errorf("removing a newer node mismatch with %v: got %d: %s", header, rec.Code, rec.Body)
+		}
+	}
+
+	// Removing it from the current revision goes through
+	rec = request(t, srv, http.MethodGet, "/scenes/prod", nil, "")
+	json.Unmarshal(rec.Body.Bytes(), &latest)
+	header := map[string]string{"If-Match": "*", starfleet.BaseRevisionHeader: rec.Header().Get("ETag")}
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", header, sceneJSON(t, old)); rec.Code != http.StatusOK {
+		t.Errorf("removal from the current revision failed: got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestServer_Sessions tests writing, reading and following viewer sessions
+func TestServer_Sessions(t *testing.T) {
+	store := starfleet.NewMemorySceneStore()
+	store.Put(context.Background(), "prod", newTestScene(), 0)
+	srv := New(store)
+	if rec := request(t, srv, http.MethodGet, "/scenes/prod/sessions", nil, ""); rec.Code != http.StatusNotImplemented {
+		t.Errorf("status mismatch without store: got %d, want %d", rec.Code, http.StatusNotImplemented)
+	}
+	srv.Sessions = starfleet.NewSessionStore()
+
+	body := `{"camera":{"position":{"x":0,"y":5,"z":10},"target":{"x":0,"y":0,"z":0}},"selection":["api"]}`
+	rec := request(t, srv, http.MethodPut, "/scenes/prod/sessions/s1", map[string]string{starfleet.ActorHeader: "ada"}, body)
+	var session starfleet.ViewerSession
+	json.Unmarshal(rec.Body.Bytes(), &session)
+	if rec.Code != http.StatusOK || session.ID != "s1" || session.SceneID != "prod" || session.User != "ada" || session.Revision != 1 {
+		t.Fatalf("put mismatch: got %d %+v", rec.Code, session)
+	}
+	if tag := rec.Header().Get("ETag"); tag != starfleet.RevisionTag(1) {
+		t.Errorf("etag mismatch: got %q", tag)
+	}
+	ada := map[string]string{starfleet.ActorHeader: "ada"}
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod/sessions/s1", map[string]string{starfleet.ActorHeader: "ada", "If-Match": starfleet.RevisionTag(5)}, body); rec.Code != http.StatusConflict {
+		t.Errorf("status mismatch for stale write: got %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := request(t, srv, http.MethodPut, "/scenes/missing/sessions/s1", nil, body); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch for unknown scene: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// Sessions belong to whoever created them, whatever the body claims
+	bob := map[string]string{starfleet.ActorHeader: "bob"}
+	for _, tt := range []struct {
+		method string
+		header map[string]string
+		body   string
+	}{
+		{http.MethodPut, bob, body},
+		{http.MethodPut, bob, `{"user":"ada"}`},
+		{http.MethodDelete, bob, ""},
+		{http.MethodDelete, nil, ""},
+	} {
+		if rec := request(t, srv, tt.method, "/scenes/prod/sessions/s1", tt.header, tt.body); rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status mismatch for another user: got %d, want %d", tt.method, tt.body, rec.Code, http.StatusForbidden)
+		}
+	}
+	rec = request(t, srv, http.MethodPut, "/scenes/prod/sessions/s2", bob, `{"user":"ada"}`)
+	json.Unmarshal(rec.Body.Bytes(), &session)
+	if session.User != "bob" {
+		t.Errorf("session user mismatch: got %q, want %q", session.User, "bob")
+	}
+	request(t, srv, http.MethodDelete, "/scenes/prod/sessions/s2", bob, "")
+	rec = request(t, srv, http.MethodGet, "/scenes/prod/sessions", nil, "")
+	var sessions []starfleet.ViewerSession
+	json.Unmarshal(rec.Body.Bytes(), &sessions)
+	if len(sessions) != 1 || sessions[0].Selection[0] != "api" {
+		t.Errorf("list mismatch: got %+v", sessions)
+	}
+
+	// The event stream starts with the current state and ends on delete
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/scenes/prod/sessions/s1/events")
+	if err != nil {
+		t.Fatalf("GET events failed: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != "event: session\n" {
+		t.Errorf("event mismatch: got %q", line)
+	}
+	if line, _ := reader.ReadString('\n'); line != "id: 1\n" {
+		t.Errorf("event id mismatch: got %q", line)
+	}
+	if rec := request(t, srv, http.MethodDelete, "/scenes/prod/sessions/s1", ada, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete status mismatch: got %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("stream did not end cleanly: %v", err)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/prod/sessions/s1", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch after delete: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestServer_Presence tests broadcasting, throttling and privacy of
+// presence
+func TestServer_Presence(t *testing.T) {
+	store := starfleet.NewMemorySceneStore()
+	store.Put(context.Background(), "prod", newTestScene(), 0)
+	srv := New(store)
+	srv.PresenceInterval = time.Hour
+	events := srv.hub.subscribe("prod")
+	defer srv.hub.unsubscribe("prod", events)
+
+	ada := map[string]string{starfleet.ActorHeader: "ada"}
+	bob := map[string]string{starfleet.ActorHeader: "bob"}
+	rec := request(t, srv, http.MethodPost, "/scenes/prod/presence", ada, `{"session":"s1","selection":["api"]}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	event := <-events
+	if event.Type != starfleet.SceneEventPresence || event.Presence.User != "ada" || event.Presence.Selection[0] != "api" {
+		t.Errorf("event mismatch: got %+v", event)
+	}
+	// Within the interval the update is held back but listed
+	request(t, srv, http.MethodPost, "/scenes/prod/presence", ada, `{"session":"s1","selection":["db"]}`)
+	select {
+	case event := <-events:
+		t.Errorf("expected throttled update, got %+v", event)
+	default:
+	}
+	rec = request(t, srv, http.MethodGet, "/scenes/prod/presence", nil, "")
+	var present []starfleet.Presence
+	json.Unmarshal(rec.Body.Bytes(), &present)
+	if len(present) != 1 || present[0].Selection[0] != "db" {
+		t.Errorf("list mismatch: got %+v", present)
+	}
+
+	// Presence belongs to the acting user, whatever the body claims
+	rec = request(t, srv, http.MethodPost, "/scenes/prod/presence", bob, `{"session":"s2","user":"ada","privacy":{"hidden":true}}`)
+	var p starfleet.Presence
+	json.Unmarshal(rec.Body.Bytes(), &p)
+	if p.User != "bob" {
+		t.Errorf("presence user mismatch: got %q, want %q", p.User, "bob")
+	}
+	rec = request(t, srv, http.MethodGet, "/scenes/prod/presence", nil, "")
+	json.Unmarshal(rec.Body.Bytes(), &present)
+	if len(present) != 1 {
+		t.Errorf("hidden viewer listed: got %+v", present)
+	}
+	if rec := request(t, srv, http.MethodPost, "/scenes/prod/presence", bob, `{"session":"s1","user":"ada"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("status mismatch updating another user's presence: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := request(t, srv, http.MethodDelete, "/scenes/prod/presence/s1", bob, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("status mismatch removing another user's presence: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	if rec := request(t, srv, http.MethodDelete, "/scenes/prod/presence/s1", ada, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if event := <-events; !event.Presence.Left || event.Presence.Session != "s1" {
+		t.Errorf("leave event mismatch: got %+v", event.Presence)
+	}
+	if rec := request(t, srv, http.MethodPost, "/scenes/prod/presence", nil, `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status mismatch without session: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := request(t, srv, http.MethodPost, "/scenes/missing/presence", nil, `{"session":"s1"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch for unknown scene: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestServer_PresenceThrottle tests that the latest throttled update is
+// sent once the interval passes
+func TestServer_PresenceThrottle(t *testing.T) {
+	store := starfleet.NewMemorySceneStore()
+	store.Put(context.Background(), "prod", newTestScene(), 0)
+	srv := New(store)
+	srv.PresenceInterval = 100 * time.Millisecond
+	events := srv.hub.subscribe("prod")
+	defer srv.hub.unsubscribe("prod", events)
+
+	for _, node := range []string{"api", "db", "cache"} {
+		request(t, srv, http.MethodPost, "/scenes/prod/presence", nil, `{"session":"s1","selection":["`+node+`"]}`)
+	}
+	var got []string
+	for len(got) < 2 {
+		got = append(got, (<-events).Presence.Selection[0])
+	}
+	if want := []string{"api", "cache"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("broadcast mismatch: got %v, want %v", got, want)
+	}
+}
+
+// TestServer_ACL tests rejecting writes to protected elements and hiding
+// unreadable changes from conflict diffs
+func TestServer_ACL(t *testing.T) {
+	store := starfleet.NewGuardedStore(starfleet.NewMemorySceneStore(), starfleet.ACLGuard)
+	srv := New(store)
+	sf := newTestScene()
+	sf.Scene.Nodes[1].Extensions = map[string]interface{}{starfleet.ACLExtension: starfleet.ACL{Read: []string{"dba"}}}
+
+	// Without authentication the guard refuses writes, whoever the header names
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{starfleet.ActorHeader: "dba"}, sceneJSON(t, sf)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status mismatch without Authenticate: got %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+	}
+	srv.Authenticate = bearerAuth
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status mismatch for an anonymous write: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"Authorization": "Bearer dba"}, sceneJSON(t, sf)); rec.Code != http.StatusCreated {
+		t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+
+	sf.Scene.Nodes[1].Name = "Primary DB"
+	rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1), "Authorization": "Bearer bob"}, sceneJSON(t, sf))
+	var apiErr starfleet.APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusForbidden || apiErr.Code != starfleet.APIErrorAccessDenied {
+		t.Errorf("status mismatch: got %d %+v, want %d", rec.Code, apiErr, http.StatusForbidden)
+	}
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1), "Authorization": "Bearer dba"}, sceneJSON(t, sf)); rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	// Bob's stale write conflicts; the diff omits the node he cannot see
+	sf.Scene.Nodes[0].Name = "Gateway"
+	rec = request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1), "Authorization": "Bearer bob"}, sceneJSON(t, sf))
+	apiErr = starfleet.APIError{}
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusConflict || apiErr.Conflict == nil || apiErr.Conflict.Diff == nil || len(apiErr.Conflict.Diff.Nodes) != 0 {
+		t.Errorf("conflict mismatch: got %d %s", rec.Code, rec.Body)
+	}
+	rec = request(t, srv, http.MethodPatch, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1), "Authorization": "Bearer dba", "Content-Type": starfleet.MergePatchContentType}, `{}`)
+	apiErr = starfleet.APIError{}
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if rec.Code != http.StatusConflict || apiErr.Conflict.Diff == nil || len(apiErr.Conflict.Diff.Nodes) != 1 {
+		t.Errorf("conflict mismatch for reader: got %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestServer_ACLReads tests hiding unreadable elements from whole-scene
+// reads and keeping them through writes of the visible part
+func TestServer_ACLReads(t *testing.T) {
+	store := starfleet.NewGuardedStore(starfleet.NewMemorySceneStore(), starfleet.ACLGuard)
+	srv := New(store)
+	srv.Authenticate = bearerAuth
+	srv.URLSigner = starfleet.NewURLSigner("https://scenes.example.com", "secret")
+	dba := map[string]string{"Authorization": "Bearer dba"}
+	bob := map[string]string{"Authorization": "Bearer bob"}
+	sf := newTestScene()
+	sf.Scene.Nodes[1].Extensions = map[string]interface{}{starfleet.ACLExtension: starfleet.ACL{Read: []string{"dba"}}}
+	request(t, srv, http.MethodPut, "/scenes/prod", dba, sceneJSON(t, sf))
+	vault := newTestScene()
+	vault.Extensions = map[string]interface{}{starfleet.ACLExtension: starfleet.ACL{Read: []string{"dba"}}}
+	request(t, srv, http.MethodPut, "/scenes/vault", dba, sceneJSON(t, vault))
+
+	get := func(header map[string]string) starfleet.SceneFile {
+		t.Helper()
+		rec := request(t, srv, http.MethodGet, "/scenes/prod", header, "")
+		var rev starfleet.SceneRevision
+		json.Unmarshal(rec.Body.Bytes(), &rev)
+		return rev.Scene
+	}
+	if got := get(bob); len(got.Scene.Nodes) != 1 || got.Scene.Nodes[0].ID != "api" || len(got.Scene.Edges) != 0 {
+		t.Errorf("scene mismatch for bob: got %+v", got.Scene)
+	}
+	if got := get(dba); len(got.Scene.Nodes) != 2 || len(got.Scene.Edges) != 1 {
+		t.Errorf("scene mismatch for dba: got %+v", got.Scene)
+	}
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", map[string]string{"Authorization": "Bearer bob", "Accept": starfleet.FlatSceneContentType}, "")
+	if flat, err := starfleet.OpenFlatScene(rec.Body.Bytes()); err != nil || flat.NodeCount() != 1 {
+		t.Errorf("flat scene mismatch for bob: got %v", err)
+	}
+	rec = request(t, srv, http.MethodGet, "/scenes/prod/stats", bob, "")
+	var stats starfleet.SceneStatsResponse
+	json.Unmarshal(rec.Body.Bytes(), &stats)
+	if stats.Stats.NodeCount != 1 || stats.Stats.EdgeCount != 0 {
+		t.Errorf("stats mismatch for bob: got %+v", stats.Stats.SceneStats)
+	}
+	rec = request(t, srv, http.MethodGet, "/scenes/prod/stats", dba, "")
+	json.Unmarshal(rec.Body.Bytes(), &stats)
+	if stats.Stats.NodeCount != 2 {
+		t.Errorf("stats mismatch for dba: got %+v", stats.Stats.SceneStats)
+	}
+
+	for _, tt := range []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/scenes/vault", ""},
+		{http.MethodGet, "/scenes/vault/stats", ""},
+		{http.MethodPost, "/signed-urls", `{"scene":"vault"}`},
+	} {
+		if rec := request(t, srv, tt.method, tt.path, bob, tt.body); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status mismatch for bob: got %d, want %d", tt.method, tt.path, rec.Code, http.StatusNotFound)
+		}
+	}
+	if rec := request(t, srv, http.MethodPost, "/signed-urls", dba, `{"scene":"vault"}`); rec.Code != http.StatusOK {
+		t.Errorf("signed url status mismatch for dba: got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Bob's stream only carries what he may read
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := func(id string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/scenes/"+id+"/events?presence=false", nil)
+		req.Header.Set("Authorization", "Bearer bob")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET events failed: %v", err)
+		}
+		return resp
+	}
+	resp := stream("vault")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("stream status mismatch for bob: got %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	resp = stream("prod")
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	next := func() starfleet.SceneEvent {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var event starfleet.SceneEvent
+				json.Unmarshal([]byte(data), &event)
+				return event
+			}
+		}
+	}
+	if event := next(); event.Scene == nil || len(event.Scene.Scene.Nodes) != 1 {
+		t.Errorf("snapshot mismatch for bob: got %+v", event.Scene)
+	}
+
+	// Bob writes what he sees; the hidden node and edge survive
+	view := get(bob)
+	view.Scene.Nodes[0].Name = "Gateway"
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"Authorization": "Bearer bob", "If-Match": starfleet.RevisionTag(1)}, sceneJSON(t, view)); rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch for bob's write: got %d: %s", rec.Code, rec.Body)
+	}
+	if event := next(); event.Scene == nil || len(event.Scene.Scene.Nodes) != 1 || event.Scene.Scene.Nodes[0].Name != "Gateway" {
+		t.Errorf("update event mismatch for bob: got %+v", event.Scene)
+	}
+	patch := `{"scene": {"nodes": [{"id": "api", "type": "server", "name": "Edge", "transform": {"position": {"x": 0, "y": 0, "z": 0}, "rotation": {"x": 0, "y": 0, "z": 0, "w": 1}, "scale": {"x": 1, "y": 1, "z": 1}}}]}}`
+	rec = request(t, srv, http.MethodPatch, "/scenes/prod", map[string]string{"Authorization": "Bearer bob", "If-Match": starfleet.RevisionTag(2), "Content-Type": starfleet.MergePatchContentType}, patch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch for bob's patch: got %d: %s", rec.Code, rec.Body)
+	}
+	got := get(dba)
+	if len(got.Scene.Nodes) != 2 || len(got.Scene.Edges) != 1 || got.FindNode("api").Name != "Edge" {
+		t.Errorf("scene mismatch after bob's writes: got %+v", got.Scene)
+	}
+}
+
+// TestServer_Search tests searching scene nodes with ACLs applied
+func TestServer_Search(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{"env": "prod"}
+	sf.Scene.Nodes[1].Metadata = map[string]interface{}{"env": "prod"}
+	sf.Scene.Nodes[1].Extensions = map[string]interface{}{starfleet.ACLExtension: starfleet.ACL{Read: []string{"dba"}}}
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+	srv.Authenticate = bearerAuth
+
+	search := func(query string, header map[string]string) starfleet.SearchResponse {
+		t.Helper()
+		rec := request(t, srv, http.MethodGet, "/scenes/prod/search?"+query, header, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var resp starfleet.SearchResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+	if resp := search("q=env:prod", map[string]string{"Authorization": "Bearer dba"}); resp.Revision != 1 || len(resp.Results) != 2 {
+		t.Errorf("search mismatch: got %+v", resp)
+	}
+	if resp := search("q=env:prod", map[string]string{"Authorization": "Bearer bob"}); len(resp.Results) != 1 || resp.Results[0].NodeID != "api" {
+		t.Errorf("search mismatch for bob: got %+v", resp)
+	}
+	if resp := search("q=env:prod&limit=1", map[string]string{"Authorization": "Bearer dba"}); len(resp.Results) != 1 {
+		t.Errorf("limit mismatch: got %+v", resp)
+	}
+	if resp := search("q=env:prod", map[string]string{starfleet.ActorHeader: "dba"}); len(resp.Results) != 1 {
+		t.Errorf("the actor header must not grant access: got %+v", resp)
+	}
+
+	// The index follows new revisions
+	sf.Scene.Nodes[0].Name = "Gateway"
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1)}, sceneJSON(t, sf))
+	if resp := search("q=gatew", nil); resp.Revision != 2 || len(resp.Results) != 1 {
+		t.Errorf("search mismatch after update: got %+v", resp)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/prod/search?limit=x", nil, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/missing/search?q=a", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestServer_Stats tests that scene statistics are computed once per
+// revision
+func TestServer_Stats(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+
+	stats := func() starfleet.SceneStatsResponse {
+		t.Helper()
+		rec := request(t, srv, http.MethodGet, "/scenes/prod/stats", nil, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status mismatch: got %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var resp starfleet.SceneStatsResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+	if resp := stats(); resp.Revision != 1 || resp.Stats.NodeCount != 2 || resp.Stats.Components != 1 {
+		t.Errorf("stats mismatch: got %+v", resp)
+	}
+
+	// Poisoning the cache shows the second request does not recompute
+	srv.stats.stats["prod"] = cachedStats{revision: 1, stats: starfleet.DetailedSceneStats{MaxDegree: 99}}
+	if resp := stats(); resp.Stats.MaxDegree != 99 {
+		t.Errorf("cached stats mismatch: got %+v", resp.Stats)
+	}
+	rec := request(t, srv, http.MethodGet, "/scenes/prod/stats", map[string]string{"If-None-Match": starfleet.RevisionTag(1)}, "")
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	sf.AddNode(starfleet.SceneNode{ID: "cache", Type: "cache", Name: "Cache", Transform: starfleet.NewTransform()})
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1)}, sceneJSON(t, sf))
+	if resp := stats(); resp.Revision != 2 || resp.Stats.NodeCount != 3 || resp.Stats.IsolatedNodes != 1 {
+		t.Errorf("stats mismatch after write: got %+v", resp)
+	}
+
+	if rec := request(t, srv, http.MethodGet, "/scenes/missing/stats", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestServer_Groups tests evaluating saved queries with ACLs applied
+func TestServer_Groups(t *testing.T) {
+	srv := New(starfleet.NewMemorySceneStore())
+	sf := newTestScene()
+	sf.SaveQuery(starfleet.SavedQuery{Name: "nodes", Query: "kind=node"})
+	sf.Scene.Nodes[1].Extensions = map[string]interface{}{starfleet.ACLExtension: starfleet.ACL{Read: []string{"dba"}}}
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+	srv.Authenticate = bearerAuth
+
+	rec := request(t, srv, http.MethodGet, "/scenes/prod/groups", map[string]string{"Authorization": "Bearer dba"}, "")
+	var groups []starfleet.SmartGroup
+	json.Unmarshal(rec.Body.Bytes(), &groups)
+	if rec.Code != http.StatusOK || len(groups) != 1 || !reflect.DeepEqual(groups[0].Nodes, []string{"api", "db"}) {
+		t.Errorf("groups mismatch: got %d %s", rec.Code, rec.Body)
+	}
+	rec = request(t, srv, http.MethodGet, "/scenes/prod/groups/nodes", map[string]string{"Authorization": "Bearer bob"}, "")
+	var group starfleet.SmartGroup
+	json.Unmarshal(rec.Body.Bytes(), &group)
+	if rec.Code != http.StatusOK || !reflect.DeepEqual(group.Nodes, []string{"api"}) {
+		t.Errorf("group mismatch for bob: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/prod/groups/missing", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// Scenes with broken queries are rejected
+	sf.Extensions[starfleet.SavedQueriesExtension] = []starfleet.SavedQuery{{Name: "bad", Query: "bogus"}}
+	if rec := request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1)}, sceneJSON(t, sf)); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+// TestServer_ExpirySweeper tests that sweeps are streamed and sent to
+// webhooks
+func TestServer_ExpirySweeper(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []starfleet.WebhookPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p starfleet.WebhookPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		payloads = append(payloads, p)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store := starfleet.NewMemorySceneStore()
+	sf := newTestScene()
+	sf.Scene.Nodes[1].SetTTL(-time.Second, now)
+	store.Put(ctx, "prod", sf, 0)
+	srv := New(store)
+	srv.Webhooks = starfleet.NewWebhookDispatcher(starfleet.WebhookEndpoint{URL: hook.URL, Secret: "s"})
+	events := srv.hub.subscribe("prod")
+	defer srv.hub.unsubscribe("prod", events)
+
+	if _, err := srv.ExpirySweeper(time.Minute).Sweep(ctx); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	event := <-events
+	if event.Type != starfleet.SceneEventExpired || event.Revision != 2 || event.Expired.Nodes[0] != "db" || len(event.Scene.Scene.Edges) != 0 {
+		t.Errorf("event mismatch: got %+v", event)
+	}
+	if err := srv.Webhooks.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(payloads) != 1 || payloads[0].Event != starfleet.SceneEventExpired || payloads[0].Diff.NodesRemoved != 1 || payloads[0].Diff.EdgesRemoved != 1 {
+		t.Errorf("payloads mismatch: got %+v", payloads)
+	}
+}
+
+// TestServer_Elements tests fetching single elements from stored and
+// archived scenes
+func TestServer_Elements(t *testing.T) {
+	ctx := context.Background()
+	store := starfleet.NewMemorySceneStore()
+	hot := newTestScene()
+	hot.Scene.Edges[0].Extensions = map[string]interface{}{starfleet.ACLExtension: starfleet.ACL{Read: []string{"dba"}}}
+	store.Put(ctx, "hot", hot, 0)
+	srv := New(store)
+	srv.Authenticate = bearerAuth
+
+	rec := request(t, srv, http.MethodGet, "/scenes/hot/nodes/db", nil, "")
+	var node starfleet.SceneNode
+	json.Unmarshal(rec.Body.Bytes(), &node)
+	if rec.Code != http.StatusOK || node.Name != "DB" || rec.Header().Get("ETag") != starfleet.RevisionTag(1) {
+		t.Errorf("node mismatch: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/hot/edges/api-db", map[string]string{"Authorization": "Bearer bob"}, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d for a hidden edge", rec.Code, http.StatusNotFound)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/hot/edges/api-db", map[string]string{"Authorization": "Bearer dba"}, ""); rec.Code != http.StatusOK {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/hot/edges/missing", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/cold/nodes/api", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	dir := t.TempDir()
+	sf := newTestScene()
+	var buf bytes.Buffer
+	if err := starfleet.EncodeIndexedScene(&buf, &sf, starfleet.IndexedSceneOptions{}); err != nil {
+		t.Fatalf("EncodeIndexedScene failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cold.sfi"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv.Archive = starfleet.DirSceneArchive{Root: dir}
+	rec = request(t, srv, http.MethodGet, "/scenes/cold/edges/api-db", nil, "")
+	var edge starfleet.SceneEdge
+	json.Unmarshal(rec.Body.Bytes(), &edge)
+	if rec.Code != http.StatusOK || edge.Source != "api" || edge.Target != "db" {
+		t.Errorf("edge mismatch: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := request(t, srv, http.MethodGet, "/scenes/cold/nodes/missing", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestServer_Cache tests serving reads from a scene cache invalidated by
+// writes
+func TestServer_Cache(t *testing.T) {
+	store := starfleet.NewMemorySceneStore()
+	srv := New(store)
+	srv.Cache = starfleet.NewSceneCache(store, 0)
+	sf := newTestScene()
+	request(t, srv, http.MethodPut, "/scenes/prod", nil, sceneJSON(t, sf))
+	for i := 0; i < 3; i++ {
+		if rec := request(t, srv, http.MethodGet, "/scenes/prod/nodes/api", nil, ""); rec.Code != http.StatusOK {
+			t.Fatalf("status mismatch: got %d, want %d", rec.Code, http.StatusOK)
+		}
+	}
+	if stats := srv.Cache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("stats mismatch: got %+v", stats)
+	}
+
+	sf.Scene.Nodes[0].Name = "Gateway"
+	request(t, srv, http.MethodPut, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(1)}, sceneJSON(t, sf))
+	rec := request(t, srv, http.MethodGet, "/scenes/prod", nil, "")
+	var rev starfleet.SceneRevision
+	json.Unmarshal(rec.Body.Bytes(), &rev)
+	if rev.Revision != 2 || rev.Scene.FindNode("api").Name != "Gateway" {
+		t.Errorf("revision mismatch: got %d", rev.Revision)
+	}
+	request(t, srv, http.MethodDelete, "/scenes/prod", map[string]string{"If-Match": starfleet.RevisionTag(2)}, "")
+	if rec := request(t, srv, http.MethodGet, "/scenes/prod", nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("status mismatch: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// handleListSessions returns the sessions of a scene ordered by ID
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	if s.Sessions == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no session store configured")
+		return
+	}
+	sessions := s.Sessions.List(r.PathValue("id"))
+	if sessions == nil {
+		sessions = []starfleet.ViewerSession{}
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// handleGetSession returns a session with its revision as the ETag
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	if s.Sessions == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no session store configured")
+		return
+	}
+	session, err := s.Sessions.Get(r.PathValue("id"), r.PathValue("session"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", starfleet.RevisionTag(session.Revision))
+	writeJSON(w, http.StatusOK, session)
+}
+
+// handlePutSession writes a session of an existing scene. Without a
+// precondition the last writer wins; If-Match makes the write conditional
+// on the session revision and If-None-Match: * on the session being new.
+// Sessions belong to the acting user, whatever the body names, and only
+// they may write them; without Authenticate that is whoever the
+// X-Starfleet-Actor header names.
+func (s *Server) handlePutSession(w http.ResponseWriter, r *http.Request) {
+	if s.Sessions == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no session store configured")
+		return
+	}
+	ctx := r.Context()
+	expected := starfleet.AnyRevision
+	switch {
+	case r.Header.Get("If-Match") != "":
+		var ok bool
+		if expected, ok = requireIfMatch(w, r); !ok {
+			return
+		}
+	case r.Header.Get("If-None-Match") == "*":
+		expected = 0
+	}
+	var session starfleet.ViewerSession
+	if !s.decodeBody(w, r, &session) {
+		return
+	}
+	if _, err := s.Store.Get(ctx, r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	session.SceneID, session.ID = r.PathValue("id"), r.PathValue("session")
+	session.User = starfleet.ActorFromContext(ctx)
+	session, err := s.Sessions.Put(session, expected)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", starfleet.RevisionTag(session.Revision))
+	writeJSON(w, http.StatusOK, session)
+}
+
+// handleDeleteSession ends a session of the acting user
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if s.Sessions == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no session store configured")
+		return
+	}
+	if err := s.Sessions.Delete(r.PathValue("id"), r.PathValue("session"), starfleet.ActorFromContext(r.Context())); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSessionEvents streams a session as server-sent "session" events,
+// starting with its current state. The stream ends when the session is
+// deleted or expires.
+func (s *Server) handleSessionEvents(w http.ResponseWriter, r *http.Request) {
+	if s.Sessions == nil {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "no session store configured")
+		return
+	}
+	ctx := r.Context()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "streaming is not supported by this connection")
+		return
+	}
+	updates, err := s.Sessions.Watch(ctx, r.PathValue("id"), r.PathValue("session"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case session, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(session)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: session\nid: %s\ndata: %s\n\n", strconv.FormatInt(session.Revision, 10), data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// statsCache keeps the statistics of the latest revision of each scene
+// asked about, so dashboards polling a scene do not rescan its graph
+type statsCache struct {
+	mu    sync.Mutex
+	stats map[string]cachedStats
+}
+
+type cachedStats struct {
+	revision int64
+	stats    starfleet.DetailedSceneStats
+}
+
+// get returns the statistics of a scene revision, computing them when the
+// cached ones are for another revision. Abandoned computations are not
+// cached.
+func (c *statsCache) get(ctx context.Context, rev *starfleet.SceneRevision) (starfleet.DetailedSceneStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.stats[rev.ID]; ok && cached.revision == rev.Revision {
+		return cached.stats, nil
+	}
+	stats, err := starfleet.CalculateDetailedStatsContext(ctx, &rev.Scene)
+	if err != nil {
+		return starfleet.DetailedSceneStats{}, err
+	}
+	if c.stats == nil {
+		c.stats = make(map[string]cachedStats)
+	}
+	c.stats[rev.ID] = cachedStats{rev.Revision, stats}
+	return stats, nil
+}
+
+// drop forgets the statistics of a scene
+func (c *statsCache) drop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.stats, id)
+}
+
+// handleStats returns the statistics of the latest revision of a scene,
+// tagged with the revision so pollers can send If-None-Match. Elements the
+// acting principal may not read are left out, and the statistics of such
+// partial views are not cached.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rev, err := s.revision(r.Context(), id, 0)
+	if err != nil {
+		s.stats.drop(id)
+		writeError(w, err)
+		return
+	}
+	visible, ok := visibleRevision(r.Context(), rev)
+	if !ok {
+		writeError(w, fmt.Errorf("%w: %s", starfleet.ErrSceneNotFound, id))
+		return
+	}
+	tag := starfleet.RevisionTag(rev.Revision)
+	w.Header().Set("ETag", tag)
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	var stats starfleet.DetailedSceneStats
+	if len(visible.Scene.Scene.Nodes) == len(rev.Scene.Scene.Nodes) && len(visible.Scene.Scene.Edges) == len(rev.Scene.Scene.Edges) {
+		stats, err = s.stats.get(r.Context(), &rev)
+	} else {
+		stats, err = starfleet.CalculateDetailedStatsContext(r.Context(), &visible.Scene)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, starfleet.SceneStatsResponse{Revision: rev.Revision, Stats: stats})
+}
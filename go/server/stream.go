@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	starfleet "github.com/hyperdrive-technology/starfleet-sdk-go"
+)
+
+// DefaultKeepAlive is the interval of comment lines sent on idle streams so
+// proxies do not close them
+const DefaultKeepAlive = 15 * time.Second
+
+// subscriberBuffer is the number of events a stream may fall behind before
+// it is dropped; clients reconnect and receive a fresh snapshot
+const subscriberBuffer = 16
+
+// hub fans scene events out to stream subscribers
+type hub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan starfleet.SceneEvent]struct{}
+}
+
+func (h *hub) subscribe(id string) chan starfleet.SceneEvent {
+	ch := make(chan starfleet.SceneEvent, subscriberBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = make(map[string]map[chan starfleet.SceneEvent]struct{})
+	}
+	if h.subs[id] == nil {
+		h.subs[id] = make(map[chan starfleet.SceneEvent]struct{})
+	}
+	h.subs[id][ch] = struct{}{}
+	return ch
+}
+
+func (h *hub) unsubscribe(id string, ch chan starfleet.SceneEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[id][ch]; ok {
+		delete(h.subs[id], ch)
+		close(ch)
+	}
+	if len(h.subs[id]) == 0 {
+		delete(h.subs, id)
+	}
+}
+
+// publish delivers an event without blocking; subscribers that cannot keep
+// up are disconnected
+func (h *hub) publish(event starfleet.SceneEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[event.ID] {
+		select {
+		case ch <- event:
+		default:
+			delete(h.subs[event.ID], ch)
+			close(ch)
+		}
+	}
+}
+
+// handleStream streams changes to a scene as server-sent events. The first
+// event is a snapshot of the current revision; the stream ends after the
+// scene is deleted or the acting principal may no longer read it. Scenes in
+// events only hold what the principal may read.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	presence := r.URL.Query().Get("presence") != "false"
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusNotImplemented, starfleet.APIErrorNotImplemented, "streaming is not supported by this connection")
+		return
+	}
+
+	// Subscribe before reading the snapshot so no write is missed in between
+	ch := s.hub.subscribe(id)
+	defer s.hub.unsubscribe(id, ch)
+	current, err := s.Store.Get(ctx, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	current, ok = visibleRevision(ctx, current)
+	if !ok {
+		writeError(w, fmt.Errorf("%w: %s", starfleet.ErrSceneNotFound, id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	snapshot := starfleet.SceneEvent{
+		Type:     starfleet.SceneEventSnapshot,
+		ID:       id,
+		Revision: current.Revision,
+		Time:     current.Updated,
+		Scene:    &current.Scene,
+	}
+	if writeEvent(w, snapshot) != nil {
+		return
+	}
+	flusher.Flush()
+
+	p := starfleet.PrincipalFromContext(ctx)
+	seen := current.Scene
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if event.Revision != 0 && event.Revision <= snapshot.Revision {
+				continue
+			}
+			if event.Type == starfleet.SceneEventPresence && !presence {
+				continue
+			}
+			if event.Scene != nil {
+				visible, ok := event.Scene.ReadableBy(p)
+				if !ok {
+					return
+				}
+				if event.Expired != nil {
+					expired := visibleExpired(*event.Expired, &seen)
+					event.Expired = &expired
+				}
+				event.Scene, seen = &visible, visible
+			}
+			if writeEvent(w, event) != nil || event.Type == starfleet.SceneEventDeleted {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// visibleExpired keeps the expired elements that were in the last scene a
+// subscriber saw
+func visibleExpired(expired starfleet.ExpiredElements, seen *starfleet.SceneFile) starfleet.ExpiredElements {
+	var out starfleet.ExpiredElements
+	for _, id := range expired.Nodes {
+		if seen.FindNode(id) != nil {
+			out.Nodes = append(out.Nodes, id)
+		}
+	}
+	for _, id := range expired.Edges {
+		if seen.FindEdge(id) != nil {
+			out.Edges = append(out.Edges, id)
+		}
+	}
+	return out
+}
+
+// writeEvent writes one server-sent event. The event ID is the revision so
+// clients can tell where they left off; events without a revision, such
+// as deletions and presence, have none.
+func writeEvent(w http.ResponseWriter, event starfleet.SceneEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.Revision == 0 {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event.Type, strconv.FormatInt(event.Revision, 10), data)
+	return err
+}
@@ -0,0 +1,242 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// VIEWER SESSIONS
+// =============================================================================
+
+// ErrSessionNotFound is returned for unknown or expired viewer sessions
+var ErrSessionNotFound = errors.New("session not found")
+
+// ViewerSession is the view state of one user looking at a scene: where the
+// camera is, what is selected, which filters are active and which panels
+// are pinned. Devices sharing a session ID see the same view, so a user can
+// move between screens or hand a view to a colleague. Only the User who
+// created a session may write or delete it.
+//
+// Revision and Updated are maintained by the SessionStore.
+type ViewerSession struct {
+	ID      string  `json:"id" validate:"required"`
+	SceneID string  `json:"sceneId" validate:"required"`
+	User    string  `json:"user,omitempty"`
+	Camera  *Camera `json:"camera,omitempty"`
+	// Selection lists the selected node and edge IDs, primary first
+	Selection []string `json:"selection,omitempty"`
+	// Filters maps filter names, such as "status" or "tag", to values as in
+	// deep links
+	Filters      map[string]string `json:"filters,omitempty"`
+	PinnedPanels []PinnedPanel     `json:"pinnedPanels,omitempty"`
+	// Time is the moment shown by time-travel capable viewers; nil is live
+	Time     *time.Time `json:"time,omitempty"`
+	Revision int64      `json:"revision"`
+	Updated  time.Time  `json:"updated"`
+}
+
+// PinnedPanel names a panel of a node kept open in a session
+type PinnedPanel struct {
+	Node  string `json:"node" validate:"required"`
+	Panel string `json:"panel" validate:"required"`
+}
+
+// DeepLink returns a link reproducing the session's view, focused on the
+// primary selection
+func (s *ViewerSession) DeepLink() DeepLink {
+	link := DeepLink{SceneID: s.SceneID, Camera: s.Camera, Time: s.Time}
+	if len(s.Selection) > 0 {
+		link.Node = s.Selection[0]
+	}
+	link.Filters = maps.Clone(s.Filters)
+	return link
+}
+
+// SessionStore keeps viewer sessions in memory, with optimistic concurrency
+// like SceneStore so devices writing the same session do not silently
+// overwrite each other. Sessions are ephemeral: with Expiry set, sessions
+// not written for that long are forgotten. Set it before the store is used.
+type SessionStore struct {
+	Expiry time.Duration
+
+	mu       sync.Mutex
+	sessions map[sessionKey]*storedSession
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// sessionKey identifies a session; session IDs are scoped to their scene
+type sessionKey struct {
+	scene, id string
+}
+
+type storedSession struct {
+	session  ViewerSession
+	watchers map[chan ViewerSession]struct{}
+}
+
+// NewSessionStore creates an empty session store
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[sessionKey]*storedSession), now: time.Now}
+}
+
+// Get returns a session of a scene
+func (s *SessionStore) Get(sceneID, id string) (ViewerSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	stored, ok := s.sessions[sessionKey{sceneID, id}]
+	if !ok {
+		return ViewerSession{}, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return cloneSession(stored.session), nil
+}
+
+// List returns the sessions of a scene ordered by ID
+func (s *SessionStore) List(sceneID string) []ViewerSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	var sessions []ViewerSession
+	for key, stored := range s.sessions {
+		if key.scene == sceneID {
+			sessions = append(sessions, cloneSession(stored.session))
+		}
+	}
+	sort.Slice(sessions, func(a, b int) bool { return sessions[a].ID < sessions[b].ID })
+	return sessions
+}
+
+// Put stores a session and returns it with its new revision. expected is
+// the revision the write is based on, zero to create the session, or
+// AnyRevision to skip the check; a mismatch fails with ErrRevisionConflict.
+// Writes by anyone but the session's user fail with ErrAccessDenied.
+func (s *SessionStore) Put(session ViewerSession, expected int64) (ViewerSession, error) {
+	if session.ID == "" || session.SceneID == "" {
+		return ViewerSession{}, errors.New("put session: id and scene id are required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	key := sessionKey{session.SceneID, session.ID}
+	stored, ok := s.sessions[key]
+	var current int64
+	if ok {
+		current = stored.session.Revision
+	}
+	if ok && stored.session.User != session.User {
+		return ViewerSession{}, fmt.Errorf("%w: %q may not write session %s", ErrAccessDenied, session.User, session.ID)
+	}
+	if expected != AnyRevision && expected != current {
+		return ViewerSession{}, fmt.Errorf("%w: session %s is at revision %d, expected %d", ErrRevisionConflict, session.ID, current, expected)
+	}
+	if !ok {
+		stored = &storedSession{watchers: make(map[chan ViewerSession]struct{})}
+		s.sessions[key] = stored
+	}
+	session = cloneSession(session)
+	session.Revision = current + 1
+	session.Updated = s.now().UTC()
+	stored.session = session
+	for ch := range stored.watchers {
+		// Watchers only need the latest state
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- cloneSession(session)
+	}
+	return cloneSession(session), nil
+}
+
+// Delete removes a session on behalf of user, closing its watchers.
+// Deleting another user's session fails with ErrAccessDenied.
+func (s *SessionStore) Delete(sceneID, id, user string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	key := sessionKey{sceneID, id}
+	stored, ok := s.sessions[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	if stored.session.User != user {
+		return fmt.Errorf("%w: %q may not delete session %s", ErrAccessDenied, user, id)
+	}
+	s.remove(key)
+	return nil
+}
+
+// Watch streams the state of a session, starting with the current one, so
+// devices sharing it stay in sync. Slow readers skip intermediate states;
+// the channel closes when the session is deleted or expires, or when ctx
+// is done.
+func (s *SessionStore) Watch(ctx context.Context, sceneID, id string) (<-chan ViewerSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	stored, ok := s.sessions[sessionKey{sceneID, id}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	ch := make(chan ViewerSession, 1)
+	ch <- cloneSession(stored.session)
+	stored.watchers[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := stored.watchers[ch]; ok {
+			delete(stored.watchers, ch)
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+// expire forgets sessions not written within Expiry. Callers hold s.mu.
+func (s *SessionStore) expire() {
+	if s.Expiry <= 0 {
+		return
+	}
+	cutoff := s.now().Add(-s.Expiry)
+	for key, stored := range s.sessions {
+		if stored.session.Updated.Before(cutoff) {
+			s.remove(key)
+		}
+	}
+}
+
+// remove deletes a session and closes its watchers. Callers hold s.mu.
+func (s *SessionStore) remove(key sessionKey) {
+	watchers := s.sessions[key].watchers
+	for ch := range watchers {
+		delete(watchers, ch)
+		close(ch)
+	}
+	delete(s.sessions, key)
+}
+
+// cloneSession copies a session so stored state is never shared with
+// callers
+func cloneSession(session ViewerSession) ViewerSession {
+	if session.Camera != nil {
+		camera := *session.Camera
+		session.Camera = &camera
+	}
+	if session.Time != nil {
+		t := *session.Time
+		session.Time = &t
+	}
+	session.Selection = slices.Clone(session.Selection)
+	session.PinnedPanels = slices.Clone(session.PinnedPanels)
+	session.Filters = maps.Clone(session.Filters)
+	return session
+}
@@ -0,0 +1,86 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSessionStore tests session revisions, isolation and expiry
+func TestSessionStore(t *testing.T) {
+	store := NewSessionStore()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return at }
+
+	session := ViewerSession{ID: "s1", SceneID: "prod", Selection: []string{"api"}}
+	created, err := store.Put(session, 0)
+	if err != nil || created.Revision != 1 || !created.Updated.Equal(at) {
+		t.Fatalf("Put mismatch: got %+v, %v", created, err)
+	}
+	if _, err := store.Put(session, 0); !errors.Is(err, ErrRevisionConflict) {
+		t.Errorf("expected ErrRevisionConflict, got %v", err)
+	}
+	if updated, err := store.Put(session, AnyRevision); err != nil || updated.Revision != 2 {
+		t.Errorf("Put mismatch: got %+v, %v", updated, err)
+	}
+	if _, err := store.Put(ViewerSession{ID: "s1", SceneID: "prod", User: "mallory"}, AnyRevision); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied writing another user's session, got %v", err)
+	}
+	store.Put(ViewerSession{ID: "s1", SceneID: "staging"}, 0)
+
+	got, _ := store.Get("prod", "s1")
+	got.Selection[0] = "db"
+	if again, _ := store.Get("prod", "s1"); again.Selection[0] != "api" {
+		t.Error("stored session modified through returned copy")
+	}
+	if sessions := store.List("prod"); len(sessions) != 1 || sessions[0].SceneID != "prod" {
+		t.Errorf("List mismatch: got %+v", sessions)
+	}
+	if _, err := store.Put(ViewerSession{ID: "s2"}, 0); err == nil {
+		t.Error("expected error for session without scene")
+	}
+
+	store.Expiry = time.Minute
+	at = at.Add(2 * time.Minute)
+	if _, err := store.Get("prod", "s1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound after expiry, got %v", err)
+	}
+}
+
+// TestSessionStore_Watch tests following a session until it is deleted
+func TestSessionStore_Watch(t *testing.T) {
+	store := NewSessionStore()
+	store.Put(ViewerSession{ID: "s1", SceneID: "prod"}, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := store.Watch(ctx, "prod", "s1")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	store.Put(ViewerSession{ID: "s1", SceneID: "prod", Selection: []string{"a"}}, 1)
+	store.Put(ViewerSession{ID: "s1", SceneID: "prod", Selection: []string{"b"}}, 2)
+	// Only the latest state is kept for slow readers
+	if got := <-ch; got.Revision != 3 || got.Selection[0] != "b" {
+		t.Errorf("watch mismatch: got %+v", got)
+	}
+	if err := store.Delete("prod", "s1", "mallory"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied deleting another user's session, got %v", err)
+	}
+	store.Delete("prod", "s1", "")
+	if _, ok := <-ch; ok {
+		t.Error("expected channel closed after delete")
+	}
+	if _, err := store.Watch(ctx, "prod", "s1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+// TestViewerSession_DeepLink tests turning a session into a deep link
+func TestViewerSession_DeepLink(t *testing.T) {
+	session := ViewerSession{SceneID: "prod", Selection: []string{"api", "db"}, Filters: map[string]string{"status": "critical"}}
+	link := session.DeepLink()
+	if link.SceneID != "prod" || link.Node != "api" || link.Filters["status"] != "critical" {
+		t.Errorf("deep link mismatch: got %+v", link)
+	}
+}
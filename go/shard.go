@@ -0,0 +1,226 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// =============================================================================
+// SHARDING
+// =============================================================================
+
+// ShardStrategy selects how ShardScene assigns nodes to shards
+type ShardStrategy string
+
+const (
+	// ShardBySubtree gives every root node and its descendants their own shard
+	ShardBySubtree ShardStrategy = "subtree"
+	// ShardByRegion groups nodes by a metadata value, "region" by default
+	ShardByRegion ShardStrategy = "region"
+	// ShardBySpatialCell groups nodes by a grid cell on the ground (XZ) plane
+	ShardBySpatialCell ShardStrategy = "cell"
+)
+
+// DefaultShard is the shard of nodes without a region
+const DefaultShard = "default"
+
+// ErrShardNotFound is returned when a shard is missing from an index
+var ErrShardNotFound = errors.New("shard not found")
+
+// ShardOptions controls how ShardScene partitions a scene
+type ShardOptions struct {
+	Strategy ShardStrategy `json:"strategy" validate:"required,oneof=subtree region cell"`
+	// RegionKey is the metadata key read by ShardByRegion; empty means "region"
+	RegionKey string `json:"regionKey,omitempty"`
+	// CellSize is the edge length of ShardBySpatialCell cells, in world units
+	CellSize float64 `json:"cellSize,omitempty" validate:"omitempty,gt=0"`
+	// URI maps a shard ID to the URI its scene is served at. Cross-shard
+	// edges point at these URIs. When nil, the shard ID is used.
+	URI func(shard string) string `json:"-"`
+}
+
+// ShardInfo describes one shard of a logical scene
+type ShardInfo struct {
+	ID    string `json:"id"`
+	URI   string `json:"uri"`
+	Nodes int    `json:"nodes"`
+	Edges int    `json:"edges"`
+}
+
+// ShardIndex records which shard owns each node of a logical scene
+type ShardIndex struct {
+	Scene    string            `json:"scene"`
+	Strategy ShardStrategy     `json:"strategy"`
+	Shards   []ShardInfo       `json:"shards"`
+	Owners   map[string]string `json:"owners"`
+}
+
+// Shard returns the shard with the given ID
+func (ix *ShardIndex) Shard(id string) (ShardInfo, error) {
+	for _, s := range ix.Shards {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return ShardInfo{}, fmt.Errorf("shard: %w: %s", ErrShardNotFound, id)
+}
+
+// Route returns the shard owning a node
+func (ix *ShardIndex) Route(nodeID string) (ShardInfo, error) {
+	owner, ok := ix.Owners[nodeID]
+	if !ok {
+		return ShardInfo{}, fmt.Errorf("route: %w: %s", ErrNodeNotFound, nodeID)
+	}
+	return ix.Shard(owner)
+}
+
+// ShardScene partitions a scene into shards. Hierarchies are never split:
+// descendants follow their root node, whose region or position decides the
+// shard. An edge is kept by the shard owning its source; when its target
+// lives in another shard it becomes a cross-scene edge to that shard's URI.
+// Shards keep the source scene's metadata, materials, geometries and assets,
+// and are returned in shard ID order.
+func ShardScene(sf *SceneFile, opts ShardOptions) ([]SceneFile, ShardIndex, error) {
+	key, err := shardKey(sf, opts)
+	if err != nil {
+		return nil, ShardIndex{}, err
+	}
+	uri := opts.URI
+	if uri == nil {
+		uri = func(shard string) string { return shard }
+	}
+
+	nodes := make(map[string]*SceneNode, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodes[sf.Scene.Nodes[i].ID] = &sf.Scene.Nodes[i]
+	}
+	index := ShardIndex{Scene: sf.Metadata.Name, Strategy: opts.Strategy, Owners: make(map[string]string, len(nodes))}
+	shards := make(map[string]*SceneFile)
+	shard := func(id string) *SceneFile {
+		if s, ok := shards[id]; ok {
+			return s
+		}
+		s := *sf
+		s.Metadata.Name = fmt.Sprintf("%s [%s]", sf.Metadata.Name, id)
+		s.Scene.Nodes, s.Scene.Edges = []SceneNode{}, []SceneEdge{}
+		shards[id] = &s
+		return &s
+	}
+
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		id := key(shardRoot(nodes, n))
+		index.Owners[n.ID] = id
+		shard(id).AddNode(*n)
+	}
+	for _, e := range sf.Scene.Edges {
+		owner, ok := index.Owners[e.Source]
+		if !ok {
+			continue
+		}
+		if target, ok := index.Owners[e.Target]; ok && !e.IsExternal() && target != owner {
+			e.TargetScene = uri(target)
+		}
+		shard(owner).AddEdge(e)
+	}
+
+	ids := make([]string, 0, len(shards))
+	for id := range shards {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	out := make([]SceneFile, 0, len(ids))
+	for _, id := range ids {
+		s := shards[id]
+		if len(sf.Capabilities) > 0 {
+			s.UpdateCapabilities()
+		}
+		out = append(out, *s)
+		index.Shards = append(index.Shards, ShardInfo{ID: id, URI: uri(id), Nodes: len(s.Scene.Nodes), Edges: len(s.Scene.Edges)})
+	}
+	return out, index, nil
+}
+
+// shardKey returns the function assigning root nodes to shards
+func shardKey(sf *SceneFile, opts ShardOptions) (func(*SceneNode) string, error) {
+	switch opts.Strategy {
+	case ShardBySubtree:
+		return func(n *SceneNode) string { return n.ID }, nil
+	case ShardByRegion:
+		regionKey := opts.RegionKey
+		if regionKey == "" {
+			regionKey = "region"
+		}
+		return func(n *SceneNode) string {
+			if region, ok := n.Metadata[regionKey].(string); ok && region != "" {
+				return region
+			}
+			return DefaultShard
+		}, nil
+	case ShardBySpatialCell:
+		if opts.CellSize <= 0 {
+			return nil, fmt.Errorf("shard scene %s: cell size must be positive", sf.Metadata.Name)
+		}
+		return func(n *SceneNode) string {
+			p := n.Transform.Position
+			return fmt.Sprintf("cell_%d_%d", int(math.Floor(p.X/opts.CellSize)), int(math.Floor(p.Z/opts.CellSize)))
+		}, nil
+	}
+	return nil, fmt.Errorf("shard scene %s: unknown strategy %q", sf.Metadata.Name, opts.Strategy)
+}
+
+// shardRoot returns the top of a node's hierarchy, stopping at missing
+// parents and cycles
+func shardRoot(nodes map[string]*SceneNode, n *SceneNode) *SceneNode {
+	seen := map[string]bool{n.ID: true}
+	for n.Parent != "" {
+		p := nodes[n.Parent]
+		if p == nil || seen[p.ID] {
+			break
+		}
+		seen[p.ID] = true
+		n = p
+	}
+	return n
+}
+
+// ShardRouter serves lookups against a sharded scene by loading the shard
+// that owns each node. Loaded shards are not cached; wrap Loader to cache.
+type ShardRouter struct {
+	Index  ShardIndex
+	Loader SceneLoader
+}
+
+// NewShardRouter creates a router over an index and a shard loader
+func NewShardRouter(index ShardIndex, loader SceneLoader) *ShardRouter {
+	return &ShardRouter{Index: index, Loader: loader}
+}
+
+// Load returns the scene of the shard owning a node
+func (r *ShardRouter) Load(ctx context.Context, nodeID string) (*SceneFile, ShardInfo, error) {
+	info, err := r.Index.Route(nodeID)
+	if err != nil {
+		return nil, ShardInfo{}, err
+	}
+	sf, err := r.Loader.LoadScene(ctx, info.URI)
+	if err != nil {
+		return nil, info, fmt.Errorf("load shard %s: %w", info.ID, err)
+	}
+	return sf, info, nil
+}
+
+// FindNode loads a node from the shard that owns it
+func (r *ShardRouter) FindNode(ctx context.Context, nodeID string) (*SceneNode, error) {
+	sf, info, err := r.Load(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	n := sf.FindNode(nodeID)
+	if n == nil {
+		return nil, fmt.Errorf("find node in shard %s: %w: %s", info.ID, ErrNodeNotFound, nodeID)
+	}
+	return n, nil
+}
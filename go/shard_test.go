@@ -0,0 +1,90 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// newShardScene returns two regions, one with a rack and its server
+func newShardScene() SceneFile {
+	sf := NewSceneFile("Global")
+	sf.AddNode(SceneNode{ID: "rack", Type: "rack", Name: "Rack", Transform: NewTransformWithPosition(5, 0, 5), Metadata: map[string]interface{}{"region": "eu"}})
+	sf.AddNode(SceneNode{ID: "srv", Type: "server", Name: "Server", Parent: "rack", Transform: NewTransformWithPosition(15, 0, 5)})
+	sf.AddNode(SceneNode{ID: "db", Type: "database", Name: "DB", Transform: NewTransformWithPosition(-5, 0, 25), Metadata: map[string]interface{}{"region": "us"}})
+	sf.AddNode(SceneNode{ID: "cdn", Type: "cdn", Name: "CDN", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "srv-db", Source: "srv", Target: "db"})
+	sf.AddEdge(SceneEdge{ID: "rack-srv", Source: "rack", Target: "srv"})
+	return sf
+}
+
+// TestShardScene tests partitioning strategies and cross-shard edges
+func TestShardScene(t *testing.T) {
+	sf := newShardScene()
+	shards, index, err := ShardScene(&sf, ShardOptions{Strategy: ShardByRegion, URI: func(id string) string { return "scenes/" + id + ".json" }})
+	if err != nil {
+		t.Fatalf("ShardScene failed: %v", err)
+	}
+	want := map[string]string{"rack": "eu", "srv": "eu", "db": "us", "cdn": DefaultShard}
+	if !reflect.DeepEqual(index.Owners, want) {
+		t.Errorf("owners mismatch: got %v, want %v", index.Owners, want)
+	}
+	if len(shards) != 3 || shards[1].Metadata.Name != "Global [eu]" || len(shards[1].Scene.Nodes) != 2 {
+		t.Fatalf("shards mismatch: got %+v", index.Shards)
+	}
+	cross := shards[1].FindEdge("srv-db")
+	if cross == nil || cross.TargetScene != "scenes/us.json" || shards[1].FindEdge("rack-srv").IsExternal() {
+		t.Errorf("cross-shard edge mismatch: got %+v", cross)
+	}
+	for _, s := range shards {
+		if result := ValidateScene(&s); !result.Valid {
+			t.Errorf("shard %s invalid: %+v", s.Metadata.Name, result.Errors)
+		}
+	}
+
+	_, index, err = ShardScene(&sf, ShardOptions{Strategy: ShardBySpatialCell, CellSize: 10})
+	if err != nil {
+		t.Fatalf("ShardScene by cell failed: %v", err)
+	}
+	if index.Owners["srv"] != "cell_0_0" || index.Owners["db"] != "cell_-1_2" {
+		t.Errorf("cell owners mismatch: got %v", index.Owners)
+	}
+	if _, index, _ = ShardScene(&sf, ShardOptions{Strategy: ShardBySubtree}); len(index.Shards) != 3 || index.Owners["srv"] != "rack" {
+		t.Errorf("subtree shards mismatch: got %+v", index)
+	}
+	if _, _, err := ShardScene(&sf, ShardOptions{Strategy: ShardBySpatialCell}); err == nil {
+		t.Error("expected error for zero cell size")
+	}
+}
+
+// TestShardRouter tests loading nodes from their owning shard
+func TestShardRouter(t *testing.T) {
+	sf := newShardScene()
+	shards, index, err := ShardScene(&sf, ShardOptions{Strategy: ShardByRegion})
+	if err != nil {
+		t.Fatalf("ShardScene failed: %v", err)
+	}
+	loads := 0
+	router := NewShardRouter(index, SceneLoaderFunc(func(ctx context.Context, uri string) (*SceneFile, error) {
+		loads++
+		for i := range shards {
+			if shards[i].Metadata.Name == "Global ["+uri+"]" {
+				return &shards[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no shard %s", uri)
+	}))
+
+	n, err := router.FindNode(context.Background(), "srv")
+	if err != nil || n.Name != "Server" || loads != 1 {
+		t.Errorf("FindNode mismatch: got %+v, %v", n, err)
+	}
+	if _, err := router.FindNode(context.Background(), "ghost"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+	if _, err := index.Shard("mars"); !errors.Is(err, ErrShardNotFound) {
+		t.Errorf("expected ErrShardNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package starfleet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// SIGNED URLS
+// =============================================================================
+
+// Query parameters of URLs signed by URLSigner
+const (
+	URLExpiresParam   = "expires"
+	URLSignatureParam = "signature"
+)
+
+// MaxPresignTTL is the longest lifetime S3 and GCS accept for presigned URLs
+const MaxPresignTTL = 7 * 24 * time.Hour
+
+// Sentinel errors returned when checking signed URLs
+var (
+	ErrInvalidURLSignature = errors.New("invalid url signature")
+	ErrURLExpired          = errors.New("signed url expired")
+)
+
+// SignedURL is a URL granting temporary access without other credentials
+type SignedURL struct {
+	URL     string    `json:"url" validate:"required"`
+	Expires time.Time `json:"expires"`
+}
+
+// URLSigner mints and verifies expiring HMAC-signed URLs for the built-in
+// server. The signature covers the path, the query and the expiry, so none
+// can be changed without invalidating the URL.
+type URLSigner struct {
+	// BaseURL is prepended to signed paths, e.g. "https://scenes.example.com"
+	BaseURL string
+	Secret  string
+
+	now func() time.Time
+}
+
+// NewURLSigner creates a signer for URLs below baseURL
+func NewURLSigner(baseURL, secret string) *URLSigner {
+	return &URLSigner{BaseURL: strings.TrimSuffix(baseURL, "/"), Secret: secret, now: time.Now}
+}
+
+// currentTime returns the signer's clock, defaulting to time.Now
+func (s *URLSigner) currentTime() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// Sign returns a URL for path, which may carry a query, valid for ttl
+func (s *URLSigner) Sign(path string, ttl time.Duration) (SignedURL, error) {
+	if ttl <= 0 {
+		return SignedURL{}, fmt.Errorf("sign url: ttl must be positive")
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return SignedURL{}, fmt.Errorf("sign url: %w", err)
+	}
+	expires := s.currentTime().Add(ttl).Truncate(time.Second)
+	query := u.Query()
+	query.Del(URLSignatureParam)
+	query.Set(URLExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	query.Set(URLSignatureParam, s.mac(u.EscapedPath(), query))
+	u.RawQuery = query.Encode()
+	return SignedURL{URL: s.BaseURL + u.RequestURI(), Expires: expires.UTC()}, nil
+}
+
+// Verify checks the signature and expiry of a signed URL. Only the path
+// and query are checked, so URLs survive being served under another host.
+func (s *URLSigner) Verify(u *url.URL) error {
+	query := u.Query()
+	signature := query.Get(URLSignatureParam)
+	unix, err := strconv.ParseInt(query.Get(URLExpiresParam), 10, 64)
+	if signature == "" || err != nil {
+		return fmt.Errorf("%w: missing signature or expiry", ErrInvalidURLSignature)
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.mac(u.EscapedPath(), query))) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidURLSignature)
+	}
+	if !s.currentTime().Before(time.Unix(unix, 0)) {
+		return fmt.Errorf("%w: at %s", ErrURLExpired, time.Unix(unix, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// mac signs a path and its query without the signature parameter
+func (s *URLSigner) mac(path string, query url.Values) string {
+	signed := url.Values{}
+	for k, v := range query {
+		if k != URLSignatureParam {
+			signed[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write([]byte(path))
+	mac.Write([]byte{'?'})
+	mac.Write([]byte(signed.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsSignedURL reports whether a URL carries a URLSigner signature
+func IsSignedURL(u *url.URL) bool {
+	return u.Query().Has(URLSignatureParam)
+}
+
+// BlobPresigner mints URLs for downloading blobs straight from object
+// storage
+type BlobPresigner interface {
+	PresignGet(d Digest, ttl time.Duration) (SignedURL, error)
+}
+
+// PresignGet returns a presigned S3 GET URL for a blob
+func (s *S3BlobStore) PresignGet(d Digest, ttl time.Duration) (SignedURL, error) {
+	if _, err := ParseDigest(string(d)); err != nil {
+		return SignedURL{}, err
+	}
+	u, err := url.Parse(fmt.Sprintf("%s/%s/%ssha256/%s", s.Endpoint, s.Bucket, s.Prefix, d.Hex()))
+	if err != nil {
+		return SignedURL{}, fmt.Errorf("presign %s: %w", d, err)
+	}
+	v4 := presignV4{
+		scheme: "AWS4", param: "X-Amz-", terminator: "aws4_request", service: "s3",
+		region: s.Region, accessKey: s.AccessKeyID, secret: s.SecretAccessKey, token: s.SessionToken,
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	return v4.sign(u, now().UTC(), ttl)
+}
+
+// GCSPresigner presigns Google Cloud Storage downloads with an HMAC key
+// using the V4 signing process. Blobs are addressed like S3BlobStore
+// objects, as Prefix + "sha256/<hex>" in Bucket.
+type GCSPresigner struct {
+	Bucket   string
+	Prefix   string
+	AccessID string
+	Secret   string
+	// Endpoint defaults to https://storage.googleapis.com
+	Endpoint string
+
+	now func() time.Time
+}
+
+// PresignGet returns a presigned GCS GET URL for a blob
+func (g *GCSPresigner) PresignGet(d Digest, ttl time.Duration) (SignedURL, error) {
+	if _, err := ParseDigest(string(d)); err != nil {
+		return SignedURL{}, err
+	}
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	u, err := url.Parse(fmt.Sprintf("%s/%s/%ssha256/%s", strings.TrimSuffix(endpoint, "/"), g.Bucket, g.Prefix, d.Hex()))
+	if err != nil {
+		return SignedURL{}, fmt.Errorf("presign %s: %w", d, err)
+	}
+	v4 := presignV4{
+		scheme: "GOOG4", param: "X-Goog-", terminator: "goog4_request", service: "storage",
+		region: "auto", accessKey: g.AccessID, secret: g.Secret,
+	}
+	now := time.Now
+	if g.now != nil {
+		now = g.now
+	}
+	return v4.sign(u, now().UTC(), ttl)
+}
+
+// presignV4 holds the parameters of S3-style V4 query signing, which GCS
+// implements with its own names
+type presignV4 struct {
+	scheme     string
+	param      string
+	terminator string
+	service    string
+	region     string
+	accessKey  string
+	secret     string
+	token      string
+}
+
+// sign adds the V4 query authentication parameters to a GET URL
+func (p presignV4) sign(u *url.URL, t time.Time, ttl time.Duration) (SignedURL, error) {
+	if ttl <= 0 || ttl > MaxPresignTTL {
+		return SignedURL{}, fmt.Errorf("presign: ttl must be between 1s and %s", MaxPresignTTL)
+	}
+	amzDate, day := t.Format("20060102T150405Z"), t.Format("20060102")
+	scope := day + "/" + p.region + "/" + p.service + "/" + p.terminator
+	algorithm := p.scheme + "-HMAC-SHA256"
+
+	query := u.Query()
+	query.Set(p.param+"Algorithm", algorithm)
+	query.Set(p.param+"Credential", p.accessKey+"/"+scope)
+	query.Set(p.param+"Date", amzDate)
+	query.Set(p.param+"Expires", strconv.Itoa(int(ttl/time.Second)))
+	query.Set(p.param+"SignedHeaders", "host")
+	if p.token != "" {
+		query.Set(p.param+"Security-Token", p.token)
+	}
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	request := strings.Join([]string{"GET", path, canonicalQuery, "host:" + u.Host + "\n", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	requestHash := sha256.Sum256([]byte(request))
+	toSign := algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte(p.scheme + p.secret)
+	for _, part := range []string{day, p.region, p.service, p.terminator} {
+		key = hmacSHA256(key, part)
+	}
+
+	u.RawQuery = canonicalQuery + "&" + p.param + "Signature=" + hex.EncodeToString(hmacSHA256(key, toSign))
+	return SignedURL{URL: u.String(), Expires: t.Add(ttl)}, nil
+}
@@ -0,0 +1,137 @@
+package starfleet
+
+import (
+	"fmt"
+	"slices"
+)
+
+// =============================================================================
+// FAILURE SIMULATION
+// =============================================================================
+
+// SimulationExtension is the node and edge extension key recording how an
+// element is affected in a simulated scene: "failed" for elements the
+// scenario took down, "down" or "degraded" for nodes affected through their
+// dependencies
+const SimulationExtension = "simulation"
+
+// FailureScenario describes what fails in a simulation. Tags select every
+// node carrying any of them, so tagging nodes with their availability zone
+// (for example "az:us-east-1a") lets a scenario take out a whole zone.
+type FailureScenario struct {
+	Name  string   `json:"name,omitempty"`
+	Nodes []string `json:"nodes,omitempty"`
+	Edges []string `json:"edges,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// ImpactSummary lists the consequences of a failure scenario in scene order.
+// Causes maps each down or degraded node to the dependencies responsible.
+type ImpactSummary struct {
+	Failed      []string            `json:"failed,omitempty"`
+	FailedEdges []string            `json:"failedEdges,omitempty"`
+	Down        []string            `json:"down,omitempty"`
+	Degraded    []string            `json:"degraded,omitempty"`
+	Causes      map[string][]string `json:"causes,omitempty"`
+}
+
+// Affected returns the number of nodes that failed, went down or degraded
+func (s ImpactSummary) Affected() int {
+	return len(s.Failed) + len(s.Down) + len(s.Degraded)
+}
+
+// SimulationResult is the outcome of Simulate
+type SimulationResult struct {
+	Scenario FailureScenario `json:"scenario"`
+	Scene    SceneFile       `json:"scene"`
+	Impact   ImpactSummary   `json:"impact"`
+}
+
+// Simulate applies a failure scenario to a copy of the scene and propagates
+// its effects with PropagateStatus. Every node starts healthy so the result
+// reflects the scenario alone rather than the scene's live status. Failed
+// nodes become critical, and affected nodes and edges are tagged with
+// SimulationExtension. The input scene is not modified.
+//
+// Propagation follows the dependency model of PropagateStatus only.
+// Metric-driven status, such as ApplySLOStatus, is not re-evaluated, since
+// a simulated failure has no metrics of its own.
+func Simulate(sf *SceneFile, scenario FailureScenario) (SimulationResult, error) {
+	result := SimulationResult{Scenario: scenario}
+	for _, id := range scenario.Nodes {
+		if sf.FindNode(id) == nil {
+			return result, fmt.Errorf("simulate: %w: %s", ErrNodeNotFound, id)
+		}
+	}
+	for _, id := range scenario.Edges {
+		if sf.FindEdge(id) == nil {
+			return result, fmt.Errorf("simulate: %w: %s", ErrEdgeNotFound, id)
+		}
+	}
+
+	out := *sf
+	out.Scene.Nodes = slices.Clone(sf.Scene.Nodes)
+	out.Scene.Edges = slices.Clone(sf.Scene.Edges)
+	failed := make(map[string]bool)
+	for i := range out.Scene.Nodes {
+		n := &out.Scene.Nodes[i]
+		n.Status = NodeStatusHealthy
+		if slices.Contains(scenario.Nodes, n.ID) || slices.ContainsFunc(n.Tags, func(tag string) bool {
+			return slices.Contains(scenario.Tags, tag)
+		}) {
+			n.Status = NodeStatusCritical
+			failed[n.ID] = true
+		}
+	}
+
+	impacts := PropagateStatus(&out, scenario.Edges...)
+
+	summary := &result.Impact
+	for i := range out.Scene.Nodes {
+		n := &out.Scene.Nodes[i]
+		var effect string
+		switch {
+		case failed[n.ID]:
+			effect = "failed"
+			summary.Failed = append(summary.Failed, n.ID)
+		case n.Status == NodeStatusCritical:
+			effect = "down"
+			summary.Down = append(summary.Down, n.ID)
+		case n.Status == NodeStatusWarning:
+			effect = "degraded"
+			summary.Degraded = append(summary.Degraded, n.ID)
+		default:
+			continue
+		}
+		if impact, ok := impacts[n.ID]; ok {
+			if summary.Causes == nil {
+				summary.Causes = make(map[string][]string)
+			}
+			summary.Causes[n.ID] = impact.Causes
+		}
+		n.Extensions = withExtension(n.Extensions, SimulationExtension, effect)
+	}
+	down := make(map[string]bool, len(out.Scene.Nodes))
+	for i := range out.Scene.Nodes {
+		down[out.Scene.Nodes[i].ID] = out.Scene.Nodes[i].Status == NodeStatusCritical
+	}
+	for i := range out.Scene.Edges {
+		e := &out.Scene.Edges[i]
+		if slices.Contains(scenario.Edges, e.ID) || down[e.Source] || down[e.Target] {
+			summary.FailedEdges = append(summary.FailedEdges, e.ID)
+			e.Extensions = withExtension(e.Extensions, SimulationExtension, "failed")
+		}
+	}
+	result.Scene = out
+	return result, nil
+}
+
+// withExtension returns a copy of ext with key set to value
+func withExtension(ext map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(ext)+1)
+	for k, v := range ext {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
@@ -0,0 +1,85 @@
+package starfleet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newZoneScene returns lb -> api -> db-a/db-b with each database in its own
+// zone and a cache used only by api
+func newZoneScene() SceneFile {
+	sf := NewSceneFile("Zones")
+	for _, n := range []SceneNode{
+		{ID: "lb", Type: "loadbalancer", Name: "LB"},
+		{ID: "api", Type: "server", Name: "API"},
+		{ID: "db-a", Type: "database", Name: "DB A", Tags: []string{"az:a"}},
+		{ID: "db-b", Type: "database", Name: "DB B", Tags: []string{"az:b"}},
+		{ID: "cache", Type: "cache", Name: "Cache", Tags: []string{"az:a"}},
+	} {
+		n.Transform = NewTransform()
+		sf.AddNode(n)
+	}
+	sf.AddEdge(SceneEdge{ID: "lb-api", Source: "lb", Target: "api"})
+	sf.AddEdge(SceneEdge{ID: "api-db-a", Source: "api", Target: "db-a"})
+	sf.AddEdge(SceneEdge{ID: "api-db-b", Source: "api", Target: "db-b"})
+	sf.AddEdge(SceneEdge{ID: "api-cache", Source: "api", Target: "cache"})
+	return sf
+}
+
+// TestPropagateStatus tests redundancy-aware propagation
+func TestPropagateStatus(t *testing.T) {
+	sf := newZoneScene()
+	sf.FindNode("db-a").Status = NodeStatusCritical
+	impacts := PropagateStatus(&sf)
+	want := map[string]StatusImpact{
+		"api": {Status: NodeStatusWarning, Causes: []string{"db-a"}},
+		"lb":  {Status: NodeStatusWarning, Causes: []string{"api"}},
+	}
+	if !reflect.DeepEqual(impacts, want) {
+		t.Errorf("impacts mismatch: got %+v, want %+v", impacts, want)
+	}
+
+	// Losing the other replica's connection takes the whole chain down
+	impacts = PropagateStatus(&sf, "api-db-b")
+	if impacts["api"].Status != NodeStatusCritical || impacts["lb"].Status != NodeStatusCritical {
+		t.Errorf("expected api and lb to go critical: got %+v", impacts)
+	}
+	if got := impacts["api"].Causes; !reflect.DeepEqual(got, []string{"api-db-b", "db-a"}) {
+		t.Errorf("causes mismatch: got %v", got)
+	}
+}
+
+// TestSimulate tests a zone outage
+func TestSimulate(t *testing.T) {
+	sf := newZoneScene()
+	sf.FindNode("api").Status = NodeStatusCritical
+
+	result, err := Simulate(&sf, FailureScenario{Name: "zone a down", Tags: []string{"az:a"}})
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	impact := result.Impact
+	if !reflect.DeepEqual(impact.Failed, []string{"db-a", "cache"}) || !reflect.DeepEqual(impact.Down, []string{"lb", "api"}) {
+		t.Errorf("impact mismatch: got %+v", impact)
+	}
+	if !reflect.DeepEqual(impact.Causes["api"], []string{"cache"}) {
+		t.Errorf("api causes mismatch: got %v", impact.Causes["api"])
+	}
+	if impact.Affected() != 4 || len(impact.FailedEdges) != 4 {
+		t.Errorf("counts mismatch: got %d affected, %v failed edges", impact.Affected(), impact.FailedEdges)
+	}
+	if db := result.Scene.FindNode("db-b"); db.Status != NodeStatusHealthy || db.Extensions != nil {
+		t.Errorf("db-b should be unaffected: got %+v", db)
+	}
+	if got := result.Scene.FindNode("lb").Extensions[SimulationExtension]; got != "down" {
+		t.Errorf("lb extension mismatch: got %v", got)
+	}
+	if sf.FindNode("db-a").Status != "" || sf.FindNode("api").Status != NodeStatusCritical {
+		t.Error("Simulate modified its input")
+	}
+
+	if _, err := Simulate(&sf, FailureScenario{Nodes: []string{"nope"}}); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
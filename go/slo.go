@@ -0,0 +1,209 @@
+package starfleet
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// =============================================================================
+// SERVICE LEVEL OBJECTIVES
+// =============================================================================
+
+// SLO is a service level objective attached to a node. The indicator is
+// computed from the node's data points for Metric: with a Threshold, a data
+// point is good when its value is at most the threshold (latency-style);
+// without one, each value is the fraction of good events in its interval
+// (availability-style). Windows are in seconds.
+type SLO struct {
+	Name      string              `json:"name" validate:"required"`
+	Metric    string              `json:"metric" validate:"required"`
+	Threshold *float64            `json:"threshold,omitempty"`
+	Objective float64             `json:"objective" validate:"required,gt=0,lt=1"`
+	Window    float64             `json:"window" validate:"required,gt=0"`
+	BurnRates []BurnRateThreshold `json:"burnRates,omitempty"`
+}
+
+// BurnRateThreshold maps a burn rate sustained over a lookback window to a
+// node status. A burn rate of 1 spends the error budget exactly over the SLO
+// window.
+type BurnRateThreshold struct {
+	Window float64    `json:"window" validate:"required,gt=0"`
+	Rate   float64    `json:"rate" validate:"required,gt=0"`
+	Status NodeStatus `json:"status" validate:"required,oneof=warning critical"`
+}
+
+// DefaultBurnRates are used by SLOs that define none: a fast burn that
+// would exhaust a 30-day budget in about two days pages as critical, and a
+// slower one that would exhaust it in five days warns.
+var DefaultBurnRates = []BurnRateThreshold{
+	{Window: 3600, Rate: 14.4, Status: NodeStatusCritical},
+	{Window: 6 * 3600, Rate: 6, Status: NodeStatusWarning},
+}
+
+// BurnRate is the measured burn rate over one lookback window
+type BurnRate struct {
+	Window   float64 `json:"window"`
+	Rate     float64 `json:"rate"`
+	Breached bool    `json:"breached,omitempty"`
+}
+
+// SLOEvaluation is the state of an SLO at a point in time. SLI is the
+// fraction of good events over the SLO window and BudgetRemaining the
+// fraction of the error budget left, which is negative once overspent.
+type SLOEvaluation struct {
+	NodeID          string     `json:"nodeId,omitempty"`
+	SLO             string     `json:"slo" validate:"required"`
+	Samples         int        `json:"samples"`
+	SLI             float64    `json:"sli"`
+	Compliant       bool       `json:"compliant"`
+	BudgetRemaining float64    `json:"budgetRemaining"`
+	BurnRates       []BurnRate `json:"burnRates,omitempty"`
+	Status          NodeStatus `json:"status"`
+}
+
+// Evaluate computes the SLO from data points at time now. Points outside the
+// SLO window and non-numeric values are ignored; without any samples the
+// status is unknown. An SLO whose budget is spent is critical, otherwise
+// the status of the most severe breached burn-rate threshold applies.
+func (s SLO) Evaluate(points []MetricsDataPoint, now time.Time) SLOEvaluation {
+	eval := SLOEvaluation{SLO: s.Name, Status: NodeStatusUnknown}
+	sli, n := s.indicator(points, now, s.Window)
+	if n == 0 {
+		return eval
+	}
+	budget := 1 - s.Objective
+	eval.Samples = n
+	eval.SLI = sli
+	eval.Compliant = sli >= s.Objective
+	eval.BudgetRemaining = 1 - (1-sli)/budget
+	eval.Status = NodeStatusHealthy
+	if !eval.Compliant {
+		eval.Status = NodeStatusCritical
+	}
+
+	thresholds := s.BurnRates
+	if len(thresholds) == 0 {
+		thresholds = DefaultBurnRates
+	}
+	for _, t := range thresholds {
+		sli, n := s.indicator(points, now, t.Window)
+		rate := BurnRate{Window: t.Window}
+		if n > 0 {
+			rate.Rate = (1 - sli) / budget
+			rate.Breached = rate.Rate >= t.Rate
+		}
+		if rate.Breached && statusSeverity(t.Status) > statusSeverity(eval.Status) {
+			eval.Status = t.Status
+		}
+		eval.BurnRates = append(eval.BurnRates, rate)
+	}
+	return eval
+}
+
+// indicator returns the mean fraction of good events over the window ending
+// at now and the number of samples it is based on
+func (s SLO) indicator(points []MetricsDataPoint, now time.Time, window float64) (float64, int) {
+	from := now.Add(-time.Duration(window * float64(time.Second)))
+	var good float64
+	n := 0
+	for _, p := range points {
+		if p.Timestamp.Before(from) || p.Timestamp.After(now) {
+			continue
+		}
+		v, ok := toFloat64(p.Value)
+		if !ok || math.IsNaN(v) {
+			continue
+		}
+		if s.Threshold != nil {
+			if v <= *s.Threshold {
+				v = 1
+			} else {
+				v = 0
+			}
+		}
+		good += math.Max(0, math.Min(1, v))
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return good / float64(n), n
+}
+
+// EvaluateSLOs evaluates the SLOs of every node against metrics results,
+// matching results to SLOs by node ID and metric name
+func EvaluateSLOs(sf *SceneFile, results []MetricsResult, now time.Time) []SLOEvaluation {
+	var evals []SLOEvaluation
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		for _, slo := range n.SLOs {
+			var points []MetricsDataPoint
+			for _, r := range results {
+				if r.NodeID == n.ID && r.MetricName == slo.Metric {
+					points = append(points, r.DataPoints...)
+				}
+			}
+			eval := slo.Evaluate(points, now)
+			eval.NodeID = n.ID
+			evals = append(evals, eval)
+		}
+	}
+	return evals
+}
+
+// ApplySLOStatus sets the status of every evaluated node to the most severe
+// status among its SLO evaluations. Nodes whose SLOs all lack samples become
+// unknown; nodes without evaluations are left alone.
+func ApplySLOStatus(sf *SceneFile, evals []SLOEvaluation) {
+	worst := make(map[string]NodeStatus)
+	for _, e := range evals {
+		current, seen := worst[e.NodeID]
+		switch {
+		case !seen, current == NodeStatusUnknown && e.Status != NodeStatusUnknown:
+			worst[e.NodeID] = e.Status
+		case statusSeverity(e.Status) > statusSeverity(current):
+			worst[e.NodeID] = e.Status
+		}
+	}
+	for i := range sf.Scene.Nodes {
+		if status, ok := worst[sf.Scene.Nodes[i].ID]; ok {
+			sf.Scene.Nodes[i].Status = status
+		}
+	}
+}
+
+// ValidateSLOs checks SLO definitions: names unique per node, an objective
+// strictly between 0 and 1, positive windows and burn-rate thresholds that
+// map to warning or critical
+func ValidateSLOs(sf *SceneFile) []string {
+	var errs []string
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		names := make(map[string]bool, len(node.SLOs))
+		for _, slo := range node.SLOs {
+			switch {
+			case slo.Name == "":
+				errs = append(errs, fmt.Sprintf("Node %s has an SLO without a name", node.ID))
+			case names[slo.Name]:
+				errs = append(errs, fmt.Sprintf("Node %s has duplicate SLO: %s", node.ID, slo.Name))
+			}
+			names[slo.Name] = true
+			if slo.Metric == "" {
+				errs = append(errs, fmt.Sprintf("SLO %s on node %s has no metric", slo.Name, node.ID))
+			}
+			if slo.Objective <= 0 || slo.Objective >= 1 {
+				errs = append(errs, fmt.Sprintf("SLO %s on node %s has objective out of range: %g", slo.Name, node.ID, slo.Objective))
+			}
+			if slo.Window <= 0 {
+				errs = append(errs, fmt.Sprintf("SLO %s on node %s has no window", slo.Name, node.ID))
+			}
+			for _, t := range slo.BurnRates {
+				if t.Window <= 0 || t.Rate <= 0 || (t.Status != NodeStatusWarning && t.Status != NodeStatusCritical) {
+					errs = append(errs, fmt.Sprintf("SLO %s on node %s has an invalid burn-rate threshold", slo.Name, node.ID))
+				}
+			}
+		}
+	}
+	return errs
+}
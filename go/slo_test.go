@@ -0,0 +1,92 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// sloPoints returns one data point per minute over the hour before now,
+// with the last bad minutes having value bad and the rest good
+func sloPoints(now time.Time, good, bad interface{}, badMinutes int) []MetricsDataPoint {
+	points := make([]MetricsDataPoint, 60)
+	for i := range points {
+		points[i] = MetricsDataPoint{Timestamp: now.Add(-time.Duration(59-i) * time.Minute), Value: good}
+		if i >= 60-badMinutes {
+			points[i].Value = bad
+		}
+	}
+	return points
+}
+
+// TestSLO_Evaluate tests compliance, budget and burn-rate evaluation
+func TestSLO_Evaluate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	availability := SLO{Name: "availability", Metric: "success_ratio", Objective: 0.99, Window: 3600}
+
+	eval := availability.Evaluate(sloPoints(now, 1.0, 0.97, 10), now)
+	if eval.Samples != 60 || math.Abs(eval.SLI-0.995) > 1e-9 || !eval.Compliant {
+		t.Errorf("evaluation mismatch: got %+v", eval)
+	}
+	if math.Abs(eval.BudgetRemaining-0.5) > 1e-9 || eval.Status != NodeStatusHealthy {
+		t.Errorf("budget mismatch: got %v, %s", eval.BudgetRemaining, eval.Status)
+	}
+
+	// Half an hour at 5% errors overspends the budget and trips only the
+	// slower burn-rate threshold
+	availability.BurnRates = []BurnRateThreshold{
+		{Window: 600, Rate: 10, Status: NodeStatusCritical},
+		{Window: 1800, Rate: 2, Status: NodeStatusWarning},
+	}
+	eval = availability.Evaluate(sloPoints(now, 1.0, 0.95, 30), now)
+	if eval.Status != NodeStatusCritical || eval.Compliant {
+		t.Errorf("overspent SLO should be critical: got %+v", eval)
+	}
+	if len(eval.BurnRates) != 2 || eval.BurnRates[0].Breached || !eval.BurnRates[1].Breached {
+		t.Errorf("burn rates mismatch: got %+v", eval.BurnRates)
+	}
+
+	threshold := 300.0
+	latency := SLO{Name: "latency", Metric: "p99_ms", Threshold: &threshold, Objective: 0.9, Window: 3600,
+		BurnRates: []BurnRateThreshold{{Window: 300, Rate: 5, Status: NodeStatusWarning}}}
+	eval = latency.Evaluate(sloPoints(now, 120, 450, 3), now)
+	if eval.Status != NodeStatusWarning || !eval.Compliant {
+		t.Errorf("latency evaluation mismatch: got %+v", eval)
+	}
+
+	if eval := latency.Evaluate(nil, now); eval.Status != NodeStatusUnknown {
+		t.Errorf("no samples should be unknown: got %s", eval.Status)
+	}
+}
+
+// TestEvaluateSLOs tests matching metrics results to node SLOs and mapping
+// the evaluations onto node status
+func TestEvaluateSLOs(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sf := NewSceneFile("SLOs")
+	slo := SLO{Name: "availability", Metric: "success_ratio", Objective: 0.999, Window: 3600}
+	sf.AddNode(SceneNode{ID: "api", Type: "server", Name: "API", Transform: NewTransform(), SLOs: []SLO{slo}})
+	sf.AddNode(SceneNode{ID: "db", Type: "database", Name: "DB", Transform: NewTransform(), SLOs: []SLO{slo}})
+	sf.AddNode(SceneNode{ID: "lb", Type: "loadbalancer", Name: "LB", Transform: NewTransform(), Status: NodeStatusWarning})
+
+	results := []MetricsResult{
+		{NodeID: "api", MetricName: "success_ratio", DataPoints: sloPoints(now, 1, 0, 5)},
+		{NodeID: "api", MetricName: "latency", DataPoints: sloPoints(now, 0, 0, 0)},
+	}
+	evals := EvaluateSLOs(&sf, results, now)
+	if len(evals) != 2 || evals[0].NodeID != "api" || evals[0].Samples != 60 || evals[1].Samples != 0 {
+		t.Fatalf("evaluations mismatch: got %+v", evals)
+	}
+
+	ApplySLOStatus(&sf, evals)
+	for id, want := range map[string]NodeStatus{"api": NodeStatusCritical, "db": NodeStatusUnknown, "lb": NodeStatusWarning} {
+		if got := sf.FindNode(id).Status; got != want {
+			t.Errorf("%s status mismatch: got %s, want %s", id, got, want)
+		}
+	}
+
+	sf.Scene.Nodes[0].SLOs = append(sf.Scene.Nodes[0].SLOs, SLO{Name: "availability", Objective: 1})
+	if errs := ValidateSLOs(&sf); len(errs) != 4 {
+		t.Errorf("validation errors mismatch: got %v", errs)
+	}
+}
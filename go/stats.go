@@ -0,0 +1,174 @@
+package starfleet
+
+import (
+	"context"
+	"math"
+)
+
+// =============================================================================
+// SCENE STATISTICS
+// =============================================================================
+
+// CalculateSceneStats counts the nodes and edges of a scene, the vertices
+// and triangles of their resolved geometry, and the bounds of node
+// positions. It mirrors calculateSceneStats in the TypeScript SDK, which
+// leaves out the mesh totals.
+func CalculateSceneStats(sf *SceneFile) SceneStats {
+	stats, _ := CalculateSceneStatsContext(context.Background(), sf)
+	return stats
+}
+
+// CalculateSceneStatsContext is CalculateSceneStats for callers that may
+// abandon it on huge scenes. It returns the context's error once it is
+// done.
+func CalculateSceneStatsContext(ctx context.Context, sf *SceneFile) (SceneStats, error) {
+	if err := ctx.Err(); err != nil {
+		return SceneStats{}, err
+	}
+	stats := SceneStats{NodeCount: len(sf.Scene.Nodes), EdgeCount: len(sf.Scene.Edges)}
+	if len(sf.Scene.Nodes) == 0 {
+		return stats, nil
+	}
+
+	c := canceler{ctx: ctx}
+	// Library geometries are shared by many nodes and only meshed once
+	type counts struct{ vertices, triangles int }
+	type geometryKey struct {
+		ref    string
+		inline *Geometry
+	}
+	meshed := make(map[geometryKey]counts)
+	inf := math.Inf(1)
+	lo, hi := Vector3{X: inf, Y: inf, Z: inf}, Vector3{X: -inf, Y: -inf, Z: -inf}
+	for i := range sf.Scene.Nodes {
+		if err := c.check(); err != nil {
+			return SceneStats{}, err
+		}
+		node := &sf.Scene.Nodes[i]
+		key := geometryKey{inline: node.Geometry}
+		if node.Geometry == nil {
+			key.ref = node.GeometryRef
+		}
+		n, ok := meshed[key]
+		if !ok {
+			mesh := geometryMesh(sf.ResolveGeometry(node))
+			n = counts{mesh.VertexCount(), mesh.TriangleCount()}
+			meshed[key] = n
+		}
+		stats.TotalVertices += n.vertices
+		stats.TotalTriangles += n.triangles
+
+		p := node.Transform.Position
+		if !p.IsFinite() {
+			continue
+		}
+		lo = Vector3{X: math.Min(lo.X, p.X), Y: math.Min(lo.Y, p.Y), Z: math.Min(lo.Z, p.Z)}
+		hi = Vector3{X: math.Max(hi.X, p.X), Y: math.Max(hi.Y, p.Y), Z: math.Max(hi.Z, p.Z)}
+	}
+	// Bounds stay unset when no position was finite
+	if lo.X <= hi.X {
+		stats.Bounds = &SceneStatsSize{Min: lo, Max: hi, Size: hi.Sub(lo)}
+	}
+	return stats, nil
+}
+
+// DetailedSceneStats adds breakdowns by type and status and the shape of
+// the graph to SceneStats
+type DetailedSceneStats struct {
+	SceneStats
+	NodesByType   map[string]int     `json:"nodesByType,omitempty"`
+	NodesByStatus map[NodeStatus]int `json:"nodesByStatus,omitempty"`
+	// EdgesByType counts typed edges only
+	EdgesByType map[string]int `json:"edgesByType,omitempty"`
+	// Components counts connected components, following edges both ways
+	Components    int `json:"components"`
+	IsolatedNodes int `json:"isolatedNodes"`
+	MaxDegree     int `json:"maxDegree"`
+	// DanglingEdges counts edges with an endpoint outside the scene,
+	// including edges into other scenes
+	DanglingEdges int `json:"danglingEdges,omitempty"`
+}
+
+// CalculateDetailedStats returns the SceneStats of a scene along with
+// node and edge breakdowns and graph connectivity
+func CalculateDetailedStats(sf *SceneFile) DetailedSceneStats {
+	stats, _ := CalculateDetailedStatsContext(context.Background(), sf)
+	return stats
+}
+
+// CalculateDetailedStatsContext is CalculateDetailedStats with
+// cancellation
+func CalculateDetailedStatsContext(ctx context.Context, sf *SceneFile) (DetailedSceneStats, error) {
+	base, err := CalculateSceneStatsContext(ctx, sf)
+	if err != nil {
+		return DetailedSceneStats{}, err
+	}
+	stats := DetailedSceneStats{SceneStats: base}
+	nodes := sf.Scene.Nodes
+	if len(nodes) == 0 && len(sf.Scene.Edges) == 0 {
+		return stats, nil
+	}
+
+	c := canceler{ctx: ctx}
+	stats.NodesByType = make(map[string]int)
+	index := make(map[string]int, len(nodes))
+	for i := range nodes {
+		if err := c.check(); err != nil {
+			return DetailedSceneStats{}, err
+		}
+		index[nodes[i].ID] = i
+		stats.NodesByType[nodes[i].Type]++
+		if status := nodes[i].Status; status != "" {
+			if stats.NodesByStatus == nil {
+				stats.NodesByStatus = make(map[NodeStatus]int)
+			}
+			stats.NodesByStatus[status]++
+		}
+	}
+
+	// Union-find over node indexes, halving paths as it goes
+	parent := make([]int, len(nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	stats.Components = len(nodes)
+	degree := make([]int, len(nodes))
+	for i := range sf.Scene.Edges {
+		if err := c.check(); err != nil {
+			return DetailedSceneStats{}, err
+		}
+		edge := &sf.Scene.Edges[i]
+		if edge.Type != "" {
+			if stats.EdgesByType == nil {
+				stats.EdgesByType = make(map[string]int)
+			}
+			stats.EdgesByType[edge.Type]++
+		}
+		src, okSrc := index[edge.Source]
+		dst, okDst := index[edge.Target]
+		if !okSrc || !okDst || edge.TargetScene != "" {
+			stats.DanglingEdges++
+			continue
+		}
+		degree[src]++
+		degree[dst]++
+		if a, b := find(src), find(dst); a != b {
+			parent[a] = b
+			stats.Components--
+		}
+	}
+	for _, d := range degree {
+		stats.MaxDegree = max(stats.MaxDegree, d)
+		if d == 0 {
+			stats.IsolatedNodes++
+		}
+	}
+	return stats, nil
+}
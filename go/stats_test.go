@@ -0,0 +1,56 @@
+package starfleet
+
+import "testing"
+
+// TestCalculateSceneStats tests counts, mesh totals and position bounds
+func TestCalculateSceneStats(t *testing.T) {
+	sf := newDiffScene()
+	sf.Geometries = map[string]Geometry{"plane": {Type: GeometryPlane}}
+	sf.Scene.Nodes[0].Transform.Position = Vector3{X: -2, Y: 1, Z: 0}
+	sf.Scene.Nodes[1].Transform.Position = Vector3{X: 3, Y: -1, Z: 4}
+	sf.Scene.Nodes[1].GeometryRef = "plane"
+	sf.Scene.Nodes[2].GeometryRef = "plane"
+
+	stats := CalculateSceneStats(&sf)
+	if stats.NodeCount != 3 || stats.EdgeCount != 1 {
+		t.Errorf("counts mismatch: got %d nodes and %d edges, want 3 and 1", stats.NodeCount, stats.EdgeCount)
+	}
+	box, plane := geometryMesh(nil), geometryMesh(&Geometry{Type: GeometryPlane})
+	if want := box.TriangleCount() + 2*plane.TriangleCount(); stats.TotalTriangles != want {
+		t.Errorf("triangles mismatch: got %d, want %d", stats.TotalTriangles, want)
+	}
+	if want := box.VertexCount() + 2*plane.VertexCount(); stats.TotalVertices != want {
+		t.Errorf("vertices mismatch: got %d, want %d", stats.TotalVertices, want)
+	}
+	want := SceneStatsSize{Min: Vector3{X: -2, Y: -1}, Max: Vector3{X: 3, Y: 1, Z: 4}, Size: Vector3{X: 5, Y: 2, Z: 4}}
+	if stats.Bounds == nil || *stats.Bounds != want {
+		t.Errorf("bounds mismatch: got %+v, want %+v", stats.Bounds, want)
+	}
+
+	empty := NewSceneFile("Empty")
+	if stats := CalculateSceneStats(&empty); stats.Bounds != nil {
+		t.Errorf("bounds mismatch: got %+v, want nil", stats.Bounds)
+	}
+}
+
+// TestCalculateDetailedStats tests breakdowns and connectivity
+func TestCalculateDetailedStats(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Status = NodeStatusCritical
+	sf.Scene.Nodes[2].Type = "db"
+	sf.Scene.Edges[0].Type = "http"
+	sf.AddEdge(SceneEdge{ID: "b-b", Source: "b", Target: "b"})
+	sf.AddEdge(SceneEdge{ID: "b-x", Source: "b", Target: "x"})
+
+	stats := CalculateDetailedStats(&sf)
+	if stats.NodeCount != 3 || stats.EdgeCount != 3 {
+		t.Errorf("counts mismatch: got %d nodes and %d edges", stats.NodeCount, stats.EdgeCount)
+	}
+	if stats.NodesByType["db"] != 1 || stats.NodesByStatus[NodeStatusCritical] != 1 || len(stats.EdgesByType) != 1 {
+		t.Errorf("breakdown mismatch: got %v %v %v", stats.NodesByType, stats.NodesByStatus, stats.EdgesByType)
+	}
+	if stats.Components != 2 || stats.IsolatedNodes != 1 || stats.MaxDegree != 3 || stats.DanglingEdges != 1 {
+		t.Errorf("graph mismatch: got %d components, %d isolated, max degree %d, %d dangling",
+			stats.Components, stats.IsolatedNodes, stats.MaxDegree, stats.DanglingEdges)
+	}
+}
@@ -0,0 +1,397 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// SCENE STORE
+// =============================================================================
+
+// Sentinel errors returned by scene stores
+var (
+	ErrSceneNotFound    = errors.New("scene not found")
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrRevisionConflict = errors.New("revision conflict")
+)
+
+// AnyRevision disables the revision check on writes. Passing zero instead
+// requires that the scene does not exist yet.
+const AnyRevision int64 = -1
+
+// SceneRevision is a stored scene at a specific revision. Revisions start at
+// one and increase by one with every successful write.
+type SceneRevision struct {
+	ID       string    `json:"id" validate:"required"`
+	Revision int64     `json:"revision" validate:"required,min=1"`
+	Updated  time.Time `json:"updated"`
+	Scene    SceneFile `json:"scene" validate:"required"`
+}
+
+// SceneSummary describes a stored scene without its content
+type SceneSummary struct {
+	ID       string    `json:"id" validate:"required"`
+	Name     string    `json:"name,omitempty"`
+	Revision int64     `json:"revision" validate:"required,min=1"`
+	Updated  time.Time `json:"updated"`
+}
+
+// RevisionConflictError reports a write whose expected revision did not
+// match the stored one. Diff, when set, describes what changed between the
+// expected revision and the current one.
+type RevisionConflictError struct {
+	ID       string     `json:"id"`
+	Expected int64      `json:"expected"`
+	Current  int64      `json:"current"`
+	Diff     *SceneDiff `json:"diff,omitempty"`
+}
+
+func (e *RevisionConflictError) Error() string {
+	return fmt.Sprintf("%s: scene %s is at revision %d, expected %d", ErrRevisionConflict, e.ID, e.Current, e.Expected)
+}
+
+// Unwrap returns ErrRevisionConflict so callers can use errors.Is
+func (e *RevisionConflictError) Unwrap() error {
+	return ErrRevisionConflict
+}
+
+// SceneStore persists scenes with optimistic concurrency. Writes name the
+// revision they were based on and fail with a *RevisionConflictError when
+// another writer got there first.
+type SceneStore interface {
+	// List returns summaries of all scenes ordered by ID
+	List(ctx context.Context) ([]SceneSummary, error)
+	// Get returns the latest revision of a scene
+	Get(ctx context.Context, id string) (SceneRevision, error)
+	// GetRevision returns a specific revision of a scene
+	GetRevision(ctx context.Context, id string, revision int64) (SceneRevision, error)
+	// Put stores a new revision. expected is the revision the write is based
+	// on, zero to create a new scene, or AnyRevision to skip the check.
+	// Element revisions are updated as by UpdateRevisions, which may reject
+	// the write with a *StaleElementError.
+	Put(ctx context.Context, id string, sf SceneFile, expected int64) (SceneRevision, error)
+	// Delete removes a scene and its history
+	Delete(ctx context.Context, id string, expected int64) error
+}
+
+// storedScene is a scene's history, oldest revision first. Scenes are kept
+// encoded so callers can never mutate stored state. Revisions hold either a
+// full snapshot or a SceneDelta against the previous retained revision;
+// head is the full encoding of the latest revision, kept to compute the
+// next delta, and name its scene name, kept for listing. Compaction may
+// leave gaps in the revision numbers.
+type storedScene struct {
+	revisions []storedRevision
+	head      []byte
+	name      string
+}
+
+type storedRevision struct {
+	revision int64
+	updated  time.Time
+	data     []byte
+	delta    bool
+}
+
+func (s *storedScene) latest() int64 {
+	if len(s.revisions) == 0 {
+		return 0
+	}
+	return s.revisions[len(s.revisions)-1].revision
+}
+
+// find returns the index of a retained revision
+func (s *storedScene) find(revision int64) (int, bool) {
+	i := sort.Search(len(s.revisions), func(i int) bool { return s.revisions[i].revision >= revision })
+	return i, i < len(s.revisions) && s.revisions[i].revision == revision
+}
+
+// deltasSinceSnapshot counts the delta revisions after the last snapshot
+func (s *storedScene) deltasSinceSnapshot() int {
+	n := 0
+	for i := len(s.revisions) - 1; i >= 0 && s.revisions[i].delta; i-- {
+		n++
+	}
+	return n
+}
+
+// MemorySceneStore is an in-memory SceneStore that keeps every revision. It
+// is safe for concurrent use.
+//
+// With SnapshotEvery set to n, only every nth revision of a scene is stored
+// in full and the others as deltas against their predecessor, which cuts
+// storage for scenes saved often with small changes. Reading a revision
+// replays at most n-1 deltas. Set it before the store is used.
+type MemorySceneStore struct {
+	SnapshotEvery int
+
+	mu     sync.RWMutex
+	scenes map[string]*storedScene
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// NewMemorySceneStore creates an empty in-memory store
+func NewMemorySceneStore() *MemorySceneStore {
+	return &MemorySceneStore{scenes: make(map[string]*storedScene), now: time.Now}
+}
+
+// List returns summaries of all scenes ordered by ID
+func (s *MemorySceneStore) List(ctx context.Context) ([]SceneSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summaries := make([]SceneSummary, 0, len(s.scenes))
+	for id, stored := range s.scenes {
+		latest := stored.revisions[len(stored.revisions)-1]
+		summaries = append(summaries, SceneSummary{
+			ID:       id,
+			Name:     stored.name,
+			Revision: latest.revision,
+			Updated:  latest.updated,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+	return summaries, nil
+}
+
+// Get returns the latest revision of a scene
+func (s *MemorySceneStore) Get(ctx context.Context, id string) (SceneRevision, error) {
+	return s.GetRevision(ctx, id, 0)
+}
+
+// LatestRevision implements RevisionReader without decoding the scene
+func (s *MemorySceneStore) LatestRevision(ctx context.Context, id string) (int64, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.scenes[id]
+	if !ok {
+		return 0, 0, fmt.Errorf("get scene: %w: %s", ErrSceneNotFound, id)
+	}
+	return stored.latest(), int64(len(stored.head)), nil
+}
+
+// GetRevision returns a specific revision of a scene. Zero selects the
+// latest revision.
+func (s *MemorySceneStore) GetRevision(ctx context.Context, id string, revision int64) (SceneRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return SceneRevision{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.scenes[id]
+	if !ok {
+		return SceneRevision{}, fmt.Errorf("get scene: %w: %s", ErrSceneNotFound, id)
+	}
+	if revision == 0 {
+		revision = stored.latest()
+	}
+	return stored.revision(id, revision)
+}
+
+// Put stores a new revision of a scene, numbering its nodes and edges with
+// UpdateRevisions
+func (s *MemorySceneStore) Put(ctx context.Context, id string, sf SceneFile, expected int64) (SceneRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return SceneRevision{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, exists := s.scenes[id]
+	if err := s.checkRevision(id, stored, expected); err != nil {
+		return SceneRevision{}, err
+	}
+	// Number elements on copies so the caller's scene is not modified
+	var prev SceneFile
+	if exists {
+		if err := json.Unmarshal(stored.head, &prev); err != nil {
+			return SceneRevision{}, fmt.Errorf("put scene %s: %w", id, err)
+		}
+	}
+	sf.Scene.Nodes = slices.Clone(sf.Scene.Nodes)
+	sf.Scene.Edges = slices.Clone(sf.Scene.Edges)
+	if err := UpdateRevisions(id, &prev, &sf); err != nil {
+		return SceneRevision{}, err
+	}
+	data, err := json.Marshal(&sf)
+	if err != nil {
+		return SceneRevision{}, fmt.Errorf("put scene %s: %w", id, err)
+	}
+	if !exists {
+		stored = &storedScene{}
+		s.scenes[id] = stored
+	}
+	rev := storedRevision{revision: stored.latest() + 1, updated: s.now().UTC(), data: data}
+	if s.SnapshotEvery > 1 && len(stored.revisions) > 0 && stored.deltasSinceSnapshot() < s.SnapshotEvery-1 {
+		if rev.data, err = json.Marshal(ComputeDelta(&prev, &sf)); err != nil {
+			return SceneRevision{}, fmt.Errorf("put scene %s: %w", id, err)
+		}
+		rev.delta = true
+	}
+	stored.revisions = append(stored.revisions, rev)
+	stored.head = data
+	stored.name = sf.Metadata.Name
+	return stored.revision(id, stored.latest())
+}
+
+// Delta returns the delta from the previous retained revision of a scene to
+// the given one; zero selects the latest revision. The oldest retained
+// revision has no predecessor and yields a delta carrying a full snapshot.
+func (s *MemorySceneStore) Delta(ctx context.Context, id string, revision int64) (SceneDelta, error) {
+	if err := ctx.Err(); err != nil {
+		return SceneDelta{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.scenes[id]
+	if !ok {
+		return SceneDelta{}, fmt.Errorf("get delta: %w: %s", ErrSceneNotFound, id)
+	}
+	if revision == 0 {
+		revision = stored.latest()
+	}
+	i, ok := stored.find(revision)
+	if !ok {
+		return SceneDelta{}, fmt.Errorf("get delta %s: %w: %d", id, ErrRevisionNotFound, revision)
+	}
+	if r := stored.revisions[i]; r.delta {
+		var d SceneDelta
+		if err := json.Unmarshal(r.data, &d); err != nil {
+			return SceneDelta{}, fmt.Errorf("decode delta %s@%d: %w", id, revision, err)
+		}
+		return d, nil
+	}
+	current, err := stored.revision(id, revision)
+	if err != nil {
+		return SceneDelta{}, err
+	}
+	if i == 0 {
+		return SceneDelta{Snapshot: &current.Scene}, nil
+	}
+	prev, err := stored.revision(id, stored.revisions[i-1].revision)
+	if err != nil {
+		return SceneDelta{}, err
+	}
+	return ComputeDelta(&prev.Scene, &current.Scene), nil
+}
+
+// StorageStats describes how a scene's history is stored
+type StorageStats struct {
+	Revisions int `json:"revisions"`
+	Snapshots int `json:"snapshots"`
+	Bytes     int `json:"bytes"`
+}
+
+// Stats reports the revisions, full snapshots and encoded bytes stored for
+// a scene
+func (s *MemorySceneStore) Stats(id string) (StorageStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.scenes[id]
+	if !ok {
+		return StorageStats{}, fmt.Errorf("stats: %w: %s", ErrSceneNotFound, id)
+	}
+	stats := StorageStats{Revisions: len(stored.revisions)}
+	for _, r := range stored.revisions {
+		if !r.delta {
+			stats.Snapshots++
+		}
+		stats.Bytes += len(r.data)
+	}
+	return stats, nil
+}
+
+// Delete removes a scene and its history
+func (s *MemorySceneStore) Delete(ctx context.Context, id string, expected int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.scenes[id]
+	if !ok {
+		return fmt.Errorf("delete scene: %w: %s", ErrSceneNotFound, id)
+	}
+	if err := s.checkRevision(id, stored, expected); err != nil {
+		return err
+	}
+	delete(s.scenes, id)
+	return nil
+}
+
+// checkRevision compares the expected revision against the stored one and
+// builds a conflict error describing the intervening changes. The caller
+// holds the lock.
+func (s *MemorySceneStore) checkRevision(id string, stored *storedScene, expected int64) error {
+	if expected == AnyRevision {
+		return nil
+	}
+	var current int64
+	if stored != nil {
+		current = stored.latest()
+	}
+	if current == expected {
+		return nil
+	}
+	conflict := &RevisionConflictError{ID: id, Expected: expected, Current: current}
+	if stored != nil && expected > 0 && expected < current {
+		base, err := stored.revision(id, expected)
+		if err == nil {
+			latest, err := stored.revision(id, current)
+			if err == nil {
+				diff := Diff(&base.Scene, &latest.Scene)
+				conflict.Diff = &diff
+			}
+		}
+	}
+	return conflict
+}
+
+// revision decodes a stored revision
+func (s *storedScene) revision(id string, revision int64) (SceneRevision, error) {
+	index, ok := s.find(revision)
+	if !ok {
+		return SceneRevision{}, fmt.Errorf("get scene %s: %w: %d", id, ErrRevisionNotFound, revision)
+	}
+	stored := s.revisions[index]
+	rev := SceneRevision{ID: id, Revision: revision, Updated: stored.updated}
+	if index == len(s.revisions)-1 {
+		if err := json.Unmarshal(s.head, &rev.Scene); err != nil {
+			return SceneRevision{}, fmt.Errorf("decode scene %s@%d: %w", id, revision, err)
+		}
+		return rev, nil
+	}
+
+	// Replay deltas forward from the closest snapshot
+	base := index
+	for s.revisions[base].delta {
+		base--
+	}
+	if err := json.Unmarshal(s.revisions[base].data, &rev.Scene); err != nil {
+		return SceneRevision{}, fmt.Errorf("decode scene %s@%d: %w", id, s.revisions[base].revision, err)
+	}
+	for _, r := range s.revisions[base+1 : index+1] {
+		var d SceneDelta
+		if err := json.Unmarshal(r.data, &d); err != nil {
+			return SceneRevision{}, fmt.Errorf("decode delta %s@%d: %w", id, r.revision, err)
+		}
+		scene, err := ApplyDelta(&rev.Scene, d)
+		if err != nil {
+			return SceneRevision{}, fmt.Errorf("reconstruct scene %s@%d: %w", id, r.revision, err)
+		}
+		rev.Scene = scene
+	}
+	return rev, nil
+}
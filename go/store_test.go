@@ -0,0 +1,95 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestMemorySceneStore_Revisions tests creation, updates and history reads
+func TestMemorySceneStore_Revisions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySceneStore()
+	sf := newDiffScene()
+
+	rev, err := store.Put(ctx, "prod", sf, 0)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if rev.Revision != 1 {
+		t.Errorf("revision mismatch: got %d, want 1", rev.Revision)
+	}
+	if _, err := store.Put(ctx, "prod", sf, 0); !errors.Is(err, ErrRevisionConflict) {
+		t.Errorf("expected conflict when creating an existing scene, got %v", err)
+	}
+
+	sf.Metadata.Name = "Renamed"
+	rev, err = store.Put(ctx, "prod", sf, 1)
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if rev.Revision != 2 {
+		t.Errorf("revision mismatch: got %d, want 2", rev.Revision)
+	}
+
+	// Mutating the caller's copy must not leak into the store
+	sf.Scene.Nodes[0].Name = "mutated"
+	first, err := store.GetRevision(ctx, "prod", 1)
+	if err != nil {
+		t.Fatalf("GetRevision failed: %v", err)
+	}
+	if first.Scene.Metadata.Name != "Diff" || first.Scene.Scene.Nodes[0].Name != "A" {
+		t.Errorf("revision 1 content mismatch: got %q / %q", first.Scene.Metadata.Name, first.Scene.Scene.Nodes[0].Name)
+	}
+
+	summaries, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Name != "Renamed" || summaries[0].Revision != 2 {
+		t.Errorf("summary mismatch: got %+v", summaries)
+	} else if latest, _ := store.Get(ctx, "prod"); !summaries[0].Updated.Equal(latest.Updated) {
+		t.Errorf("summary updated mismatch: got %v, want %v", summaries[0].Updated, latest.Updated)
+	}
+
+	if _, err := store.GetRevision(ctx, "prod", 3); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("expected ErrRevisionNotFound, got %v", err)
+	}
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrSceneNotFound) {
+		t.Errorf("expected ErrSceneNotFound, got %v", err)
+	}
+}
+
+// TestMemorySceneStore_Conflict tests the diff attached to stale writes
+func TestMemorySceneStore_Conflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySceneStore()
+	sf := newDiffScene()
+	if _, err := store.Put(ctx, "prod", sf, 0); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	theirs := newDiffScene()
+	theirs.Scene.Nodes[1].Status = NodeStatusWarning
+	if _, err := store.Put(ctx, "prod", theirs, 1); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	_, err := store.Put(ctx, "prod", sf, 1)
+	var conflict *RevisionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected RevisionConflictError, got %v", err)
+	}
+	if conflict.Expected != 1 || conflict.Current != 2 {
+		t.Errorf("revision mismatch: got expected=%d current=%d", conflict.Expected, conflict.Current)
+	}
+	if conflict.Diff == nil || len(conflict.Diff.Nodes) != 1 || conflict.Diff.Nodes[0].ID != "b" {
+		t.Errorf("diff mismatch: got %+v", conflict.Diff)
+	}
+
+	if err := store.Delete(ctx, "prod", 1); !errors.Is(err, ErrRevisionConflict) {
+		t.Errorf("expected conflict on stale delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "prod", AnyRevision); err != nil {
+		t.Errorf("unconditional delete failed: %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// =============================================================================
+// GRAPH SUMMARIZATION
+// =============================================================================
+
+// SupernodeKind is the structure a supernode replaces
+type SupernodeKind string
+
+const (
+	// SupernodeClique replaces nodes that are all connected to each other
+	SupernodeClique SupernodeKind = "clique"
+	// SupernodeStar replaces the leaves of a hub, which is kept
+	SupernodeStar SupernodeKind = "star"
+	// SupernodeCluster replaces nodes with the same type, parent and metadata
+	SupernodeCluster SupernodeKind = "cluster"
+)
+
+// SupernodeType is the node type of supernodes
+const SupernodeType = "supernode"
+
+// SupernodeExtension is the node extension key holding a supernode's
+// SupernodeMembership
+const SupernodeExtension = "supernode"
+
+// SupernodeMembership records what a supernode replaced so it can be
+// expanded again. Edges holds the members' edges as they were, both those
+// between members and those rerouted to the supernode.
+type SupernodeMembership struct {
+	Kind  SupernodeKind `json:"kind" validate:"required,oneof=clique star cluster"`
+	Count int           `json:"count"`
+	Nodes []SceneNode   `json:"nodes"`
+	Edges []SceneEdge   `json:"edges,omitempty"`
+}
+
+// SummarizeOptions represents the smallest structures Summarize replaces;
+// zero disables a kind
+type SummarizeOptions struct {
+	// MinClique is the smallest clique replaced
+	MinClique int
+	// MinStarLeaves is the smallest number of leaves of a hub replaced
+	MinStarLeaves int
+	// MinCluster is the smallest group of nodes with identical metadata
+	// replaced
+	MinCluster int
+}
+
+// DefaultSummarizeOptions returns the options used for overview renderings
+func DefaultSummarizeOptions() SummarizeOptions {
+	return SummarizeOptions{MinClique: 4, MinStarLeaves: 3, MinCluster: 3}
+}
+
+// Summarize returns a copy of the scene with dense substructures replaced by
+// supernodes: cliques, the leaves of stars and clusters of nodes sharing a
+// type, parent and non-empty metadata, detected in that order with each node
+// in at most one supernode. Parent nodes and existing supernodes are never
+// replaced. A supernode sits at the centroid of its members with their worst
+// status and mean numeric metrics, and carries a SupernodeMembership under
+// SupernodeExtension. Edges between members are dropped and the others are
+// rerouted to the supernode, parallel ones merged as by
+// AggregateParallelEdges. sf is not modified; see ExpandSupernodes.
+func Summarize(sf *SceneFile, opts SummarizeOptions) SceneFile {
+	nodes := sf.Scene.Nodes
+	index := make(map[string]int, len(nodes))
+	for i := range nodes {
+		index[nodes[i].ID] = i
+	}
+	adj := make([]map[int]bool, len(nodes))
+	for i := range adj {
+		adj[i] = make(map[int]bool)
+	}
+	for _, e := range sf.Scene.Edges {
+		s, okS := index[e.Source]
+		t, okT := index[e.Target]
+		if okS && okT && s != t {
+			adj[s][t], adj[t][s] = true, true
+		}
+	}
+	used := make([]bool, len(nodes))
+	for i := range nodes {
+		used[i] = len(nodes[i].Children) > 0 || nodes[i].Type == SupernodeType
+	}
+	byDegree := make([]int, len(nodes))
+	for i := range byDegree {
+		byDegree[i] = i
+	}
+	sort.SliceStable(byDegree, func(a, b int) bool { return len(adj[byDegree[a]]) > len(adj[byDegree[b]]) })
+	rank := make([]int, len(nodes))
+	for r, i := range byDegree {
+		rank[i] = r
+	}
+	// neighbors returns the neighbors of a node, best-connected first
+	neighbors := func(v int) []int {
+		out := make([]int, 0, len(adj[v]))
+		for u := range adj[v] {
+			out = append(out, u)
+		}
+		sort.Slice(out, func(a, b int) bool { return rank[out[a]] < rank[out[b]] })
+		return out
+	}
+
+	type group struct {
+		kind    SupernodeKind
+		members []int
+	}
+	var groups []group
+	claim := func(kind SupernodeKind, members []int) {
+		for _, i := range members {
+			used[i] = true
+		}
+		sort.Ints(members)
+		groups = append(groups, group{kind, members})
+	}
+
+	// Grow a clique greedily from each node, best-connected neighbors first
+	if opts.MinClique > 1 {
+		for _, v := range byDegree {
+			if used[v] || len(adj[v]) < opts.MinClique-1 {
+				continue
+			}
+			clique := []int{v}
+			for _, u := range neighbors(v) {
+				if used[u] {
+					continue
+				}
+				if !slices.ContainsFunc(clique, func(c int) bool { return !adj[u][c] }) {
+					clique = append(clique, u)
+				}
+			}
+			if len(clique) >= opts.MinClique {
+				claim(SupernodeClique, clique)
+			}
+		}
+	}
+	if opts.MinStarLeaves > 0 {
+		for _, hub := range byDegree {
+			var leaves []int
+			for _, u := range neighbors(hub) {
+				if !used[u] && len(adj[u]) == 1 {
+					leaves = append(leaves, u)
+				}
+			}
+			if len(leaves) >= opts.MinStarLeaves {
+				claim(SupernodeStar, leaves)
+			}
+		}
+	}
+	if opts.MinCluster > 1 {
+		clusters := make(map[string][]int)
+		var keys []string
+		for i := range nodes {
+			if used[i] || len(nodes[i].Metadata) == 0 {
+				continue
+			}
+			metadata, _ := json.Marshal(nodes[i].Metadata)
+			key := fmt.Sprintf("%s\x00%s\x00%s", nodes[i].Type, nodes[i].Parent, metadata)
+			if _, ok := clusters[key]; !ok {
+				keys = append(keys, key)
+			}
+			clusters[key] = append(clusters[key], i)
+		}
+		for _, key := range keys {
+			if len(clusters[key]) >= opts.MinCluster {
+				claim(SupernodeCluster, clusters[key])
+			}
+		}
+	}
+
+	out := *sf
+	if len(groups) == 0 {
+		return out
+	}
+	owner := make(map[string]int, len(nodes))
+	supernodes := make([]SceneNode, len(groups))
+	memberships := make([]SupernodeMembership, len(groups))
+	for g, grp := range groups {
+		first := &nodes[grp.members[0]]
+		sn := aggregateNode(nodes, grp.members, fmt.Sprintf("supernode:%s:%s", grp.kind, first.ID))
+		sn.Type = SupernodeType
+		sn.Name = fmt.Sprintf("%d %s nodes", len(grp.members), first.Type)
+		sn.Parent = first.Parent
+		m := SupernodeMembership{Kind: grp.kind, Count: len(grp.members)}
+		for _, i := range grp.members {
+			owner[nodes[i].ID] = g
+			m.Nodes = append(m.Nodes, nodes[i])
+			if nodes[i].Parent != sn.Parent {
+				sn.Parent = ""
+			}
+		}
+		supernodes[g], memberships[g] = sn, m
+	}
+
+	out.Scene.Nodes = make([]SceneNode, 0, len(nodes)-len(owner)+len(groups))
+	for _, n := range nodes {
+		if _, ok := owner[n.ID]; ok {
+			continue
+		}
+		if len(n.Children) > 0 {
+			children := slices.DeleteFunc(slices.Clone(n.Children), func(id string) bool {
+				_, ok := owner[id]
+				return ok
+			})
+			for _, sn := range supernodes {
+				if sn.Parent == n.ID {
+					children = append(children, sn.ID)
+				}
+			}
+			n.Children = children
+		}
+		out.Scene.Nodes = append(out.Scene.Nodes, n)
+	}
+
+	var kept, rerouted SceneFile
+	for _, e := range sf.Scene.Edges {
+		gs, inS := owner[e.Source]
+		gt, inT := owner[e.Target]
+		if !inS && !inT {
+			kept.Scene.Edges = append(kept.Scene.Edges, e)
+			continue
+		}
+		if inS {
+			memberships[gs].Edges = append(memberships[gs].Edges, e)
+		}
+		if inT && (!inS || gt != gs) {
+			memberships[gt].Edges = append(memberships[gt].Edges, e)
+		}
+		if inS && inT && gs == gt {
+			continue
+		}
+		if inS {
+			e.Source, e.SourcePort = supernodes[gs].ID, ""
+		}
+		if inT {
+			e.Target, e.TargetPort = supernodes[gt].ID, ""
+		}
+		e.Waypoints = nil
+		rerouted.Scene.Edges = append(rerouted.Scene.Edges, e)
+	}
+	AggregateParallelEdges(&rerouted)
+	out.Scene.Edges = append(kept.Scene.Edges, rerouted.Scene.Edges...)
+
+	for g := range supernodes {
+		supernodes[g].Extensions = map[string]interface{}{SupernodeExtension: memberships[g]}
+	}
+	out.Scene.Nodes = append(out.Scene.Nodes, supernodes...)
+	return out
+}
+
+// Membership returns the membership of a supernode and whether the node is
+// one, decoding it when the scene was read from JSON
+func (n *SceneNode) Membership() (SupernodeMembership, bool) {
+	switch m := n.Extensions[SupernodeExtension].(type) {
+	case SupernodeMembership:
+		return m, true
+	case map[string]interface{}:
+		var membership SupernodeMembership
+		if err := roundTripJSON(m, &membership); err == nil {
+			return membership, true
+		}
+	}
+	return SupernodeMembership{}, false
+}
+
+// ExpandSupernodes returns a copy of the scene with the given supernodes, or
+// all of them when no IDs are given, replaced by the nodes and edges they
+// stand for. Edges attached to an expanded supernode are dropped in favor
+// of the recorded ones; recorded edges to members of a supernode that stays
+// collapsed are rerouted to it, and those to nodes no longer in the scene
+// are dropped. sf is not modified.
+func ExpandSupernodes(sf *SceneFile, ids ...string) (SceneFile, error) {
+	expand := make(map[string]SupernodeMembership)
+	collapsed := make(map[string]string)
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		m, ok := n.Membership()
+		if n.Type != SupernodeType || !ok {
+			if slices.Contains(ids, n.ID) {
+				return SceneFile{}, fmt.Errorf("%w: %s is not a supernode", ErrNodeNotFound, n.ID)
+			}
+			continue
+		}
+		if len(ids) == 0 || slices.Contains(ids, n.ID) {
+			expand[n.ID] = m
+			continue
+		}
+		for _, member := range m.Nodes {
+			collapsed[member.ID] = n.ID
+		}
+	}
+	for _, id := range ids {
+		if _, ok := expand[id]; !ok && sf.FindNode(id) == nil {
+			return SceneFile{}, fmt.Errorf("%w: %s", ErrNodeNotFound, id)
+		}
+	}
+
+	out := *sf
+	out.Scene.Nodes = make([]SceneNode, 0, len(sf.Scene.Nodes))
+	restoredParents := make(map[string][]string)
+	for _, n := range sf.Scene.Nodes {
+		if m, ok := expand[n.ID]; ok {
+			for _, member := range m.Nodes {
+				if member.Parent != "" {
+					restoredParents[member.Parent] = append(restoredParents[member.Parent], member.ID)
+				}
+			}
+			continue
+		}
+		out.Scene.Nodes = append(out.Scene.Nodes, n)
+	}
+	present := make(map[string]bool, len(out.Scene.Nodes))
+	for i := range out.Scene.Nodes {
+		n := &out.Scene.Nodes[i]
+		present[n.ID] = true
+		if len(n.Children) == 0 && len(restoredParents[n.ID]) == 0 {
+			continue
+		}
+		children := slices.DeleteFunc(slices.Clone(n.Children), func(id string) bool {
+			_, ok := expand[id]
+			return ok
+		})
+		n.Children = append(children, restoredParents[n.ID]...)
+	}
+	for _, m := range expand {
+		for _, member := range m.Nodes {
+			present[member.ID] = true
+		}
+	}
+
+	out.Scene.Edges = make([]SceneEdge, 0, len(sf.Scene.Edges))
+	seen := make(map[string]bool, len(sf.Scene.Edges))
+	for _, e := range sf.Scene.Edges {
+		_, fromExpanded := expand[e.Source]
+		_, toExpanded := expand[e.Target]
+		if !fromExpanded && !toExpanded {
+			seen[e.ID] = true
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	// Expanded supernodes are restored in scene order so results are stable
+	for _, n := range sf.Scene.Nodes {
+		m, ok := expand[n.ID]
+		if !ok {
+			continue
+		}
+		out.Scene.Nodes = append(out.Scene.Nodes, m.Nodes...)
+		for _, e := range m.Edges {
+			if seen[e.ID] {
+				continue
+			}
+			if sn, ok := collapsed[e.Source]; ok {
+				e.Source, e.SourcePort = sn, ""
+			}
+			if sn, ok := collapsed[e.Target]; ok {
+				e.Target, e.TargetPort = sn, ""
+			}
+			if !present[e.Source] || !present[e.Target] {
+				continue
+			}
+			seen[e.ID] = true
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,124 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// newSummaryScene builds a scene with a four-node clique, a hub with four
+// leaves and three identically labeled workers in a pool
+func newSummaryScene() SceneFile {
+	sf := NewSceneFile("Summary")
+	add := func(id, typ string) *SceneNode {
+		sf.AddNode(SceneNode{ID: id, Type: typ, Name: id, Transform: NewTransform()})
+		return &sf.Scene.Nodes[len(sf.Scene.Nodes)-1]
+	}
+	for i := 0; i < 4; i++ {
+		add(fmt.Sprintf("db%d", i), "database")
+		for j := 0; j < i; j++ {
+			sf.AddEdge(SceneEdge{ID: fmt.Sprintf("db%d-db%d", j, i), Source: fmt.Sprintf("db%d", j), Target: fmt.Sprintf("db%d", i)})
+		}
+	}
+	add("lb", "loadbalancer")
+	sf.AddEdge(SceneEdge{ID: "lb-db0", Source: "lb", Target: "db0"})
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("client%d", i)
+		add(id, "client").Metrics = map[string]interface{}{"rps": float64(i)}
+		sf.AddEdge(SceneEdge{ID: id + "-lb", Source: id, Target: "lb"})
+	}
+	add("pool", "group").Children = []string{"w0", "w1", "w2"}
+	for i := 0; i < 3; i++ {
+		w := add(fmt.Sprintf("w%d", i), "worker")
+		w.Parent = "pool"
+		w.Metadata = map[string]interface{}{"queue": "jobs"}
+		sf.AddEdge(SceneEdge{ID: fmt.Sprintf("w%d-db%d", i, i), Source: w.ID, Target: fmt.Sprintf("db%d", i)})
+	}
+	return sf
+}
+
+// TestSummarize tests replacing cliques, stars and clusters with supernodes
+func TestSummarize(t *testing.T) {
+	sf := newSummaryScene()
+	out := Summarize(&sf, DefaultSummarizeOptions())
+	if result := ValidateScene(&out); !result.Valid {
+		t.Fatalf("summary is invalid: %v", result.Errors)
+	}
+	want := map[string]struct {
+		kind  SupernodeKind
+		count int
+	}{
+		"supernode:clique:db0":   {SupernodeClique, 4},
+		"supernode:star:client0": {SupernodeStar, 4},
+		"supernode:cluster:w0":   {SupernodeCluster, 3},
+	}
+	for id, w := range want {
+		n := out.FindNode(id)
+		if n == nil {
+			t.Fatalf("supernode %s missing: got %+v", id, out.Scene.Nodes)
+		}
+		m, ok := n.Membership()
+		if !ok || m.Kind != w.kind || m.Count != w.count || len(m.Nodes) != w.count {
+			t.Errorf("%s membership mismatch: got %+v", id, m)
+		}
+	}
+	if len(out.Scene.Nodes) != 5 {
+		t.Errorf("node count mismatch: got %d, want 5", len(out.Scene.Nodes))
+	}
+	if stars := out.FindNode("supernode:star:client0"); stars.Metrics["rps"] != 1.5 {
+		t.Errorf("star metrics mismatch: got %v", stars.Metrics)
+	}
+	if pool := out.FindNode("pool"); len(pool.Children) != 1 || pool.Children[0] != "supernode:cluster:w0" {
+		t.Errorf("pool children mismatch: got %v", pool.Children)
+	}
+	if e := out.FindEdge("w0-db0"); e == nil || e.Source != "supernode:cluster:w0" || e.Target != "supernode:clique:db0" || e.Weight != 3 {
+		t.Errorf("rerouted edge mismatch: got %+v", e)
+	}
+	if len(sf.Scene.Nodes) != 13 || sf.FindNode("pool").Children[0] != "w0" {
+		t.Error("input scene was modified")
+	}
+	if same := Summarize(&sf, SummarizeOptions{}); len(same.Scene.Nodes) != 13 {
+		t.Errorf("disabled summary should be unchanged: got %d nodes", len(same.Scene.Nodes))
+	}
+}
+
+// TestExpandSupernodes tests restoring summarized structures, also after a
+// JSON round trip
+func TestExpandSupernodes(t *testing.T) {
+	sf := newSummaryScene()
+	summary := Summarize(&sf, DefaultSummarizeOptions())
+	data, _ := json.Marshal(summary)
+	var decoded SceneFile
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	partial, err := ExpandSupernodes(&decoded, "supernode:cluster:w0")
+	if err != nil {
+		t.Fatalf("ExpandSupernodes failed: %v", err)
+	}
+	if result := ValidateScene(&partial); !result.Valid {
+		t.Fatalf("partial expansion is invalid: %v", result.Errors)
+	}
+	if e := partial.FindEdge("w1-db1"); e == nil || e.Source != "w1" || e.Target != "supernode:clique:db0" {
+		t.Errorf("edge to a collapsed supernode mismatch: got %+v", e)
+	}
+	if pool := partial.FindNode("pool"); len(pool.Children) != 3 {
+		t.Errorf("pool children mismatch: got %v", pool.Children)
+	}
+
+	full, err := ExpandSupernodes(&partial)
+	if err != nil {
+		t.Fatalf("ExpandSupernodes failed: %v", err)
+	}
+	if d := Diff(&sf, &full); len(d.Nodes) != 0 || len(d.Edges) != 0 {
+		t.Errorf("expansion should restore the scene: got %+v", d)
+	}
+
+	if _, err := ExpandSupernodes(&decoded, "lb"); err == nil {
+		t.Error("expected error expanding a regular node")
+	}
+	if _, err := ExpandSupernodes(&decoded, "nope"); err == nil {
+		t.Error("expected error expanding a missing node")
+	}
+}
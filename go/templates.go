@@ -0,0 +1,165 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+)
+
+// =============================================================================
+// TEXT TEMPLATES
+// =============================================================================
+
+// TemplateData is the value of "." in label and metadata templates
+type TemplateData struct {
+	ID       string
+	Name     string
+	Type     string
+	Status   NodeStatus
+	Metrics  map[string]interface{}
+	Metadata map[string]interface{}
+
+	// element is the node or edge that prop resolves paths against
+	element interface{}
+}
+
+// templateFuncs returns the functions available to templates of one element:
+//
+//	metric "name"      the numeric value of a metric; fails when missing
+//	hasMetric "name"   whether the metric is set
+//	meta "key"         a metadata value, or an empty string
+//	prop "path"        the value at a property path, e.g. "transform.position.y"
+//	round n x          x rounded to n decimal places
+//	bytes x            x bytes in binary units, e.g. "1.5 GiB"
+//	default d x        x, or d when x is empty or missing
+func templateFuncs(data TemplateData) template.FuncMap {
+	return template.FuncMap{
+		"metric": func(name string) (float64, error) {
+			v, ok := data.Metrics[name]
+			if !ok {
+				return 0, fmt.Errorf("metric %q is not set", name)
+			}
+			f, ok := toFloat64(v)
+			if !ok {
+				return 0, fmt.Errorf("metric %q is not numeric", name)
+			}
+			return f, nil
+		},
+		"hasMetric": func(name string) bool {
+			_, ok := data.Metrics[name]
+			return ok
+		},
+		"meta": func(key string) interface{} {
+			if v, ok := data.Metadata[key]; ok {
+				return v
+			}
+			return ""
+		},
+		"prop": func(path string) (interface{}, error) {
+			return GetProperty(data.element, path)
+		},
+		"round": func(places int, x float64) float64 {
+			scale := math.Pow(10, float64(places))
+			return math.Round(x*scale) / scale
+		},
+		"bytes":   formatBytes,
+		"default": templateDefault,
+	}
+}
+
+// formatBytes formats a byte count with binary unit prefixes
+func formatBytes(x float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := 0
+	for math.Abs(x) >= 1024 && i < len(units)-1 {
+		x /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", x, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", x, units[i])
+}
+
+// templateDefault returns value unless it is nil or an empty string
+func templateDefault(fallback, value interface{}) interface{} {
+	if value == nil || value == "" {
+		return fallback
+	}
+	return value
+}
+
+// ResolveTemplates returns a copy of the scene with label texts and string
+// metadata values of nodes and edges evaluated as Go templates against the
+// element's current metrics, e.g. `{{ metric "cpu_usage" | printf "%.0f%%" }}`.
+// Only text containing "{{" is treated as a template. Text whose template
+// fails keeps its source and the failures are returned joined; the input
+// scene is not modified.
+func ResolveTemplates(sf SceneFile) (SceneFile, error) {
+	out := sf
+	var errs []error
+	out.Scene.Nodes = make([]SceneNode, len(sf.Scene.Nodes))
+	for i, node := range sf.Scene.Nodes {
+		data := TemplateData{ID: node.ID, Name: node.Name, Type: node.Type, Status: node.Status, Metrics: node.Metrics, Metadata: node.Metadata, element: &node}
+		node.Label = resolveLabel(node.Label, "node "+node.ID, data, &errs)
+		node.Metadata = resolveMetadata(node.Metadata, "node "+node.ID, data, &errs)
+		out.Scene.Nodes[i] = node
+	}
+	out.Scene.Edges = make([]SceneEdge, len(sf.Scene.Edges))
+	for i, edge := range sf.Scene.Edges {
+		data := TemplateData{ID: edge.ID, Type: edge.Type, Metrics: edge.Metrics, Metadata: edge.Metadata, element: &edge}
+		edge.Label = resolveLabel(edge.Label, "edge "+edge.ID, data, &errs)
+		edge.Metadata = resolveMetadata(edge.Metadata, "edge "+edge.ID, data, &errs)
+		out.Scene.Edges[i] = edge
+	}
+	return out, errors.Join(errs...)
+}
+
+// resolveLabel returns the label with its text resolved, copying it only
+// when it is a template
+func resolveLabel(label *Label, where string, data TemplateData, errs *[]error) *Label {
+	if label == nil || !strings.Contains(label.Text, "{{") {
+		return label
+	}
+	resolved := *label
+	resolved.Text = executeTemplate(label.Text, where+" label", data, errs)
+	return &resolved
+}
+
+// resolveMetadata returns the metadata with template strings resolved,
+// copying the map only when it holds templates. Templates see the
+// unresolved metadata.
+func resolveMetadata(metadata map[string]interface{}, where string, data TemplateData, errs *[]error) map[string]interface{} {
+	var out map[string]interface{}
+	for k, v := range metadata {
+		text, ok := v.(string)
+		if !ok || !strings.Contains(text, "{{") {
+			continue
+		}
+		if out == nil {
+			out = copyMap(metadata)
+		}
+		out[k] = executeTemplate(text, where+" metadata."+k, data, errs)
+	}
+	if out == nil {
+		return metadata
+	}
+	return out
+}
+
+// executeTemplate evaluates text, returning it unchanged on failure
+func executeTemplate(text, where string, data TemplateData, errs *[]error) string {
+	tmpl, err := template.New(where).Funcs(templateFuncs(data)).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %w", where, err))
+		return text
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		*errs = append(*errs, fmt.Errorf("%s: %w", where, err))
+		return text
+	}
+	return b.String()
+}
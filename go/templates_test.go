@@ -0,0 +1,48 @@
+package starfleet
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestResolveTemplates tests metric-driven labels and metadata
+func TestResolveTemplates(t *testing.T) {
+	sf := newDiffScene()
+	a := &sf.Scene.Nodes[0]
+	a.Metrics = map[string]interface{}{"cpu_usage": 42.4, "memory": 1610612736}
+	a.Label = &Label{Text: `{{ .Name }}: {{ metric "cpu_usage" | printf "%.0f%%" }}`}
+	a.Metadata = map[string]interface{}{
+		"memory":  `{{ metric "memory" | bytes }}`,
+		"owner":   `{{ meta "team" | default "unowned" }}`,
+		"static":  "no template",
+		"rounded": `{{ if hasMetric "cpu_usage" }}{{ metric "cpu_usage" | round 0 }}{{ end }}`,
+		"height":  `{{ prop "transform.scale.y" }}`,
+	}
+	sf.Scene.Nodes[1].Label = &Label{Text: `{{ metric "missing" }}`}
+	sf.Scene.Edges[0].Metrics = map[string]interface{}{"rps": 1200}
+	sf.Scene.Edges[0].Label = &Label{Text: `{{ metric "rps" }} rps`}
+
+	out, err := ResolveTemplates(sf)
+	if err == nil || !strings.Contains(err.Error(), `node b label`) || !strings.Contains(err.Error(), `metric "missing" is not set`) {
+		t.Errorf("expected error for missing metric, got %v", err)
+	}
+	node := out.FindNode("a")
+	if node.Label.Text != "A: 42%" {
+		t.Errorf("label mismatch: got %q", node.Label.Text)
+	}
+	want := map[string]interface{}{"memory": "1.5 GiB", "owner": "unowned", "static": "no template", "rounded": "42", "height": "1"}
+	for k, v := range want {
+		if node.Metadata[k] != v {
+			t.Errorf("metadata %s mismatch: got %v, want %v", k, node.Metadata[k], v)
+		}
+	}
+	if got := out.FindNode("b").Label.Text; got != `{{ metric "missing" }}` {
+		t.Errorf("failed template should keep its source: got %q", got)
+	}
+	if got := out.FindEdge("a-b").Label.Text; got != "1200 rps" {
+		t.Errorf("edge label mismatch: got %q", got)
+	}
+	if !strings.Contains(sf.Scene.Nodes[0].Label.Text, "{{") || !strings.Contains(sf.Scene.Nodes[0].Metadata["memory"].(string), "{{") {
+		t.Error("input scene was modified")
+	}
+}
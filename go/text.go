@@ -0,0 +1,188 @@
+package starfleet
+
+import (
+	"strings"
+	"unicode"
+)
+
+// =============================================================================
+// TEXT MEASUREMENT
+// =============================================================================
+
+// Default text style values, expressed in scene units
+const (
+	DefaultFontSize   = 1.0
+	DefaultLineHeight = 1.2
+)
+
+// TextStyle represents the font parameters used to measure and build text
+type TextStyle struct {
+	FontSize   float64 `json:"fontSize,omitempty" validate:"omitempty,gt=0"`
+	LineHeight float64 `json:"lineHeight,omitempty" validate:"omitempty,gt=0"`
+	Monospace  bool    `json:"monospace,omitempty"`
+}
+
+// TextMetrics represents the measured extent of a block of text
+type TextMetrics struct {
+	Width   float64 `json:"width"`
+	Height  float64 `json:"height"`
+	Lines   int     `json:"lines"`
+	Ascent  float64 `json:"ascent"`
+	Descent float64 `json:"descent"`
+}
+
+// Approximate glyph proportions relative to the font size. These follow the
+// averages of common sans-serif UI fonts and are intentionally conservative so
+// that layout computed server-side never underestimates rendered text.
+const (
+	glyphAscent    = 0.8
+	glyphDescent   = 0.2
+	glyphCapHeight = 0.7
+	glyphMonospace = 0.6
+)
+
+// normalized returns the style with zero values replaced by defaults
+func (s TextStyle) normalized() TextStyle {
+	if s.FontSize <= 0 {
+		s.FontSize = DefaultFontSize
+	}
+	if s.LineHeight <= 0 {
+		s.LineHeight = DefaultLineHeight
+	}
+	return s
+}
+
+// glyphAdvance returns the advance width of a rune relative to the font size
+func glyphAdvance(r rune, monospace bool) float64 {
+	switch {
+	case r == '\t':
+		return 4 * glyphAdvance(' ', monospace)
+	case unicode.IsControl(r) || unicode.Is(unicode.Mn, r):
+		return 0
+	case isWideRune(r):
+		return 1.0
+	case monospace:
+		return glyphMonospace
+	case r == ' ':
+		return 0.28
+	case strings.ContainsRune("il.,;:!|'`", r):
+		return 0.28
+	case strings.ContainsRune("fjrtI()[]{}", r):
+		return 0.36
+	case strings.ContainsRune("mwMW@%", r):
+		return 0.88
+	case unicode.IsUpper(r):
+		return 0.68
+	case unicode.IsDigit(r):
+		return 0.56
+	default:
+		return 0.52
+	}
+}
+
+// isWideRune reports whether a rune is rendered at full (em) width, as is the
+// case for CJK ideographs, kana, hangul and fullwidth forms
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0xFF01 && r <= 0xFF60)
+}
+
+// MeasureText estimates the extent of text rendered with the given style.
+// Lines are separated by '\n'; width is the widest line.
+func MeasureText(text string, style TextStyle) TextMetrics {
+	style = style.normalized()
+	lines := strings.Split(text, "\n")
+
+	widest := 0.0
+	for _, line := range lines {
+		if w := lineAdvance(line, style.Monospace); w > widest {
+			widest = w
+		}
+	}
+
+	ascent := glyphAscent * style.FontSize
+	descent := glyphDescent * style.FontSize
+	height := ascent + descent + float64(len(lines)-1)*style.LineHeight*style.FontSize
+
+	return TextMetrics{
+		Width:   widest * style.FontSize,
+		Height:  height,
+		Lines:   len(lines),
+		Ascent:  ascent,
+		Descent: descent,
+	}
+}
+
+// lineAdvance returns the advance of a single line relative to the font size
+func lineAdvance(line string, monospace bool) float64 {
+	total := 0.0
+	for _, r := range line {
+		total += glyphAdvance(r, monospace)
+	}
+	return total
+}
+
+// TruncateText shortens text so that it fits within maxWidth, appending an
+// ellipsis when characters were removed. Only the first line is considered.
+func TruncateText(text string, style TextStyle, maxWidth float64) string {
+	style = style.normalized()
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	if lineAdvance(text, style.Monospace)*style.FontSize <= maxWidth {
+		return text
+	}
+
+	const ellipsis = "…"
+	budget := maxWidth/style.FontSize - lineAdvance(ellipsis, style.Monospace)
+	used := 0.0
+	for i, r := range text {
+		used += glyphAdvance(r, style.Monospace)
+		if used > budget {
+			return text[:i] + ellipsis
+		}
+	}
+	return text
+}
+
+// =============================================================================
+// TEXT MESHES
+// =============================================================================
+
+// TextMesh builds a coarse mesh for text consisting of one quad per visible
+// glyph. The quads match the measured glyph boxes, which is sufficient for
+// exporters that need occluders or placeholders rather than outlines. The
+// text is left-aligned with the first baseline at y=0.
+func TextMesh(text string, style TextStyle) Mesh {
+	style = style.normalized()
+	mesh := Mesh{}
+	for lineNo, line := range strings.Split(text, "\n") {
+		baseline := -float64(lineNo) * style.LineHeight * style.FontSize
+		x := 0.0
+		for _, r := range line {
+			adv := glyphAdvance(r, style.Monospace) * style.FontSize
+			if adv > 0 && !unicode.IsSpace(r) {
+				top := glyphCapHeight
+				if unicode.IsLower(r) {
+					top = glyphCapHeight * 0.75
+				}
+				mesh.appendQuad(x, baseline, x+adv*0.85, baseline+top*style.FontSize)
+			}
+			x += adv
+		}
+	}
+	return mesh
+}
+
+// NewTextGeometry creates custom geometry holding the mesh for text
+func NewTextGeometry(text string, style TextStyle) Geometry {
+	mesh := TextMesh(text, style)
+	return Geometry{
+		Type: GeometryCustom,
+		Parameters: map[string]interface{}{
+			"text":     text,
+			"fontSize": style.normalized().FontSize,
+		},
+		Mesh: &mesh,
+	}
+}
@@ -0,0 +1,85 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// TestMeasureText tests text extent estimation
+func TestMeasureText(t *testing.T) {
+	style := TextStyle{FontSize: 2}
+
+	short := MeasureText("db", style)
+	long := MeasureText("database-primary", style)
+	if short.Width <= 0 || long.Width <= short.Width {
+		t.Errorf("MeasureText width not monotonic: short %f, long %f", short.Width, long.Width)
+	}
+	if short.Lines != 1 {
+		t.Errorf("MeasureText lines mismatch: got %d, want 1", short.Lines)
+	}
+
+	multi := MeasureText("database\nprimary", style)
+	if multi.Lines != 2 {
+		t.Errorf("MeasureText lines mismatch: got %d, want 2", multi.Lines)
+	}
+	wantHeight := short.Height + DefaultLineHeight*style.FontSize
+	if math.Abs(multi.Height-wantHeight) > 1e-9 {
+		t.Errorf("MeasureText height mismatch: got %f, want %f", multi.Height, wantHeight)
+	}
+	if multi.Width != MeasureText("database", style).Width {
+		t.Errorf("MeasureText width should equal widest line")
+	}
+
+	mono := MeasureText("iiii", TextStyle{Monospace: true})
+	if math.Abs(mono.Width-MeasureText("MMMM", TextStyle{Monospace: true}).Width) > 1e-9 {
+		t.Errorf("Monospace widths should not depend on glyphs")
+	}
+}
+
+// TestTruncateText tests width-limited truncation
+func TestTruncateText(t *testing.T) {
+	style := TextStyle{}
+	text := "very-long-service-name"
+
+	if got := TruncateText(text, style, 1000); got != text {
+		t.Errorf("TruncateText should not modify fitting text: got %s", got)
+	}
+
+	maxWidth := 4.0
+	got := TruncateText(text, style, maxWidth)
+	if got == text {
+		t.Fatalf("TruncateText did not truncate")
+	}
+	if w := MeasureText(got, style).Width; w > maxWidth {
+		t.Errorf("TruncateText result too wide: got %f, want <= %f", w, maxWidth)
+	}
+}
+
+// TestTextMesh tests glyph quad generation
+func TestTextMesh(t *testing.T) {
+	mesh := TextMesh("a b", TextStyle{})
+	if mesh.TriangleCount() != 4 {
+		t.Errorf("TextMesh triangle count mismatch: got %d, want 4", mesh.TriangleCount())
+	}
+	if mesh.VertexCount() != 8 {
+		t.Errorf("TextMesh vertex count mismatch: got %d, want 8", mesh.VertexCount())
+	}
+
+	geometry := NewTextGeometry("api", TextStyle{FontSize: 3})
+	data, err := json.Marshal(geometry)
+	if err != nil {
+		t.Fatalf("Failed to marshal text Geometry: %v", err)
+	}
+
+	var result Geometry
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal text Geometry: %v", err)
+	}
+	if result.Type != GeometryCustom || result.Mesh == nil {
+		t.Fatalf("Text geometry mismatch: got %+v", result)
+	}
+	if result.Mesh.TriangleCount() != 6 {
+		t.Errorf("Text geometry triangle count mismatch: got %d, want 6", result.Mesh.TriangleCount())
+	}
+}
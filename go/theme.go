@@ -0,0 +1,200 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// =============================================================================
+// THEMES
+// =============================================================================
+
+// StyleProperty is the visual property a style rule drives
+type StyleProperty string
+
+const (
+	// StyleColor sets the node material color or the edge color
+	StyleColor StyleProperty = "color"
+	// StyleOpacity sets the node material opacity or the edge opacity
+	StyleOpacity StyleProperty = "opacity"
+	// StyleWidth sets the edge width; it does not apply to nodes
+	StyleWidth StyleProperty = "width"
+)
+
+// StyleRule maps a numeric metric onto a visual property. Metric values are
+// clamped to Domain and mapped linearly, or logarithmically with Log, onto
+// Output for widths and opacities, or onto the Colors gradient whose stops
+// are spread evenly over the domain.
+type StyleRule struct {
+	Metric   string        `json:"metric" validate:"required"`
+	Property StyleProperty `json:"property" validate:"required,oneof=color opacity width"`
+	Domain   [2]float64    `json:"domain"`
+	Output   [2]float64    `json:"output,omitempty"`
+	Colors   []Color       `json:"colors,omitempty"`
+	Log      bool          `json:"log,omitempty"`
+}
+
+// ThemeExtension is the scene extension key holding the scene's own theme
+const ThemeExtension = "theme"
+
+// Theme declares how live metrics restyle a scene. Later rules win when
+// several drive the same property.
+type Theme struct {
+	Nodes []StyleRule `json:"nodes,omitempty"`
+	Edges []StyleRule `json:"edges,omitempty"`
+}
+
+// Validate checks the theme rules
+func (t *Theme) Validate() []string {
+	var errs []string
+	check := func(kind string, rules []StyleRule) {
+		for i, r := range rules {
+			name := fmt.Sprintf("%s rule %d", kind, i)
+			if r.Metric == "" {
+				errs = append(errs, fmt.Sprintf("Theme %s has no metric", name))
+			}
+			switch r.Property {
+			case StyleColor:
+				if len(r.Colors) < 2 {
+					errs = append(errs, fmt.Sprintf("Theme %s needs at least two colors", name))
+				}
+			case StyleOpacity, StyleWidth:
+				if r.Property == StyleWidth && kind == "node" {
+					errs = append(errs, fmt.Sprintf("Theme %s sets width, which nodes do not have", name))
+				}
+			default:
+				errs = append(errs, fmt.Sprintf("Theme %s has unknown property: %s", name, r.Property))
+			}
+			if r.Domain[0] == r.Domain[1] || (r.Log && (r.Domain[0] <= 0 || r.Domain[1] <= 0)) {
+				errs = append(errs, fmt.Sprintf("Theme %s has an invalid domain", name))
+			}
+		}
+	}
+	check("node", t.Nodes)
+	check("edge", t.Edges)
+	return errs
+}
+
+// Theme returns the theme stored with the scene, or nil
+func (sf *SceneFile) Theme() (*Theme, error) {
+	v, ok := sf.Extensions[ThemeExtension]
+	if !ok {
+		return nil, nil
+	}
+	var t Theme
+	if err := roundTripJSON(v, &t); err != nil {
+		return nil, fmt.Errorf("theme: %w", err)
+	}
+	return &t, nil
+}
+
+// SetTheme stores a theme with the scene; nil removes it
+func (sf *SceneFile) SetTheme(t *Theme) error {
+	if t == nil {
+		delete(sf.Extensions, ThemeExtension)
+		return nil
+	}
+	if errs := t.Validate(); len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	sf.Extensions = withExtension(sf.Extensions, ThemeExtension, t)
+	return nil
+}
+
+// ValidateTheme checks the theme stored with the scene
+func ValidateTheme(sf *SceneFile) []string {
+	t, err := sf.Theme()
+	if err != nil {
+		return []string{fmt.Sprintf("Scene has an invalid theme: %v", err)}
+	}
+	if t == nil {
+		return nil
+	}
+	return t.Validate()
+}
+
+// Apply restyles every node and edge whose metrics a rule reads. Elements
+// without a numeric value for a rule's metric keep their style. Node
+// materials are replaced rather than modified, since they may be shared.
+func (t *Theme) Apply(sf *SceneFile) {
+	for i := range sf.Scene.Nodes {
+		n := &sf.Scene.Nodes[i]
+		for _, r := range t.Nodes {
+			pos, ok := r.position(n.Metrics)
+			if !ok || r.Property == StyleWidth {
+				continue
+			}
+			m := Material{}
+			if resolved := sf.ResolveMaterial(n); resolved != nil {
+				m = *resolved
+			}
+			switch r.Property {
+			case StyleColor:
+				c, ok := r.color(pos)
+				if !ok {
+					continue
+				}
+				m.Color = &c
+			case StyleOpacity:
+				m.Opacity = r.output(pos)
+				m.Transparent = m.Opacity < 1
+			}
+			n.Material, n.MaterialRef = &m, ""
+		}
+	}
+	for i := range sf.Scene.Edges {
+		e := &sf.Scene.Edges[i]
+		for _, r := range t.Edges {
+			pos, ok := r.position(e.Metrics)
+			if !ok {
+				continue
+			}
+			switch r.Property {
+			case StyleColor:
+				if c, ok := r.color(pos); ok {
+					e.Color = &c
+				}
+			case StyleOpacity:
+				e.Opacity = r.output(pos)
+			case StyleWidth:
+				e.Width = r.output(pos)
+			}
+		}
+	}
+}
+
+// position returns where the rule's metric falls in its domain, from 0 to 1
+func (r StyleRule) position(metrics map[string]interface{}) (float64, bool) {
+	v, ok := toFloat64(metrics[r.Metric])
+	if !ok || math.IsNaN(v) || r.Domain[0] == r.Domain[1] {
+		return 0, false
+	}
+	lo, hi := r.Domain[0], r.Domain[1]
+	if r.Log {
+		if lo <= 0 || hi <= 0 {
+			return 0, false
+		}
+		lo, hi, v = math.Log(lo), math.Log(hi), math.Log(math.Max(v, math.SmallestNonzeroFloat64))
+	}
+	return math.Max(0, math.Min(1, (v-lo)/(hi-lo))), true
+}
+
+// output maps a domain position onto the rule's output range
+func (r StyleRule) output(pos float64) float64 {
+	return r.Output[0] + (r.Output[1]-r.Output[0])*pos
+}
+
+// color maps a domain position onto the rule's gradient
+func (r StyleRule) color(pos float64) (Color, bool) {
+	switch len(r.Colors) {
+	case 0:
+		return Color{}, false
+	case 1:
+		return r.Colors[0], true
+	}
+	scaled := pos * float64(len(r.Colors)-1)
+	i := min(int(scaled), len(r.Colors)-2)
+	return lerpColor(r.Colors[i], r.Colors[i+1], scaled-float64(i)), true
+}
@@ -0,0 +1,81 @@
+package starfleet
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestTheme_Apply tests mapping metrics onto node and edge styles
+func TestTheme_Apply(t *testing.T) {
+	green, red := NewColor(0, 1, 0), NewColor(1, 0, 0)
+	theme := Theme{
+		Nodes: []StyleRule{{Metric: "load", Property: StyleOpacity, Domain: [2]float64{0, 1}, Output: [2]float64{0.2, 1}}},
+		Edges: []StyleRule{
+			{Metric: "rps", Property: StyleWidth, Domain: [2]float64{1, 1000}, Output: [2]float64{1, 4}, Log: true},
+			{Metric: "latency", Property: StyleColor, Domain: [2]float64{0, 200}, Colors: []Color{green, red}},
+		},
+	}
+	if errs := theme.Validate(); len(errs) != 0 {
+		t.Fatalf("unexpected validation errors: %v", errs)
+	}
+
+	sf := NewSceneFile("Theme")
+	sf.Materials = map[string]Material{"steel": {Metalness: 0.5}}
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "A", Transform: NewTransform(), MaterialRef: "steel", Metrics: map[string]interface{}{"load": 0.5}})
+	sf.AddNode(SceneNode{ID: "b", Type: "server", Name: "B", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "ab", Source: "a", Target: "b", Metrics: map[string]interface{}{"rps": 100, "latency": 150.0}})
+	theme.Apply(&sf)
+
+	a := sf.FindNode("a")
+	if a.Material == nil || math.Abs(a.Material.Opacity-0.6) > 1e-9 || !a.Material.Transparent || a.Material.Metalness != 0.5 || a.MaterialRef != "" {
+		t.Errorf("node style mismatch: got %+v", a.Material)
+	}
+	if sf.Materials["steel"].Opacity != 0 {
+		t.Error("shared material was modified")
+	}
+	if b := sf.FindNode("b"); b.Material != nil {
+		t.Errorf("node without metrics restyled: got %+v", b.Material)
+	}
+	e := sf.FindEdge("ab")
+	if math.Abs(e.Width-3) > 1e-9 {
+		t.Errorf("edge width mismatch: got %v, want 3", e.Width)
+	}
+	if want := NewColor(0.75, 0.25, 0); e.Color == nil || *e.Color != want {
+		t.Errorf("edge color mismatch: got %+v, want %+v", e.Color, want)
+	}
+
+	bad := Theme{Nodes: []StyleRule{{Metric: "x", Property: StyleWidth, Domain: [2]float64{1, 1}}}}
+	if errs := bad.Validate(); len(errs) != 2 {
+		t.Errorf("validation errors mismatch: got %v", errs)
+	}
+}
+
+// TestMetricsBinder_Edges tests binding edge metrics and restyling edges
+func TestMetricsBinder_Edges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var query MetricsQuery
+	source := MetricsSourceFunc(func(ctx context.Context, q MetricsQuery) ([]MetricsResult, error) {
+		query = q
+		return []MetricsResult{
+			{EdgeID: "a-b", MetricName: "rps", DataPoints: []MetricsDataPoint{{Timestamp: now, Value: 500.0}}},
+			{EdgeID: "gone", MetricName: "rps", DataPoints: []MetricsDataPoint{{Timestamp: now, Value: 1.0}}},
+		}, nil
+	})
+	sf := newDiffScene()
+	b := NewMetricsBinder(source, "rps")
+	b.Theme = &Theme{Edges: []StyleRule{{Metric: "rps", Property: StyleWidth, Domain: [2]float64{0, 1000}, Output: [2]float64{1, 5}}}}
+	b.now = func() time.Time { return now }
+
+	if _, err := b.Bind(context.Background(), &sf); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if !reflect.DeepEqual(query.EdgeIDs, []string{"a-b"}) || len(query.NodeIDs) != 3 {
+		t.Errorf("query mismatch: got %+v", query)
+	}
+	if e := sf.FindEdge("a-b"); e.Metrics["rps"] != 500.0 || e.Width != 3 {
+		t.Errorf("edge binding mismatch: got %+v", e)
+	}
+}
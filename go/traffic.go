@@ -0,0 +1,156 @@
+package starfleet
+
+// =============================================================================
+// TRAFFIC MATRICES
+// =============================================================================
+
+// TrafficPlaceholderExtension is the extension key marking nodes created by
+// ApplyTrafficMatrix for endpoints that are not in the scene
+const TrafficPlaceholderExtension = "trafficPlaceholder"
+
+// TrafficWeight selects the flow measure that becomes the edge weight
+type TrafficWeight string
+
+const (
+	// TrafficByBytes weights edges by bytes transferred; it is the default
+	TrafficByBytes    TrafficWeight = "bytes"
+	TrafficByRequests TrafficWeight = "requests"
+)
+
+// TrafficFlow is one cell of a traffic matrix, as exported from flow logs or
+// eBPF probes. Source and Destination are node IDs, or raw endpoints such as
+// addresses when TrafficOptions.Resolve maps them to node IDs.
+type TrafficFlow struct {
+	Source      string  `json:"source" validate:"required"`
+	Destination string  `json:"destination" validate:"required"`
+	Bytes       float64 `json:"bytes,omitempty" validate:"omitempty,min=0"`
+	Requests    float64 `json:"requests,omitempty" validate:"omitempty,min=0"`
+}
+
+// TrafficOptions controls how ApplyTrafficMatrix maps flows onto the scene
+type TrafficOptions struct {
+	// Weight is the measure copied to edge weights; empty means bytes
+	Weight TrafficWeight `json:"weight,omitempty"`
+	// EdgeType is the type of synthesized edges; empty means "traffic"
+	EdgeType string `json:"edgeType,omitempty"`
+	// PlaceholderType is the type of placeholder nodes; empty means "external"
+	PlaceholderType string `json:"placeholderType,omitempty"`
+	// Resolve maps a flow endpoint to a node ID. An empty result drops the
+	// flow. When nil, endpoints are used as node IDs.
+	Resolve func(endpoint string) string `json:"-"`
+}
+
+// TrafficReport lists the scene elements ApplyTrafficMatrix touched
+type TrafficReport struct {
+	AddedEdges   []string `json:"addedEdges"`
+	UpdatedEdges []string `json:"updatedEdges"`
+	Placeholders []string `json:"placeholders"`
+	// Skipped counts flows dropped for empty endpoints or self-traffic
+	Skipped int `json:"skipped"`
+}
+
+// ApplyTrafficMatrix folds a traffic matrix into the scene in place. Flows
+// between the same pair of nodes are summed, and the totals replace the
+// bytes and requests metrics and the weight of the first edge running from
+// source to destination. Pairs without such an edge get a new directed edge,
+// and endpoints missing from the scene get placeholder nodes.
+func ApplyTrafficMatrix(sf *SceneFile, flows []TrafficFlow, opts TrafficOptions) TrafficReport {
+	report := TrafficReport{AddedEdges: []string{}, UpdatedEdges: []string{}, Placeholders: []string{}}
+	resolve := opts.Resolve
+	if resolve == nil {
+		resolve = func(endpoint string) string { return endpoint }
+	}
+
+	type pair struct{ source, target string }
+	totals := make(map[pair]*TrafficFlow)
+	var order []pair
+	for _, f := range flows {
+		p := pair{resolve(f.Source), resolve(f.Destination)}
+		if p.source == "" || p.target == "" || p.source == p.target {
+			report.Skipped++
+			continue
+		}
+		t, ok := totals[p]
+		if !ok {
+			t = &TrafficFlow{Source: p.source, Destination: p.target}
+			totals[p] = t
+			order = append(order, p)
+		}
+		t.Bytes += f.Bytes
+		t.Requests += f.Requests
+	}
+
+	nodes := make(map[string]bool, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		nodes[sf.Scene.Nodes[i].ID] = true
+	}
+	edges := make(map[string]bool, len(sf.Scene.Edges))
+	existing := make(map[pair]int, len(sf.Scene.Edges))
+	for i, e := range sf.Scene.Edges {
+		edges[e.ID] = true
+		p := pair{e.Source, e.Target}
+		if _, ok := existing[p]; !ok && e.TargetScene == "" {
+			existing[p] = i
+		}
+	}
+
+	placeholderType := opts.PlaceholderType
+	if placeholderType == "" {
+		placeholderType = "external"
+	}
+	edgeType := opts.EdgeType
+	if edgeType == "" {
+		edgeType = "traffic"
+	}
+
+	for _, p := range order {
+		for _, id := range []string{p.source, p.target} {
+			if nodes[id] {
+				continue
+			}
+			sf.AddNode(SceneNode{
+				ID:         id,
+				Type:       placeholderType,
+				Name:       id,
+				Transform:  NewTransform(),
+				Status:     NodeStatusUnknown,
+				Extensions: map[string]interface{}{TrafficPlaceholderExtension: true},
+			})
+			nodes[id] = true
+			report.Placeholders = append(report.Placeholders, id)
+		}
+
+		t := totals[p]
+		if i, ok := existing[p]; ok {
+			e := &sf.Scene.Edges[i]
+			t.apply(e, opts.Weight)
+			report.UpdatedEdges = append(report.UpdatedEdges, e.ID)
+			continue
+		}
+		id := p.source + "-" + p.target
+		if edges[id] {
+			id = uniqueID(edges, id, "edge")
+		}
+		e := SceneEdge{ID: id, Source: p.source, Target: p.target, Type: edgeType, Direction: EdgeDirected}
+		t.apply(&e, opts.Weight)
+		sf.AddEdge(e)
+		edges[id] = true
+		existing[p] = len(sf.Scene.Edges) - 1
+		report.AddedEdges = append(report.AddedEdges, id)
+	}
+	return report
+}
+
+// apply writes the flow totals onto an edge
+func (f *TrafficFlow) apply(e *SceneEdge, weight TrafficWeight) {
+	if e.Metrics == nil {
+		e.Metrics = make(map[string]interface{}, 2)
+	}
+	e.Metrics["bytes"] = f.Bytes
+	e.Metrics["requests"] = f.Requests
+	if weight == TrafficByRequests {
+		e.Weight = f.Requests
+	} else {
+		e.Weight = f.Bytes
+	}
+}
@@ -0,0 +1,53 @@
+package starfleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestApplyTrafficMatrix tests synthesizing weighted edges from flows
+func TestApplyTrafficMatrix(t *testing.T) {
+	sf := newDiffScene()
+	addresses := map[string]string{"10.0.0.1": "a", "10.0.0.2": "b", "10.0.0.3": "c"}
+	flows := []TrafficFlow{
+		{Source: "10.0.0.1", Destination: "10.0.0.2", Bytes: 100, Requests: 2},
+		{Source: "10.0.0.1", Destination: "10.0.0.2", Bytes: 50, Requests: 1},
+		{Source: "10.0.0.3", Destination: "8.8.8.8", Bytes: 10, Requests: 5},
+		{Source: "10.0.0.3", Destination: "10.0.0.3", Bytes: 1},
+		{Source: "", Destination: "10.0.0.1", Bytes: 1},
+	}
+	report := ApplyTrafficMatrix(&sf, flows, TrafficOptions{
+		Weight: TrafficByRequests,
+		Resolve: func(endpoint string) string {
+			if id, ok := addresses[endpoint]; ok {
+				return id
+			}
+			return endpoint
+		},
+	})
+
+	if !reflect.DeepEqual(report.UpdatedEdges, []string{"a-b"}) || !reflect.DeepEqual(report.AddedEdges, []string{"c-8.8.8.8"}) {
+		t.Errorf("edge report mismatch: got %+v", report)
+	}
+	if !reflect.DeepEqual(report.Placeholders, []string{"8.8.8.8"}) || report.Skipped != 2 {
+		t.Errorf("placeholder report mismatch: got %+v", report)
+	}
+	if e := sf.FindEdge("a-b"); e.Weight != 3 || e.Metrics["bytes"] != 150.0 {
+		t.Errorf("updated edge mismatch: got %+v", e)
+	}
+	added := sf.FindEdge("c-8.8.8.8")
+	if added == nil || added.Type != "traffic" || added.Direction != EdgeDirected || added.Weight != 5 {
+		t.Errorf("added edge mismatch: got %+v", added)
+	}
+	if n := sf.FindNode("8.8.8.8"); n == nil || n.Type != "external" || n.Extensions[TrafficPlaceholderExtension] != true {
+		t.Errorf("placeholder mismatch: got %+v", n)
+	}
+	if result := ValidateScene(&sf); !result.Valid {
+		t.Errorf("scene invalid after import: %+v", result.Errors)
+	}
+
+	again := ApplyTrafficMatrix(&sf, []TrafficFlow{{Source: "c", Destination: "8.8.8.8", Bytes: 10}}, TrafficOptions{})
+	if len(again.AddedEdges) != 0 || len(again.Placeholders) != 0 || sf.FindEdge("c-8.8.8.8").Weight != 10 {
+		t.Errorf("reapplied report mismatch: got %+v", again)
+	}
+}
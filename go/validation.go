@@ -0,0 +1,149 @@
+package starfleet
+
+import (
+	"context"
+	"fmt"
+)
+
+// =============================================================================
+// SCENE VALIDATION
+// =============================================================================
+
+// ValidateScene checks the structural integrity of a scene file: required
+// fields, unique IDs, edge endpoints, edge semantics, and the metadata
+// schemas and saved queries the scene declares. It mirrors validateScene in
+// the TypeScript SDK. Targets of cross-scene edges are not checked here;
+// see ValidateExternalEdges.
+func ValidateScene(sf *SceneFile) ValidationResult {
+	result, _ := ValidateSceneContext(context.Background(), sf)
+	return result
+}
+
+// ValidateSceneContext is ValidateScene for callers that may abandon the
+// validation of a huge scene. It returns the context's error once it is
+// done.
+func ValidateSceneContext(ctx context.Context, sf *SceneFile) (ValidationResult, error) {
+	c := canceler{ctx: ctx}
+	if err := ctx.Err(); err != nil {
+		return ValidationResult{}, err
+	}
+	errs := []string{}
+	warnings := []string{}
+
+	if sf.Version == "" {
+		errs = append(errs, "Scene file must have a version")
+	}
+	if sf.Metadata.Name == "" {
+		errs = append(errs, "Scene file must have a name")
+	}
+	if sf.Scene.Nodes == nil {
+		errs = append(errs, "Scene file must have nodes")
+	}
+
+	nodeIDs := make(map[string]bool, len(sf.Scene.Nodes))
+	for i := range sf.Scene.Nodes {
+		if err := c.check(); err != nil {
+			return ValidationResult{}, err
+		}
+		node := &sf.Scene.Nodes[i]
+		switch {
+		case node.ID == "":
+			errs = append(errs, "All nodes must have an id")
+		case nodeIDs[node.ID]:
+			errs = append(errs, fmt.Sprintf("Duplicate node id: %s", node.ID))
+		default:
+			nodeIDs[node.ID] = true
+		}
+
+		if node.Name == "" {
+			warnings = append(warnings, fmt.Sprintf("Node %s has no name", node.ID))
+		}
+	}
+
+	edgeIDs := make(map[string]bool, len(sf.Scene.Edges))
+	for i := range sf.Scene.Edges {
+		if err := c.check(); err != nil {
+			return ValidationResult{}, err
+		}
+		edge := &sf.Scene.Edges[i]
+		switch {
+		case edge.ID == "":
+			errs = append(errs, "All edges must have an id")
+		case edgeIDs[edge.ID]:
+			errs = append(errs, fmt.Sprintf("Duplicate edge id: %s", edge.ID))
+		default:
+			edgeIDs[edge.ID] = true
+		}
+
+		if !nodeIDs[edge.Source] {
+			errs = append(errs, fmt.Sprintf("Edge %s references non-existent source node: %s", edge.ID, edge.Source))
+		}
+		if !nodeIDs[edge.Target] && !edge.IsExternal() {
+			errs = append(errs, fmt.Sprintf("Edge %s references non-existent target node: %s", edge.ID, edge.Target))
+		}
+	}
+
+	validators := []func(*SceneFile) []string{
+		ValidateEdgeSemantics,
+		ValidateAttachments,
+		ValidateResources,
+		ValidatePhysics,
+		ValidateSLOs,
+		ValidatePanels,
+		ValidateMetadataSchemas,
+		ValidateSavedQueries,
+		ValidateRollups,
+		ValidateTheme,
+		ValidateVisibilityRules,
+		ValidateRequirements,
+		ValidateFiniteNumbers,
+	}
+	// The checks only read the scene, so they run side by side and their
+	// errors are gathered in a fixed order
+	found := make([][]string, len(validators))
+	if err := parallelFor(ctx, len(validators), 1, func(i int) {
+		found[i] = validators[i](sf)
+	}); err != nil {
+		return ValidationResult{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return ValidationResult{}, err
+	}
+	for _, e := range found {
+		errs = append(errs, e...)
+	}
+	warnings = append(warnings, PhysicsWarnings(sf)...)
+
+	return ValidationResult{
+		Valid:    len(errs) == 0,
+		Errors:   errs,
+		Warnings: warnings,
+	}, nil
+}
+
+// CheckScene runs the checks the scene service applies before storing a
+// scene: its format must be readable by this SDK without a downgrade and
+// it must pass ValidateScene. An incompatible format is reported as an
+// error alongside the validation results.
+func CheckScene(sf *SceneFile) ValidationResult {
+	result, _ := CheckSceneContext(context.Background(), sf)
+	return result
+}
+
+// CheckSceneContext is CheckScene with the cancellation of
+// ValidateSceneContext
+func CheckSceneContext(ctx context.Context, sf *SceneFile) (ValidationResult, error) {
+	result, err := ValidateSceneContext(ctx, sf)
+	if err != nil {
+		return result, err
+	}
+	compat, err := CheckSceneCompatibility(sf, LocalCompatibility().Accepts)
+	switch {
+	case err != nil:
+		result.Errors = append([]string{fmt.Sprintf("Unsupported scene version: %v", err)}, result.Errors...)
+	case !compat.Compatible():
+		result.Errors = append([]string{fmt.Sprintf("Unsupported scene version: %s", compat.Reason)}, result.Errors...)
+	}
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
@@ -0,0 +1,58 @@
+package starfleet
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateScene tests structural scene validation
+func TestValidateScene(t *testing.T) {
+	sf := NewSceneFile("Valid")
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Name: "A", Transform: NewTransform()})
+	sf.AddNode(SceneNode{ID: "b", Type: "server", Name: "B", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "e1", Source: "a", Target: "b"})
+
+	result := ValidateScene(&sf)
+	if !result.Valid || len(result.Errors) != 0 {
+		t.Fatalf("Valid scene reported errors: %v", result.Errors)
+	}
+
+	sf.AddNode(SceneNode{ID: "a", Type: "server", Transform: NewTransform()})
+	sf.AddEdge(SceneEdge{ID: "e1", Source: "a", Target: "missing"})
+	sf.Metadata.Name = ""
+
+	result = ValidateScene(&sf)
+	if result.Valid {
+		t.Fatalf("Invalid scene reported valid")
+	}
+	for _, want := range []string{"must have a name", "Duplicate node id: a", "Duplicate edge id: e1", "non-existent target node: missing"} {
+		if !containsMessage(result.Errors, want) {
+			t.Errorf("ValidateScene missing error %q: %v", want, result.Errors)
+		}
+	}
+	if !containsMessage(result.Warnings, "has no name") {
+		t.Errorf("ValidateScene missing name warning: %v", result.Warnings)
+	}
+}
+
+// containsMessage reports whether any message contains the substring
+func containsMessage(messages []string, substr string) bool {
+	for _, m := range messages {
+		if strings.Contains(m, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestCheckScene tests that incompatible formats fail validation
+func TestCheckScene(t *testing.T) {
+	sf := newDiffScene()
+	if result := CheckScene(&sf); !result.Valid {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+	sf.Version = "9.0.0"
+	if result := CheckScene(&sf); result.Valid || len(result.Errors) != 1 {
+		t.Errorf("expected version error, got %+v", result)
+	}
+}
@@ -0,0 +1,79 @@
+package starfleet
+
+import "strconv"
+
+// toFloat64 converts a numeric value decoded from JSON or set in code to a
+// float64. It reports false for non-numeric values.
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// toStringSlice converts a []string or a JSON-decoded []interface{} of strings
+// to a []string, skipping non-string elements
+func toStringSlice(v interface{}) []string {
+	switch s := v.(type) {
+	case []string:
+		return s
+	case []interface{}:
+		out := make([]string, 0, len(s))
+		for _, e := range s {
+			if str, ok := e.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// toString converts a string or numeric value to its string form, printing
+// integral numbers without a fractional part. It reports false for other
+// values.
+func toString(v interface{}) (string, bool) {
+	if s, ok := v.(string); ok {
+		return s, s != ""
+	}
+	if f, ok := toFloat64(v); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// naturalLess orders two strings by numeric value when both parse as numbers
+// and lexically otherwise, so rows "2" and "10" sort in physical order
+func naturalLess(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil && fa != fb {
+		return fa < fb
+	}
+	return a < b
+}
@@ -0,0 +1,65 @@
+package starfleet
+
+import "math"
+
+// =============================================================================
+// VECTOR MATH
+// =============================================================================
+
+// Vector operations follow IEEE 754: a NaN or infinite component spreads
+// to the results. Callers working with computed positions can check
+// IsFinite; Normalize alone guards against them.
+
+// Add returns the component-wise sum of two vectors
+func (v Vector3) Add(o Vector3) Vector3 {
+	return Vector3{X: v.X + o.X, Y: v.Y + o.Y, Z: v.Z + o.Z}
+}
+
+// Sub returns the component-wise difference of two vectors
+func (v Vector3) Sub(o Vector3) Vector3 {
+	return Vector3{X: v.X - o.X, Y: v.Y - o.Y, Z: v.Z - o.Z}
+}
+
+// Scale returns the vector multiplied by a scalar
+func (v Vector3) Scale(s float64) Vector3 {
+	return Vector3{X: v.X * s, Y: v.Y * s, Z: v.Z * s}
+}
+
+// Dot returns the dot product of two vectors
+func (v Vector3) Dot(o Vector3) float64 {
+	return v.X*o.X + v.Y*o.Y + v.Z*o.Z
+}
+
+// Cross returns the cross product of two vectors
+func (v Vector3) Cross(o Vector3) Vector3 {
+	return Vector3{
+		X: v.Y*o.Z - v.Z*o.Y,
+		Y: v.Z*o.X - v.X*o.Z,
+		Z: v.X*o.Y - v.Y*o.X,
+	}
+}
+
+// Length returns the Euclidean length of the vector
+func (v Vector3) Length() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Normalize returns the unit vector in the same direction, or the zero
+// vector when the length is zero or not finite
+func (v Vector3) Normalize() Vector3 {
+	l := v.Length()
+	if l == 0 || !isFinite(l) {
+		return Vector3{}
+	}
+	return v.Scale(1 / l)
+}
+
+// IsFinite reports whether no component is NaN or infinite
+func (v Vector3) IsFinite() bool {
+	return isFinite(v.X) && isFinite(v.Y) && isFinite(v.Z)
+}
+
+// Lerp linearly interpolates between two vectors
+func (v Vector3) Lerp(o Vector3, t float64) Vector3 {
+	return v.Add(o.Sub(v).Scale(t))
+}
@@ -0,0 +1,34 @@
+package starfleet
+
+import (
+	"math"
+	"testing"
+)
+
+// TestVector3_Math tests basic vector arithmetic
+func TestVector3_Math(t *testing.T) {
+	a := Vector3{X: 1, Y: 2, Z: 3}
+	b := Vector3{X: 4, Y: 5, Z: 6}
+
+	if got := a.Add(b); got != (Vector3{X: 5, Y: 7, Z: 9}) {
+		t.Errorf("Add mismatch: got %+v", got)
+	}
+	if got := b.Sub(a); got != (Vector3{X: 3, Y: 3, Z: 3}) {
+		t.Errorf("Sub mismatch: got %+v", got)
+	}
+	if got := a.Dot(b); got != 32 {
+		t.Errorf("Dot mismatch: got %f, want 32", got)
+	}
+	if got := (Vector3{X: 1}).Cross(Vector3{Y: 1}); got != (Vector3{Z: 1}) {
+		t.Errorf("Cross mismatch: got %+v", got)
+	}
+	if got := (Vector3{X: 3, Y: 4}).Normalize().Length(); math.Abs(got-1) > 1e-12 {
+		t.Errorf("Normalize length mismatch: got %f, want 1", got)
+	}
+	if got := (Vector3{}).Normalize(); got != (Vector3{}) {
+		t.Errorf("Normalize of zero vector should be zero: got %+v", got)
+	}
+	if got := a.Lerp(b, 0.5); got != (Vector3{X: 2.5, Y: 3.5, Z: 4.5}) {
+		t.Errorf("Lerp mismatch: got %+v", got)
+	}
+}
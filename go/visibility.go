@@ -0,0 +1,758 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// VISIBILITY RULES
+// =============================================================================
+
+// VisibilityExtension is the scene extension key holding visibility rules
+const VisibilityExtension = "visibility"
+
+// ErrInvalidVisibilityExpr is returned when a visibility expression cannot
+// be parsed
+var ErrInvalidVisibilityExpr = errors.New("invalid visibility expression")
+
+// VisibilityRule hides the elements it selects while its expression holds,
+// as in a rule with selector "kind=node" hiding `status == "healthy" &&
+// filter("incidents")`. Viewers and exporters evaluate the same rules with
+// EvaluateVisibility, so a scene looks the same in both.
+type VisibilityRule struct {
+	Name string `json:"name" validate:"required"`
+	// Selector limits the rule to elements a ParseSelector expression
+	// matches; empty applies it to every node and edge
+	Selector string `json:"selector,omitempty"`
+	// Hide is a visibility expression; see ParseVisibilityExpr
+	Hide        string `json:"hide" validate:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// VisibilityContext is the viewer state rules are evaluated in
+type VisibilityContext struct {
+	// Camera is the viewpoint distance is measured from; without one,
+	// distance is null
+	Camera *Camera `json:"camera,omitempty"`
+	// Filters names the filters active in the viewer
+	Filters []string `json:"filters,omitempty"`
+}
+
+// HiddenElements lists the nodes and edges visibility rules hide, in scene
+// order
+type HiddenElements struct {
+	Nodes []string `json:"nodes"`
+	Edges []string `json:"edges"`
+}
+
+// VisibilityRules returns the visibility rules stored with the scene
+func (sf *SceneFile) VisibilityRules() ([]VisibilityRule, error) {
+	v, ok := sf.Extensions[VisibilityExtension]
+	if !ok {
+		return nil, nil
+	}
+	var rules []VisibilityRule
+	if err := roundTripJSON(v, &rules); err != nil {
+		return nil, fmt.Errorf("visibility rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SetVisibilityRules stores visibility rules with the scene, replacing any
+// it had; no rules removes them. Every selector and expression must parse.
+func (sf *SceneFile) SetVisibilityRules(rules []VisibilityRule) error {
+	if len(rules) == 0 {
+		delete(sf.Extensions, VisibilityExtension)
+		return nil
+	}
+	if _, err := compileVisibilityRules(rules); err != nil {
+		return err
+	}
+	sf.Extensions = withExtension(sf.Extensions, VisibilityExtension, rules)
+	return nil
+}
+
+// ValidateVisibilityRules checks that visibility rules have unique names
+// and selectors and expressions that parse
+func ValidateVisibilityRules(sf *SceneFile) []string {
+	rules, err := sf.VisibilityRules()
+	if err != nil {
+		return []string{fmt.Sprintf("Scene has invalid visibility rules: %v", err)}
+	}
+	var errs []string
+	names := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		switch {
+		case r.Name == "":
+			errs = append(errs, "All visibility rules must have a name")
+		case names[r.Name]:
+			errs = append(errs, fmt.Sprintf("Duplicate visibility rule: %s", r.Name))
+		}
+		names[r.Name] = true
+		if _, err := compileVisibilityRule(r); err != nil {
+			errs = append(errs, fmt.Sprintf("Visibility rule %s is invalid: %v", r.Name, err))
+		}
+	}
+	return errs
+}
+
+// EvaluateVisibility returns the elements the scene's visibility rules hide
+// in the given context. A node is hidden when any rule selecting it
+// evaluates true, and so are its descendants; an edge is hidden the same
+// way or when either of its endpoints is.
+func EvaluateVisibility(sf *SceneFile, vc VisibilityContext) (HiddenElements, error) {
+	rules, err := sf.VisibilityRules()
+	if err != nil {
+		return HiddenElements{}, err
+	}
+	compiled, err := compileVisibilityRules(rules)
+	if err != nil {
+		return HiddenElements{}, err
+	}
+
+	env := visEnv{filters: vc.Filters}
+	hidden := make(map[string]bool)
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		env.node, env.edge = node, nil
+		env.distance = nil
+		if vc.Camera != nil {
+			env.distance = node.Transform.Position.Sub(vc.Camera.Position).Length()
+		}
+		for _, r := range compiled {
+			if r.selector.MatchNode(node) && r.hide.root.eval(&env) == true {
+				hidden[node.ID] = true
+				break
+			}
+		}
+	}
+	var out HiddenElements
+	for i := range sf.Scene.Nodes {
+		node := &sf.Scene.Nodes[i]
+		// Walk up to the first hidden ancestor, stopping at parent cycles
+		seen := map[string]bool{}
+		for n := node; n != nil && !seen[n.ID]; n = sf.FindNode(n.Parent) {
+			seen[n.ID] = true
+			if hidden[n.ID] {
+				out.Nodes = append(out.Nodes, node.ID)
+				break
+			}
+		}
+	}
+	hidden = make(map[string]bool, len(out.Nodes))
+	for _, id := range out.Nodes {
+		hidden[id] = true
+	}
+
+	for i := range sf.Scene.Edges {
+		edge := &sf.Scene.Edges[i]
+		if hidden[edge.Source] || hidden[edge.Target] {
+			out.Edges = append(out.Edges, edge.ID)
+			continue
+		}
+		env.node, env.edge = nil, edge
+		env.distance = nil
+		source, target := sf.FindNode(edge.Source), sf.FindNode(edge.Target)
+		if vc.Camera != nil && source != nil && target != nil {
+			mid := source.Transform.Position.Lerp(target.Transform.Position, 0.5)
+			env.distance = mid.Sub(vc.Camera.Position).Length()
+		}
+		for _, r := range compiled {
+			if r.selector.MatchEdge(edge) && r.hide.root.eval(&env) == true {
+				out.Edges = append(out.Edges, edge.ID)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// ApplyVisibility returns a copy of the scene without the elements
+// EvaluateVisibility hides. Visible nodes drop hidden children from their
+// child lists; the input is not modified.
+func ApplyVisibility(sf *SceneFile, vc VisibilityContext) (SceneFile, error) {
+	hidden, err := EvaluateVisibility(sf, vc)
+	if err != nil {
+		return SceneFile{}, err
+	}
+	if len(hidden.Nodes) == 0 && len(hidden.Edges) == 0 {
+		return *sf, nil
+	}
+	hiddenNodes := make(map[string]bool, len(hidden.Nodes))
+	for _, id := range hidden.Nodes {
+		hiddenNodes[id] = true
+	}
+	hiddenEdges := make(map[string]bool, len(hidden.Edges))
+	for _, id := range hidden.Edges {
+		hiddenEdges[id] = true
+	}
+
+	out := *sf
+	out.Scene.Nodes = make([]SceneNode, 0, len(sf.Scene.Nodes)-len(hidden.Nodes))
+	for _, n := range sf.Scene.Nodes {
+		if hiddenNodes[n.ID] {
+			continue
+		}
+		if len(n.Children) > 0 {
+			n.Children = slices.DeleteFunc(slices.Clone(n.Children), func(id string) bool { return hiddenNodes[id] })
+		}
+		out.Scene.Nodes = append(out.Scene.Nodes, n)
+	}
+	out.Scene.Edges = make([]SceneEdge, 0, len(sf.Scene.Edges)-len(hidden.Edges))
+	for _, e := range sf.Scene.Edges {
+		if !hiddenEdges[e.ID] {
+			out.Scene.Edges = append(out.Scene.Edges, e)
+		}
+	}
+	return out, nil
+}
+
+type visibilityFiltersKey struct{}
+
+// WithVisibilityFilters returns a context whose renders treat the given
+// filters as active when evaluating visibility rules
+func WithVisibilityFilters(ctx context.Context, filters ...string) context.Context {
+	return context.WithValue(ctx, visibilityFiltersKey{}, filters)
+}
+
+// VisibilityFiltersFromContext returns the filters set by
+// WithVisibilityFilters, or nil
+func VisibilityFiltersFromContext(ctx context.Context) []string {
+	filters, _ := ctx.Value(visibilityFiltersKey{}).([]string)
+	return filters
+}
+
+// compiledVisibilityRule is a rule with its selector and expression parsed
+type compiledVisibilityRule struct {
+	selector Selector
+	hide     *VisibilityExpr
+}
+
+// compileVisibilityRules parses every rule, failing on the first invalid one
+func compileVisibilityRules(rules []VisibilityRule) ([]compiledVisibilityRule, error) {
+	out := make([]compiledVisibilityRule, 0, len(rules))
+	for _, r := range rules {
+		c, err := compileVisibilityRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("visibility rule %s: %w", r.Name, err)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// compileVisibilityRule parses a rule's selector and expression
+func compileVisibilityRule(r VisibilityRule) (compiledVisibilityRule, error) {
+	sel, err := ParseSelector(r.Selector)
+	if err != nil {
+		return compiledVisibilityRule{}, err
+	}
+	hide, err := ParseVisibilityExpr(r.Hide)
+	if err != nil {
+		return compiledVisibilityRule{}, err
+	}
+	return compiledVisibilityRule{selector: sel, hide: hide}, nil
+}
+
+// =============================================================================
+// VISIBILITY EXPRESSIONS
+// =============================================================================
+
+// VisibilityExpr is a parsed visibility expression
+type VisibilityExpr struct {
+	src  string
+	root visNode
+}
+
+// ParseVisibilityExpr parses a visibility expression. Expressions combine
+// numbers, double-quoted strings, true, false and null with the operators
+// ! && || == != < <= > >= + - * / and parentheses, with the usual
+// precedence. Names read the element being evaluated:
+//
+//	kind              "node" or "edge"
+//	id, type, name    the element's fields; edges have no name
+//	status            a node's status
+//	source, target    an edge's endpoints
+//	distance          from the camera to a node, or to an edge's midpoint
+//	metrics.<key>     a metric; metrics["key"] for other characters
+//	metadata.<key>    a metadata value, likewise
+//	tag("name")       whether a node has the tag
+//	filter("name")    whether the viewer has the filter active
+//
+// Evaluation never fails. Missing values, empty strings and non-scalar
+// values are null. == compares values of the same type and null equals
+// only null; ordering compares two numbers or two strings and is false
+// otherwise. Arithmetic on anything but numbers, and division by zero,
+// yields null. ! && || treat only true as true, and an element is hidden
+// only when its expression yields true.
+func ParseVisibilityExpr(src string) (*VisibilityExpr, error) {
+	tokens, err := lexVisibility(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &visParser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != visEOF {
+		return nil, t.errorf("unexpected %s", t)
+	}
+	return &VisibilityExpr{src: src, root: root}, nil
+}
+
+// String returns the expression's source
+func (e *VisibilityExpr) String() string {
+	return e.src
+}
+
+// visEnv is the element an expression is evaluated for
+type visEnv struct {
+	node     *SceneNode
+	edge     *SceneEdge
+	distance interface{}
+	filters  []string
+}
+
+// visNode is a node of a parsed expression. Values are float64, string,
+// bool or nil.
+type visNode interface {
+	eval(env *visEnv) interface{}
+}
+
+type (
+	visLiteral struct{ value interface{} }
+	visRef     struct{ name, key string }
+	visCall    struct{ fn, arg string }
+	visUnary   struct {
+		op string
+		x  visNode
+	}
+	visBinary struct {
+		op   string
+		x, y visNode
+	}
+)
+
+func (n visLiteral) eval(*visEnv) interface{} { return n.value }
+
+func (n visRef) eval(env *visEnv) interface{} {
+	var value interface{}
+	switch {
+	case n.name == "kind" && env.node != nil:
+		value = ElementNode
+	case n.name == "kind":
+		value = ElementEdge
+	case n.name == "distance":
+		value = env.distance
+	case env.node != nil:
+		node := env.node
+		switch n.name {
+		case "id":
+			value = node.ID
+		case "type":
+			value = node.Type
+		case "name":
+			value = node.Name
+		case "status":
+			value = string(node.Status)
+		case "metrics":
+			value = node.Metrics[n.key]
+		case "metadata":
+			value = node.Metadata[n.key]
+		}
+	case env.edge != nil:
+		edge := env.edge
+		switch n.name {
+		case "id":
+			value = edge.ID
+		case "type":
+			value = edge.Type
+		case "source":
+			value = edge.Source
+		case "target":
+			value = edge.Target
+		case "metrics":
+			value = edge.Metrics[n.key]
+		case "metadata":
+			value = edge.Metadata[n.key]
+		}
+	}
+	return visScalar(value)
+}
+
+func (n visCall) eval(env *visEnv) interface{} {
+	switch n.fn {
+	case "tag":
+		return env.node != nil && slices.Contains(env.node.Tags, n.arg)
+	default:
+		return slices.Contains(env.filters, n.arg)
+	}
+}
+
+func (n visUnary) eval(env *visEnv) interface{} {
+	x := n.x.eval(env)
+	if n.op == "!" {
+		return x != true
+	}
+	if f, ok := x.(float64); ok {
+		return -f
+	}
+	return nil
+}
+
+func (n visBinary) eval(env *visEnv) interface{} {
+	switch n.op {
+	case "&&":
+		return n.x.eval(env) == true && n.y.eval(env) == true
+	case "||":
+		return n.x.eval(env) == true || n.y.eval(env) == true
+	}
+	x, y := n.x.eval(env), n.y.eval(env)
+	switch n.op {
+	case "==":
+		return x == y
+	case "!=":
+		return x != y
+	}
+	ordering := n.op != "+" && n.op != "-" && n.op != "*" && n.op != "/"
+	if xs, ok := x.(string); ok && ordering {
+		ys, ok := y.(string)
+		return ok && visCompare(n.op, strings.Compare(xs, ys))
+	}
+	xf, xok := x.(float64)
+	yf, yok := y.(float64)
+	if !xok || !yok {
+		if ordering {
+			return false
+		}
+		return nil
+	}
+	var out float64
+	switch n.op {
+	case "+":
+		out = xf + yf
+	case "-":
+		out = xf - yf
+	case "*":
+		out = xf * yf
+	case "/":
+		if yf == 0 {
+			return nil
+		}
+		out = xf / yf
+	default:
+		switch {
+		case xf < yf:
+			return visCompare(n.op, -1)
+		case xf > yf:
+			return visCompare(n.op, 1)
+		}
+		return visCompare(n.op, 0)
+	}
+	return visScalar(out)
+}
+
+// visCompare applies an ordering operator to a comparison result
+func visCompare(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// visScalar converts a field, metric or metadata value to an expression
+// value, mapping anything else to null
+func visScalar(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	case bool:
+		return v
+	}
+	if f, ok := toFloat64(v); ok && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	return nil
+}
+
+// visToken kinds
+const (
+	visEOF = iota
+	visNumber
+	visString
+	visIdent
+	visOp
+)
+
+// visToken is a lexed expression token; col is its 1-based byte column
+type visToken struct {
+	kind  int
+	text  string
+	value interface{}
+	col   int
+}
+
+func (t visToken) String() string {
+	switch t.kind {
+	case visEOF:
+		return "end of expression"
+	case visString:
+		return strconv.Quote(t.value.(string))
+	}
+	return strconv.Quote(t.text)
+}
+
+func (t visToken) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: column %d: %s", ErrInvalidVisibilityExpr, t.col, fmt.Sprintf(format, args...))
+}
+
+// visOperators lists operators, longest first so prefixes match last
+var visOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ".", ","}
+
+// lexVisibility splits an expression into tokens
+func lexVisibility(src string) ([]visToken, error) {
+	var tokens []visToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := visToken{col: i + 1}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, start.errorf("unterminated string")
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, start.errorf("invalid string %s", src[i:j+1])
+			}
+			start.kind, start.text, start.value = visString, src[i:j+1], s
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (isVisIdentByte(src[j]) || src[j] == '.' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, start.errorf("invalid number %q", src[i:j])
+			}
+			start.kind, start.text, start.value = visNumber, src[i:j], f
+			i = j
+		case isVisIdentByte(c):
+			j := i
+			for j < len(src) && isVisIdentByte(src[j]) {
+				j++
+			}
+			start.kind, start.text = visIdent, src[i:j]
+			i = j
+		default:
+			for _, op := range visOperators {
+				if strings.HasPrefix(src[i:], op) {
+					start.kind, start.text = visOp, op
+					break
+				}
+			}
+			if start.kind != visOp {
+				return nil, start.errorf("unexpected %q", src[i:i+1])
+			}
+			i += len(start.text)
+		}
+		tokens = append(tokens, start)
+	}
+	return append(tokens, visToken{kind: visEOF, col: len(src) + 1}), nil
+}
+
+// isVisIdentByte reports whether c may appear in a name
+func isVisIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// visParser is a recursive-descent parser over lexed tokens
+type visParser struct {
+	tokens []visToken
+	pos    int
+}
+
+func (p *visParser) peek() visToken { return p.tokens[p.pos] }
+
+func (p *visParser) next() visToken {
+	t := p.tokens[p.pos]
+	if t.kind != visEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators
+func (p *visParser) accept(ops ...string) (string, bool) {
+	if t := p.peek(); t.kind == visOp && slices.Contains(ops, t.text) {
+		p.pos++
+		return t.text, true
+	}
+	return "", false
+}
+
+func (p *visParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		t := p.peek()
+		return t.errorf("expected %q, found %s", op, t)
+	}
+	return nil
+}
+
+// binary parses a left-associative chain of operators over operands
+func (p *visParser) binary(operand func() (visNode, error), ops ...string) (visNode, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return x, nil
+		}
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = visBinary{op: op, x: x, y: y}
+	}
+}
+
+func (p *visParser) or() (visNode, error) { return p.binary(p.and, "||") }
+
+func (p *visParser) and() (visNode, error) { return p.binary(p.comparison, "&&") }
+
+// comparison parses at most one comparison; chains like a < b < c are
+// rejected rather than given a surprising meaning
+func (p *visParser) comparison() (visNode, error) {
+	x, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return x, nil
+	}
+	y, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == visOp && slices.Contains([]string{"==", "!=", "<=", ">=", "<", ">"}, t.text) {
+		return nil, t.errorf("comparisons cannot be chained")
+	}
+	return visBinary{op: op, x: x, y: y}, nil
+}
+
+func (p *visParser) sum() (visNode, error) { return p.binary(p.product, "+", "-") }
+
+func (p *visParser) product() (visNode, error) { return p.binary(p.unary, "*", "/") }
+
+func (p *visParser) unary() (visNode, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return visUnary{op: op, x: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *visParser) primary() (visNode, error) {
+	t := p.next()
+	switch t.kind {
+	case visNumber, visString:
+		return visLiteral{value: t.value}, nil
+	case visOp:
+		if t.text != "(" {
+			break
+		}
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case visIdent:
+		return p.name(t)
+	}
+	return nil, t.errorf("expected a value, found %s", t)
+}
+
+// name parses a literal keyword, field reference or function call
+func (p *visParser) name(t visToken) (visNode, error) {
+	switch t.text {
+	case "true":
+		return visLiteral{value: true}, nil
+	case "false":
+		return visLiteral{value: false}, nil
+	case "null":
+		return visLiteral{value: nil}, nil
+	case "kind", "id", "type", "name", "status", "source", "target", "distance":
+		return visRef{name: t.text}, nil
+	case "metrics", "metadata":
+		var key string
+		if _, ok := p.accept("."); ok {
+			k := p.next()
+			if k.kind != visIdent {
+				return nil, k.errorf("expected a key after %s.", t.text)
+			}
+			key = k.text
+		} else if _, ok := p.accept("["); ok {
+			k := p.next()
+			if k.kind != visString {
+				return nil, k.errorf("expected a string key in %s[...]", t.text)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			key = k.value.(string)
+		} else {
+			return nil, t.errorf("%s needs a key, as in %s.name", t.text, t.text)
+		}
+		return visRef{name: t.text, key: key}, nil
+	case "tag", "filter":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		arg := p.next()
+		if arg.kind != visString {
+			return nil, arg.errorf("%s takes a string, found %s", t.text, arg)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return visCall{fn: t.text, arg: arg.value.(string)}, nil
+	}
+	return nil, t.errorf("unknown name %q", t.text)
+}
@@ -0,0 +1,205 @@
+package starfleet
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestVisibilityExpr tests expression semantics against one node
+func TestVisibilityExpr(t *testing.T) {
+	node := SceneNode{
+		ID: "api", Type: "server", Name: "API", Status: NodeStatusHealthy,
+		Tags:     []string{"prod"},
+		Metrics:  map[string]interface{}{"cpu": 0.75, "rps": 120, "k8s.io/pods": 3, "region": "eu", "up": true, "list": []interface{}{1}},
+		Metadata: map[string]interface{}{"tier": "web"},
+	}
+	env := visEnv{node: &node, distance: 40.0, filters: []string{"incidents"}}
+	for expr, want := range map[string]interface{}{
+		`status == "healthy" && filter("incidents")`: true,
+		`filter("maintenance") || tag("staging")`:    false,
+		`!tag("prod")`: false,
+		`metrics.cpu > 0.5 && metrics.rps >= 120`: true,
+		`metrics["k8s.io/pods"] * 2 + 1`:          7.0,
+		`-metrics.cpu < 0`:                        true,
+		`(1 + 2) * 3 - 8 / 4`:                     7.0,
+		`metrics.region == "eu" && metrics.up`:    true,
+		`metadata.tier < "x"`:                     true,
+		`distance > 25`:                           true,
+		`kind == "node" && source == null`:        true,
+		// Missing and non-scalar values are null and never order
+		`metrics.missing`:         nil,
+		`metrics.missing > 0`:     false,
+		`metrics.missing <= 0`:    false,
+		`metrics.list == null`:    true,
+		`metrics.missing + 1`:     nil,
+		`1 / 0`:                   nil,
+		`"a" + "b"`:               nil,
+		`"10" == 10`:              false,
+		`!metrics.missing`:        true,
+		`metrics.cpu && true`:     false,
+		`name != "API" || false`:  false,
+		`1e3 == 1000 && 2.5 > -1`: true,
+	} {
+		e, err := ParseVisibilityExpr(expr)
+		if err != nil {
+			t.Errorf("ParseVisibilityExpr(%q) failed: %v", expr, err)
+			continue
+		}
+		if got := e.root.eval(&env); got != want {
+			t.Errorf("value mismatch for %q: got %v, want %v", expr, got, want)
+		}
+	}
+}
+
+// TestParseVisibilityExpr_Errors tests that parse errors name their column
+func TestParseVisibilityExpr_Errors(t *testing.T) {
+	for expr, want := range map[string]string{
+		``:                    "column 1: expected a value, found end of expression",
+		`status ==`:           "column 10: expected a value",
+		`colour == "red"`:     `column 1: unknown name "colour"`,
+		`metrics`:             "column 1: metrics needs a key",
+		`metrics[cpu]`:        "column 9: expected a string key",
+		`tag(prod)`:           "column 5: tag takes a string",
+		`filter("a"`:          `column 11: expected ")"`,
+		`1 < 2 < 3`:           "column 7: comparisons cannot be chained",
+		`status == "ok" "x"`:  `column 16: unexpected "x"`,
+		`status = "ok"`:       `column 8: unexpected "="`,
+		`name == "open`:       "column 9: unterminated string",
+		`1.2.3 > 0`:           `column 1: invalid number "1.2.3"`,
+		`(distance > 1`:       `column 14: expected ")", found end of expression`,
+		`metrics.cpu > 0 and`: `column 17: unexpected "and"`,
+	} {
+		_, err := ParseVisibilityExpr(expr)
+		if !errors.Is(err, ErrInvalidVisibilityExpr) || !strings.Contains(err.Error(), want) {
+			t.Errorf("error mismatch for %q: got %v, want %q", expr, err, want)
+		}
+	}
+}
+
+// TestEvaluateVisibility tests hiding nodes, their descendants and edges
+func TestEvaluateVisibility(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Status = NodeStatusHealthy
+	sf.Scene.Nodes[0].Children = []string{"c"}
+	sf.Scene.Nodes[2].Parent = "a"
+	sf.Scene.Nodes[1].Transform.Position = Vector3{X: 100}
+	sf.AddEdge(SceneEdge{ID: "b-c", Source: "b", Target: "c", Metrics: map[string]interface{}{"rps": 0}})
+	err := sf.SetVisibilityRules([]VisibilityRule{
+		{Name: "calm", Selector: "kind=node", Hide: `status == "healthy" && filter("incidents")`},
+		{Name: "idle", Selector: "kind=edge", Hide: `metrics.rps == 0`},
+		{Name: "far", Hide: `distance > 50`},
+	})
+	if err != nil {
+		t.Fatalf("SetVisibilityRules failed: %v", err)
+	}
+
+	// Rules survive a JSON round trip of the scene
+	var decoded SceneFile
+	if err := roundTripJSON(sf, &decoded); err != nil {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	camera := &Camera{Position: Vector3{Z: 10}}
+	for _, tc := range []struct {
+		name string
+		vc   VisibilityContext
+		want HiddenElements
+	}{
+		{"no filters", VisibilityContext{}, HiddenElements{Edges: []string{"b-c"}}},
+		{"incident", VisibilityContext{Filters: []string{"incidents"}}, HiddenElements{Nodes: []string{"a", "c"}, Edges: []string{"a-b", "b-c"}}},
+		{"camera", VisibilityContext{Camera: camera}, HiddenElements{Nodes: []string{"b"}, Edges: []string{"a-b", "b-c"}}},
+	} {
+		got, err := EvaluateVisibility(&decoded, tc.vc)
+		if err != nil {
+			t.Fatalf("%s: EvaluateVisibility failed: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: hidden mismatch: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	out, err := ApplyVisibility(&decoded, VisibilityContext{Camera: camera})
+	if err != nil {
+		t.Fatalf("ApplyVisibility failed: %v", err)
+	}
+	if len(out.Scene.Nodes) != 2 || len(out.Scene.Edges) != 0 || len(decoded.Scene.Nodes) != 3 {
+		t.Errorf("applied scene mismatch: got %+v", out.Scene)
+	}
+
+	// Visible parents drop hidden children without changing the input
+	decoded.Scene.Nodes[0].Status = ""
+	decoded.Scene.Nodes[0].Children = []string{"b", "c"}
+	decoded.Scene.Nodes[1].Parent = "a"
+	decoded.Scene.Nodes[2].Status = NodeStatusHealthy
+	out, _ = ApplyVisibility(&decoded, VisibilityContext{Filters: []string{"incidents"}})
+	if got := out.FindNode("a").Children; !reflect.DeepEqual(got, []string{"b"}) || len(decoded.Scene.Nodes[0].Children) != 2 {
+		t.Errorf("children mismatch: got %v", got)
+	}
+	if r := ValidateScene(&out); !r.Valid {
+		t.Errorf("applied scene is invalid: %v", r.Errors)
+	}
+}
+
+// TestVisibilityRules_Invalid tests that bad rules are rejected and reported
+func TestVisibilityRules_Invalid(t *testing.T) {
+	sf := newDiffScene()
+	if err := sf.SetVisibilityRules([]VisibilityRule{{Name: "bad", Hide: "cpu > 1"}}); !errors.Is(err, ErrInvalidVisibilityExpr) {
+		t.Errorf("expected ErrInvalidVisibilityExpr, got %v", err)
+	}
+	if err := sf.SetVisibilityRules([]VisibilityRule{{Name: "bad", Selector: "colour=red", Hide: "true"}}); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("expected ErrInvalidSelector, got %v", err)
+	}
+
+	sf.Extensions = map[string]interface{}{VisibilityExtension: []interface{}{
+		map[string]interface{}{"name": "x", "hide": "true"},
+		map[string]interface{}{"name": "x", "hide": "metrics."},
+	}}
+	if _, err := EvaluateVisibility(&sf, VisibilityContext{}); !errors.Is(err, ErrInvalidVisibilityExpr) {
+		t.Errorf("expected ErrInvalidVisibilityExpr, got %v", err)
+	}
+	want := []string{"Duplicate visibility rule: x", "Visibility rule x is invalid: invalid visibility expression: column 9: expected a key after metrics."}
+	if got := ValidateVisibilityRules(&sf); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors mismatch: got %q, want %q", got, want)
+	}
+	if _, err := RenderImage(&sf, 8, 8); err == nil {
+		t.Error("expected render to fail with invalid rules")
+	}
+
+	if err := sf.SetVisibilityRules(nil); err != nil || sf.Extensions[VisibilityExtension] != nil {
+		t.Errorf("rules should be removed: %v", sf.Extensions)
+	}
+}
+
+// TestRenderImage_Visibility tests that renders leave out hidden nodes
+func TestRenderImage_Visibility(t *testing.T) {
+	sf := NewSceneFile("Render")
+	red := NewColor(1, 0, 0)
+	sf.Scene.Nodes = []SceneNode{{
+		ID:        "box",
+		Type:      "server",
+		Status:    NodeStatusHealthy,
+		Transform: NewTransform(),
+		Geometry:  &Geometry{Type: GeometryBox},
+		Material:  &Material{Color: &red},
+	}}
+	sf.Scene.Camera = &Camera{Position: Vector3{Z: 3}}
+	sf.SetVisibilityRules([]VisibilityRule{{Name: "calm", Hide: `status == "healthy" && filter("incidents")`}})
+
+	for _, tc := range []struct {
+		ctx  context.Context
+		want bool
+	}{
+		{context.Background(), true},
+		{WithVisibilityFilters(context.Background(), "incidents"), false},
+	} {
+		img, err := RenderImageContext(tc.ctx, &sf, 16, 16)
+		if err != nil {
+			t.Fatalf("RenderImageContext failed: %v", err)
+		}
+		if c := img.NRGBAAt(8, 8); (c.G < 100) != tc.want {
+			t.Errorf("center pixel mismatch: got %v, want drawn %v", c, tc.want)
+		}
+	}
+}
@@ -0,0 +1,488 @@
+package starfleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// WEBHOOKS
+// =============================================================================
+
+// Webhook request headers
+const (
+	WebhookSignatureHeader = "X-Starfleet-Signature"
+	WebhookEventHeader     = "X-Starfleet-Event"
+	WebhookDeliveryHeader  = "X-Starfleet-Delivery"
+)
+
+// Webhook delivery defaults
+const (
+	DefaultWebhookAttempts   = 5
+	DefaultWebhookMinBackoff = time.Second
+	DefaultWebhookMaxBackoff = time.Minute
+	DefaultWebhookQueueSize  = 256
+	// DefaultWebhookTolerance bounds the age of signatures accepted by
+	// VerifyWebhookSignature
+	DefaultWebhookTolerance = 5 * time.Minute
+)
+
+// ErrInvalidWebhookSignature is returned when a webhook signature does not
+// verify
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// actorKey is the context key of the acting user
+type actorKey struct{}
+
+// WithActor returns a context that records who is making a change. Stores
+// and servers pass it on to change notifications.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor recorded by WithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// DiffSummary condenses a SceneDiff into counts for notifications
+type DiffSummary struct {
+	Fields        []string `json:"fields,omitempty"`
+	NodesAdded    int      `json:"nodesAdded"`
+	NodesRemoved  int      `json:"nodesRemoved"`
+	NodesModified int      `json:"nodesModified"`
+	EdgesAdded    int      `json:"edgesAdded"`
+	EdgesRemoved  int      `json:"edgesRemoved"`
+	EdgesModified int      `json:"edgesModified"`
+}
+
+// Summary counts the changes in the diff
+func (d SceneDiff) Summary() DiffSummary {
+	count := func(changes []ElementChange, kind ChangeKind) int {
+		n := 0
+		for _, c := range changes {
+			if c.Kind == kind {
+				n++
+			}
+		}
+		return n
+	}
+	return DiffSummary{
+		Fields:        d.Fields,
+		NodesAdded:    count(d.Nodes, ChangeAdded),
+		NodesRemoved:  count(d.Nodes, ChangeRemoved),
+		NodesModified: count(d.Nodes, ChangeModified),
+		EdgesAdded:    count(d.Edges, ChangeAdded),
+		EdgesRemoved:  count(d.Edges, ChangeRemoved),
+		EdgesModified: count(d.Edges, ChangeModified),
+	}
+}
+
+// WebhookPayload is the JSON body posted to webhook endpoints. Diff is set
+// for updates and summarizes the change from the previous revision; imports
+// also explain each change in Changes.
+type WebhookPayload struct {
+	Delivery string             `json:"delivery"`
+	Event    SceneEventType     `json:"event" validate:"required"`
+	SceneID  string             `json:"sceneId" validate:"required"`
+	Revision int64              `json:"revision,omitempty"`
+	Actor    string             `json:"actor,omitempty"`
+	Time     time.Time          `json:"time"`
+	Diff     *DiffSummary       `json:"diff,omitempty"`
+	Changes  []ProvenanceChange `json:"changes,omitempty"`
+}
+
+// WebhookEndpoint is a subscriber URL. Payloads are signed with Secret;
+// Events restricts delivery to the listed event types, all when empty.
+type WebhookEndpoint struct {
+	URL    string           `json:"url" validate:"required,url"`
+	Secret string           `json:"-"`
+	Events []SceneEventType `json:"events,omitempty"`
+}
+
+func (e WebhookEndpoint) accepts(event SceneEventType) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadLetter is a delivery that exhausted its retries
+type DeadLetter struct {
+	Endpoint  string         `json:"endpoint"`
+	Payload   WebhookPayload `json:"payload"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"lastError"`
+	Failed    time.Time      `json:"failed"`
+}
+
+// DeadLetterQueue keeps failed deliveries for inspection and redelivery
+type DeadLetterQueue interface {
+	Add(ctx context.Context, letter DeadLetter) error
+}
+
+// MemoryDeadLetterQueue is an in-memory DeadLetterQueue
+type MemoryDeadLetterQueue struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// Add appends a dead letter
+func (q *MemoryDeadLetterQueue) Add(ctx context.Context, letter DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, letter)
+	return nil
+}
+
+// Drain removes and returns all dead letters
+func (q *MemoryDeadLetterQueue) Drain() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters := q.letters
+	q.letters = nil
+	return letters
+}
+
+// Len returns the number of dead letters
+func (q *MemoryDeadLetterQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.letters)
+}
+
+// WebhookDispatcher posts signed change notifications to endpoints.
+// Deliveries that fail with a network error, 429 or 5xx are retried with
+// exponential backoff; other failures and exhausted retries go to the
+// dead-letter queue. Each endpoint URL has its own queue and worker, so a
+// slow or failing endpoint delays only its own deliveries. The zero value is
+// not usable; create dispatchers with NewWebhookDispatcher.
+type WebhookDispatcher struct {
+	Endpoints []WebhookEndpoint
+	// HTTPClient sends deliveries; nil uses a client with a 10s timeout
+	HTTPClient  *http.Client
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	DeadLetters DeadLetterQueue
+
+	mu      sync.Mutex
+	queues  map[string]chan webhookJob
+	closed  bool
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// now is replaceable in tests
+	now func() time.Time
+}
+
+// NewWebhookDispatcher creates a dispatcher with default retries and an
+// in-memory dead-letter queue
+func NewWebhookDispatcher(endpoints ...WebhookEndpoint) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookDispatcher{
+		Endpoints:   endpoints,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: DefaultWebhookAttempts,
+		MinBackoff:  DefaultWebhookMinBackoff,
+		MaxBackoff:  DefaultWebhookMaxBackoff,
+		DeadLetters: &MemoryDeadLetterQueue{},
+		ctx:         ctx,
+		cancel:      cancel,
+		now:         time.Now,
+	}
+}
+
+// webhookJob is a queued delivery to one endpoint
+type webhookJob struct {
+	endpoint WebhookEndpoint
+	payload  WebhookPayload
+	body     []byte
+}
+
+// Notify queues a payload for asynchronous delivery to every subscribed
+// endpoint. Endpoints whose queue is full, and all endpoints once the
+// dispatcher is closed, have the payload dead-lettered immediately.
+func (d *WebhookDispatcher) Notify(payload WebhookPayload) {
+	payload = d.prepare(payload)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.deadLetterAll(payload, 0, err.Error())
+		return
+	}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		d.deadLetterAll(payload, 0, "dispatcher closed")
+		return
+	}
+	var full []WebhookEndpoint
+	for _, endpoint := range d.Endpoints {
+		if !endpoint.accepts(payload.Event) {
+			continue
+		}
+		queue := d.queues[endpoint.URL]
+		if queue == nil {
+			if d.queues == nil {
+				d.queues = make(map[string]chan webhookJob)
+			}
+			queue = make(chan webhookJob, DefaultWebhookQueueSize)
+			d.queues[endpoint.URL] = queue
+			d.workers.Add(1)
+			go d.work(queue)
+		}
+		select {
+		case queue <- webhookJob{endpoint, payload, body}:
+		default:
+			full = append(full, endpoint)
+		}
+	}
+	d.mu.Unlock()
+	for _, endpoint := range full {
+		d.deadLetter(endpoint, payload, 0, "delivery queue full")
+	}
+}
+
+// work delivers the jobs of one endpoint queue in order
+func (d *WebhookDispatcher) work(queue chan webhookJob) {
+	defer d.workers.Done()
+	for job := range queue {
+		if attempts, err := d.deliverTo(d.ctx, job.endpoint, job.payload, job.body); err != nil {
+			d.deadLetter(job.endpoint, job.payload, attempts, err.Error())
+		}
+	}
+}
+
+// Close stops accepting payloads and waits for queued deliveries. When ctx
+// expires first, pending retries are abandoned and dead-lettered.
+func (d *WebhookDispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Deliver sends a payload to every subscribed endpoint synchronously and
+// returns the failures, which have also been dead-lettered
+func (d *WebhookDispatcher) Deliver(ctx context.Context, payload WebhookPayload) error {
+	payload = d.prepare(payload)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("deliver webhook: %w", err)
+	}
+	var errs []error
+	for _, endpoint := range d.Endpoints {
+		if !endpoint.accepts(payload.Event) {
+			continue
+		}
+		attempts, err := d.deliverTo(ctx, endpoint, payload, body)
+		if err != nil {
+			d.deadLetter(endpoint, payload, attempts, err.Error())
+			errs = append(errs, fmt.Errorf("deliver webhook to %s: %w", endpoint.URL, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redeliver retries a dead letter once against its original endpoint. The
+// letter is dead-lettered again if delivery still fails.
+func (d *WebhookDispatcher) Redeliver(ctx context.Context, letter DeadLetter) error {
+	for _, endpoint := range d.Endpoints {
+		if endpoint.URL != letter.Endpoint {
+			continue
+		}
+		body, err := json.Marshal(letter.Payload)
+		if err != nil {
+			return fmt.Errorf("redeliver webhook: %w", err)
+		}
+		attempts, err := d.deliverTo(ctx, endpoint, letter.Payload, body)
+		if err != nil {
+			d.deadLetter(endpoint, letter.Payload, letter.Attempts+attempts, err.Error())
+			return fmt.Errorf("redeliver webhook to %s: %w", endpoint.URL, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("redeliver webhook: endpoint %s is not configured", letter.Endpoint)
+}
+
+// prepare fills in the delivery ID and time
+func (d *WebhookDispatcher) prepare(payload WebhookPayload) WebhookPayload {
+	if payload.Delivery == "" {
+		var id [16]byte
+		_, _ = rand.Read(id[:])
+		payload.Delivery = hex.EncodeToString(id[:])
+	}
+	if payload.Time.IsZero() {
+		payload.Time = d.now().UTC()
+	}
+	return payload
+}
+
+// deliverTo posts the body until it succeeds, fails permanently or runs out
+// of attempts, and returns the number of attempts made
+func (d *WebhookDispatcher) deliverTo(ctx context.Context, endpoint WebhookEndpoint, payload WebhookPayload, body []byte) (int, error) {
+	attempts := max(d.MaxAttempts, 1)
+	backoff := d.MinBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, endpoint, payload, body)
+		if err == nil {
+			return attempt, nil
+		}
+		if !retry || attempt >= attempts {
+			return attempt, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, max(d.MaxBackoff, d.MinBackoff))
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is
+// transient
+func (d *WebhookDispatcher) post(ctx context.Context, endpoint WebhookEndpoint, payload WebhookPayload, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(payload.Event))
+	req.Header.Set(WebhookDeliveryHeader, payload.Delivery)
+	if endpoint.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(endpoint.Secret, d.now(), body))
+	}
+	httpClient := d.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
+
+func (d *WebhookDispatcher) deadLetter(endpoint WebhookEndpoint, payload WebhookPayload, attempts int, reason string) {
+	if d.DeadLetters == nil {
+		return
+	}
+	_ = d.DeadLetters.Add(context.Background(), DeadLetter{
+		Endpoint:  endpoint.URL,
+		Payload:   payload,
+		Attempts:  attempts,
+		LastError: reason,
+		Failed:    d.now().UTC(),
+	})
+}
+
+func (d *WebhookDispatcher) deadLetterAll(payload WebhookPayload, attempts int, reason string) {
+	for _, endpoint := range d.Endpoints {
+		if endpoint.accepts(payload.Event) {
+			d.deadLetter(endpoint, payload, attempts, reason)
+		}
+	}
+}
+
+// SignWebhook computes the signature header value for a body: the signing
+// time and an HMAC-SHA256 over "<unix time>.<body>", as in
+// t=1700000000,v1=5257a869...
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+// VerifyWebhookSignature checks a signature header produced by SignWebhook.
+// Signatures older than tolerance are rejected to limit replays; zero uses
+// DefaultWebhookTolerance.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidWebhookSignature)
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+	}
+	want := webhookMAC(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature mismatch", ErrInvalidWebhookSignature)
+}
+
+func webhookMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
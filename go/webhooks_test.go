@@ -0,0 +1,182 @@
+package starfleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWebhookSignature tests signing and verification
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"updated"}`)
+	header := SignWebhook("secret", time.Now(), body)
+	if err := VerifyWebhookSignature("secret", header, body, 0); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+	}{
+		{"wrong secret", "other", header, body},
+		{"tampered body", "secret", header, []byte(`{"event":"deleted"}`)},
+		{"stale", "secret", SignWebhook("secret", time.Now().Add(-time.Hour), body), body},
+		{"malformed", "secret", "v1=abc", body},
+	}
+	for _, tt := range tests {
+		if err := VerifyWebhookSignature(tt.secret, tt.header, tt.body, 0); !errors.Is(err, ErrInvalidWebhookSignature) {
+			t.Errorf("%s: expected ErrInvalidWebhookSignature, got %v", tt.name, err)
+		}
+	}
+}
+
+// TestWebhookDispatcher_Retry tests that transient failures are retried and
+// permanent ones are dead-lettered without retrying
+func TestWebhookDispatcher_Retry(t *testing.T) {
+	var flakyCalls, rejectCalls atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if flakyCalls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhookSignature("s1", r.Header.Get(WebhookSignatureHeader), body, 0); err != nil {
+			t.Errorf("signature rejected: %v", err)
+		}
+		if r.Header.Get(WebhookEventHeader) != "updated" {
+			t.Errorf("event header mismatch: got %q", r.Header.Get(WebhookEventHeader))
+		}
+	}))
+	defer flaky.Close()
+	reject := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rejectCalls.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer reject.Close()
+
+	d := NewWebhookDispatcher(
+		WebhookEndpoint{URL: flaky.URL, Secret: "s1"},
+		WebhookEndpoint{URL: reject.URL},
+		WebhookEndpoint{URL: reject.URL + "/deletes", Events: []SceneEventType{SceneEventDeleted}},
+	)
+	d.MinBackoff, d.MaxBackoff = time.Millisecond, time.Millisecond
+
+	err := d.Deliver(context.Background(), WebhookPayload{Event: SceneEventUpdated, SceneID: "prod", Revision: 2})
+	if err == nil {
+		t.Fatal("expected an error for the rejecting endpoint")
+	}
+	if got := flakyCalls.Load(); got != 3 {
+		t.Errorf("flaky attempt count mismatch: got %d, want 3", got)
+	}
+	if got := rejectCalls.Load(); got != 1 {
+		t.Errorf("rejecting endpoint should be tried once, got %d", got)
+	}
+
+	letters := d.DeadLetters.(*MemoryDeadLetterQueue).Drain()
+	if len(letters) != 1 || letters[0].Endpoint != reject.URL || letters[0].Payload.SceneID != "prod" {
+		t.Fatalf("dead letters mismatch: got %+v", letters)
+	}
+
+	if err := d.Redeliver(context.Background(), letters[0]); err == nil {
+		t.Error("expected redelivery to fail again")
+	}
+	if n := d.DeadLetters.(*MemoryDeadLetterQueue).Len(); n != 1 {
+		t.Errorf("dead letter count after redelivery mismatch: got %d, want 1", n)
+	}
+}
+
+// TestWebhookDispatcher_Notify tests asynchronous delivery and draining on
+// close
+func TestWebhookDispatcher_Notify(t *testing.T) {
+	var mu sync.Mutex
+	var received []WebhookPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode failed: %v", err)
+		}
+		mu.Lock()
+		received = append(received, p)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	d := NewWebhookDispatcher(WebhookEndpoint{URL: ts.URL})
+	summary := DiffSummary{NodesAdded: 1}
+	d.Notify(WebhookPayload{Event: SceneEventCreated, SceneID: "a", Actor: "alice"})
+	d.Notify(WebhookPayload{Event: SceneEventUpdated, SceneID: "a", Revision: 2, Diff: &summary})
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("delivery count mismatch: got %d, want 2", len(received))
+	}
+	if received[0].Actor != "alice" || received[0].Delivery == "" || received[0].Time.IsZero() {
+		t.Errorf("first payload mismatch: got %+v", received[0])
+	}
+	if received[1].Diff == nil || received[1].Diff.NodesAdded != 1 {
+		t.Errorf("second payload diff mismatch: got %+v", received[1].Diff)
+	}
+
+	d.Notify(WebhookPayload{Event: SceneEventDeleted, SceneID: "a"})
+	if n := d.DeadLetters.(*MemoryDeadLetterQueue).Len(); n != 1 {
+		t.Errorf("notify after close should dead-letter, got %d letters", n)
+	}
+}
+
+// TestWebhookDispatcher_SlowEndpoint tests that a stalled endpoint does not
+// hold up deliveries to the others
+func TestWebhookDispatcher_SlowEndpoint(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	delivered := make(chan struct{}, 3)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer fast.Close()
+
+	d := NewWebhookDispatcher(WebhookEndpoint{URL: slow.URL}, WebhookEndpoint{URL: fast.URL})
+	for i := 0; i < 3; i++ {
+		d.Notify(WebhookPayload{Event: SceneEventUpdated, SceneID: "a", Revision: int64(i + 1)})
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-delivered:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fast endpoint got %d of 3 deliveries while the slow one stalled", i)
+		}
+	}
+	close(release)
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if n := d.DeadLetters.(*MemoryDeadLetterQueue).Len(); n != 0 {
+		t.Errorf("expected no dead letters, got %d", n)
+	}
+}
+
+// TestSceneDiff_Summary tests change counting
+func TestSceneDiff_Summary(t *testing.T) {
+	d := SceneDiff{
+		Fields: []string{"metadata.name"},
+		Nodes:  []ElementChange{{ID: "a", Kind: ChangeAdded}, {ID: "b", Kind: ChangeAdded}, {ID: "c", Kind: ChangeModified}},
+		Edges:  []ElementChange{{ID: "e", Kind: ChangeRemoved}},
+	}
+	got := d.Summary()
+	if got.NodesAdded != 2 || got.NodesModified != 1 || got.EdgesRemoved != 1 || len(got.Fields) != 1 {
+		t.Errorf("summary mismatch: got %+v", got)
+	}
+}
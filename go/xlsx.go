@@ -0,0 +1,333 @@
+package starfleet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// XLSX WORKBOOKS
+// =============================================================================
+
+// WorkbookContentType is the media type of XLSX workbooks
+const WorkbookContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// WorkbookOptions selects the columns of an exported workbook
+type WorkbookOptions struct {
+	// Metrics are the metric columns of the node and edge sheets; nil
+	// includes every metric the scene has, sorted by name
+	Metrics []string
+	// MetadataKeys are the metadata columns, chosen like Metrics
+	MetadataKeys []string
+}
+
+// workbookSheet is a worksheet of rows of string, float64 or bool cells,
+// where nil is an empty cell
+type workbookSheet struct {
+	name string
+	rows [][]interface{}
+	// headers are the indexes of rows shown in bold
+	headers []int
+}
+
+// EncodeWorkbook writes a scene as an XLSX workbook for spreadsheet tools
+// such as Excel and Google Sheets. The Nodes and Edges sheets have a row
+// per element with a column per metric and metadata key; the Summary sheet
+// has the scene's counts and the count, minimum, mean and maximum of every
+// metric. Numbers are stored as numbers, so they can be sorted and charted,
+// and other metric or metadata values as text, with structured values
+// encoded as JSON.
+func EncodeWorkbook(w io.Writer, sf *SceneFile, opts WorkbookOptions) error {
+	nodeMetrics, edgeMetrics := opts.Metrics, opts.Metrics
+	if nodeMetrics == nil {
+		nodeMetrics = workbookKeys(len(sf.Scene.Nodes), func(i int) map[string]interface{} { return sf.Scene.Nodes[i].Metrics })
+		edgeMetrics = workbookKeys(len(sf.Scene.Edges), func(i int) map[string]interface{} { return sf.Scene.Edges[i].Metrics })
+	}
+	nodeKeys, edgeKeys := opts.MetadataKeys, opts.MetadataKeys
+	if nodeKeys == nil {
+		nodeKeys = workbookKeys(len(sf.Scene.Nodes), func(i int) map[string]interface{} { return sf.Scene.Nodes[i].Metadata })
+		edgeKeys = workbookKeys(len(sf.Scene.Edges), func(i int) map[string]interface{} { return sf.Scene.Edges[i].Metadata })
+	}
+
+	nodes := workbookSheet{name: "Nodes", headers: []int{0}}
+	nodes.rows = append(nodes.rows, workbookHeader([]string{"ID", "Type", "Name", "Status", "Parent", "Tags"}, nodeMetrics, nodeKeys))
+	for _, n := range sf.Scene.Nodes {
+		row := []interface{}{n.ID, n.Type, n.Name, string(n.Status), n.Parent, strings.Join(n.Tags, ", ")}
+		nodes.rows = append(nodes.rows, workbookValues(row, n.Metrics, nodeMetrics, n.Metadata, nodeKeys))
+	}
+
+	edges := workbookSheet{name: "Edges", headers: []int{0}}
+	edges.rows = append(edges.rows, workbookHeader([]string{"ID", "Source", "Target", "Type", "Direction", "Weight"}, edgeMetrics, edgeKeys))
+	for _, e := range sf.Scene.Edges {
+		var weight interface{}
+		if e.Weight != 0 {
+			weight = e.Weight
+		}
+		row := []interface{}{e.ID, e.Source, e.Target, e.Type, string(e.Direction), weight}
+		edges.rows = append(edges.rows, workbookValues(row, e.Metrics, edgeMetrics, e.Metadata, edgeKeys))
+	}
+
+	summary := workbookSummary(sf, nodeMetrics, edgeMetrics)
+	return writeWorkbook(w, []workbookSheet{nodes, edges, summary})
+}
+
+// workbookKeys returns the sorted keys of n maps
+func workbookKeys(n int, m func(int) map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for i := 0; i < n; i++ {
+		for k := range m(i) {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// workbookHeader returns a header row, naming metadata columns by key
+// prefixed with "metadata." so they cannot clash with metrics
+func workbookHeader(fixed, metrics, keys []string) []interface{} {
+	row := make([]interface{}, 0, len(fixed)+len(metrics)+len(keys))
+	for _, name := range fixed {
+		row = append(row, name)
+	}
+	for _, name := range metrics {
+		row = append(row, name)
+	}
+	for _, key := range keys {
+		row = append(row, "metadata."+key)
+	}
+	return row
+}
+
+// workbookValues appends the metric and metadata columns of an element
+func workbookValues(row []interface{}, metrics map[string]interface{}, metricNames []string, metadata map[string]interface{}, keys []string) []interface{} {
+	for _, name := range metricNames {
+		row = append(row, workbookValue(metrics[name]))
+	}
+	for _, key := range keys {
+		row = append(row, workbookValue(metadata[key]))
+	}
+	return row
+}
+
+// workbookValue converts a metric or metadata value to a cell
+func workbookValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string, bool:
+		return v
+	}
+	if f, ok := toFloat64(v); ok {
+		if !isFinite(f) {
+			// Spreadsheets have no NaN or infinities
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		return f
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// workbookSummary builds the Summary sheet
+func workbookSummary(sf *SceneFile, nodeMetrics, edgeMetrics []string) workbookSheet {
+	stats := CalculateDetailedStats(sf)
+	sheet := workbookSheet{name: "Summary"}
+	section := func(title string, header ...interface{}) {
+		if len(sheet.rows) > 0 {
+			sheet.rows = append(sheet.rows, nil)
+		}
+		sheet.headers = append(sheet.headers, len(sheet.rows))
+		sheet.rows = append(sheet.rows, append([]interface{}{title}, header...))
+	}
+	counts := func(title string, m map[string]int) {
+		if len(m) == 0 {
+			return
+		}
+		section(title, "Count")
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sheet.rows = append(sheet.rows, []interface{}{k, float64(m[k])})
+		}
+	}
+
+	section("Scene", sf.Metadata.Name)
+	for _, row := range [][]interface{}{
+		{"Version", sf.Version},
+		{"Nodes", float64(stats.NodeCount)},
+		{"Edges", float64(stats.EdgeCount)},
+		{"Components", float64(stats.Components)},
+		{"Isolated nodes", float64(stats.IsolatedNodes)},
+		{"Dangling edges", float64(stats.DanglingEdges)},
+		{"Max degree", float64(stats.MaxDegree)},
+	} {
+		sheet.rows = append(sheet.rows, row)
+	}
+	counts("Node type", stats.NodesByType)
+	byStatus := make(map[string]int, len(stats.NodesByStatus))
+	for status, n := range stats.NodesByStatus {
+		byStatus[string(status)] = n
+	}
+	counts("Node status", byStatus)
+	counts("Edge type", stats.EdgesByType)
+
+	if len(nodeMetrics)+len(edgeMetrics) == 0 {
+		return sheet
+	}
+	section("Metric", "Element", "Count", "Min", "Mean", "Max")
+	aggregate := func(element string, names []string, n int, m func(int) map[string]interface{}) {
+		for _, name := range names {
+			count, sum, lo, hi := 0, 0.0, 0.0, 0.0
+			for i := 0; i < n; i++ {
+				f, ok := toFloat64(m(i)[name])
+				if !ok || !isFinite(f) {
+					continue
+				}
+				if count == 0 || f < lo {
+					lo = f
+				}
+				if count == 0 || f > hi {
+					hi = f
+				}
+				count++
+				sum += f
+			}
+			row := []interface{}{name, element, float64(count)}
+			if count > 0 {
+				row = append(row, lo, sum/float64(count), hi)
+			}
+			sheet.rows = append(sheet.rows, row)
+		}
+	}
+	aggregate("node", nodeMetrics, len(sf.Scene.Nodes), func(i int) map[string]interface{} { return sf.Scene.Nodes[i].Metrics })
+	aggregate("edge", edgeMetrics, len(sf.Scene.Edges), func(i int) map[string]interface{} { return sf.Scene.Edges[i].Metrics })
+	return sheet
+}
+
+// Package parts of a workbook. Style 1 is the bold header style.
+const (
+	workbookContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>%s</Types>`
+	workbookRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	workbookStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+)
+
+// writeWorkbook packages sheets as an XLSX file
+func writeWorkbook(w io.Writer, sheets []workbookSheet) error {
+	zw := zip.NewWriter(w)
+	part := func(name, content string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+
+	var overrides, entries, rels strings.Builder
+	for i, sheet := range sheets {
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&entries, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, sheet.name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+	for _, p := range []struct{ name, content string }{
+		{"[Content_Types].xml", fmt.Sprintf(workbookContentTypes, overrides.String())},
+		{"_rels/.rels", workbookRootRels},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + entries.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`},
+		{"xl/styles.xml", workbookStyles},
+	} {
+		if err := part(p.name, p.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range sheets {
+		f, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err := writeWorksheet(f, sheet); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeWorksheet writes the cells of a sheet, with text inline rather than
+// in a shared string table so the sheet can be written in one pass. Sheets
+// whose first row is a header keep it in view while scrolling.
+func writeWorksheet(w io.Writer, sheet workbookSheet) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(sheet.headers) == 1 && sheet.headers[0] == 0 {
+		bw.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	bw.WriteString(`<sheetData>`)
+	headers := make(map[int]bool, len(sheet.headers))
+	for _, i := range sheet.headers {
+		headers[i] = true
+	}
+	for i, row := range sheet.rows {
+		fmt.Fprintf(bw, `<row r="%d">`, i+1)
+		style := ""
+		if headers[i] {
+			style = ` s="1"`
+		}
+		for j, v := range row {
+			ref := workbookColumn(j) + strconv.Itoa(i+1)
+			switch v := v.(type) {
+			case string:
+				if v == "" {
+					continue
+				}
+				fmt.Fprintf(bw, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+				xml.EscapeText(bw, []byte(v))
+				bw.WriteString(`</t></is></c>`)
+			case float64:
+				fmt.Fprintf(bw, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'g', -1, 64))
+			case bool:
+				b := 0
+				if v {
+					b = 1
+				}
+				fmt.Fprintf(bw, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, style, b)
+			}
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	return bw.Flush()
+}
+
+// workbookColumn returns the letters of a zero-based column index, as in
+// A, Z, AA
+func workbookColumn(i int) string {
+	var b []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		b = append([]byte{byte('A' + (i-1)%26)}, b...)
+	}
+	return string(b)
+}
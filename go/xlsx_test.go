@@ -0,0 +1,129 @@
+package starfleet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// readWorksheet returns the cells of a worksheet by reference
+func readWorksheet(t *testing.T, zr *zip.Reader, name string) map[string]string {
+	t.Helper()
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("missing %s: %v", name, err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		t.Fatalf("%s is not XML: %v", name, err)
+	}
+	cells := make(map[string]string)
+	for _, row := range sheet.Rows {
+		for _, c := range row.Cells {
+			if c.Type == "inlineStr" {
+				cells[c.Ref] = c.Inline
+			} else {
+				cells[c.Ref] = c.Value
+			}
+		}
+	}
+	return cells
+}
+
+// TestEncodeWorkbook tests the node, edge and summary sheets of a workbook
+func TestEncodeWorkbook(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Status = NodeStatusCritical
+	sf.Scene.Nodes[0].Tags = []string{"prod", "eu"}
+	sf.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": 0.5, "rps": 10}
+	sf.Scene.Nodes[1].Metrics = map[string]interface{}{"cpu": 0.9, "rps": math.Inf(1)}
+	sf.Scene.Nodes[2].Metadata = map[string]interface{}{"owner": "team <a&b>", "ports": []int{80}}
+	sf.Scene.Edges[0].Weight = 2
+
+	var buf bytes.Buffer
+	if err := EncodeWorkbook(&buf, &sf, WorkbookOptions{}); err != nil {
+		t.Fatalf("EncodeWorkbook failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip: %v", err)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, err := zr.Open(name); err != nil {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	nodes := readWorksheet(t, zr, "xl/worksheets/sheet1.xml")
+	for ref, want := range map[string]string{
+		"A1": "ID", "G1": "cpu", "H1": "rps", "I1": "metadata.owner", "J1": "metadata.ports",
+		"A2": "a", "D2": "critical", "F2": "prod, eu", "G2": "0.5", "H2": "10",
+		"H3": "+Inf", "I4": "team <a&b>", "J4": "[80]",
+	} {
+		if nodes[ref] != want {
+			t.Errorf("node cell %s mismatch: got %q, want %q", ref, nodes[ref], want)
+		}
+	}
+	edges := readWorksheet(t, zr, "xl/worksheets/sheet2.xml")
+	if edges["B2"] != "a" || edges["C2"] != "b" || edges["F2"] != "2" {
+		t.Errorf("edge row mismatch: got %v", edges)
+	}
+
+	summary := readWorksheet(t, zr, "xl/worksheets/sheet3.xml")
+	found := map[string][]string{}
+	for row := 1; row < 40; row++ {
+		ref := func(col string) string { return summary[col+strconv.Itoa(row)] }
+		if ref("A") != "" {
+			found[ref("A")] = []string{ref("B"), ref("C"), ref("D"), ref("E"), ref("F")}
+		}
+	}
+	if got := found["Nodes"]; got[0] != "3" {
+		t.Errorf("node count mismatch: got %v", got)
+	}
+	if got := found["cpu"]; got[0] != "node" || got[1] != "2" || got[2] != "0.5" || got[4] != "0.9" {
+		t.Errorf("cpu summary mismatch: got %v", got)
+	}
+	if got := found["rps"]; got[1] != "1" || got[3] != "10" {
+		t.Errorf("rps summary mismatch, non-finite values must be skipped: got %v", got)
+	}
+}
+
+// TestEncodeWorkbook_Options tests choosing the metric and metadata columns
+func TestEncodeWorkbook_Options(t *testing.T) {
+	sf := newDiffScene()
+	sf.Scene.Nodes[0].Metrics = map[string]interface{}{"cpu": 0.5, "rps": 10}
+	sf.Scene.Nodes[0].Metadata = map[string]interface{}{"owner": "ops"}
+	var buf bytes.Buffer
+	if err := EncodeWorkbook(&buf, &sf, WorkbookOptions{Metrics: []string{"rps"}, MetadataKeys: []string{}}); err != nil {
+		t.Fatalf("EncodeWorkbook failed: %v", err)
+	}
+	zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	nodes := readWorksheet(t, zr, "xl/worksheets/sheet1.xml")
+	if nodes["G1"] != "rps" || nodes["H1"] != "" || nodes["G2"] != "10" {
+		t.Errorf("columns mismatch: got %v", nodes)
+	}
+}
+
+// TestWorkbookColumn tests spreadsheet column letters
+func TestWorkbookColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := workbookColumn(i); got != want {
+			t.Errorf("column %d mismatch: got %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,682 @@
+package starfleet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// YAML SCENE FILES
+// =============================================================================
+
+// ErrInvalidSceneYAML is returned when a YAML scene file cannot be parsed
+var ErrInvalidSceneYAML = errors.New("invalid scene YAML")
+
+// The YAML reader covers what scene files written by hand or exported by
+// other tools use: one document of block mappings and sequences, flow
+// collections, plain, quoted, literal and folded scalars, and comments.
+// Plain scalars resolve as in the YAML 1.2 core schema, so null, booleans
+// and numbers must be quoted to be read as strings, versions included.
+// Anchors, aliases, tags and plain scalars continued over several lines are
+// rejected rather than guessed at.
+
+// DecodeSceneYAML decodes a scene file from YAML
+func DecodeSceneYAML(data []byte) (SceneFile, error) {
+	doc, err := parseYAML(string(data))
+	if err != nil {
+		return SceneFile{}, err
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return SceneFile{}, fmt.Errorf("%w: document is not a mapping", ErrInvalidSceneYAML)
+	}
+	var sf SceneFile
+	if err := roundTripJSON(doc, &sf); err != nil {
+		return SceneFile{}, fmt.Errorf("%w: %v", ErrInvalidSceneYAML, err)
+	}
+	return sf, nil
+}
+
+// yamlLine is a line of the source. text has the indentation and any
+// comment removed; raw is kept for block scalars.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+	raw    string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func yamlErrorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidSceneYAML, line, fmt.Sprintf(format, args...))
+}
+
+// parseYAML parses a single YAML document into JSON-compatible values
+func parseYAML(src string) (interface{}, error) {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+	p := &yamlParser{}
+	started := false
+	for i, raw := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		text := strings.TrimRight(stripYAMLComment(raw[indent:]), " \t")
+		if text != "" && strings.HasPrefix(raw[indent:], "\t") {
+			return nil, yamlErrorf(i+1, "tabs cannot indent")
+		}
+		if indent == 0 && !started && len(p.lines) == 0 {
+			// Directives and the document start marker precede the content
+			if strings.HasPrefix(text, "%") {
+				continue
+			}
+			if text == "---" || strings.HasPrefix(text, "--- ") {
+				started = true
+				text = strings.TrimSpace(strings.TrimPrefix(text, "---"))
+				indent = len(raw) - len(strings.TrimLeft(strings.TrimPrefix(raw, "---"), " "))
+				if text == "" {
+					continue
+				}
+			}
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: indent, text: text, raw: raw})
+	}
+
+	line, ok := p.peek()
+	if !ok || line.text == "..." {
+		return nil, fmt.Errorf("%w: empty document", ErrInvalidSceneYAML)
+	}
+	doc, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if line, ok := p.peek(); ok {
+		if !isYAMLDocumentMarker(line) {
+			return nil, yamlErrorf(line.num, "unexpected indentation")
+		}
+		if line.text == "..." {
+			p.pos++
+			line, ok = p.peek()
+		}
+		if ok {
+			return nil, yamlErrorf(line.num, "only one document is supported")
+		}
+	}
+	return doc, nil
+}
+
+// peek returns the next line with content, skipping blank and comment
+// lines
+func (p *yamlParser) peek() (yamlLine, bool) {
+	for p.pos < len(p.lines) && p.lines[p.pos].text == "" {
+		p.pos++
+	}
+	if p.pos == len(p.lines) {
+		return yamlLine{}, false
+	}
+	return p.lines[p.pos], true
+}
+
+// node parses the block node starting at the next line, which must be
+// indented by at least minIndent
+func (p *yamlParser) node(minIndent int) (interface{}, error) {
+	line, ok := p.peek()
+	if !ok || line.indent < minIndent {
+		return nil, nil
+	}
+	switch {
+	case isYAMLSequenceItem(line.text):
+		return p.sequence(line.indent)
+	case yamlKeyEnd(line.text) >= 0:
+		return p.mapping(line.indent)
+	}
+	p.pos++
+	return p.inline(line)
+}
+
+// mapping parses the block mapping whose keys are indented by indent
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	out := make(map[string]interface{})
+	for {
+		line, ok := p.peek()
+		if !ok || line.indent < indent || isYAMLDocumentMarker(line) {
+			return out, nil
+		}
+		if line.indent > indent {
+			return nil, yamlErrorf(line.num, "unexpected indentation; plain scalars cannot continue over several lines")
+		}
+		end := yamlKeyEnd(line.text)
+		if end < 0 {
+			if isYAMLSequenceItem(line.text) {
+				return out, nil
+			}
+			return nil, yamlErrorf(line.num, "expected a mapping key")
+		}
+		key, err := yamlKey(line.text[:end], line.num)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := out[key]; dup {
+			return nil, yamlErrorf(line.num, "duplicate key %q", key)
+		}
+		p.pos++
+		value, err := p.value(indent, strings.TrimSpace(line.text[end+1:]), line)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+}
+
+// sequence parses the block sequence whose dashes are indented by indent
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	out := []interface{}{}
+	for {
+		line, ok := p.peek()
+		if !ok || line.indent != indent || !isYAMLSequenceItem(line.text) {
+			if ok && line.indent > indent {
+				return nil, yamlErrorf(line.num, "unexpected indentation; plain scalars cannot continue over several lines")
+			}
+			return out, nil
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			item, err := p.node(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+			continue
+		}
+		if rest[0] == '|' || rest[0] == '>' {
+			p.pos++
+			item, err := p.blockScalar(rest, indent, line.num)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+			continue
+		}
+		// A compact item continues as a node indented to where it starts
+		p.lines[p.pos].indent += len(line.text) - len(rest)
+		p.lines[p.pos].text = rest
+		item, err := p.node(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+}
+
+// value parses the value of a mapping entry: the text after the colon, or
+// the block beneath the key
+func (p *yamlParser) value(indent int, text string, line yamlLine) (interface{}, error) {
+	if text == "" {
+		next, ok := p.peek()
+		if ok && next.indent == indent && isYAMLSequenceItem(next.text) {
+			// Sequences may sit at the indentation of their key
+			return p.sequence(indent)
+		}
+		return p.node(indent + 1)
+	}
+	if text[0] == '|' || text[0] == '>' {
+		return p.blockScalar(text, indent, line.num)
+	}
+	line.text = text
+	return p.inline(line)
+}
+
+// inline parses a scalar or flow collection, joining following lines while
+// brackets are open
+func (p *yamlParser) inline(line yamlLine) (interface{}, error) {
+	text := line.text
+	if text[0] == '[' || text[0] == '{' {
+		for yamlOpenBrackets(text) > 0 {
+			next, ok := p.peek()
+			if !ok {
+				return nil, yamlErrorf(line.num, "unclosed flow collection")
+			}
+			text += " " + next.text
+			p.pos++
+		}
+	}
+	f := &yamlFlow{src: text, line: line.num}
+	v, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.pos < len(f.src) {
+		return nil, yamlErrorf(line.num, "unexpected %q after value", f.src[f.pos:])
+	}
+	return v, nil
+}
+
+// yamlBlockHeader parses the indicators after | or >
+var yamlBlockHeader = regexp.MustCompile(`^([|>])([1-9]?)([+-]?)([1-9]?)$`)
+
+// blockScalar reads a literal (|) or folded (>) scalar nested under a
+// parent indented by parentIndent
+func (p *yamlParser) blockScalar(header string, parentIndent, num int) (interface{}, error) {
+	m := yamlBlockHeader.FindStringSubmatch(header)
+	if m == nil {
+		return nil, yamlErrorf(num, "invalid block scalar header %q", header)
+	}
+	style, chomp := m[1], m[3]
+	indicator := m[2] + m[4]
+
+	indent := -1
+	if indicator != "" {
+		indent = parentIndent + int(indicator[0]-'0')
+	}
+	var lines []string
+	for p.pos < len(p.lines) {
+		raw := p.lines[p.pos].raw
+		if strings.TrimSpace(raw) == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		lead := len(raw) - len(strings.TrimLeft(raw, " "))
+		if indent < 0 {
+			if lead <= parentIndent {
+				break
+			}
+			indent = lead
+		}
+		if lead < indent {
+			break
+		}
+		lines = append(lines, raw[indent:])
+		p.pos++
+	}
+	// Blank lines after the content belong to the chomping, not the content
+	content := len(lines)
+	for content > 0 && lines[content-1] == "" {
+		content--
+	}
+	trailing := len(lines) - content
+	lines = lines[:content]
+
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			foldable := style == ">" && prev != "" && prev[0] != ' '
+			switch {
+			case foldable && l == "":
+				// The break before blank lines is folded away
+			case foldable && l[0] != ' ':
+				b.WriteByte(' ')
+			default:
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(l)
+	}
+	text := b.String()
+	switch {
+	case content == 0:
+		if chomp == "+" {
+			text = strings.Repeat("\n", trailing)
+		}
+	case chomp == "+":
+		text += "\n" + strings.Repeat("\n", trailing)
+	case chomp == "":
+		text += "\n"
+	}
+	return text, nil
+}
+
+// yamlFlow parses flow collections and scalars on one logical line
+type yamlFlow struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.src) && (f.src[f.pos] == ' ' || f.src[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+// value parses the next value. Inside a flow collection plain scalars end
+// at the collection's indicators.
+func (f *yamlFlow) value(inFlow bool) (interface{}, error) {
+	f.skipSpace()
+	if f.pos == len(f.src) {
+		return nil, nil
+	}
+	switch c := f.src[f.pos]; c {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		return f.quoted()
+	case '&', '*', '!':
+		return nil, yamlErrorf(f.line, "anchors, aliases and tags are not supported")
+	case '|', '>':
+		if inFlow {
+			return nil, yamlErrorf(f.line, "block scalars cannot appear in flow collections")
+		}
+	}
+	return resolveYAMLScalar(f.plain(inFlow), f.line)
+}
+
+func (f *yamlFlow) sequence() (interface{}, error) {
+	f.pos++
+	out := []interface{}{}
+	for {
+		f.skipSpace()
+		if f.pos == len(f.src) {
+			return nil, yamlErrorf(f.line, "unclosed flow sequence")
+		}
+		if f.src[f.pos] == ']' {
+			f.pos++
+			return out, nil
+		}
+		v, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (interface{}, error) {
+	f.pos++
+	out := make(map[string]interface{})
+	for {
+		f.skipSpace()
+		if f.pos == len(f.src) {
+			return nil, yamlErrorf(f.line, "unclosed flow mapping")
+		}
+		if f.src[f.pos] == '}' {
+			f.pos++
+			return out, nil
+		}
+		var key string
+		if c := f.src[f.pos]; c == '"' || c == '\'' {
+			k, err := f.quoted()
+			if err != nil {
+				return nil, err
+			}
+			key = k.(string)
+		} else {
+			key = f.plain(true)
+		}
+		f.skipSpace()
+		if f.pos == len(f.src) || f.src[f.pos] != ':' {
+			return nil, yamlErrorf(f.line, "expected ':' after flow mapping key %q", key)
+		}
+		f.pos++
+		if _, dup := out[key]; dup {
+			return nil, yamlErrorf(f.line, "duplicate key %q", key)
+		}
+		v, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the comma between flow entries, leaving the closing
+// bracket for the caller
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpace()
+	switch {
+	case f.pos == len(f.src):
+		return yamlErrorf(f.line, "unclosed flow collection")
+	case f.src[f.pos] == ',':
+		f.pos++
+	case f.src[f.pos] != closing:
+		return yamlErrorf(f.line, "expected ',' or %q, found %q", closing, f.src[f.pos])
+	}
+	return nil
+}
+
+// plain reads a plain scalar. In flow context it ends at ",[]{}" and at
+// ": "; outside it runs to the end of the line.
+func (f *yamlFlow) plain(inFlow bool) string {
+	start := f.pos
+	for f.pos < len(f.src) {
+		c := f.src[f.pos]
+		if inFlow && strings.IndexByte(",[]{}", c) >= 0 {
+			break
+		}
+		if inFlow && c == ':' && (f.pos+1 == len(f.src) || strings.IndexByte(" ,[]{}", f.src[f.pos+1]) >= 0) {
+			break
+		}
+		f.pos++
+	}
+	return strings.TrimRight(f.src[start:f.pos], " \t")
+}
+
+// quoted reads a single- or double-quoted scalar
+func (f *yamlFlow) quoted() (interface{}, error) {
+	quote := f.src[f.pos]
+	f.pos++
+	var b strings.Builder
+	for f.pos < len(f.src) {
+		c := f.src[f.pos]
+		switch {
+		case c == quote && quote == '\'' && f.pos+1 < len(f.src) && f.src[f.pos+1] == '\'':
+			b.WriteByte('\'')
+			f.pos += 2
+		case c == quote:
+			f.pos++
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if err := f.escape(&b); err != nil {
+				return nil, err
+			}
+		default:
+			b.WriteByte(c)
+			f.pos++
+		}
+	}
+	return nil, yamlErrorf(f.line, "unterminated quoted string")
+}
+
+// yamlEscapes maps the single-character escapes of double-quoted scalars
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085",
+	'_': " ", 'L': " ", 'P': " ",
+}
+
+// escape decodes the escape sequence at the current backslash
+func (f *yamlFlow) escape(b *strings.Builder) error {
+	if f.pos+1 == len(f.src) {
+		return yamlErrorf(f.line, "unterminated escape sequence")
+	}
+	c := f.src[f.pos+1]
+	if s, ok := yamlEscapes[c]; ok {
+		b.WriteString(s)
+		f.pos += 2
+		return nil
+	}
+	digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if digits == 0 || f.pos+2+digits > len(f.src) {
+		return yamlErrorf(f.line, "invalid escape sequence \\%c", c)
+	}
+	r, err := strconv.ParseUint(f.src[f.pos+2:f.pos+2+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return yamlErrorf(f.line, "invalid escape sequence \\%s", f.src[f.pos+1:f.pos+2+digits])
+	}
+	b.WriteRune(rune(r))
+	f.pos += 2 + digits
+	return nil
+}
+
+// Plain scalars of the YAML 1.2 core schema
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlOctal = regexp.MustCompile(`^0o[0-7]+$`)
+	yamlHex   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlInf   = regexp.MustCompile(`^[-+]?\.(inf|Inf|INF)$|^\.(nan|NaN|NAN)$`)
+)
+
+// resolveYAMLScalar gives a plain scalar its core schema type
+func resolveYAMLScalar(s string, line int) (interface{}, error) {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	switch {
+	case yamlInt.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+	case yamlOctal.MatchString(s):
+		if n, err := strconv.ParseInt(s[2:], 8, 64); err == nil {
+			return n, nil
+		}
+	case yamlHex.MatchString(s):
+		if n, err := strconv.ParseInt(s[2:], 16, 64); err == nil {
+			return n, nil
+		}
+	case yamlInf.MatchString(s):
+		return nil, yamlErrorf(line, "%s has no JSON representation", s)
+	}
+	if yamlInt.MatchString(s) || yamlFloat.MatchString(s) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) {
+			return nil, yamlErrorf(line, "number %s out of range", s)
+		}
+		return f, nil
+	}
+	return s, nil
+}
+
+// isYAMLDocumentMarker reports whether a line starts or ends a document
+func isYAMLDocumentMarker(line yamlLine) bool {
+	return line.indent == 0 && (line.text == "---" || line.text == "..." || strings.HasPrefix(line.text, "--- "))
+}
+
+// isYAMLSequenceItem reports whether a line is a block sequence entry
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlKeyEnd returns the index of the colon ending the implicit key of a
+// block mapping entry, or -1 when the line is not one
+func yamlKeyEnd(text string) int {
+	if text == "" || strings.IndexByte("[{-#&*!|>%@`", text[0]) >= 0 && !(text[0] == '-' && len(text) > 1 && text[1] != ' ') {
+		return -1
+	}
+	i := 0
+	if text[0] == '"' || text[0] == '\'' {
+		f := &yamlFlow{src: text}
+		if _, err := f.quoted(); err != nil {
+			return -1
+		}
+		i = f.pos
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		if i < len(text) && text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+		return -1
+	}
+	for ; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlKey decodes the key of a block mapping entry
+func yamlKey(text string, line int) (string, error) {
+	text = strings.TrimSpace(text)
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		f := &yamlFlow{src: text, line: line}
+		k, err := f.quoted()
+		if err != nil {
+			return "", err
+		}
+		return k.(string), nil
+	}
+	return text, nil
+}
+
+// yamlOpenBrackets counts the flow collections text leaves open
+func yamlOpenBrackets(text string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if yamlTokenStart(text, i) {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// stripYAMLComment removes a trailing comment: a # at the start or after
+// whitespace, outside quoted scalars
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if yamlTokenStart(text, i) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// yamlTokenStart reports whether a quote at i opens a quoted scalar rather
+// than sitting inside a plain one, like the apostrophe in it's
+func yamlTokenStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	return strings.IndexByte(" \t[{,:-", text[i-1]) >= 0
+}
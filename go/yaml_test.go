@@ -0,0 +1,196 @@
+package starfleet
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encodeTestYAML writes a decoded JSON value as block YAML, quoting every
+// string as JSON, which YAML reads as a double-quoted scalar
+func encodeTestYAML(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(pad + k + ":")
+			encodeTestYAMLValue(b, v[k], indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			b.WriteString(pad + "-")
+			encodeTestYAMLValue(b, item, indent)
+		}
+	}
+}
+
+func encodeTestYAMLValue(b *strings.Builder, v interface{}, indent int) {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		if len(c) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		encodeTestYAML(b, c, indent+2)
+	case []interface{}:
+		if len(c) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		encodeTestYAML(b, c, indent+2)
+	default:
+		data, _ := json.Marshal(c)
+		b.WriteString(" " + string(data) + "\n")
+	}
+}
+
+// TestDecodeSceneYAML tests block and flow styles, scalars and comments
+func TestDecodeSceneYAML(t *testing.T) {
+	src := `%YAML 1.2
+---
+# Hand-written scene
+version: "0.2.0"
+metadata:
+  name: Plant # trailing comment
+  description: >
+    Folded text
+    on two lines
+
+    and a paragraph
+  tags: [ops, "it's quoted", 'single ''quoted''']
+scene:
+  nodes:
+  - id: api
+    type: server
+    name: API
+    transform: {position: {x: 1, y: -2.5, z: 0x10},
+      rotation: {x: 0, y: 0, z: 0}, scale: {x: 1, y: 1, z: 1}}
+    visible: true
+    metadata:
+      url: http://api:8080/health
+      owner: ~
+      notes: |
+        line one
+          indented
+      empty: ""
+  - id: db
+    type: database
+    name: "DB \u00e9"
+    transform:
+      position: {x: 0, y: 0, z: 0}
+      rotation: {x: 0, y: 0, z: 0}
+      scale: {x: 1, y: 1, z: 1}
+    metadata:
+      matrix:
+        - - nested
+          - second
+        - []
+  edges:
+    - id: api-db
+      source: api
+      target: db
+...
+`
+	sf, err := DecodeSceneYAML([]byte(src))
+	if err != nil {
+		t.Fatalf("DecodeSceneYAML failed: %v", err)
+	}
+	if sf.Version != "0.2.0" || sf.Metadata.Name != "Plant" {
+		t.Errorf("header mismatch: got %q %q", sf.Version, sf.Metadata.Name)
+	}
+	if want := "Folded text on two lines\nand a paragraph\n"; sf.Metadata.Description != want {
+		t.Errorf("folded scalar mismatch: got %q, want %q", sf.Metadata.Description, want)
+	}
+	if got := strings.Join(sf.Metadata.Tags, "|"); got != "ops|it's quoted|single 'quoted'" {
+		t.Errorf("flow sequence mismatch: got %q", got)
+	}
+	if len(sf.Scene.Nodes) != 2 || len(sf.Scene.Edges) != 1 || sf.Scene.Edges[0].Target != "db" {
+		t.Fatalf("element count mismatch: got %+v", sf.Scene)
+	}
+	api, db := sf.Scene.Nodes[0], sf.Scene.Nodes[1]
+	if api.Transform.Position != (Vector3{X: 1, Y: -2.5, Z: 16}) || !api.Visible {
+		t.Errorf("api mismatch: got %+v", api)
+	}
+	wantMeta := map[string]interface{}{"url": "http://api:8080/health", "owner": nil, "notes": "line one\n  indented\n", "empty": ""}
+	for k, want := range wantMeta {
+		if got, ok := api.Metadata[k]; !ok || got != want {
+			t.Errorf("metadata %s mismatch: got %#v, want %#v", k, got, want)
+		}
+	}
+	if db.Name != "DB é" {
+		t.Errorf("escaped name mismatch: got %q", db.Name)
+	}
+	if got, _ := json.Marshal(db.Metadata["matrix"]); string(got) != `[["nested","second"],[]]` {
+		t.Errorf("nested sequence mismatch: got %s", got)
+	}
+}
+
+// TestParseYAML_BlockScalars tests chomping and folding of block scalars
+func TestParseYAML_BlockScalars(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a: |\n  x\n  y\n\n", "x\ny\n"},
+		{"a: |-\n  x\n  y\n\n", "x\ny"},
+		{"a: |+\n  x\n\n\n", "x\n\n\n"},
+		{"a: >\n  x\n  y\n\n\n  z\n", "x y\n\nz\n"},
+		{"a: >\n  x\n    code\n  y\n", "x\n  code\ny\n"},
+		{"a: |2\n    x\n   y\n", "  x\n y\n"},
+		{"- |\n  x # not a comment\n- y\n", "x # not a comment\n"},
+	}
+	for _, tt := range tests {
+		doc, err := parseYAML(tt.src)
+		if err != nil {
+			t.Errorf("parseYAML(%q) failed: %v", tt.src, err)
+			continue
+		}
+		var got interface{}
+		switch doc := doc.(type) {
+		case map[string]interface{}:
+			got = doc["a"]
+		case []interface{}:
+			got = doc[0]
+		}
+		if got != tt.want {
+			t.Errorf("parseYAML(%q) mismatch: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestDecodeSceneYAML_Errors tests rejecting what the reader does not
+// support with the offending line
+func TestDecodeSceneYAML_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "# nothing\n", "empty document"},
+		{"not a mapping", "- a\n- b\n", "not a mapping"},
+		{"tab indent", "a:\n\tb: 1\n", "line 2"},
+		{"anchor", "a: &x 1\nb: *x\n", "anchors"},
+		{"duplicate", "a: 1\na: 2\n", "duplicate key"},
+		{"continuation", "a: one\n  two\n", "line 2"},
+		{"documents", "a: 1\n---\na: 2\n", "one document"},
+		{"unclosed", "a: [1, 2\n", "unclosed"},
+		{"quote", "a: \"open\n", "unterminated"},
+		{"nan", "a: .nan\n", "no JSON representation"},
+		{"type", "version: 1.0\n", "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		_, err := DecodeSceneYAML([]byte(tt.src))
+		if !errors.Is(err, ErrInvalidSceneYAML) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error mismatch: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
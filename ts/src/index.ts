@@ -67,6 +67,17 @@ export interface Material {
   transparent?: boolean;
   wireframe?: boolean;
   texture?: string; // URL or asset ID
+  textureRegion?: TextureRegion; // atlas region sampled from texture
+}
+
+/**
+ * Normalized rectangle of a texture atlas
+ */
+export interface TextureRegion {
+  u: number; // 0-1
+  v: number; // 0-1
+  width: number; // 0-1
+  height: number; // 0-1
 }
 
 /**
@@ -76,6 +87,37 @@ export interface Geometry {
   type: 'box' | 'sphere' | 'cylinder' | 'plane' | 'custom';
   parameters?: Record<string, any>;
   asset?: string; // URL or asset ID for custom geometry
+  mesh?: Mesh; // inline triangle data for custom geometry
+  lods?: GeometryLOD[]; // simplified meshes by camera distance
+}
+
+/**
+ * Simplified geometry variant used beyond a camera distance
+ */
+export interface GeometryLOD {
+  distance: number;
+  mesh: Mesh;
+}
+
+/**
+ * Indexed triangle data for custom geometry
+ */
+export interface Mesh {
+  positions?: number[]; // flattened x, y, z triples
+  normals?: number[]; // flattened x, y, z triples
+  indices?: number[]; // three vertex indices per triangle
+  compressed?: CompressedMesh; // replaces the streams above when present
+}
+
+/**
+ * Mesh streams encoded with the EXT_meshopt_compression bitstreams, base64-encoded
+ */
+export interface CompressedMesh {
+  vertexCount: number;
+  indexCount?: number;
+  positions: string; // float32 triples, ATTRIBUTES mode
+  normals?: string; // float32 triples, ATTRIBUTES mode
+  indices?: string; // uint32, TRIANGLES mode
 }
 
 /**
@@ -105,18 +147,219 @@ export interface Animation {
   tracks: AnimationTrack[];
 }
 
+/**
+ * Text style for labels
+ */
+export interface TextStyle {
+  fontSize?: number; // scene units
+  lineHeight?: number; // multiple of fontSize
+  monospace?: boolean;
+}
+
+/**
+ * Text annotation attached to a node or edge
+ */
+export interface Label {
+  text: string;
+  style?: TextStyle;
+  anchor?: 'top' | 'bottom' | 'left' | 'right' | 'top-left' | 'top-right' | 'bottom-left' | 'bottom-right' | 'center';
+  offset?: Vector3; // from the node position or edge midpoint to the label center
+  priority?: number; // higher priority labels are placed first
+  hidden?: boolean;
+}
+
+/**
+ * Declarative particle effect visualizing traffic or event volume
+ */
+export interface ParticleSystem {
+  name?: string;
+  emitter: {
+    shape: 'point' | 'sphere' | 'box' | 'cone' | 'path'; // path follows an edge from source to target
+    radius?: number;
+    size?: Vector3;
+    angle?: number; // cone half-angle in radians
+  };
+  rate: number; // particles per second
+  lifetime: number; // seconds
+  speed?: number;
+  size?: number;
+  maxParticles?: number;
+  colorOverLife?: Array<{
+    t: number; // 0-1 normalized age
+    color: Color;
+  }>;
+
+  // Scales the rate by a metric mapped linearly from [min, max] onto [0, 1]
+  binding?: {
+    metric: string;
+    min?: number;
+    max: number;
+  };
+}
+
+/**
+ * Supporting media bundled with a node
+ */
+export interface Attachment {
+  id: string;
+  kind: 'audio' | 'image' | 'document' | 'link';
+  title?: string;
+  description?: string;
+  asset?: string; // key into SceneFile.assets
+  url?: string; // direct URL when no asset is set
+  mimeType?: string;
+  onStatus?: Array<'healthy' | 'warning' | 'critical' | 'unknown'>; // audio cues played on entering these statuses
+}
+
+/**
+ * Assistive-technology metadata for a node
+ */
+export interface Accessibility {
+  altText?: string;
+  description?: string;
+  role?: string;
+  hidden?: boolean; // hide from assistive technology
+}
+
+/**
+ * Locale-specific display text; empty fields fall back to less specific locales
+ */
+export interface Localization {
+  name?: string;
+  label?: string;
+  altText?: string;
+  description?: string;
+}
+
+/**
+ * Localized text keyed by BCP 47 language tag
+ */
+export type LocalizationMap = Record<string, Localization>;
+
+/**
+ * Named connection point on a node, such as a listener or replication endpoint
+ */
+export interface Port {
+  id: string;
+  name?: string;
+  direction?: 'in' | 'out' | 'inout';
+  protocol?: string;
+  position?: Vector3; // offset in node-local space
+}
+
+/**
+ * Reference from a mount node to another scene file
+ */
+export interface SceneRef {
+  uri: string;
+  transform?: Transform; // placement of the inlined roots; defaults to the mount node's transform
+  filter?: {
+    // a node is included when it matches every non-empty criterion
+    nodeIds?: string[];
+    nodeTypes?: string[];
+    tags?: string[];
+  };
+  namespace?: string; // prefix for inlined IDs
+}
+
+/**
+ * Collision volume in local space; box colliders use size, spheres radius,
+ * capsules and cylinders radius and height along Y
+ */
+export interface Collider {
+  shape: 'box' | 'sphere' | 'capsule' | 'cylinder' | 'convex' | 'mesh';
+  size?: Vector3;
+  radius?: number;
+  height?: number;
+  offset?: Vector3;
+}
+
+/**
+ * Simulation properties of a node
+ */
+export interface PhysicsBody {
+  type: 'static' | 'dynamic' | 'kinematic';
+  mass?: number; // required for dynamic bodies
+  collider?: Collider; // derived from the geometry when omitted
+  friction?: number;
+  restitution?: number; // 0-1
+  linearDamping?: number;
+  angularDamping?: number;
+}
+
+/**
+ * Physical connection between the source and target bodies of an edge
+ */
+export interface Joint {
+  type: 'fixed' | 'hinge' | 'ball' | 'slider' | 'spring';
+  sourceAnchor?: Vector3; // source body local space; defaults to its origin
+  targetAnchor?: Vector3; // target body local space; defaults to its origin
+  axis?: Vector3; // hinge rotation or slider direction
+  limits?: { min: number; max: number }; // radians for hinges, scene units for sliders
+  stiffness?: number; // springs
+  damping?: number; // springs
+  restLength?: number; // springs; defaults to the initial anchor distance
+}
+
+/**
+ * Service level objective attached to a node. With a threshold a data point
+ * is good when its value is at most the threshold (latency-style); without
+ * one each value is the fraction of good events (availability-style).
+ */
+export interface SLO {
+  name: string;
+  metric: string;
+  threshold?: number;
+  objective: number; // e.g. 0.999
+  window: number; // seconds
+  burnRates?: Array<{
+    window: number; // lookback in seconds
+    rate: number; // 1 spends the budget exactly over the SLO window
+    status: 'warning' | 'critical';
+  }>; // defaults to a fast critical and a slow warning burn
+}
+
+/**
+ * Chart attached to a node so every viewer shows the same dashboard for it
+ */
+export interface Panel {
+  id: string;
+  title?: string;
+  chart: 'line' | 'area' | 'bar' | 'gauge' | 'stat' | 'heatmap';
+  queries: Array<{
+    metric: string;
+    label?: string;
+    nodeIds?: string[]; // defaults to the node the panel is attached to
+    resolution?: number; // seconds
+    filters?: Record<string, any>;
+  }>;
+  range?: number; // seconds; default 3600
+  unit?: string;
+  min?: number;
+  max?: number;
+  thresholds?: Array<{
+    value: number; // the highest threshold at or below the value applies
+    status: 'healthy' | 'warning' | 'critical' | 'unknown';
+    color?: Color;
+  }>;
+}
+
 /**
  * Individual node in the scene graph
  */
 export interface SceneNode {
   id: string;
+  revision?: number; // maintained by the scene store
   type: string; // 'server', 'database', 'network', 'container', etc.
   name: string;
 
   // 3D Properties
   transform: Transform;
   geometry?: Geometry;
+  geometryRef?: string; // key into SceneFile.geometries
   material?: Material;
+  materialRef?: string; // key into SceneFile.materials
+  label?: Label;
   visible?: boolean;
 
   // Data Properties
@@ -126,14 +369,36 @@ export interface SceneNode {
   // Live Data
   metrics?: Record<string, any>;
   status?: 'healthy' | 'warning' | 'critical' | 'unknown';
+  slos?: SLO[]; // objectives whose burn rates drive status
+  panels?: Panel[]; // dashboard shown for the node
 
   // Animation
   animations?: Animation[];
+  particles?: ParticleSystem[];
+
+  // Media
+  attachments?: Attachment[];
+
+  // Accessibility and localization
+  accessibility?: Accessibility;
+  localizations?: LocalizationMap;
+
+  // Connection points
+  ports?: Port[];
+
+  // Physics
+  physics?: PhysicsBody;
+
+  // Composition
+  ref?: SceneRef; // scene inlined beneath this node
 
   // Hierarchy
   parent?: string; // parent node ID
   children?: string[]; // child node IDs
 
+  // Expiry
+  expiresAt?: string; // ISO timestamp after which the node is removed
+
   // Extensibility
   extensions?: Record<string, any>;
 }
@@ -143,15 +408,28 @@ export interface SceneNode {
  */
 export interface SceneEdge {
   id: string;
+  revision?: number; // maintained by the scene store
   source: string; // source node ID
   target: string; // target node ID
+  targetScene?: string; // scene holding the target node, for cross-scene edges
   type?: string; // 'network', 'data-flow', 'dependency', etc.
 
+  // Semantics
+  direction?: 'directed' | 'undirected' | 'bidirectional'; // default directed
+  key?: string; // distinguishes parallel edges between the same endpoints
+  sourcePort?: string; // port ID on the source node
+  targetPort?: string; // port ID on the target node
+  weight?: number; // e.g. the number of aggregated parallel edges
+
+  // Routing
+  waypoints?: Vector3[]; // control points between source and target
+
   // Visual Properties
   color?: Color;
   width?: number;
   style?: 'solid' | 'dashed' | 'dotted';
   opacity?: number;
+  label?: Label;
 
   // Data Properties
   metadata?: Record<string, any>;
@@ -159,6 +437,16 @@ export interface SceneEdge {
 
   // Animation
   animations?: Animation[];
+  particles?: ParticleSystem[];
+
+  // Physics
+  joint?: Joint; // connects the endpoint bodies
+
+  // Localization
+  localizations?: LocalizationMap;
+
+  // Expiry
+  expiresAt?: string; // ISO timestamp after which the edge is removed
 
   // Extensibility
   extensions?: Record<string, any>;
@@ -206,6 +494,30 @@ export interface SceneGraph {
   };
 }
 
+export type LifecycleState = 'draft' | 'in-review' | 'published' | 'archived';
+
+/**
+ * Change-control workflow state of a scene. Approvals and history are
+ * written by the service's lifecycle endpoints, not by scene writes.
+ */
+export interface Lifecycle {
+  state: LifecycleState;
+  requiredApprovers?: string[]; // each must approve before publishing
+  minApprovals?: number;
+  approvals?: Array<{
+    actor: string; // authenticated approver
+    time: string; // ISO timestamp
+    comment?: string;
+  }>;
+  history?: Array<{
+    from: LifecycleState;
+    to: LifecycleState;
+    actor?: string;
+    time: string; // ISO timestamp
+    comment?: string;
+  }>;
+}
+
 /**
  * Scene metadata
  */
@@ -218,26 +530,91 @@ export interface SceneMetadata {
   updated?: string; // ISO timestamp
   tags?: string[];
 
+  // Localization
+  locale?: string; // BCP 47 tag of the authored text
+  localizations?: LocalizationMap;
+
   // Import info
   importSource?: string;
   importedAt?: string;
   importedBy?: string;
 
+  // Change control
+  lifecycle?: Lifecycle;
+
   // Extensibility
   extensions?: Record<string, any>;
 }
 
+/**
+ * Optional construct a reader must understand to render a scene. Newer
+ * releases may add names.
+ */
+export type Capability =
+  | 'labels'
+  | 'particles'
+  | 'attachments'
+  | 'accessibility'
+  | 'localization'
+  | 'ports'
+  | 'edge-semantics'
+  | 'edge-routing'
+  | 'scene-refs'
+  | 'resource-libraries'
+  | 'meshes'
+  | 'mesh-compression'
+  | 'lods'
+  | 'texture-atlas'
+  | 'physics'
+  | 'lifecycle'
+  | 'slos'
+  | 'panels'
+  | 'expiry'
+  | 'revisions'
+  | 'requirements';
+
+/**
+ * Rendering feature a viewer may or may not have
+ */
+export type ViewerFeature = 'instancing' | 'custom-shaders' | 'post-processing' | 'webxr';
+
+/**
+ * Viewer rendering feature a scene uses. Required features must be present
+ * to show the scene; optional ones are stripped by removing the extension
+ * holding their data.
+ */
+export interface FeatureRequirement {
+  feature: ViewerFeature;
+  required?: boolean;
+  extension?: string; // extension key of the feature's data
+}
+
+/**
+ * What a viewer needs to render a scene
+ */
+export interface SceneRequirements {
+  minViewerVersion?: string; // semver
+  features?: FeatureRequirement[];
+  fallback?: string; // shown by viewers that cannot meet the requirements
+}
+
 /**
  * Complete scene file
  */
 export interface SceneFile {
   version: string; // SDK version
+  capabilities?: Capability[]; // optional constructs the scene uses
+  requirements?: SceneRequirements; // what viewers need to show it
   metadata: SceneMetadata;
   scene: SceneGraph;
 
   // Asset references
   assets?: Record<string, string>; // asset ID -> URL mapping
 
+  // Shared resources
+  materials?: Record<string, Material>;
+  geometries?: Record<string, Geometry>;
+
   // Extensibility
   extensions?: Record<string, any>;
 }
@@ -298,6 +675,7 @@ export interface ProviderConfig {
  */
 export interface MetricsQuery {
   nodeIds?: string[];
+  edgeIds?: string[];
   metricNames?: string[];
   from?: Date;
   to?: Date;
@@ -318,7 +696,8 @@ export interface MetricsDataPoint {
  * Metrics result
  */
 export interface MetricsResult {
-  nodeId: string;
+  nodeId?: string; // set for node results
+  edgeId?: string; // set instead of nodeId for edge results
   metricName: string;
   dataPoints: MetricsDataPoint[];
   unit?: string;